@@ -0,0 +1,67 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/audit"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// SetAuditLog records the administrative and write operations performed
+// through the API in the log
+func (a *CatalogAPI) SetAuditLog(log audit.Log) {
+	a.auditLog = log
+}
+
+// audit records an operation by the client performing it, as failed if err
+// is set. The operation has already happened, so a failure to record it is
+// logged rather than returned.
+func (a *CatalogAPI) audit(entry model.AuditEntry, err error, ctx context.Context) {
+	if a.auditLog == nil {
+		return
+	}
+
+	entry.Time = time.Now().UTC()
+	entry.Actor = audit.Actor(ctx)
+	entry.Outcome = model.AuditSucceeded
+	if err != nil {
+		entry.Outcome = model.AuditFailed
+		entry.Error = err.Error()
+	}
+
+	if err := a.auditLog.Record(entry, ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to record audit entry", "action", entry.Action, "actor", entry.Actor, "error", err)
+	}
+}
+
+// auditSnapshot returns the stored product as it was before a change, when
+// there is an audit log to record it in
+func (a *CatalogAPI) auditSnapshot(id string, ctx context.Context) *model.Product {
+	if a.auditLog == nil {
+		return nil
+	}
+
+	product, err := a.repository.GetProduct(id, ctx)
+	if err != nil {
+		return nil
+	}
+	return product
+}
@@ -0,0 +1,229 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/cache"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// Namespaces of the values in the shared cache, which also name them in the
+// cache metrics
+const (
+	cacheProducts  = "products"
+	cacheSearch    = "search"
+	cacheReference = "reference"
+)
+
+// searchKey identifies a page of search results, either by its number or, for
+// providers that continue from the last hit, as the first page of a cursor
+type searchKey struct {
+	// query is the key of the search query
+	query  string
+	page   int
+	size   int
+	cursor bool
+	// fields are the product fields loaded for each result, since results
+	// loaded with only some of them can't answer searches for the rest
+	fields string
+	// facets is set for searches that count facets, whose results are
+	// cached with the counts
+	facets bool
+}
+
+// newSearchKey returns the key of a page of results for the query
+func newSearchKey(query repository.SearchQuery, page, size int, cursor bool) searchKey {
+	// The ID is always loaded and the order fields are asked for in doesn't
+	// matter
+	fields := slices.Clone(query.LoadedFields())
+	slices.Sort(fields)
+
+	return searchKey{
+		query:  query.Key(),
+		page:   page,
+		size:   size,
+		cursor: cursor,
+		fields: strings.Join(slices.Compact(fields), ","),
+		facets: query.Facets,
+	}
+}
+
+func (k searchKey) String() string {
+	key := fmt.Sprintf("%d:%d:%t", k.page, k.size, k.cursor)
+	if k.fields != "" {
+		key += ":fields=" + k.fields
+	}
+	if k.facets {
+		key += ":facets"
+	}
+	return key + ":" + k.query
+}
+
+// searchResult is a cached page of search results with the sort values of
+// its last hit, for pages that can be continued from. Degraded results, from
+// the search provider's fallback, are only shared with searches made while
+// they load.
+type searchResult struct {
+	Products []model.Product           `json:"products"`
+	Last     []interface{}             `json:"last,omitempty"`
+	Degraded bool                      `json:"degraded,omitempty"`
+	Facets   map[string]map[string]int `json:"facets,omitempty"`
+}
+
+// responseCache keeps products looked up by ID and pages of search results in
+// memory, in front of a cache shared by every replica that also keeps the
+// tags and categories. Either tier may be missing. Every change made through
+// the API invalidates both, and a change made by another replica is seen
+// once the value cached in memory expires.
+type responseCache struct {
+	products *cache.LRU[string, model.Product]
+	searches *cache.LRU[searchKey, searchResult]
+
+	shared       *cache.Redis
+	productTTL   time.Duration
+	searchTTL    time.Duration
+	referenceTTL time.Duration
+}
+
+// SetCache caches up to size product lookups and size pages of search
+// results in memory for at most ttl each
+func (a *CatalogAPI) SetCache(size int, ttl time.Duration) {
+	if a.cache == nil {
+		a.cache = &responseCache{}
+	}
+	a.cache.products = cache.New[string, model.Product](cacheProducts, size, ttl)
+	a.cache.searches = cache.New[searchKey, searchResult](cacheSearch, size, ttl)
+}
+
+// SetSharedCache caches product lookups, pages of search results, and the
+// tags and categories in a cache shared by every replica, for the given time
+func (a *CatalogAPI) SetSharedCache(shared *cache.Redis, productTTL, searchTTL, referenceTTL time.Duration) {
+	if a.cache == nil {
+		a.cache = &responseCache{}
+	}
+	a.cache.shared = shared
+	a.cache.productTTL = productTTL
+	a.cache.searchTTL = searchTTL
+	a.cache.referenceTTL = referenceTTL
+}
+
+// getProduct returns a copy of the cached product, so that callers changing
+// it don't change the cache
+func (c *responseCache) getProduct(id string, load func() (*model.Product, error), ctx context.Context) (*model.Product, error) {
+	if c == nil {
+		return load()
+	}
+
+	product, err := c.products.Get(id, func() (model.Product, error) {
+		return cache.GetJSON(c.shared, cacheProducts, id, c.productTTL, func() (model.Product, error) {
+			product, err := load()
+			if err != nil {
+				return model.Product{}, err
+			}
+			return *product, nil
+		}, ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	restoreCategory(&product)
+	return &product, nil
+}
+
+// search returns a copy of the cached page of results. Degraded results are
+// dropped once loaded, so that searches go back to the provider's own index
+// as soon as it recovers, and marked degraded for every caller they reach.
+// Facet counts are cached with the results.
+func (c *responseCache) search(key searchKey, load func(ctx context.Context) (searchResult, error), ctx context.Context) (searchResult, error) {
+	if c == nil {
+		return load(ctx)
+	}
+
+	result, err := c.searches.Get(key, func() (searchResult, error) {
+		return cache.GetJSON(c.shared, cacheSearch, key.String(), c.searchTTL, func() (searchResult, error) {
+			ctx, degraded := repository.TrackDegraded(ctx)
+			result, err := load(ctx)
+			result.Degraded = degraded()
+			return result, err
+		}, ctx)
+	})
+	if err != nil {
+		return searchResult{}, err
+	}
+	if result.Degraded {
+		c.searches.Remove(key)
+		c.shared.Delete(cacheSearch, key.String(), ctx)
+		repository.MarkDegraded(ctx)
+	}
+
+	products := slices.Clone(result.Products)
+	for i := range products {
+		restoreCategory(&products[i])
+	}
+	return searchResult{Products: products, Last: result.Last, Degraded: result.Degraded, Facets: result.Facets}, nil
+}
+
+// reference returns the tags or categories, which only the shared cache keeps
+func reference[V any](c *responseCache, key string, load func() (V, error), ctx context.Context) (V, error) {
+	if c == nil {
+		return load()
+	}
+	return cache.GetJSON(c.shared, cacheReference, key, c.referenceTTL, load, ctx)
+}
+
+// productChanged drops the product and every page of search results, which
+// it may have been added to, removed from or moved within
+func (c *responseCache) productChanged(id string, ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	c.products.Remove(id)
+	c.searches.Purge()
+	c.shared.Delete(cacheProducts, id, ctx)
+	c.shared.Invalidate(cacheSearch, ctx)
+}
+
+// catalogChanged drops everything, for changes to many products at once or
+// to the search index
+func (c *responseCache) catalogChanged(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	c.products.Purge()
+	c.searches.Purge()
+	for _, namespace := range []string{cacheProducts, cacheSearch, cacheReference} {
+		c.shared.Invalidate(namespace, ctx)
+	}
+}
+
+// restoreCategory sets the category name of a product read back from the
+// shared cache, which isn't part of its JSON representation
+func restoreCategory(product *model.Product) {
+	if product.CategoryName == nil && product.Category != nil {
+		name := product.Category.Name
+		product.CategoryName = &name
+	}
+}
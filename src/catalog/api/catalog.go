@@ -18,54 +18,428 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
+	"github.com/aws-containers/retail-store-sample-app/catalog/audit"
+	"github.com/aws-containers/retail-store-sample-app/catalog/images"
 	"github.com/aws-containers/retail-store-sample-app/catalog/model"
 	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
 )
 
+// ErrReadOnly is returned when the persistence provider does not accept changes
+var ErrReadOnly = errors.New("the persistence provider does not support changes")
+
+// ErrExplainNotSupported is returned when the search provider can't explain
+// how it scores results
+var ErrExplainNotSupported = errors.New("the search provider does not support explaining results")
+
 // CatalogAPI type
 type CatalogAPI struct {
 	repository       repository.CatalogRepository
 	searchRepository repository.SearchRepository
+	events           *EventBroker
+	reindexJobs      *reindexJobs
+	importJobs       *importJobs
+	background       *backgroundJobs
+	cursorSecret     []byte
+	sitemap          *sitemap
+	auditLog         audit.Log
+	cache            *responseCache
+	images           images.Store
 }
 
-func (a *CatalogAPI) GetProducts(tags []string, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
-	products, err := a.repository.GetProducts(tags, order, pageNum, pageSize, ctx)
+func (a *CatalogAPI) GetProducts(filter repository.ProductFilter, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
+	products, err := a.repository.GetProducts(filter, order, pageNum, pageSize, ctx)
 	if err != nil {
 		return nil, err
 	}
 	return products, nil
 }
 
+// ExportProducts passes every product in the catalog matching the filter to
+// the callback, one page at a time, so the caller never has to hold the
+// whole catalog in memory
+func (a *CatalogAPI) ExportProducts(filter repository.ProductFilter, pageSize int, ctx context.Context, fn func([]model.Product) error) error {
+	for page := 1; ; page++ {
+		products, err := a.repository.GetProducts(filter, "", page, pageSize, ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(products) > 0 {
+			if err := fn(products); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < pageSize {
+			return nil
+		}
+	}
+}
+
 func (a *CatalogAPI) GetProduct(id string, ctx context.Context) (*model.Product, error) {
-	return a.repository.GetProduct(id, ctx)
+	return a.cache.getProduct(id, func() (*model.Product, error) {
+		return a.repository.GetProduct(id, ctx)
+	}, ctx)
+}
+
+func (a *CatalogAPI) GetProductsByIDs(ids []string, ctx context.Context) ([]model.Product, error) {
+	return a.repository.GetProductsByIDs(ids, ctx)
 }
 
 func (a *CatalogAPI) GetTags(ctx context.Context) ([]model.Tag, error) {
-	return a.repository.GetTags(ctx)
+	return reference(a.cache, "tags", func() ([]model.Tag, error) {
+		return a.repository.GetTags(ctx)
+	}, ctx)
+}
+
+// getCategories returns every category, in the order the repository lists them
+func (a *CatalogAPI) getCategories(ctx context.Context) ([]model.Category, error) {
+	return reference(a.cache, "categories", func() ([]model.Category, error) {
+		return a.repository.GetCategories(ctx)
+	}, ctx)
+}
+
+// GetTagCounts returns every tag with the number of products that have it.
+// Counts come from the search index when it supports aggregation, and from
+// the catalog otherwise or if the index can't be queried.
+func (a *CatalogAPI) GetTagCounts(ctx context.Context) ([]model.TagCount, error) {
+	tags, err := a.GetTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts map[string]int
+	if counter, ok := a.searchRepository.(repository.TagCounter); ok {
+		counts, err = counter.CountProductsByTag(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to count tags in the search index, using the catalog", "error", err)
+		}
+	}
+
+	if counts == nil {
+		counts, err = a.repository.CountProductsByTag(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]model.TagCount, len(tags))
+	for i, tag := range tags {
+		result[i] = model.TagCount{Tag: tag, ProductCount: counts[tag.Name]}
+	}
+
+	return result, nil
+}
+
+// GetCategoryTree returns the top-level categories with their subcategories
+// nested beneath them
+func (a *CatalogAPI) GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error) {
+	categories, err := a.getCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]model.Category)
+	roots := []model.Category{}
+	for _, category := range categories {
+		if category.ParentName == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentName] = append(children[*category.ParentName], category)
+		}
+	}
+
+	var build func([]model.Category) []model.CategoryNode
+	build = func(categories []model.Category) []model.CategoryNode {
+		nodes := make([]model.CategoryNode, len(categories))
+		for i, category := range categories {
+			nodes[i] = model.CategoryNode{
+				Category: category,
+				Children: build(children[category.Name]),
+			}
+		}
+		return nodes
+	}
+
+	return build(roots), nil
+}
+
+// GetCategory returns a category with its breadcrumbs, or
+// ErrCategoryNotFound if there is no such category
+func (a *CatalogAPI) GetCategory(name string, ctx context.Context) (*model.Category, error) {
+	categories, err := a.getCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		if category.Name == name {
+			category.Breadcrumbs = breadcrumbs(category, categories)
+			return &category, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", repository.ErrCategoryNotFound, name)
+}
+
+// GetCategoryProducts returns a page of the products in a category and its
+// descendants, or ErrCategoryNotFound if there is no such category
+func (a *CatalogAPI) GetCategoryProducts(name string, order string, token string, pageNum, pageSize int, ctx context.Context) (*ProductPage, error) {
+	if _, err := a.GetCategory(name, ctx); err != nil {
+		return nil, err
+	}
+
+	return a.GetProductsPage(repository.ProductFilter{Category: name, Statuses: []string{model.StatusActive}}, order, token, pageNum, pageSize, ctx)
+}
+
+// WithBreadcrumbs returns the products with the breadcrumbs of their
+// categories filled in. The products are copied, since they may be shared
+// with the cache, and returned unchanged if the categories can't be read.
+func (a *CatalogAPI) WithBreadcrumbs(products []model.Product, ctx context.Context) []model.Product {
+	categories, err := a.getCategories(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read categories for breadcrumbs", "error", err)
+		return products
+	}
+
+	resolved := make([]model.Product, len(products))
+	for i, product := range products {
+		if product.Category != nil {
+			category := *product.Category
+			category.Breadcrumbs = breadcrumbs(category, categories)
+			product.Category = &category
+		}
+		resolved[i] = product
+	}
+	return resolved
+}
+
+// breadcrumbs lists the category's ancestors from the top of the tree, and
+// then the category itself
+func breadcrumbs(category model.Category, categories []model.Category) []model.Breadcrumb {
+	byName := make(map[string]model.Category, len(categories))
+	for _, c := range categories {
+		byName[c.Name] = c
+	}
+
+	// Parents are followed until the top of the tree, stopping at a missing
+	// or repeated category so that a broken tree can't loop
+	var crumbs []model.Breadcrumb
+	seen := make(map[string]bool)
+	for name := &category.Name; name != nil && !seen[*name]; {
+		current, ok := byName[*name]
+		if !ok {
+			break
+		}
+		seen[current.Name] = true
+		crumbs = append([]model.Breadcrumb{{Name: current.Name, DisplayName: current.DisplayName}}, crumbs...)
+		name = current.ParentName
+	}
+	return crumbs
+}
+
+func (a *CatalogAPI) GetSize(filter repository.ProductFilter, ctx context.Context) (int, error) {
+	return a.repository.CountProducts(filter, ctx)
+}
+
+func (a *CatalogAPI) CreateProduct(product *model.Product, ctx context.Context) (*model.Product, error) {
+	writer, ok := a.repository.(repository.CatalogWriter)
+	if !ok {
+		return nil, ErrReadOnly
+	}
+
+	if err := writer.CreateProduct(product, ctx); err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductCreate, ProductIDs: []string{product.ID}}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(product.ID, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductCreate, ProductIDs: []string{product.ID}, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductCreated, ProductID: product.ID, Product: product})
+	return product, nil
 }
 
-func (a *CatalogAPI) GetSize(tags []string, ctx context.Context) (int, error) {
-	return a.repository.CountProducts(tags, ctx)
+func (a *CatalogAPI) UpdateProduct(product *model.Product, ctx context.Context) (*model.Product, error) {
+	writer, ok := a.repository.(repository.CatalogWriter)
+	if !ok {
+		return nil, ErrReadOnly
+	}
+
+	// The snapshot is read separately from the update, so a change made
+	// between the two would be missing from the audit log's before
+	before := a.auditSnapshot(product.ID, ctx)
+
+	if err := writer.UpdateProduct(product, ctx); err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductUpdate, ProductIDs: []string{product.ID}, Before: before}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(product.ID, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductUpdate, ProductIDs: []string{product.ID}, Before: before, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return product, nil
+}
+
+func (a *CatalogAPI) DeleteProduct(id string, ctx context.Context) error {
+	writer, ok := a.repository.(repository.CatalogWriter)
+	if !ok {
+		return ErrReadOnly
+	}
+
+	before := a.auditSnapshot(id, ctx)
+
+	if err := writer.DeleteProduct(id, ctx); err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductDelete, ProductIDs: []string{id}, Before: before}, err, ctx)
+		return err
+	}
+	a.cache.productChanged(id, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductDelete, ProductIDs: []string{id}, Before: before}, nil, ctx)
+
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductDeleted, ProductID: id})
+	return nil
 }
 
 func (a *CatalogAPI) IsSearchEnabled() bool {
 	return a.searchRepository != nil
 }
 
-func (a *CatalogAPI) SearchProducts(keyword string, page, size int, ctx context.Context) ([]model.Product, error) {
+func (a *CatalogAPI) SearchProducts(query repository.SearchQuery, page, size int, ctx context.Context) ([]model.Product, error) {
 	if a.searchRepository == nil {
 		return nil, nil
 	}
-	return a.searchRepository.SearchProducts(keyword, page, size, ctx)
+	result, err := a.searchProducts(query, page, size, ctx)
+	return result.Products, err
+}
+
+// searchProducts returns a page of search results, along with their facet
+// counts if the query asks for them
+func (a *CatalogAPI) searchProducts(query repository.SearchQuery, page, size int, ctx context.Context) (searchResult, error) {
+	return a.cache.search(newSearchKey(query, page, size, false), func(ctx context.Context) (searchResult, error) {
+		result, err := a.searchRepository.SearchProducts(query, page, size, ctx)
+		return searchResult{Products: result.Products, Facets: result.Facets}, err
+	}, ctx)
+}
+
+// StreamSearch passes every product matching the keyword to the callback, a
+// batch at a time in order of relevance, bypassing the cache. Search
+// providers that can't stream are paged through instead, which is only
+// consistent if the index doesn't change meanwhile.
+func (a *CatalogAPI) StreamSearch(keyword string, batchSize int, ctx context.Context, fn func([]model.Product) error) error {
+	if a.searchRepository == nil {
+		return fmt.Errorf("search is not enabled")
+	}
+	if streamer, ok := a.searchRepository.(repository.SearchStreamer); ok {
+		return streamer.StreamSearch(keyword, batchSize, ctx, fn)
+	}
+
+	for page := 1; ; page++ {
+		result, err := a.searchRepository.SearchProducts(repository.SearchQuery{Keyword: keyword}, page, batchSize, ctx)
+		if err != nil {
+			return err
+		}
+		products := result.Products
+
+		if len(products) > 0 {
+			if err := fn(products); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < batchSize {
+			return nil
+		}
+	}
+}
+
+// ExplainSearch shows the query the search provider runs for a keyword and
+// how each hit on the first page of results was scored
+func (a *CatalogAPI) ExplainSearch(keyword string, size int, ctx context.Context) (*model.SearchExplanation, error) {
+	explainer, ok := a.searchRepository.(repository.SearchExplainer)
+	if !ok {
+		return nil, ErrExplainNotSupported
+	}
+	return explainer.ExplainSearch(keyword, size, ctx)
 }
 
-func (a *CatalogAPI) Reindex() error {
+func (a *CatalogAPI) Reindex(ctx context.Context) error {
 	if a.searchRepository == nil {
 		return fmt.Errorf("search is not enabled")
 	}
-	return a.searchRepository.Reindex()
+	err := a.searchRepository.Reindex(ctx)
+	a.cache.catalogChanged(ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditCatalogReindex}, err, ctx)
+		return err
+	}
+
+	a.audit(model.AuditEntry{Action: model.AuditCatalogReindex}, nil, ctx)
+
+	a.events.Publish(model.CatalogEvent{Type: model.EventReindexCompleted})
+	return nil
+}
+
+// ResetCatalog restores the catalog to the bundled seed data and rebuilds the
+// search index to match
+func (a *CatalogAPI) ResetCatalog(ctx context.Context) error {
+	resetter, ok := a.repository.(repository.CatalogResetter)
+	if !ok {
+		return ErrReadOnly
+	}
+
+	if a.reindexJobs.isRunning() {
+		return ErrReindexInProgress
+	}
+
+	// Dropped again once the index is rebuilt, since searches made in
+	// between may still find the products that were removed
+	err := resetter.ResetCatalog(ctx)
+	a.cache.catalogChanged(ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditCatalogReset}, err, ctx)
+		return err
+	}
+
+	// The catalog has been reset whether or not the index can be rebuilt
+	a.audit(model.AuditEntry{Action: model.AuditCatalogReset}, nil, ctx)
+
+	if a.searchRepository != nil {
+		err := a.searchRepository.Reindex(ctx)
+		a.cache.catalogChanged(ctx)
+		if err != nil {
+			return fmt.Errorf("the catalog was reset but the search index could not be rebuilt: %w", err)
+		}
+	}
+
+	a.events.Publish(model.CatalogEvent{Type: model.EventCatalogReset})
+	return nil
+}
+
+// Events returns the broker that publishes changes made through the API
+func (a *CatalogAPI) Events() *EventBroker {
+	return a.events
+}
+
+func (a *CatalogAPI) IsDualWriteEnabled() bool {
+	_, ok := a.repository.(*repository.DualWriteRepository)
+	return ok
+}
+
+func (a *CatalogAPI) Reconcile(repair bool, ctx context.Context) (*repository.ReconcileReport, error) {
+	dualWrite, ok := a.repository.(*repository.DualWriteRepository)
+	if !ok {
+		return nil, fmt.Errorf("dual-write is not enabled")
+	}
+
+	report, err := dualWrite.Reconcile(repair, ctx)
+	if repair {
+		a.cache.catalogChanged(ctx)
+		a.audit(model.AuditEntry{Action: model.AuditCatalogReconcile}, err, ctx)
+	}
+	return report, err
 }
 
 // NewCatalogAPI constructor
@@ -73,5 +447,27 @@ func NewCatalogAPI(repository repository.CatalogRepository, searchRepository rep
 	return &CatalogAPI{
 		repository:       repository,
 		searchRepository: searchRepository,
+		events:           NewEventBroker(),
+		reindexJobs:      newReindexJobs(),
+		importJobs:       newImportJobs(),
+		background:       newBackgroundJobs(),
+		cursorSecret:     newCursorSecret(),
+		sitemap:          newSitemap(),
+		images:           images.NewEmbeddedStore(),
 	}, nil
 }
+
+// ErrSKULookupNotSupported is returned when the persistence provider can't
+// find products by SKU
+var ErrSKULookupNotSupported = errors.New("the persistence provider does not look up products by SKU")
+
+// GetProductBySKU returns the product with the SKU or EAN, or with a variant
+// with the SKU
+func (a *CatalogAPI) GetProductBySKU(code string, ctx context.Context) (*model.Product, error) {
+	lookup, ok := a.repository.(repository.SKULookup)
+	if !ok {
+		return nil, ErrSKULookupNotSupported
+	}
+
+	return lookup.GetProductBySKU(code, ctx)
+}
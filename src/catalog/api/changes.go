@@ -0,0 +1,144 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrChangesNotSupported is returned when the persistence provider doesn't
+// keep a log of changes
+var ErrChangesNotSupported = errors.New("the persistence provider does not record changes")
+
+// ErrInvalidSince is returned for a since value that is neither a timestamp
+// nor a cursor from an earlier response
+var ErrInvalidSince = errors.New("since must be an RFC 3339 timestamp or a cursor")
+
+const changesCursorPrefix = "changes:"
+
+// GetChanges returns the changes made to the catalog after since, which is
+// either an RFC 3339 timestamp or the cursor of an earlier response. Without
+// since the log is read from the beginning. Created and updated products are
+// included in their current state.
+func (a *CatalogAPI) GetChanges(since string, limit int, ctx context.Context) (*model.ChangesResponse, error) {
+	changeLog, ok := a.repository.(repository.ChangeLog)
+	if !ok {
+		return nil, ErrChangesNotSupported
+	}
+
+	var changes []model.ProductChange
+	var err error
+
+	if timestamp, parseErr := time.Parse(time.RFC3339Nano, since); parseErr == nil {
+		changes, err = changeLog.GetChangesSince(timestamp, limit+1, ctx)
+	} else {
+		var sequence uint64
+		if since != "" {
+			if sequence, err = decodeChangesCursor(since); err != nil {
+				return nil, err
+			}
+		}
+		changes, err = changeLog.GetChangesAfter(sequence, limit+1, ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.ChangesResponse{
+		Changes: changes,
+		Cursor:  since,
+	}
+
+	if len(changes) > limit {
+		response.Changes = changes[:limit]
+		response.HasMore = true
+	}
+
+	if len(response.Changes) == 0 {
+		return response, nil
+	}
+	response.Cursor = encodeChangesCursor(response.Changes[len(response.Changes)-1].Sequence)
+
+	if err := a.attachChangedProducts(response.Changes, ctx); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// attachChangedProducts adds the current state of the product to every change
+// that created or updated one which still exists
+func (a *CatalogAPI) attachChangedProducts(changes []model.ProductChange, ctx context.Context) error {
+	ids := []string{}
+	for _, change := range changes {
+		if change.Type == model.ChangeCreated || change.Type == model.ChangeUpdated {
+			ids = append(ids, change.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	products, err := a.repository.GetProductsByIDs(ids, ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*model.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+
+	for i := range changes {
+		if changes[i].Type != model.ChangeDeleted {
+			changes[i].Product = byID[changes[i].ProductID]
+		}
+	}
+
+	return nil
+}
+
+func encodeChangesCursor(sequence uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(changesCursorPrefix + strconv.FormatUint(sequence, 10)))
+}
+
+func decodeChangesCursor(token string) (uint64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidSince
+	}
+
+	value, found := strings.CutPrefix(string(decoded), changesCursorPrefix)
+	if !found {
+		return 0, ErrInvalidSince
+	}
+
+	sequence, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSince
+	}
+
+	return sequence, nil
+}
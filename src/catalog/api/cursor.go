@@ -0,0 +1,250 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed, has been
+// altered or was issued for a different query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProductPage is a page of products with the cursor for the page after it,
+// which is empty when there are no more products
+type ProductPage struct {
+	Products   []model.Product
+	NextCursor string
+	// Facets are the facet counts of a search that asked for them
+	Facets map[string]map[string]int
+}
+
+// cursor is the position a client has reached in a list or search. Cursors
+// are signed so clients can't forge positions, and scoped to the query they
+// were issued for.
+type cursor struct {
+	Scope string `json:"q"`
+	Size  int    `json:"n"`
+	// Keyset position in the database
+	After *repository.ProductPosition `json:"a,omitempty"`
+	// Sort values of the last hit for search providers that support them
+	SearchAfter []interface{} `json:"s,omitempty"`
+	// Next page for search providers that only support offsets
+	Page int `json:"p,omitempty"`
+}
+
+func newCursorSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor secret: %v", err))
+	}
+	return secret
+}
+
+// SetCursorSecret sets the key used to sign cursors. Without one a random key
+// is used, so cursors can't be used across restarts or with other replicas.
+func (a *CatalogAPI) SetCursorSecret(secret string) {
+	if secret != "" {
+		a.cursorSecret = []byte(secret)
+	}
+}
+
+func (a *CatalogAPI) encodeCursor(c cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(a.sign(payload)), nil
+}
+
+func (a *CatalogAPI) decodeCursor(token string, scope string) (*cursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, a.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Scope != scope {
+		return nil, fmt.Errorf("%w: it was issued for a different query", ErrInvalidCursor)
+	}
+
+	if c.Size < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+func (a *CatalogAPI) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, a.cursorSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// cursorScope identifies a query so that its cursors are rejected by others
+func cursorScope(kind string, parts ...interface{}) string {
+	hash := sha256.New()
+	hash.Write([]byte(kind))
+	for _, part := range parts {
+		encoded, _ := json.Marshal(part)
+		hash.Write(encoded)
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:12])
+}
+
+// GetProductsPage returns a page of products, continuing from the cursor if
+// one is given and from the page number otherwise. Continuing from a cursor
+// doesn't skip or repeat products when the catalog changes between pages.
+func (a *CatalogAPI) GetProductsPage(filter repository.ProductFilter, order string, token string, pageNum, pageSize int, ctx context.Context) (*ProductPage, error) {
+	scope := cursorScope("products", filter, order)
+
+	var products []model.Product
+	var err error
+	if token != "" {
+		var c *cursor
+		if c, err = a.decodeCursor(token, scope); err != nil {
+			return nil, err
+		}
+		if c.After == nil {
+			return nil, ErrInvalidCursor
+		}
+
+		pageSize = c.Size
+		products, err = a.repository.GetProductsAfter(filter, order, *c.After, pageSize, ctx)
+	} else {
+		products, err = a.repository.GetProducts(filter, order, pageNum, pageSize, ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > 0 && len(products) == pageSize {
+		position := repository.PositionOf(products[len(products)-1])
+		page.NextCursor, err = a.encodeCursor(cursor{Scope: scope, Size: pageSize, After: &position})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// SearchProductsPage returns a page of search results, continuing from the
+// cursor if one is given and from the page number otherwise. Providers that
+// can continue from the last hit do so, others fall back to offsets.
+func (a *CatalogAPI) SearchProductsPage(query repository.SearchQuery, token string, pageNum, pageSize int, ctx context.Context) (*ProductPage, error) {
+	if a.searchRepository == nil {
+		return &ProductPage{}, nil
+	}
+
+	scope := cursorScope("search", query.Key())
+
+	var c *cursor
+	if token != "" {
+		var err error
+		if c, err = a.decodeCursor(token, scope); err != nil {
+			return nil, err
+		}
+		pageSize = c.Size
+		pageNum = c.Page
+	}
+
+	searcher, ok := a.searchRepository.(repository.CursorSearcher)
+	if ok && ((c != nil && c.SearchAfter != nil) || (c == nil && pageNum == 1)) {
+		var after []interface{}
+		if c != nil {
+			after = c.SearchAfter
+		}
+
+		search := func(ctx context.Context) (searchResult, error) {
+			result, last, err := searcher.SearchProductsAfter(query, after, pageSize, ctx)
+			return searchResult{Products: result.Products, Last: last, Facets: result.Facets}, err
+		}
+
+		// Only first pages are cached, since later ones are reached through
+		// cursors that are rarely shared
+		var result searchResult
+		var err error
+		if after == nil {
+			result, err = a.cache.search(newSearchKey(query, 0, pageSize, true), search, ctx)
+		} else {
+			result, err = search(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		products, last := result.Products, result.Last
+
+		page := &ProductPage{Products: products, Facets: result.Facets}
+		if len(products) > 0 && len(products) == pageSize && len(last) > 0 {
+			page.NextCursor, err = a.encodeCursor(cursor{Scope: scope, Size: pageSize, SearchAfter: last})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return page, nil
+	}
+
+	if pageNum < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	result, err := a.searchProducts(query, pageNum, pageSize, ctx)
+	if err != nil {
+		return nil, err
+	}
+	products := result.Products
+
+	page := &ProductPage{Products: products, Facets: result.Facets}
+	if len(products) > 0 && len(products) == pageSize {
+		page.NextCursor, err = a.encodeCursor(cursor{Scope: scope, Size: pageSize, Page: pageNum + 1})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
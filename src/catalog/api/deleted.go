@@ -0,0 +1,59 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrDeletedProductsNotSupported is returned when the persistence provider
+// doesn't keep deleted products
+var ErrDeletedProductsNotSupported = errors.New("the persistence provider does not keep deleted products")
+
+// GetDeletedProducts returns a page of the deleted products, most recently
+// deleted first
+func (a *CatalogAPI) GetDeletedProducts(pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
+	deleted, ok := a.repository.(repository.DeletedProductRepository)
+	if !ok {
+		return nil, ErrDeletedProductsNotSupported
+	}
+
+	return deleted.GetDeletedProducts(pageNum, pageSize, ctx)
+}
+
+// RestoreProduct undeletes a product, which is published as created again
+func (a *CatalogAPI) RestoreProduct(id string, ctx context.Context) (*model.Product, error) {
+	deleted, ok := a.repository.(repository.DeletedProductRepository)
+	if !ok {
+		return nil, ErrDeletedProductsNotSupported
+	}
+
+	product, err := deleted.RestoreProduct(id, ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductRestore, ProductIDs: []string{id}}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(id, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductRestore, ProductIDs: []string{id}, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductCreated, ProductID: product.ID, Product: product})
+	return product, nil
+}
@@ -0,0 +1,162 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// eventHistorySize is the number of recent events kept so that reconnecting
+// subscribers can catch up on what they missed
+const eventHistorySize = 100
+
+// subscriberBufferSize is the number of events a subscriber can fall behind
+// by before it is disconnected
+const subscriberBufferSize = 64
+
+// EventBroker fans catalog change events out to subscribers
+type EventBroker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []model.CatalogEvent
+	subscribers map[*Subscription]struct{}
+	sinks       []EventSink
+	closed      bool
+}
+
+// EventSink receives every event published, such as to forward it to a
+// message broker. Events are sent in the order they are published, so
+// Publish must not block.
+type EventSink interface {
+	Publish(event model.CatalogEvent)
+}
+
+// Subscription receives events published after it was created. Its channel is
+// closed if the subscriber falls too far behind or the broker is closed.
+type Subscription struct {
+	Events <-chan model.CatalogEvent
+
+	events chan model.CatalogEvent
+	broker *EventBroker
+}
+
+// NewEventBroker constructor
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// AddSink sends the events published from now on to the sink as well as
+// the subscribers
+func (b *EventBroker) AddSink(sink EventSink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sinks = append(b.sinks, sink)
+}
+
+// Publish assigns the event an ID and delivers it to all subscribers and
+// sinks without blocking
+func (b *EventBroker) Publish(event model.CatalogEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistorySize {
+		b.history = b.history[len(b.history)-eventHistorySize:]
+	}
+
+	for _, sink := range b.sinks {
+		sink.Publish(event)
+	}
+
+	for subscription := range b.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			slog.Warn("Disconnecting slow catalog event subscriber")
+			b.unsubscribe(subscription)
+		}
+	}
+}
+
+// Subscribe returns a subscription to new events. When lastEventID is set,
+// retained events published after it are delivered first.
+func (b *EventBroker) Subscribe(lastEventID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []model.CatalogEvent
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	events := make(chan model.CatalogEvent, subscriberBufferSize+len(backlog))
+	for _, event := range backlog {
+		events <- event
+	}
+
+	subscription := &Subscription{Events: events, events: events, broker: b}
+	if b.closed {
+		close(events)
+	} else {
+		b.subscribers[subscription] = struct{}{}
+	}
+
+	return subscription
+}
+
+// Close ends all subscriptions, for example when the server is shutting down
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscription := range b.subscribers {
+		b.unsubscribe(subscription)
+	}
+}
+
+func (b *EventBroker) unsubscribe(subscription *Subscription) {
+	if _, ok := b.subscribers[subscription]; ok {
+		delete(b.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+// Close stops the subscription receiving events
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.unsubscribe(s)
+}
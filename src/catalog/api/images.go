@@ -0,0 +1,102 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/images"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/google/uuid"
+)
+
+// SetImageStore sets where product images are uploaded to and the URLs they
+// are given in responses. The bundled images are served from the binary
+// until it is set.
+func (a *CatalogAPI) SetImageStore(store images.Store) {
+	a.images = store
+}
+
+// Images returns the store product images are kept in
+func (a *CatalogAPI) Images() images.Store {
+	return a.images
+}
+
+// UploadImage stores an image of a product and adds it after the product's
+// other images, with the alternative text. The image is stored first, so
+// callers check that the product exists before uploading.
+func (a *CatalogAPI) UploadImage(id, alt, contentType string, body io.ReadSeeker, ctx context.Context) (*model.Product, error) {
+	patcher, ok := a.repository.(repository.ProductPatcher)
+	if !ok {
+		return nil, ErrReadOnly
+	}
+
+	key := fmt.Sprintf("products/%s/%s%s", url.PathEscape(id), uuid.NewString(), images.ContentTypes[contentType])
+	if err := a.images.Put(key, contentType, body, ctx); err != nil {
+		return nil, err
+	}
+
+	var before *model.Product
+	product, err := patcher.PatchProduct(id, func(product *model.Product) error {
+		if a.auditLog != nil {
+			snapshot := *product
+			before = &snapshot
+		}
+
+		product.Images = append(append([]model.ProductImage{}, product.Images...), model.ImageRequest{Key: key, Alt: alt}.ToImage(id, len(product.Images)))
+		return nil
+	}, ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductImage, ProductIDs: []string{id}, Before: before}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(id, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductImage, ProductIDs: []string{id}, Before: before, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return product, nil
+}
+
+// WithImageURLs returns the products with the URLs of the images in the image
+// store filled in. The products are copied, so that cached ones are left as
+// they were. An image whose URL can't be made is left without one.
+func (a *CatalogAPI) WithImageURLs(products []model.Product, ctx context.Context) []model.Product {
+	resolved := make([]model.Product, len(products))
+	for i, product := range products {
+		if product.Images != nil {
+			productImages := make([]model.ProductImage, len(product.Images))
+			for j, image := range product.Images {
+				if image.Key != "" {
+					url, err := a.images.URL(image.Key)
+					if err != nil {
+						slog.WarnContext(ctx, "Failed to get the URL of a product image", "product", product.ID, "key", image.Key, "error", err)
+					}
+					image.URL = url
+				}
+				productImages[j] = image
+			}
+			product.Images = productImages
+		}
+		resolved[i] = product
+	}
+	return resolved
+}
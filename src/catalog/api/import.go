@@ -0,0 +1,344 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/generator"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/google/uuid"
+)
+
+// importBatchSize is the number of products saved between progress updates
+const importBatchSize = 100
+
+// importJobHistory is the number of finished jobs kept for status queries
+const importJobHistory = 10
+
+// importQueueSize is the number of jobs that can wait to be processed
+const importQueueSize = 32
+
+// importErrorLimit caps the rejected products listed on a job
+const importErrorLimit = 100
+
+var (
+	// ErrImportJobNotFound is returned for unknown import job IDs
+	ErrImportJobNotFound = errors.New("import job not found")
+	// ErrImportNotResumable is returned when resuming a job that hasn't failed
+	ErrImportNotResumable = errors.New("only failed import jobs can be resumed")
+	// ErrImportQueueFull is returned when too many imports are waiting
+	ErrImportQueueFull = errors.New("too many imports are queued")
+)
+
+// importJobs tracks bulk imports and the products uploaded for them. Jobs
+// are processed one at a time, in the order they were queued.
+type importJobs struct {
+	mu       sync.Mutex
+	jobs     map[string]*model.ImportJob
+	products map[string][]model.Product
+	order    []string
+	queue    chan string
+	worker   sync.Once
+}
+
+func newImportJobs() *importJobs {
+	return &importJobs{
+		jobs:     map[string]*model.ImportJob{},
+		products: map[string][]model.Product{},
+		queue:    make(chan string, importQueueSize),
+	}
+}
+
+func (j *importJobs) add(products []model.Product) model.ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job := &model.ImportJob{
+		ID:        uuid.NewString(),
+		Status:    model.ImportQueued,
+		Total:     len(products),
+		CreatedAt: time.Now().UTC(),
+	}
+	j.jobs[job.ID] = job
+	j.products[job.ID] = products
+	j.order = append(j.order, job.ID)
+
+	// Forget the oldest finished jobs. Failed jobs are kept for longer so
+	// that they can still be resumed.
+	for i := 0; i < len(j.order) && len(j.order) > importJobHistory; {
+		status := j.jobs[j.order[i]].Status
+		if status == model.ImportCompleted || (status == model.ImportFailed && len(j.order) > 2*importJobHistory) {
+			j.remove(j.order[i])
+			continue
+		}
+		i++
+	}
+
+	return *job
+}
+
+func (j *importJobs) remove(id string) {
+	delete(j.jobs, id)
+	delete(j.products, id)
+	for i, queued := range j.order {
+		if queued == id {
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// resume marks a failed job as queued again
+func (j *importJobs) resume(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrImportJobNotFound, id)
+	}
+	if job.Status != model.ImportFailed {
+		return fmt.Errorf("%w: job %s is %s", ErrImportNotResumable, id, job.Status)
+	}
+
+	job.Status = model.ImportQueued
+	job.Error = ""
+	job.FinishedAt = nil
+	return nil
+}
+
+func (j *importJobs) enqueue(id string) error {
+	select {
+	case j.queue <- id:
+		return nil
+	default:
+		return ErrImportQueueFull
+	}
+}
+
+func (j *importJobs) update(id string, fn func(job *model.ImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if job, ok := j.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (j *importJobs) get(id string) (model.ImportJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return model.ImportJob{}, fmt.Errorf("%w: %s", ErrImportJobNotFound, id)
+	}
+
+	snapshot := *job
+	snapshot.Errors = append([]model.ImportError(nil), job.Errors...)
+	return snapshot, nil
+}
+
+// StartImport saves the products to the catalog in the background, creating
+// those that don't exist and replacing those that do, and returns the job
+// that reports its progress
+func (a *CatalogAPI) StartImport(products []model.Product, ctx context.Context) (model.ImportJob, error) {
+	if _, ok := a.repository.(repository.CatalogWriter); !ok {
+		return model.ImportJob{}, ErrReadOnly
+	}
+	if err := a.background.add(); err != nil {
+		return model.ImportJob{}, err
+	}
+
+	a.importJobs.worker.Do(func() { go a.processImports() })
+
+	job := a.importJobs.add(products)
+	if err := a.importJobs.enqueue(job.ID); err != nil {
+		a.background.done()
+		a.importJobs.mu.Lock()
+		a.importJobs.remove(job.ID)
+		a.importJobs.mu.Unlock()
+		return model.ImportJob{}, err
+	}
+
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	a.audit(model.AuditEntry{Action: model.AuditCatalogImport, JobID: job.ID, ProductIDs: ids}, nil, ctx)
+
+	return job, nil
+}
+
+// GenerateProducts imports count synthetic products made up from the seed,
+// in the catalog's categories and tags
+func (a *CatalogAPI) GenerateProducts(count int, seed uint64, ctx context.Context) (model.ImportJob, error) {
+	tags, err := a.GetTags(ctx)
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+	categories, err := a.getCategories(ctx)
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+
+	return a.StartImport(generator.Products(count, seed, tags, categories), ctx)
+}
+
+// ResumeImport queues a failed import again, continuing after the last
+// product it processed
+func (a *CatalogAPI) ResumeImport(id string, ctx context.Context) (model.ImportJob, error) {
+	if err := a.background.add(); err != nil {
+		return model.ImportJob{}, err
+	}
+	if err := a.importJobs.resume(id); err != nil {
+		a.background.done()
+		return model.ImportJob{}, err
+	}
+
+	a.importJobs.worker.Do(func() { go a.processImports() })
+	if err := a.importJobs.enqueue(id); err != nil {
+		a.background.done()
+		a.importJobs.update(id, func(job *model.ImportJob) { job.Status = model.ImportFailed })
+		return model.ImportJob{}, err
+	}
+
+	a.audit(model.AuditEntry{Action: model.AuditImportResume, JobID: id}, nil, ctx)
+	return a.importJobs.get(id)
+}
+
+// GetImportJob returns the progress of an import job
+func (a *CatalogAPI) GetImportJob(id string) (model.ImportJob, error) {
+	return a.importJobs.get(id)
+}
+
+func (a *CatalogAPI) processImports() {
+	for id := range a.importJobs.queue {
+		now := time.Now().UTC()
+		a.importJobs.update(id, func(job *model.ImportJob) {
+			job.Status = model.ImportRunning
+			if job.StartedAt == nil {
+				job.StartedAt = &now
+			}
+		})
+
+		err := a.runImport(id, a.background.ctx)
+
+		finished := time.Now().UTC()
+		a.importJobs.update(id, func(job *model.ImportJob) {
+			job.FinishedAt = &finished
+			job.Status = model.ImportCompleted
+			if err != nil {
+				job.Status = model.ImportFailed
+				job.Error = err.Error()
+			}
+		})
+
+		if err != nil {
+			slog.Error("Import job failed", "job", id, "error", err)
+		} else {
+			a.events.Publish(model.CatalogEvent{Type: model.EventImportCompleted})
+		}
+		a.background.done()
+	}
+}
+
+func (a *CatalogAPI) runImport(id string, ctx context.Context) error {
+	writer, ok := a.repository.(repository.CatalogWriter)
+	if !ok {
+		return ErrReadOnly
+	}
+
+	a.importJobs.mu.Lock()
+	products := a.importJobs.products[id]
+	start := a.importJobs.jobs[id].Processed
+	a.importJobs.mu.Unlock()
+
+	for batchStart := start; batchStart < len(products); batchStart += importBatchSize {
+		batchEnd := min(batchStart+importBatchSize, len(products))
+
+		var created, updated int
+		var rejected []model.ImportError
+		processed := batchStart
+		var err error
+		for i := batchStart; i < batchEnd; i++ {
+			var isNew bool
+			isNew, err = importProduct(writer, products[i], ctx)
+			if isImportRejection(err) {
+				rejected = append(rejected, model.ImportError{Index: i, ProductID: products[i].ID, Error: err.Error()})
+				err = nil
+			} else if err != nil {
+				break
+			} else if isNew {
+				created++
+			} else {
+				updated++
+			}
+			processed = i + 1
+		}
+
+		if processed > batchStart {
+			a.cache.catalogChanged(ctx)
+		}
+
+		// Progress is recorded up to the last product handled, so that a
+		// resumed job neither skips nor repeats products
+		a.importJobs.update(id, func(job *model.ImportJob) {
+			job.Processed = processed
+			job.Created += created
+			job.Updated += updated
+			job.Failed += len(rejected)
+			for _, rejection := range rejected {
+				if len(job.Errors) < importErrorLimit {
+					job.Errors = append(job.Errors, rejection)
+				}
+			}
+		})
+
+		if err != nil {
+			return fmt.Errorf("saving product %s: %w", products[processed].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// importProduct replaces the product if it exists and creates it otherwise,
+// reporting whether it was created
+func importProduct(writer repository.CatalogWriter, product model.Product, ctx context.Context) (bool, error) {
+	err := writer.UpdateProduct(&product, ctx)
+	if !errors.Is(err, repository.ErrProductNotFound) {
+		return false, err
+	}
+
+	return true, writer.CreateProduct(&product, ctx)
+}
+
+// isImportRejection reports whether an error is a problem with the product
+// rather than with saving it, so the import can carry on without it
+func isImportRejection(err error) bool {
+	return errors.Is(err, repository.ErrUnknownTag) ||
+		errors.Is(err, repository.ErrUnknownCategory) ||
+		errors.Is(err, repository.ErrProductExists)
+}
@@ -0,0 +1,89 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIndexUpdatesNotSupported is returned when the search provider can't
+	// index or remove products one at a time
+	ErrIndexUpdatesNotSupported = errors.New("the search provider does not support updating single products")
+	// ErrInvalidIndexMessage is returned for index messages that can never be
+	// applied, such as those of an unknown type
+	ErrInvalidIndexMessage = errors.New("invalid index message")
+)
+
+// ApplyIndexMessage updates the search index entries of the products named
+// by the message, or starts a reindex when the message asks for one
+func (a *CatalogAPI) ApplyIndexMessage(message model.IndexMessage, ctx context.Context) error {
+	switch message.Type {
+	case model.IndexReindexRequested:
+		_, err := a.StartReindex(ctx)
+		if errors.Is(err, ErrReindexInProgress) {
+			// Not retried, since requests sent while a reindex runs would
+			// otherwise queue up a reindex each
+			return nil
+		}
+		return err
+	case model.IndexProductChanged, model.EventProductCreated, model.EventProductUpdated, model.EventProductDeleted:
+		ids := message.IDs()
+		if len(ids) == 0 {
+			return fmt.Errorf("%w: %s message without product IDs", ErrInvalidIndexMessage, message.Type)
+		}
+		return a.UpdateIndex(ids, ctx)
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidIndexMessage, message.Type)
+	}
+}
+
+// UpdateIndex indexes the current version of the products with the IDs, and
+// removes those that are missing or deleted from the catalog from the index
+func (a *CatalogAPI) UpdateIndex(ids []string, ctx context.Context) error {
+	indexer, ok := a.searchRepository.(repository.SearchIndexer)
+	if !ok {
+		return ErrIndexUpdatesNotSupported
+	}
+
+	errs := []error{}
+	for _, id := range ids {
+		// Read from the repository, since the cache may still hold the
+		// version from before the change
+		product, err := a.repository.GetProduct(id, ctx)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = indexer.RemoveProduct(id, ctx)
+		case err == nil:
+			err = indexer.IndexProduct(*product, ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to update %s in the search index: %w", id, err))
+			continue
+		}
+
+		a.cache.productChanged(id, ctx)
+	}
+
+	return errors.Join(errs...)
+}
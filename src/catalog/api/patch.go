@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrInvalidPatch is returned for a merge patch that isn't a JSON object or
+// that would leave the product invalid
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// patchableFields are the members of a product merge patch
+var patchableFields = map[string]bool{
+	"id":           true,
+	"name":         true,
+	"description":  true,
+	"price":        true,
+	"tags":         true,
+	"category":     true,
+	"variants":     true,
+	"stock":        true,
+	"images":       true,
+	"attributes":   true,
+	"translations": true,
+	"status":       true,
+	"sku":          true,
+	"ean":          true,
+}
+
+// PatchProduct applies a JSON merge patch (RFC 7386) to a product. The patch
+// is applied to the stored product in one step, so concurrent changes to
+// other fields are not lost, and validate checks the result before it is
+// saved. If version is set the patch is only applied to that version.
+func (a *CatalogAPI) PatchProduct(id string, version int, patch []byte, validate func(*model.ProductRequest) error, ctx context.Context) (*model.Product, error) {
+	patcher, ok := a.repository.(repository.ProductPatcher)
+	if !ok {
+		return nil, ErrReadOnly
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("%w: the patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	for name, value := range members {
+		if !patchableFields[name] {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidPatch, name)
+		}
+		if name == "id" && value != id {
+			return nil, fmt.Errorf("%w: the product ID can't be changed", ErrInvalidPatch)
+		}
+	}
+
+	var before *model.Product
+	product, err := patcher.PatchProduct(id, func(product *model.Product) error {
+		if a.auditLog != nil {
+			snapshot := *product
+			before = &snapshot
+		}
+
+		if version != 0 && product.Version != version {
+			return fmt.Errorf("%w: %s is no longer at version %d", repository.ErrVersionConflict, id, version)
+		}
+
+		current, err := json.Marshal(product.ToRequest())
+		if err != nil {
+			return err
+		}
+
+		var target interface{}
+		if err := json.Unmarshal(current, &target); err != nil {
+			return err
+		}
+
+		patched, err := json.Marshal(mergePatch(target, members))
+		if err != nil {
+			return err
+		}
+
+		var request model.ProductRequest
+		if err := json.Unmarshal(patched, &request); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if err := validate(&request); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		*product = *request.ToProduct(id)
+		return nil
+	}, ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductPatch, ProductIDs: []string{id}, Before: before}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(id, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductPatch, ProductIDs: []string{id}, Before: before, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return product, nil
+}
+
+// mergePatch applies a JSON merge patch to a decoded JSON document: members
+// of an object patch replace those of the target, recursively for objects,
+// and null members remove them
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+
+	return targetObject
+}
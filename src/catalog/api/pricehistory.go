@@ -0,0 +1,50 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrPriceHistoryNotSupported is returned when the persistence provider
+// doesn't record price changes
+var ErrPriceHistoryNotSupported = errors.New("the persistence provider does not record price history")
+
+// GetPriceHistory returns the current price of a product with up to limit of
+// the changes to it, newest first
+func (a *CatalogAPI) GetPriceHistory(product *model.Product, limit int, ctx context.Context) (*model.PriceHistoryResponse, error) {
+	history, ok := a.repository.(repository.PriceHistoryRepository)
+	if !ok {
+		return nil, ErrPriceHistoryNotSupported
+	}
+
+	// The previous price is the second change, however few are returned
+	changes, err := history.GetPriceHistory(product.ID, max(limit, 2), ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.PriceHistoryResponse{ProductID: product.ID, Price: product.Price, Changes: changes[:min(limit, len(changes))]}
+	if len(changes) > 1 {
+		response.PreviousPrice = &changes[1].Price
+	}
+	return response, nil
+}
@@ -0,0 +1,147 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrPromotionsNotSupported is returned when the persistence provider
+// doesn't store promotions
+var ErrPromotionsNotSupported = errors.New("the persistence provider does not store promotions")
+
+// GetPromotions returns every promotion, whether running or not
+func (a *CatalogAPI) GetPromotions(ctx context.Context) ([]model.Promotion, error) {
+	promotions, ok := a.repository.(repository.PromotionRepository)
+	if !ok {
+		return nil, ErrPromotionsNotSupported
+	}
+
+	return reference(a.cache, "promotions", func() ([]model.Promotion, error) {
+		return promotions.GetPromotions(ctx)
+	}, ctx)
+}
+
+// CreatePromotion stores a promotion, which applies to products read from
+// when it starts
+func (a *CatalogAPI) CreatePromotion(promotion *model.Promotion, ctx context.Context) error {
+	promotions, ok := a.repository.(repository.PromotionRepository)
+	if !ok {
+		return ErrPromotionsNotSupported
+	}
+
+	if err := promotions.CreatePromotion(promotion, ctx); err != nil {
+		return err
+	}
+	// Cached searches for products on sale are dropped with the promotions
+	a.cache.catalogChanged(ctx)
+	return nil
+}
+
+// DeletePromotion ends a promotion by removing it
+func (a *CatalogAPI) DeletePromotion(id string, ctx context.Context) error {
+	promotions, ok := a.repository.(repository.PromotionRepository)
+	if !ok {
+		return ErrPromotionsNotSupported
+	}
+
+	if err := promotions.DeletePromotion(id, ctx); err != nil {
+		return err
+	}
+	a.cache.catalogChanged(ctx)
+	return nil
+}
+
+// runningPromotions returns the promotions running now, or none if the
+// persistence provider doesn't store them
+func (a *CatalogAPI) runningPromotions(ctx context.Context) ([]model.Promotion, error) {
+	promotions, err := a.GetPromotions(ctx)
+	if errors.Is(err, ErrPromotionsNotSupported) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	running := []model.Promotion{}
+	for _, promotion := range promotions {
+		if promotion.Active(now) {
+			running = append(running, promotion)
+		}
+	}
+	return running, nil
+}
+
+// WithPromotions returns the products with the sale price of the running
+// promotion that takes the most off, for those that any promotion makes
+// cheaper. The products are copied, since they may be shared with the cache,
+// and returned unchanged if the promotions can't be read.
+func (a *CatalogAPI) WithPromotions(products []model.Product, ctx context.Context) []model.Product {
+	promotions, err := a.runningPromotions(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read promotions for sale prices", "error", err)
+		return products
+	}
+	if len(promotions) == 0 {
+		return products
+	}
+
+	discounted := make([]model.Product, len(products))
+	for i, product := range products {
+		for _, promotion := range promotions {
+			if !promotion.AppliesTo(product) {
+				continue
+			}
+			price := promotion.Discount(product.Price)
+			if price.Amount < product.Price.Amount && (product.SalePrice == nil || price.Amount < product.SalePrice.Amount) {
+				product.SalePrice = &price
+				product.OnSale = true
+			}
+		}
+		discounted[i] = product
+	}
+	return discounted
+}
+
+// Sale returns the products that running promotions apply to, for searches
+// of products on sale. Searches filter on it without the products' prices,
+// so it includes products that WithPromotions doesn't mark OnSale because no
+// promotion makes them cheaper, such as free products or those with a lower
+// sale price already.
+func (a *CatalogAPI) Sale(ctx context.Context) (repository.Sale, error) {
+	promotions, err := a.runningPromotions(ctx)
+	if err != nil {
+		return repository.Sale{}, err
+	}
+
+	sale := repository.Sale{}
+	for _, promotion := range promotions {
+		sale.ProductIDs = append(sale.ProductIDs, promotion.ProductIDs...)
+		sale.Tags = append(sale.Tags, promotion.Tags...)
+	}
+	slices.Sort(sale.ProductIDs)
+	slices.Sort(sale.Tags)
+	sale.ProductIDs, sale.Tags = slices.Compact(sale.ProductIDs), slices.Compact(sale.Tags)
+	return sale, nil
+}
@@ -0,0 +1,246 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/google/uuid"
+)
+
+// Default number of products read from the catalog and sent to the search
+// index at a time, and of batches sent at once
+const (
+	defaultReindexBatchSize = 100
+	defaultReindexWorkers   = 4
+)
+
+// reindexJobHistory is the number of finished jobs kept for progress queries
+const reindexJobHistory = 10
+
+var (
+	// ErrReindexInProgress is returned when a reindex is requested while another is running
+	ErrReindexInProgress = errors.New("a reindex is already in progress")
+	// ErrReindexJobNotFound is returned for unknown reindex job IDs
+	ErrReindexJobNotFound = errors.New("reindex job not found")
+)
+
+// reindexJobs tracks background reindex jobs. Only one runs at a time.
+type reindexJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*model.ReindexJob
+	order   []string
+	running string
+
+	workers   int
+	batchSize int
+}
+
+func newReindexJobs() *reindexJobs {
+	return &reindexJobs{
+		jobs:      map[string]*model.ReindexJob{},
+		workers:   defaultReindexWorkers,
+		batchSize: defaultReindexBatchSize,
+	}
+}
+
+// SetReindexOptions sets how many batches of products background reindex
+// jobs send to the search index at once, and how many products are in each
+func (a *CatalogAPI) SetReindexOptions(workers, batchSize int) {
+	a.reindexJobs.mu.Lock()
+	defer a.reindexJobs.mu.Unlock()
+
+	if workers > 0 {
+		a.reindexJobs.workers = workers
+	}
+	if batchSize > 0 {
+		a.reindexJobs.batchSize = batchSize
+	}
+}
+
+// options returns the number of workers and the batch size for a job
+func (j *reindexJobs) options() (workers, batchSize int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.workers, j.batchSize
+}
+
+// start registers a new running job, unless one is already running
+func (j *reindexJobs) start() (model.ReindexJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running != "" {
+		return j.snapshot(j.running), fmt.Errorf("%w: job %s", ErrReindexInProgress, j.running)
+	}
+
+	job := &model.ReindexJob{
+		ID:        uuid.NewString(),
+		Status:    model.ReindexRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	j.jobs[job.ID] = job
+	j.running = job.ID
+
+	j.order = append(j.order, job.ID)
+	if len(j.order) > reindexJobHistory {
+		delete(j.jobs, j.order[0])
+		j.order = j.order[1:]
+	}
+
+	return *job, nil
+}
+
+func (j *reindexJobs) isRunning() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running != ""
+}
+
+func (j *reindexJobs) update(id string, fn func(job *model.ReindexJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if job, ok := j.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (j *reindexJobs) finish(id string, err error) {
+	j.update(id, func(job *model.ReindexJob) {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		job.Status = model.ReindexCompleted
+		if err != nil {
+			job.Status = model.ReindexFailed
+			job.Error = err.Error()
+		}
+	})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running == id {
+		j.running = ""
+	}
+}
+
+func (j *reindexJobs) get(id string) (model.ReindexJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.jobs[id]; !ok {
+		return model.ReindexJob{}, fmt.Errorf("%w: %s", ErrReindexJobNotFound, id)
+	}
+	return j.snapshot(id), nil
+}
+
+// snapshot copies a job, estimating the time it has left
+func (j *reindexJobs) snapshot(id string) model.ReindexJob {
+	job := *j.jobs[id]
+
+	done := job.Indexed + job.Failed
+	if job.Status == model.ReindexRunning && done > 0 && job.Total >= done {
+		elapsed := time.Since(job.StartedAt)
+		eta := int(math.Ceil(elapsed.Seconds() * float64(job.Total-done) / float64(done)))
+		job.ETASeconds = &eta
+	}
+
+	return job
+}
+
+// StartReindex rebuilds the search index from the catalog in the background,
+// returning the job that reports its progress
+func (a *CatalogAPI) StartReindex(ctx context.Context) (model.ReindexJob, error) {
+	if a.searchRepository == nil {
+		return model.ReindexJob{}, fmt.Errorf("search is not enabled")
+	}
+
+	if err := a.background.add(); err != nil {
+		return model.ReindexJob{}, err
+	}
+
+	job, err := a.reindexJobs.start()
+	if err != nil {
+		a.background.done()
+		return job, err
+	}
+
+	a.audit(model.AuditEntry{Action: model.AuditCatalogReindex, JobID: job.ID}, nil, ctx)
+
+	go func() {
+		defer a.background.done()
+
+		err := a.runReindex(job.ID, a.background.ctx)
+		a.cache.catalogChanged(a.background.ctx)
+		if err != nil {
+			slog.Error("Reindex job failed", "job", job.ID, "error", err)
+		} else {
+			a.events.Publish(model.CatalogEvent{Type: model.EventReindexCompleted})
+		}
+		a.reindexJobs.finish(job.ID, err)
+	}()
+
+	return job, nil
+}
+
+// GetReindexJob returns the progress of a reindex job
+func (a *CatalogAPI) GetReindexJob(id string) (model.ReindexJob, error) {
+	return a.reindexJobs.get(id)
+}
+
+func (a *CatalogAPI) runReindex(id string, ctx context.Context) error {
+	total, err := a.repository.CountProducts(repository.ProductFilter{}, ctx)
+	if err != nil {
+		return err
+	}
+	a.reindexJobs.update(id, func(job *model.ReindexJob) { job.Total = total })
+
+	indexer, ok := a.searchRepository.(repository.BulkIndexer)
+	if !ok {
+		// Providers that can't be given batches rebuild themselves in one step
+		if err := a.searchRepository.Reindex(ctx); err != nil {
+			return err
+		}
+		a.reindexJobs.update(id, func(job *model.ReindexJob) { job.Indexed = total })
+		return nil
+	}
+
+	if err := indexer.ResetIndex(ctx); err != nil {
+		return err
+	}
+
+	// Reading the next batch from the database overlaps with indexing the
+	// previous ones
+	workers, batchSize := a.reindexJobs.options()
+	return repository.IndexBatches(indexer, workers, func(send func([]model.Product) error) error {
+		return a.ExportProducts(repository.ProductFilter{}, batchSize, ctx, send)
+	}, func(indexed, failed int) {
+		a.reindexJobs.update(id, func(job *model.ReindexJob) {
+			job.Indexed += indexed
+			job.Failed += failed
+		})
+	}, ctx)
+}
@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrRelationsNotSupported is returned when the persistence provider doesn't
+// store relations between products
+var ErrRelationsNotSupported = errors.New("the persistence provider does not store product relations")
+
+// SetRelations replaces the relations of a product to others
+func (a *CatalogAPI) SetRelations(productID string, request model.RelationsRequest, ctx context.Context) (*model.Product, error) {
+	relations, ok := a.repository.(repository.RelationRepository)
+	if !ok {
+		return nil, ErrRelationsNotSupported
+	}
+
+	before := a.auditSnapshot(productID, ctx)
+
+	product, err := relations.SetRelations(productID, request.ToRelations(productID), ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductRelated, ProductIDs: []string{productID}, Before: before}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(productID, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductRelated, ProductIDs: []string{productID}, Before: before, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return product, nil
+}
+
+// GetRelatedProducts returns the products related to a product, of the
+// given type or of any if it's empty, in the order of its relations.
+// Related products that no longer exist are skipped.
+func (a *CatalogAPI) GetRelatedProducts(product *model.Product, relationType string, ctx context.Context) ([]model.RelatedProduct, error) {
+	ids := []string{}
+	for _, relation := range product.Related {
+		if relationType == "" || relation.Type == relationType {
+			ids = append(ids, relation.RelatedID)
+		}
+	}
+
+	products, err := a.GetProductsByIDs(ids, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]model.Product, len(products))
+	for _, related := range products {
+		byID[related.ID] = related
+	}
+
+	related := []model.RelatedProduct{}
+	for _, relation := range product.Related {
+		if relationType != "" && relation.Type != relationType {
+			continue
+		}
+		if found, ok := byID[relation.RelatedID]; ok {
+			related = append(related, model.RelatedProduct{Type: relation.Type, Product: found})
+		}
+	}
+	return related, nil
+}
@@ -0,0 +1,58 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrReviewsNotSupported is returned when the persistence provider doesn't
+// store reviews
+var ErrReviewsNotSupported = errors.New("the persistence provider does not store reviews")
+
+// AddReview stores a review of a product, whose rating is updated to
+// include it
+func (a *CatalogAPI) AddReview(productID string, request model.ReviewRequest, ctx context.Context) (*model.ProductReview, error) {
+	reviews, ok := a.repository.(repository.ReviewRepository)
+	if !ok {
+		return nil, ErrReviewsNotSupported
+	}
+
+	review := request.ToReview(productID)
+	product, err := reviews.AddReview(&review, ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.cache.productChanged(productID, ctx)
+
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return &review, nil
+}
+
+// GetReviews returns a page of the reviews of a product, newest first
+func (a *CatalogAPI) GetReviews(productID string, pageNum, pageSize int, ctx context.Context) ([]model.ProductReview, error) {
+	reviews, ok := a.repository.(repository.ReviewRepository)
+	if !ok {
+		return nil, ErrReviewsNotSupported
+	}
+
+	return reviews.GetReviews(productID, pageNum, pageSize, ctx)
+}
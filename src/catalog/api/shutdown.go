@@ -0,0 +1,85 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is returned when an import or reindex is started once the
+// service has begun shutting down
+var ErrShuttingDown = errors.New("the service is shutting down")
+
+// backgroundJobs tracks the imports and reindexes queued or running in the
+// background, so that shutting down can wait for them to finish
+type backgroundJobs struct {
+	mu      sync.Mutex
+	closed  bool
+	pending sync.WaitGroup
+
+	// ctx is cancelled when shutting down can't wait any longer
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newBackgroundJobs() *backgroundJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundJobs{ctx: ctx, cancel: cancel}
+}
+
+// add counts a job that is about to be queued or started, failing once
+// shutdown has begun. Each successful call is matched by a call to done.
+func (b *backgroundJobs) add() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrShuttingDown
+	}
+	b.pending.Add(1)
+	return nil
+}
+
+func (b *backgroundJobs) done() {
+	b.pending.Done()
+}
+
+// Shutdown stops imports and reindexes from being started and waits for
+// those queued or running to finish. If ctx ends first they are cancelled,
+// leaving imports failed so that they can be resumed after a restart of a
+// persistent store, and its error is returned.
+func (a *CatalogAPI) Shutdown(ctx context.Context) error {
+	a.background.mu.Lock()
+	a.background.closed = true
+	a.background.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		a.background.pending.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		a.background.cancel()
+		return ctx.Err()
+	}
+}
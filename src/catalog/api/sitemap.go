@@ -0,0 +1,154 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrSitemapNotFound is returned for a sitemap page past the end of the catalog
+var ErrSitemapNotFound = errors.New("sitemap not found")
+
+// maxSitemapPageSize is the most URLs the sitemaps.org protocol allows in
+// one sitemap
+const maxSitemapPageSize = 50000
+
+// sitemap holds the product IDs listed in the sitemaps. They are read from
+// the repository again when the change log shows the catalog has changed,
+// or on every request if the repository doesn't keep one.
+type sitemap struct {
+	mu        sync.Mutex
+	baseURL   string
+	pageSize  int
+	ids       []string
+	sequence  uint64
+	generated time.Time
+	built     bool
+}
+
+func newSitemap() *sitemap {
+	return &sitemap{
+		baseURL:  "http://localhost:8888",
+		pageSize: 1000,
+	}
+}
+
+// SetSitemapOptions sets the URL of the store the product pages in the
+// sitemaps belong to, and how many are listed in each sitemap
+func (a *CatalogAPI) SetSitemapOptions(baseURL string, pageSize int) {
+	a.sitemap.mu.Lock()
+	defer a.sitemap.mu.Unlock()
+
+	if baseURL != "" {
+		a.sitemap.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	if pageSize > 0 {
+		a.sitemap.pageSize = min(pageSize, maxSitemapPageSize)
+	}
+}
+
+// SitemapIndex returns the index of the product sitemaps
+func (a *CatalogAPI) SitemapIndex(ctx context.Context) (*model.SitemapIndex, error) {
+	ids, generated, err := a.sitemapProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := max(1, (len(ids)+a.sitemap.pageSize-1)/a.sitemap.pageSize)
+
+	index := &model.SitemapIndex{}
+	for page := 1; page <= pages; page++ {
+		index.Sitemaps = append(index.Sitemaps, model.SitemapEntry{
+			Loc:     fmt.Sprintf("%s/sitemap/products-%d.xml", a.sitemap.baseURL, page),
+			LastMod: generated.Format(time.RFC3339),
+		})
+	}
+
+	return index, nil
+}
+
+// SitemapPage returns one page of the product sitemaps, starting from 1
+func (a *CatalogAPI) SitemapPage(page int, ctx context.Context) (*model.URLSet, error) {
+	ids, _, err := a.sitemapProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := (page - 1) * a.sitemap.pageSize
+	if page < 1 || (start >= len(ids) && page > 1) {
+		return nil, ErrSitemapNotFound
+	}
+	end := min(start+a.sitemap.pageSize, len(ids))
+
+	urls := &model.URLSet{URLs: []model.SitemapURL{}}
+	for _, id := range ids[start:end] {
+		urls.URLs = append(urls.URLs, model.SitemapURL{
+			Loc: a.sitemap.baseURL + "/catalog/" + url.PathEscape(id),
+		})
+	}
+
+	return urls, nil
+}
+
+// sitemapProducts returns the IDs of every product and when the list was
+// read, reading it again first if the catalog has changed since
+func (a *CatalogAPI) sitemapProducts(ctx context.Context) ([]string, time.Time, error) {
+	a.sitemap.mu.Lock()
+	defer a.sitemap.mu.Unlock()
+
+	// The sequence is read before the products, so a change made while they
+	// are being read is picked up by the next request
+	changeLog, tracked := a.repository.(repository.ChangeLog)
+	var sequence uint64
+	if tracked {
+		var err error
+		if sequence, err = changeLog.LatestChangeSequence(ctx); err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if a.sitemap.built && sequence == a.sitemap.sequence {
+			return a.sitemap.ids, a.sitemap.generated, nil
+		}
+	}
+
+	ids := []string{}
+	err := a.ExportProducts(repository.ProductFilter{Statuses: []string{model.StatusActive}}, 500, ctx, func(products []model.Product) error {
+		for _, product := range products {
+			ids = append(ids, product.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	a.sitemap.ids = ids
+	a.sitemap.sequence = sequence
+	a.sitemap.generated = time.Now().UTC()
+	a.sitemap.built = true
+
+	return ids, a.sitemap.generated, nil
+}
@@ -0,0 +1,102 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// ErrInvalidAdjustment is returned for a stock adjustment that doesn't apply
+// to the product, such as one without a SKU for a product with variants
+var ErrInvalidAdjustment = errors.New("invalid stock adjustment")
+
+// AdjustStock changes the stock of a product, or of one of its variants if
+// the adjustment names a SKU, by a number of units or to a new number. It is
+// applied to the stored product in one step, like a patch, so that
+// concurrent adjustments are not lost, and fails with
+// repository.ErrInsufficientStock rather than leave fewer than no units.
+func (a *CatalogAPI) AdjustStock(id string, adjustment model.StockAdjustment, ctx context.Context) (*model.Product, error) {
+	patcher, ok := a.repository.(repository.ProductPatcher)
+	if !ok {
+		return nil, ErrReadOnly
+	}
+
+	var before *model.Product
+	product, err := patcher.PatchProduct(id, func(product *model.Product) error {
+		if a.auditLog != nil {
+			snapshot := *product
+			before = &snapshot
+		}
+
+		if adjustment.SKU == "" {
+			if len(product.Variants) > 0 {
+				return fmt.Errorf("%w: %s has variants, whose stock is adjusted by SKU", ErrInvalidAdjustment, id)
+			}
+
+			stock, err := adjusted(product.Stock, adjustment)
+			if err != nil {
+				return err
+			}
+			product.Stock = &stock
+			return nil
+		}
+
+		i := slices.IndexFunc(product.Variants, func(variant model.Variant) bool { return variant.SKU == adjustment.SKU })
+		if i < 0 {
+			return fmt.Errorf("%w: %s is not a variant of %s", repository.ErrVariantNotFound, adjustment.SKU, id)
+		}
+
+		stock, err := adjusted(&product.Variants[i].Stock, adjustment)
+		if err != nil {
+			return err
+		}
+		product.Variants = slices.Clone(product.Variants)
+		product.Variants[i].Stock = stock
+		return nil
+	}, ctx)
+	if err != nil {
+		a.audit(model.AuditEntry{Action: model.AuditProductStock, ProductIDs: []string{id}, Before: before}, err, ctx)
+		return nil, err
+	}
+	a.cache.productChanged(id, ctx)
+
+	a.audit(model.AuditEntry{Action: model.AuditProductStock, ProductIDs: []string{id}, Before: before, After: product}, nil, ctx)
+	a.events.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: product.ID, Product: product})
+	return product, nil
+}
+
+// adjusted returns the stock after an adjustment
+func adjusted(stock *int, adjustment model.StockAdjustment) (int, error) {
+	if adjustment.Stock != nil {
+		return *adjustment.Stock, nil
+	}
+
+	current := 0
+	if stock != nil {
+		current = *stock
+	}
+	if current+*adjustment.Delta < 0 {
+		return 0, fmt.Errorf("%w: %d units available, adjusted by %d", repository.ErrInsufficientStock, current, *adjustment.Delta)
+	}
+	return current + *adjustment.Delta, nil
+}
@@ -0,0 +1,141 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"golang.org/x/sync/errgroup"
+)
+
+// Warm-up requests mirror the first page a storefront shows, at the page
+// size the REST API defaults to
+const (
+	warmUpPageSize = 10
+	warmUpSearches = 5
+)
+
+// WarmUpResult counts the requests made while warming up
+type WarmUpResult struct {
+	Requests int
+	Failed   int
+	Took     time.Duration
+}
+
+// Err returns an error if every warm-up request failed, which means the
+// dependencies are unusable rather than slow to start
+func (r WarmUpResult) Err() error {
+	if r.Requests > 0 && r.Failed == r.Requests {
+		return errors.New("every warm-up request failed")
+	}
+	return nil
+}
+
+// WarmUp makes the requests a storefront makes first, with up to concurrency
+// at once, so that connections to the database and search backend are opened
+// and the caches are filled before the first real request rather than
+// during it. It lists the first page of products, fetches each of them, the
+// tags and categories, and searches for words from their names. Failures are
+// logged but don't stop the warm-up, which is best effort.
+func (a *CatalogAPI) WarmUp(concurrency int, ctx context.Context) WarmUpResult {
+	started := time.Now()
+
+	var requests, failed atomic.Int32
+	run := func(name string, request func() error) {
+		requests.Add(1)
+		if err := request(); err != nil {
+			failed.Add(1)
+			slog.WarnContext(ctx, "Warm-up request failed", "request", name, "error", err)
+		}
+	}
+
+	// The products are needed to know what to fetch and search for
+	var products []model.Product
+	run("products", func() error {
+		page, err := a.GetProductsPage(repository.ProductFilter{Statuses: []string{model.StatusActive}}, "", "", 1, warmUpPageSize, ctx)
+		if err == nil {
+			products = page.Products
+		}
+		return err
+	})
+
+	var group errgroup.Group
+	group.SetLimit(max(concurrency, 1))
+	do := func(name string, request func() error) {
+		group.Go(func() error {
+			run(name, request)
+			return nil
+		})
+	}
+
+	do("tags", func() error {
+		_, err := a.GetTags(ctx)
+		return err
+	})
+	do("categories", func() error {
+		_, err := a.GetCategoryTree(ctx)
+		return err
+	})
+	for _, product := range products {
+		do("product", func() error {
+			_, err := a.GetProduct(product.ID, ctx)
+			return err
+		})
+	}
+	if a.IsSearchEnabled() {
+		for _, keyword := range warmUpKeywords(products) {
+			do("search", func() error {
+				_, err := a.SearchProductsPage(repository.SearchQuery{Keyword: keyword}, "", 1, warmUpPageSize, ctx)
+				return err
+			})
+		}
+	}
+	group.Wait()
+
+	return WarmUpResult{Requests: int(requests.Load()), Failed: int(failed.Load()), Took: time.Since(started)}
+}
+
+// warmUpKeywords picks distinct keywords to search for from the tags and
+// names of the products, ending with the word of the name that usually says
+// what the product is
+func warmUpKeywords(products []model.Product) []string {
+	keywords := []string{}
+	seen := map[string]bool{}
+	add := func(keyword string) {
+		if keyword != "" && !seen[keyword] && len(keywords) < warmUpSearches {
+			seen[keyword] = true
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	for _, product := range products {
+		for _, tag := range product.Tags {
+			add(tag.Name)
+		}
+		if words := strings.Fields(strings.ToLower(product.Name)); len(words) > 0 {
+			add(words[len(words)-1])
+		}
+	}
+	return keywords
+}
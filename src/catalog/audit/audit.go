@@ -0,0 +1,89 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// Anonymous is the actor of operations by unauthenticated clients, which
+// are allowed when authentication is not enabled
+const Anonymous = "anonymous"
+
+// Log records administrative and write operations
+type Log interface {
+	Record(entry model.AuditEntry, ctx context.Context) error
+}
+
+type actorKey struct{}
+
+// WithActor returns a context for operations performed by the client
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the client performing operations with the context
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return Anonymous
+}
+
+// WriterLog writes entries as JSON lines, in the same shape as the service's
+// JSON logs so that a log collector can route them by their "audit" message
+type WriterLog struct {
+	handler slog.Handler
+}
+
+// NewWriterLog writes entries to w, which is usually a stream of their own
+// rather than the service logs
+func NewWriterLog(w io.Writer) *WriterLog {
+	return &WriterLog{handler: slog.NewJSONHandler(w, nil)}
+}
+
+// Record writes the entry. Entries are written whatever the service's log
+// level, since an audit log with gaps is of little use.
+func (l *WriterLog) Record(entry model.AuditEntry, ctx context.Context) error {
+	record := slog.NewRecord(entry.Time, slog.LevelInfo, "audit", 0)
+	record.AddAttrs(
+		slog.String("actor", entry.Actor),
+		slog.String("action", entry.Action),
+		slog.String("outcome", entry.Outcome),
+	)
+	if entry.Error != "" {
+		record.AddAttrs(slog.String("error", entry.Error))
+	}
+	if len(entry.ProductIDs) > 0 {
+		record.AddAttrs(slog.Any("productIds", entry.ProductIDs))
+	}
+	if entry.JobID != "" {
+		record.AddAttrs(slog.String("jobId", entry.JobID))
+	}
+	if entry.Before != nil {
+		record.AddAttrs(slog.Any("before", entry.Before))
+	}
+	if entry.After != nil {
+		record.AddAttrs(slog.Any("after", entry.After))
+	}
+
+	return l.handler.Handle(ctx, record)
+}
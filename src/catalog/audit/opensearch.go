@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// OpenSearchLog indexes entries into an index of their own, which is created
+// with dynamic mappings on the first entry
+type OpenSearchLog struct {
+	client *opensearch.Client
+	index  string
+}
+
+// NewOpenSearchLog records entries in the index
+func NewOpenSearchLog(client *opensearch.Client, index string) *OpenSearchLog {
+	return &OpenSearchLog{client: client, index: index}
+}
+
+// Record indexes the entry, letting OpenSearch generate its ID
+func (l *OpenSearchLog) Record(entry model.AuditEntry, ctx context.Context) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index: l.index,
+		Body:  bytes.NewReader(body),
+	}
+
+	res, err := req.Do(ctx, l.client)
+	if err != nil {
+		return fmt.Errorf("failed to index audit entry: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("audit indexing error: %s", res.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package buildinfo describes the build of the service that is running. The
+// version, commit and build date are set when building, for example:
+//
+//	go build -ldflags "-X github.com/aws-containers/retail-store-sample-app/catalog/buildinfo.Version=1.2.0"
+//
+// Builds from a git checkout fall back to the commit and time recorded by
+// the Go toolchain.
+package buildinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/features"
+)
+
+// Set with -ldflags "-X" when building
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the build that is running and what it is connected to, so
+// that operators can check what is deployed
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	// Features are the feature flags and whether each is on
+	Features            map[string]bool `json:"features"`
+	PersistenceProvider string          `json:"persistenceProvider"`
+	// SearchProvider is "disabled" when searches use the database
+	SearchProvider string `json:"searchProvider"`
+}
+
+// Build returns the version, commit and date of the build, the latter two
+// from the Go toolchain when they weren't set when building
+func Build() (version, commit, date string) {
+	version, commit, date = Version, Commit, Date
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+
+	return version, commit, date
+}
+
+// NewHandler serves the build information along with the providers in use
+// and the current state of the feature flags
+func NewHandler(persistenceProvider, searchProvider string, flags *features.Flags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := Info{
+			GoVersion:           runtime.Version(),
+			Features:            flags.All(),
+			PersistenceProvider: persistenceProvider,
+			SearchProvider:      searchProvider,
+		}
+		info.Version, info.Commit, info.BuildDate = Build()
+
+		c.JSON(http.StatusOK, info)
+	}
+}
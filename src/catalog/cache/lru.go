@@ -0,0 +1,172 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package cache keeps recently used values in memory for a limited time
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU holds up to size values for at most ttl each, evicting the least
+// recently used value to make room for a new one. It is safe for concurrent
+// use, and a nil LRU caches nothing. Its name identifies it in the hit, miss
+// and eviction metrics.
+type LRU[K comparable, V any] struct {
+	name string
+	size int
+	ttl  time.Duration
+
+	mu         sync.Mutex
+	entries    map[K]*list.Element
+	order      *list.List
+	generation uint64
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// New returns an empty cache holding up to size values for at most ttl each
+func New[K comparable, V any](name string, size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		name:    name,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value cached for the key, or calls load to read it and
+// caches the result. Errors aren't cached. A value loaded while the cache is
+// invalidated isn't cached either, since it may have been read before the
+// change that invalidated it.
+func (c *LRU[K, V]) Get(key K, load func() (V, error)) (V, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	if value, ok := c.lookup(key); ok {
+		c.mu.Unlock()
+		observeRequest(c.name, tierMemory, resultHit)
+		return value, nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	observeRequest(c.name, tierMemory, resultMiss)
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.add(key, value)
+	}
+	return value, nil
+}
+
+// Remove drops the value cached for the key
+func (c *LRU[K, V]) Remove(key K) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Purge drops every cached value
+func (c *LRU[K, V]) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+// Len returns the number of values cached, including any that have expired
+// but haven't been looked up since
+func (c *LRU[K, V]) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// lookup returns the cached value unless it is missing or has expired,
+// marking it as the most recently used
+func (c *LRU[K, V]) lookup(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	e := element.Value.(*entry[K, V])
+	if !time.Now().Before(e.expires) {
+		c.remove(element)
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// add caches a value, evicting the least recently used one if the cache is full
+func (c *LRU[K, V]) add(key K, value V) {
+	expires := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		element.Value = &entry[K, V]{key: key, value: value, expires: expires}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+		observeEviction(c.name)
+	}
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+}
+
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,102 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Tiers of the cache, in the order they are looked in
+const (
+	tierMemory = "memory"
+	tierRedis  = "redis"
+)
+
+// Results of looking a key up in a cache. A value is coalesced when another
+// replica loaded it while this one waited, and an error means the cache
+// couldn't be reached so the value was loaded without it.
+const (
+	resultHit       = "hit"
+	resultMiss      = "miss"
+	resultCoalesced = "coalesced"
+	resultError     = "error"
+)
+
+type metrics struct {
+	requests  *prometheus.CounterVec
+	evictions *prometheus.CounterVec
+}
+
+var registeredMetrics atomic.Pointer[metrics]
+
+// statsDClient is set by RegisterStatsDMetrics when the metrics are sent to a
+// DogStatsD agent instead
+var statsDClient atomic.Pointer[statsd.Client]
+
+// RegisterMetrics registers the counts of cache hits, misses and evictions,
+// by cache and tier. Lookups before it is called aren't recorded.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_cache_requests_total",
+			Help: "Cache lookups, by cache, tier and whether the value was cached",
+		}, []string{"cache", "tier", "result"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_cache_evictions_total",
+			Help: "Values evicted to make room in a full cache, by cache",
+		}, []string{"cache"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.evictions} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	registeredMetrics.Store(m)
+	return nil
+}
+
+// RegisterStatsDMetrics sends the same counts as RegisterMetrics to a
+// DogStatsD agent, as catalog.cache.requests and catalog.cache.evictions,
+// until it is called with nil
+func RegisterStatsDMetrics(client *statsd.Client) {
+	statsDClient.Store(client)
+}
+
+func observeRequest(cache, tier, result string) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.requests.WithLabelValues(cache, tier, result).Inc()
+	}
+
+	if client := statsDClient.Load(); client != nil {
+		client.Incr("catalog.cache.requests", []string{"cache:" + cache, "tier:" + tier, "result:" + result}, 1)
+	}
+}
+
+func observeEviction(cache string) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.evictions.WithLabelValues(cache).Inc()
+	}
+
+	if client := statsDClient.Load(); client != nil {
+		client.Incr("catalog.cache.evictions", []string{"cache:" + cache}, 1)
+	}
+}
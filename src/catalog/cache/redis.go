@@ -0,0 +1,244 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockPoll is how often a replica waiting for another to load a value checks
+// whether it has been cached
+const lockPoll = 20 * time.Millisecond
+
+// storeIfCurrent caches a loaded value as long as the loader still holds the
+// lock on its key and the namespace hasn't been invalidated since the load
+// started, so that a value read before a change is never cached after it.
+// KEYS are the namespace version, the value and the lock, ARGV the version
+// the load started at, the value, its TTL in milliseconds and the lock token.
+var storeIfCurrent = redis.NewScript(`
+if redis.call('GET', KEYS[3]) ~= ARGV[4] then
+	return 0
+end
+redis.call('DEL', KEYS[3])
+if (redis.call('GET', KEYS[1]) or '0') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// Redis is a cache shared by every replica, with values stored as JSON under
+// a namespace per kind of value. A namespace is invalidated as a whole by
+// increasing its version, which is part of the keys of its values, leaving
+// the old values to expire. Only one replica loads a missing value at a time
+// while the others wait for it, so that an expired popular value doesn't
+// send every request to the database at once. A nil Redis caches nothing.
+type Redis struct {
+	client   redis.UniversalClient
+	prefix   string
+	lockWait time.Duration
+}
+
+// NewRedis returns a cache storing its keys under the prefix, where replicas
+// wait up to lockWait for another to load a missing value
+func NewRedis(client redis.UniversalClient, prefix string, lockWait time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, lockWait: lockWait}
+}
+
+// Close closes the connections to Redis
+func (r *Redis) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.client.Close()
+}
+
+// Status reports how long Redis takes to answer a ping, for the detailed
+// health report
+func (r *Redis) Status(ctx context.Context) (map[string]any, error) {
+	started := time.Now()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return map[string]any{"pingLatencyMs": time.Since(started).Milliseconds()}, nil
+}
+
+// GetJSON returns the value cached under the key in the namespace, or calls
+// load to read it and caches the result for ttl. Errors aren't cached. When
+// Redis can't be reached the value is loaded without it, since the cache
+// must never make reads fail, which is counted rather than logged above the
+// debug level so that an outage doesn't flood the logs.
+func GetJSON[V any](r *Redis, namespace, key string, ttl time.Duration, load func() (V, error), ctx context.Context) (V, error) {
+	if r == nil {
+		return load()
+	}
+
+	version, err := r.version(namespace, ctx)
+	if err != nil {
+		return loadUncached(namespace, err, load, ctx)
+	}
+	keys := r.keys(namespace, version, key)
+
+	if value, ok, err := getValue[V](r, keys.value, ctx); err != nil {
+		return loadUncached(namespace, err, load, ctx)
+	} else if ok {
+		observeRequest(namespace, tierRedis, resultHit)
+		return value, nil
+	}
+
+	token := rand.Text()
+	locked, err := r.client.SetNX(ctx, keys.lock, token, r.lockWait).Result()
+	if err != nil {
+		return loadUncached(namespace, err, load, ctx)
+	}
+
+	if !locked {
+		if value, ok := waitForValue[V](r, keys.value, ctx); ok {
+			observeRequest(namespace, tierRedis, resultCoalesced)
+			return value, nil
+		}
+	}
+
+	observeRequest(namespace, tierRedis, resultMiss)
+	value, err := load()
+	if err != nil || !locked {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = storeIfCurrent.Run(ctx, r.client, []string{keys.version, keys.value, keys.lock},
+			version, data, ttl.Milliseconds(), token).Err()
+	}
+	if err != nil {
+		slog.DebugContext(ctx, "Failed to cache a value in Redis", "namespace", namespace, "error", err)
+	}
+	return value, nil
+}
+
+// Delete drops the value cached under the key in the namespace, along with
+// any lock on it so that a value being loaded at the time isn't cached
+func (r *Redis) Delete(namespace, key string, ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	version, err := r.version(namespace, ctx)
+	if err == nil {
+		keys := r.keys(namespace, version, key)
+		err = r.client.Del(ctx, keys.value, keys.lock).Err()
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to drop a value from Redis", "namespace", namespace, "error", err)
+	}
+}
+
+// Invalidate drops every value cached in the namespace
+func (r *Redis) Invalidate(namespace string, ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	if err := r.client.Incr(ctx, r.versionKey(namespace)).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate values cached in Redis", "namespace", namespace, "error", err)
+	}
+}
+
+// redisKeys are the keys of a cached value and the lock held while loading it
+type redisKeys struct {
+	version string
+	value   string
+	lock    string
+}
+
+// keys returns the keys of a value. The namespace is a hash tag, so that a
+// Redis cluster keeps every key of the namespace in the same slot for the
+// script that stores values.
+func (r *Redis) keys(namespace, version, key string) redisKeys {
+	value := r.prefix + "{" + namespace + "}:" + version + ":" + key
+	return redisKeys{version: r.versionKey(namespace), value: value, lock: value + ":lock"}
+}
+
+func (r *Redis) versionKey(namespace string) string {
+	return r.prefix + "{" + namespace + "}:version"
+}
+
+// version returns the current version of a namespace, which is 0 until it
+// is first invalidated
+func (r *Redis) version(namespace string, ctx context.Context) (string, error) {
+	version, err := r.client.Get(ctx, r.versionKey(namespace)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return "0", nil
+	}
+	return strconv.FormatInt(version, 10), err
+}
+
+// getValue reads a cached value, reporting whether there was one. A value
+// that can't be decoded, such as one cached by an older version of the
+// service, is treated as missing.
+func getValue[V any](r *Redis, key string, ctx context.Context) (V, bool, error) {
+	var value V
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	return value, json.Unmarshal(data, &value) == nil, nil
+}
+
+// waitForValue waits for the replica holding the lock on a value to cache
+// it, giving up after the lock wait
+func waitForValue[V any](r *Redis, key string, ctx context.Context) (V, bool) {
+	deadline := time.Now().Add(r.lockWait)
+	ticker := time.NewTicker(lockPoll)
+	defer ticker.Stop()
+
+	var zero V
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return zero, false
+		case <-ticker.C:
+		}
+
+		value, ok, err := getValue[V](r, key, ctx)
+		if err != nil {
+			return zero, false
+		}
+		if ok {
+			return value, true
+		}
+	}
+	return zero, false
+}
+
+// loadUncached loads a value without the cache after failing to reach Redis
+func loadUncached[V any](namespace string, err error, load func() (V, error), ctx context.Context) (V, error) {
+	slog.DebugContext(ctx, "Redis is unavailable, reading without the cache", "namespace", namespace, "error", err)
+	observeRequest(namespace, tierRedis, resultError)
+	return load()
+}
@@ -16,30 +16,495 @@
 
 package config
 
+import "time"
+
 // Configuration exported
 type AppConfiguration struct {
-	Port       int `env:"PORT,default=8080"`
-	Database   DatabaseConfiguration
-	OpenSearch OpenSearchConfiguration
+	Port        int                      `env:"PORT,default=8080" yaml:"port"`
+	Server      ServerConfiguration      `yaml:"server"`
+	Logging     LoggingConfiguration     `yaml:"logging"`
+	AccessLog   AccessLogConfiguration   `yaml:"accessLog"`
+	Metrics     MetricsConfiguration     `yaml:"metrics"`
+	Tracing     TracingConfiguration     `yaml:"tracing"`
+	Debug       DebugConfiguration       `yaml:"debug"`
+	Startup     StartupConfiguration     `yaml:"startup"`
+	Shutdown    ShutdownConfiguration    `yaml:"shutdown"`
+	Reload      ReloadConfiguration      `yaml:"reload"`
+	Features    FeaturesConfiguration    `yaml:"features"`
+	Chaos       ChaosConfiguration       `yaml:"chaos"`
+	Bulkhead    BulkheadConfiguration    `yaml:"bulkhead"`
+	TLS         TLSConfiguration         `yaml:"tls"`
+	HTTP2       HTTP2Configuration       `yaml:"http2"`
+	GRPC        GRPCConfiguration        `yaml:"grpc"`
+	Gateway     GatewayConfiguration     `yaml:"gateway"`
+	CORS        CORSConfiguration        `yaml:"cors"`
+	Compression CompressionConfiguration `yaml:"compression"`
+	RateLimit   RateLimitConfiguration   `yaml:"rateLimit"`
+	Routes      RoutesConfiguration      `yaml:"routes"`
+	Idempotency IdempotencyConfiguration `yaml:"idempotency"`
+	Pagination  PaginationConfiguration  `yaml:"pagination"`
+	Currency    CurrencyConfiguration    `yaml:"currency"`
+	Concurrency ConcurrencyConfiguration `yaml:"concurrency"`
+	Sitemap     SitemapConfiguration     `yaml:"sitemap"`
+	Images      ImagesConfiguration      `yaml:"images"`
+	Cache       CacheConfiguration       `yaml:"cache"`
+	Auth        AuthConfiguration        `yaml:"auth"`
+	Admin       AdminConfiguration       `yaml:"admin"`
+	Audit       AuditConfiguration       `yaml:"audit"`
+	Events      EventsConfiguration      `yaml:"events"`
+	Database    DatabaseConfiguration    `yaml:"database"`
+	Seed        SeedConfiguration        `yaml:"seed"`
+	Search      SearchConfiguration      `yaml:"search"`
+	OpenSearch  OpenSearchConfiguration  `yaml:"openSearch"`
+	Loadgen     LoadgenConfiguration     `yaml:"loadgen"`
+}
+
+// ServerConfiguration exported
+type ServerConfiguration struct {
+	// Addresses replace PORT with a list of host:port addresses and Unix
+	// domain sockets written as unix:/path/to/socket
+	Addresses  []string `env:"RETAIL_CATALOG_SERVER_ADDRESSES" yaml:"addresses"`
+	SocketMode string   `env:"RETAIL_CATALOG_SERVER_SOCKET_MODE,default=0660" yaml:"socketMode"`
+}
+
+// LoggingConfiguration exported
+type LoggingConfiguration struct {
+	Level  string `env:"RETAIL_CATALOG_LOG_LEVEL,default=info" yaml:"level"`
+	Format string `env:"RETAIL_CATALOG_LOG_FORMAT,default=text" yaml:"format"`
+	// OTLP exports logs to the collector given by the OTEL_EXPORTER_OTLP_*
+	// variables as well as writing them out
+	OTLP bool `env:"RETAIL_CATALOG_LOG_OTLP,default=false" yaml:"otlp"`
+}
+
+// AccessLogConfiguration exported
+type AccessLogConfiguration struct {
+	Enabled bool `env:"RETAIL_CATALOG_ACCESS_LOG_ENABLED,default=true" yaml:"enabled"`
+	// SampleRate is the fraction of requests logged, requests that fail or
+	// take longer than SlowThreshold are always logged
+	SampleRate    float64       `env:"RETAIL_CATALOG_ACCESS_LOG_SAMPLE_RATE,default=1" yaml:"sampleRate"`
+	SlowThreshold time.Duration `env:"RETAIL_CATALOG_ACCESS_LOG_SLOW_THRESHOLD,default=1s" yaml:"slowThreshold"`
+	// SkipPaths are never logged, in addition to the health checks
+	SkipPaths []string `env:"RETAIL_CATALOG_ACCESS_LOG_SKIP_PATHS" yaml:"skipPaths"`
+}
+
+// MetricsConfiguration exported
+type MetricsConfiguration struct {
+	// Buckets are upper bounds in seconds, using the Prometheus defaults
+	// when empty
+	RequestBuckets    []float64 `env:"RETAIL_CATALOG_METRICS_REQUEST_BUCKETS" yaml:"requestBuckets"`
+	RepositoryBuckets []float64 `env:"RETAIL_CATALOG_METRICS_REPOSITORY_BUCKETS" yaml:"repositoryBuckets"`
+	Runtime           bool      `env:"RETAIL_CATALOG_METRICS_RUNTIME,default=true" yaml:"runtime"`
+	// Exporter is prometheus, which serves /metrics, or dogstatsd, which
+	// sends the request and repository metrics to a Datadog agent instead
+	Exporter string `env:"RETAIL_CATALOG_METRICS_EXPORTER,default=prometheus" yaml:"exporter"`
+	// StatsDAddress is the agent's host:port or unix:///path/to/socket,
+	// found from DD_AGENT_HOST and DD_DOGSTATSD_PORT when empty
+	StatsDAddress string `env:"RETAIL_CATALOG_METRICS_STATSD_ADDRESS" yaml:"statsdAddress"`
+}
+
+// TracingConfiguration exported
+type TracingConfiguration struct {
+	XRay bool `env:"RETAIL_CATALOG_TRACING_XRAY,default=false" yaml:"xray"`
+}
+
+// DebugConfiguration exported
+type DebugConfiguration struct {
+	Enabled bool   `env:"RETAIL_CATALOG_DEBUG_ENABLED,default=false" yaml:"enabled"`
+	Address string `env:"RETAIL_CATALOG_DEBUG_ADDRESS,default=127.0.0.1:6060" yaml:"address"`
+}
+
+// StartupConfiguration exported
+type StartupConfiguration struct {
+	// WaitTimeout is how long to keep retrying the database and search
+	// backend while they are unreachable, zero fails on the first attempt
+	WaitTimeout time.Duration `env:"RETAIL_CATALOG_STARTUP_WAIT_TIMEOUT,default=30s" yaml:"waitTimeout"`
+	// InitialBackoff is doubled after every failed attempt up to MaxBackoff
+	InitialBackoff time.Duration `env:"RETAIL_CATALOG_STARTUP_INITIAL_BACKOFF,default=500ms" yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `env:"RETAIL_CATALOG_STARTUP_MAX_BACKOFF,default=10s" yaml:"maxBackoff"`
+	// WarmUp makes the requests a storefront makes first before the service
+	// reports itself ready, so that opening connections and filling caches
+	// doesn't slow down the first real requests
+	WarmUp            bool          `env:"RETAIL_CATALOG_STARTUP_WARM_UP,default=false" yaml:"warmUp"`
+	WarmUpConcurrency int           `env:"RETAIL_CATALOG_STARTUP_WARM_UP_CONCURRENCY,default=4" yaml:"warmUpConcurrency"`
+	WarmUpTimeout     time.Duration `env:"RETAIL_CATALOG_STARTUP_WARM_UP_TIMEOUT,default=30s" yaml:"warmUpTimeout"`
+}
+
+// ShutdownConfiguration exported
+type ShutdownConfiguration struct {
+	// Delay keeps serving once readiness starts failing, so that load
+	// balancers stop sending requests before the listeners close
+	Delay time.Duration `env:"RETAIL_CATALOG_SHUTDOWN_DELAY,default=0s" yaml:"delay"`
+	// GracePeriod bounds the time left for requests and background jobs to
+	// finish after that
+	GracePeriod time.Duration `env:"RETAIL_CATALOG_SHUTDOWN_GRACE_PERIOD,default=20s" yaml:"gracePeriod"`
+}
+
+// ReloadConfiguration exported
+type ReloadConfiguration struct {
+	Interval time.Duration `env:"RETAIL_CATALOG_CONFIG_RELOAD_INTERVAL,default=10s" yaml:"interval"`
+}
+
+// FeaturesConfiguration exported
+type FeaturesConfiguration struct {
+	// Flags turns features on or off, for example chaos:false
+	Flags     map[string]bool        `env:"RETAIL_CATALOG_FEATURE_FLAGS" yaml:"flags"`
+	AppConfig AppConfigConfiguration `yaml:"appConfig"`
+}
+
+// ChaosConfiguration exported
+type ChaosConfiguration struct {
+	// Faults injected into database and OpenSearch calls once the service
+	// has started, while the chaos feature flag is on
+	DatabaseLatency     time.Duration `env:"RETAIL_CATALOG_CHAOS_DATABASE_LATENCY,default=0s" yaml:"databaseLatency"`
+	DatabaseErrorRate   float64       `env:"RETAIL_CATALOG_CHAOS_DATABASE_ERROR_RATE,default=0" yaml:"databaseErrorRate"`
+	DatabaseBlackhole   bool          `env:"RETAIL_CATALOG_CHAOS_DATABASE_BLACKHOLE,default=false" yaml:"databaseBlackhole"`
+	OpenSearchLatency   time.Duration `env:"RETAIL_CATALOG_CHAOS_OPENSEARCH_LATENCY,default=0s" yaml:"openSearchLatency"`
+	OpenSearchErrorRate float64       `env:"RETAIL_CATALOG_CHAOS_OPENSEARCH_ERROR_RATE,default=0" yaml:"openSearchErrorRate"`
+	OpenSearchBlackhole bool          `env:"RETAIL_CATALOG_CHAOS_OPENSEARCH_BLACKHOLE,default=false" yaml:"openSearchBlackhole"`
+}
+
+// BulkheadConfiguration exported
+type BulkheadConfiguration struct {
+	// DatabaseLimit is the most statements run against the database at once
+	// and OpenSearchLimit the most requests sent to OpenSearch, zero for no
+	// limit. A call waits up to the wait for a slot before it fails.
+	DatabaseLimit   int           `env:"RETAIL_CATALOG_BULKHEAD_DATABASE_LIMIT,default=0" yaml:"databaseLimit"`
+	DatabaseWait    time.Duration `env:"RETAIL_CATALOG_BULKHEAD_DATABASE_WAIT,default=100ms" yaml:"databaseWait"`
+	OpenSearchLimit int           `env:"RETAIL_CATALOG_BULKHEAD_OPENSEARCH_LIMIT,default=0" yaml:"openSearchLimit"`
+	OpenSearchWait  time.Duration `env:"RETAIL_CATALOG_BULKHEAD_OPENSEARCH_WAIT,default=100ms" yaml:"openSearchWait"`
+}
+
+// AppConfigConfiguration exported
+type AppConfigConfiguration struct {
+	Application  string        `env:"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_APPLICATION" yaml:"application"`
+	Environment  string        `env:"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_ENVIRONMENT" yaml:"environment"`
+	Profile      string        `env:"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_PROFILE" yaml:"profile"`
+	PollInterval time.Duration `env:"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_POLL_INTERVAL,default=30s" yaml:"pollInterval"`
+}
+
+// TLSConfiguration exported
+type TLSConfiguration struct {
+	CertFile       string        `env:"RETAIL_CATALOG_TLS_CERT_FILE" yaml:"certFile"`
+	KeyFile        string        `env:"RETAIL_CATALOG_TLS_KEY_FILE" yaml:"keyFile"`
+	ReloadInterval time.Duration `env:"RETAIL_CATALOG_TLS_RELOAD_INTERVAL,default=1m" yaml:"reloadInterval"`
+}
+
+// HTTP2Configuration exported
+type HTTP2Configuration struct {
+	Enabled   bool `env:"RETAIL_CATALOG_HTTP2_ENABLED,default=true" yaml:"enabled"`
+	Cleartext bool `env:"RETAIL_CATALOG_HTTP2_CLEARTEXT,default=false" yaml:"cleartext"`
+}
+
+// GRPCConfiguration exported
+type GRPCConfiguration struct {
+	Enabled bool `env:"RETAIL_CATALOG_GRPC_ENABLED,default=false" yaml:"enabled"`
+	Port    int  `env:"RETAIL_CATALOG_GRPC_PORT,default=9090" yaml:"port"`
+}
+
+// GatewayConfiguration exported
+type GatewayConfiguration struct {
+	Enabled bool `env:"RETAIL_CATALOG_GATEWAY_ENABLED,default=false" yaml:"enabled"`
+}
+
+// CORSConfiguration exported
+type CORSConfiguration struct {
+	AllowedOrigins   []string      `env:"RETAIL_CATALOG_CORS_ALLOWED_ORIGINS" yaml:"allowedOrigins"`
+	AllowedMethods   []string      `env:"RETAIL_CATALOG_CORS_ALLOWED_METHODS" yaml:"allowedMethods"`
+	AllowedHeaders   []string      `env:"RETAIL_CATALOG_CORS_ALLOWED_HEADERS" yaml:"allowedHeaders"`
+	AllowCredentials bool          `env:"RETAIL_CATALOG_CORS_ALLOW_CREDENTIALS,default=false" yaml:"allowCredentials"`
+	MaxAge           time.Duration `env:"RETAIL_CATALOG_CORS_MAX_AGE,default=10m" yaml:"maxAge"`
+}
+
+// CompressionConfiguration exported
+type CompressionConfiguration struct {
+	Enabled bool `env:"RETAIL_CATALOG_COMPRESSION_ENABLED,default=true" yaml:"enabled"`
+	MinSize int  `env:"RETAIL_CATALOG_COMPRESSION_MIN_SIZE,default=1024" yaml:"minSize"`
+}
+
+// RateLimitConfiguration exported
+type RateLimitConfiguration struct {
+	Enabled     bool    `env:"RETAIL_CATALOG_RATE_LIMIT_ENABLED,default=false" yaml:"enabled"`
+	ReadRate    float64 `env:"RETAIL_CATALOG_RATE_LIMIT_READ_RPS,default=50" yaml:"readRate"`
+	ReadBurst   int     `env:"RETAIL_CATALOG_RATE_LIMIT_READ_BURST,default=100" yaml:"readBurst"`
+	SearchRate  float64 `env:"RETAIL_CATALOG_RATE_LIMIT_SEARCH_RPS,default=10" yaml:"searchRate"`
+	SearchBurst int     `env:"RETAIL_CATALOG_RATE_LIMIT_SEARCH_BURST,default=20" yaml:"searchBurst"`
+	WriteRate   float64 `env:"RETAIL_CATALOG_RATE_LIMIT_WRITE_RPS,default=5" yaml:"writeRate"`
+	WriteBurst  int     `env:"RETAIL_CATALOG_RATE_LIMIT_WRITE_BURST,default=10" yaml:"writeBurst"`
+}
+
+// RoutesConfiguration exported
+type RoutesConfiguration struct {
+	ReadTimeout       time.Duration `env:"RETAIL_CATALOG_ROUTES_READ_TIMEOUT,default=10s" yaml:"readTimeout"`
+	ReadMaxBodySize   int64         `env:"RETAIL_CATALOG_ROUTES_READ_MAX_BODY_SIZE,default=1048576" yaml:"readMaxBodySize"`
+	SearchTimeout     time.Duration `env:"RETAIL_CATALOG_ROUTES_SEARCH_TIMEOUT,default=5s" yaml:"searchTimeout"`
+	SearchMaxBodySize int64         `env:"RETAIL_CATALOG_ROUTES_SEARCH_MAX_BODY_SIZE,default=16384" yaml:"searchMaxBodySize"`
+	WriteTimeout      time.Duration `env:"RETAIL_CATALOG_ROUTES_WRITE_TIMEOUT,default=10s" yaml:"writeTimeout"`
+	WriteMaxBodySize  int64         `env:"RETAIL_CATALOG_ROUTES_WRITE_MAX_BODY_SIZE,default=1048576" yaml:"writeMaxBodySize"`
+	AdminTimeout      time.Duration `env:"RETAIL_CATALOG_ROUTES_ADMIN_TIMEOUT,default=60s" yaml:"adminTimeout"`
+	AdminMaxBodySize  int64         `env:"RETAIL_CATALOG_ROUTES_ADMIN_MAX_BODY_SIZE,default=104857600" yaml:"adminMaxBodySize"`
+}
+
+// IdempotencyConfiguration exported
+type IdempotencyConfiguration struct {
+	TTL time.Duration `env:"RETAIL_CATALOG_IDEMPOTENCY_TTL,default=10m" yaml:"ttl"`
+}
+
+// PaginationConfiguration exported
+type PaginationConfiguration struct {
+	CursorSecret string `env:"RETAIL_CATALOG_PAGINATION_CURSOR_SECRET" yaml:"cursorSecret"`
+}
+
+// CurrencyConfiguration exported
+type CurrencyConfiguration struct {
+	// Rates convert prices to other currencies, as the amount of each that
+	// one USD buys, for example EUR:0.92,GBP:0.79
+	Rates map[string]float64 `env:"RETAIL_CATALOG_CURRENCY_RATES" yaml:"rates"`
+	// RatesURL is polled for rates, which take precedence over Rates, as
+	// JSON such as {"base": "USD", "rates": {"EUR": 0.92}}
+	RatesURL      string        `env:"RETAIL_CATALOG_CURRENCY_RATES_URL" yaml:"ratesUrl"`
+	RatesInterval time.Duration `env:"RETAIL_CATALOG_CURRENCY_RATES_INTERVAL,default=1h" yaml:"ratesInterval"`
+}
+
+// ConcurrencyConfiguration exported
+type ConcurrencyConfiguration struct {
+	RequireIfMatch bool `env:"RETAIL_CATALOG_CONCURRENCY_REQUIRE_IF_MATCH,default=false" yaml:"requireIfMatch"`
+}
+
+// SitemapConfiguration exported
+type SitemapConfiguration struct {
+	BaseURL  string `env:"RETAIL_CATALOG_SITEMAP_BASE_URL,default=http://localhost:8888" yaml:"baseUrl"`
+	PageSize int    `env:"RETAIL_CATALOG_SITEMAP_PAGE_SIZE,default=1000" yaml:"pageSize"`
+}
+
+// ImagesConfiguration exported
+type ImagesConfiguration struct {
+	// Store is where product images are kept, embedded for the bundled
+	// images only or s3 to upload them to S3Bucket
+	Store    string `env:"RETAIL_CATALOG_IMAGES_STORE,default=embedded" yaml:"store"`
+	S3Bucket string `env:"RETAIL_CATALOG_IMAGES_S3_BUCKET" yaml:"s3Bucket"`
+	S3Prefix string `env:"RETAIL_CATALOG_IMAGES_S3_PREFIX" yaml:"s3Prefix"`
+	// S3Endpoint replaces the S3 endpoint, for example to use LocalStack
+	S3Endpoint string `env:"RETAIL_CATALOG_IMAGES_S3_ENDPOINT" yaml:"s3Endpoint"`
+	// CDNURL is the base URL images in the bucket are served from, instead
+	// of presigned S3 URLs that expire after PresignExpiry
+	CDNURL        string        `env:"RETAIL_CATALOG_IMAGES_CDN_URL" yaml:"cdnUrl"`
+	PresignExpiry time.Duration `env:"RETAIL_CATALOG_IMAGES_PRESIGN_EXPIRY,default=1h" yaml:"presignExpiry"`
+	MaxSize       int64         `env:"RETAIL_CATALOG_IMAGES_MAX_SIZE,default=5242880" yaml:"maxSize"`
+}
+
+// CacheConfiguration exported
+type CacheConfiguration struct {
+	// Enabled keeps product lookups and search results in memory, in a cache
+	// of each holding up to Size entries for at most TTL
+	Enabled bool          `env:"RETAIL_CATALOG_CACHE_ENABLED,default=false" yaml:"enabled"`
+	Size    int           `env:"RETAIL_CATALOG_CACHE_SIZE,default=1000" yaml:"size"`
+	TTL     time.Duration `env:"RETAIL_CATALOG_CACHE_TTL,default=30s" yaml:"ttl"`
+	// Redis is shared by every replica, behind the in-memory cache if that
+	// is enabled too
+	Redis RedisCacheConfiguration `yaml:"redis"`
+}
+
+// RedisCacheConfiguration exported
+type RedisCacheConfiguration struct {
+	// Endpoint is the host:port of Redis or ElastiCache, leaving it empty
+	// disables the Redis cache
+	Endpoint  string `env:"RETAIL_CATALOG_CACHE_REDIS_ENDPOINT" yaml:"endpoint"`
+	Password  string `env:"RETAIL_CATALOG_CACHE_REDIS_PASSWORD" yaml:"password"`
+	TLS       bool   `env:"RETAIL_CATALOG_CACHE_REDIS_TLS,default=false" yaml:"tls"`
+	Cluster   bool   `env:"RETAIL_CATALOG_CACHE_REDIS_CLUSTER,default=false" yaml:"cluster"`
+	KeyPrefix string `env:"RETAIL_CATALOG_CACHE_REDIS_KEY_PREFIX,default=catalog:" yaml:"keyPrefix"`
+	// How long each kind of value is cached for, where reference data is
+	// the tags and categories
+	ProductTTL   time.Duration `env:"RETAIL_CATALOG_CACHE_REDIS_PRODUCT_TTL,default=5m" yaml:"productTtl"`
+	SearchTTL    time.Duration `env:"RETAIL_CATALOG_CACHE_REDIS_SEARCH_TTL,default=1m" yaml:"searchTtl"`
+	ReferenceTTL time.Duration `env:"RETAIL_CATALOG_CACHE_REDIS_REFERENCE_TTL,default=10m" yaml:"referenceTtl"`
+	// Timeout bounds each call to Redis, so that an unreachable cache slows
+	// reads down as little as possible before they go to the repositories
+	Timeout time.Duration `env:"RETAIL_CATALOG_CACHE_REDIS_TIMEOUT,default=100ms" yaml:"timeout"`
+	// LockWait bounds how long a replica waits for another that is loading
+	// the same missing value before loading it too
+	LockWait time.Duration `env:"RETAIL_CATALOG_CACHE_REDIS_LOCK_WAIT,default=500ms" yaml:"lockWait"`
+}
+
+// AdminConfiguration exported
+type AdminConfiguration struct {
+	ResetEnabled bool `env:"RETAIL_CATALOG_ADMIN_RESET_ENABLED,default=false" yaml:"resetEnabled"`
+}
+
+// AuditConfiguration exported
+type AuditConfiguration struct {
+	Enabled bool `env:"RETAIL_CATALOG_AUDIT_ENABLED,default=false" yaml:"enabled"`
+	// Sink is where entries are written: stdout, file or opensearch, which
+	// uses the connection settings of the search index
+	Sink  string `env:"RETAIL_CATALOG_AUDIT_SINK,default=stdout" yaml:"sink"`
+	File  string `env:"RETAIL_CATALOG_AUDIT_FILE" yaml:"file"`
+	Index string `env:"RETAIL_CATALOG_AUDIT_INDEX,default=catalog-audit" yaml:"index"`
+}
+
+// EventsConfiguration exported
+type EventsConfiguration struct {
+	Kafka KafkaConfiguration `yaml:"kafka"`
+}
+
+// KafkaConfiguration exported
+type KafkaConfiguration struct {
+	// Brokers are the host:port bootstrap brokers of Kafka or Amazon MSK,
+	// leaving them empty disables publishing events to Kafka
+	Brokers []string `env:"RETAIL_CATALOG_EVENTS_KAFKA_BROKERS" yaml:"brokers"`
+	Topic   string   `env:"RETAIL_CATALOG_EVENTS_KAFKA_TOPIC,default=catalog-events" yaml:"topic"`
+	TLS     bool     `env:"RETAIL_CATALOG_EVENTS_KAFKA_TLS,default=false" yaml:"tls"`
+	// Auth is none, scram to sign in with Username and Password using
+	// SCRAM-SHA-512, or iam for MSK IAM access control with the AWS
+	// credentials of the service
+	Auth     string `env:"RETAIL_CATALOG_EVENTS_KAFKA_AUTH,default=none" yaml:"auth"`
+	Username string `env:"RETAIL_CATALOG_EVENTS_KAFKA_USERNAME" yaml:"username"`
+	Password string `env:"RETAIL_CATALOG_EVENTS_KAFKA_PASSWORD" yaml:"password"`
+	Region   string `env:"RETAIL_CATALOG_EVENTS_KAFKA_REGION" yaml:"region"`
+	// Format is json or avro. SchemaRegistryURL registers the schema of the
+	// format with a Confluent-compatible schema registry, and prefixes
+	// payloads with its ID so that the registry's deserializers can read them.
+	Format            string `env:"RETAIL_CATALOG_EVENTS_KAFKA_FORMAT,default=json" yaml:"format"`
+	SchemaRegistryURL string `env:"RETAIL_CATALOG_EVENTS_KAFKA_SCHEMA_REGISTRY_URL" yaml:"schemaRegistryUrl"`
+	// BatchTimeout is the longest an event waits to be sent with others
+	BatchTimeout time.Duration `env:"RETAIL_CATALOG_EVENTS_KAFKA_BATCH_TIMEOUT,default=100ms" yaml:"batchTimeout"`
+}
+
+// AuthConfiguration exported
+type AuthConfiguration struct {
+	APIKeys       []string         `env:"RETAIL_CATALOG_AUTH_API_KEYS" yaml:"apiKeys"`
+	APIKeysSecret string           `env:"RETAIL_CATALOG_AUTH_API_KEYS_SECRET" yaml:"apiKeysSecret"`
+	JWT           JWTConfiguration `yaml:"jwt"`
+}
+
+// JWTConfiguration exported
+type JWTConfiguration struct {
+	Issuer     string `env:"RETAIL_CATALOG_AUTH_JWT_ISSUER" yaml:"issuer"`
+	Audience   string `env:"RETAIL_CATALOG_AUTH_JWT_AUDIENCE" yaml:"audience"`
+	JWKSURL    string `env:"RETAIL_CATALOG_AUTH_JWT_JWKS_URL" yaml:"jwksUrl"`
+	ReadScope  string `env:"RETAIL_CATALOG_AUTH_JWT_READ_SCOPE" yaml:"readScope"`
+	WriteScope string `env:"RETAIL_CATALOG_AUTH_JWT_WRITE_SCOPE,default=catalog/write" yaml:"writeScope"`
 }
 
 // DatabaseConfiguration exported
 type DatabaseConfiguration struct {
-	Type           string `env:"RETAIL_CATALOG_PERSISTENCE_PROVIDER,default=in-memory"`
-	Endpoint       string `env:"RETAIL_CATALOG_PERSISTENCE_ENDPOINT"`
-	Name           string `env:"RETAIL_CATALOG_PERSISTENCE_DB_NAME,default=catalogdb"`
-	User           string `env:"RETAIL_CATALOG_PERSISTENCE_USER,default=catalog_user"`
-	Password       string `env:"RETAIL_CATALOG_PERSISTENCE_PASSWORD"`
-	ConnectTimeout int    `env:"RETAIL_CATALOG_PERSISTENCE_CONNECT_TIMEOUT,default=5"`
+	Type           string `env:"RETAIL_CATALOG_PERSISTENCE_PROVIDER,default=in-memory" yaml:"type"`
+	Endpoint       string `env:"RETAIL_CATALOG_PERSISTENCE_ENDPOINT" yaml:"endpoint"`
+	ReaderEndpoint string `env:"RETAIL_CATALOG_PERSISTENCE_READER_ENDPOINT" yaml:"readerEndpoint"`
+	Path           string `env:"RETAIL_CATALOG_PERSISTENCE_PATH,default=catalog.db" yaml:"path"`
+	Name           string `env:"RETAIL_CATALOG_PERSISTENCE_DB_NAME,default=catalogdb" yaml:"name"`
+	User           string `env:"RETAIL_CATALOG_PERSISTENCE_USER,default=catalog_user" yaml:"user"`
+	Password       string `env:"RETAIL_CATALOG_PERSISTENCE_PASSWORD" yaml:"password"`
+	// PasswordSecret is the ARN or name of a Secrets Manager secret holding
+	// the password, or a JSON object with the username and password
+	PasswordSecret        string        `env:"RETAIL_CATALOG_PERSISTENCE_PASSWORD_SECRET" yaml:"passwordSecret"`
+	SecretRefreshInterval time.Duration `env:"RETAIL_CATALOG_PERSISTENCE_SECRET_REFRESH_INTERVAL,default=5m" yaml:"secretRefreshInterval"`
+	VaultPath             string        `env:"RETAIL_CATALOG_PERSISTENCE_VAULT_PATH" yaml:"vaultPath"`
+	PasswordFile          string        `env:"RETAIL_CATALOG_PERSISTENCE_PASSWORD_FILE" yaml:"passwordFile"`
+	ConnectTimeout        int           `env:"RETAIL_CATALOG_PERSISTENCE_CONNECT_TIMEOUT,default=5" yaml:"connectTimeout"`
+	IAMAuth               bool          `env:"RETAIL_CATALOG_PERSISTENCE_IAM_AUTH,default=false" yaml:"iamAuth"`
+	Region                string        `env:"RETAIL_CATALOG_PERSISTENCE_REGION" yaml:"region"`
+	CABundle              string        `env:"RETAIL_CATALOG_PERSISTENCE_CA_BUNDLE" yaml:"caBundle"`
+	// Reads are sent to the writer for this long after a write, so callers
+	// see their own changes while the replica catches up
+	ReplicaLagTolerance time.Duration `env:"RETAIL_CATALOG_PERSISTENCE_REPLICA_LAG_TOLERANCE,default=1s" yaml:"replicaLagTolerance"`
+}
+
+// SeedConfiguration exported
+type SeedConfiguration struct {
+	// URL replaces the bundled products with a dataset in the same layout,
+	// downloaded from S3 for s3://bucket/key URLs and over HTTP otherwise.
+	// It is only used if it matches the SHA256 checksum.
+	URL     string        `env:"RETAIL_CATALOG_SEED_URL" yaml:"url"`
+	SHA256  string        `env:"RETAIL_CATALOG_SEED_SHA256" yaml:"sha256"`
+	Timeout time.Duration `env:"RETAIL_CATALOG_SEED_TIMEOUT,default=30s" yaml:"timeout"`
+	// Format is json or csv, and by default csv for URLs ending in .csv
+	Format string `env:"RETAIL_CATALOG_SEED_FORMAT" yaml:"format"`
+	// S3Endpoint replaces the S3 endpoint, for example to use LocalStack
+	S3Endpoint string `env:"RETAIL_CATALOG_SEED_S3_ENDPOINT" yaml:"s3Endpoint"`
+}
+
+// SearchConfiguration exported
+type SearchConfiguration struct {
+	Backend           string   `env:"RETAIL_CATALOG_SEARCH_BACKEND" yaml:"backend"`
+	FederatedBackends []string `env:"RETAIL_CATALOG_SEARCH_FEDERATED_BACKENDS" yaml:"federatedBackends"`
+	// ReindexWorkers is how many batches of products background reindex
+	// jobs send to the search index at once
+	ReindexWorkers   int `env:"RETAIL_CATALOG_SEARCH_REINDEX_WORKERS,default=4" yaml:"reindexWorkers"`
+	ReindexBatchSize int `env:"RETAIL_CATALOG_SEARCH_REINDEX_BATCH_SIZE,default=100" yaml:"reindexBatchSize"`
+	// Queue is an SQS queue of product changes and reindex requests that are
+	// applied to the search index in the background
+	Queue IndexQueueConfiguration `yaml:"queue"`
+}
+
+// IndexQueueConfiguration exported
+type IndexQueueConfiguration struct {
+	// URL is the queue's URL, leaving it empty disables the consumer
+	URL    string `env:"RETAIL_CATALOG_SEARCH_QUEUE_URL" yaml:"url"`
+	Region string `env:"RETAIL_CATALOG_SEARCH_QUEUE_REGION" yaml:"region"`
+	// Endpoint replaces the SQS endpoint, for example to use LocalStack
+	Endpoint string `env:"RETAIL_CATALOG_SEARCH_QUEUE_ENDPOINT" yaml:"endpoint"`
+	// BatchSize is how many messages are received at once, up to 10, and
+	// WaitTime how long a receive waits for messages to arrive, up to 20s
+	BatchSize int           `env:"RETAIL_CATALOG_SEARCH_QUEUE_BATCH_SIZE,default=10" yaml:"batchSize"`
+	WaitTime  time.Duration `env:"RETAIL_CATALOG_SEARCH_QUEUE_WAIT_TIME,default=20s" yaml:"waitTime"`
 }
 
 // OpenSearchConfiguration exported
 type OpenSearchConfiguration struct {
-	Enabled       bool   `env:"RETAIL_CATALOG_SEARCH_ENABLED,default=false"`
-	Type          string `env:"RETAIL_CATALOG_SEARCH_PROVIDER,default=self-hosted"`
-	Endpoint      string `env:"RETAIL_CATALOG_SEARCH_OS_ENDPOINT,default=http://localhost:9200"`
-	IndexName     string `env:"RETAIL_CATALOG_SEARCH_OS_INDEX,default=products"`
-	Username      string `env:"RETAIL_CATALOG_SEARCH_OS_USERNAME,default=admin"`
-	Password      string `env:"RETAIL_CATALOG_SEARCH_OS_PASSWORD"`
-	TLSSkipVerify bool   `env:"RETAIL_CATALOG_SEARCH_OS_TLS_SKIP_VERIFY,default=false"`
+	Enabled   bool   `env:"RETAIL_CATALOG_SEARCH_ENABLED,default=false" yaml:"enabled"`
+	Type      string `env:"RETAIL_CATALOG_SEARCH_PROVIDER,default=self-hosted" yaml:"type"`
+	Endpoint  string `env:"RETAIL_CATALOG_SEARCH_OS_ENDPOINT,default=http://localhost:9200" yaml:"endpoint"`
+	IndexName string `env:"RETAIL_CATALOG_SEARCH_OS_INDEX,default=products" yaml:"indexName"`
+	Username  string `env:"RETAIL_CATALOG_SEARCH_OS_USERNAME,default=admin" yaml:"username"`
+	Password  string `env:"RETAIL_CATALOG_SEARCH_OS_PASSWORD" yaml:"password"`
+	// PasswordSecret is the ARN or name of a Secrets Manager secret holding
+	// the password, or a JSON object with the username and password
+	PasswordSecret        string        `env:"RETAIL_CATALOG_SEARCH_OS_PASSWORD_SECRET" yaml:"passwordSecret"`
+	SecretRefreshInterval time.Duration `env:"RETAIL_CATALOG_SEARCH_OS_SECRET_REFRESH_INTERVAL,default=5m" yaml:"secretRefreshInterval"`
+	VaultPath             string        `env:"RETAIL_CATALOG_SEARCH_OS_VAULT_PATH" yaml:"vaultPath"`
+	PasswordFile          string        `env:"RETAIL_CATALOG_SEARCH_OS_PASSWORD_FILE" yaml:"passwordFile"`
+	TLSSkipVerify         bool          `env:"RETAIL_CATALOG_SEARCH_OS_TLS_SKIP_VERIFY,default=false" yaml:"tlsSkipVerify"`
+	// Boosts weight matches by the field they are in, for example name:3
+	Boosts map[string]float64 `env:"RETAIL_CATALOG_SEARCH_OS_BOOSTS" yaml:"boosts"`
+	// Synonyms are rules in the Solr format such as "tv, television", which
+	// contain commas, so they can only be set in the configuration file
+	Synonyms []string `yaml:"synonyms"`
+	// HedgeDelay is how long a search runs before a second copy is sent,
+	// taking whichever answers first. Zero never hedges.
+	HedgeDelay time.Duration `env:"RETAIL_CATALOG_SEARCH_OS_HEDGE_DELAY,default=0s" yaml:"hedgeDelay"`
+	// HedgeBudget is the largest fraction of searches that can be hedged
+	HedgeBudget float64 `env:"RETAIL_CATALOG_SEARCH_OS_HEDGE_BUDGET,default=0.1" yaml:"hedgeBudget"`
+	// Coalesce runs a single query for identical searches in flight at the
+	// same time, sharing its results between them
+	Coalesce bool `env:"RETAIL_CATALOG_SEARCH_OS_COALESCE,default=true" yaml:"coalesce"`
+	// BreakerThreshold is how many searches in a row can fail before the
+	// circuit breaker opens and searches stop being sent for BreakerCooldown.
+	// Zero never opens it.
+	BreakerThreshold int           `env:"RETAIL_CATALOG_SEARCH_OS_BREAKER_THRESHOLD,default=5" yaml:"breakerThreshold"`
+	BreakerCooldown  time.Duration `env:"RETAIL_CATALOG_SEARCH_OS_BREAKER_COOLDOWN,default=30s" yaml:"breakerCooldown"`
+	// Failover answers searches from the database while the breaker is open
+	Failover bool `env:"RETAIL_CATALOG_SEARCH_OS_FAILOVER,default=true" yaml:"failover"`
+	// BulkRetries is how many times writes rejected because the cluster's
+	// write queues are full are sent again, with bulk requests shrunk to no
+	// fewer than BulkMinBatchSize documents and a pause of up to BulkMaxDelay
+	// before each write until the cluster catches up
+	BulkRetries      int           `env:"RETAIL_CATALOG_SEARCH_OS_BULK_RETRIES,default=5" yaml:"bulkRetries"`
+	BulkMinBatchSize int           `env:"RETAIL_CATALOG_SEARCH_OS_BULK_MIN_BATCH_SIZE,default=10" yaml:"bulkMinBatchSize"`
+	BulkMaxDelay     time.Duration `env:"RETAIL_CATALOG_SEARCH_OS_BULK_MAX_DELAY,default=5s" yaml:"bulkMaxDelay"`
+}
+
+// LoadgenConfiguration exported
+type LoadgenConfiguration struct {
+	// Target is the base URL of the catalog service load is generated for
+	Target string `env:"RETAIL_CATALOG_LOADGEN_TARGET,default=http://localhost:8080" yaml:"target"`
+	// RPS is the rate requests are sent at, whether or not earlier ones have
+	// been answered
+	RPS float64 `env:"RETAIL_CATALOG_LOADGEN_RPS,default=10" yaml:"rps"`
+	// Duration is how long to generate load for, zero until stopped
+	Duration time.Duration `env:"RETAIL_CATALOG_LOADGEN_DURATION,default=0s" yaml:"duration"`
+	// Workers is the most requests in flight at once
+	Workers int           `env:"RETAIL_CATALOG_LOADGEN_WORKERS,default=50" yaml:"workers"`
+	Timeout time.Duration `env:"RETAIL_CATALOG_LOADGEN_TIMEOUT,default=10s" yaml:"timeout"`
+	// APIKey is sent with every request, for when reads require one
+	APIKey string `env:"RETAIL_CATALOG_LOADGEN_API_KEY" yaml:"apiKey"`
 }
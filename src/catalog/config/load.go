@@ -0,0 +1,134 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable holding the path of the YAML
+// configuration file
+const FileEnv = "RETAIL_CATALOG_CONFIG_FILE"
+
+// Load reads the configuration from the environment, the parameters under
+// SSMPathEnv in SSM Parameter Store if it is set, and the YAML file named by
+// FileEnv if it is set. Environment variables take precedence over
+// parameters, which take precedence over the file and then the defaults. The
+// result is validated so that every problem is reported at once, before
+// anything tries to use it.
+func Load(ctx context.Context) (AppConfiguration, error) {
+	lookuper := envconfig.OsLookuper()
+
+	if parameterPath, ok := lookuper.Lookup(SSMPathEnv); ok && parameterPath != "" {
+		client, err := newSSMClient()
+		if err != nil {
+			return AppConfiguration{}, err
+		}
+
+		parameters, err := LoadParameters(ctx, client, parameterPath)
+		if err != nil {
+			return AppConfiguration{}, err
+		}
+
+		lookuper = envconfig.MultiLookuper(lookuper, parameters)
+	}
+
+	config, err := LoadWith(ctx, lookuper)
+	if err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// LoadWith is Load with the environment, and any parameters, read from the
+// given lookuper
+func LoadWith(ctx context.Context, lookuper envconfig.Lookuper) (AppConfiguration, error) {
+	var config AppConfiguration
+	if err := envconfig.ProcessWith(ctx, &config, lookuper); err != nil {
+		return config, err
+	}
+
+	path, ok := lookuper.Lookup(FileEnv)
+	if !ok || path == "" {
+		return config, nil
+	}
+
+	if err := readFile(path, &config); err != nil {
+		return config, err
+	}
+
+	// The file replaces values set in the environment as well as defaults, so
+	// set those again
+	var environment AppConfiguration
+	if err := envconfig.ProcessWith(ctx, &environment, lookuper); err != nil {
+		return config, err
+	}
+	applyEnvironment(reflect.ValueOf(&config).Elem(), reflect.ValueOf(environment), lookuper)
+
+	return config, nil
+}
+
+// readFile decodes a YAML file over the configuration, leaving the fields it
+// doesn't mention unchanged. Unknown keys are rejected so that typos aren't
+// silently ignored.
+func readFile(path string, config *AppConfiguration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open configuration file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read configuration file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnvironment copies the fields whose environment variables are set
+// from environment to config
+func applyEnvironment(config, environment reflect.Value, lookuper envconfig.Lookuper) {
+	for i := 0; i < config.NumField(); i++ {
+		field := config.Type().Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			applyEnvironment(config.Field(i), environment.Field(i), lookuper)
+			continue
+		}
+
+		key, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if key == "" {
+			continue
+		}
+
+		if _, ok := lookuper.Lookup(key); ok {
+			config.Field(i).Set(environment.Field(i))
+		}
+	}
+}
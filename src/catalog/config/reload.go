@@ -0,0 +1,142 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader loads the configuration again when the configuration file changes
+// or the process receives SIGHUP, and passes it to the functions registered
+// with OnReload. Only the settings those functions apply take effect, the
+// rest still need a restart.
+type Reloader struct {
+	path string
+
+	mu       sync.Mutex
+	modified time.Time
+	handlers []func(AppConfiguration)
+}
+
+// NewReloader creates a reloader for the configuration file named by FileEnv,
+// if any
+func NewReloader() *Reloader {
+	r := &Reloader{path: os.Getenv(FileEnv)}
+	r.modified, _ = r.lastModified()
+
+	return r
+}
+
+// OnReload registers a function to apply reloaded configuration
+func (r *Reloader) OnReload(apply func(AppConfiguration)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers = append(r.handlers, apply)
+}
+
+// Reload loads the configuration and applies it. Configuration that fails to
+// load is not applied, so a mistake in the file leaves the current settings
+// in place.
+func (r *Reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modified, _ = r.lastModified()
+
+	config, err := Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, apply := range r.handlers {
+		apply(config)
+	}
+
+	return nil
+}
+
+// Watch reloads the configuration on SIGHUP, and when the file has changed
+// when checked every interval, until the context is done
+func (r *Reloader) Watch(interval time.Duration, ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if r.path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.reload("SIGHUP", ctx)
+		case <-tick:
+			if r.changed() {
+				r.reload(r.path+" changed", ctx)
+			}
+		}
+	}
+}
+
+func (r *Reloader) reload(reason string, ctx context.Context) {
+	if err := r.Reload(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to reload configuration, keeping the current settings", "reason", reason, "error", err)
+		return
+	}
+
+	slog.InfoContext(ctx, "Reloaded configuration", "reason", reason)
+}
+
+// changed reports whether the file has been modified since it was last loaded
+func (r *Reloader) changed() bool {
+	modified, err := r.lastModified()
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !modified.Equal(r.modified)
+}
+
+// lastModified returns the modification time of the file, following symlinks
+// as used by Kubernetes config map volumes
+func (r *Reloader) lastModified() (time.Time, error) {
+	if r.path == "" {
+		return time.Time{}, nil
+	}
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
@@ -0,0 +1,71 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+)
+
+// SSMPathEnv is the environment variable holding the SSM Parameter Store
+// path to read configuration parameters from
+const SSMPathEnv = "RETAIL_CATALOG_CONFIG_SSM_PATH"
+
+// newSSMClient creates a Parameter Store client for the default AWS
+// configuration
+func newSSMClient() (ssmiface.SSMAPI, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return ssm.New(sess), nil
+}
+
+// LoadParameters reads every parameter under the path, decrypting
+// SecureString parameters. Each parameter is named after the environment
+// variable it sets, for example /retail-store/catalog/RETAIL_CATALOG_PERSISTENCE_ENDPOINT.
+func LoadParameters(ctx context.Context, client ssmiface.SSMAPI, parameterPath string) (envconfig.Lookuper, error) {
+	values := map[string]string{}
+
+	err := client.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path:           aws.String(strings.TrimSuffix(parameterPath, "/")),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			values[path.Base(aws.StringValue(parameter.Name))] = aws.StringValue(parameter.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters under %s: %w", parameterPath, err)
+	}
+
+	slog.Info("Read configuration parameters", "count", len(values), "path", parameterPath)
+
+	return envconfig.MapLookuper(values), nil
+}
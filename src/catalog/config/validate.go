@@ -0,0 +1,457 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// currencyCode matches the form of an ISO 4217 currency code
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// sha256Pattern matches a hex SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// ValidationError reports every problem found in the configuration, so that
+// they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validation collects problems with the configuration
+type validation struct {
+	problems []string
+}
+
+// check records the problem unless ok
+func (v *validation) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// url records a problem unless value is an absolute URL with one of the schemes
+func (v *validation) url(name, value string, schemes ...string) {
+	parsed, err := url.Parse(value)
+	v.check(err == nil && parsed.Host != "" && slices.Contains(schemes, parsed.Scheme),
+		"%s must be a URL starting with %s://, got %q", name, strings.Join(schemes, ":// or "), value)
+}
+
+// hostPort records a problem unless value is a host and port
+func (v *validation) hostPort(name, value string) {
+	host, port, err := net.SplitHostPort(value)
+	v.check(err == nil && host != "" && port != "", "%s must be a host and port such as db:3306, got %q", name, value)
+}
+
+// port records a problem unless value is a valid port number
+func (v *validation) port(name string, value int) {
+	v.check(value > 0 && value <= 65535, "%s must be between 1 and 65535, got %d", name, value)
+}
+
+// buckets records a problem unless value is empty or increasing positive
+// histogram bounds
+func (v *validation) buckets(name string, value []float64) {
+	valid := true
+	for i, bound := range value {
+		if bound <= 0 || (i > 0 && bound <= value[i-1]) {
+			valid = false
+		}
+	}
+	v.check(valid, "%s must be increasing positive numbers of seconds, such as 0.01,0.1,1, got %v", name, value)
+}
+
+// exclusive records a problem if more than one of the named options is set
+func (v *validation) exclusive(options map[string]bool) {
+	set := []string{}
+	for name, isSet := range options {
+		if isSet {
+			set = append(set, name)
+		}
+	}
+	slices.Sort(set)
+
+	v.check(len(set) <= 1, "only one of %s can be set", strings.Join(set, ", "))
+}
+
+// Validate checks the configuration as a whole, returning a ValidationError
+// listing every problem found. Problems are described in terms of the
+// environment variables, which are listed with the file keys in the README.
+func (c AppConfiguration) Validate() error {
+	v := &validation{}
+
+	c.validateServer(v)
+	c.validateAPI(v)
+	c.validateDatabase(v)
+	c.validateSearch(v)
+	c.validateFeatures(v)
+	c.validateChaos(v)
+	c.validateBulkhead(v)
+	c.validateLoadgen(v)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+
+	return nil
+}
+
+func (c AppConfiguration) validateServer(v *validation) {
+	v.port("PORT", c.Port)
+	for _, address := range c.Server.Addresses {
+		if path, ok := strings.CutPrefix(address, "unix:"); ok {
+			v.check(path != "", "RETAIL_CATALOG_SERVER_ADDRESSES must give a socket path after unix:")
+			continue
+		}
+		_, port, err := net.SplitHostPort(address)
+		number, _ := strconv.Atoi(port)
+		v.check(err == nil && number > 0 && number <= 65535,
+			"RETAIL_CATALOG_SERVER_ADDRESSES must contain host:port addresses, such as 127.0.0.1:8080 or :8080, or unix:/path/to/socket, got %q", address)
+	}
+	_, err := strconv.ParseUint(c.Server.SocketMode, 8, 32)
+	v.check(err == nil, "RETAIL_CATALOG_SERVER_SOCKET_MODE must be octal permissions such as 0660, got %q", c.Server.SocketMode)
+	v.check(c.Reload.Interval >= 0, "RETAIL_CATALOG_CONFIG_RELOAD_INTERVAL can't be negative")
+	v.check(c.Startup.WaitTimeout >= 0, "RETAIL_CATALOG_STARTUP_WAIT_TIMEOUT can't be negative")
+	v.check(c.Startup.InitialBackoff > 0, "RETAIL_CATALOG_STARTUP_INITIAL_BACKOFF must be positive")
+	v.check(c.Startup.MaxBackoff >= c.Startup.InitialBackoff,
+		"RETAIL_CATALOG_STARTUP_MAX_BACKOFF can't be less than RETAIL_CATALOG_STARTUP_INITIAL_BACKOFF")
+	v.check(c.Startup.WarmUpConcurrency > 0, "RETAIL_CATALOG_STARTUP_WARM_UP_CONCURRENCY must be positive")
+	v.check(c.Startup.WarmUpTimeout > 0, "RETAIL_CATALOG_STARTUP_WARM_UP_TIMEOUT must be positive")
+	v.check(c.Shutdown.Delay >= 0, "RETAIL_CATALOG_SHUTDOWN_DELAY can't be negative")
+	v.check(c.Shutdown.GracePeriod > 0, "RETAIL_CATALOG_SHUTDOWN_GRACE_PERIOD must be positive")
+
+	var level slog.Level
+	v.check(level.UnmarshalText([]byte(c.Logging.Level)) == nil,
+		"RETAIL_CATALOG_LOG_LEVEL must be debug, info, warn or error, got %q", c.Logging.Level)
+	v.check(slices.Contains([]string{"text", "console", "json"}, strings.ToLower(c.Logging.Format)),
+		"RETAIL_CATALOG_LOG_FORMAT must be text or json, got %q", c.Logging.Format)
+	v.check(c.AccessLog.SampleRate >= 0 && c.AccessLog.SampleRate <= 1,
+		"RETAIL_CATALOG_ACCESS_LOG_SAMPLE_RATE must be between 0 and 1, got %g", c.AccessLog.SampleRate)
+	v.check(c.AccessLog.SlowThreshold >= 0, "RETAIL_CATALOG_ACCESS_LOG_SLOW_THRESHOLD can't be negative")
+	v.buckets("RETAIL_CATALOG_METRICS_REQUEST_BUCKETS", c.Metrics.RequestBuckets)
+	v.buckets("RETAIL_CATALOG_METRICS_REPOSITORY_BUCKETS", c.Metrics.RepositoryBuckets)
+	v.check(slices.Contains([]string{"prometheus", "dogstatsd"}, c.Metrics.Exporter),
+		"RETAIL_CATALOG_METRICS_EXPORTER must be prometheus or dogstatsd, got %q", c.Metrics.Exporter)
+
+	if c.Debug.Enabled {
+		_, port, err := net.SplitHostPort(c.Debug.Address)
+		v.check(err == nil && port != "", "RETAIL_CATALOG_DEBUG_ADDRESS must be a host:port address such as 127.0.0.1:6060, got %q", c.Debug.Address)
+		v.check(port != strconv.Itoa(c.Port), "RETAIL_CATALOG_DEBUG_ADDRESS must use a different port from PORT, both are %s", port)
+	}
+
+	v.check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""),
+		"RETAIL_CATALOG_TLS_CERT_FILE and RETAIL_CATALOG_TLS_KEY_FILE must be set together")
+	if c.TLS.CertFile != "" {
+		v.check(c.TLS.ReloadInterval > 0, "RETAIL_CATALOG_TLS_RELOAD_INTERVAL must be positive")
+	}
+
+	v.check(c.HTTP2.Enabled || !c.HTTP2.Cleartext,
+		"RETAIL_CATALOG_HTTP2_CLEARTEXT requires RETAIL_CATALOG_HTTP2_ENABLED")
+
+	if c.GRPC.Enabled {
+		v.port("RETAIL_CATALOG_GRPC_PORT", c.GRPC.Port)
+		v.check(c.GRPC.Port != c.Port, "RETAIL_CATALOG_GRPC_PORT must differ from PORT, both are %d", c.Port)
+	}
+}
+
+func (c AppConfiguration) validateAPI(v *validation) {
+	v.check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+		"RETAIL_CATALOG_CORS_ALLOW_CREDENTIALS can't be used with the * origin, list the allowed origins instead")
+	v.check(c.CORS.MaxAge >= 0, "RETAIL_CATALOG_CORS_MAX_AGE can't be negative")
+	v.check(c.Compression.MinSize >= 0, "RETAIL_CATALOG_COMPRESSION_MIN_SIZE can't be negative")
+
+	if c.RateLimit.Enabled {
+		for _, limit := range []struct {
+			class string
+			rate  float64
+			burst int
+		}{
+			{"READ", c.RateLimit.ReadRate, c.RateLimit.ReadBurst},
+			{"SEARCH", c.RateLimit.SearchRate, c.RateLimit.SearchBurst},
+			{"WRITE", c.RateLimit.WriteRate, c.RateLimit.WriteBurst},
+		} {
+			v.check(limit.rate > 0, "RETAIL_CATALOG_RATE_LIMIT_%s_RPS must be positive", limit.class)
+			v.check(limit.burst >= 1, "RETAIL_CATALOG_RATE_LIMIT_%s_BURST must be at least 1", limit.class)
+		}
+	}
+
+	for _, route := range []struct {
+		group       string
+		timeout     time.Duration
+		maxBodySize int64
+	}{
+		{"READ", c.Routes.ReadTimeout, c.Routes.ReadMaxBodySize},
+		{"SEARCH", c.Routes.SearchTimeout, c.Routes.SearchMaxBodySize},
+		{"WRITE", c.Routes.WriteTimeout, c.Routes.WriteMaxBodySize},
+		{"ADMIN", c.Routes.AdminTimeout, c.Routes.AdminMaxBodySize},
+	} {
+		v.check(route.timeout >= 0, "RETAIL_CATALOG_ROUTES_%s_TIMEOUT can't be negative", route.group)
+		v.check(route.maxBodySize >= 0, "RETAIL_CATALOG_ROUTES_%s_MAX_BODY_SIZE can't be negative", route.group)
+	}
+
+	v.check(c.Idempotency.TTL > 0, "RETAIL_CATALOG_IDEMPOTENCY_TTL must be positive")
+
+	for _, currency := range slices.Sorted(maps.Keys(c.Currency.Rates)) {
+		v.check(currencyCode.MatchString(currency), "RETAIL_CATALOG_CURRENCY_RATES must be keyed by ISO 4217 currency codes, got %q", currency)
+		v.check(c.Currency.Rates[currency] > 0, "RETAIL_CATALOG_CURRENCY_RATES must be positive, got %g for %s", c.Currency.Rates[currency], currency)
+	}
+	if c.Currency.RatesURL != "" {
+		v.url("RETAIL_CATALOG_CURRENCY_RATES_URL", c.Currency.RatesURL, "https", "http")
+		v.check(c.Currency.RatesInterval > 0, "RETAIL_CATALOG_CURRENCY_RATES_INTERVAL must be positive")
+	}
+
+	v.url("RETAIL_CATALOG_SITEMAP_BASE_URL", c.Sitemap.BaseURL, "http", "https")
+	v.check(c.Sitemap.PageSize >= 1 && c.Sitemap.PageSize <= 50000,
+		"RETAIL_CATALOG_SITEMAP_PAGE_SIZE must be between 1 and 50000, the most a sitemap can hold, got %d", c.Sitemap.PageSize)
+
+	v.check(slices.Contains([]string{"embedded", "s3"}, c.Images.Store),
+		"RETAIL_CATALOG_IMAGES_STORE must be embedded or s3, got %q", c.Images.Store)
+	if c.Images.Store == "s3" {
+		v.check(c.Images.S3Bucket != "", "RETAIL_CATALOG_IMAGES_S3_BUCKET is required when RETAIL_CATALOG_IMAGES_STORE is s3")
+		if c.Images.S3Endpoint != "" {
+			v.url("RETAIL_CATALOG_IMAGES_S3_ENDPOINT", c.Images.S3Endpoint, "https", "http")
+		}
+		if c.Images.CDNURL != "" {
+			v.url("RETAIL_CATALOG_IMAGES_CDN_URL", c.Images.CDNURL, "https", "http")
+		} else {
+			v.check(c.Images.PresignExpiry >= time.Minute && c.Images.PresignExpiry <= 7*24*time.Hour,
+				"RETAIL_CATALOG_IMAGES_PRESIGN_EXPIRY must be between 1m and 168h, the longest S3 allows, got %s", c.Images.PresignExpiry)
+		}
+	}
+	v.check(c.Images.MaxSize > 0, "RETAIL_CATALOG_IMAGES_MAX_SIZE must be positive")
+
+	if c.Cache.Enabled {
+		v.check(c.Cache.Size > 0, "RETAIL_CATALOG_CACHE_SIZE must be positive")
+		v.check(c.Cache.TTL > 0, "RETAIL_CATALOG_CACHE_TTL must be positive")
+	}
+	if redis := c.Cache.Redis; redis.Endpoint != "" {
+		v.hostPort("RETAIL_CATALOG_CACHE_REDIS_ENDPOINT", redis.Endpoint)
+		v.check(redis.ProductTTL > 0, "RETAIL_CATALOG_CACHE_REDIS_PRODUCT_TTL must be positive")
+		v.check(redis.SearchTTL > 0, "RETAIL_CATALOG_CACHE_REDIS_SEARCH_TTL must be positive")
+		v.check(redis.ReferenceTTL > 0, "RETAIL_CATALOG_CACHE_REDIS_REFERENCE_TTL must be positive")
+		v.check(redis.Timeout > 0, "RETAIL_CATALOG_CACHE_REDIS_TIMEOUT must be positive")
+		v.check(redis.LockWait > 0, "RETAIL_CATALOG_CACHE_REDIS_LOCK_WAIT must be positive")
+	}
+
+	jwt := c.Auth.JWT
+	if jwt.Issuer != "" {
+		v.url("RETAIL_CATALOG_AUTH_JWT_ISSUER", jwt.Issuer, "https", "http")
+	}
+	if jwt.JWKSURL != "" {
+		v.check(jwt.Issuer != "", "RETAIL_CATALOG_AUTH_JWT_JWKS_URL requires RETAIL_CATALOG_AUTH_JWT_ISSUER")
+		v.url("RETAIL_CATALOG_AUTH_JWT_JWKS_URL", jwt.JWKSURL, "https", "http")
+	}
+
+	if c.Audit.Enabled {
+		v.check(slices.Contains([]string{"stdout", "file", "opensearch"}, c.Audit.Sink),
+			"RETAIL_CATALOG_AUDIT_SINK must be stdout, file or opensearch, got %q", c.Audit.Sink)
+		v.check(c.Audit.Sink != "file" || c.Audit.File != "", "RETAIL_CATALOG_AUDIT_FILE must be set for the file sink")
+		v.check(c.Audit.Sink != "opensearch" || c.Audit.Index != "", "RETAIL_CATALOG_AUDIT_INDEX must be set for the opensearch sink")
+	}
+
+	kafka := c.Events.Kafka
+	if len(kafka.Brokers) > 0 {
+		for _, broker := range kafka.Brokers {
+			v.hostPort("RETAIL_CATALOG_EVENTS_KAFKA_BROKERS", broker)
+		}
+		v.check(kafka.Topic != "", "RETAIL_CATALOG_EVENTS_KAFKA_TOPIC must be set")
+		v.check(slices.Contains([]string{"none", "scram", "iam"}, kafka.Auth),
+			"RETAIL_CATALOG_EVENTS_KAFKA_AUTH must be none, scram or iam, got %q", kafka.Auth)
+		v.check(kafka.Auth != "scram" || (kafka.Username != "" && kafka.Password != ""),
+			"RETAIL_CATALOG_EVENTS_KAFKA_USERNAME and RETAIL_CATALOG_EVENTS_KAFKA_PASSWORD must be set for scram authentication")
+		v.check(kafka.Auth != "iam" || kafka.TLS, "RETAIL_CATALOG_EVENTS_KAFKA_TLS must be enabled for iam authentication")
+		v.check(slices.Contains([]string{"json", "avro"}, kafka.Format),
+			"RETAIL_CATALOG_EVENTS_KAFKA_FORMAT must be json or avro, got %q", kafka.Format)
+		if kafka.SchemaRegistryURL != "" {
+			v.url("RETAIL_CATALOG_EVENTS_KAFKA_SCHEMA_REGISTRY_URL", kafka.SchemaRegistryURL, "https", "http")
+		}
+		v.check(kafka.BatchTimeout > 0, "RETAIL_CATALOG_EVENTS_KAFKA_BATCH_TIMEOUT must be positive")
+	}
+}
+
+func (c AppConfiguration) validateDatabase(v *validation) {
+	db := c.Database
+
+	switch db.Type {
+	case "mysql":
+		v.check(db.Endpoint != "", "RETAIL_CATALOG_PERSISTENCE_ENDPOINT must be set for the mysql provider")
+		if db.Endpoint != "" {
+			v.hostPort("RETAIL_CATALOG_PERSISTENCE_ENDPOINT", db.Endpoint)
+		}
+		if db.ReaderEndpoint != "" {
+			v.hostPort("RETAIL_CATALOG_PERSISTENCE_READER_ENDPOINT", db.ReaderEndpoint)
+		}
+		v.check(db.ConnectTimeout > 0, "RETAIL_CATALOG_PERSISTENCE_CONNECT_TIMEOUT must be positive")
+
+		v.exclusive(map[string]bool{
+			"RETAIL_CATALOG_PERSISTENCE_PASSWORD":        db.Password != "",
+			"RETAIL_CATALOG_PERSISTENCE_PASSWORD_SECRET": db.PasswordSecret != "",
+			"RETAIL_CATALOG_PERSISTENCE_VAULT_PATH":      db.VaultPath != "",
+			"RETAIL_CATALOG_PERSISTENCE_PASSWORD_FILE":   db.PasswordFile != "",
+			"RETAIL_CATALOG_PERSISTENCE_IAM_AUTH":        db.IAMAuth,
+		})
+		validateSecret(v, "PERSISTENCE", db.PasswordFile != "" || db.PasswordSecret != "" || db.VaultPath != "", db.VaultPath, db.SecretRefreshInterval)
+	case "sqlite":
+		v.check(db.Path != "", "RETAIL_CATALOG_PERSISTENCE_PATH must be set for the sqlite provider")
+	}
+
+	if db.Type != "mysql" {
+		v.check(db.ReaderEndpoint == "", "RETAIL_CATALOG_PERSISTENCE_READER_ENDPOINT is only supported by the mysql provider")
+	}
+
+	if seed := c.Seed; seed.URL != "" {
+		v.url("RETAIL_CATALOG_SEED_URL", seed.URL, "s3", "https", "http")
+		v.check(sha256Pattern.MatchString(seed.SHA256),
+			"RETAIL_CATALOG_SEED_SHA256 must be the SHA-256 checksum of the dataset as 64 hex digits when RETAIL_CATALOG_SEED_URL is set")
+		v.check(seed.Timeout > 0, "RETAIL_CATALOG_SEED_TIMEOUT must be positive")
+		v.check(slices.Contains([]string{"", "json", "csv"}, seed.Format), "RETAIL_CATALOG_SEED_FORMAT must be json or csv, got %q", seed.Format)
+		if seed.S3Endpoint != "" {
+			v.url("RETAIL_CATALOG_SEED_S3_ENDPOINT", seed.S3Endpoint, "https", "http")
+		}
+	}
+}
+
+func (c AppConfiguration) validateSearch(v *validation) {
+	if c.Search.Backend == "federated" {
+		v.check(len(c.Search.FederatedBackends) > 0,
+			"RETAIL_CATALOG_SEARCH_FEDERATED_BACKENDS must list the backends to query when RETAIL_CATALOG_SEARCH_BACKEND is federated")
+		v.check(!slices.Contains(c.Search.FederatedBackends, "federated"),
+			"RETAIL_CATALOG_SEARCH_FEDERATED_BACKENDS can't include federated")
+	}
+	v.check(c.Search.ReindexWorkers > 0, "RETAIL_CATALOG_SEARCH_REINDEX_WORKERS must be positive")
+	v.check(c.Search.ReindexBatchSize > 0, "RETAIL_CATALOG_SEARCH_REINDEX_BATCH_SIZE must be positive")
+
+	queue := c.Search.Queue
+	if queue.URL != "" {
+		v.url("RETAIL_CATALOG_SEARCH_QUEUE_URL", queue.URL, "https", "http")
+		if queue.Endpoint != "" {
+			v.url("RETAIL_CATALOG_SEARCH_QUEUE_ENDPOINT", queue.Endpoint, "https", "http")
+		}
+		v.check(queue.BatchSize >= 1 && queue.BatchSize <= 10, "RETAIL_CATALOG_SEARCH_QUEUE_BATCH_SIZE must be between 1 and 10, got %d", queue.BatchSize)
+		v.check(queue.WaitTime >= 0 && queue.WaitTime <= 20*time.Second, "RETAIL_CATALOG_SEARCH_QUEUE_WAIT_TIME must be between 0s and 20s, got %s", queue.WaitTime)
+	}
+
+	if !c.usesOpenSearch() {
+		return
+	}
+
+	search := c.OpenSearch
+	v.url("RETAIL_CATALOG_SEARCH_OS_ENDPOINT", search.Endpoint, "http", "https")
+	v.check(search.IndexName != "", "RETAIL_CATALOG_SEARCH_OS_INDEX must be set")
+
+	v.exclusive(map[string]bool{
+		"RETAIL_CATALOG_SEARCH_OS_PASSWORD":        search.Password != "",
+		"RETAIL_CATALOG_SEARCH_OS_PASSWORD_SECRET": search.PasswordSecret != "",
+		"RETAIL_CATALOG_SEARCH_OS_VAULT_PATH":      search.VaultPath != "",
+		"RETAIL_CATALOG_SEARCH_OS_PASSWORD_FILE":   search.PasswordFile != "",
+	})
+	validateSecret(v, "SEARCH_OS", search.PasswordFile != "" || search.PasswordSecret != "" || search.VaultPath != "", search.VaultPath, search.SecretRefreshInterval)
+
+	for _, field := range slices.Sorted(maps.Keys(search.Boosts)) {
+		v.check(search.Boosts[field] > 0, "RETAIL_CATALOG_SEARCH_OS_BOOSTS must be positive, got %g for %s", search.Boosts[field], field)
+	}
+
+	v.check(search.HedgeDelay >= 0, "RETAIL_CATALOG_SEARCH_OS_HEDGE_DELAY can't be negative")
+	v.check(search.HedgeBudget >= 0 && search.HedgeBudget <= 1,
+		"RETAIL_CATALOG_SEARCH_OS_HEDGE_BUDGET must be between 0 and 1, got %g", search.HedgeBudget)
+	v.check(search.BreakerThreshold >= 0, "RETAIL_CATALOG_SEARCH_OS_BREAKER_THRESHOLD can't be negative")
+	v.check(search.BreakerThreshold == 0 || search.BreakerCooldown > 0,
+		"RETAIL_CATALOG_SEARCH_OS_BREAKER_COOLDOWN must be positive when the breaker is enabled")
+	v.check(search.BulkRetries >= 0, "RETAIL_CATALOG_SEARCH_OS_BULK_RETRIES can't be negative")
+	v.check(search.BulkMinBatchSize > 0, "RETAIL_CATALOG_SEARCH_OS_BULK_MIN_BATCH_SIZE must be positive")
+	v.check(search.BulkMaxDelay >= 0, "RETAIL_CATALOG_SEARCH_OS_BULK_MAX_DELAY can't be negative")
+}
+
+// usesOpenSearch reports whether OpenSearch is the search backend or one of
+// the federated backends
+func (c AppConfiguration) usesOpenSearch() bool {
+	switch c.Search.Backend {
+	case "":
+		return c.OpenSearch.Enabled
+	case "federated":
+		return slices.Contains(c.Search.FederatedBackends, "opensearch")
+	default:
+		return c.Search.Backend == "opensearch"
+	}
+}
+
+// validateSecret checks the settings for reading credentials that can be
+// rotated, from a file, Secrets Manager or Vault
+func validateSecret(v *validation, prefix string, rotated bool, vaultPath string, refreshInterval time.Duration) {
+	if !rotated {
+		return
+	}
+
+	v.check(refreshInterval > 0, "RETAIL_CATALOG_%s_SECRET_REFRESH_INTERVAL must be positive", prefix)
+
+	if vaultPath != "" {
+		_, ok := os.LookupEnv("VAULT_TOKEN")
+		v.check(ok, "VAULT_TOKEN must be set to read RETAIL_CATALOG_%s_VAULT_PATH", prefix)
+	}
+}
+
+func (c AppConfiguration) validateChaos(v *validation) {
+	chaos := c.Chaos
+	v.check(chaos.DatabaseLatency >= 0, "RETAIL_CATALOG_CHAOS_DATABASE_LATENCY can't be negative")
+	v.check(chaos.DatabaseErrorRate >= 0 && chaos.DatabaseErrorRate <= 1,
+		"RETAIL_CATALOG_CHAOS_DATABASE_ERROR_RATE must be between 0 and 1, got %g", chaos.DatabaseErrorRate)
+	v.check(chaos.OpenSearchLatency >= 0, "RETAIL_CATALOG_CHAOS_OPENSEARCH_LATENCY can't be negative")
+	v.check(chaos.OpenSearchErrorRate >= 0 && chaos.OpenSearchErrorRate <= 1,
+		"RETAIL_CATALOG_CHAOS_OPENSEARCH_ERROR_RATE must be between 0 and 1, got %g", chaos.OpenSearchErrorRate)
+}
+
+func (c AppConfiguration) validateBulkhead(v *validation) {
+	bulkhead := c.Bulkhead
+	v.check(bulkhead.DatabaseLimit >= 0, "RETAIL_CATALOG_BULKHEAD_DATABASE_LIMIT can't be negative")
+	v.check(bulkhead.DatabaseWait >= 0, "RETAIL_CATALOG_BULKHEAD_DATABASE_WAIT can't be negative")
+	v.check(bulkhead.OpenSearchLimit >= 0, "RETAIL_CATALOG_BULKHEAD_OPENSEARCH_LIMIT can't be negative")
+	v.check(bulkhead.OpenSearchWait >= 0, "RETAIL_CATALOG_BULKHEAD_OPENSEARCH_WAIT can't be negative")
+}
+
+func (c AppConfiguration) validateLoadgen(v *validation) {
+	loadgen := c.Loadgen
+	v.url("RETAIL_CATALOG_LOADGEN_TARGET", loadgen.Target, "http", "https")
+	v.check(loadgen.RPS > 0, "RETAIL_CATALOG_LOADGEN_RPS must be positive")
+	v.check(loadgen.Duration >= 0, "RETAIL_CATALOG_LOADGEN_DURATION can't be negative")
+	v.check(loadgen.Workers > 0, "RETAIL_CATALOG_LOADGEN_WORKERS must be positive")
+	v.check(loadgen.Timeout > 0, "RETAIL_CATALOG_LOADGEN_TIMEOUT must be positive")
+}
+
+func (c AppConfiguration) validateFeatures(v *validation) {
+	appConfig := c.Features.AppConfig
+	if appConfig.Application == "" && appConfig.Environment == "" && appConfig.Profile == "" {
+		return
+	}
+
+	v.check(appConfig.Application != "" && appConfig.Environment != "" && appConfig.Profile != "",
+		"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_APPLICATION, _ENVIRONMENT and _PROFILE must be set together")
+	v.check(appConfig.PollInterval >= 15*time.Second,
+		"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_POLL_INTERVAL must be at least 15s, the shortest AppConfig allows")
+}
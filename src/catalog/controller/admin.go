@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// StartReindex godoc
+// @Summary Start a reindex
+// @Description Rebuild the search index from the catalog database in the background. The Location header links to the job's progress.
+// @Tags admin
+// @Produce  json
+// @Success 202 {object} model.ReindexJob
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/reindex [post]
+func (c *Controller) StartReindex(ctx *gin.Context) {
+	if !c.api.IsSearchEnabled() {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("search is not enabled"))
+		return
+	}
+
+	job, err := c.api.StartReindex(ctx.Request.Context())
+	if errors.Is(err, api.ErrReindexInProgress) {
+		ctx.Header("Location", "/admin/reindex/"+job.ID)
+		httputil.NewError(ctx, http.StatusConflict, err)
+		return
+	} else if errors.Is(err, api.ErrShuttingDown) {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Header("Location", "/admin/reindex/"+job.ID)
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetReindexJob godoc
+// @Summary Get reindex progress
+// @Description Get the progress of a reindex job, with an estimate of the time left while it runs
+// @Tags admin
+// @Produce  json
+// @Param jobId path string true "Reindex job ID"
+// @Success 200 {object} model.ReindexJob
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/reindex/{jobId} [get]
+func (c *Controller) GetReindexJob(ctx *gin.Context) {
+	job, err := c.api.GetReindexJob(ctx.Param("jobId"))
+	if err != nil {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// ResetCatalog godoc
+// @Summary Reset catalog
+// @Description Restore the catalog and search index to the bundled seed data, discarding all changes
+// @Tags admin
+// @Produce  json
+// @Success 204
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/reset [post]
+func (c *Controller) ResetCatalog(ctx *gin.Context) {
+	err := c.api.ResetCatalog(ctx.Request.Context())
+	switch {
+	case err == nil:
+		ctx.Status(http.StatusNoContent)
+	case errors.Is(err, api.ErrReindexInProgress):
+		httputil.NewError(ctx, http.StatusConflict, err)
+	case errors.Is(err, api.ErrReadOnly):
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+	default:
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// maxAttributeFilters is the most attribute filters a search can have
+const maxAttributeFilters = 10
+
+// getAttributeFilters reads the attribute query parameters, each written as
+// name:value or name:min..max, that search results must all match. It
+// returns nil if there are none.
+func getAttributeFilters(ctx *gin.Context) ([]model.AttributeFilter, error) {
+	params := ctx.QueryArray("attribute")
+	if len(params) == 0 {
+		return nil, nil
+	}
+	if len(params) > maxAttributeFilters {
+		return nil, fmt.Errorf("at most %d attribute filters can be given", maxAttributeFilters)
+	}
+
+	filters := make([]model.AttributeFilter, len(params))
+	for i, param := range params {
+		filter, err := model.ParseAttributeFilter(param)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = filter
+	}
+	return filters, nil
+}
@@ -0,0 +1,139 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCategories godoc
+// @Summary List categories
+// @Description Get the category tree, with subcategories nested under their parents
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} model.CategoryNode
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/categories [get]
+func (c *Controller) ListCategories(ctx *gin.Context) {
+	tree, err := c.api.GetCategoryTree(ctx.Request.Context())
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	jsonWithETag(ctx, tree)
+}
+
+// GetCategory godoc
+// @Summary Get category
+// @Description Get a category with its breadcrumbs, the categories from the top of the tree down to it
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Category name"
+// @Success 200 {object} model.Category
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/categories/{name} [get]
+func (c *Controller) GetCategory(ctx *gin.Context) {
+	category, err := c.api.GetCategory(ctx.Param("name"), ctx.Request.Context())
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	jsonWithETag(ctx, category)
+}
+
+// GetCategoryProducts godoc
+// @Summary Get category products
+// @Description Get the products in a category, including those in its subcategories
+// @Tags catalog
+// @Accept  json
+// @Produce  json,xml,application/x-protobuf
+// @Param name path string true "Category name"
+// @Param sort query string false "Sort by name or price, prefixed with - for descending, or newest first"
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Param cursor query string false "Cursor for the next page, from the Link header of the previous response"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Param If-None-Match header string false "ETag of a previously fetched response"
+// @Success 200 {array} model.Product
+// @Success 304
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/categories/{name}/products [get]
+func (c *Controller) GetCategoryProducts(ctx *gin.Context) {
+	order, err := getOrder(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	paging, err := getPagination(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	fields, err := parseFields(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := c.api.GetCategoryProducts(ctx.Param("name"), order, paging.cursor, paging.page, paging.size, ctx.Request.Context())
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	} else if errors.Is(err, api.ErrInvalidCursor) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	setNextLink(ctx, page.NextCursor)
+	writeProducts(ctx, c.expand(localize(c.convertPrices(page.Products, code, ctx), locale, ctx), ctx), page.NextCursor, fields)
+}
@@ -0,0 +1,72 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// maxChanges limits how many changes are returned in one response
+const maxChanges = 1000
+
+// GetChanges godoc
+// @Summary Changes since
+// @Description Get the products created, updated or deleted since a point in time, oldest first. Products that aren't active are left out of their changes. Pass the cursor of the response as since to continue from where it ended.
+// @Tags catalog
+// @Produce  json
+// @Param since query string false "RFC 3339 timestamp or the cursor of an earlier response, defaults to the beginning of the log"
+// @Param size query int false "Maximum number of changes to return"
+// @Success 200 {object} model.ChangesResponse
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/changes [get]
+func (c *Controller) GetChanges(ctx *gin.Context) {
+	size, err := getQueryInt("size", 100, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if size < 1 || size > maxChanges {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("size must be between 1 and %d", maxChanges))
+		return
+	}
+
+	changes, err := c.api.GetChanges(ctx.Query("since"), size, ctx.Request.Context())
+	if errors.Is(err, api.ErrInvalidSince) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	} else if errors.Is(err, api.ErrChangesNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range changes.Changes {
+		changes.Changes[i].Product = feedProduct(changes.Changes[i].Product, ctx)
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
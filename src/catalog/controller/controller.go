@@ -17,26 +17,43 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
 	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
 	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
 )
 
+// degradedHeader marks responses answered by a fallback during an outage,
+// naming the dependency that failed
+const degradedHeader = "X-Catalog-Degraded"
+
 // Controller example
 type Controller struct {
-	api *api.CatalogAPI
+	api          *api.CatalogAPI
+	rates        *currency.Rates
+	maxImageSize int64
 }
 
 // NewController example
 func NewController(api *api.CatalogAPI) (*Controller, error) {
 	return &Controller{
-		api: api,
+		api:          api,
+		rates:        currency.New(nil),
+		maxImageSize: DefaultMaxImageSize,
 	}, nil
 }
 
@@ -45,46 +62,87 @@ func NewController(api *api.CatalogAPI) (*Controller, error) {
 // @Description Get catalog
 // @Tags catalog
 // @Accept  json
-// @Produce  json
+// @Produce  json,xml,application/x-protobuf
+// @Param ids query string false "Comma-separated IDs of products to fetch, instead of listing the catalog"
 // @Param tags query string false "Tagged products to include"
+// @Param category query string false "Category of products to include, with its subcategories"
+// @Param minPrice query int false "Minimum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param maxPrice query int false "Maximum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param variant query []string false "Attribute values one of the product's variants must have, as name:value" collectionFormat(multi)
+// @Param inStock query bool false "Only include products with stock if true, or without if false"
+// @Param status query []string false "Statuses of products to include, on /admin/products only" collectionFormat(multi)
+// @Param sort query string false "Sort by name or price, prefixed with - for descending, or newest first"
 // @Param order query string false "Order of response"
 // @Param page query int false "Page number"
 // @Param size query int false "Page size"
+// @Param cursor query string false "Cursor for the next page, from the Link header of the previous response"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Param If-None-Match header string false "ETag of a previously fetched response"
 // @Success 200 {array} model.Product
+// @Success 304
 // @Failure 400 {object} httputil.HTTPError
 // @Failure 404 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /catalog/products [get]
+// @Router /admin/products [get]
 func (c *Controller) GetProducts(ctx *gin.Context) {
-	var tags []string
+	if ids := ctx.Query("ids"); ids != "" {
+		c.getProductsByIDs(parseIDs(ids), ctx)
+		return
+	}
 
-	tagString := ctx.Query("tags")
-	if len(tagString) > 0 {
-		tags = strings.Split(tagString, ",")
-	} else {
-		tags = []string{}
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
 	}
 
-	order := ctx.Query("order")
+	filter, err := c.getProductFilter(code, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
 
-	page, err := getQueryInt("page", 1, ctx)
+	order, err := getOrder(ctx)
 	if err != nil {
 		httputil.NewError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	size, err := getQueryInt("size", 10, ctx)
+	paging, err := getPagination(ctx)
 	if err != nil {
 		httputil.NewError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	products, err := c.api.GetProducts(tags, order, page, size, ctx.Request.Context())
+	fields, err := parseFields(ctx)
 	if err != nil {
-		httputil.NewError(ctx, http.StatusNotFound, err)
+		httputil.NewError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, products)
+
+	page, err := c.api.GetProductsPage(filter, order, paging.cursor, paging.page, paging.size, ctx.Request.Context())
+	if errors.Is(err, api.ErrInvalidCursor) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	setNextLink(ctx, page.NextCursor)
+	writeProducts(ctx, c.expand(localize(c.convertPrices(page.Products, code, ctx), locale, ctx), ctx), page.NextCursor, fields)
 }
 
 // GetProducts godoc
@@ -92,22 +150,246 @@ func (c *Controller) GetProducts(ctx *gin.Context) {
 // @Description Get catalog
 // @Tags catalog
 // @Accept  json
-// @Produce  json
+// @Produce  json,xml,application/x-protobuf
 // @Param id path string true "product ID"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Param If-None-Match header string false "ETag of a previously fetched response"
 // @Success 200 {object} model.Product
+// @Success 304
 // @Failure 400 {object} httputil.HTTPError
 // @Failure 404 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /catalog/products/{id} [get]
+// @Router /admin/products/{id} [get]
 func (c *Controller) GetProduct(ctx *gin.Context) {
 	id := ctx.Param("id")
 
+	fields, err := parseFields(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
 	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
 	if err != nil {
-		httputil.NewError(ctx, http.StatusNotFound, err)
+		readError(ctx, err)
+		return
+	}
+
+	writeProduct(ctx, c.expand(localize(c.convertPrices([]model.Product{*product}, code, ctx), locale, ctx), ctx)[0], fields)
+}
+
+// CreateProduct godoc
+// @Summary Create product
+// @Description Add a product to the catalog, generating an ID if none is given
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param product body model.ProductRequest true "Product"
+// @Param Idempotency-Key header string false "Unique key for the request, so that retries with the same key return the original response"
+// @Success 201 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 422 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products [post]
+func (c *Controller) CreateProduct(ctx *gin.Context) {
+	var request model.ProductRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	id := request.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	product, err := c.api.CreateProduct(request.ToProduct(id), ctx.Request.Context())
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", ctx.Request.URL.Path+"/"+product.ID)
+	ctx.JSON(http.StatusCreated, productJSON(ctx, c.expand([]model.Product{*product}, ctx)[0]))
+}
+
+// UpdateProduct godoc
+// @Summary Update product
+// @Description Replace the details of a product
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param product body model.ProductRequest true "Product"
+// @Param If-Match header string false "ETag of the product the change is based on"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 412 {object} httputil.HTTPError
+// @Failure 428 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id} [put]
+func (c *Controller) UpdateProduct(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	var request model.ProductRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.ID != "" && request.ID != id {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("product ID in body does not match path"))
+		return
+	}
+
+	version, ok := c.expectedVersion(id, ctx)
+	if !ok {
+		return
+	}
+
+	product := request.ToProduct(id)
+	product.Version = version
+
+	product, err := c.api.UpdateProduct(product, ctx.Request.Context())
+	if err != nil {
+		writeError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, product)
+
+	c.jsonWithProductETag(ctx, product)
+}
+
+// PatchProduct godoc
+// @Summary Patch product
+// @Description Change some fields of a product with a JSON merge patch (RFC 7386). Fields in the patch replace those of the product, null removes them, and fields not in the patch are unchanged.
+// @Tags catalog
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param patch body model.ProductRequest true "Merge patch"
+// @Param If-Match header string false "ETag of the product the change is based on"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 412 {object} httputil.HTTPError
+// @Failure 415 {object} httputil.HTTPError
+// @Failure 428 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id} [patch]
+func (c *Controller) PatchProduct(ctx *gin.Context) {
+	if contentType := ctx.ContentType(); contentType != "application/merge-patch+json" && contentType != "application/json" {
+		httputil.NewError(ctx, http.StatusUnsupportedMediaType, fmt.Errorf("patches must be application/merge-patch+json"))
+		return
+	}
+
+	patch, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	validate := func(request *model.ProductRequest) error {
+		return binding.Validator.ValidateStruct(request)
+	}
+
+	version, ok := c.expectedVersion(ctx.Param("id"), ctx)
+	if !ok {
+		return
+	}
+
+	product, err := c.api.PatchProduct(ctx.Param("id"), version, patch, validate, ctx.Request.Context())
+	if errors.Is(err, api.ErrInvalidPatch) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	c.jsonWithProductETag(ctx, product)
+}
+
+// expectedVersion resolves the If-Match header to the version of the product
+// a change is based on, or 0 for an unconditional change when it isn't sent.
+// If the product has changed since, it responds 412 Precondition Failed and
+// returns false.
+func (c *Controller) expectedVersion(id string, ctx *gin.Context) (int, bool) {
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch == "" {
+		return 0, true
+	}
+
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err != nil {
+		readError(ctx, err)
+		return 0, false
+	}
+
+	etag, err := productETag(ctx, c.expand(c.api.WithPromotions([]model.Product{*product}, ctx.Request.Context()), ctx)[0])
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return 0, false
+	}
+
+	if !etagMatches(ifMatch, etag) {
+		httputil.NewError(ctx, http.StatusPreconditionFailed, repository.ErrVersionConflict)
+		return 0, false
+	}
+
+	return product.Version, true
+}
+
+// jsonWithProductETag writes a changed product with the ETag that identifies
+// it in later conditional requests
+func (c *Controller) jsonWithProductETag(ctx *gin.Context, product *model.Product) {
+	product = &c.expand(c.api.WithPromotions([]model.Product{*product}, ctx.Request.Context()), ctx)[0]
+	if etag, err := productETag(ctx, *product); err == nil {
+		ctx.Header("ETag", etag)
+	}
+	ctx.JSON(http.StatusOK, productJSON(ctx, *product))
+}
+
+// DeleteProduct godoc
+// @Summary Delete product
+// @Description Remove a product from the catalog
+// @Tags catalog
+// @Param id path string true "product ID"
+// @Success 204
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id} [delete]
+func (c *Controller) DeleteProduct(ctx *gin.Context) {
+	if err := c.api.DeleteProduct(ctx.Param("id"), ctx.Request.Context()); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
 }
 
 // CatalogSize godoc
@@ -117,24 +399,34 @@ func (c *Controller) GetProduct(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param tags query string false "Tagged products to include"
+// @Param category query string false "Category of products to include, with its subcategories"
+// @Param minPrice query int false "Minimum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param maxPrice query int false "Maximum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param variant query []string false "Attribute values one of the product's variants must have, as name:value" collectionFormat(multi)
+// @Param inStock query bool false "Only include products with stock if true, or without if false"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
 // @Success 200 {object} model.CatalogSizeResponse
 // @Failure 400 {object} httputil.HTTPError
 // @Failure 404 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /catalog/size [get]
 func (c *Controller) CatalogSize(ctx *gin.Context) {
-	var tags []string
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
 
-	tagString := ctx.Query("tags")
-	if len(tagString) > 0 {
-		tags = strings.Split(tagString, ",")
-	} else {
-		tags = []string{}
+	filter, err := c.getProductFilter(code, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
 	}
 
-	count, err := c.api.GetSize(tags, ctx.Request.Context())
+	count, err := c.api.GetSize(filter, ctx.Request.Context())
 	if err != nil {
-		httputil.NewError(ctx, http.StatusNotFound, err)
+		readError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, model.CatalogSizeResponse{
@@ -144,19 +436,19 @@ func (c *Controller) CatalogSize(ctx *gin.Context) {
 
 // ListTags godoc
 // @Summary List tags
-// @Description get tags
+// @Description List tags with the number of products that have each
 // @Tags catalog
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} model.Tag
+// @Success 200 {array} model.TagCount
 // @Failure 400 {object} httputil.HTTPError
 // @Failure 404 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /catalog/tags [get]
 func (c *Controller) ListTags(ctx *gin.Context) {
-	accounts, err := c.api.GetTags(ctx.Request.Context())
+	accounts, err := c.api.GetTagCounts(ctx.Request.Context())
 	if err != nil {
-		httputil.NewError(ctx, http.StatusNotFound, err)
+		readError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, accounts)
@@ -167,52 +459,290 @@ func (c *Controller) ListTags(ctx *gin.Context) {
 // @Description Search products by keyword using OpenSearch
 // @Tags catalog
 // @Accept  json
-// @Produce  json
+// @Produce  json,xml,application/x-protobuf
 // @Param keyword query string true "Search keyword"
+// @Param category query string false "Category of products to include, with its subcategories"
+// @Param minPrice query int false "Minimum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param maxPrice query int false "Maximum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param variant query []string false "Attribute values one of the product's variants must have, as name:value" collectionFormat(multi)
+// @Param inStock query bool false "Only include products with stock if true, or without if false"
+// @Param attribute query []string false "Product attribute filters as name:value, or name:min..max for numbers" collectionFormat(multi)
+// @Param minRating query number false "Lowest average rating of the products to include"
+// @Param onSale query bool false "Only include products a running promotion applies to if true, or those none does if false"
+// @Param sort query string false "Sort by relevance, by average rating with rating or -rating, or newest first"
 // @Param page query int false "Page number"
 // @Param size query int false "Page size"
+// @Param cursor query string false "Cursor for the next page of results, from the previous response"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
 // @Success 200 {array} model.Product
 // @Failure 400 {object} httputil.HTTPError
 // @Failure 404 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
 // @Failure 500 {object} httputil.HTTPError
 // @Router /catalog/search [get]
 func (c *Controller) SearchProducts(ctx *gin.Context) {
+	result, ok := c.searchProducts(false, ctx)
+	if !ok {
+		return
+	}
+
+	setNextLink(ctx, result.nextCursor)
+	writeSearchResults(ctx, result.products, result.nextCursor, result.fields)
+}
+
+// SearchProductsV2 godoc
+// @Summary Search products
+// @Description Search products by keyword, returning the results in a response envelope
+// @Tags catalog
+// @Accept  json
+// @Produce  json,xml,application/x-protobuf
+// @Param keyword query string true "Search keyword"
+// @Param category query string false "Category of products to include, with its subcategories"
+// @Param minPrice query int false "Minimum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param maxPrice query int false "Maximum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param variant query []string false "Attribute values one of the product's variants must have, as name:value" collectionFormat(multi)
+// @Param inStock query bool false "Only include products with stock if true, or without if false"
+// @Param attribute query []string false "Product attribute filters as name:value, or name:min..max for numbers" collectionFormat(multi)
+// @Param minRating query number false "Lowest average rating of the products to include"
+// @Param onSale query bool false "Only include products a running promotion applies to if true, or those none does if false"
+// @Param sort query string false "Sort by relevance, by average rating with rating or -rating, or newest first"
+// @Param status query []string false "Statuses of products to include, on /admin/search only" collectionFormat(multi)
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Param cursor query string false "Cursor for the next page of results, from the previous response"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Success 200 {object} model.SearchResponse
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /v2/catalog/search [get]
+// @Router /admin/search [get]
+func (c *Controller) SearchProductsV2(ctx *gin.Context) {
+	result, ok := c.searchProducts(true, ctx)
+	if !ok {
+		return
+	}
+
+	response := model.SearchResponse{
+		Products:   result.products,
+		Page:       result.page,
+		Size:       result.size,
+		NextCursor: result.nextCursor,
+		Facets:     result.facets,
+	}
+
+	negotiate(ctx, result.fields, representations{
+		json: func() (any, error) {
+			if result.fields == nil {
+				return response, nil
+			}
+
+			products, err := selectFieldsList(ctx, result.products, result.fields)
+			if err != nil {
+				return nil, err
+			}
+
+			selected := gin.H{
+				"products": products,
+				"page":     result.page,
+				"size":     result.size,
+			}
+			if result.nextCursor != "" {
+				selected["nextCursor"] = result.nextCursor
+			}
+			if result.facets != nil {
+				selected["facets"] = result.facets
+			}
+			return selected, nil
+		},
+		xml: func() any { return response },
+		proto: func() proto.Message {
+			return &catalogv1.SearchProductsResponse{
+				Products:      model.ProductsToProto(result.products),
+				NextPageToken: result.nextCursor,
+			}
+		},
+	})
+}
+
+type searchResult struct {
+	products   []model.Product
+	page       int
+	size       int
+	nextCursor string
+	fields     []string
+	facets     map[string]map[string]int
+}
+
+// searchProducts runs the search described by the query parameters, writing
+// an error response and returning false if it could not be completed. Facets
+// are counted if the response has room for them and the provider can.
+func (c *Controller) searchProducts(withFacets bool, ctx *gin.Context) (*searchResult, bool) {
 	if !c.api.IsSearchEnabled() {
 		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("Search is not enabled"))
-		return
+		return nil, false
 	}
 
 	keyword := ctx.Query("keyword")
 
 	if keyword == "" {
 		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("keyword query parameter is required"))
-		return
+		return nil, false
 	}
 
-	page, err := getQueryInt("page", 1, ctx)
+	paging, err := getPagination(ctx)
 	if err != nil {
 		httputil.NewError(ctx, http.StatusBadRequest, err)
-		return
+		return nil, false
 	}
 
-	size, err := getQueryInt("size", 10, ctx)
+	fields, err := parseFields(ctx)
 	if err != nil {
 		httputil.NewError(ctx, http.StatusBadRequest, err)
-		return
+		return nil, false
 	}
 
-	products, err := c.api.SearchProducts(keyword, page, size, ctx.Request.Context())
+	code, err := c.requestedCurrency(ctx)
 	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	minPrice, maxPrice, err := getPriceRange(code, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	variants, err := getVariantAttributes(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	inStock, err := getOptionalQueryBool("inStock", ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	attributes, err := getAttributeFilters(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	minRating, err := getMinRating(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	order, err := getSearchOrder(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	statuses, err := getStatuses(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	onSale, err := getOptionalQueryBool("onSale", ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	query := repository.SearchQuery{
+		Keyword:           keyword,
+		VariantAttributes: variants,
+		InStock:           inStock,
+		Attributes:        attributes,
+		MinRating:         minRating,
+		Locale:            locale,
+		Order:             order,
+		Fields:            pricedFields(localizedFields(fields, locale)),
+		Facets:            withFacets,
+	}
+	if name := ctx.Query("category"); name != "" {
+		category, err := c.api.GetCategory(name, ctx.Request.Context())
+		if errors.Is(err, repository.ErrCategoryNotFound) {
+			httputil.NewError(ctx, http.StatusBadRequest, err)
+			return nil, false
+		} else if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return nil, false
+		}
+		query.Category = category
+	}
+	if seesAllStatuses(ctx) {
+		// Without any statuses asked for, every status is searched
+		query.Statuses = statuses
+		query.AnyStatus = len(statuses) == 0
+	}
+	if onSale != nil {
+		sale, err := c.api.Sale(ctx.Request.Context())
+		if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return nil, false
+		}
+		query.OnSale = onSale
+		query.Sale = sale
+	}
+	if code != "" && (minPrice != nil || maxPrice != nil) {
+		query.PriceRanges = c.priceRanges(minPrice, maxPrice, code)
+	} else if minPrice != nil || maxPrice != nil {
+		query.PriceRanges = []repository.PriceRange{{Min: minPrice, Max: maxPrice}}
+	}
+
+	searchCtx, degraded := repository.TrackDegraded(ctx.Request.Context())
+	page, err := c.api.SearchProductsPage(query, paging.cursor, paging.page, paging.size, searchCtx)
+	if errors.Is(err, api.ErrInvalidCursor) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	} else if errors.Is(err, repository.ErrBreakerOpen) {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, err)
+		return nil, false
+	} else if err != nil {
 		httputil.NewError(ctx, http.StatusInternalServerError, err)
-		return
+		return nil, false
 	}
 
-	if products == nil {
+	if page.Products == nil {
 		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("search service is not available"))
-		return
+		return nil, false
 	}
 
-	ctx.JSON(http.StatusOK, products)
+	if degraded() {
+		ctx.Header(degradedHeader, "search")
+	}
+
+	return &searchResult{
+		products:   c.expand(localize(c.convertPrices(page.Products, code, ctx), locale, ctx), ctx),
+		page:       paging.page,
+		size:       paging.size,
+		nextCursor: page.NextCursor,
+		fields:     fields,
+		facets:     page.Facets,
+	}, true
 }
 
 // ReindexProducts godoc
@@ -230,7 +760,7 @@ func (c *Controller) ReindexProducts(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.api.Reindex(); err != nil {
+	if err := c.api.Reindex(ctx.Request.Context()); err != nil {
 		httputil.NewError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -238,6 +768,177 @@ func (c *Controller) ReindexProducts(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "reindex completed successfully"})
 }
 
+// CheckConsistency godoc
+// @Summary Check search index consistency
+// @Description Compare the products in the database with the documents in the search index
+// @Tags catalog
+// @Produce  json
+// @Success 200 {object} repository.ReconcileReport
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/reconcile [get]
+func (c *Controller) CheckConsistency(ctx *gin.Context) {
+	c.reconcile(false, ctx)
+}
+
+// ReconcileProducts godoc
+// @Summary Reconcile search index
+// @Description Re-index products missing from or stale in the search index and remove orphaned documents
+// @Tags catalog
+// @Produce  json
+// @Success 200 {object} repository.ReconcileReport
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/reconcile [post]
+func (c *Controller) ReconcileProducts(ctx *gin.Context) {
+	c.reconcile(true, ctx)
+}
+
+func (c *Controller) reconcile(repair bool, ctx *gin.Context) {
+	if !c.api.IsDualWriteEnabled() {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("dual-write is not enabled"))
+		return
+	}
+
+	report, err := c.api.Reconcile(repair, ctx.Request.Context())
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// expand returns the products with the URLs of their images and the
+// breadcrumbs of their categories filled in
+func (c *Controller) expand(products []model.Product, ctx *gin.Context) []model.Product {
+	return c.api.WithBreadcrumbs(c.api.WithImageURLs(products, ctx.Request.Context()), ctx.Request.Context())
+}
+
+// readError maps repository errors from read operations to HTTP statuses.
+// Only a missing record is reported as not found, so that a failing or slow
+// database shows as a server error.
+func readError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrProductNotFound) {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	}
+	httputil.NewError(ctx, http.StatusInternalServerError, err)
+}
+
+// writeError maps repository errors from write operations to HTTP statuses
+func writeError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrProductExists), errors.Is(err, repository.ErrSKUExists), errors.Is(err, repository.ErrEANExists), errors.Is(err, repository.ErrInsufficientStock):
+		httputil.NewError(ctx, http.StatusConflict, err)
+	case errors.Is(err, repository.ErrProductNotFound), errors.Is(err, repository.ErrVariantNotFound):
+		httputil.NewError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, repository.ErrVersionConflict):
+		httputil.NewError(ctx, http.StatusPreconditionFailed, err)
+	case errors.Is(err, repository.ErrInvalidStatusTransition):
+		httputil.NewError(ctx, http.StatusConflict, err)
+	case errors.Is(err, repository.ErrUnknownTag), errors.Is(err, repository.ErrUnknownCategory), errors.Is(err, repository.ErrInvalidEAN), errors.Is(err, repository.ErrInvalidRelation):
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+	case errors.Is(err, api.ErrReadOnly):
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+	default:
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// getProductFilter reads the tags, category, variant, inStock, minPrice,
+// maxPrice and, for requests that can see every status, status query
+// parameters. Prices are compared in the currency the request asks for, if
+// any, and as amounts in any currency otherwise.
+func (c *Controller) getProductFilter(code string, ctx *gin.Context) (repository.ProductFilter, error) {
+	filter := repository.ProductFilter{
+		Tags:     []string{},
+		Category: ctx.Query("category"),
+	}
+
+	if tagString := ctx.Query("tags"); len(tagString) > 0 {
+		filter.Tags = strings.Split(tagString, ",")
+	}
+
+	variants, err := getVariantAttributes(ctx)
+	if err != nil {
+		return filter, err
+	}
+	filter.Variants = variants
+
+	if filter.InStock, err = getOptionalQueryBool("inStock", ctx); err != nil {
+		return filter, err
+	}
+
+	if filter.Statuses, err = getStatuses(ctx); err != nil {
+		return filter, err
+	}
+
+	minPrice, maxPrice, err := getPriceRange(code, ctx)
+	if err != nil {
+		return filter, err
+	}
+
+	if code != "" && (minPrice != nil || maxPrice != nil) {
+		filter.Prices = c.priceRanges(minPrice, maxPrice, code)
+	} else {
+		filter.MinPrice, filter.MaxPrice = minPrice, maxPrice
+	}
+
+	return filter, nil
+}
+
+// getOrder reads the sort query parameter (name, -name, price, -price or
+// newest), falling back to the older order parameter
+func getOrder(ctx *gin.Context) (string, error) {
+	sort := ctx.Query("sort")
+
+	switch sort {
+	case "":
+		return ctx.Query("order"), nil
+	case "name":
+		return repository.OrderNameAsc, nil
+	case "-name":
+		return repository.OrderNameDesc, nil
+	case "price":
+		return repository.OrderPriceAsc, nil
+	case "-price":
+		return repository.OrderPriceDesc, nil
+	case "newest":
+		return repository.OrderNewest, nil
+	}
+
+	return "", fmt.Errorf("unsupported sort %q, use name, -name, price, -price or newest", sort)
+}
+
+func getOptionalQueryInt(name string, ctx *gin.Context) (*int, error) {
+	str := ctx.Query(name)
+	if len(str) == 0 {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", name)
+	}
+
+	return &value, nil
+}
+
+func getOptionalQueryBool(name string, ctx *gin.Context) (*bool, error) {
+	str := ctx.Query(name)
+	if len(str) == 0 {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+
+	return &value, nil
+}
+
 func getQueryInt(name string, defaultValue int, ctx *gin.Context) (int, error) {
 	str := ctx.Query(name)
 
@@ -0,0 +1,164 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// contentCurrencyHeader names the currency the prices in a response were
+// converted to
+const contentCurrencyHeader = "Content-Currency"
+
+// SetExchangeRates sets the rates prices are converted with to the currency
+// a request asks for
+func (c *Controller) SetExchangeRates(rates *currency.Rates) {
+	c.rates = rates
+}
+
+// requestedCurrency returns the currency the request asks for prices in, from
+// the currency query parameter or else the Accept-Currency header, or "" to
+// leave each product in its own. A currency without an exchange rate is
+// rejected in the query parameter, while the header may list several in
+// order of preference and those without a rate are passed over.
+func (c *Controller) requestedCurrency(ctx *gin.Context) (string, error) {
+	if code := ctx.Query("currency"); code != "" {
+		code = strings.ToUpper(code)
+		if !c.rates.Supports(code) {
+			return "", fmt.Errorf("currency %s is not supported, use one of %s", code, strings.Join(c.rates.Supported(), ", "))
+		}
+		return code, nil
+	}
+
+	return preferredCurrency(ctx.GetHeader("Accept-Currency"), c.rates), nil
+}
+
+// preferredCurrency returns the supported currency with the highest quality
+// in an Accept-Currency header such as "EUR, GBP;q=0.5", or "" if there is none
+func preferredCurrency(header string, rates *currency.Rates) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		code, params, _ := strings.Cut(part, ";")
+		code = strings.ToUpper(strings.TrimSpace(code))
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality > bestQuality && rates.Supports(code) {
+			best, bestQuality = code, quality
+		}
+	}
+
+	return best
+}
+
+// convertPrices returns the products with the sale prices of running
+// promotions, priced in the currency if one was requested, and names it in
+// the Content-Currency header. Products priced in a currency without an
+// exchange rate keep their own price. The products are copied, so that
+// cached ones are left as they were.
+func (c *Controller) convertPrices(products []model.Product, currency string, ctx *gin.Context) []model.Product {
+	products = c.api.WithPromotions(products, ctx.Request.Context())
+	ctx.Writer.Header().Add("Vary", "Accept-Currency")
+	if currency == "" {
+		return products
+	}
+	ctx.Header(contentCurrencyHeader, currency)
+
+	converted := make([]model.Product, len(products))
+	for i, product := range products {
+		if price, err := c.rates.Convert(product.Price, currency); err == nil {
+			product.Price = price
+		}
+		if product.SalePrice != nil {
+			if price, err := c.rates.Convert(*product.SalePrice, currency); err == nil {
+				product.SalePrice = &price
+			}
+		}
+		if product.Variants != nil {
+			variants := make([]model.Variant, len(product.Variants))
+			for j, variant := range product.Variants {
+				if price, err := c.rates.Convert(variant.Price, currency); err == nil {
+					variant.Price = price
+				}
+				variants[j] = variant
+			}
+			product.Variants = variants
+		}
+		converted[i] = product
+	}
+
+	return converted
+}
+
+// getPriceRange reads the minPrice and maxPrice query parameters, in minor
+// units of the currency
+func getPriceRange(currency string, ctx *gin.Context) (*int, *int, error) {
+	minPrice, err := getOptionalQueryInt("minPrice", ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxPrice, err := getOptionalQueryInt("maxPrice", ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	minPrice, maxPrice = priceQuery(ctx, minPrice, currency), priceQuery(ctx, maxPrice, currency)
+
+	if minPrice != nil && maxPrice != nil && *minPrice > *maxPrice {
+		return nil, nil, fmt.Errorf("minPrice must not be greater than maxPrice")
+	}
+
+	return minPrice, maxPrice, nil
+}
+
+// priceRanges converts a range of prices in one currency to the same range
+// in each currency there is an exchange rate for, so that products priced in
+// any of them can be compared with it
+func (c *Controller) priceRanges(minPrice, maxPrice *int, code string) []repository.PriceRange {
+	ranges := []repository.PriceRange{}
+	for _, to := range c.rates.Supported() {
+		r := repository.PriceRange{Currency: to}
+		if minPrice != nil {
+			price, err := c.rates.Convert(model.Money{Amount: *minPrice, Currency: code}, to)
+			if err != nil {
+				continue
+			}
+			r.Min = &price.Amount
+		}
+		if maxPrice != nil {
+			price, err := c.rates.Convert(model.Money{Amount: *maxPrice, Currency: code}, to)
+			if err != nil {
+				continue
+			}
+			r.Max = &price.Amount
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
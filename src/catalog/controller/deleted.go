@@ -0,0 +1,91 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeletedProducts is the largest page of deleted products that can be
+// requested
+const maxDeletedProducts = 100
+
+// GetDeletedProducts godoc
+// @Summary Get deleted products
+// @Description Get the products that have been deleted and can be restored, most recently deleted first
+// @Tags admin
+// @Produce  json
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Success 200 {array} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/deleted-products [get]
+func (c *Controller) GetDeletedProducts(ctx *gin.Context) {
+	page, err := getQueryInt("page", 1, ctx)
+	if err != nil || page < 1 {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("page must be a positive integer"))
+		return
+	}
+	size, err := getQueryInt("size", 10, ctx)
+	if err != nil || size < 1 || size > maxDeletedProducts {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("size must be between 1 and %d", maxDeletedProducts))
+		return
+	}
+
+	products, err := c.api.GetDeletedProducts(page, size, ctx.Request.Context())
+	if errors.Is(err, api.ErrDeletedProductsNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
+
+// RestoreProduct godoc
+// @Summary Restore a deleted product
+// @Description Undelete a product, with its variants, images, reviews and everything else it had when it was deleted
+// @Tags admin
+// @Produce  json
+// @Param id path string true "product ID"
+// @Success 200 {object} model.Product
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/deleted-products/{id}/restore [post]
+func (c *Controller) RestoreProduct(ctx *gin.Context) {
+	product, err := c.api.RestoreProduct(ctx.Param("id"), ctx.Request.Context())
+	if errors.Is(err, api.ErrDeletedProductsNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
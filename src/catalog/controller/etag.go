@@ -0,0 +1,88 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+)
+
+// jsonWithETag writes body as JSON with a strong ETag computed from the
+// serialized document, or responds 304 Not Modified if the client already
+// holds that representation
+func jsonWithETag(ctx *gin.Context, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	dataWithETag(ctx, "application/json; charset=utf-8", data)
+}
+
+// dataWithETag writes an already serialized body with its ETag, or responds
+// 304 Not Modified if the client already holds that representation
+func dataWithETag(ctx *gin.Context, contentType string, data []byte) {
+	etag := etagOf(data)
+	ctx.Header("ETag", etag)
+
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// etagOf returns the strong ETag of a serialized document
+func etagOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// productETag returns the ETag of a product's complete representation, as
+// sent with GET
+func productETag(ctx *gin.Context, product model.Product) (string, error) {
+	data, err := json.Marshal(productJSON(ctx, product))
+	if err != nil {
+		return "", err
+	}
+	return etagOf(data), nil
+}
+
+// etagMatches implements the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
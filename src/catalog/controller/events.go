@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventHeartbeatInterval is how often a comment is sent on an idle event
+// stream, so proxies and load balancers don't close the connection
+const eventHeartbeatInterval = 15 * time.Second
+
+// StreamEvents godoc
+// @Summary Stream catalog events
+// @Description Stream product changes and reindex completions as Server-Sent Events
+// @Tags catalog
+// @Produce  text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received, to resume a stream"
+// @Success 200 {object} model.CatalogEvent
+// @Router /catalog/events [get]
+func (c *Controller) StreamEvents(ctx *gin.Context) {
+	// Browsers send Last-Event-ID automatically when an EventSource reconnects
+	lastEventID, _ := strconv.ParseUint(ctx.GetHeader("Last-Event-ID"), 10, 64)
+
+	subscription := c.api.Events().Subscribe(lastEventID)
+	defer subscription.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := ctx.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			event.Product = feedProduct(event.Product, ctx)
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+
+			if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+
+		ctx.Writer.Flush()
+	}
+}
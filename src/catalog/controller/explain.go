@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// maxExplainHits limits how many hits are explained in one request, since
+// each of them is a separate request to the search provider
+const maxExplainHits = 20
+
+// ExplainSearch godoc
+// @Summary Explain search
+// @Description Show the query sent to the search provider for a keyword and how the score of each hit on the first page was calculated
+// @Tags catalog
+// @Produce  json
+// @Param keyword query string true "Search keyword"
+// @Param size query int false "Number of hits to explain"
+// @Success 200 {object} model.SearchExplanation
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/search/explain [get]
+func (c *Controller) ExplainSearch(ctx *gin.Context) {
+	if !c.api.IsSearchEnabled() {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("Search is not enabled"))
+		return
+	}
+
+	keyword := ctx.Query("keyword")
+	if keyword == "" {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("keyword query parameter is required"))
+		return
+	}
+
+	size, err := getQueryInt("size", 10, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if size < 1 || size > maxExplainHits {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("size must be between 1 and %d", maxExplainHits))
+		return
+	}
+
+	explanation, err := c.api.ExplainSearch(keyword, size, ctx.Request.Context())
+	if errors.Is(err, api.ErrExplainNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, explanation)
+}
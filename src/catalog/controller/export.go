@@ -0,0 +1,203 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// exportPageSize is the number of products read from the repository and
+// flushed to the client at a time
+const exportPageSize = 100
+
+// CSVHeader is the column layout used when exporting products as CSV, with
+// prices in minor units of their currency, tags are separated by
+// CSVTagSeparator
+var CSVHeader = []string{"id", "name", "description", "price", "currency", "tags"}
+
+// CSVTagSeparator separates multiple tags within the tags column
+const CSVTagSeparator = "|"
+
+// exportSource passes the products to export to the callback, a page at a
+// time
+type exportSource func(fn func([]model.Product) error) error
+
+// ExportProducts godoc
+// @Summary Export catalog
+// @Description Stream the entire catalog, or every product matching a search keyword, as JSON, NDJSON or CSV
+// @Tags catalog
+// @Produce  json
+// @Produce  application/x-ndjson
+// @Produce  text/csv
+// @Param format query string false "Export format, json (default), ndjson or csv"
+// @Param keyword query string false "Search keyword, to export only the matching products in order of relevance"
+// @Success 200 {array} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Router /catalog/export [get]
+func (c *Controller) ExportProducts(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", "json")
+	if format != "json" && format != "ndjson" && format != "csv" {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("unsupported export format %q, use json, ndjson or csv", format))
+		return
+	}
+
+	source := exportSource(func(fn func([]model.Product) error) error {
+		return c.api.ExportProducts(repository.ProductFilter{Statuses: []string{model.StatusActive}}, exportPageSize, ctx.Request.Context(), fn)
+	})
+	if keyword := ctx.Query("keyword"); keyword != "" {
+		if !c.api.IsSearchEnabled() {
+			httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("Search is not enabled"))
+			return
+		}
+		source = func(fn func([]model.Product) error) error {
+			return c.api.StreamSearch(keyword, exportPageSize, ctx.Request.Context(), fn)
+		}
+	}
+
+	var err error
+	switch format {
+	case "json":
+		err = c.exportJSON(source, ctx)
+	case "ndjson":
+		err = c.exportNDJSON(source, ctx)
+	case "csv":
+		err = c.exportCSV(source, ctx)
+	}
+
+	// Once streaming has started the status has been sent, so all that can be
+	// done is to stop writing and log the problem
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "Catalog export failed", "error", err)
+		ctx.Abort()
+	}
+}
+
+func (c *Controller) exportJSON(source exportSource, ctx *gin.Context) error {
+	ctx.Header("Content-Type", "application/json")
+	ctx.Header("Content-Disposition", `attachment; filename="catalog.json"`)
+	ctx.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(ctx.Writer)
+	first := true
+
+	if _, err := ctx.Writer.WriteString("["); err != nil {
+		return err
+	}
+
+	err := source(func(products []model.Product) error {
+		for _, product := range products {
+			if !first {
+				if _, err := ctx.Writer.WriteString(","); err != nil {
+					return err
+				}
+			}
+			first = false
+
+			if err := encoder.Encode(product); err != nil {
+				return err
+			}
+		}
+
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Writer.WriteString("]\n")
+	return err
+}
+
+// exportNDJSON writes a product per line, so that clients can process each
+// one as it arrives rather than parsing the whole array
+func (c *Controller) exportNDJSON(source exportSource, ctx *gin.Context) error {
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", `attachment; filename="catalog.ndjson"`)
+	ctx.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(ctx.Writer)
+
+	return source(func(products []model.Product) error {
+		for _, product := range products {
+			if err := encoder.Encode(product); err != nil {
+				return err
+			}
+		}
+
+		ctx.Writer.Flush()
+		return nil
+	})
+}
+
+func (c *Controller) exportCSV(source exportSource, ctx *gin.Context) error {
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", `attachment; filename="catalog.csv"`)
+	ctx.Status(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	if err := writer.Write(CSVHeader); err != nil {
+		return err
+	}
+
+	err := source(func(products []model.Product) error {
+		for _, product := range products {
+			tags := make([]string, len(product.Tags))
+			for i, tag := range product.Tags {
+				tags[i] = tag.Name
+			}
+
+			err := writer.Write([]string{
+				product.ID,
+				product.Name,
+				product.Description,
+				strconv.Itoa(product.Price.Amount),
+				product.Price.WithDefaultCurrency().Currency,
+				strings.Join(tags, CSVTagSeparator),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
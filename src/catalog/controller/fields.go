@@ -0,0 +1,94 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+)
+
+// parseFields reads the comma-separated fields query parameter, returning nil
+// when every field should be included
+func parseFields(ctx *gin.Context) ([]string, error) {
+	value := ctx.Query("fields")
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !slices.Contains(model.ProductFields, field) {
+			return nil, fmt.Errorf("unknown field %q, valid fields are %s", field, strings.Join(model.ProductFields, ","))
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// selectFields reduces a product to the requested fields
+func selectFields(ctx *gin.Context, product model.Product, fields []string) (any, error) {
+	if fields == nil {
+		return productJSON(ctx, product), nil
+	}
+
+	data, err := json.Marshal(productJSON(ctx, product))
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		selected[field] = all[field]
+	}
+
+	return selected, nil
+}
+
+// selectFieldsList applies selectFields to each product
+func selectFieldsList(ctx *gin.Context, products []model.Product, fields []string) (any, error) {
+	if fields == nil {
+		return productsJSON(ctx, products), nil
+	}
+
+	result := make([]any, len(products))
+	for i, product := range products {
+		selected, err := selectFields(ctx, product, fields)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = selected
+	}
+
+	return result, nil
+}
@@ -0,0 +1,166 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/images"
+	"github.com/aws-containers/retail-store-sample-app/catalog/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxImageSize is the largest image that can be uploaded unless
+// SetMaxImageSize says otherwise
+const DefaultMaxImageSize = 5 << 20
+
+// maxAltLength is the longest alternative text an image can have
+const maxAltLength = 255
+
+// imageForm describes the multipart form an image is uploaded with
+type imageForm struct {
+	Image openapi.Binary `json:"image" binding:"required"`
+	Alt   string         `json:"alt" binding:"max=255"`
+}
+
+// SetMaxImageSize sets the largest image in bytes that can be uploaded
+func (c *Controller) SetMaxImageSize(size int64) {
+	c.maxImageSize = size
+}
+
+// UploadProductImage godoc
+// @Summary Upload product image
+// @Description Store an image of a product, added after its other images. The image is sent as the image field of a multipart form, and its type is detected from its content.
+// @Tags catalog
+// @Accept  mpfd
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param image formData file true "JPEG, PNG, WebP or GIF image"
+// @Param alt formData string false "Alternative text describing the image"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 413 {object} httputil.HTTPError
+// @Failure 415 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/images [post]
+func (c *Controller) UploadProductImage(ctx *gin.Context) {
+	header, err := ctx.FormFile("image")
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("the image must be sent as the image field of a multipart form: %w", err))
+		return
+	}
+	if header.Size > c.maxImageSize {
+		httputil.NewError(ctx, http.StatusRequestEntityTooLarge, fmt.Errorf("the image is larger than %d bytes", c.maxImageSize))
+		return
+	}
+
+	alt := ctx.PostForm("alt")
+	if len(alt) > maxAltLength {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("alt must be at most %d characters", maxAltLength))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	// The type is detected from the content, since the one the client
+	// names can't be trusted
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	if _, ok := images.ContentTypes[contentType]; !ok {
+		httputil.NewError(ctx, http.StatusUnsupportedMediaType, fmt.Errorf("images must be JPEG, PNG, WebP or GIF, got %s", contentType))
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Images aren't stored for products that don't exist
+	if _, err := c.api.GetProduct(ctx.Param("id"), ctx.Request.Context()); err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	product, err := c.api.UploadImage(ctx.Param("id"), alt, contentType, file, ctx.Request.Context())
+	if errors.Is(err, images.ErrReadOnly) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	c.jsonWithProductETag(ctx, product)
+}
+
+// GetImage godoc
+// @Summary Get image
+// @Description Get a product image by its key. Images the catalog doesn't serve itself, such as those in S3, are redirected to.
+// @Tags catalog
+// @Produce  image/jpeg,image/png,image/webp,image/gif
+// @Param key path string true "image key"
+// @Success 200
+// @Success 302
+// @Failure 404 {object} httputil.HTTPError
+// @Router /catalog/images/{key} [get]
+func (c *Controller) GetImage(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	file, err := c.api.Images().Open(key)
+	if errors.Is(err, images.ErrNotServed) {
+		url, err := c.api.Images().URL(key)
+		if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		ctx.Redirect(http.StatusFound, url)
+		return
+	}
+	if err != nil {
+		httputil.NewError(ctx, http.StatusNotFound, fmt.Errorf("image %s not found", key))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	content, ok := file.(io.ReadSeeker)
+	if err != nil || info.IsDir() || !ok {
+		httputil.NewError(ctx, http.StatusNotFound, fmt.Errorf("image %s not found", key))
+		return
+	}
+
+	// Bundled images only change with the binary
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	http.ServeContent(ctx.Writer, ctx.Request, info.Name(), info.ModTime(), content)
+}
@@ -0,0 +1,235 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// StartImport godoc
+// @Summary Start an import
+// @Description Save a JSON array or CSV file of products to the catalog in the background, creating new products and replacing existing ones. CSV files use the export layout. The Location header links to the job's progress.
+// @Tags admin
+// @Accept  json
+// @Accept  text/csv
+// @Produce  json
+// @Param products body []model.ProductRequest true "Products"
+// @Param Idempotency-Key header string false "Unique key for the request, so that retries with the same key return the original response"
+// @Success 202 {object} model.ImportJob
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/imports [post]
+func (c *Controller) StartImport(ctx *gin.Context) {
+	var requests []model.ProductRequest
+	var err error
+	if ctx.ContentType() == "text/csv" {
+		requests, err = readCSVImport(ctx.Request.Body)
+	} else {
+		err = json.NewDecoder(ctx.Request.Body).Decode(&requests)
+	}
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(requests) == 0 {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("no products to import"))
+		return
+	}
+
+	// Problems with the upload itself are reported now rather than left for
+	// the job to find
+	products := make([]model.Product, len(requests))
+	for i, request := range requests {
+		if err := binding.Validator.ValidateStruct(&request); err != nil {
+			httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("product %d: %w", i, err))
+			return
+		}
+
+		id := request.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+		products[i] = *request.ToProduct(id)
+	}
+
+	job, err := c.api.StartImport(products, ctx.Request.Context())
+	if err != nil {
+		importError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/admin/imports/"+job.ID)
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GenerateProducts godoc
+// @Summary Generate products
+// @Description Import synthetic products with made-up names, descriptions, prices and stock in the catalog's categories and tags, so the catalog can be demonstrated at scale. A seed generates the same products with the same IDs every time, so generating again replaces them. The Location header links to the job's progress.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body model.GenerateRequest true "Products to generate"
+// @Param Idempotency-Key header string false "Unique key for the request, so that retries with the same key return the original response"
+// @Success 202 {object} model.ImportJob
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/generate [post]
+func (c *Controller) GenerateProducts(ctx *gin.Context) {
+	var request model.GenerateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	seed := rand.Uint64()
+	if request.Seed != nil {
+		seed = *request.Seed
+	}
+
+	job, err := c.api.GenerateProducts(request.Count, seed, ctx.Request.Context())
+	if err != nil {
+		importError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/admin/imports/"+job.ID)
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob godoc
+// @Summary Get import progress
+// @Description Get the progress of an import job, with the products it rejected
+// @Tags admin
+// @Produce  json
+// @Param jobId path string true "Import job ID"
+// @Success 200 {object} model.ImportJob
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/imports/{jobId} [get]
+func (c *Controller) GetImportJob(ctx *gin.Context) {
+	job, err := c.api.GetImportJob(ctx.Param("jobId"))
+	if err != nil {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// ResumeImportJob godoc
+// @Summary Resume an import
+// @Description Queue a failed import job again, continuing after the last product it processed
+// @Tags admin
+// @Produce  json
+// @Param jobId path string true "Import job ID"
+// @Success 202 {object} model.ImportJob
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 503 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /admin/imports/{jobId}/resume [post]
+func (c *Controller) ResumeImportJob(ctx *gin.Context) {
+	job, err := c.api.ResumeImport(ctx.Param("jobId"), ctx.Request.Context())
+	if err != nil {
+		importError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/admin/imports/"+job.ID)
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+func importError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, api.ErrImportJobNotFound):
+		httputil.NewError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, api.ErrImportNotResumable):
+		httputil.NewError(ctx, http.StatusConflict, err)
+	case errors.Is(err, api.ErrImportQueueFull), errors.Is(err, api.ErrShuttingDown):
+		httputil.NewError(ctx, http.StatusServiceUnavailable, err)
+	case errors.Is(err, api.ErrReadOnly):
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+	default:
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// readCSVImport reads products in the layout written by the CSV export
+func readCSVImport(body io.Reader) ([]model.ProductRequest, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = len(CSVHeader)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(header, CSVHeader) {
+		return nil, fmt.Errorf("CSV header must be %s", strings.Join(CSVHeader, ","))
+	}
+
+	requests := []model.ProductRequest{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return requests, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		price, err := strconv.Atoi(record[3])
+		if err != nil {
+			line, _ := reader.FieldPos(3)
+			return nil, fmt.Errorf("line %d: price must be an integer", line)
+		}
+
+		var tags []string
+		if record[5] != "" {
+			tags = strings.Split(record[5], CSVTagSeparator)
+		}
+
+		requests = append(requests, model.ProductRequest{
+			ID:          record[0],
+			Name:        record[1],
+			Description: record[2],
+			Price:       model.Money{Amount: price, Currency: record[4]},
+			Tags:        tags,
+		})
+	}
+}
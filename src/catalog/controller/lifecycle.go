@@ -0,0 +1,85 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// allStatusesKey is the context key marking requests that can see products
+// whatever their status
+const allStatusesKey = "catalog.allStatuses"
+
+// AllStatuses lets the routes it is used on see products whatever their
+// status, and filter them by it with the status query parameter. Other
+// routes only list and search active products, and don't show drafts.
+func AllStatuses() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(allStatusesKey, true)
+	}
+}
+
+// seesAllStatuses reports whether a request can see products whatever their
+// status
+func seesAllStatuses(ctx *gin.Context) bool {
+	return ctx.GetBool(allStatusesKey)
+}
+
+// getStatuses returns the statuses the products listed or searched for may
+// have. Requests that can see every status choose them with the status query
+// parameter, repeated or separated by commas, and get any status without
+// it, which is returned as nil.
+func getStatuses(ctx *gin.Context) ([]string, error) {
+	if !seesAllStatuses(ctx) {
+		return []string{model.StatusActive}, nil
+	}
+
+	var statuses []string
+	for _, value := range ctx.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			if !model.IsValidStatus(status) {
+				return nil, fmt.Errorf("invalid status %q, use %s", status, strings.Join(model.Statuses, ", "))
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// visible reports whether a request can see a product. Drafts are hidden
+// unless it can see every status, while discontinued products can still be
+// read by ID, such as from old orders.
+func visible(product model.Product, ctx *gin.Context) bool {
+	return product.Status != model.StatusDraft || seesAllStatuses(ctx)
+}
+
+// feedProduct returns the product to send with a change or event, which is
+// left out unless the product is active or the request can see every status.
+// The product ID and type of change are still sent, so that followers can
+// drop a product that is no longer active. Products saved before they had a
+// status are active.
+func feedProduct(product *model.Product, ctx *gin.Context) *model.Product {
+	if product == nil || seesAllStatuses(ctx) || product.Status == model.StatusActive || product.Status == "" {
+		return product
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// liveSearchDebounce is how long the query must be unchanged before it is
+	// searched, so that a burst of keystrokes results in one search
+	liveSearchDebounce = 200 * time.Millisecond
+
+	liveSearchDefaultSize = 10
+	liveSearchMaxSize     = 50
+	liveSearchMaxMessage  = 4096
+	liveSearchWriteWait   = 10 * time.Second
+)
+
+// Origins other than the catalog's own host are refused, as for any
+// WebSocket endpoint without an explicit origin policy
+var liveSearchUpgrader = websocket.Upgrader{}
+
+// liveSearchMessage is a parsed client message, or the reason it was invalid
+type liveSearchMessage struct {
+	request model.LiveSearchRequest
+	err     error
+}
+
+// liveSearchResult is the outcome of the search with the given sequence number
+type liveSearchResult struct {
+	sequence int
+	response model.LiveSearchResponse
+}
+
+// LiveSearch godoc
+// @Summary Live search
+// @Description Upgrade to a WebSocket that searches as the client types. Clients send {"query": "..."} as the query changes and receive the results for the latest query.
+// @Tags catalog
+// @Success 101
+// @Failure 503 {object} httputil.HTTPError
+// @Router /catalog/search/live [get]
+func (c *Controller) LiveSearch(ctx *gin.Context) {
+	if !c.api.IsSearchEnabled() {
+		httputil.NewError(ctx, http.StatusServiceUnavailable, fmt.Errorf("Search is not enabled"))
+		return
+	}
+
+	conn, err := liveSearchUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// The upgrader has already sent an error response
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(liveSearchMaxMessage)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	messages := make(chan liveSearchMessage)
+	go readLiveSearchMessages(conn, messages, done)
+
+	debounce := time.NewTimer(liveSearchDebounce)
+	debounce.Stop()
+
+	results := make(chan liveSearchResult)
+	cancelSearch := func() {}
+	defer func() { cancelSearch() }()
+
+	var pending model.LiveSearchRequest
+	sequence := 0
+
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			if message.err != nil {
+				if !writeLiveSearch(conn, model.LiveSearchResponse{Products: []model.Product{}, Error: message.err.Error()}) {
+					return
+				}
+				continue
+			}
+
+			pending = message.request
+			debounce.Reset(liveSearchDebounce)
+
+		case <-debounce.C:
+			// Only the results for the latest query are wanted, so abandon
+			// any search still in progress
+			cancelSearch()
+			sequence++
+			cancelSearch = c.startLiveSearch(pending, sequence, results, ctx.Request.Context())
+
+		case result := <-results:
+			if result.sequence != sequence {
+				continue
+			}
+
+			if !writeLiveSearch(conn, result.response) {
+				return
+			}
+		}
+	}
+}
+
+func readLiveSearchMessages(conn *websocket.Conn, messages chan<- liveSearchMessage, done <-chan struct{}) {
+	defer close(messages)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message liveSearchMessage
+		if err := json.Unmarshal(data, &message.request); err != nil {
+			message.err = fmt.Errorf("invalid message, expected {\"query\": \"...\"}")
+		} else if message.request.Size < 0 || message.request.Size > liveSearchMaxSize {
+			message.err = fmt.Errorf("size must be between 1 and %d", liveSearchMaxSize)
+		}
+
+		select {
+		case messages <- message:
+		case <-done:
+			return
+		}
+	}
+}
+
+// startLiveSearch searches in the background, returning a function that
+// abandons the search
+func (c *Controller) startLiveSearch(request model.LiveSearchRequest, sequence int, results chan<- liveSearchResult, ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go c.runLiveSearch(request, sequence, results, ctx)
+	return cancel
+}
+
+func (c *Controller) runLiveSearch(request model.LiveSearchRequest, sequence int, results chan<- liveSearchResult, ctx context.Context) {
+	response := model.LiveSearchResponse{Query: request.Query, Products: []model.Product{}}
+
+	size := request.Size
+	if size == 0 {
+		size = liveSearchDefaultSize
+	}
+
+	if request.Query != "" {
+		products, err := c.api.SearchProducts(repository.SearchQuery{Keyword: request.Query}, 1, size, ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "Live search failed", "error", err)
+			response.Error = "search failed"
+		} else if products != nil {
+			response.Products = products
+		}
+	}
+
+	select {
+	case results <- liveSearchResult{sequence: sequence, response: response}:
+	case <-ctx.Done():
+	}
+}
+
+func writeLiveSearch(conn *websocket.Conn, response model.LiveSearchResponse) bool {
+	conn.SetWriteDeadline(time.Now().Add(liveSearchWriteWait))
+	return conn.WriteJSON(response) == nil
+}
@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+)
+
+// requestedLocale returns the locale the request asks for product names and
+// descriptions in, from the locale query parameter or else the
+// Accept-Language header, or "" for the default locale. An unsupported
+// locale is rejected in the query parameter, while the header may list
+// several in order of preference and those without translations are passed
+// over.
+func requestedLocale(ctx *gin.Context) (string, error) {
+	if locale := ctx.Query("locale"); locale != "" {
+		locale = strings.ToLower(locale)
+		if !model.SupportsLocale(locale) {
+			return "", fmt.Errorf("locale %s is not supported, use one of %s, %s", locale, model.DefaultLocale, strings.Join(model.Locales, ", "))
+		}
+		return locale, nil
+	}
+
+	return preferredLocale(ctx.GetHeader("Accept-Language")), nil
+}
+
+// preferredLocale returns the supported locale with the highest quality in an
+// Accept-Language header such as "fr-CA, de;q=0.5", matching on the language
+// alone, or "" if there is none
+func preferredLocale(header string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality > bestQuality && model.SupportsLocale(language) {
+			best, bestQuality = language, quality
+		}
+	}
+
+	return best
+}
+
+// localize returns the products with their names and descriptions in the
+// locale, if one was requested, and names it in the Content-Language header.
+// Products without a translation into the locale keep their own. The products
+// are copied, so that cached ones are left as they were.
+func localize(products []model.Product, locale string, ctx *gin.Context) []model.Product {
+	ctx.Writer.Header().Add("Vary", "Accept-Language")
+	if locale == "" {
+		locale = model.DefaultLocale
+	}
+	ctx.Header("Content-Language", locale)
+	if locale == model.DefaultLocale {
+		return products
+	}
+
+	localized := make([]model.Product, len(products))
+	for i, product := range products {
+		localized[i] = product.Localized(locale)
+	}
+
+	return localized
+}
+
+// localizedFields adds translations to the product fields loaded for a
+// response, when names or descriptions are to be localized from them
+func localizedFields(fields []string, locale string) []string {
+	if fields == nil || locale == "" || locale == model.DefaultLocale {
+		return fields
+	}
+	if !(slices.Contains(fields, "name") || slices.Contains(fields, "description")) || slices.Contains(fields, "translations") {
+		return fields
+	}
+
+	return append(append([]string{}, fields...), "translations")
+}
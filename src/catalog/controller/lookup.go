@@ -0,0 +1,165 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// maxLookupIDs limits how many products can be fetched by ID in one request
+const maxLookupIDs = 100
+
+// LookupProducts godoc
+// @Summary Look up products
+// @Description Get the products with the given IDs in one request, in the order given. IDs that don't exist are skipped.
+// @Tags catalog
+// @Accept  json
+// @Produce  json,xml,application/x-protobuf
+// @Param request body model.ProductLookupRequest true "Product IDs"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Success 200 {array} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/lookup [post]
+func (c *Controller) LookupProducts(ctx *gin.Context) {
+	var request model.ProductLookupRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	c.getProductsByIDs(request.IDs, ctx)
+}
+
+// parseIDs splits the comma-separated ids query parameter
+func parseIDs(value string) []string {
+	ids := []string{}
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (c *Controller) getProductsByIDs(ids []string, ctx *gin.Context) {
+	if len(ids) > maxLookupIDs {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("at most %d ids can be looked up at once", maxLookupIDs))
+		return
+	}
+
+	fields, err := parseFields(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	products, err := c.api.GetProductsByIDs(ids, ctx.Request.Context())
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Drafts are left out as if they didn't exist
+	products = slices.DeleteFunc(slices.Clone(products), func(product model.Product) bool {
+		return !visible(product, ctx)
+	})
+
+	writeProducts(ctx, c.expand(localize(c.convertPrices(products, code, ctx), locale, ctx), ctx), "", fields)
+}
+
+// GetProductBySKU godoc
+// @Summary Get product by SKU
+// @Description Get the product with the SKU or EAN, or with a variant with the SKU. Codes are matched exactly.
+// @Tags catalog
+// @Accept  json
+// @Produce  json,xml,application/x-protobuf
+// @Param sku path string true "SKU or EAN"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/sku/{sku} [get]
+func (c *Controller) GetProductBySKU(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+
+	fields, err := parseFields(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := c.api.GetProductBySKU(sku, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, sku)
+	}
+	if errors.Is(err, api.ErrSKULookupNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	writeProduct(ctx, c.expand(localize(c.convertPrices([]model.Product{*product}, code, ctx), locale, ctx), ctx)[0], fields)
+}
@@ -0,0 +1,117 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// MIMEProtobuf is the media type of protobuf encoded responses
+const MIMEProtobuf = "application/x-protobuf"
+
+// offeredFormats are the media types product and search responses can be
+// negotiated as with the Accept header. JSON comes first, so it is used when
+// the client accepts anything, and it is also used when the client accepts
+// none of them so that existing clients keep working.
+var offeredFormats = []string{gin.MIMEJSON, gin.MIMEXML, gin.MIMEXML2, MIMEProtobuf}
+
+var errFieldsNotJSON = errors.New("the fields parameter is only supported for JSON responses")
+
+// representations builds a response body in each format it can be negotiated as
+type representations struct {
+	json  func() (any, error)
+	xml   func() any
+	proto func() proto.Message
+}
+
+// negotiate writes the representation that the Accept header asks for, with
+// an ETag. Sparse fieldsets only apply to JSON, so other formats are refused
+// when fields are selected.
+func negotiate(ctx *gin.Context, fields []string, body representations) {
+	ctx.Writer.Header().Add("Vary", "Accept")
+
+	format := ctx.NegotiateFormat(offeredFormats...)
+	if format != "" && format != gin.MIMEJSON && fields != nil {
+		httputil.NewError(ctx, http.StatusNotAcceptable, errFieldsNotJSON)
+		return
+	}
+
+	switch format {
+	case gin.MIMEXML, gin.MIMEXML2:
+		data, err := xml.Marshal(body.xml())
+		if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		dataWithETag(ctx, format+"; charset=utf-8", append([]byte(xml.Header), data...))
+	case MIMEProtobuf:
+		data, err := proto.Marshal(body.proto())
+		if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		dataWithETag(ctx, MIMEProtobuf, data)
+	default:
+		result, err := body.json()
+		if err != nil {
+			httputil.NewError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		jsonWithETag(ctx, result)
+	}
+}
+
+// writeProduct writes a single product as a Product message in protobuf
+func writeProduct(ctx *gin.Context, product model.Product, fields []string) {
+	negotiate(ctx, fields, representations{
+		json:  func() (any, error) { return selectFields(ctx, product, fields) },
+		xml:   func() any { return product },
+		proto: func() proto.Message { return product.ToProto() },
+	})
+}
+
+// writeProducts writes a page of products as a ListProductsResponse message in
+// protobuf
+func writeProducts(ctx *gin.Context, products []model.Product, nextCursor string, fields []string) {
+	negotiate(ctx, fields, representations{
+		json: func() (any, error) { return selectFieldsList(ctx, products, fields) },
+		xml:  func() any { return model.ProductList{Products: products, NextCursor: nextCursor} },
+		proto: func() proto.Message {
+			return &catalogv1.ListProductsResponse{Products: model.ProductsToProto(products), NextPageToken: nextCursor}
+		},
+	})
+}
+
+// writeSearchResults writes a page of search results as a
+// SearchProductsResponse message in protobuf
+func writeSearchResults(ctx *gin.Context, products []model.Product, nextCursor string, fields []string) {
+	negotiate(ctx, fields, representations{
+		json: func() (any, error) { return selectFieldsList(ctx, products, fields) },
+		xml:  func() any { return model.ProductList{Products: products, NextCursor: nextCursor} },
+		proto: func() proto.Message {
+			return &catalogv1.SearchProductsResponse{Products: model.ProductsToProto(products), NextPageToken: nextCursor}
+		},
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// maxNewArrivalDays and maxNewArrivals limit how far back new arrivals go
+// and how many can be requested
+const (
+	maxNewArrivalDays = 365
+	maxNewArrivals    = 100
+)
+
+// GetNewArrivals godoc
+// @Summary Get new arrivals
+// @Description Get the products added to the catalog in the last days, newest first
+// @Tags catalog
+// @Produce  json,xml,application/x-protobuf
+// @Param days query int false "How many days back to include products from, 30 by default"
+// @Param size query int false "Most products to return"
+// @Param tags query string false "Tagged products to include"
+// @Param category query string false "Category of products to include, with its subcategories"
+// @Param minPrice query int false "Minimum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param maxPrice query int false "Maximum price in the requested currency, in whole units in v1 and minor units from v2"
+// @Param inStock query bool false "Only include products with stock if true, or without if false"
+// @Param fields query string false "Comma-separated product fields to include"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Success 200 {array} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 406 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/new-arrivals [get]
+func (c *Controller) GetNewArrivals(ctx *gin.Context) {
+	days, err := getQueryInt("days", 30, ctx)
+	if err != nil || days < 1 || days > maxNewArrivalDays {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("days must be between 1 and %d", maxNewArrivalDays))
+		return
+	}
+	size, err := getQueryInt("size", 10, ctx)
+	if err != nil || size < 1 || size > maxNewArrivals {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("size must be between 1 and %d", maxNewArrivals))
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := c.getProductFilter(code, ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	fields, err := parseFields(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	products, err := c.api.GetNewArrivals(filter, since, size, ctx.Request.Context())
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	writeProducts(ctx, c.expand(localize(c.convertPrices(products, code, ctx), locale, ctx), ctx), "", fields)
+}
@@ -0,0 +1,671 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/openapi"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Describe documents the controller's handlers for the generated OpenAPI document
+func (c *Controller) Describe(spec *openapi.Spec) {
+	tags := []string{"catalog"}
+
+	spec.SecurityScheme("apiKey", openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"})
+	spec.SecurityScheme("bearerAuth", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+	adminSecurity := []string{"apiKey", "bearerAuth"}
+	ifNoneMatch := openapi.HeaderParam("If-None-Match", "ETag of a previously fetched response")
+	ifMatch := openapi.HeaderParam("If-Match", "ETag of the product the change is based on, so that it fails if the product has changed since")
+	idempotencyKey := openapi.HeaderParam("Idempotency-Key", "Unique key for the request, so that retries with the same key return the original response")
+	minPrice := openapi.QueryParam("minPrice", "Minimum price in the requested currency, in whole units in v1 and minor units from v2", "integer")
+	maxPrice := openapi.QueryParam("maxPrice", "Maximum price in the requested currency, in whole units in v1 and minor units from v2", "integer")
+	variant := openapi.QueryParam("variant", "Attribute value one of the product's variants must have, as name:value. Repeat it to require several on the same variant.", "array")
+	variant.Schema.Items = &openapi.Schema{Type: "string"}
+	inStock := openapi.QueryParam("inStock", "Only include products with stock if true, or without if false", "boolean")
+	attribute := openapi.QueryParam("attribute", "Product attribute filter as name:value, or name:min..max for a range of numbers where either bound may be left out. Repeat it to require several.", "array")
+	attribute.Schema.Items = &openapi.Schema{Type: "string"}
+	onSale := openapi.QueryParam("onSale", "Only include products a running promotion applies to if true, or those none does if false", "boolean")
+	minRating := openapi.QueryParam("minRating", "Lowest average rating of the products to include, from 0 to 5", "number")
+	searchSort := openapi.QueryParam("sort", "Sort by relevance, by average rating with products rated the same sorted by relevance, or newest first", "string")
+	searchSort.Schema.Enum = []any{"relevance", "rating", "-rating", "newest"}
+	productStatus := openapi.QueryParam("status", "Statuses of the products to include, on the /admin endpoints only, which include any status without it. Repeat it or separate statuses with commas. Other endpoints only include active products.", "array")
+	productStatus.Schema.Items = &openapi.Schema{Type: "string", Enum: []any{model.StatusDraft, model.StatusActive, model.StatusDiscontinued}}
+	category := openapi.QueryParam("category", "Category of products to include, with its subcategories", "string")
+	sort := openapi.QueryParam("sort", "Sort field, prefixed with - for descending order, or newest for the most recently added first", "string")
+	sort.Schema.Enum = []any{"name", "-name", "price", "-price", "newest"}
+	cursor := openapi.QueryParam("cursor", "Cursor for the next page, from the previous response. Takes the place of page and size.", "string")
+	currency := openapi.QueryParam("currency", "ISO 4217 currency to convert prices to, instead of the Accept-Currency header", "string")
+	acceptCurrency := openapi.HeaderParam("Accept-Currency", "Currencies to convert prices to in order of preference, such as EUR, GBP;q=0.5")
+	locale := openapi.QueryParam("locale", "Language to show product names and descriptions in, instead of the Accept-Language header, one of "+model.DefaultLocale+","+strings.Join(model.Locales, ","), "string")
+	acceptLanguage := openapi.HeaderParam("Accept-Language", "Languages to show product names and descriptions in order of preference, such as fr-CA, de;q=0.5")
+	fields := openapi.QueryParam("fields", "Comma-separated product fields to include, any of "+strings.Join(model.ProductFields, ","), "string")
+
+	spec.Describe(c.GetProducts, openapi.Operation{
+		Summary: "Get catalog",
+		Tags:    tags,
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("ids", "Comma-separated IDs of products to fetch, instead of listing the catalog", "string"),
+			openapi.QueryParam("tags", "Comma-separated tags of products to include", "string"),
+			category,
+			minPrice,
+			maxPrice,
+			variant,
+			inStock,
+			productStatus,
+			sort,
+			openapi.QueryParam("order", "Order of response, superseded by sort", "string"),
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size", "integer"),
+			cursor,
+			fields,
+			currency,
+			acceptCurrency,
+			locale,
+			acceptLanguage,
+			ifNoneMatch,
+		},
+		Responses: responses(notModified(negotiable(ok([]model.Product{}), model.ProductList{})), http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable),
+	})
+
+	spec.Describe(c.GetNewArrivals, openapi.Operation{
+		Summary:     "Get new arrivals",
+		Description: "Get the products added to the catalog in the last days, newest first",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("days", "How many days back to include products from, 30 by default and at most 365", "integer"),
+			openapi.QueryParam("size", "Most products to return, at most 100", "integer"),
+			openapi.QueryParam("tags", "Comma-separated tags of products to include", "string"),
+			category,
+			minPrice,
+			maxPrice,
+			variant,
+			inStock,
+			fields,
+			currency,
+			acceptCurrency,
+			locale,
+			acceptLanguage,
+		},
+		Responses: responses(negotiable(ok([]model.Product{}), model.ProductList{}), http.StatusBadRequest, http.StatusNotAcceptable),
+	})
+
+	spec.Describe(c.GetProduct, openapi.Operation{
+		Summary:     "Get product",
+		Description: "Get a product by ID. Drafts are only found on the /admin endpoint, while discontinued products can still be read everywhere.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID"), fields, currency, acceptCurrency, locale, acceptLanguage, ifNoneMatch},
+		Responses:   responses(notModified(negotiable(ok(model.Product{}), model.Product{})), http.StatusNotFound, http.StatusNotAcceptable),
+	})
+
+	spec.Describe(c.GetProductVariants, openapi.Operation{
+		Summary:     "Get product variants",
+		Description: "Get the variants of a product in SKU order, optionally only those with the given attribute values",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID"), variant, currency, acceptCurrency},
+		Responses:   responses(ok([]model.Variant{}), http.StatusBadRequest, http.StatusNotFound),
+	})
+
+	spec.Describe(c.GetReviews, openapi.Operation{
+		Summary:     "Get product reviews",
+		Description: "Get the reviews of a product, newest first",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.PathParam("id", "Product ID"),
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size, at most 100", "integer"),
+		},
+		Responses: responses(ok([]model.ProductReview{}), http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.GetPriceHistory, openapi.Operation{
+		Summary:     "Get product price history",
+		Description: "Get the current price of a product, its price before the last change and the changes to it, newest first",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.PathParam("id", "Product ID"),
+			openapi.QueryParam("limit", "Most changes to return, at most 100", "integer"),
+		},
+		Responses: responses(ok(model.PriceHistoryResponse{}), http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.GetRelatedProducts, openapi.Operation{
+		Summary:     "Get related products",
+		Description: "Get the products related to a product, such as those bought together with it, in the order of its relations",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.PathParam("id", "Product ID"),
+			openapi.QueryParam("type", "Type of relation, one of "+strings.Join(model.RelationTypes, ","), "string"),
+			currency, acceptCurrency, locale, acceptLanguage,
+		},
+		Responses: responses(ok(model.RelatedProductsResponse{}), http.StatusBadRequest, http.StatusNotFound),
+	})
+
+	spec.Describe(c.AddReview, openapi.Operation{
+		Summary:     "Review product",
+		Description: "Review a product with a rating from 1 to 5, which is included in the product's average rating",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID"), idempotencyKey},
+		Body:        model.ReviewRequest{},
+		Responses:   responses(map[int]openapi.Response{http.StatusCreated: {Body: model.ProductReview{}}}, http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.GetImage, openapi.Operation{
+		Summary:     "Get image",
+		Description: "Get a product image by its key. Images the catalog doesn't serve itself, such as those uploaded to S3, are redirected to.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("key", "Image key, such as products/{id}.jpg")},
+		Responses: responses(map[int]openapi.Response{
+			http.StatusOK: {Body: openapi.Binary{}, ContentType: "image/jpeg", Alternatives: map[string]any{
+				"image/png":  openapi.Binary{},
+				"image/webp": openapi.Binary{},
+				"image/gif":  openapi.Binary{},
+			}},
+			http.StatusFound: {Description: "The image is fetched from its URL in the Location header"},
+		}, http.StatusNotFound),
+	})
+
+	spec.Describe(c.GetProductBySKU, openapi.Operation{
+		Summary:     "Get product by SKU",
+		Description: "Get the product with the SKU or EAN, or with a variant with the SKU. Codes are matched exactly, and drafts aren't found.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("sku", "SKU or EAN"), fields, currency, acceptCurrency, locale, acceptLanguage},
+		Responses:   responses(negotiable(ok(model.Product{}), model.Product{}), http.StatusNotFound, http.StatusNotAcceptable, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.GetProductBySlug, openapi.Operation{
+		Summary:     "Get product by slug",
+		Description: "Get the product with the slug made from its name, for frontends with readable product URLs. Slugs a product had before it was renamed are permanently redirected to its current one, and drafts aren't found.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("slug", "Product slug"), fields, currency, acceptCurrency, locale, acceptLanguage},
+		Responses:   responses(movedPermanently(negotiable(ok(model.Product{}), model.Product{}), "The product's current slug is in the Location header"), http.StatusNotFound, http.StatusNotAcceptable, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.LookupProducts, openapi.Operation{
+		Summary:     "Look up products",
+		Description: "Get the products with the given IDs in one request, in the order given. IDs that don't exist are skipped.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{fields, currency, acceptCurrency, locale, acceptLanguage},
+		Body:        model.ProductLookupRequest{},
+		Responses:   responses(negotiable(ok([]model.Product{}), model.ProductList{}), http.StatusBadRequest, http.StatusNotAcceptable),
+	})
+
+	spec.Describe(c.LiveSearch, openapi.Operation{
+		Summary:     "Live search",
+		Description: `Upgrade to a WebSocket that searches as the client types. Clients send {"query": "..."} messages as the query changes and receive a message with the results of the latest query once it has been unchanged briefly.`,
+		Tags:        tags,
+		Responses: responses(
+			map[int]openapi.Response{http.StatusSwitchingProtocols: {Description: "Switching Protocols"}},
+			http.StatusServiceUnavailable,
+		),
+	})
+
+	spec.Describe(c.StreamEvents, openapi.Operation{
+		Summary:     "Stream catalog events",
+		Description: "Stream product changes and reindex completions as Server-Sent Events",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.HeaderParam("Last-Event-ID", "ID of the last event received, to resume a stream")},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusOK: {Body: model.CatalogEvent{}, ContentType: "text/event-stream"}},
+		),
+	})
+
+	spec.Describe(c.CreateProduct, openapi.Operation{
+		Summary:     "Create product",
+		Description: "Add a product to the catalog, generating an ID if none is given",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{idempotencyKey},
+		Body:        model.ProductRequest{},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusCreated: {Body: model.Product{}}},
+			http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.UpdateProduct, openapi.Operation{
+		Summary:    "Update product",
+		Tags:       tags,
+		Parameters: []openapi.Parameter{openapi.PathParam("id", "Product ID"), ifMatch},
+		Body:       model.ProductRequest{},
+		Responses:  responses(ok(model.Product{}), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:   adminSecurity,
+	})
+
+	spec.Describe(c.PatchProduct, openapi.Operation{
+		Summary:     "Patch product",
+		Description: "Change some fields of a product with a JSON merge patch (RFC 7386). Fields in the patch replace those of the product, null removes them, and fields not in the patch are unchanged.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID"), ifMatch},
+		Body:        model.ProductRequest{},
+		Responses:   responses(ok(model.Product{}), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusUnsupportedMediaType, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.AdjustStock, openapi.Operation{
+		Summary:     "Adjust stock",
+		Description: "Change the stock of a product, or of one of its variants if a SKU is given, by a number of units or to a new number. Adjustments that would leave fewer than no units are rejected with 409 Conflict.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID")},
+		Body:        model.StockAdjustment{},
+		Responses:   responses(ok(model.Product{}), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.SetRelatedProducts, openapi.Operation{
+		Summary:     "Set related products",
+		Description: "Replace the relations of a product to others, which are bundles bought together with it, products it is an accessory of or products it replaces",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID")},
+		Body:        model.RelationsRequest{},
+		Responses:   responses(ok([]model.ProductRelation{}), http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.UploadProductImage, openapi.Operation{
+		Summary:         "Upload product image",
+		Description:     "Store a JPEG, PNG, WebP or GIF image of a product, added after its other images. The type is detected from the content of the image.",
+		Tags:            tags,
+		Parameters:      []openapi.Parameter{openapi.PathParam("id", "Product ID")},
+		Body:            imageForm{},
+		BodyContentType: "multipart/form-data",
+		Responses:       responses(ok(model.Product{}), http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:        adminSecurity,
+	})
+
+	spec.Describe(c.DeleteProduct, openapi.Operation{
+		Summary:    "Delete product",
+		Tags:       tags,
+		Parameters: []openapi.Parameter{openapi.PathParam("id", "Product ID")},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusNoContent: {}},
+			http.StatusNotFound, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.GetPromotions, openapi.Operation{
+		Summary:     "List promotions",
+		Description: "List every promotion, including those that haven't started or have ended, in ID order",
+		Tags:        tags,
+		Responses:   responses(ok([]model.Promotion{}), http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.CreatePromotion, openapi.Operation{
+		Summary:     "Create promotion",
+		Description: "Add a promotion taking a percentage or an amount off the listed products and those with the tags, generating an ID if none is given. Products show the sale price of the running promotion that takes the most off.",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{idempotencyKey},
+		Body:        model.PromotionRequest{},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusCreated: {Body: model.Promotion{}}},
+			http.StatusBadRequest, http.StatusConflict, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.DeletePromotion, openapi.Operation{
+		Summary:    "Delete promotion",
+		Tags:       tags,
+		Parameters: []openapi.Parameter{openapi.PathParam("id", "Promotion ID")},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusNoContent: {}},
+			http.StatusNotFound, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.CatalogSize, openapi.Operation{
+		Summary: "Get catalog size",
+		Tags:    tags,
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("ids", "Comma-separated IDs of products to fetch, instead of listing the catalog", "string"),
+			openapi.QueryParam("tags", "Comma-separated tags of products to include", "string"),
+			category,
+			minPrice,
+			maxPrice,
+			variant,
+			inStock,
+			currency,
+			acceptCurrency,
+		},
+		Responses: responses(ok(model.CatalogSizeResponse{}), http.StatusBadRequest, http.StatusNotFound),
+	})
+
+	spec.Describe(c.ListTags, openapi.Operation{
+		Summary:     "List tags",
+		Description: "List tags with the number of products that have each",
+		Tags:        tags,
+		Responses:   responses(ok([]model.TagCount{}), http.StatusNotFound),
+	})
+
+	spec.Describe(c.ListCategories, openapi.Operation{
+		Summary:     "List categories",
+		Description: "Get the category tree, with subcategories nested under their parents",
+		Tags:        tags,
+		Responses:   responses(ok([]model.CategoryNode{})),
+	})
+
+	spec.Describe(c.GetCategory, openapi.Operation{
+		Summary:     "Get category",
+		Description: "Get a category with its breadcrumbs, the categories from the top of the tree down to it",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("name", "Category name")},
+		Responses:   responses(ok(model.Category{}), http.StatusNotFound),
+	})
+
+	spec.Describe(c.GetCategoryProducts, openapi.Operation{
+		Summary:     "Get category products",
+		Description: "Get the products in a category, including those in its subcategories",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.PathParam("name", "Category name"),
+			sort,
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size", "integer"),
+			cursor,
+			fields,
+			currency,
+			acceptCurrency,
+			locale,
+			acceptLanguage,
+			ifNoneMatch,
+		},
+		Responses: responses(notModified(negotiable(ok([]model.Product{}), model.ProductList{})), http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable),
+	})
+
+	searchKeyword := openapi.QueryParam("keyword", "Search keyword", "string")
+	searchKeyword.Required = true
+
+	spec.Describe(c.SearchProducts, openapi.Operation{
+		Summary:     "Search products",
+		Description: "Search products by keyword using the configured search provider. While OpenSearch is unavailable the results may come from a basic database search instead, which is marked by the X-Catalog-Degraded header",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			searchKeyword,
+			category,
+			minPrice,
+			maxPrice,
+			variant,
+			inStock,
+			attribute,
+			minRating,
+			onSale,
+			searchSort,
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size", "integer"),
+			cursor,
+			fields,
+			currency,
+			acceptCurrency,
+			locale,
+			acceptLanguage,
+			ifNoneMatch,
+		},
+		Responses: responses(notModified(negotiable(ok([]model.Product{}), model.ProductList{})), http.StatusBadRequest, http.StatusNotAcceptable, http.StatusServiceUnavailable),
+	})
+
+	spec.Describe(c.SearchProductsV2, openapi.Operation{
+		Summary:     "Search products",
+		Description: "Search products by keyword, returning the results in a response envelope. While OpenSearch is unavailable the results may come from a basic database search instead, which is marked by the X-Catalog-Degraded header",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			searchKeyword,
+			category,
+			minPrice,
+			maxPrice,
+			variant,
+			inStock,
+			attribute,
+			minRating,
+			onSale,
+			searchSort,
+			productStatus,
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size", "integer"),
+			cursor,
+			fields,
+			currency,
+			acceptCurrency,
+			locale,
+			acceptLanguage,
+			ifNoneMatch,
+		},
+		Responses: responses(notModified(negotiable(ok(model.SearchResponse{}), model.SearchResponse{})), http.StatusBadRequest, http.StatusNotAcceptable, http.StatusServiceUnavailable),
+	})
+
+	spec.Describe(c.GetChanges, openapi.Operation{
+		Summary:     "Changes since",
+		Description: "Get the products created, updated or deleted since a point in time, oldest first. Products that aren't active are left out of their changes. Pass the cursor of the response as since to continue from where it ended.",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("since", "RFC 3339 timestamp or the cursor of an earlier response, defaults to the beginning of the log", "string"),
+			openapi.QueryParam("size", "Maximum number of changes to return, up to 1000", "integer"),
+		},
+		Responses: responses(ok(model.ChangesResponse{}), http.StatusBadRequest, http.StatusNotImplemented),
+	})
+
+	spec.Describe(c.ExplainSearch, openapi.Operation{
+		Summary:     "Explain search",
+		Description: "Show the query sent to the search provider for a keyword and how the score of each hit on the first page was calculated, for tuning relevance",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			searchKeyword,
+			openapi.QueryParam("size", "Number of hits to explain, up to 20", "integer"),
+		},
+		Responses: responses(ok(model.SearchExplanation{}), http.StatusBadRequest, http.StatusNotImplemented, http.StatusServiceUnavailable),
+	})
+
+	spec.Describe(c.ReindexProducts, openapi.Operation{
+		Summary:     "Reindex products",
+		Description: "Drop and recreate the search index with fresh product data",
+		Tags:        tags,
+		Responses:   responses(ok(map[string]string{}), http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.CheckConsistency, openapi.Operation{
+		Summary:     "Check search index consistency",
+		Description: "Compare the products in the database with the documents in the search index",
+		Tags:        tags,
+		Responses:   responses(ok(repository.ReconcileReport{}), http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.ReconcileProducts, openapi.Operation{
+		Summary:     "Reconcile search index",
+		Description: "Re-index products missing from or stale in the search index and remove orphaned documents",
+		Tags:        tags,
+		Responses:   responses(ok(repository.ReconcileReport{}), http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	adminTags := []string{"admin"}
+
+	spec.Describe(c.StartReindex, openapi.Operation{
+		Summary:     "Start a reindex",
+		Description: "Rebuild the search index from the catalog database in the background. The Location header links to the job's progress.",
+		Tags:        adminTags,
+		Responses: responses(
+			map[int]openapi.Response{http.StatusAccepted: {Body: model.ReindexJob{}}},
+			http.StatusConflict, http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.GetReindexJob, openapi.Operation{
+		Summary:     "Get reindex progress",
+		Description: "Get the progress of a reindex job, with an estimate of the time left while it runs",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("jobId", "Reindex job ID")},
+		Responses:   responses(ok(model.ReindexJob{}), http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.StartImport, openapi.Operation{
+		Summary:     "Start an import",
+		Description: "Save a JSON array or CSV file of products to the catalog in the background, creating new products and replacing existing ones. CSV files use the export layout. The Location header links to the job's progress.",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{idempotencyKey},
+		Body:        []model.ProductRequest{},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusAccepted: {Body: model.ImportJob{}}},
+			http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented, http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.GenerateProducts, openapi.Operation{
+		Summary:     "Generate products",
+		Description: "Import synthetic products with made-up names, descriptions, prices and stock in the catalog's categories and tags, so the catalog can be demonstrated at scale. A seed generates the same products with the same IDs every time, so generating again replaces them. The Location header links to the job's progress.",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{idempotencyKey},
+		Body:        model.GenerateRequest{},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusAccepted: {Body: model.ImportJob{}}},
+			http.StatusBadRequest, http.StatusNotImplemented, http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.GetImportJob, openapi.Operation{
+		Summary:     "Get import progress",
+		Description: "Get the progress of an import job, with the products it rejected",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("jobId", "Import job ID")},
+		Responses:   responses(ok(model.ImportJob{}), http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.ResumeImportJob, openapi.Operation{
+		Summary:     "Resume an import",
+		Description: "Queue a failed import job again, continuing after the last product it processed",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("jobId", "Import job ID")},
+		Responses: responses(
+			map[int]openapi.Response{http.StatusAccepted: {Body: model.ImportJob{}}},
+			http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	spec.Describe(c.GetDeletedProducts, openapi.Operation{
+		Summary:     "Get deleted products",
+		Description: "Get the products that have been deleted and can be restored, most recently deleted first",
+		Tags:        adminTags,
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("page", "Page number", "integer"),
+			openapi.QueryParam("size", "Page size, at most 100", "integer"),
+		},
+		Responses: responses(ok([]model.Product{}), http.StatusBadRequest, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:  adminSecurity,
+	})
+
+	spec.Describe(c.RestoreProduct, openapi.Operation{
+		Summary:     "Restore a deleted product",
+		Description: "Undelete a product, with its variants, images, reviews and everything else it had when it was deleted",
+		Tags:        adminTags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("id", "Product ID")},
+		Responses:   responses(ok(model.Product{}), http.StatusNotFound, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    adminSecurity,
+	})
+
+	spec.Describe(c.ResetCatalog, openapi.Operation{
+		Summary:     "Reset catalog",
+		Description: "Restore the catalog and search index to the bundled seed data, discarding all changes. Only available when enabled in the configuration.",
+		Tags:        adminTags,
+		Responses: responses(
+			map[int]openapi.Response{http.StatusNoContent: {}},
+			http.StatusConflict, http.StatusNotImplemented, http.StatusUnauthorized, http.StatusForbidden,
+		),
+		Security: adminSecurity,
+	})
+
+	format := openapi.QueryParam("format", "Export format", "string")
+	format.Schema.Enum = []any{"json", "ndjson", "csv"}
+
+	spec.Describe(c.ExportProducts, openapi.Operation{
+		Summary:     "Export catalog",
+		Description: "Stream the entire catalog, or every product matching a search keyword, as JSON, NDJSON or CSV",
+		Tags:        tags,
+		Parameters: []openapi.Parameter{
+			format,
+			openapi.QueryParam("keyword", "Search keyword, to export only the matching products in order of relevance", "string"),
+		},
+		Responses: responses(ok([]model.Product{}), http.StatusBadRequest, http.StatusServiceUnavailable),
+	})
+
+	sitemapTags := []string{"sitemap"}
+
+	spec.Describe(c.SitemapIndex, openapi.Operation{
+		Summary:     "Sitemap index",
+		Description: "Get the sitemap index, which lists the product sitemaps",
+		Tags:        sitemapTags,
+		Parameters:  []openapi.Parameter{ifNoneMatch},
+		Responses:   responses(notModified(map[int]openapi.Response{http.StatusOK: {Body: model.SitemapIndex{}, ContentType: gin.MIMEXML}})),
+	})
+
+	spec.Describe(c.SitemapPage, openapi.Operation{
+		Summary:     "Product sitemap",
+		Description: "Get a page of the product sitemaps",
+		Tags:        sitemapTags,
+		Parameters:  []openapi.Parameter{openapi.PathParam("file", "Sitemap file name, for example products-1.xml"), ifNoneMatch},
+		Responses:   responses(notModified(map[int]openapi.Response{http.StatusOK: {Body: model.URLSet{}, ContentType: gin.MIMEXML}}), http.StatusNotFound),
+	})
+}
+
+func ok(body any) map[int]openapi.Response {
+	return map[int]openapi.Response{http.StatusOK: {Body: body}}
+}
+
+// negotiable adds the XML and protobuf representations of the success
+// response, with xmlBody as the XML document
+func negotiable(success map[int]openapi.Response, xmlBody any) map[int]openapi.Response {
+	response := success[http.StatusOK]
+	response.Alternatives = map[string]any{
+		gin.MIMEXML:  xmlBody,
+		MIMEProtobuf: openapi.Binary{},
+	}
+	success[http.StatusOK] = response
+	return success
+}
+
+// notModified adds the response to a conditional GET that matched an ETag
+func notModified(success map[int]openapi.Response) map[int]openapi.Response {
+	success[http.StatusNotModified] = openapi.Response{}
+	return success
+}
+
+// movedPermanently adds the response to a request for a resource that has
+// moved to the URL in the Location header
+func movedPermanently(success map[int]openapi.Response, description string) map[int]openapi.Response {
+	success[http.StatusMovedPermanently] = openapi.Response{Description: description}
+	return success
+}
+
+// responses adds the given error statuses, plus 500, to the success responses
+func responses(success map[int]openapi.Response, errorStatuses ...int) map[int]openapi.Response {
+	for _, status := range append(errorStatuses, http.StatusInternalServerError) {
+		success[status] = openapi.Response{Body: httputil.HTTPError{}}
+	}
+	return success
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pagination holds the paging query parameters. A cursor from a previous
+// response takes the place of the page number and size.
+type pagination struct {
+	cursor string
+	page   int
+	size   int
+}
+
+// getPagination reads the cursor, page and size query parameters
+func getPagination(ctx *gin.Context) (pagination, error) {
+	page, err := getQueryInt("page", 1, ctx)
+	if err != nil {
+		return pagination{}, err
+	}
+
+	size, err := getQueryInt("size", 10, ctx)
+	if err != nil {
+		return pagination{}, err
+	}
+
+	return pagination{cursor: ctx.Query("cursor"), page: page, size: size}, nil
+}
+
+// setNextLink adds a Link header for the next page to list responses, which
+// is the request with the cursor in place of the page number
+func setNextLink(ctx *gin.Context, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	query := ctx.Request.URL.Query()
+	query.Del("page")
+	query.Set("cursor", cursor)
+
+	ctx.Header("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, ctx.Request.URL.Path, query.Encode()))
+}
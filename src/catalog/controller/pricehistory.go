@@ -0,0 +1,75 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// maxPriceChanges is the most price changes that can be requested at once
+const maxPriceChanges = 100
+
+// GetPriceHistory godoc
+// @Summary Get product price history
+// @Description Get the current price of a product, its price before the last change and the changes to it, newest first
+// @Tags catalog
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param limit query int false "Most changes to return"
+// @Success 200 {object} model.PriceHistoryResponse
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/price-history [get]
+func (c *Controller) GetPriceHistory(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	limit, err := getQueryInt("limit", 20, ctx)
+	if err != nil || limit < 1 || limit > maxPriceChanges {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxPriceChanges))
+		return
+	}
+
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	history, err := c.api.GetPriceHistory(product, limit, ctx.Request.Context())
+	if errors.Is(err, api.ErrPriceHistoryNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
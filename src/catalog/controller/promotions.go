@@ -0,0 +1,139 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// GetPromotions godoc
+// @Summary List promotions
+// @Description List every promotion, including those that haven't started or have ended, in ID order
+// @Tags catalog
+// @Produce  json
+// @Success 200 {array} model.Promotion
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/promotions [get]
+func (c *Controller) GetPromotions(ctx *gin.Context) {
+	promotions, err := c.api.GetPromotions(ctx.Request.Context())
+	if err != nil {
+		promotionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promotions)
+}
+
+// CreatePromotion godoc
+// @Summary Create promotion
+// @Description Add a promotion taking a percentage or an amount off the listed products and those with the tags, generating an ID if none is given
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param promotion body model.PromotionRequest true "Promotion"
+// @Success 201 {object} model.Promotion
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/promotions [post]
+func (c *Controller) CreatePromotion(ctx *gin.Context) {
+	var request model.PromotionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.StartsAt != nil && request.EndsAt != nil && !request.EndsAt.After(*request.StartsAt) {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("endsAt must be after startsAt"))
+		return
+	}
+
+	id := request.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	promotion := request.ToPromotion(id)
+	if err := c.api.CreatePromotion(&promotion, ctx.Request.Context()); err != nil {
+		promotionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, promotion)
+}
+
+// DeletePromotion godoc
+// @Summary Delete promotion
+// @Description Remove a promotion, ending it if it is running
+// @Tags catalog
+// @Param id path string true "promotion ID"
+// @Success 204
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/promotions/{id} [delete]
+func (c *Controller) DeletePromotion(ctx *gin.Context) {
+	if err := c.api.DeletePromotion(ctx.Param("id"), ctx.Request.Context()); err != nil {
+		promotionError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// promotionError maps errors from promotion requests to HTTP statuses
+func promotionError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, api.ErrPromotionsNotSupported):
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+	case errors.Is(err, repository.ErrPromotionExists):
+		httputil.NewError(ctx, http.StatusConflict, err)
+	case errors.Is(err, repository.ErrPromotionNotFound):
+		httputil.NewError(ctx, http.StatusNotFound, err)
+	default:
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// pricedFields adds the fields that decide a product's sale price to a
+// sparse fieldset that includes it, so that search providers return them
+func pricedFields(fields []string) []string {
+	if fields == nil || !(slices.Contains(fields, "salePrice") || slices.Contains(fields, "onSale")) {
+		return fields
+	}
+
+	priced := append([]string{}, fields...)
+	for _, field := range []string{"price", "tags"} {
+		if !slices.Contains(priced, field) {
+			priced = append(priced, field)
+		}
+	}
+	return priced
+}
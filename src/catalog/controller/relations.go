@@ -0,0 +1,138 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// GetRelatedProducts godoc
+// @Summary Get related products
+// @Description Get the products related to a product, such as those bought together with it, in the order of its relations
+// @Tags catalog
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param type query string false "Type of relation, one of bundle, accessory-of and replacement-for"
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Param locale query string false "Language to show product names and descriptions in, instead of the Accept-Language header"
+// @Param Accept-Language header string false "Languages to show product names and descriptions in, in order of preference"
+// @Success 200 {object} model.RelatedProductsResponse
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/related [get]
+func (c *Controller) GetRelatedProducts(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	relationType := ctx.Query("type")
+	if relationType != "" && !slices.Contains(model.RelationTypes, relationType) {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("unsupported type %q, use one of %s", relationType, strings.Join(model.RelationTypes, ", ")))
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	locale, err := requestedLocale(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	related, err := c.api.GetRelatedProducts(product, relationType, ctx.Request.Context())
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	// Drafts are left out as if they didn't exist
+	related = slices.DeleteFunc(related, func(related model.RelatedProduct) bool {
+		return !visible(related.Product, ctx)
+	})
+
+	products := make([]model.Product, len(related))
+	for i, r := range related {
+		products[i] = r.Product
+	}
+	products = c.expand(localize(c.convertPrices(products, code, ctx), locale, ctx), ctx)
+	for i := range related {
+		related[i].Product = products[i]
+	}
+
+	ctx.JSON(http.StatusOK, model.RelatedProductsResponse{ProductID: id, Related: related})
+}
+
+// SetRelatedProducts godoc
+// @Summary Set related products
+// @Description Replace the relations of a product to others, which are bundles bought together with it, products it is an accessory of or products it replaces
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param relations body model.RelationsRequest true "Relations"
+// @Success 200 {array} model.ProductRelation
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/related [put]
+func (c *Controller) SetRelatedProducts(ctx *gin.Context) {
+	var request model.RelationsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := c.api.SetRelations(ctx.Param("id"), request, ctx.Request.Context())
+	if errors.Is(err, api.ErrRelationsNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	relations := product.Related
+	if relations == nil {
+		relations = []model.ProductRelation{}
+	}
+	ctx.JSON(http.StatusOK, relations)
+}
@@ -0,0 +1,158 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// maxReviews is the largest page of reviews that can be requested
+const maxReviews = 100
+
+// AddReview godoc
+// @Summary Review product
+// @Description Review a product with a rating from 1 to 5, which is included in the product's average rating
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param review body model.ReviewRequest true "Review"
+// @Success 201 {object} model.ProductReview
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/reviews [post]
+func (c *Controller) AddReview(ctx *gin.Context) {
+	var request model.ReviewRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// Drafts can't be reviewed, as shoppers can't see them
+	id := ctx.Param("id")
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	review, err := c.api.AddReview(id, request, ctx.Request.Context())
+	if errors.Is(err, api.ErrReviewsNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, review)
+}
+
+// GetReviews godoc
+// @Summary Get product reviews
+// @Description Get the reviews of a product, newest first
+// @Tags catalog
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Success 200 {array} model.ProductReview
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 501 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/reviews [get]
+func (c *Controller) GetReviews(ctx *gin.Context) {
+	page, err := getQueryInt("page", 1, ctx)
+	if err != nil || page < 1 {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("page must be a positive integer"))
+		return
+	}
+	size, err := getQueryInt("size", 10, ctx)
+	if err != nil || size < 1 || size > maxReviews {
+		httputil.NewError(ctx, http.StatusBadRequest, fmt.Errorf("size must be between 1 and %d", maxReviews))
+		return
+	}
+
+	id := ctx.Param("id")
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	reviews, err := c.api.GetReviews(id, page, size, ctx.Request.Context())
+	if errors.Is(err, api.ErrReviewsNotSupported) {
+		httputil.NewError(ctx, http.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// getSearchOrder reads the sort query parameter of a search, returning ""
+// to sort by relevance
+func getSearchOrder(ctx *gin.Context) (string, error) {
+	switch sort := ctx.Query("sort"); sort {
+	case "", "relevance":
+		return "", nil
+	case "rating":
+		return repository.OrderRatingAsc, nil
+	case "-rating":
+		return repository.OrderRatingDesc, nil
+	case "newest":
+		return repository.OrderNewest, nil
+	default:
+		return "", fmt.Errorf("unsupported sort %q, use relevance, rating, -rating or newest", sort)
+	}
+}
+
+// getMinRating reads the minRating query parameter, the lowest average
+// rating search results may have, returning nil if it isn't given
+func getMinRating(ctx *gin.Context) (*float64, error) {
+	value := ctx.Query("minRating")
+	if value == "" {
+		return nil, nil
+	}
+
+	rating, err := strconv.ParseFloat(value, 64)
+	if err != nil || rating < 0 || rating > model.MaxRating {
+		return nil, fmt.Errorf("minRating must be a number between 0 and %d", model.MaxRating)
+	}
+	return &rating, nil
+}
@@ -0,0 +1,107 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// SitemapIndex godoc
+// @Summary Sitemap index
+// @Description Get the sitemap index, which lists the product sitemaps
+// @Tags sitemap
+// @Produce  xml
+// @Success 200 {object} model.SitemapIndex
+// @Failure 500 {object} httputil.HTTPError
+// @Router /sitemap.xml [get]
+func (c *Controller) SitemapIndex(ctx *gin.Context) {
+	index, err := c.api.SitemapIndex(ctx.Request.Context())
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSitemap(ctx, index)
+}
+
+// SitemapPage godoc
+// @Summary Product sitemap
+// @Description Get a page of the product sitemaps
+// @Tags sitemap
+// @Produce  xml
+// @Param file path string true "Sitemap file name, for example products-1.xml"
+// @Success 200 {object} model.URLSet
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /sitemap/{file} [get]
+func (c *Controller) SitemapPage(ctx *gin.Context) {
+	page, err := sitemapPageNumber(ctx.Param("file"))
+	if err != nil {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	urls, err := c.api.SitemapPage(page, ctx.Request.Context())
+	if errors.Is(err, api.ErrSitemapNotFound) {
+		httputil.NewError(ctx, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSitemap(ctx, urls)
+}
+
+// sitemapPageNumber reads the page number from a file name like products-1.xml
+func sitemapPageNumber(file string) (int, error) {
+	number, found := strings.CutPrefix(file, "products-")
+	if found {
+		number, found = strings.CutSuffix(number, ".xml")
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: %s", api.ErrSitemapNotFound, file)
+	}
+
+	page, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", api.ErrSitemapNotFound, file)
+	}
+
+	return page, nil
+}
+
+// writeSitemap serializes a sitemap with an ETag, so crawlers revisiting an
+// unchanged catalog get 304 Not Modified
+func writeSitemap(ctx *gin.Context, body any) {
+	data, err := xml.Marshal(body)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	dataWithETag(ctx, "application/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+)
+
+// AdjustStock godoc
+// @Summary Adjust stock
+// @Description Change the stock of a product, or of one of its variants if a SKU is given, by a number of units or to a new number. Adjustments that would leave fewer than no units are rejected.
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param adjustment body model.StockAdjustment true "Stock adjustment"
+// @Success 200 {object} model.Product
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 409 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/stock [post]
+func (c *Controller) AdjustStock(ctx *gin.Context) {
+	var adjustment model.StockAdjustment
+	if err := ctx.ShouldBindJSON(&adjustment); err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := c.api.AdjustStock(ctx.Param("id"), adjustment, ctx.Request.Context())
+	if errors.Is(err, api.ErrInvalidAdjustment) {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	c.jsonWithProductETag(ctx, product)
+}
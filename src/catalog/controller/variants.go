@@ -0,0 +1,99 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductVariants godoc
+// @Summary Get product variants
+// @Description Get the variants of a product in SKU order, optionally only those with the given attribute values
+// @Tags catalog
+// @Accept  json
+// @Produce  json
+// @Param id path string true "product ID"
+// @Param variant query []string false "Attribute values the variants must have, as name:value" collectionFormat(multi)
+// @Param currency query string false "ISO 4217 currency to convert prices to, instead of the Accept-Currency header"
+// @Param Accept-Currency header string false "Currencies to convert prices to in order of preference"
+// @Success 200 {array} model.Variant
+// @Failure 400 {object} httputil.HTTPError
+// @Failure 404 {object} httputil.HTTPError
+// @Failure 500 {object} httputil.HTTPError
+// @Router /catalog/products/{id}/variants [get]
+func (c *Controller) GetProductVariants(ctx *gin.Context) {
+	attributes, err := getVariantAttributes(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	code, err := c.requestedCurrency(ctx)
+	if err != nil {
+		httputil.NewError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	id := ctx.Param("id")
+	product, err := c.api.GetProduct(id, ctx.Request.Context())
+	if err == nil && !visible(*product, ctx) {
+		err = fmt.Errorf("%w: %s", repository.ErrProductNotFound, id)
+	}
+	if err != nil {
+		readError(ctx, err)
+		return
+	}
+
+	variants := []model.Variant{}
+	for _, variant := range c.convertPrices([]model.Product{*product}, code, ctx)[0].Variants {
+		if variant.MatchesAttributes(attributes) {
+			variants = append(variants, variant)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, variants)
+}
+
+// getVariantAttributes reads the variant query parameters, each an attribute
+// value written as name:value that a variant must have. It returns nil if
+// there are none.
+func getVariantAttributes(ctx *gin.Context) (map[string]string, error) {
+	filters := ctx.QueryArray("variant")
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	attributes := make(map[string]string, len(filters))
+	for _, filter := range filters {
+		name, value, err := model.ParseVariantAttribute(filter)
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := attributes[name]; ok && previous != value {
+			return nil, fmt.Errorf("variant attribute %s can only have one value, got %s and %s", name, previous, value)
+		}
+		attributes[name] = value
+	}
+
+	return attributes, nil
+}
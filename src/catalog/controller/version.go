@@ -0,0 +1,84 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package controller
+
+import (
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/gin-gonic/gin"
+)
+
+// versionKey is the context key of the version of the API a request was made to
+const versionKey = "catalog.apiVersion"
+
+// APIVersion records the version of the API the routes it is used on belong
+// to, which decides how prices are represented in JSON
+func APIVersion(version int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(versionKey, version)
+	}
+}
+
+// apiVersion returns the version of the API a request was made to, which is
+// 1 for routes that aren't versioned
+func apiVersion(ctx *gin.Context) int {
+	if version, ok := ctx.Get(versionKey); ok {
+		return version.(int)
+	}
+	return 1
+}
+
+// v1Product is a product as v1 of the API represents it in JSON, with its
+// price as a bare number of whole units of its currency, which is what
+// clients written before prices had a currency, such as the UI, expect
+type v1Product struct {
+	model.Product
+	Price int `json:"price"`
+}
+
+// productJSON returns the JSON representation of a product in the version
+// of the API a request was made to
+func productJSON(ctx *gin.Context, product model.Product) any {
+	if apiVersion(ctx) == 1 {
+		return v1Product{Product: product, Price: product.Price.Units()}
+	}
+	return product
+}
+
+// productsJSON returns the JSON representation of a list of products in the
+// version of the API a request was made to
+func productsJSON(ctx *gin.Context, products []model.Product) []any {
+	result := make([]any, len(products))
+	for i, product := range products {
+		result[i] = productJSON(ctx, product)
+	}
+	return result
+}
+
+// priceQuery converts a price given in a query parameter to minor units of
+// the currency, or the default currency if none is given, from whole units in
+// v1 of the API
+func priceQuery(ctx *gin.Context, price *int, currency string) *int {
+	if price == nil || apiVersion(ctx) != 1 {
+		return price
+	}
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+
+	amount := model.MoneyFromUnits(*price, currency).Amount
+	return &amount
+}
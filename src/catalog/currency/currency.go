@@ -0,0 +1,127 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// ErrUnsupported is returned when there is no exchange rate for a currency
+var ErrUnsupported = errors.New("unsupported currency")
+
+// Rates holds the exchange rate of each currency prices can be converted to,
+// as the amount of it that one unit of model.DefaultCurrency buys. Rates
+// fetched from a source take precedence over those configured in the
+// environment or configuration file.
+type Rates struct {
+	mu         sync.RWMutex
+	configured map[string]float64
+	remote     map[string]float64
+}
+
+// New creates rates with the configured values
+func New(configured map[string]float64) *Rates {
+	r := &Rates{}
+	r.SetConfigured(configured)
+
+	return r
+}
+
+// SetConfigured replaces the rates from the environment or configuration file
+func (r *Rates) SetConfigured(rates map[string]float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.configured = maps.Clone(rates)
+}
+
+// SetRemote replaces the rates fetched from a source
+func (r *Rates) SetRemote(rates map[string]float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remote = maps.Clone(rates)
+}
+
+// rate returns the exchange rate of a currency
+func (r *Rates) rate(currency string) (float64, bool) {
+	if currency == model.DefaultCurrency {
+		return 1, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rate, ok := r.remote[currency]; ok {
+		return rate, true
+	}
+	rate, ok := r.configured[currency]
+	return rate, ok
+}
+
+// Supports reports whether prices can be converted to and from a currency
+func (r *Rates) Supports(currency string) bool {
+	_, ok := r.rate(currency)
+	return ok
+}
+
+// Supported returns the currencies prices can be converted between, in
+// alphabetical order
+func (r *Rates) Supported() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	currencies := map[string]bool{model.DefaultCurrency: true}
+	for currency := range r.configured {
+		currencies[currency] = true
+	}
+	for currency := range r.remote {
+		currencies[currency] = true
+	}
+
+	return slices.Sorted(maps.Keys(currencies))
+}
+
+// Convert returns the price in another currency, rounded to the nearest
+// minor unit of that currency
+func (r *Rates) Convert(price model.Money, currency string) (model.Money, error) {
+	price = price.WithDefaultCurrency()
+	if price.Currency == currency {
+		return price, nil
+	}
+
+	from, ok := r.rate(price.Currency)
+	if !ok {
+		return model.Money{}, fmt.Errorf("%w: %s", ErrUnsupported, price.Currency)
+	}
+	to, ok := r.rate(currency)
+	if !ok {
+		return model.Money{}, fmt.Errorf("%w: %s", ErrUnsupported, currency)
+	}
+
+	amount := float64(price.Amount) * to / from * float64(model.MinorUnits(currency)) / float64(model.MinorUnits(price.Currency))
+	amount = math.Round(amount)
+
+	return model.Money{Amount: int(amount), Currency: currency}, nil
+}
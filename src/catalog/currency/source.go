@@ -0,0 +1,130 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// Source polls a URL for exchange rates and sets them, so that prices follow
+// the market without restarting the service
+type Source struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	rates    *Rates
+}
+
+// NewSource creates a poller of the rates published at a URL
+func NewSource(url string, interval time.Duration, rates *Rates) *Source {
+	return &Source{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		rates:    rates,
+	}
+}
+
+// Poll fetches the latest rates and sets them
+func (s *Source) Poll(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", s.url, res.Status)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+
+	rates, err := Rebase(body.Base, body.Rates)
+	if err != nil {
+		return err
+	}
+
+	s.rates.SetRemote(rates)
+	slog.InfoContext(ctx, "Applied exchange rates", "url", s.url, "currencies", len(rates))
+
+	return nil
+}
+
+// Watch polls until the context is done, keeping the current rates when a
+// poll fails
+func (s *Source) Watch(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Poll(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to poll exchange rates", "error", err)
+			}
+		}
+	}
+}
+
+// Rebase converts rates quoted against any base currency, such as
+// {"base": "EUR", "rates": {"USD": 1.08}}, to rates against
+// model.DefaultCurrency, which the base currency must be or have a rate for
+func Rebase(base string, rates map[string]float64) (map[string]float64, error) {
+	if base == "" {
+		base = model.DefaultCurrency
+	}
+
+	divisor := 1.0
+	if base != model.DefaultCurrency {
+		var ok bool
+		if divisor, ok = rates[model.DefaultCurrency]; !ok || divisor <= 0 {
+			return nil, fmt.Errorf("exchange rates against %s have no rate for %s", base, model.DefaultCurrency)
+		}
+	}
+
+	rebased := make(map[string]float64, len(rates)+1)
+	rebased[base] = 1 / divisor
+	for currency, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("exchange rate for %s must be positive, got %g", currency, rate)
+		}
+		rebased[currency] = rate / divisor
+	}
+	delete(rebased, model.DefaultCurrency)
+
+	return rebased, nil
+}
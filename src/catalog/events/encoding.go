@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package events
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// Formats events are encoded in
+const (
+	FormatJSON = "json"
+	FormatAvro = "avro"
+)
+
+// jsonSchema describes events encoded as JSON, in the same shape as those
+// streamed by the API
+const jsonSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"title": "CatalogEvent",
+	"type": "object",
+	"properties": {
+		"id": {"type": "integer", "description": "Increases with every event published by a catalog instance"},
+		"type": {"type": "string", "enum": ["product.created", "product.updated", "product.deleted", "reindex.completed", "catalog.reset", "import.completed"]},
+		"productId": {"type": "string"},
+		"product": {"type": "object", "description": "The product as returned by the API, for creates and updates"},
+		"time": {"type": "string", "format": "date-time"}
+	},
+	"required": ["id", "type", "time"]
+}`
+
+// avroSchema describes events encoded as Avro. The product is kept as the
+// JSON the API returns, rather than a record of its own that would need to
+// change with every new product field.
+const avroSchema = `{
+	"type": "record",
+	"name": "CatalogEvent",
+	"namespace": "com.amazon.sample.retail.catalog",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "type", "type": "string"},
+		{"name": "productId", "type": ["null", "string"], "default": null},
+		{"name": "product", "type": ["null", "string"], "default": null},
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+	]
+}`
+
+// Encoder encodes catalog events as JSON or Avro. Once the schema of its
+// format is registered, payloads start with the schema ID in the wire format
+// of the Confluent schema registry's serializers.
+type Encoder struct {
+	format   string
+	schemaID int32
+}
+
+// NewEncoder returns an encoder for the format, json or avro
+func NewEncoder(format string) (*Encoder, error) {
+	if format != FormatJSON && format != FormatAvro {
+		return nil, fmt.Errorf("unknown event format %q", format)
+	}
+	return &Encoder{format: format, schemaID: -1}, nil
+}
+
+// Schema returns the JSON Schema or Avro schema of encoded events
+func (e *Encoder) Schema() string {
+	if e.format == FormatAvro {
+		return avroSchema
+	}
+	return jsonSchema
+}
+
+// Encode returns the payload of the event
+func (e *Encoder) Encode(event model.CatalogEvent) ([]byte, error) {
+	payload := []byte{}
+	if e.schemaID >= 0 {
+		// A zero magic byte followed by the big-endian schema ID
+		payload = binary.BigEndian.AppendUint32([]byte{0}, uint32(e.schemaID))
+	}
+
+	if e.format == FormatJSON {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		return append(payload, body...), nil
+	}
+
+	payload = binary.AppendVarint(payload, int64(event.ID))
+	payload = appendAvroString(payload, event.Type)
+	if event.ProductID == "" {
+		payload = binary.AppendVarint(payload, 0)
+	} else {
+		payload = appendAvroString(binary.AppendVarint(payload, 1), event.ProductID)
+	}
+	if event.Product == nil {
+		payload = binary.AppendVarint(payload, 0)
+	} else {
+		product, err := json.Marshal(event.Product)
+		if err != nil {
+			return nil, err
+		}
+		payload = appendAvroString(binary.AppendVarint(payload, 1), string(product))
+	}
+	return binary.AppendVarint(payload, event.Time.UnixMilli()), nil
+}
+
+// appendAvroString appends a string as Avro encodes it: its length in bytes
+// and then the bytes. Avro lengths, longs and union branches are zig-zag
+// varints, which is how Go encodes signed varints too.
+func appendAvroString(payload []byte, value string) []byte {
+	return append(binary.AppendVarint(payload, int64(len(value))), value...)
+}
@@ -0,0 +1,114 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+const (
+	// iamAction is the MSK permission checked when a client connects
+	iamAction = "kafka-cluster:Connect"
+	// iamSignatureExpiry is how long a signed connection request is valid
+	iamSignatureExpiry = 5 * time.Minute
+)
+
+// iamMechanism signs in to Amazon MSK with IAM access control, sending a
+// presigned kafka-cluster:Connect request signed with the AWS credentials of
+// the service
+type iamMechanism struct {
+	signer *v4.Signer
+	region string
+}
+
+// newIAMMechanism signs connections with the default credentials, in the
+// region or that of the AWS configuration
+func newIAMMechanism(region string) (*iamMechanism, error) {
+	awsConfig := aws.NewConfig()
+	if region != "" {
+		awsConfig = awsConfig.WithRegion(region)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	region = aws.StringValue(sess.Config.Region)
+	if region == "" {
+		return nil, fmt.Errorf("MSK IAM authentication requires a region, set RETAIL_CATALOG_EVENTS_KAFKA_REGION or AWS_REGION")
+	}
+
+	return &iamMechanism{signer: v4.NewSigner(sess.Config.Credentials), region: region}, nil
+}
+
+func (m *iamMechanism) Name() string {
+	return "AWS_MSK_IAM"
+}
+
+// Start returns the signed request, as a JSON object of its lower case
+// query parameters and headers
+func (m *iamMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	metadata := sasl.MetadataFromContext(ctx)
+	if metadata == nil {
+		return nil, nil, errors.New("the broker to sign in to is unknown")
+	}
+
+	target := url.URL{Scheme: "kafka", Host: metadata.Host, Path: "/", RawQuery: url.Values{"Action": {iamAction}}.Encode()}
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := m.signer.Presign(req, nil, "kafka-cluster", m.region, iamSignatureExpiry, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign MSK connection request: %w", err)
+	}
+
+	payload := map[string]string{
+		"version":    "2020_10_22",
+		"host":       metadata.Host,
+		"user-agent": "retail-store-sample-catalog",
+		"action":     iamAction,
+	}
+	for key, values := range header {
+		payload[strings.ToLower(key)] = values[0]
+	}
+	for key, values := range req.URL.Query() {
+		payload[strings.ToLower(key)] = values[0]
+	}
+
+	body, err := json.Marshal(payload)
+	return m, body, err
+}
+
+// Next completes the exchange, since the broker fails the authentication
+// rather than sending a challenge when it rejects the request
+func (m *iamMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	return true, nil, nil
+}
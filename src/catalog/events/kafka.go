@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// eventTypeHeader is the Kafka header with the type of the event, so that
+// consumers can skip events they don't handle without decoding them
+const eventTypeHeader = "eventType"
+
+// MessageWriter writes messages to a Kafka topic, as a kafka.Writer does
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// KafkaPublisher publishes catalog events to a Kafka topic. Events are keyed
+// by product ID, so that the events of a product stay in order on one
+// partition. A nil KafkaPublisher publishes nothing.
+type KafkaPublisher struct {
+	writer  MessageWriter
+	encoder *Encoder
+}
+
+// NewKafkaPublisher returns a publisher writing events encoded by the
+// encoder with the writer
+func NewKafkaPublisher(writer MessageWriter, encoder *Encoder) *KafkaPublisher {
+	return &KafkaPublisher{writer: writer, encoder: encoder}
+}
+
+// Publish sends the event to the topic. The writer is expected to send it
+// in the background, so events that can't be sent are logged rather than
+// failing the change they describe.
+func (p *KafkaPublisher) Publish(event model.CatalogEvent) {
+	value, err := p.encoder.Encode(event)
+	if err != nil {
+		slog.Error("Failed to encode catalog event", "type", event.Type, "productId", event.ProductID, "error", err)
+		return
+	}
+
+	message := kafka.Message{
+		Value:   value,
+		Headers: []kafka.Header{{Key: eventTypeHeader, Value: []byte(event.Type)}},
+	}
+	// Events that aren't about one product, such as a finished reindex,
+	// have no key and are spread over the partitions
+	if event.ProductID != "" {
+		message.Key = []byte(event.ProductID)
+	}
+
+	if err := p.writer.WriteMessages(context.Background(), message); err != nil {
+		slog.Error("Failed to publish catalog event to Kafka", "type", event.Type, "productId", event.ProductID, "error", err)
+	}
+}
+
+// Close sends the events still waiting to be sent and closes the
+// connections to the brokers
+func (p *KafkaPublisher) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.writer.Close()
+}
+
+// NewKafkaWriter returns a writer to the configured topic that sends
+// messages in batches in the background, logging those it fails to send
+func NewKafkaWriter(config config.KafkaConfiguration) (*kafka.Writer, error) {
+	transport := &kafka.Transport{}
+	if config.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	switch config.Auth {
+	case "scram":
+		mechanism, err := scram.Mechanism(scram.SHA512, config.Username, config.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up SCRAM authentication: %w", err)
+		}
+		transport.SASL = mechanism
+	case "iam":
+		mechanism, err := newIAMMechanism(config.Region)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+
+	return &kafka.Writer{
+		Addr:  kafka.TCP(config.Brokers...),
+		Topic: config.Topic,
+		// Keys are hashed like the default partitioner of the Java client, so
+		// that other producers put a product's events on the same partition
+		Balancer:     kafka.Murmur2Balancer{},
+		BatchTimeout: config.BatchTimeout,
+		RequiredAcks: kafka.RequireAll,
+		Async:        true,
+		Transport:    transport,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				slog.Error("Failed to publish catalog events to Kafka", "topic", config.Topic, "events", len(messages), "error", err)
+			}
+		},
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// registryContentType is the media type of schema registry requests
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+// Register registers the encoder's schema for the subject with a
+// Confluent-compatible schema registry, and starts the payloads it encodes
+// with the schema's ID. Registering a schema the subject already has
+// returns its ID, so every instance of the service gets the same one.
+// Credentials in the registry URL are sent with basic authentication, as
+// hosted registries expect.
+func (e *Encoder) Register(registryURL, subject string, client *http.Client, ctx context.Context) error {
+	target, err := url.Parse(registryURL)
+	if err != nil {
+		return err
+	}
+	target = target.JoinPath("subjects", subject, "versions")
+	credentials := target.User
+	target.User = nil
+
+	request := map[string]string{"schema": e.Schema()}
+	// Avro is the registry's default schema type
+	if e.format == FormatJSON {
+		request["schemaType"] = "JSON"
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", registryContentType)
+	req.Header.Set("Accept", registryContentType)
+	if credentials != nil {
+		password, _ := credentials.Password()
+		req.SetBasicAuth(credentials.Username(), password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to register the event schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to register the event schema, the registry returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var registered struct {
+		ID int32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil {
+		return fmt.Errorf("failed to read the registered schema ID: %w", err)
+	}
+
+	e.schemaID = registered.ID
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// How long the consumer waits before receiving again after a failed
+// receive, doubled after every failure in a row
+const (
+	minReceiveBackoff = time.Second
+	maxReceiveBackoff = time.Minute
+)
+
+// IndexUpdater applies index messages to the search index, as the catalog
+// API does
+type IndexUpdater interface {
+	ApplyIndexMessage(message model.IndexMessage, ctx context.Context) error
+}
+
+// SQSConsumer receives index messages from an SQS queue and applies them to
+// the search index, so that writers only need to send a message for the
+// index to catch up. Applied messages are deleted from the queue. Those that
+// fail are received again once their visibility timeout ends, until the
+// queue's redrive policy moves them to a dead-letter queue.
+type SQSConsumer struct {
+	client    sqsiface.SQSAPI
+	queueURL  string
+	updater   IndexUpdater
+	batchSize int
+	waitTime  time.Duration
+}
+
+// NewSQSConsumer returns a consumer receiving up to batchSize messages at a
+// time from the queue, waiting up to waitTime for them to arrive
+func NewSQSConsumer(client sqsiface.SQSAPI, queueURL string, updater IndexUpdater, batchSize int, waitTime time.Duration) *SQSConsumer {
+	return &SQSConsumer{
+		client:    client,
+		queueURL:  queueURL,
+		updater:   updater,
+		batchSize: batchSize,
+		waitTime:  waitTime,
+	}
+}
+
+// NewSQSClient creates an SQS client with the region and credentials of the
+// AWS configuration, unless the configuration overrides the region
+func NewSQSClient(config config.IndexQueueConfiguration) (sqsiface.SQSAPI, error) {
+	awsConfig := aws.NewConfig()
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+	if config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return sqs.New(sess), nil
+}
+
+// Run receives and applies messages until ctx is done
+func (c *SQSConsumer) Run(ctx context.Context) {
+	backoff := minReceiveBackoff
+	for ctx.Err() == nil {
+		if _, err := c.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Failed to receive index messages", "queue", c.queueURL, "retryIn", backoff, "error", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxReceiveBackoff)
+			continue
+		}
+		backoff = minReceiveBackoff
+	}
+}
+
+// Poll receives one batch of messages and applies them, returning how many
+// were applied. Messages that can't be decoded, or that can never be
+// applied, are logged and deleted rather than retried.
+func (c *SQSConsumer) Poll(ctx context.Context) (int, error) {
+	output, err := c.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.queueURL),
+		MaxNumberOfMessages: aws.Int64(int64(c.batchSize)),
+		WaitTimeSeconds:     aws.Int64(int64(c.waitTime / time.Second)),
+		AttributeNames:      aws.StringSlice([]string{sqs.MessageSystemAttributeNameApproximateReceiveCount}),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	done := []*sqs.DeleteMessageBatchRequestEntry{}
+	for i, message := range output.Messages {
+		receives := aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount])
+		logger := slog.With("messageId", aws.StringValue(message.MessageId), "receiveCount", receives)
+
+		indexMessage, err := decodeIndexMessage(aws.StringValue(message.Body))
+		if err == nil {
+			err = c.updater.ApplyIndexMessage(indexMessage, ctx)
+		}
+
+		switch {
+		case err == nil:
+			logger.Debug("Applied index message", "type", indexMessage.Type, "products", indexMessage.IDs())
+			applied++
+		case errors.Is(err, errMalformedMessage), errors.Is(err, api.ErrInvalidIndexMessage):
+			logger.Warn("Dropped index message that can't be applied", "error", err)
+		default:
+			logger.Error("Failed to apply index message, it will be retried", "type", indexMessage.Type, "error", err)
+			continue
+		}
+
+		done = append(done, &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+	}
+
+	if len(done) == 0 {
+		return applied, nil
+	}
+
+	// Messages that aren't deleted are only applied again, which is harmless
+	result, err := c.client.DeleteMessageBatchWithContext(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(c.queueURL),
+		Entries:  done,
+	})
+	if err != nil {
+		return applied, fmt.Errorf("failed to delete index messages: %w", err)
+	}
+	for _, failed := range result.Failed {
+		slog.Warn("Failed to delete index message", "entry", aws.StringValue(failed.Id), "error", aws.StringValue(failed.Message))
+	}
+
+	return applied, nil
+}
+
+var errMalformedMessage = errors.New("malformed index message")
+
+// snsNotification is the envelope SNS wraps messages in when a queue
+// subscribes to a topic without raw message delivery
+type snsNotification struct {
+	Type     string `json:"Type"`
+	TopicArn string `json:"TopicArn"`
+	Message  string `json:"Message"`
+}
+
+// decodeIndexMessage decodes the body of a message, unwrapping it first if
+// it was delivered by SNS
+func decodeIndexMessage(body string) (model.IndexMessage, error) {
+	var notification snsNotification
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return model.IndexMessage{}, fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	if notification.Type == "Notification" && notification.TopicArn != "" {
+		body = notification.Message
+	}
+
+	var message model.IndexMessage
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		return model.IndexMessage{}, fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	return message, nil
+}
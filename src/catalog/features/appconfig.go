@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/appconfigdata"
+	"github.com/aws/aws-sdk-go/service/appconfigdata/appconfigdataiface"
+)
+
+// AppConfig polls an AWS AppConfig feature flag configuration profile and
+// sets the flags it holds, so that features can be turned on and off across
+// every instance without a deployment
+type AppConfig struct {
+	client appconfigdataiface.AppConfigDataAPI
+	config config.AppConfigConfiguration
+	flags  *Flags
+
+	// token is for the next poll of the configuration session, which is
+	// started again if it expires
+	token        *string
+	pollInterval time.Duration
+}
+
+// NewAppConfig creates a poller for the configured profile using the default
+// AWS configuration
+func NewAppConfig(config config.AppConfigConfiguration, flags *Flags) (*AppConfig, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return NewAppConfigWithClient(appconfigdata.New(sess), config, flags), nil
+}
+
+// NewAppConfigWithClient creates a poller that uses the given client
+func NewAppConfigWithClient(client appconfigdataiface.AppConfigDataAPI, config config.AppConfigConfiguration, flags *Flags) *AppConfig {
+	return &AppConfig{
+		client:       client,
+		config:       config,
+		flags:        flags,
+		pollInterval: config.PollInterval,
+	}
+}
+
+// Poll fetches the latest configuration and sets the flags from it. AppConfig
+// only returns the configuration when it has changed since the last poll.
+func (a *AppConfig) Poll(ctx context.Context) error {
+	if a.token == nil {
+		session, err := a.client.StartConfigurationSessionWithContext(ctx, &appconfigdata.StartConfigurationSessionInput{
+			ApplicationIdentifier:          aws.String(a.config.Application),
+			EnvironmentIdentifier:          aws.String(a.config.Environment),
+			ConfigurationProfileIdentifier: aws.String(a.config.Profile),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start AppConfig session: %w", err)
+		}
+		a.token = session.InitialConfigurationToken
+	}
+
+	output, err := a.client.GetLatestConfigurationWithContext(ctx, &appconfigdata.GetLatestConfigurationInput{
+		ConfigurationToken: a.token,
+	})
+	if err != nil {
+		// Tokens expire, so start a new session next time
+		a.token = nil
+		return fmt.Errorf("failed to get AppConfig configuration: %w", err)
+	}
+
+	a.token = output.NextPollConfigurationToken
+	if seconds := aws.Int64Value(output.NextPollIntervalInSeconds); seconds > 0 {
+		a.pollInterval = max(a.config.PollInterval, time.Duration(seconds)*time.Second)
+	}
+
+	if len(output.Configuration) == 0 {
+		return nil
+	}
+
+	values, err := ParseAppConfigFlags(output.Configuration)
+	if err != nil {
+		return err
+	}
+
+	a.flags.SetRemote(values)
+	slog.InfoContext(ctx, "Applied feature flags from AppConfig", "version", aws.StringValue(output.VersionLabel))
+
+	return nil
+}
+
+// Watch polls until the context is done, keeping the current flags when a
+// poll fails
+func (a *AppConfig) Watch(ctx context.Context) {
+	for {
+		timer := time.NewTimer(a.pollInterval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := a.Poll(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to poll feature flags", "error", err)
+			}
+		}
+	}
+}
+
+// ParseAppConfigFlags reads the enabled state of each flag from the JSON of
+// an AppConfig feature flag profile, for example {"chaos": {"enabled": false}}
+func ParseAppConfigFlags(data []byte) (map[string]bool, error) {
+	var flags map[string]struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("failed to parse AppConfig feature flags: %w", err)
+	}
+
+	values := make(map[string]bool, len(flags))
+	for name, flag := range flags {
+		values[name] = flag.Enabled
+	}
+
+	return values, nil
+}
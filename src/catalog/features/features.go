@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package features
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// Feature flags. Each is on unless turned off, so that existing deployments
+// keep their behavior.
+const (
+	// Chaos applies the latency, errors and dependency faults set with the
+	// /chaos endpoints or configuration
+	Chaos = "chaos"
+	// LiveSearch serves the search-as-you-type endpoint
+	LiveSearch = "liveSearch"
+	// SearchExplain serves the search explain endpoint
+	SearchExplain = "searchExplain"
+)
+
+var defaults = map[string]bool{
+	Chaos:         true,
+	LiveSearch:    true,
+	SearchExplain: true,
+}
+
+// Flags holds the state of each feature. Flags set remotely, such as in AWS
+// AppConfig, take precedence over those configured in the environment or
+// configuration file, which take precedence over the defaults.
+type Flags struct {
+	mu         sync.RWMutex
+	configured map[string]bool
+	remote     map[string]bool
+}
+
+// New creates flags with the configured values
+func New(configured map[string]bool) *Flags {
+	f := &Flags{}
+	f.SetConfigured(configured)
+
+	return f
+}
+
+// Enabled reports whether a feature is on
+func (f *Flags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if enabled, ok := f.remote[name]; ok {
+		return enabled
+	}
+	if enabled, ok := f.configured[name]; ok {
+		return enabled
+	}
+	return defaults[name]
+}
+
+// SetConfigured replaces the values from the environment or configuration file
+func (f *Flags) SetConfigured(values map[string]bool) {
+	warnUnknown(values)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.configured = values
+}
+
+// SetRemote replaces the values set remotely
+func (f *Flags) SetRemote(values map[string]bool) {
+	warnUnknown(values)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remote = values
+}
+
+// All returns the state of every known feature
+func (f *Flags) All() map[string]bool {
+	all := map[string]bool{}
+	for name := range defaults {
+		all[name] = f.Enabled(name)
+	}
+
+	return all
+}
+
+// Require responds 404 to requests for a route while its feature is off, as
+// if the route wasn't registered
+func (f *Flags) Require(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !f.Enabled(name) {
+			httputil.NewError(c, http.StatusNotFound, fmt.Errorf("feature %s is disabled", name))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// When applies the middleware only while its feature is on
+func (f *Flags) When(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !f.Enabled(name) {
+			c.Next()
+			return
+		}
+
+		handler(c)
+	}
+}
+
+// warnUnknown logs flags that don't gate anything, which are most likely typos
+func warnUnknown(values map[string]bool) {
+	unknown := []string{}
+	for name := range values {
+		if _, ok := defaults[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		slog.Warn("Ignoring unknown feature flag", "flag", name)
+	}
+}
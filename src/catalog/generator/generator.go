@@ -0,0 +1,227 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// IDPrefix starts the ID of every generated product
+const IDPrefix = "generated-"
+
+// maxStock is the most units a generated product has in stock
+const maxStock = 100
+
+// outOfStock is the share of generated products that are sold out
+const outOfStock = 0.1
+
+// priceSpread is the standard deviation of the logarithm of prices, which
+// are log-normally distributed around the median of their category
+const priceSpread = 0.6
+
+// vocabulary is the words products of a kind are named and described with
+type vocabulary struct {
+	nouns []string
+	// median is the typical price, in cents
+	median   float64
+	features []string
+}
+
+// vocabularies are keyed by the name of the category they describe, or of
+// its top-level category
+var vocabularies = map[string]vocabulary{
+	"timepieces": {
+		nouns:    []string{"Tickstopper", "Chronometer", "Pocket Watch", "Hourglass", "Sundial", "Wristwatch"},
+		median:   20000,
+		features: []string{"keeps time to the nanosecond", "pauses the afternoon for a nap", "runs backwards on Sundays", "chimes only when you're late"},
+	},
+	"eyewear": {
+		nouns:    []string{"Spectacles", "Monocle", "Goggles", "Shades", "Lorgnette"},
+		median:   15000,
+		features: []string{"sees through fog and small talk", "dims the glare of bad news", "magnifies the fine print", "hides you from meetings"},
+	},
+	"gadgets": {
+		nouns:    []string{"Teleporter", "Quill", "Spinner", "Gizmo", "Contraption", "Widget"},
+		median:   12000,
+		features: []string{"fits in a coat pocket", "hums a soothing tune", "runs on a single sunbeam", "answers questions you haven't asked"},
+	},
+	"accessories": {
+		nouns:    []string{"Umbrella", "Satchel", "Keyring", "Wallet", "Scarf"},
+		median:   8000,
+		features: []string{"never gets lost", "folds to the size of a coin", "matches any outfit"},
+	},
+	"footwear": {
+		nouns:    []string{"Oxfords", "Sneakers", "Boots", "Loafers", "Slippers"},
+		median:   15000,
+		features: []string{"walks an inch above puddles", "never needs tying", "makes every step a little lighter", "grips the ceiling in a pinch"},
+	},
+	"formalwear": {
+		nouns:    []string{"Tuxedo", "Bow Tie", "Cummerbund", "Evening Gown", "Top Hat"},
+		median:   9000,
+		features: []string{"changes color with the room", "never wrinkles", "adjusts to any dress code"},
+	},
+	"rainwear": {
+		nouns:    []string{"Parasol", "Raincoat", "Galoshes", "Poncho"},
+		median:   8000,
+		features: []string{"repels rain and gloom alike", "floats you gently home", "dries in a single shake"},
+	},
+	"clothing": {
+		nouns:    []string{"Jacket", "Sweater", "Cardigan", "Trousers", "Cape"},
+		median:   7000,
+		features: []string{"keeps you warm in any weather", "never fades", "fits everyone perfectly"},
+	},
+	"confectionery": {
+		nouns:    []string{"Lollipop", "Bonbon", "Toffee", "Gumdrop", "Truffle"},
+		median:   1500,
+		features: []string{"tastes of a forgotten summer", "fizzes for an hour", "changes flavor with every bite"},
+	},
+	"food": {
+		nouns:    []string{"Biscuits", "Preserves", "Tea Blend", "Cocoa"},
+		median:   1200,
+		features: []string{"keeps fresh for a century", "warms you from the inside", "pairs with anything"},
+	},
+	"cars": {
+		nouns:    []string{"Roadster", "Coupe", "Sedan", "Cruiser", "Convertible"},
+		median:   1000000,
+		features: []string{"drives underwater", "parks itself on rooftops", "runs on lemonade", "turns invisible in traffic"},
+	},
+	"motorcycles": {
+		nouns:    []string{"SkyCycle", "Scooter", "Chopper", "Trike"},
+		median:   800000,
+		features: []string{"takes off from any straight road", "purrs instead of roaring", "leans into clouds"},
+	},
+	"vehicles": {
+		nouns:    []string{"Hovercraft", "Airship", "Gyrocopter", "Wagon"},
+		median:   900000,
+		features: []string{"travels by moonlight", "seats a family of giants", "needs no road at all"},
+	},
+}
+
+// general is used for products in categories without a vocabulary, or
+// without a category
+var general = vocabulary{
+	nouns:    []string{"Gadget", "Trinket", "Curio", "Novelty", "Marvel"},
+	median:   5000,
+	features: []string{"delights everyone who sees it", "does something nobody can explain", "comes with a lifetime of wonder"},
+}
+
+var adjectives = []string{
+	"Temporal", "Levitating", "Quantum", "Whispering", "Invisible", "Perpetual",
+	"Clockwork", "Moonlit", "Velvet", "Thunder", "Featherweight", "Phantom",
+	"Gilded", "Silent", "Rocket", "Aurora", "Nebula", "Echo", "Midnight", "Sparkling",
+}
+
+var models = []string{"MK-II", "X-1000", "GT", "Pro", "Mini", "Deluxe", "3000", "Classic"}
+
+var qualities = []string{"Remarkable", "Handcrafted", "Peculiar", "Beloved", "Ingenious", "Elegant"}
+
+// Products makes up count products in the catalog's categories and tags.
+// Products are the same for the same seed, and their IDs are numbered from
+// one under the seed, so generating again with the seed replaces them.
+// Each is placed in a category without subcategories and tagged with its
+// top-level category when that's one of the tags.
+func Products(count int, seed uint64, tags []model.Tag, categories []model.Category) []model.Product {
+	random := rand.New(rand.NewPCG(seed, seed))
+
+	tagNames := map[string]bool{}
+	for _, tag := range tags {
+		tagNames[tag.Name] = true
+	}
+
+	products := make([]model.Product, count)
+	leaves := leafCategories(categories)
+	for i := range products {
+		request := model.ProductRequest{}
+		words := general
+		if len(leaves) > 0 {
+			category := leaves[random.IntN(len(leaves))]
+			top, _, _ := strings.Cut(category.Path, model.CategoryPathSeparator)
+
+			request.Category = category.Name
+			if tagNames[top] {
+				request.Tags = []string{top}
+			}
+			if v, ok := vocabularies[category.Name]; ok {
+				words = v
+			} else if v, ok := vocabularies[top]; ok {
+				words = v
+			}
+		}
+
+		request.Name = name(random, words)
+		request.Description = description(random, words)
+		request.Price = model.Money{Amount: price(random, words.median), Currency: model.DefaultCurrency}
+
+		stock := 0
+		if random.Float64() >= outOfStock {
+			stock = 1 + random.IntN(maxStock)
+		}
+		request.Stock = &stock
+
+		products[i] = *request.ToProduct(fmt.Sprintf("%s%d-%d", IDPrefix, seed, i+1))
+	}
+
+	return products
+}
+
+// leafCategories returns the categories that aren't the parent of another
+func leafCategories(categories []model.Category) []model.Category {
+	parents := map[string]bool{}
+	for _, category := range categories {
+		if category.ParentName != nil {
+			parents[*category.ParentName] = true
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(categories), func(category model.Category) bool {
+		return parents[category.Name]
+	})
+}
+
+func name(random *rand.Rand, words vocabulary) string {
+	name := pick(random, adjectives) + " " + pick(random, words.nouns)
+	if random.IntN(4) == 0 {
+		name += " " + pick(random, models)
+	}
+	return name
+}
+
+func description(random *rand.Rand, words vocabulary) string {
+	first := pick(random, words.features)
+	second := pick(random, words.features)
+	if second == first {
+		return fmt.Sprintf("%s and built to last, it %s.", pick(random, qualities), first)
+	}
+	return fmt.Sprintf("%s and built to last, it %s and %s.", pick(random, qualities), first, second)
+}
+
+// price draws a whole number of dollars, in cents, from a log-normal
+// distribution around the median
+func price(random *rand.Rand, median float64) int {
+	dollars := math.Round(median * math.Exp(priceSpread*random.NormFloat64()) / 100)
+	return int(max(dollars, 1)) * 100
+}
+
+func pick(random *rand.Rand, words []string) string {
+	return words[random.IntN(len(words))]
+}
@@ -0,0 +1,213 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// degradedHeader marks responses answered by a fallback during an outage,
+// as the header of the same name does for the REST API
+const degradedHeader = "x-catalog-degraded"
+
+// Server implements the CatalogService gRPC API on top of CatalogAPI
+type Server struct {
+	catalogv1.UnimplementedCatalogServiceServer
+
+	api *api.CatalogAPI
+}
+
+// NewServer constructor
+func NewServer(api *api.CatalogAPI) *Server {
+	return &Server{
+		api: api,
+	}
+}
+
+// Register adds the catalog, health and reflection services to a gRPC server
+func (s *Server) Register(server *grpc.Server) {
+	catalogv1.RegisterCatalogServiceServer(server, s)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(catalogv1.CatalogService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+}
+
+func (s *Server) GetProduct(ctx context.Context, req *catalogv1.GetProductRequest) (*catalogv1.Product, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	// Drafts aren't shown to clients until they're published
+	product, err := s.api.GetProduct(req.GetId(), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && product.Status == model.StatusDraft {
+		return nil, status.Errorf(codes.NotFound, "product %s not found", req.GetId())
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	return product.ToProto(), nil
+}
+
+func (s *Server) ListProducts(ctx context.Context, req *catalogv1.ListProductsRequest) (*catalogv1.ListProductsResponse, error) {
+	page, size, err := pagination(req.GetPage(), req.GetSize())
+	if err != nil {
+		return nil, err
+	}
+
+	tags := req.GetTags()
+	if tags == nil {
+		tags = []string{}
+	}
+
+	result, err := s.api.GetProductsPage(repository.ProductFilter{Tags: tags, Statuses: []string{model.StatusActive}}, req.GetOrder(), req.GetPageToken(), page, size, ctx)
+	if errors.Is(err, api.ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	return &catalogv1.ListProductsResponse{
+		Products:      model.ProductsToProto(result.Products),
+		NextPageToken: result.NextCursor,
+	}, nil
+}
+
+func (s *Server) SearchProducts(ctx context.Context, req *catalogv1.SearchProductsRequest) (*catalogv1.SearchProductsResponse, error) {
+	if !s.api.IsSearchEnabled() {
+		return nil, status.Error(codes.Unavailable, "search is not enabled")
+	}
+
+	if req.GetKeyword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "keyword is required")
+	}
+
+	page, size, err := pagination(req.GetPage(), req.GetSize())
+	if err != nil {
+		return nil, err
+	}
+
+	searchCtx, degraded := repository.TrackDegraded(ctx)
+	result, err := s.api.SearchProductsPage(repository.SearchQuery{Keyword: req.GetKeyword()}, req.GetPageToken(), page, size, searchCtx)
+	if errors.Is(err, api.ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, serverError(err)
+	}
+
+	// Headers can only be sent on calls made through the server
+	if degraded() {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(degradedHeader, "search"))
+	}
+
+	return &catalogv1.SearchProductsResponse{
+		Products:      model.ProductsToProto(result.Products),
+		NextPageToken: result.NextCursor,
+	}, nil
+}
+
+// Timeouts bounds how long catalog calls can take, as the route timeouts do
+// for the REST API. A zero timeout leaves calls unbounded.
+type Timeouts struct {
+	Read   time.Duration
+	Search time.Duration
+}
+
+// UnaryTimeouts sets a deadline on catalog calls from timeouts, keeping any
+// earlier deadline sent by the client
+func UnaryTimeouts(timeouts Timeouts) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var timeout time.Duration
+		switch info.FullMethod {
+		case catalogv1.CatalogService_GetProduct_FullMethodName, catalogv1.CatalogService_ListProducts_FullMethodName:
+			timeout = timeouts.Read
+		case catalogv1.CatalogService_SearchProducts_FullMethodName:
+			timeout = timeouts.Search
+		}
+
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// Gateway returns a handler for the HTTP rules of the service, which are
+// served as JSON by calling the server directly rather than over a connection
+func (s *Server) Gateway(ctx context.Context) (http.Handler, error) {
+	mux := runtime.NewServeMux()
+	if err := catalogv1.RegisterCatalogServiceHandlerServer(ctx, mux, s); err != nil {
+		return nil, fmt.Errorf("failed to register gateway handlers: %w", err)
+	}
+
+	return mux, nil
+}
+
+// serverError reports a call that ran out of time or was cancelled with the
+// matching code, so clients can tell it apart from a failure and retry
+func serverError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+	var unavailable interface{ Unavailable() bool }
+	if errors.Is(err, repository.ErrBreakerOpen) || (errors.As(err, &unavailable) && unavailable.Unavailable()) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// pagination applies the same defaults as the REST API to unset page fields
+func pagination(page, size int32) (int, int, error) {
+	if page == 0 {
+		page = 1
+	}
+	if size == 0 {
+		size = 10
+	}
+
+	if page < 1 || size < 1 {
+		return 0, 0, status.Error(codes.InvalidArgument, "page and size must be positive")
+	}
+
+	return int(page), int(size), nil
+}
@@ -0,0 +1,332 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// Statuses reported for the service and for each check
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+// checkTimeout bounds how long a single dependency check can take, so that a
+// hung dependency fails the probe rather than timing it out
+const checkTimeout = 2 * time.Second
+
+// detailsTTL is how long the detailed report is reused, so that polling it
+// from dashboards or during a workshop doesn't load the dependencies
+const detailsTTL = 5 * time.Second
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Details describes the state of a dependency, returning an error along with
+// the details when it isn't usable
+type Details func(ctx context.Context) (map[string]any, error)
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string `json:"status" example:"UP"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+	// Details describe the dependency, in the detailed report only
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Report is the body of each health endpoint
+type Report struct {
+	Status string            `json:"status" example:"UP"`
+	Checks map[string]Result `json:"checks,omitempty"`
+	// CheckedAt is when the checks ran, in the detailed report only since it
+	// may be reused
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+}
+
+// Checker serves the liveness, readiness and startup endpoints. Liveness only
+// reflects the process itself, readiness checks that dependencies can be
+// reached, and startup reports how initialization went once it has finished.
+// The detailed report describes each dependency for operators.
+type Checker struct {
+	alive func() bool
+
+	mu        sync.Mutex
+	started   bool
+	stopping  bool
+	startup   map[string]Result
+	readiness map[string]Check
+	details   map[string]Details
+
+	// refreshing is held while the detailed report is refreshed, so that
+	// concurrent requests wait for it rather than checking again
+	refreshing sync.Mutex
+	cached     *Report
+}
+
+// NewChecker creates a checker. alive is consulted by every endpoint so that
+// the service can be made to report itself as unhealthy.
+func NewChecker(alive func() bool) *Checker {
+	return &Checker{
+		alive:     alive,
+		startup:   map[string]Result{},
+		readiness: map[string]Check{},
+		details:   map[string]Details{},
+	}
+}
+
+// AddReadinessCheck registers a dependency that must be reachable for the
+// service to be ready
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readiness[name] = check
+}
+
+// AddDetails adds a dependency to the detailed report
+func (c *Checker) AddDetails(name string, details Details) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.details[name] = details
+}
+
+// RecordStartup records the outcome of an initialization step. Failed steps
+// are reported by the startup endpoint but don't fail it, since the service
+// can run without optional dependencies.
+func (c *Checker) RecordStartup(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.startup[name] = result(err, 0)
+}
+
+// MarkStarted records that initialization has finished
+func (c *Checker) MarkStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.started = true
+}
+
+// MarkStopping records that the service is shutting down, so that readiness
+// fails and load balancers stop sending it new requests
+func (c *Checker) MarkStopping() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopping = true
+}
+
+// Live godoc
+// @Summary Liveness
+// @Description Report whether the process is running
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz [get]
+func (c *Checker) Live(ctx *gin.Context) {
+	respond(ctx, Report{Status: c.status(c.alive())})
+}
+
+// Ready godoc
+// @Summary Readiness
+// @Description Report whether initialization has finished and the database and search index can be reached
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (c *Checker) Ready(ctx *gin.Context) {
+	c.mu.Lock()
+	started, stopping := c.started, c.stopping
+	checks := make(map[string]Details, len(c.readiness))
+	for name, check := range c.readiness {
+		checks[name] = func(ctx context.Context) (map[string]any, error) {
+			return nil, check(ctx)
+		}
+	}
+	c.mu.Unlock()
+
+	report := Report{Checks: run(checks, ctx.Request.Context())}
+	if !started {
+		report.Checks["startup"] = Result{Status: StatusDown, Error: "initialization has not finished"}
+	}
+	if stopping {
+		report.Checks["shutdown"] = Result{Status: StatusDown, Error: "the service is shutting down"}
+	}
+	report.Status = c.status(c.alive() && allUp(report.Checks))
+
+	respond(ctx, report)
+}
+
+// Details reports the state of each dependency in more detail than the
+// readiness probe, such as the database's ping latency and the health of the
+// search cluster. The report is reused for a few seconds.
+func (c *Checker) Details(ctx *gin.Context) {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+
+	if c.cached == nil || time.Since(*c.cached.CheckedAt) >= detailsTTL {
+		c.mu.Lock()
+		checks := make(map[string]Details, len(c.details))
+		for name, details := range c.details {
+			checks[name] = details
+		}
+		c.mu.Unlock()
+
+		checkedAt := time.Now().UTC()
+		results := run(checks, ctx.Request.Context())
+		c.cached = &Report{Checks: results, CheckedAt: &checkedAt, Status: c.status(allUp(results))}
+	}
+
+	// The service being made unhealthy shows straight away
+	report := *c.cached
+	if !c.alive() {
+		report.Status = StatusDown
+	}
+	respond(ctx, report)
+}
+
+// Startup godoc
+// @Summary Startup
+// @Description Report whether initialization has finished, with the outcome of each step
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /startupz [get]
+func (c *Checker) Startup(ctx *gin.Context) {
+	c.mu.Lock()
+	report := Report{Checks: make(map[string]Result, len(c.startup))}
+	for name, r := range c.startup {
+		report.Checks[name] = r
+	}
+	started := c.started
+	c.mu.Unlock()
+
+	report.Status = c.status(started && c.alive())
+	respond(ctx, report)
+}
+
+// Describe documents the health endpoints for the generated OpenAPI document
+func (c *Checker) Describe(spec *openapi.Spec) {
+	tags := []string{"health"}
+	responses := map[int]openapi.Response{
+		http.StatusOK:                 {Body: Report{}},
+		http.StatusServiceUnavailable: {Body: Report{}},
+	}
+
+	spec.Describe(c.Live, openapi.Operation{
+		Summary:     "Liveness",
+		Description: "Report whether the process is running",
+		Tags:        tags,
+		Responses:   responses,
+	})
+
+	spec.Describe(c.Ready, openapi.Operation{
+		Summary:     "Readiness",
+		Description: "Report whether initialization has finished and the database and search index can be reached",
+		Tags:        tags,
+		Responses:   responses,
+	})
+
+	spec.Describe(c.Details, openapi.Operation{
+		Summary:     "Dependency details",
+		Description: "Report the state of each dependency, such as database latency and search cluster health, reused for a few seconds",
+		Tags:        tags,
+		Responses:   responses,
+	})
+
+	spec.Describe(c.Startup, openapi.Operation{
+		Summary:     "Startup",
+		Description: "Report whether initialization has finished, with the outcome of each step",
+		Tags:        tags,
+		Responses:   responses,
+	})
+}
+
+func (c *Checker) status(healthy bool) string {
+	if healthy {
+		return StatusUp
+	}
+	return StatusDown
+}
+
+// run runs checks concurrently, each bounded by checkTimeout
+func run(checks map[string]Details, ctx context.Context) map[string]Result {
+	results := make(map[string]Result, len(checks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check(checkCtx)
+			r := result(err, time.Since(start))
+			r.Details = details
+
+			mu.Lock()
+			results[name] = r
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func allUp(results map[string]Result) bool {
+	for _, r := range results {
+		if r.Status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
+
+func result(err error, latency time.Duration) Result {
+	r := Result{Status: StatusUp, LatencyMs: latency.Milliseconds()}
+	if err != nil {
+		r.Status = StatusDown
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func respond(ctx *gin.Context, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
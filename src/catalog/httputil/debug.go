@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package httputil
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+var started = time.Now()
+
+// RuntimeStats is a summary of the process, for watching during load tests
+type RuntimeStats struct {
+	GoVersion    string        `json:"goVersion"`
+	Uptime       string        `json:"uptime"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	Goroutines   int           `json:"goroutines"`
+	HeapAlloc    uint64        `json:"heapAllocBytes"`
+	HeapInuse    uint64        `json:"heapInuseBytes"`
+	HeapObjects  uint64        `json:"heapObjects"`
+	Sys          uint64        `json:"sysBytes"`
+	NumGC        uint32        `json:"numGC"`
+	LastGCPause  time.Duration `json:"lastGCPauseNanos"`
+	TotalGCPause time.Duration `json:"totalGCPauseNanos"`
+}
+
+// NewDebugHandler serves the pprof profiles under /debug/pprof/, the expvar
+// variables, including memory statistics, on /debug/vars and a summary of
+// the runtime on /debug/runtime. It exposes the internals of the process, so
+// it is only served on the separate debug address.
+func NewDebugHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/runtime", serveRuntimeStats)
+
+	return mux
+}
+
+func serveRuntimeStats(w http.ResponseWriter, r *http.Request) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	stats := RuntimeStats{
+		GoVersion:    runtime.Version(),
+		Uptime:       time.Since(started).Round(time.Second).String(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    memory.HeapAlloc,
+		HeapInuse:    memory.HeapInuse,
+		HeapObjects:  memory.HeapObjects,
+		Sys:          memory.Sys,
+		NumGC:        memory.NumGC,
+		LastGCPause:  time.Duration(memory.PauseNs[(memory.NumGC+255)%256]),
+		TotalGCPause: time.Duration(memory.PauseTotalNs),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -16,10 +16,36 @@
 
 package httputil
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 // NewError example
 func NewError(ctx *gin.Context, status int, err error) {
+	// A server error caused by the request running out of time is reported
+	// as a timeout, so clients can tell it apart and retry
+	if status >= http.StatusInternalServerError && errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+
+	// A dependency turning calls away to protect itself is reported as
+	// unavailable, so clients back off and retry
+	var unavailable interface{ Unavailable() bool }
+	if status >= http.StatusInternalServerError && errors.As(err, &unavailable) && unavailable.Unavailable() {
+		status = http.StatusServiceUnavailable
+	}
+
+	// Handlers see a body cut off at its size limit as malformed
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+
 	er := HTTPError{
 		Code:    status,
 		Message: err.Error(),
@@ -32,3 +58,34 @@ type HTTPError struct {
 	Code    int    `json:"code" example:"400"`
 	Message string `json:"message" example:"status bad request"`
 }
+
+// ProblemContentType is the media type of RFC 9457 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem describes an error in the RFC 9457 problem details format
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Internal Server Error"`
+	Status   int    `json:"status" example:"500"`
+	Detail   string `json:"detail,omitempty" example:"the server encountered an unexpected error"`
+	Instance string `json:"instance,omitempty" example:"/catalog/products/123"`
+}
+
+// NewProblem responds with problem details for the status, identifying the
+// request path as the instance
+func NewProblem(ctx *gin.Context, status int, detail string) {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: ctx.Request.URL.Path,
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		ctx.Status(status)
+		return
+	}
+	ctx.Data(status, ProblemContentType, body)
+}
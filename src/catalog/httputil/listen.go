@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package httputil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// unixPrefix marks a listen address as the path of a Unix domain socket
+const unixPrefix = "unix:"
+
+// ListenAddresses returns the addresses the HTTP server listens on, which
+// default to PORT on every interface
+func ListenAddresses(config config.AppConfiguration) []string {
+	if len(config.Server.Addresses) > 0 {
+		return config.Server.Addresses
+	}
+
+	return []string{":" + strconv.Itoa(config.Port)}
+}
+
+// Listen opens a listener for each address. Unix domain sockets, which let a
+// sidecar proxy reach the server without a TCP port, are created with the
+// configured permissions.
+func Listen(addresses []string, config config.ServerConfiguration) ([]net.Listener, error) {
+	listeners := []net.Listener{}
+
+	for _, address := range addresses {
+		listener, err := listen(address, config)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+func listen(address string, config config.ServerConfiguration) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	// A socket left behind by a process that didn't shut down cleanly would
+	// stop the server from listening, but anything else at the path is kept
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := strconv.ParseUint(config.SocketMode, 8, 32)
+	if err == nil {
+		err = os.Chmod(path, os.FileMode(mode))
+	}
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions of socket %s: %w", path, err)
+	}
+
+	return listener, nil
+}
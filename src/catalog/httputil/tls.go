@@ -0,0 +1,142 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// CertificateReloader serves a TLS certificate loaded from disk, reloading it
+// when the files change so that renewed certificates are picked up without a
+// restart
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	modified    [2]time.Time
+}
+
+// NewCertificateReloader loads the certificate and key, failing if they can't be read
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for use in tls.Config
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.certificate, nil
+}
+
+// Reload loads the certificate again if either file has changed since it was
+// last loaded, and reports whether it did
+func (r *CertificateReloader) Reload() (bool, error) {
+	modified, err := r.lastModified()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.certificate != nil && modified == r.modified
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.certificate = &certificate
+	r.modified = modified
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch checks for changed files every interval until the context is done. A
+// certificate that fails to load is logged and the previous one kept, since
+// the files may be caught part way through being replaced.
+func (r *CertificateReloader) Watch(interval time.Duration, ctx context.Context) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to reload TLS certificate", "error", err)
+			} else if reloaded {
+				slog.InfoContext(ctx, "Reloaded TLS certificate")
+			}
+		}
+	}
+}
+
+// lastModified returns the modification times of the certificate and key,
+// following symlinks as used by Kubernetes secret volumes
+func (r *CertificateReloader) lastModified() ([2]time.Time, error) {
+	var modified [2]time.Time
+
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modified, fmt.Errorf("loading TLS certificate: %w", err)
+		}
+		modified[i] = info.ModTime()
+	}
+
+	return modified, nil
+}
+
+// Protocols returns the HTTP versions the server should accept. HTTP/2 is
+// negotiated over TLS, and can optionally be accepted in cleartext (h2c) for
+// load balancers that speak HTTP/2 to their targets.
+func Protocols(config config.HTTP2Configuration) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(config.Enabled)
+	protocols.SetUnencryptedHTTP2(config.Enabled && config.Cleartext)
+
+	return protocols
+}
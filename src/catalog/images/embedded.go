@@ -0,0 +1,62 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package images
+
+import (
+	"context"
+	"embed"
+	"io"
+	"io/fs"
+)
+
+//go:embed bundled
+var bundled embed.FS
+
+// EmbeddedStore serves the images of the bundled products from the binary
+type EmbeddedStore struct {
+	files fs.FS
+}
+
+// NewEmbeddedStore creates a store of the bundled images
+func NewEmbeddedStore() *EmbeddedStore {
+	files, err := fs.Sub(bundled, "bundled")
+	if err != nil {
+		panic(err)
+	}
+	return &EmbeddedStore{files: files}
+}
+
+// URL returns the path the catalog serves the image under
+func (s *EmbeddedStore) URL(key string) (string, error) {
+	return BasePath + key, nil
+}
+
+// Open opens a bundled image
+func (s *EmbeddedStore) Open(key string) (fs.File, error) {
+	return s.files.Open(key)
+}
+
+// Put returns ErrReadOnly, since bundled images can't be changed
+func (s *EmbeddedStore) Put(key, contentType string, body io.ReadSeeker, ctx context.Context) error {
+	return ErrReadOnly
+}
+
+// Has reports whether there is a bundled image with the key
+func (s *EmbeddedStore) Has(key string) bool {
+	_, err := fs.Stat(s.files, key)
+	return err == nil
+}
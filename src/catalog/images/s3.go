@@ -0,0 +1,123 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Store uploads images to an S3 bucket, and gives either their URL on a CDN
+// in front of the bucket or presigned URLs. The bundled images are still
+// served from the binary.
+type S3Store struct {
+	client  s3iface.S3API
+	signer  *v4.Signer
+	region  string
+	bucket  string
+	prefix  string
+	cdnURL  string
+	expiry  time.Duration
+	bundled *EmbeddedStore
+	now     func() time.Time
+}
+
+// NewS3Store creates a store for the bucket in the configuration, with the
+// region and credentials of the AWS configuration
+func NewS3Store(config config.ImagesConfiguration) (*S3Store, error) {
+	awsConfig := aws.NewConfig()
+	if config.S3Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.S3Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &S3Store{
+		client:  s3.New(sess),
+		signer:  v4.NewSigner(sess.Config.Credentials),
+		region:  aws.StringValue(sess.Config.Region),
+		bucket:  config.S3Bucket,
+		prefix:  config.S3Prefix,
+		cdnURL:  strings.TrimSuffix(config.CDNURL, "/"),
+		expiry:  config.PresignExpiry,
+		bundled: NewEmbeddedStore(),
+		now:     time.Now,
+	}, nil
+}
+
+// URL returns the URL of the image on the CDN if there is one, or else a
+// presigned URL. URLs are signed as of the start of a window of half their
+// expiry, so that they are valid for at least that long and responses, and
+// their ETags, stay the same within the window.
+func (s *S3Store) URL(key string) (string, error) {
+	if s.bundled.Has(key) {
+		return s.bundled.URL(key)
+	}
+	if s.cdnURL != "" {
+		return s.cdnURL + "/" + s.prefix + key, nil
+	}
+
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + key),
+	})
+	if err := req.Build(); err != nil {
+		return "", fmt.Errorf("failed to build the request for image %s: %w", key, err)
+	}
+
+	signed := s.now().Truncate(s.expiry / 2)
+	if _, err := s.signer.Presign(req.HTTPRequest, nil, "s3", s.region, s.expiry, signed); err != nil {
+		return "", fmt.Errorf("failed to presign the URL of image %s: %w", key, err)
+	}
+	return req.HTTPRequest.URL.String(), nil
+}
+
+// Open opens a bundled image, or returns ErrNotServed for one in the bucket
+func (s *S3Store) Open(key string) (fs.File, error) {
+	if s.bundled.Has(key) {
+		return s.bundled.Open(key)
+	}
+	return nil, ErrNotServed
+}
+
+// Put uploads an image to the bucket
+func (s *S3Store) Put(key, contentType string, body io.ReadSeeker, ctx context.Context) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.prefix + key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload image %s: %w", key, err)
+	}
+	return nil
+}
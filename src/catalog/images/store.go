@@ -0,0 +1,67 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package images keeps product images and gives the URLs clients fetch them
+// from. The images of the bundled products are embedded in the binary, and
+// uploads go to S3.
+package images
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// BasePath is the path the catalog serves images under, followed by their key
+const BasePath = "/catalog/images/"
+
+// ErrReadOnly is returned when the store doesn't accept uploads
+var ErrReadOnly = errors.New("the image store does not support uploads")
+
+// ErrNotServed is returned when opening an image that clients fetch from
+// elsewhere rather than from the catalog
+var ErrNotServed = errors.New("the image is not served by the catalog")
+
+// ContentTypes are the types of image that can be uploaded, with the file
+// extension of each
+var ContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// Store keeps product images by key
+type Store interface {
+	// URL returns the URL clients fetch the image with the key from
+	URL(key string) (string, error)
+	// Open opens an image the catalog serves itself under BasePath, or
+	// returns ErrNotServed for one that is fetched from its URL
+	Open(key string) (fs.File, error)
+	// Put stores an image under the key
+	Put(key, contentType string, body io.ReadSeeker, ctx context.Context) error
+}
+
+// New creates the store the configuration asks for
+func New(config config.ImagesConfiguration) (Store, error) {
+	if config.Store == "s3" {
+		return NewS3Store(config)
+	}
+	return NewEmbeddedStore(), nil
+}
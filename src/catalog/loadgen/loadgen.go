@@ -0,0 +1,246 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/buildinfo"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// reportInterval is how often progress is logged while load is generated
+const reportInterval = 10 * time.Second
+
+// Generator replays search and browse traffic against a catalog service at
+// a steady rate. Requests are sent on schedule whether or not earlier ones
+// have been answered, as they would be by independent shoppers, by a pool
+// of workers; a request due while every worker is busy is dropped and
+// counted rather than sent late.
+type Generator struct {
+	target   string
+	rps      float64
+	duration time.Duration
+	workers  int
+	apiKey   string
+	client   *http.Client
+	traffic  *traffic
+}
+
+// Stats counts the requests of one kind of traffic
+type Stats struct {
+	Requests int
+	// Failed counts requests that couldn't be sent or weren't successful,
+	// with Statuses counting the responses by status code
+	Failed     int
+	Statuses   map[int]int
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+// Summary describes the load generated by a run
+type Summary struct {
+	Elapsed time.Duration
+	// Dropped counts the requests that were due while every worker was busy
+	Dropped int
+	Kinds   map[string]*Stats
+}
+
+// New returns a generator for the configured target, rate and duration
+func New(config config.LoadgenConfiguration) (*Generator, error) {
+	traffic, err := newTraffic(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Generator{
+		target:   strings.TrimSuffix(config.Target, "/"),
+		rps:      config.RPS,
+		duration: config.Duration,
+		workers:  config.Workers,
+		apiKey:   config.APIKey,
+		client:   &http.Client{Timeout: config.Timeout},
+		traffic:  traffic,
+	}, nil
+}
+
+// Run sends requests until the duration has passed, if one was configured,
+// or the context is done, and returns a summary of what was sent. Requests
+// still in flight when it stops are abandoned and not counted.
+func (g *Generator) Run(ctx context.Context) *Summary {
+	if g.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.duration)
+		defer cancel()
+	}
+
+	summary := &Summary{Kinds: map[string]*Stats{}}
+	for _, k := range kinds {
+		summary.Kinds[k.kind] = &Stats{Statuses: map[int]int{}}
+	}
+	var mu sync.Mutex
+
+	// Unbuffered, so that a request is only handed over when a worker is
+	// free to send it straight away
+	requests := make(chan request)
+	var wg sync.WaitGroup
+	for range g.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range requests {
+				status, latency, err := g.send(req, ctx)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				summary.Kinds[req.kind].record(status, latency, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	started := time.Now()
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.rps))
+	defer ticker.Stop()
+	report := time.NewTicker(reportInterval)
+	defer report.Stop()
+
+	slog.Info("Generating load", "target", g.target, "rps", g.rps, "duration", g.duration, "workers", g.workers)
+	for ctx.Err() == nil {
+		select {
+		case <-ticker.C:
+			select {
+			case requests <- g.traffic.next():
+			default:
+				mu.Lock()
+				summary.Dropped++
+				mu.Unlock()
+			}
+		case <-report.C:
+			mu.Lock()
+			summary.Elapsed = time.Since(started)
+			summary.logProgress()
+			mu.Unlock()
+		case <-ctx.Done():
+		}
+	}
+
+	close(requests)
+	wg.Wait()
+
+	summary.Elapsed = time.Since(started)
+	return summary
+}
+
+// send sends a request and reads the whole response, returning its status
+// and how long it took
+func (g *Generator) send(req request, ctx context.Context) (int, time.Duration, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, g.target+req.path, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	version, _, _ := buildinfo.Build()
+	httpReq.Header.Set("User-Agent", "catalog-loadgen/"+version)
+	if g.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", g.apiKey)
+	}
+
+	started := time.Now()
+	res, err := g.client.Do(httpReq)
+	if err != nil {
+		return 0, time.Since(started), err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(io.Discard, res.Body)
+	return res.StatusCode, time.Since(started), err
+}
+
+// record counts a request, which failed unless it was answered successfully
+func (s *Stats) record(status int, latency time.Duration, err error) {
+	s.Requests++
+	s.Latency += latency
+	s.MaxLatency = max(s.MaxLatency, latency)
+
+	if status != 0 {
+		s.Statuses[status]++
+	}
+	if err != nil || status >= http.StatusBadRequest {
+		s.Failed++
+	}
+}
+
+// totals adds up the requests of every kind
+func (s *Summary) totals() (requests, failed int) {
+	for _, stats := range s.Kinds {
+		requests += stats.Requests
+		failed += stats.Failed
+	}
+	return requests, failed
+}
+
+// logProgress logs the requests sent so far
+func (s *Summary) logProgress() {
+	requests, failed := s.totals()
+	slog.Info("Load generated so far", "requests", requests, "failed", failed, "dropped", s.Dropped,
+		"rps", fmt.Sprintf("%.1f", float64(requests)/s.Elapsed.Seconds()))
+}
+
+// Log logs the requests of each kind sent during the run, and in total
+func (s *Summary) Log() {
+	for _, k := range kinds {
+		stats := s.Kinds[k.kind]
+		if stats.Requests == 0 {
+			continue
+		}
+
+		var statuses []string
+		for _, status := range slices.Sorted(maps.Keys(stats.Statuses)) {
+			statuses = append(statuses, fmt.Sprintf("%d:%d", status, stats.Statuses[status]))
+		}
+		slog.Info("Load generated", "kind", k.kind, "requests", stats.Requests, "failed", stats.Failed,
+			"statuses", strings.Join(statuses, ","),
+			"mean", (stats.Latency / time.Duration(stats.Requests)).Round(time.Microsecond),
+			"max", stats.MaxLatency.Round(time.Microsecond))
+	}
+
+	requests, failed := s.totals()
+	slog.Info("Load generation finished", "elapsed", s.Elapsed.Round(time.Millisecond), "requests", requests,
+		"failed", failed, "dropped", s.Dropped)
+}
+
+// Err reports whether every request failed, which usually means the target
+// is wrong or not running
+func (s *Summary) Err() error {
+	if requests, failed := s.totals(); requests > 0 && failed == requests {
+		return errors.New("every request failed")
+	}
+	return nil
+}
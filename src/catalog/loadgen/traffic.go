@@ -0,0 +1,233 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package loadgen
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// Kinds of traffic, in the order they are reported
+const (
+	kindSearch    = "search"
+	kindBrowse    = "browse"
+	kindProduct   = "product"
+	kindReference = "reference"
+)
+
+// kinds is the share of requests of each kind, out of 100
+var kinds = []struct {
+	kind   string
+	weight int
+}{
+	{kindSearch, 40},
+	{kindBrowse, 25},
+	{kindProduct, 30},
+	{kindReference, 5},
+}
+
+// keywordCount is how many of the most common words in the seed data are
+// searched for
+const keywordCount = 100
+
+// pageSize is the page size shoppers browse and search with
+const pageSize = 12
+
+// stopWords are too common to be worth searching for
+var stopWords = map[string]bool{
+	"this": true, "that": true, "with": true, "your": true, "from": true,
+	"into": true, "while": true, "when": true, "than": true, "each": true,
+	"their": true, "them": true, "they": true, "will": true, "also": true,
+	"features": true, "includes": true, "including": true,
+}
+
+// request is a request to send and the kind of traffic it is part of
+type request struct {
+	kind string
+	path string
+}
+
+// traffic picks requests resembling shoppers using the store, with a few
+// popular keywords and products accounting for most of them, as in a real
+// store. It isn't safe for concurrent use.
+type traffic struct {
+	rand *rand.Rand
+
+	keywords   []string
+	cumulative []int
+
+	products   []string
+	popularity *rand.Zipf
+
+	tags       []string
+	categories []string
+}
+
+// newTraffic builds the keywords, products, tags and categories requested
+// from the data the catalog is seeded with
+func newTraffic(r *rand.Rand) (*traffic, error) {
+	products, err := repository.LoadProductData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load products: %w", err)
+	}
+	tags, err := repository.LoadProductTagData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+	categories, err := repository.LoadCategoryData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+
+	t := &traffic{rand: r}
+
+	// Words in names are what shoppers remember, so weigh more than those
+	// only found in descriptions
+	counts := map[string]int{}
+	for _, product := range products {
+		for _, word := range words(product.Name) {
+			counts[word] += 3
+		}
+		for _, word := range words(product.Description) {
+			counts[word]++
+		}
+		t.products = append(t.products, product.ID)
+	}
+	t.keywords, t.cumulative = mostCommon(counts, keywordCount)
+
+	// Which products are popular changes with every run
+	r.Shuffle(len(t.products), func(i, j int) {
+		t.products[i], t.products[j] = t.products[j], t.products[i]
+	})
+	t.popularity = rand.NewZipf(r, 1.1, 1, uint64(len(t.products)-1))
+
+	for _, tag := range tags {
+		t.tags = append(t.tags, tag.Name)
+	}
+	for _, category := range categories {
+		t.categories = append(t.categories, category.Name)
+	}
+
+	return t, nil
+}
+
+// words splits text into lower case words worth searching for
+func words(text string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len(word) >= 4 && !stopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// mostCommon returns up to n of the most counted words, with the running
+// total of their counts for picking them in proportion
+func mostCommon(counts map[string]int, n int) ([]string, []int) {
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	slices.SortFunc(words, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	words = words[:min(n, len(words))]
+
+	cumulative := make([]int, len(words))
+	total := 0
+	for i, word := range words {
+		total += counts[word]
+		cumulative[i] = total
+	}
+
+	return words, cumulative
+}
+
+// next picks the next request to send
+func (t *traffic) next() request {
+	n := t.rand.IntN(100)
+	for _, k := range kinds {
+		if n -= k.weight; n < 0 {
+			return t.request(k.kind)
+		}
+	}
+	return t.request(kindSearch)
+}
+
+// request picks a request of the given kind
+func (t *traffic) request(kind string) request {
+	query := url.Values{}
+
+	switch kind {
+	case kindSearch:
+		query.Set("keyword", t.keyword())
+		query.Set("page", fmt.Sprint(t.page()))
+		query.Set("size", fmt.Sprint(pageSize))
+		return request{kind, "/catalog/search?" + query.Encode()}
+	case kindBrowse:
+		switch t.rand.IntN(3) {
+		case 0:
+			query.Set("tags", t.tags[t.rand.IntN(len(t.tags))])
+		case 1:
+			query.Set("category", t.categories[t.rand.IntN(len(t.categories))])
+		}
+		query.Set("page", fmt.Sprint(t.page()))
+		query.Set("size", fmt.Sprint(pageSize))
+		return request{kind, "/catalog/products?" + query.Encode()}
+	case kindProduct:
+		return request{kind, "/catalog/products/" + t.products[t.popularity.Uint64()]}
+	default:
+		if t.rand.IntN(2) == 0 {
+			return request{kind, "/catalog/tags"}
+		}
+		return request{kind, "/catalog/categories"}
+	}
+}
+
+// keyword picks a keyword in proportion to how common it is, misspelling
+// one in ten to exercise fuzzy matching
+func (t *traffic) keyword() string {
+	i, _ := slices.BinarySearch(t.cumulative, t.rand.IntN(t.cumulative[len(t.cumulative)-1])+1)
+	keyword := t.keywords[i]
+
+	if t.rand.IntN(10) == 0 {
+		letters := []rune(keyword)
+		j := t.rand.IntN(len(letters) - 1)
+		letters[j], letters[j+1] = letters[j+1], letters[j]
+		keyword = string(letters)
+	}
+
+	return keyword
+}
+
+// page picks the page of results to request, with fewer shoppers reaching
+// each page than the one before
+func (t *traffic) page() int {
+	page := 1
+	for page < 5 && t.rand.IntN(4) == 0 {
+		page++
+	}
+	return page
+}
@@ -0,0 +1,62 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package logging
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// Export sends the records logged through the default logger to handler as
+// well as writing them out, such as to ship them to an OpenTelemetry
+// collector. Records are redacted before they're exported and filtered by
+// the same level. Configure must be called first, and calling it again stops
+// the export.
+func Export(handler slog.Handler) error {
+	current, ok := slog.Default().Handler().(contextHandler)
+	if !ok {
+		return errors.New("logging must be configured before logs are exported")
+	}
+
+	current.exported = handler
+	slog.SetDefault(slog.New(current))
+	return nil
+}
+
+// redactRecord copies a record with its message and attributes redacted,
+// since only the handlers created by Configure redact what they write
+func redactRecord(record slog.Record) slog.Record {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttrs([]slog.Attr{a})...)
+		return true
+	})
+	return redacted
+}
+
+func redactAttrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			redacted = append(redacted, slog.Attr{Key: a.Key, Value: slog.GroupValue(redactAttrs(a.Value.Group())...)})
+			continue
+		}
+		redacted = append(redacted, redactAttr(nil, a))
+	}
+	return redacted
+}
@@ -0,0 +1,132 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// level is shared by every logger created by Configure so that it can be
+// changed while the service runs
+var level = new(slog.LevelVar)
+
+// Configure makes the default logger, which the log package also writes
+// through, use the given format and level. The format is "text" for
+// console output or "json". Credentials are redacted from every record, see
+// Redact.
+func Configure(format, levelName string, w io.Writer) error {
+	if err := SetLevel(levelName); err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text", "console":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{Handler: handler}))
+	return nil
+}
+
+// SetLevel changes the level of the loggers created by Configure
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+
+	level.Set(l)
+	return nil
+}
+
+// Fatal logs an error and exits the process
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type contextKey struct{}
+
+// With returns a context whose log records carry the given attributes, for
+// example the ID of the request being handled. Records only pick them up
+// when logged with a context, such as with slog.InfoContext.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append(attrsFrom(ctx), slog.Group("", args...).Value.Group()...)
+	return context.WithValue(ctx, contextKey{}, attrs)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs[:len(attrs):len(attrs)]
+}
+
+// contextHandler adds the attributes stored in a context by With to the
+// records logged with it, along with the IDs of the trace and span it
+// carries. Records are sent to the exported handler as well when logs are
+// exported.
+type contextHandler struct {
+	slog.Handler
+	exported slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(attrsFrom(ctx)...)
+
+	// Exported records are correlated with the trace through the context,
+	// and failing to export one doesn't stop it being written out
+	if h.exported != nil && h.exported.Enabled(ctx, record.Level) {
+		h.exported.Handle(ctx, redactRecord(record))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+	if h.exported != nil {
+		handler.exported = h.exported.WithAttrs(redactAttrs(attrs))
+	}
+	return handler
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	handler := contextHandler{Handler: h.Handler.WithGroup(name)}
+	if h.exported != nil {
+		handler.exported = h.exported.WithGroup(name)
+	}
+	return handler
+}
@@ -0,0 +1,160 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces credentials in log records
+const Redacted = "[REDACTED]"
+
+// sensitiveSuffixes end the names of attributes, and of the fields of
+// structs and maps, whose values are credentials. Names are compared in
+// lower case without separators, so that "api_key" and "X-Api-Key" match
+// "apikey", while settings about a credential such as PasswordFile or
+// SecretRefreshInterval are still logged.
+var sensitiveSuffixes = []string{
+	"password", "passwd", "secret", "token", "tokens", "apikey", "apikeys",
+	"authorization", "credentials", "cookie", "privatekey", "secretaccesskey",
+}
+
+var (
+	// urlCredentials matches the user information of URLs, such as an
+	// OpenSearch endpoint echoed in a client error
+	urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]*):[^/\s@]+@`)
+	// dsnCredentials matches the password of MySQL data source names
+	dsnCredentials = regexp.MustCompile(`(\b[^\s:/@]+):[^\s/@]+@(tcp|unix)\(`)
+	// authorizationValues matches the scheme and credentials of an
+	// Authorization header
+	authorizationValues = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]+`)
+	// keyValues matches credentials written as key=value or "key": "value",
+	// including query strings
+	keyValues = regexp.MustCompile(`(?i)\b([a-z_-]*(?:password|passwd|secret|token|api[_-]?key|authorization))(["']?\s*[:=]\s*["']?)([^\s"'&,}]+)`)
+)
+
+// Redact removes credentials from a message, such as the password in a URL
+// or the token in an Authorization header
+func Redact(s string) string {
+	s = urlCredentials.ReplaceAllString(s, "${1}:"+Redacted+"@")
+	s = dsnCredentials.ReplaceAllString(s, "${1}:"+Redacted+"@${2}(")
+	s = authorizationValues.ReplaceAllString(s, "${1} "+Redacted)
+	return keyValues.ReplaceAllStringFunc(s, func(match string) string {
+		parts := keyValues.FindStringSubmatch(match)
+		// The scheme of an Authorization header already redacted is kept
+		if strings.HasPrefix(parts[3], Redacted) || strings.EqualFold(parts[3], "bearer") || strings.EqualFold(parts[3], "basic") {
+			return match
+		}
+		return parts[1] + parts[2] + Redacted
+	})
+}
+
+// isSensitive reports whether an attribute or field name holds a credential
+func isSensitive(name string) bool {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr is the ReplaceAttr hook of the handlers created by Configure.
+// It hides attributes named like credentials, scrubs strings and errors, and
+// redacts the fields of structs and maps such as configuration dumps.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	// Flags and counts such as AllowCredentials aren't credentials themselves
+	if isSensitive(a.Key) && (a.Value.Kind() == slog.KindAny || (a.Value.Kind() == slog.KindString && a.Value.String() != "")) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(v.Error()))
+		default:
+			if redacted, ok := redactComposite(v); ok {
+				return slog.Any(a.Key, redacted)
+			}
+		}
+	}
+	return a
+}
+
+// redactComposite converts structs and maps to their JSON form with the
+// credentials redacted, returning false for other values
+func redactComposite(v any) (any, bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || (t.Kind() != reflect.Struct && t.Kind() != reflect.Map) {
+		return nil, false
+	}
+
+	// Values with a JSON form of their own, such as times, are logged as is
+	if _, ok := v.(json.Marshaler); ok {
+		return nil, false
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, false
+	}
+	return redactValue(decoded), true
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch value.(type) {
+			case string, map[string]any, []any:
+				if isSensitive(key) && value != "" {
+					v[key] = Redacted
+					continue
+				}
+			}
+			v[key] = redactValue(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	case string:
+		return Redact(v)
+	}
+	return v
+}
@@ -18,33 +18,58 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/audit"
+	"github.com/aws-containers/retail-store-sample-app/catalog/buildinfo"
+	"github.com/aws-containers/retail-store-sample-app/catalog/cache"
 	"github.com/aws-containers/retail-store-sample-app/catalog/config"
 	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	"github.com/aws-containers/retail-store-sample-app/catalog/events"
+	"github.com/aws-containers/retail-store-sample-app/catalog/features"
+	"github.com/aws-containers/retail-store-sample-app/catalog/grpcserver"
+	"github.com/aws-containers/retail-store-sample-app/catalog/health"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/images"
+	"github.com/aws-containers/retail-store-sample-app/catalog/loadgen"
+	"github.com/aws-containers/retail-store-sample-app/catalog/logging"
 	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/openapi"
 	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	ginprometheus "github.com/zsais/go-gin-prometheus"
+	"google.golang.org/grpc"
 
+	"go.opentelemetry.io/contrib/bridges/otelslog"
 	"go.opentelemetry.io/contrib/detectors/aws/ec2"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/contrib/propagators/aws/xray"
 	"go.opentelemetry.io/otel"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
@@ -61,88 +86,252 @@ import (
 func main() {
 	ctx := context.Background()
 
+	config, err := config.Load(ctx)
+	if err != nil {
+		logging.Fatal("Failed to load configuration", "error", err)
+	}
+
+	if err := logging.Configure(config.Logging.Format, config.Logging.Level, os.Stdout); err != nil {
+		logging.Fatal("Failed to configure logging", "error", err)
+	}
+	slog.Debug("Loaded configuration", "config", config)
+
+	// Workshops can swap in their own catalog without rebuilding the image.
+	// It's loaded before the loadgen command too, which searches for words
+	// from the products.
+	if config.Seed.URL != "" {
+		data, err := repository.WaitFor("seed data", config.Startup, func() ([]byte, error) {
+			return repository.FetchProductData(config.Seed, ctx)
+		}, ctx)
+		if err != nil {
+			logging.Fatal("Failed to download product data", "url", config.Seed.URL, "error", err)
+		}
+		format := repository.ProductDataFormat(config.Seed)
+		if err := repository.UseProductData(data, format); err != nil {
+			logging.Fatal("Invalid product data", "url", config.Seed.URL, "format", format, "error", err)
+		}
+		slog.Info("Using downloaded product data", "url", config.Seed.URL, "format", format, "bytes", len(data))
+	}
+
+	// The same binary replays traffic against a running service when given
+	// the loadgen command, so that workshops don't need a separate tool
+	if len(os.Args) > 1 {
+		if os.Args[1] != "loadgen" {
+			logging.Fatal("Unknown command, the only command is loadgen", "command", os.Args[1])
+		}
+		runLoadgen(config.Loadgen, ctx)
+		return
+	}
+
 	_, otelPresent := os.LookupEnv("OTEL_SERVICE_NAME")
 
 	if otelPresent {
-		_, err := initTracer(ctx)
+		tp, err := initTracer(config.Tracing, ctx)
 		if err != nil {
-			log.Fatal(err)
+			logging.Fatal("Failed to initialize tracing", "error", err)
 		}
+
+		// Export the spans still buffered when the server exits
+		defer tp.Shutdown(context.Background())
+	}
+
+	if config.Logging.OTLP {
+		lp, err := initLogExporter(ctx)
+		if err != nil {
+			logging.Fatal("Failed to initialize log export", "error", err)
+		}
+
+		// Export the records still buffered when the server exits
+		defer lp.Shutdown(context.Background())
 	}
 
-	var config config.AppConfiguration
-	if err := envconfig.Process(ctx, &config); err != nil {
-		log.Fatal(err)
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+
+	flags := newFeatureFlags(config.Features, watchCtx)
+
+	requestMetrics, panicMetrics, closeMetrics := newMetrics(config.Metrics)
+	defer closeMetrics()
+
+	chaosController := middleware.NewChaosController()
+	checker := health.NewChecker(chaosController.IsHealthy)
+
+	db, err := repository.WaitFor("database", config.Startup, func() (repository.CatalogRepository, error) {
+		return repository.NewRepository(config.Database)
+	}, ctx)
+	if err != nil {
+		logging.Fatal("Failed to initialize the database", "error", err)
 	}
+	checker.RecordStartup("database", nil)
 
-	db, err := repository.NewRepository(config.Database)
+	searchRepo, err := repository.WaitFor("search", config.Startup, func() (repository.SearchRepository, error) {
+		return repository.NewSearchRepository(config, db)
+	}, ctx)
 	if err != nil {
-		log.Fatal(err)
+		slog.Warn("Failed to initialize search", "error", err)
+		checker.RecordStartup("search", err)
+	} else if searchRepo == nil {
+		slog.Info("Search is disabled")
+	} else {
+		checker.RecordStartup("search", nil)
 	}
 
-	// Initialize OpenSearch if enabled
-	var searchRepo repository.SearchRepository
-	if config.OpenSearch.Enabled {
-		fmt.Println("OpenSearch is enabled, initializing...")
-		osRepo, err := repository.NewOpenSearchRepository(config.OpenSearch)
-		if err != nil {
-			log.Printf("Warning: Failed to initialize OpenSearch: %v\n", err)
-		} else {
-			// Initialize OpenSearch data
-			if err := osRepo.InitializeData(); err != nil {
-				log.Printf("Warning: Failed to initialize OpenSearch data: %v\n", err)
-			} else {
-				searchRepo = osRepo
-				fmt.Println("OpenSearch initialized successfully")
-			}
+	// Keep the search index in step with product changes when both sides support it
+	if writable, ok := db.(repository.WritableCatalogRepository); ok {
+		if indexer, ok := searchRepo.(repository.SearchIndexer); ok {
+			slog.Info("Dual-write to the database and search index is enabled")
+			db = repository.NewDualWriteRepository(writable, indexer)
 		}
-	} else {
-		fmt.Println("OpenSearch is disabled")
 	}
 
 	api, err := api.NewCatalogAPI(db, searchRepo)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to create the catalog API", "error", err)
+	}
+	api.SetCursorSecret(config.Pagination.CursorSecret)
+	api.SetSitemapOptions(config.Sitemap.BaseURL, config.Sitemap.PageSize)
+	api.SetReindexOptions(config.Search.ReindexWorkers, config.Search.ReindexBatchSize)
+	imageStore, err := images.New(config.Images)
+	if err != nil {
+		logging.Fatal("Failed to create the image store", "error", err)
+	}
+	if config.Images.Store == "s3" {
+		slog.Info("Uploading product images to S3", "bucket", config.Images.S3Bucket, "cdn", config.Images.CDNURL)
+	}
+	api.SetImageStore(imageStore)
+	if config.Cache.Enabled {
+		slog.Info("Caching product lookups and search results", "size", config.Cache.Size, "ttl", config.Cache.TTL)
+		api.SetCache(config.Cache.Size, config.Cache.TTL)
+	}
+	sharedCache := newRedisCache(config.Cache.Redis)
+	if sharedCache != nil {
+		ttls := config.Cache.Redis
+		api.SetSharedCache(sharedCache, ttls.ProductTTL, ttls.SearchTTL, ttls.ReferenceTTL)
+		checker.AddDetails("cache", sharedCache.Status)
+	}
+	if config.Audit.Enabled {
+		api.SetAuditLog(newAuditLog(config))
+	}
+	eventPublisher := newKafkaPublisher(config.Events.Kafka, ctx)
+	if eventPublisher != nil {
+		api.Events().AddSink(eventPublisher)
+	}
+
+	// Stopped before the background jobs at shutdown, since reindex
+	// requests start them
+	indexQueue := newIndexQueueConsumer(config.Search.Queue, api, searchRepo)
+	indexQueueCtx, stopIndexQueue := context.WithCancel(ctx)
+	defer stopIndexQueue()
+	indexQueueDone := make(chan struct{})
+	go func() {
+		defer close(indexQueueDone)
+		if indexQueue != nil {
+			indexQueue.Run(indexQueueCtx)
+		}
+	}()
+
+	if pinger, ok := db.(repository.Pinger); ok {
+		checker.AddReadinessCheck("database", pinger.Ping)
+	}
+	if pinger, ok := searchRepo.(repository.Pinger); ok {
+		checker.AddReadinessCheck("search", pinger.Ping)
+	}
+	if reporter, ok := db.(repository.StatusReporter); ok {
+		checker.AddDetails("database", reporter.Status)
+	}
+	if reporter, ok := searchRepo.(repository.StatusReporter); ok {
+		checker.AddDetails("search", reporter.Status)
+	}
+
+	// Gin's own output, such as the routes it registers, goes through the
+	// logger so that it is in the same format as everything else
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("Route registered", "method", method, "path", path, "handler", handler)
 	}
 
 	r := gin.New()
-	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{
-		SkipPaths: []string{"/health"},
-	}))
+	r.Use(middleware.RequestID())
+	r.Use(requestMetrics)
+	r.Use(middleware.NewAccessLog(config.AccessLog, "/health", "/healthz", "/readyz", "/startupz"))
+
+	// Inside the metrics and access log, so that they see the 500 a panic
+	// is turned into
+	r.Use(middleware.Recovery(panicMetrics))
+
+	// Registered before any routes so that preflight requests, which have no
+	// matching OPTIONS route, are still answered
+	r.Use(middleware.NewCORS(config.CORS))
+	r.Use(middleware.NewCompression(config.Compression))
 
-	p := ginprometheus.NewPrometheus("gin")
-	p.Use(r)
+	if config.Metrics.Exporter == "prometheus" {
+		p := ginprometheus.NewPrometheus("gin")
+		r.Use(p.HandlerFunc())
+
+		// Served in the OpenMetrics format to scrapers that accept it, which
+		// is the only one carrying the trace exemplars of the histograms
+		r.GET(p.MetricsPath, gin.WrapH(promhttp.InstrumentMetricHandler(
+			prometheus.DefaultRegisterer,
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+		)))
+	}
 
 	c, err := controller.NewController(api)
 	if err != nil {
-		log.Fatalln("Error creating controller", err)
+		logging.Fatal("Failed to create the controller", "error", err)
 	}
-
-	chaosController := middleware.NewChaosController()
+	rates := newExchangeRates(config.Currency, watchCtx)
+	c.SetExchangeRates(rates)
+	c.SetMaxImageSize(config.Images.MaxSize)
 
 	chaosController.SetupChaosRoutes(r)
 
-	catalog := r.Group("/catalog")
+	apiKeys, err := middleware.LoadAPIKeys(config.Auth, ctx)
+	if err != nil {
+		logging.Fatal("Failed to load API keys", "error", err)
+	}
+
+	auth := middleware.NewAuth(
+		middleware.NewAPIKeyAuth(apiKeys),
+		middleware.NewJWTAuth(config.Auth.JWT),
+	)
 
-	catalog.Use(chaosController.ChaosMiddleware())
-	catalog.Use(otelgin.Middleware("catalog-server"))
+	routes := newRouteMiddleware(config, chaosController, auth, flags)
 
-	catalog.GET("/products", c.GetProducts)
+	// Rate limits, search tuning, feature flags, exchange rates and the log level follow
+	// changes to the configuration file, or SIGHUP, without a restart
+	go newReloader(routes, searchRepo, rates).Watch(config.Reload.Interval, watchCtx)
 
-	catalog.GET("/size", c.CatalogSize)
-	catalog.GET("/tags", c.ListTags)
-	catalog.GET("/products/:id", c.GetProduct)
-	catalog.GET("/search", c.SearchProducts)
-	catalog.POST("/reindex", c.ReindexProducts)
+	// /catalog is kept as an unversioned alias of /v1/catalog so existing
+	// consumers are unaffected by changes to response shapes in later versions
+	for _, prefix := range []string{"/catalog", "/v1/catalog"} {
+		catalogRoutes(r.Group(prefix), c, routes, 1)
+	}
+	catalogRoutes(r.Group("/v2/catalog"), c, routes, 2)
+	adminRoutes(r.Group("/admin"), c, routes, config.Admin)
+	sitemapRoutes(r, c, routes)
 
-	r.GET("/health", func(c *gin.Context) {
-		if !chaosController.IsHealthy() {
-			c.AbortWithError(503, fmt.Errorf("health check failed"))
-			return
+	// The gRPC-Gateway serves the HTTP rules in the proto definition, so the
+	// REST and gRPC APIs generated from it can't drift apart
+	if config.Gateway.Enabled {
+		gateway, err := grpcserver.NewServer(api).Gateway(ctx)
+		if err != nil {
+			logging.Fatal("Failed to create the gRPC-Gateway", "error", err)
 		}
 
-		c.String(http.StatusOK, "OK")
-	})
+		slog.Info("gRPC-Gateway is enabled on /gateway")
+		gatewayRoutes(r.Group("/gateway"), gateway, routes)
+	}
+
+	r.GET("/healthz", checker.Live)
+	r.GET("/readyz", checker.Ready)
+	r.GET("/startupz", checker.Startup)
+
+	// Kept for probes configured before the split into the endpoints above
+	r.GET("/health", checker.Live)
+	r.GET("/health/details", checker.Details)
 
 	r.GET("/topology", func(c *gin.Context) {
 		topology := make(map[string]string)
@@ -150,48 +339,754 @@ func main() {
 		topology["persistenceProvider"] = config.Database.Type
 		topology["databaseEndpoint"] = "N/A"
 
-		if config.Database.Type != "in-memory" {
+		if config.Database.Type == "sqlite" {
+			topology["databaseEndpoint"] = config.Database.Path
+		} else if config.Database.Type != "in-memory" {
 			topology["databaseEndpoint"] = config.Database.Endpoint
 		}
 
+		if config.Database.ReaderEndpoint != "" {
+			topology["databaseReaderEndpoint"] = config.Database.ReaderEndpoint
+		}
+
 		c.JSON(http.StatusOK, topology)
 	})
 
+	r.GET("/info", newInfoHandler(config, searchRepo, flags))
+
+	// The OpenAPI document is generated from the routes registered above
+	spec := openapi.New(openapi.Info{
+		Title:       "Catalog API",
+		Description: "This API serves the product catalog",
+		Version:     "1.0",
+		License: &openapi.License{
+			Name: "Apache 2.0",
+			URL:  "http://www.apache.org/licenses/LICENSE-2.0.html",
+		},
+	})
+	c.Describe(spec)
+	checker.Describe(spec)
+
+	r.GET("/openapi.json", spec.Handler(r))
+	r.GET("/swagger-ui", openapi.SwaggerUI("Catalog API", "/openapi.json"))
+
 	srv := &http.Server{
-		Addr:    ":" + strconv.Itoa(config.Port),
-		Handler: r,
+		Handler:   r,
+		Protocols: httputil.Protocols(config.HTTP2),
+	}
+
+	// Terminate TLS in the server when a certificate is configured, picking
+	// up renewed certificates as they are written
+	tlsEnabled := config.TLS.CertFile != "" || config.TLS.KeyFile != ""
+	if tlsEnabled {
+		certificates, err := httputil.NewCertificateReloader(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			logging.Fatal("Failed to load the TLS certificate", "error", err)
+		}
+
+		go certificates.Watch(config.TLS.ReloadInterval, watchCtx)
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certificates.GetCertificate,
+		}
+
+		slog.Info("TLS is enabled")
+	}
+
+	// Event streams are long-lived, so end them rather than waiting out the
+	// shutdown timeout
+	srv.RegisterOnShutdown(api.Events().Close)
+
+	// The server can listen on several addresses at once, including Unix
+	// domain sockets for sidecar proxies
+	addresses := httputil.ListenAddresses(config)
+	listeners, err := httputil.Listen(addresses, config.Server)
+	if err != nil {
+		logging.Fatal("Failed to listen", "error", err)
 	}
 
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
+	for _, listener := range listeners {
+		go func() {
+			var err error
+			if tlsEnabled {
+				err = srv.ServeTLS(listener, "", "")
+			} else {
+				err = srv.Serve(listener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logging.Fatal("Failed to serve", "address", listener.Addr().String(), "error", err)
+			}
+		}()
+	}
+
+	slog.Info("Listening", "addresses", addresses)
+
+	// Serve the gRPC API on its own port, sharing the same CatalogAPI
+	var grpcServer *grpc.Server
+	if config.GRPC.Enabled {
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(config.GRPC.Port))
+		if err != nil {
+			logging.Fatal("Failed to listen for gRPC", "error", err)
 		}
-	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 5 seconds.
+		grpcServer = grpc.NewServer(grpc.UnaryInterceptor(grpcserver.UnaryTimeouts(grpcserver.Timeouts{
+			Read:   config.Routes.ReadTimeout,
+			Search: config.Routes.SearchTimeout,
+		})))
+		grpcserver.NewServer(api).Register(grpcServer)
+
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logging.Fatal("Failed to serve gRPC", "error", err)
+			}
+		}()
+
+		slog.Info("gRPC server listening", "port", config.GRPC.Port)
+	}
+
+	// Profiles and runtime statistics are served apart from the API, so that
+	// they can't be reached through the port exposed to clients
+	var debugServer *http.Server
+	if config.Debug.Enabled {
+		listener, err := net.Listen("tcp", config.Debug.Address)
+		if err != nil {
+			logging.Fatal("Failed to listen for debug requests", "error", err)
+		}
+
+		debugServer = &http.Server{Handler: httputil.NewDebugHandler()}
+
+		go func() {
+			if err := debugServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				logging.Fatal("Failed to serve debug requests", "error", err)
+			}
+		}()
+
+		slog.Info("Debug endpoints are enabled", "address", listener.Addr().String())
+	}
+
+	// Readiness waits for the warm-up, while liveness is already served
+	if config.Startup.WarmUp {
+		checker.RecordStartup("warmUp", warmUp(api, config.Startup, ctx))
+	}
+
+	// Configured faults start once initialization, which they would
+	// otherwise fail, has finished, as do the bulkheads, which protect
+	// serving requests rather than startup
+	injectFaults(config.Chaos, flags)
+	setBulkheads(config.Bulkhead)
+
+	checker.MarkStarted()
+
+	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	// kill (no param) default send syscall.SIGTERM
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall.SIGKILL but can't be catch, so don't need add it
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server", "delay", config.Shutdown.Delay, "gracePeriod", config.Shutdown.GracePeriod)
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Fail readiness first and keep serving for a while, so that load
+	// balancers stop routing new requests before the listeners close
+	checker.MarkStopping()
+	time.Sleep(config.Shutdown.Delay)
+
+	// The context is used to inform the servers and background jobs how long
+	// they have left to finish what they are doing
+	ctx, cancel := context.WithTimeout(context.Background(), config.Shutdown.GracePeriod)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		slog.Warn("Requests were still in progress when the grace period ended", "error", err)
+	}
+
+	if grpcServer != nil {
+		stopGRPC(grpcServer, ctx)
+	}
+
+	stopIndexQueue()
+	select {
+	case <-indexQueueDone:
+	case <-ctx.Done():
+	}
+
+	if err := api.Shutdown(ctx); err != nil {
+		slog.Warn("Background jobs were cancelled when the grace period ended", "error", err)
+	}
+
+	// Profiles in progress are of no use once the server has stopped
+	if debugServer != nil {
+		debugServer.Close()
+	}
+
+	// Background jobs publish events too, so the last are sent once they
+	// have finished
+	if err := eventPublisher.Close(); err != nil {
+		slog.Warn("Failed to send the remaining events to Kafka", "error", err)
+	}
+
+	closeRepository("search", searchRepo)
+	closeRepository("database", db)
+	if err := sharedCache.Close(); err != nil {
+		slog.Warn("Failed to close the Redis cache", "error", err)
+	}
+
+	slog.Info("Server exiting")
+}
+
+// stopGRPC waits for in-flight calls to finish, stopping the server
+// immediately if ctx ends first
+func stopGRPC(server *grpc.Server, ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("gRPC calls were still in progress when the grace period ended")
+		server.Stop()
+	}
+}
+
+// runLoadgen generates load until the configured duration has passed or
+// the process is interrupted, exiting with an error if every request failed
+func runLoadgen(config config.LoadgenConfiguration, ctx context.Context) {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	generator, err := loadgen.New(config)
+	if err != nil {
+		logging.Fatal("Failed to start load generation", "error", err)
+	}
+
+	summary := generator.Run(ctx)
+	summary.Log()
+	if err := summary.Err(); err != nil {
+		logging.Fatal("Load generation failed", "target", config.Target, "error", err)
+	}
+}
+
+// warmUp primes connections and caches with the requests a storefront makes
+// first, returning an error if every one of them failed
+func warmUp(catalogAPI *api.CatalogAPI, config config.StartupConfiguration, ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.WarmUpTimeout)
+	defer cancel()
+
+	slog.Info("Warming up", "concurrency", config.WarmUpConcurrency)
+	result := catalogAPI.WarmUp(config.WarmUpConcurrency, ctx)
+	slog.Info("Warmed up", "requests", result.Requests, "failed", result.Failed, "took", result.Took)
+
+	return result.Err()
+}
+
+// newKafkaPublisher connects to the configured Kafka topic, registering the
+// event schema first when there is a schema registry
+func newKafkaPublisher(config config.KafkaConfiguration, ctx context.Context) *events.KafkaPublisher {
+	if len(config.Brokers) == 0 {
+		return nil
+	}
+
+	encoder, err := events.NewEncoder(config.Format)
+	if err != nil {
+		logging.Fatal("Failed to create the event encoder", "error", err)
+	}
+	// Events are the values of the topic, named as the registry's
+	// serializers name them by default
+	if config.SchemaRegistryURL != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		if err := encoder.Register(config.SchemaRegistryURL, config.Topic+"-value", client, ctx); err != nil {
+			logging.Fatal("Failed to register the event schema", "error", err)
+		}
+	}
+
+	writer, err := events.NewKafkaWriter(config)
+	if err != nil {
+		logging.Fatal("Failed to create the Kafka writer", "error", err)
+	}
+
+	slog.Info("Publishing catalog events to Kafka", "brokers", config.Brokers, "topic", config.Topic, "format", config.Format, "auth", config.Auth)
+	return events.NewKafkaPublisher(writer, encoder)
+}
+
+// newIndexQueueConsumer consumes the SQS queue of product changes and reindex
+// requests for the search index, or returns nil if none is configured or the
+// search provider can't update single products
+func newIndexQueueConsumer(config config.IndexQueueConfiguration, catalogAPI *api.CatalogAPI, searchRepo repository.SearchRepository) *events.SQSConsumer {
+	if config.URL == "" {
+		return nil
+	}
+
+	if _, ok := searchRepo.(repository.SearchIndexer); !ok {
+		slog.Warn("Not consuming the index queue, since the search provider can't update single products", "queue", config.URL)
+		return nil
+	}
+
+	client, err := events.NewSQSClient(config)
+	if err != nil {
+		logging.Fatal("Failed to create the SQS client", "error", err)
+	}
+
+	slog.Info("Applying product changes and reindex requests from SQS to the search index", "queue", config.URL, "batchSize", config.BatchSize, "waitTime", config.WaitTime)
+	return events.NewSQSConsumer(client, config.URL, catalogAPI, config.BatchSize, config.WaitTime)
+}
+
+// newRedisCache connects to the Redis cache shared by every replica, or
+// returns nil if none is configured. Connections are made when the cache is
+// first used, so that the service starts while Redis is unavailable and
+// reads without the cache until it is back.
+func newRedisCache(config config.RedisCacheConfiguration) *cache.Redis {
+	if config.Endpoint == "" {
+		return nil
+	}
+
+	// Failed calls aren't retried, since the value can be read from the
+	// repositories instead
+	options := &redis.UniversalOptions{
+		Addrs:        []string{config.Endpoint},
+		Password:     config.Password,
+		DialTimeout:  config.Timeout,
+		ReadTimeout:  config.Timeout,
+		WriteTimeout: config.Timeout,
+		MaxRetries:   -1,
+	}
+	if config.TLS {
+		options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	slog.Info("Caching catalog reads in Redis", "endpoint", config.Endpoint, "cluster", config.Cluster)
+
+	var client redis.UniversalClient
+	if config.Cluster {
+		client = redis.NewClusterClient(options.Cluster())
+	} else {
+		simple := options.Simple()
+		simple.DialerRetries = 1
+		client = redis.NewClient(simple)
+	}
+	return cache.NewRedis(client, config.KeyPrefix, config.LockWait)
+}
+
+// closeRepository closes the connections held by a repository, if it has any
+func closeRepository(name string, repo any) {
+	closer, ok := repo.(io.Closer)
+	if !ok {
+		return
+	}
+
+	if err := closer.Close(); err != nil {
+		slog.Warn("Failed to close repository", "repository", name, "error", err)
+	}
+}
+
+// multipartOverhead is how much larger than the image an upload's body can be
+const multipartOverhead = 64 << 10
+
+// routeMiddleware holds the middleware applied to catalog routes. It is shared
+// between API versions so that, for example, each client has one rate limit budget.
+type routeMiddleware struct {
+	chaos       *middleware.ChaosController
+	features    *features.Flags
+	auth        *middleware.Auth
+	readAuth    gin.HandlerFunc
+	readLimit   gin.HandlerFunc
+	searchLimit gin.HandlerFunc
+	writeLimit  gin.HandlerFunc
+	limiters    map[string]*middleware.RateLimiter
+	readSize    gin.HandlerFunc
+	readTime    gin.HandlerFunc
+	searchSize  gin.HandlerFunc
+	searchTime  gin.HandlerFunc
+	writeSize   gin.HandlerFunc
+	writeTime   gin.HandlerFunc
+	adminSize   gin.HandlerFunc
+	adminTime   gin.HandlerFunc
+	imageSize   gin.HandlerFunc
+	idempotency gin.HandlerFunc
+	ifMatch     gin.HandlerFunc
+}
+
+func newRouteMiddleware(config config.AppConfiguration, chaos *middleware.ChaosController, auth *middleware.Auth, flags *features.Flags) routeMiddleware {
+	routes := routeMiddleware{
+		chaos:       chaos,
+		features:    flags,
+		auth:        auth,
+		readAuth:    func(c *gin.Context) { c.Next() },
+		idempotency: middleware.NewIdempotency(config.Idempotency.TTL).Middleware(),
+		ifMatch:     func(c *gin.Context) { c.Next() },
+	}
+
+	if config.Concurrency.RequireIfMatch {
+		slog.Info("Product updates require an If-Match header")
+		routes.ifMatch = middleware.RequireIfMatch()
+	}
+
+	if auth.Enabled() {
+		slog.Info("Authentication is enabled for write and admin endpoints")
+	}
+
+	// Reads stay public unless tokens must carry a read scope
+	if config.Auth.JWT.Issuer != "" && config.Auth.JWT.ReadScope != "" {
+		slog.Info("Authentication is enabled for read endpoints")
+		routes.readAuth = auth.Require(middleware.PermissionRead)
+	}
+
+	// Limiters are created even when disabled so that reloading the
+	// configuration can turn them on
+	limits := config.RateLimit
+	routes.limiters = map[string]*middleware.RateLimiter{
+		"read":   middleware.NewRateLimiter("read", limits.ReadRate, limits.ReadBurst),
+		"search": middleware.NewRateLimiter("search", limits.SearchRate, limits.SearchBurst),
+		"write":  middleware.NewRateLimiter("write", limits.WriteRate, limits.WriteBurst),
+	}
+	routes.readLimit = routes.limiters["read"].Middleware()
+	routes.searchLimit = routes.limiters["search"].Middleware()
+	routes.writeLimit = routes.limiters["write"].Middleware()
+	routes.setRateLimits(limits)
+
+	if limits.Enabled {
+		slog.Info("Rate limiting is enabled")
+	}
+
+	// Body sizes and timeouts are separate so that streaming routes can
+	// keep the body limit of their group without being cut off
+	bounds := config.Routes
+	routes.readSize = middleware.NewRequestLimits(0, bounds.ReadMaxBodySize)
+	routes.readTime = middleware.NewRequestLimits(bounds.ReadTimeout, 0)
+	routes.searchSize = middleware.NewRequestLimits(0, bounds.SearchMaxBodySize)
+	routes.searchTime = middleware.NewRequestLimits(bounds.SearchTimeout, 0)
+	routes.writeSize = middleware.NewRequestLimits(0, bounds.WriteMaxBodySize)
+	routes.writeTime = middleware.NewRequestLimits(bounds.WriteTimeout, 0)
+	routes.adminSize = middleware.NewRequestLimits(0, bounds.AdminMaxBodySize)
+	routes.adminTime = middleware.NewRequestLimits(bounds.AdminTimeout, 0)
+	// leaving room in image uploads for the rest of the multipart form
+	routes.imageSize = middleware.NewRequestLimits(0, config.Images.MaxSize+multipartOverhead)
+
+	return routes
+}
+
+// setRateLimits applies rate limit configuration to the existing limiters
+func (routes routeMiddleware) setRateLimits(limits config.RateLimitConfiguration) {
+	routes.limiters["read"].SetLimit(limits.ReadRate, limits.ReadBurst)
+	routes.limiters["search"].SetLimit(limits.SearchRate, limits.SearchBurst)
+	routes.limiters["write"].SetLimit(limits.WriteRate, limits.WriteBurst)
+
+	for _, limiter := range routes.limiters {
+		limiter.SetEnabled(limits.Enabled)
+	}
+}
+
+// newMetrics registers the repository and runtime metrics, returning the
+// middleware that records request metrics and a function that flushes any
+// metrics not yet sent
+func newMetrics(config config.MetricsConfiguration) (gin.HandlerFunc, middleware.PanicObserver, func()) {
+	if config.Exporter == "dogstatsd" {
+		client, err := statsd.New(config.StatsDAddress)
+		if err != nil {
+			logging.Fatal("Failed to create the DogStatsD client", "error", err)
+		}
+
+		slog.Info("Sending metrics to DogStatsD")
+		repository.RegisterStatsDMetrics(client)
+		cache.RegisterStatsDMetrics(client)
+		return middleware.NewStatsDRequestMetrics(client), middleware.NewStatsDPanicMetrics(client), func() { client.Close() }
+	}
+
+	if err := repository.RegisterMetrics(config.RepositoryBuckets, prometheus.DefaultRegisterer); err != nil {
+		logging.Fatal("Failed to register repository metrics", "error", err)
+	}
+	if err := cache.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		logging.Fatal("Failed to register cache metrics", "error", err)
+	}
+
+	requestMetrics, err := middleware.NewRequestMetrics(config.RequestBuckets, prometheus.DefaultRegisterer)
+	if err != nil {
+		logging.Fatal("Failed to register request metrics", "error", err)
+	}
+
+	panicMetrics, err := middleware.NewPanicMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		logging.Fatal("Failed to register panic metrics", "error", err)
 	}
 
-	log.Println("Server exiting")
+	// Replace the default Go collector with one that also reports garbage
+	// collection, memory and scheduler metrics from the runtime
+	if config.Runtime {
+		prometheus.Unregister(collectors.NewGoCollector())
+		prometheus.MustRegister(collectors.NewGoCollector(
+			collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsGC, collectors.MetricsMemory, collectors.MetricsScheduler),
+		))
+	}
+
+	return requestMetrics, panicMetrics, func() {}
 }
 
-func initTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
+// newAuditLog opens the configured audit log sink
+func newAuditLog(config config.AppConfiguration) audit.Log {
+	switch config.Audit.Sink {
+	case "file":
+		file, err := os.OpenFile(config.Audit.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+		if err != nil {
+			logging.Fatal("Failed to open the audit log", "error", err)
+		}
+		slog.Info("Writing the audit log to a file", "file", config.Audit.File)
+		return audit.NewWriterLog(file)
+	case "opensearch":
+		client, err := repository.NewOpenSearchClient(config.OpenSearch)
+		if err != nil {
+			logging.Fatal("Failed to connect to OpenSearch for the audit log", "error", err)
+		}
+		slog.Info("Writing the audit log to OpenSearch", "index", config.Audit.Index)
+		return audit.NewOpenSearchLog(client, config.Audit.Index)
+	default:
+		slog.Info("Writing the audit log to standard output")
+		return audit.NewWriterLog(os.Stdout)
+	}
+}
+
+// newInfoHandler serves the build information along with the providers
+// actually in use, which for search depends on whether it could be reached
+// at startup
+func newInfoHandler(config config.AppConfiguration, searchRepo repository.SearchRepository, flags *features.Flags) gin.HandlerFunc {
+	searchProvider := "disabled"
+	if searchRepo != nil {
+		searchProvider = repository.SearchBackend(config)
+	}
+
+	return buildinfo.NewHandler(config.Database.Type, searchProvider, flags)
+}
+
+// injectFaults starts injecting the configured faults into database and
+// OpenSearch calls, which like the other chaos apply while the chaos
+// feature flag is on
+func injectFaults(config config.ChaosConfiguration, flags *features.Flags) {
+	repository.SetFaultsEnabled(func() bool { return flags.Enabled(features.Chaos) })
+
+	configured := map[string]repository.Fault{
+		repository.DependencyDatabase: {
+			Latency:   config.DatabaseLatency,
+			ErrorRate: config.DatabaseErrorRate,
+			Blackhole: config.DatabaseBlackhole,
+		},
+		repository.DependencyOpenSearch: {
+			Latency:   config.OpenSearchLatency,
+			ErrorRate: config.OpenSearchErrorRate,
+			Blackhole: config.OpenSearchBlackhole,
+		},
+	}
+
+	for dependency, fault := range configured {
+		if err := repository.SetFault(dependency, fault); err != nil {
+			logging.Fatal("Failed to inject a fault", "dependency", dependency, "error", err)
+		}
+		if fault != (repository.Fault{}) {
+			slog.Warn("Injecting faults", "dependency", dependency, "latency", fault.Latency, "errorRate", fault.ErrorRate, "blackhole", fault.Blackhole)
+		}
+	}
+}
+
+// setBulkheads limits the calls in flight to the database and OpenSearch
+// independently, so that one slowing down can't starve requests served by
+// the other
+func setBulkheads(config config.BulkheadConfiguration) {
+	configured := map[string]struct {
+		limit int
+		wait  time.Duration
+	}{
+		repository.DependencyDatabase:   {config.DatabaseLimit, config.DatabaseWait},
+		repository.DependencyOpenSearch: {config.OpenSearchLimit, config.OpenSearchWait},
+	}
+
+	for dependency, bulkhead := range configured {
+		if err := repository.SetBulkhead(dependency, bulkhead.limit, bulkhead.wait); err != nil {
+			logging.Fatal("Failed to set a bulkhead", "dependency", dependency, "error", err)
+		}
+		if bulkhead.limit > 0 {
+			slog.Info("Limiting calls in flight", "dependency", dependency, "limit", bulkhead.limit, "wait", bulkhead.wait)
+		}
+	}
+}
+
+// newFeatureFlags creates the feature flags from the configuration, keeping
+// them in step with AWS AppConfig if a profile is configured
+func newFeatureFlags(config config.FeaturesConfiguration, ctx context.Context) *features.Flags {
+	flags := features.New(config.Flags)
+	if config.AppConfig.Application == "" {
+		return flags
+	}
+
+	appConfig, err := features.NewAppConfig(config.AppConfig, flags)
+	if err != nil {
+		logging.Fatal("Failed to create the AppConfig client", "error", err)
+	}
+
+	// Features keep their configured state until AppConfig can be reached
+	if err := appConfig.Poll(ctx); err != nil {
+		slog.Warn("Failed to read feature flags from AppConfig", "error", err)
+	}
+	go appConfig.Watch(ctx)
+
+	slog.Info("Feature flags are read from AppConfig", "profile", config.AppConfig.Profile)
+
+	return flags
+}
+
+// newExchangeRates creates the rates prices are converted to other currencies
+// with, polling the configured source for them if there is one
+func newExchangeRates(config config.CurrencyConfiguration, ctx context.Context) *currency.Rates {
+	rates := currency.New(config.Rates)
+	if config.RatesURL == "" {
+		return rates
+	}
+
+	// Prices keep the configured rates until the source can be reached
+	source := currency.NewSource(config.RatesURL, config.RatesInterval, rates)
+	if err := source.Poll(ctx); err != nil {
+		slog.Warn("Failed to read exchange rates", "error", err)
+	}
+	go source.Watch(ctx)
+
+	return rates
+}
+
+// newReloader applies the settings that can be changed without dropping
+// connections when the configuration is reloaded
+func newReloader(routes routeMiddleware, searchRepo repository.SearchRepository, rates *currency.Rates) *config.Reloader {
+	reloader := config.NewReloader()
+
+	reloader.OnReload(func(config config.AppConfiguration) {
+		routes.setRateLimits(config.RateLimit)
+		routes.features.SetConfigured(config.Features.Flags)
+		rates.SetConfigured(config.Currency.Rates)
+		logging.SetLevel(config.Logging.Level)
+	})
+
+	if tuner, ok := searchRepo.(repository.SearchTuner); ok {
+		reloader.OnReload(func(config config.AppConfiguration) {
+			tuner.SetBoosts(config.OpenSearch.Boosts)
+		})
+	}
+
+	return reloader
+}
+
+// catalogRoutes registers the catalog API for the given version on a route group
+func catalogRoutes(catalog *gin.RouterGroup, c *controller.Controller, routes routeMiddleware, version int) {
+	catalog.Use(controller.APIVersion(version))
+	catalog.Use(routes.features.When(features.Chaos, routes.chaos.ChaosMiddleware()))
+	catalog.Use(otelgin.Middleware("catalog-server"))
+	catalog.Use(routes.auth.Identify())
+
+	reads := catalog.Group("", routes.readAuth, routes.readLimit, routes.readSize)
+	reads.GET("/products", routes.readTime, c.GetProducts)
+	reads.GET("/new-arrivals", routes.readTime, c.GetNewArrivals)
+	reads.GET("/size", routes.readTime, c.CatalogSize)
+	reads.GET("/tags", routes.readTime, c.ListTags)
+	reads.GET("/categories", routes.readTime, c.ListCategories)
+	reads.GET("/categories/:name", routes.readTime, c.GetCategory)
+	reads.GET("/categories/:name/products", routes.readTime, c.GetCategoryProducts)
+	reads.GET("/export", c.ExportProducts)
+	reads.GET("/products/:id", routes.readTime, c.GetProduct)
+	reads.GET("/products/:id/variants", routes.readTime, c.GetProductVariants)
+	reads.GET("/products/:id/reviews", routes.readTime, c.GetReviews)
+	reads.GET("/products/:id/price-history", routes.readTime, c.GetPriceHistory)
+	reads.GET("/products/:id/related", routes.readTime, c.GetRelatedProducts)
+	reads.GET("/images/*key", routes.readTime, c.GetImage)
+	reads.POST("/products/lookup", routes.readTime, c.LookupProducts)
+	reads.GET("/sku/:sku", routes.readTime, c.GetProductBySKU)
+	reads.GET("/p/:slug", routes.readTime, c.GetProductBySlug)
+	reads.GET("/events", c.StreamEvents)
+	reads.GET("/changes", routes.readTime, c.GetChanges)
+	reads.GET("/promotions", routes.readTime, c.GetPromotions)
+
+	// Search and reindexing are limited separately to protect OpenSearch
+	search := catalog.Group("", routes.readAuth, routes.searchLimit, routes.searchSize)
+	switch version {
+	case 1:
+		search.GET("/search", routes.searchTime, c.SearchProducts)
+	default:
+		search.GET("/search", routes.searchTime, c.SearchProductsV2)
+	}
+	search.GET("/search/live", routes.features.Require(features.LiveSearch), c.LiveSearch)
+	search.GET("/search/explain", routes.searchTime, routes.features.Require(features.SearchExplain), c.ExplainSearch)
+
+	// Product changes and admin operations require authentication when configured
+	writes := catalog.Group("", routes.auth.Require(middleware.PermissionWrite), routes.writeLimit, routes.writeSize, routes.writeTime)
+	writes.POST("/products", routes.idempotency, c.CreateProduct)
+	writes.PUT("/products/:id", routes.ifMatch, c.UpdateProduct)
+	writes.PATCH("/products/:id", routes.ifMatch, c.PatchProduct)
+	writes.DELETE("/products/:id", c.DeleteProduct)
+	writes.POST("/products/:id/stock", c.AdjustStock)
+	writes.PUT("/products/:id/related", c.SetRelatedProducts)
+	writes.POST("/promotions", routes.idempotency, c.CreatePromotion)
+	writes.DELETE("/promotions/:id", c.DeletePromotion)
+	writes.POST("/reindex", c.ReindexProducts)
+	writes.GET("/reconcile", c.CheckConsistency)
+	writes.POST("/reconcile", c.ReconcileProducts)
+
+	// Shoppers review products, so reviews only need read access, but are
+	// limited like other writes
+	reviews := catalog.Group("", routes.readAuth, routes.writeLimit, routes.writeSize, routes.writeTime)
+	reviews.POST("/products/:id/reviews", routes.idempotency, c.AddReview)
+
+	// Image uploads are allowed larger bodies than other writes
+	uploads := catalog.Group("", routes.auth.Require(middleware.PermissionWrite), routes.writeLimit, routes.imageSize, routes.writeTime)
+	uploads.POST("/products/:id/images", c.UploadProductImage)
+}
+
+// sitemapRoutes serves the product sitemaps from the root, where crawlers
+// look for them, limited and authenticated like the other read endpoints
+func sitemapRoutes(r *gin.Engine, c *controller.Controller, routes routeMiddleware) {
+	sitemap := r.Group("", otelgin.Middleware("catalog-server"), routes.auth.Identify(), routes.readAuth, routes.readLimit, routes.readSize, routes.readTime)
+
+	sitemap.GET("/sitemap.xml", c.SitemapIndex)
+	sitemap.GET("/sitemap/:file", c.SitemapPage)
+}
+
+// gatewayRoutes serves the gRPC-Gateway mux, which is limited and
+// authenticated like the other read endpoints
+func gatewayRoutes(gateway *gin.RouterGroup, handler http.Handler, routes routeMiddleware) {
+	gateway.Use(otelgin.Middleware("catalog-server"))
+	gateway.Use(routes.auth.Identify(), routes.readAuth, routes.readLimit, routes.readSize, routes.readTime)
+
+	gateway.GET("/*path", gin.WrapH(http.StripPrefix("/gateway", handler)))
+}
+
+// adminRoutes registers operational endpoints, which always require write access
+func adminRoutes(admin *gin.RouterGroup, c *controller.Controller, routes routeMiddleware, config config.AdminConfiguration) {
+	admin.Use(otelgin.Middleware("catalog-server"))
+	admin.Use(routes.auth.Identify(), routes.auth.Require(middleware.PermissionWrite), routes.writeLimit)
+	admin.Use(routes.adminSize, routes.adminTime)
+
+	admin.POST("/reindex", c.StartReindex)
+	admin.GET("/reindex/:jobId", c.GetReindexJob)
+
+	admin.POST("/imports", routes.idempotency, c.StartImport)
+	admin.GET("/imports/:jobId", c.GetImportJob)
+	admin.POST("/imports/:jobId/resume", c.ResumeImportJob)
+	admin.POST("/generate", routes.idempotency, c.GenerateProducts)
+
+	admin.GET("/deleted-products", c.GetDeletedProducts)
+	admin.POST("/deleted-products/:id/restore", c.RestoreProduct)
+
+	// Unlike the public endpoints these see products whatever their status
+	products := admin.Group("", controller.APIVersion(2), controller.AllStatuses())
+	products.GET("/products", c.GetProducts)
+	products.GET("/products/:id", c.GetProduct)
+	products.GET("/search", c.SearchProductsV2)
+
+	// Resetting discards every change, so it is only exposed for demo deployments
+	if config.ResetEnabled {
+		slog.Info("Catalog reset endpoint is enabled")
+		admin.POST("/reset", c.ResetCatalog)
+	}
+}
+
+func initTracer(config config.TracingConfiguration, ctx context.Context) (*sdktrace.TracerProvider, error) {
 	client := otlptracehttp.NewClient()
 	exporter, err := otlptrace.New(ctx, client)
 	if err != nil {
@@ -205,7 +1100,40 @@ func initTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
 		sdktrace.WithIDGenerator(idg),
 		sdktrace.WithResource(resource),
 	)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	// Trace IDs are generated in the X-Ray format so that the ADOT collector
+	// can export them to X-Ray. Its header is read and written as well when
+	// traces are started by load balancers or services using the X-Ray SDK.
+	propagators := []propagation.TextMapPropagator{propagation.TraceContext{}, propagation.Baggage{}}
+	if config.XRay {
+		slog.Info("Propagating the X-Ray trace header")
+		propagators = append(propagators, xray.Propagator{})
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagators...))
 	otel.SetTracerProvider(tp)
 	return tp, nil
 }
+
+// initLogExporter sends the records logged through the default logger to the
+// OTLP/HTTP collector as well, describing the same resource as the traces so
+// that records logged while handling a request can be found from its trace
+func initLogExporter(ctx context.Context) (*sdklog.LoggerProvider, error) {
+	exporter, err := otlploghttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP log exporter: %w", err)
+	}
+	ec2ResourceDetector := ec2.NewResourceDetector()
+	resource, _ := ec2ResourceDetector.Detect(context.Background())
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(resource),
+	)
+
+	if err := logging.Export(otelslog.NewHandler("catalog", otelslog.WithLoggerProvider(lp))); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Exporting logs over OTLP")
+	return lp, nil
+}
@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/gin-gonic/gin"
+)
+
+// NewAccessLog logs a structured record of each request once it has been
+// handled. The request ID is added to the record from the request context,
+// so this must run after RequestID. Sampling drops a share of the requests
+// that succeed quickly, keeping every error and slow request.
+func NewAccessLog(config config.AccessLogConfiguration, skipPaths ...string) gin.HandlerFunc {
+	if !config.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	skipPaths = append(skipPaths, config.SkipPaths...)
+
+	return func(c *gin.Context) {
+		if slices.Contains(skipPaths, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		started := time.Now()
+		c.Next()
+		latency := time.Since(started)
+
+		status := c.Writer.Status()
+		slow := config.SlowThreshold > 0 && latency >= config.SlowThreshold
+		if status < http.StatusBadRequest && !slow && rand.Float64() >= config.SampleRate {
+			return
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if slow {
+			level = slog.LevelWarn
+		}
+
+		// The size is -1 when nothing was written
+		bytes := max(c.Writer.Size(), 0)
+
+		slog.LogAttrs(c.Request.Context(), level, "Request handled",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+			slog.Int("bytes", bytes),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		)
+	}
+}
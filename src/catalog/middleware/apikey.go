@@ -0,0 +1,127 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyAuth checks the X-API-Key header against a set of keys. Keys are
+// stored and compared as SHA-256 hashes so every comparison takes the same time
+// regardless of where the keys differ or how long they are.
+type APIKeyAuth struct {
+	hashes [][sha256.Size]byte
+}
+
+// NewAPIKeyAuth creates an APIKeyAuth accepting any of the given keys. With no
+// keys it is disabled.
+func NewAPIKeyAuth(keys []string) *APIKeyAuth {
+	auth := &APIKeyAuth{}
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			auth.hashes = append(auth.hashes, sha256.Sum256([]byte(key)))
+		}
+	}
+	return auth
+}
+
+// Enabled reports whether any keys are configured
+func (a *APIKeyAuth) Enabled() bool {
+	return len(a.hashes) > 0
+}
+
+// Authenticate grants every permission to requests with a valid key
+func (a *APIKeyAuth) Authenticate(c *gin.Context) (*Principal, error) {
+	key := c.GetHeader(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256([]byte(key))
+
+	match := 0
+	for _, candidate := range a.hashes {
+		match |= subtle.ConstantTimeCompare(hash[:], candidate[:])
+	}
+
+	if match != 1 {
+		return nil, errInvalidAPIKey
+	}
+
+	return &Principal{
+		// A short prefix of the hash identifies the key without revealing it
+		ID:          "key:" + hex.EncodeToString(hash[:8]),
+		Permissions: []string{PermissionRead, PermissionWrite},
+	}, nil
+}
+
+// Challenge for WWW-Authenticate
+func (a *APIKeyAuth) Challenge() string {
+	return "ApiKey"
+}
+
+// LoadAPIKeys returns the configured API keys, combining those set directly
+// with those stored in Secrets Manager
+func LoadAPIKeys(config config.AuthConfiguration, ctx context.Context) ([]string, error) {
+	keys := append([]string{}, config.APIKeys...)
+
+	if config.APIKeysSecret == "" {
+		return keys, nil
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	output, err := secretsmanager.New(sess).GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(config.APIKeysSecret),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys secret %s: %w", config.APIKeysSecret, err)
+	}
+
+	return append(keys, parseAPIKeysSecret(aws.StringValue(output.SecretString))...), nil
+}
+
+// parseAPIKeysSecret accepts a JSON array of keys or a comma or newline
+// separated list
+func parseAPIKeysSecret(value string) []string {
+	var keys []string
+	if err := json.Unmarshal([]byte(value), &keys); err == nil {
+		return keys
+	}
+
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/audit"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIDKey is the gin context key holding the identity of an
+// authenticated client
+const ClientIDKey = "catalog.clientID"
+
+// principalKey is the gin context key holding the authenticated Principal
+const principalKey = "catalog.principal"
+
+// Permissions that can be required of a client
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+)
+
+var (
+	errMissingCredentials = errors.New("authentication is required")
+	errForbidden          = errors.New("insufficient permissions")
+)
+
+// Principal is an authenticated client
+type Principal struct {
+	ID          string
+	Permissions []string
+}
+
+// Authenticator checks one kind of request credential
+type Authenticator interface {
+	// Enabled reports whether the authenticator has been configured
+	Enabled() bool
+	// Authenticate returns the principal for the request's credential, nil if
+	// the request does not carry this kind of credential, or an error if it
+	// carries an invalid one
+	Authenticate(c *gin.Context) (*Principal, error)
+	// Challenge is the WWW-Authenticate value for this kind of credential
+	Challenge() string
+}
+
+// Auth authenticates requests using the first enabled authenticator whose
+// credential they carry
+type Auth struct {
+	authenticators []Authenticator
+}
+
+// NewAuth combines authenticators, ignoring those that are not enabled. With
+// none enabled every request is allowed.
+func NewAuth(authenticators ...Authenticator) *Auth {
+	auth := &Auth{}
+	for _, authenticator := range authenticators {
+		if authenticator.Enabled() {
+			auth.authenticators = append(auth.authenticators, authenticator)
+		}
+	}
+	return auth
+}
+
+// Enabled reports whether any authenticator is enabled
+func (a *Auth) Enabled() bool {
+	return len(a.authenticators) > 0
+}
+
+// Identify records the principal for requests with valid credentials and
+// rejects requests with invalid ones, without requiring credentials
+func (a *Auth) Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := a.authenticate(c); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// Require rejects requests without credentials granting the permission, when
+// authentication is enabled
+func (a *Auth) Require(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+
+		principal, ok := a.authenticate(c)
+		if !ok {
+			return
+		}
+
+		if principal == nil {
+			a.unauthorized(c, errMissingCredentials)
+			return
+		}
+
+		if !slices.Contains(principal.Permissions, permission) {
+			httputil.NewError(c, http.StatusForbidden, errForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate returns the request's principal, which is nil for anonymous
+// requests, or aborts with 401 and returns false if its credentials are invalid
+func (a *Auth) authenticate(c *gin.Context) (*Principal, bool) {
+	if value, exists := c.Get(principalKey); exists {
+		return value.(*Principal), true
+	}
+
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(c)
+		if err != nil {
+			a.unauthorized(c, err)
+			return nil, false
+		}
+
+		if principal != nil {
+			c.Set(principalKey, principal)
+			c.Set(ClientIDKey, principal.ID)
+			c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), principal.ID))
+			return principal, true
+		}
+	}
+
+	return nil, true
+}
+
+func (a *Auth) unauthorized(c *gin.Context, err error) {
+	for _, authenticator := range a.authenticators {
+		c.Writer.Header().Add("WWW-Authenticate", authenticator.Challenge())
+	}
+	httputil.NewError(c, http.StatusUnauthorized, err)
+	c.Abort()
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
 	"github.com/gin-gonic/gin"
 )
 
@@ -89,7 +90,80 @@ func (cc *ChaosController) SetupChaosRoutes(r *gin.Engine) {
 		// Enable/disable health check
 		chaos.POST("/health", cc.setHealth)
 		chaos.DELETE("/health", cc.disableHealth)
+
+		// Inject/clear faults in calls to the database and OpenSearch
+		chaos.GET("/dependencies", cc.getDependencyFaults)
+		chaos.POST("/dependencies/:name", cc.setDependencyFault)
+		chaos.DELETE("/dependencies/:name", cc.clearDependencyFault)
+	}
+}
+
+// dependencyFault is how a fault is set and reported through the chaos
+// endpoints
+type dependencyFault struct {
+	LatencyMs int64   `json:"latencyMs"`
+	ErrorRate float64 `json:"errorRate"`
+	Blackhole bool    `json:"blackhole"`
+}
+
+func (cc *ChaosController) setDependencyFault(c *gin.Context) {
+	var request dependencyFault
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid fault. Please provide latencyMs, errorRate and/or blackhole.",
+		})
+		return
+	}
+
+	err := repository.SetFault(c.Param("name"), repository.Fault{
+		Latency:   time.Duration(request.LatencyMs) * time.Millisecond,
+		ErrorRate: request.ErrorRate,
+		Blackhole: request.Blackhole,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid fault: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fault set for " + c.Param("name"),
+	})
+}
+
+func (cc *ChaosController) clearDependencyFault(c *gin.Context) {
+	if err := repository.SetFault(c.Param("name"), repository.Fault{}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fault cleared for " + c.Param("name"),
+	})
+}
+
+func (cc *ChaosController) getDependencyFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, dependencyFaults())
+}
+
+// dependencyFaults reports the fault of every dependency, with zero values
+// for those without one
+func dependencyFaults() map[string]dependencyFault {
+	current := repository.Faults()
+
+	faults := map[string]dependencyFault{}
+	for _, dependency := range repository.FaultDependencies() {
+		fault := current[dependency]
+		faults[dependency] = dependencyFault{
+			LatencyMs: fault.Latency.Milliseconds(),
+			ErrorRate: fault.ErrorRate,
+			Blackhole: fault.Blackhole,
+		}
 	}
+	return faults
 }
 
 func (cc *ChaosController) setLatency(c *gin.Context) {
@@ -185,6 +259,7 @@ func (cc *ChaosController) getChaosStatus(c *gin.Context) {
 			"enabled": cc.isErrorStatusOn,
 			"code":    cc.errorStatus,
 		},
+		"dependencies": dependencyFaults(),
 	})
 }
 
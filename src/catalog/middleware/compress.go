@@ -0,0 +1,243 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/gin-gonic/gin"
+)
+
+// compressionEncodings are the supported content codings, in order of preference
+var compressionEncodings = []string{"br", "gzip"}
+
+var encoderPools = map[string]*sync.Pool{
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// NewCompression returns middleware that compresses JSON responses of at least
+// the configured size with brotli or gzip, whichever the client prefers
+func NewCompression(config config.CompressionConfiguration) gin.HandlerFunc {
+	if !config.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minSize:        config.MinSize,
+		}
+		c.Writer = writer
+		defer func() {
+			writer.close()
+			c.Writer = writer.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the preferred supported coding from an
+// Accept-Encoding header, or "" if the response should not be compressed
+func negotiateEncoding(header string) string {
+	// An explicitly named coding takes precedence over the * wildcard
+	qualities := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range compressionEncodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+
+		// Earlier encodings win ties, so br is preferred over gzip
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter buffers the start of a response until it is large enough to
+// be worth compressing, then either compresses the rest or passes it through
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow commits the headers, so the response can no longer be compressed
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decided = true
+		w.flushBuffer()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide starts compressing if the response is a compressible type, and writes
+// out whatever has been buffered so far
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	header := w.Header()
+	if w.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+
+		if len(w.buf) >= w.minSize {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+
+			// The compressed bytes differ from the original representation
+			if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+				header.Set("ETag", "W/"+etag)
+			}
+
+			w.encoder = encoderPools[w.encoding].Get().(encoder)
+			w.encoder.Reset(w.ResponseWriter)
+		}
+	}
+
+	return w.flushBuffer()
+}
+
+func (w *compressWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+
+	header := w.Header()
+	return header.Get("Content-Encoding") == "" && strings.Contains(header.Get("Content-Type"), "json")
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.encoder != nil {
+		w.encoder.Close()
+		encoderPools[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Accept", "Accept-Currency", "Authorization", "Content-Type", IdempotencyKeyHeader, "If-Match", "If-None-Match", APIKeyHeader}
+
+	// corsExposedHeaders are response headers that browser clients need to read
+	corsExposedHeaders = []string{"Content-Currency", "ETag", IdempotentReplayedHeader, "Location", "Retry-After", "WWW-Authenticate", "X-Catalog-Degraded"}
+)
+
+// NewCORS returns middleware that answers CORS preflight requests and adds
+// CORS headers to responses for the configured origins. An origin of "*"
+// allows any origin, and a single "*" within an origin matches any subdomain,
+// for example https://*.example.com. With no origins configured it does nothing.
+func NewCORS(config config.CORSConfiguration) gin.HandlerFunc {
+	if len(config.AllowedOrigins) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	methods := config.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+
+	allowAll := slices.Contains(config.AllowedOrigins, "*")
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	exposeHeaders := strings.Join(corsExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !allowAll && !originAllowed(config.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// Credentials can't be combined with a wildcard origin, so the origin
+		// is echoed back instead
+		if allowAll && !config.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if config.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", exposeHeaders)
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", allowMethods)
+		c.Header("Access-Control-Allow-Headers", allowHeaders)
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if strings.EqualFold(pattern, origin) {
+				return true
+			}
+			continue
+		}
+
+		if len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+
+	return false
+}
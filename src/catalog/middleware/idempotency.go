@@ -0,0 +1,215 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header identifying retries of a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a retried request
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength bounds the keys clients can make the store hold
+const maxIdempotencyKeyLength = 255
+
+var (
+	errIdempotencyKeyTooLong    = fmt.Errorf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+	errIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	errIdempotencyKeyReused     = errors.New("the idempotency key was already used for a different request")
+)
+
+// replayedHeaders are the response headers stored and replayed with the body
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// Idempotency makes retried write requests safe. The first response to a
+// request carrying an Idempotency-Key is stored for a while and replayed to
+// retries with the same key instead of running the handler again. Keys are
+// scoped to the client and route.
+type Idempotency struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+	lastSweep time.Time
+}
+
+// NewIdempotency stores responses for ttl after they are sent
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware replays stored responses to retries. A retry that arrives before
+// the first request has finished, or that reuses a key with a different body,
+// is rejected. Server errors are not stored so that the request can be retried.
+func (i *Idempotency) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			httputil.NewError(c, http.StatusBadRequest, errIdempotencyKeyTooLong)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			httputil.NewError(c, http.StatusBadRequest, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := clientKey(c) + " " + c.Request.Method + " " + c.FullPath() + " " + key
+		fingerprint := sha256.Sum256(body)
+
+		stored, found := i.claim(storeKey, fingerprint, time.Now())
+		if found {
+			switch {
+			case stored.fingerprint != fingerprint:
+				httputil.NewError(c, http.StatusUnprocessableEntity, errIdempotencyKeyReused)
+			case !stored.done:
+				httputil.NewError(c, http.StatusConflict, errIdempotencyKeyInProgress)
+			default:
+				for name, values := range stored.header {
+					c.Writer.Header()[name] = values
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(stored.status, stored.header.Get("Content-Type"), stored.body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			// Forget the key if the handler panicked so that a retry can run
+			if !completed {
+				i.release(storeKey)
+			}
+		}()
+
+		c.Next()
+		completed = true
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			i.release(storeKey)
+			return
+		}
+
+		header := http.Header{}
+		for _, name := range replayedHeaders {
+			if values := c.Writer.Header().Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		i.complete(storeKey, status, header, recorder.body.Bytes(), time.Now())
+	}
+}
+
+// claim returns the stored response for the key, or reserves the key for a
+// new request if there is none
+func (i *Idempotency) claim(key string, fingerprint [sha256.Size]byte, now time.Time) (idempotentResponse, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Expire stored responses now and then so the map does not grow without bound
+	if now.Sub(i.lastSweep) > i.ttl {
+		for k, r := range i.responses {
+			if r.done && now.After(r.expires) {
+				delete(i.responses, k)
+			}
+		}
+		i.lastSweep = now
+	}
+
+	if r, ok := i.responses[key]; ok && (!r.done || now.Before(r.expires)) {
+		return *r, true
+	}
+
+	i.responses[key] = &idempotentResponse{fingerprint: fingerprint}
+	return idempotentResponse{}, false
+}
+
+func (i *Idempotency) complete(key string, status int, header http.Header, body []byte, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if r, ok := i.responses[key]; ok {
+		r.done = true
+		r.status = status
+		r.header = header
+		r.body = body
+		r.expires = now.Add(i.ttl)
+	}
+}
+
+func (i *Idempotency) release(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.responses, key)
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,197 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval limits how often the key set is fetched again when a
+// token is signed with an unknown key, for example after key rotation
+const jwksRefreshInterval = time.Minute
+
+var errUnknownKey = errors.New("token signed with an unknown key")
+
+// keySet fetches and caches the public keys published at a JWKS endpoint. The
+// endpoint is found with OpenID Connect discovery if it is not configured.
+type keySet struct {
+	issuer string
+	url    string
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastFetched time.Time
+}
+
+func newKeySet(issuer, url string) *keySet {
+	return &keySet{
+		issuer: issuer,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+// key returns the public key with the given ID, fetching the key set if the
+// ID is not known yet
+func (k *keySet) key(kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(k.lastFetched) < jwksRefreshInterval {
+		return nil, errUnknownKey
+	}
+
+	if err := k.fetch(); err != nil {
+		return nil, err
+	}
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, errUnknownKey
+}
+
+func (k *keySet) fetch() error {
+	k.lastFetched = time.Now()
+
+	if k.url == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := k.getJSON(strings.TrimSuffix(k.issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("OpenID Connect discovery failed: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("OpenID Connect discovery document has no jwks_uri")
+		}
+		k.url = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := k.getJSON(k.url, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip key types we do not support rather than failing entirely
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	k.keys = keys
+	return nil
+}
+
+func (k *keySet) getJSON(url string, v any) error {
+	res, err := k.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", j.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtLeeway tolerates clock skew between the catalog and the identity provider
+const jwtLeeway = 30 * time.Second
+
+var errInvalidAudience = errors.New("token has an invalid audience")
+
+// JWTAuth validates OAuth 2.0 / OpenID Connect bearer tokens against an
+// issuer's published keys, mapping token scopes to permissions
+type JWTAuth struct {
+	config config.JWTConfiguration
+	keys   *keySet
+	parser *jwt.Parser
+}
+
+// NewJWTAuth creates a JWTAuth for the configured issuer. With no issuer it is
+// disabled. Keys are fetched on first use so that the catalog can start while
+// the identity provider is unavailable.
+func NewJWTAuth(config config.JWTConfiguration) *JWTAuth {
+	return &JWTAuth{
+		config: config,
+		keys:   newKeySet(config.Issuer, config.JWKSURL),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(jwtLeeway),
+		),
+	}
+}
+
+// Enabled reports whether an issuer is configured
+func (a *JWTAuth) Enabled() bool {
+	return a.config.Issuer != ""
+}
+
+// Authenticate validates a bearer token from the Authorization header
+func (a *JWTAuth) Authenticate(c *gin.Context) (*Principal, error) {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(strings.TrimSpace(token), claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	if !a.validAudience(claims) {
+		return nil, errInvalidAudience
+	}
+
+	subject, _ := claims.GetSubject()
+
+	return &Principal{
+		ID:          "sub:" + subject,
+		Permissions: a.permissions(tokenScopes(claims)),
+	}, nil
+}
+
+// Challenge for WWW-Authenticate
+func (a *JWTAuth) Challenge() string {
+	return "Bearer"
+}
+
+// validAudience checks the aud claim, or client_id for access tokens such as
+// Cognito's that do not carry an audience
+func (a *JWTAuth) validAudience(claims jwt.MapClaims) bool {
+	if a.config.Audience == "" {
+		return true
+	}
+
+	audience, _ := claims.GetAudience()
+	if slices.Contains(audience, a.config.Audience) {
+		return true
+	}
+
+	clientID, _ := claims["client_id"].(string)
+	return clientID == a.config.Audience
+}
+
+// permissions maps token scopes to permissions. Reads are granted to any
+// valid token unless a read scope is configured.
+func (a *JWTAuth) permissions(scopes []string) []string {
+	permissions := []string{}
+
+	if a.config.ReadScope == "" || slices.Contains(scopes, a.config.ReadScope) {
+		permissions = append(permissions, PermissionRead)
+	}
+
+	if slices.Contains(scopes, a.config.WriteScope) {
+		permissions = append(permissions, PermissionWrite)
+	}
+
+	return permissions
+}
+
+// tokenScopes reads scopes from the space-separated scope claim (Cognito,
+// Keycloak) or the scp array claim used by some other providers
+func tokenScopes(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	scopes := []string{}
+	if scp, ok := claims["scp"].([]any); ok {
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+	}
+	return scopes
+}
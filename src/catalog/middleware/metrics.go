@@ -0,0 +1,106 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewRequestMetrics records the rate, errors and duration of requests by
+// route, using the Prometheus default buckets if none are given. Routes are
+// identified by their pattern, such as /catalog/products/:id, so that each
+// product doesn't create a series of its own.
+func NewRequestMetrics(buckets []float64, registerer prometheus.Registerer) (gin.HandlerFunc, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_http_requests_total",
+		Help: "HTTP requests handled, by route and status code",
+	}, []string{"method", "route", "status"})
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catalog_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by route",
+		Buckets: buckets,
+	}, []string{"method", "route"})
+
+	for _, collector := range []prometheus.Collector{requests, duration} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := metricsRoute(c)
+		requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		duration.WithLabelValues(c.Request.Method, route).Observe(time.Since(started).Seconds())
+	}, nil
+}
+
+// NewStatsDRequestMetrics sends the same request metrics as NewRequestMetrics
+// to a DogStatsD agent, as the catalog.http.requests count and the
+// catalog.http.request.duration distribution in seconds, tagged by route
+func NewStatsDRequestMetrics(client *statsd.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := metricsRoute(c)
+		tags := []string{"method:" + c.Request.Method, "route:" + route}
+		client.Incr("catalog.http.requests", append(tags, "status:"+strconv.Itoa(c.Writer.Status())), 1)
+		client.Distribution("catalog.http.request.duration", time.Since(started).Seconds(), tags, 1)
+	}
+}
+
+// NewPanicMetrics counts the requests that panicked by route, as
+// catalog_http_panics_total
+func NewPanicMetrics(registerer prometheus.Registerer) (PanicObserver, error) {
+	panics := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "catalog_http_panics_total",
+		Help: "HTTP requests whose handler panicked, by route",
+	}, []string{"method", "route"})
+
+	if err := registerer.Register(panics); err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		panics.WithLabelValues(c.Request.Method, metricsRoute(c)).Inc()
+	}, nil
+}
+
+// NewStatsDPanicMetrics sends the same count as NewPanicMetrics to a
+// DogStatsD agent, as catalog.http.panics tagged by route
+func NewStatsDPanicMetrics(client *statsd.Client) PanicObserver {
+	return func(c *gin.Context) {
+		client.Incr("catalog.http.panics", []string{"method:" + c.Request.Method, "route:" + metricsRoute(c)}, 1)
+	}
+}
+
+// metricsRoute identifies the route of a request by its pattern
+func metricsRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
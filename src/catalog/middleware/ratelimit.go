@@ -0,0 +1,170 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// APIKeyHeader is the request header carrying a client API key
+const APIKeyHeader = "X-API-Key"
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// clientIdleTimeout is how long a client's bucket is kept after its last request
+const clientIdleTimeout = 5 * time.Minute
+
+var rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "catalog_rate_limited_requests_total",
+	Help: "Requests rejected by rate limiting",
+}, []string{"group"})
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter applies a token bucket per client, identified by API key when
+// one is sent and client IP otherwise
+type RateLimiter struct {
+	name    string
+	enabled atomic.Bool
+
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter allows each client requestsPerSecond on average, with bursts
+// of up to burst requests
+func NewRateLimiter(name string, requestsPerSecond float64, burst int) *RateLimiter {
+	rl := &RateLimiter{
+		name:      name,
+		limit:     rate.Limit(requestsPerSecond),
+		burst:     burst,
+		clients:   map[string]*clientBucket{},
+		lastSweep: time.Now(),
+	}
+	rl.enabled.Store(true)
+
+	return rl
+}
+
+// SetEnabled turns limiting on or off, letting every request through while
+// it is off
+func (rl *RateLimiter) SetEnabled(enabled bool) {
+	rl.enabled.Store(enabled)
+}
+
+// SetLimit changes the rate and burst allowed to each client, including
+// clients that already have a bucket
+func (rl *RateLimiter) SetLimit(requestsPerSecond float64, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limit = rate.Limit(requestsPerSecond)
+	rl.burst = burst
+
+	now := time.Now()
+	for _, b := range rl.clients {
+		b.limiter.SetLimitAt(now, rl.limit)
+		b.limiter.SetBurstAt(now, rl.burst)
+	}
+}
+
+// Middleware rejects requests over the limit with 429 and a Retry-After header
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.enabled.Load() {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+
+		reservation := rl.bucket(clientKey(c), now).ReserveN(now, 1)
+		if !reservation.OK() {
+			rl.reject(c, time.Second)
+			return
+		}
+
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			rl.reject(c, delay)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (rl *RateLimiter) reject(c *gin.Context, retryAfter time.Duration) {
+	rateLimitedRequests.WithLabelValues(rl.name).Inc()
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	httputil.NewError(c, http.StatusTooManyRequests, errRateLimited)
+	c.Abort()
+}
+
+func (rl *RateLimiter) bucket(key string, now time.Time) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Forget idle clients now and then so the map does not grow without bound
+	if now.Sub(rl.lastSweep) > clientIdleTimeout {
+		for k, b := range rl.clients {
+			if now.Sub(b.lastSeen) > clientIdleTimeout {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	b, ok := rl.clients[key]
+	if !ok {
+		b = &clientBucket{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[key] = b
+	}
+	b.lastSeen = now
+
+	return b.limiter
+}
+
+// clientKey identifies the client by the identity established by
+// authentication, otherwise by its IP address. Headers that weren't verified
+// are ignored, so a client can't get a bucket of its own by making up keys.
+func clientKey(c *gin.Context) string {
+	if id := c.GetString(ClientIDKey); id != "" {
+		return id
+	}
+
+	return "ip:" + c.ClientIP()
+}
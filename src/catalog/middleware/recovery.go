@@ -0,0 +1,72 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// PanicObserver is called for every request whose handler panicked
+type PanicObserver func(c *gin.Context)
+
+// Recovery turns a panic in a later handler into a 500 response, logging the
+// panic with its stack trace and the request ID from the request context,
+// so this must run after RequestID. When the response has already started it
+// can't be replaced, so the connection is dropped instead to show the client
+// that it is incomplete.
+func Recovery(observe PanicObserver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// Handlers abort the response on purpose with ErrAbortHandler,
+			// which the server drops the connection for without logging
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(c.Request.Context(), "Recovered from a panic while handling a request",
+				"panic", recovered,
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"route", metricsRoute(c),
+				"stack", string(debug.Stack()),
+			)
+			if observe != nil {
+				observe(c)
+			}
+
+			if c.Writer.Written() {
+				panic(http.ErrAbortHandler)
+			}
+
+			c.Abort()
+			httputil.NewProblem(c, http.StatusInternalServerError, "the server encountered an unexpected error")
+		}()
+
+		c.Next()
+	}
+}
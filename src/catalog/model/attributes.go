@@ -0,0 +1,203 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of the values of product attributes
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+// ProductAttribute describes a product in a way shared with others, such as
+// its material or brand, so that products can be filtered and counted by it.
+// Value is the attribute's value written as text, and Number is set as well
+// for numbers so that they can be compared as such.
+type ProductAttribute struct {
+	ProductID string   `json:"-" xml:"-" gorm:"primaryKey;size:64"`
+	Name      string   `json:"name" xml:"name,attr" gorm:"primaryKey;size:64"`
+	Type      string   `json:"type" xml:"type,attr" gorm:"size:16;not null"`
+	Value     string   `json:"value" xml:",chardata" gorm:"index;size:255"`
+	Number    *float64 `json:"-" xml:"-" gorm:"index"`
+}
+
+// AttributeValue is the value of a product attribute in a request, given as
+// a JSON string, number or boolean
+type AttributeValue struct {
+	Type  string
+	Value string `binding:"max=255"`
+}
+
+// UnmarshalJSON reads a string, number or boolean, recording which it was
+func (v *AttributeValue) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case string:
+		*v = AttributeValue{Type: AttributeString, Value: value}
+	case json.Number:
+		number, err := value.Float64()
+		if err != nil {
+			return fmt.Errorf("attribute value %s is not a valid number", value)
+		}
+		*v = AttributeValue{Type: AttributeNumber, Value: formatNumber(number)}
+	case bool:
+		*v = AttributeValue{Type: AttributeBoolean, Value: strconv.FormatBool(value)}
+	default:
+		return fmt.Errorf("attribute value %s must be a string, number or boolean", data)
+	}
+	return nil
+}
+
+// MarshalJSON writes the value as the JSON type it was given as
+func (v AttributeValue) MarshalJSON() ([]byte, error) {
+	if v.Type == AttributeNumber || v.Type == AttributeBoolean {
+		return []byte(v.Value), nil
+	}
+	return json.Marshal(v.Value)
+}
+
+// formatNumber writes a number the same way however it was given, so that
+// 120 and 120.0 are the same value
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// ToAttributes converts attribute values to the attributes of a product,
+// sorted by name
+func ToAttributes(productID string, values map[string]AttributeValue) []ProductAttribute {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]ProductAttribute, len(names))
+	for i, name := range names {
+		value := values[name]
+		attribute := ProductAttribute{ProductID: productID, Name: name, Type: value.Type, Value: value.Value}
+		if value.Type == AttributeNumber {
+			if number, err := strconv.ParseFloat(value.Value, 64); err == nil {
+				attribute.Number = &number
+			}
+		}
+		attributes[i] = attribute
+	}
+	return attributes
+}
+
+// attributeValues converts the attributes of a product to the values that
+// would create them
+func attributeValues(attributes []ProductAttribute) map[string]AttributeValue {
+	values := make(map[string]AttributeValue, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.Name] = AttributeValue{Type: attribute.Type, Value: attribute.Value}
+	}
+	return values
+}
+
+// AttributeFilter limits search results to products with an attribute that
+// has the value, or for numbers is within the range. Either bound of a range
+// may be missing.
+type AttributeFilter struct {
+	Name  string
+	Value *string
+	Min   *float64
+	Max   *float64
+}
+
+// ParseAttributeFilter reads an attribute filter written as name:value, or
+// as name:min..max for a range of numbers where either bound may be left out
+func ParseAttributeFilter(filter string) (AttributeFilter, error) {
+	name, value, ok := strings.Cut(filter, ":")
+	if !ok || name == "" || value == "" {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q must be written as name:value or name:min..max", filter)
+	}
+
+	low, high, isRange := strings.Cut(value, "..")
+	if !isRange {
+		return AttributeFilter{Name: name, Value: &value}, nil
+	}
+	if low == "" && high == "" {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q needs at least one bound", filter)
+	}
+
+	result := AttributeFilter{Name: name}
+	var err error
+	if result.Min, err = parseBound(low); err != nil {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q has a minimum that isn't a number", filter)
+	}
+	if result.Max, err = parseBound(high); err != nil {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q has a maximum that isn't a number", filter)
+	}
+	if result.Min != nil && result.Max != nil && *result.Min > *result.Max {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q has a minimum above its maximum", filter)
+	}
+	return result, nil
+}
+
+// parseBound reads a bound of a range, which is nil if it's left out
+func parseBound(text string) (*float64, error) {
+	if text == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &number, nil
+}
+
+// String writes the filter the way it is parsed
+func (f AttributeFilter) String() string {
+	if f.Value != nil {
+		return f.Name + ":" + *f.Value
+	}
+
+	var low, high string
+	if f.Min != nil {
+		low = formatNumber(*f.Min)
+	}
+	if f.Max != nil {
+		high = formatNumber(*f.Max)
+	}
+	return f.Name + ":" + low + ".." + high
+}
+
+// Number returns the filter's value as a number, if it is one
+func (f AttributeFilter) Number() (float64, bool) {
+	if f.Value == nil {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(*f.Value, 64)
+	return number, err == nil
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "time"
+
+// Actions recorded in the audit log
+const (
+	AuditProductCreate    = "product.create"
+	AuditProductUpdate    = "product.update"
+	AuditProductPatch     = "product.patch"
+	AuditProductStock     = "product.stock"
+	AuditProductImage     = "product.image"
+	AuditProductRelated   = "product.related"
+	AuditProductDelete    = "product.delete"
+	AuditProductRestore   = "product.restore"
+	AuditCatalogReindex   = "catalog.reindex"
+	AuditCatalogReset     = "catalog.reset"
+	AuditCatalogImport    = "catalog.import"
+	AuditImportResume     = "catalog.import.resume"
+	AuditCatalogReconcile = "catalog.reconcile"
+)
+
+// Outcomes of audited actions
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+)
+
+// AuditEntry records who performed an administrative or write operation
+// and what it changed
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Actor is the ID of the authenticated client, such as key:1a2b3c4d for
+	// an API key or sub:alice for a JWT subject
+	Actor      string   `json:"actor" example:"sub:alice"`
+	Action     string   `json:"action" example:"product.update"`
+	Outcome    string   `json:"outcome" example:"succeeded"`
+	Error      string   `json:"error,omitempty"`
+	ProductIDs []string `json:"productIds,omitempty"`
+	JobID      string   `json:"jobId,omitempty"`
+	// Before and After are snapshots of a single product changed by the
+	// operation
+	Before *Product `json:"before,omitempty"`
+	After  *Product `json:"after,omitempty"`
+}
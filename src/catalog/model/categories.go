@@ -0,0 +1,43 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+// CategoryPathSeparator separates category names in a Category's Path
+const CategoryPathSeparator = "/"
+
+// Category is a node in the category tree. Path lists the names of the
+// category's ancestors and then its own, for example clothing/footwear.
+// Breadcrumbs are filled in for responses and aren't stored.
+type Category struct {
+	Name        string       `json:"name" xml:"name,attr" gorm:"primaryKey"`
+	DisplayName string       `json:"displayName" xml:",chardata"`
+	ParentName  *string      `json:"parent,omitempty" xml:"parent,attr,omitempty" gorm:"index"`
+	Path        string       `json:"path" xml:"path,attr" gorm:"index"`
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs,omitempty" xml:"breadcrumb,omitempty" gorm:"-"`
+}
+
+// Breadcrumb is a category on the way from the top of the tree to another
+type Breadcrumb struct {
+	Name        string `json:"name" xml:"name,attr"`
+	DisplayName string `json:"displayName" xml:",chardata"`
+}
+
+// CategoryNode is a category with its subcategories
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
@@ -0,0 +1,50 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "time"
+
+// Types of ProductChange
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+	// ChangeReset means the catalog was restored to the seed data, so every
+	// product may have changed
+	ChangeReset = "reset"
+)
+
+// ProductChange is an entry in the log of changes to the catalog
+type ProductChange struct {
+	Sequence  uint64    `json:"sequence" gorm:"primaryKey;autoIncrement"`
+	Type      string    `json:"type" example:"updated"`
+	ProductID string    `json:"productId,omitempty" gorm:"index"`
+	ChangedAt time.Time `json:"changedAt" gorm:"index"`
+	// Product is the current state of a created or updated product, and is
+	// omitted if the product has been deleted since or isn't active
+	Product *Product `json:"product,omitempty" gorm:"-"`
+}
+
+// ChangesResponse is a page of the change log
+type ChangesResponse struct {
+	Changes []ProductChange `json:"changes"`
+	// Cursor is the since value for the next request, which continues after
+	// the last change returned
+	Cursor string `json:"cursor"`
+	// HasMore is true when changes were left out to limit the page size
+	HasMore bool `json:"hasMore"`
+}
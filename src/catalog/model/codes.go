@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+// ValidEAN reports whether code is an EAN-8 or EAN-13 barcode with the
+// right check digit
+func ValidEAN(code string) bool {
+	if len(code) != 8 && len(code) != 13 {
+		return false
+	}
+
+	// Digits are weighted 3 and 1 alternately from the right, starting
+	// with the one before the check digit
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	check := int(code[len(code)-1] - '0')
+	return check >= 0 && check <= 9 && (10-sum%10)%10 == check
+}
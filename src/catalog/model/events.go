@@ -0,0 +1,38 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "time"
+
+// Types of CatalogEvent
+const (
+	EventProductCreated   = "product.created"
+	EventProductUpdated   = "product.updated"
+	EventProductDeleted   = "product.deleted"
+	EventReindexCompleted = "reindex.completed"
+	EventCatalogReset     = "catalog.reset"
+	EventImportCompleted  = "import.completed"
+)
+
+// CatalogEvent describes a change to the catalog
+type CatalogEvent struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type" example:"product.updated"`
+	ProductID string    `json:"productId,omitempty"`
+	Product   *Product  `json:"product,omitempty"`
+	Time      time.Time `json:"time"`
+}
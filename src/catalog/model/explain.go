@@ -0,0 +1,42 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+// SearchExplanation shows how the search provider handled a keyword search:
+// the query it ran and how each hit's relevance score was calculated
+type SearchExplanation struct {
+	Keyword string `json:"keyword"`
+	// Query is the query body sent to the search provider
+	Query map[string]interface{} `json:"query"`
+	Hits  []ExplainedHit         `json:"hits"`
+}
+
+// ExplainedHit is a search result with the breakdown of its score
+type ExplainedHit struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Score       float64          `json:"score"`
+	Explanation ScoreExplanation `json:"explanation"`
+}
+
+// ScoreExplanation is one step of a score calculation, made up of the
+// values in Details
+type ScoreExplanation struct {
+	Value       float64            `json:"value"`
+	Description string             `json:"description"`
+	Details     []ScoreExplanation `json:"details,omitempty"`
+}
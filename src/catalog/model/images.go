@@ -0,0 +1,53 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+// ProductImage is a picture of a product, in the order it is shown. Images
+// the catalog stores have the Key of the object in its image store, and
+// their URL is filled in for each response. Others link to a URL elsewhere.
+type ProductImage struct {
+	ProductID string `json:"-" xml:"-" gorm:"primaryKey;size:64"`
+	Position  int    `json:"-" xml:"-" gorm:"primaryKey"`
+	Key       string `json:"key,omitempty" xml:"key,attr,omitempty" gorm:"size:255"`
+	URL       string `json:"url" xml:"url,attr" gorm:"size:2048"`
+	Alt       string `json:"alt" xml:",chardata" gorm:"size:255"`
+}
+
+// ImageRequest is an image of a product being created or updated, either a
+// key in the image store or a URL elsewhere
+type ImageRequest struct {
+	Key string `json:"key,omitempty" binding:"required_without=URL,excluded_with=URL,max=255"`
+	URL string `json:"url,omitempty" binding:"omitempty,url,max=2048"`
+	Alt string `json:"alt" binding:"max=255"`
+}
+
+// ToImage converts the request to the image of the product at the position
+func (r ImageRequest) ToImage(productID string, position int) ProductImage {
+	image := ProductImage{ProductID: productID, Position: position, Key: r.Key, Alt: r.Alt}
+	if r.Key == "" {
+		image.URL = r.URL
+	}
+	return image
+}
+
+// toRequest converts the image to the request that would create it
+func (i ProductImage) toRequest() ImageRequest {
+	if i.Key != "" {
+		return ImageRequest{Key: i.Key, Alt: i.Alt}
+	}
+	return ImageRequest{URL: i.URL, Alt: i.Alt}
+}
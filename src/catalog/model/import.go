@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "time"
+
+// Statuses of an ImportJob
+const (
+	ImportQueued    = "queued"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportJob reports the progress of a bulk import running in the background.
+// Processed counts the products handled so far, whether they were saved or
+// rejected, and is where a failed job resumes from.
+type ImportJob struct {
+	ID         string        `json:"id"`
+	Status     string        `json:"status" example:"running"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Created    int           `json:"created"`
+	Updated    int           `json:"updated"`
+	Failed     int           `json:"failed"`
+	Errors     []ImportError `json:"errors,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	StartedAt  *time.Time    `json:"startedAt,omitempty"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// ImportError describes a product that was rejected by an import, by its
+// position in the upload
+type ImportError struct {
+	Index     int    `json:"index"`
+	ProductID string `json:"productId"`
+	Error     string `json:"error"`
+}
+
+// GenerateRequest is the body accepted when generating synthetic products
+type GenerateRequest struct {
+	Count int `json:"count" binding:"required,min=1,max=100000" example:"1000"`
+	// Seed generates the same products every time it's given, and is random
+	// if it's omitted
+	Seed *uint64 `json:"seed,omitempty" example:"42"`
+}
@@ -0,0 +1,45 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+// Types of IndexMessage. The product types of CatalogEvent are accepted too,
+// so that catalog events can be delivered to the queue as they are.
+const (
+	IndexProductChanged   = "product.changed"
+	IndexReindexRequested = "reindex.requested"
+)
+
+// IndexMessage asks for the search index entries of products to be brought
+// in line with the catalog, or for the whole index to be rebuilt
+type IndexMessage struct {
+	Type       string   `json:"type" example:"product.changed"`
+	ProductID  string   `json:"productId,omitempty"`
+	ProductIDs []string `json:"productIds,omitempty"`
+}
+
+// IDs returns the IDs of the products the message is about, without duplicates
+func (m IndexMessage) IDs() []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range append([]string{m.ProductID}, m.ProductIDs...) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
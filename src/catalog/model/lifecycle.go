@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "slices"
+
+// Product statuses. Only active products are shown to shoppers: drafts are
+// hidden until they're published, and discontinued products can still be
+// looked up by ID but aren't listed or found by searches.
+const (
+	StatusDraft        = "draft"
+	StatusActive       = "active"
+	StatusDiscontinued = "discontinued"
+)
+
+// Statuses are the statuses a product can have
+var Statuses = []string{StatusDraft, StatusActive, StatusDiscontinued}
+
+// statusTransitions are the statuses a product can move to from each status.
+// Published products can't become drafts again, and drafts are published
+// before they can be discontinued.
+var statusTransitions = map[string][]string{
+	StatusDraft:        {StatusActive},
+	StatusActive:       {StatusDiscontinued},
+	StatusDiscontinued: {StatusActive},
+}
+
+// CanTransition reports whether a product can move from one status to
+// another. Keeping the same status is always allowed.
+func CanTransition(from, to string) bool {
+	return from == to || slices.Contains(statusTransitions[from], to)
+}
+
+// IsValidStatus reports whether status is one of Statuses
+func IsValidStatus(status string) bool {
+	return slices.Contains(Statuses, status)
+}
@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// DefaultCurrency is the currency of prices given without one, which the
+// bundled catalog is priced in
+const DefaultCurrency = "USD"
+
+// currencyExponents are the ISO 4217 currencies whose minor unit isn't a
+// hundredth of the major unit
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Money is an amount in the minor units of an ISO 4217 currency, such as
+// cents for USD, so that prices are exact
+type Money struct {
+	Amount   int    `json:"amount" xml:",chardata" gorm:"column:price;index" binding:"gte=0"`
+	Currency string `json:"currency" xml:"currency,attr" gorm:"size:3;not null;default:USD" binding:"omitempty,iso4217"`
+}
+
+// MinorUnits returns how many minor units make up a major unit of the currency
+func MinorUnits(currency string) int {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		exponent = 2
+	}
+
+	units := 1
+	for range exponent {
+		units *= 10
+	}
+	return units
+}
+
+// MoneyFromUnits returns an amount given in whole units of the currency
+func MoneyFromUnits(units int, currency string) Money {
+	return Money{Amount: units * MinorUnits(currency), Currency: currency}
+}
+
+// Units returns the amount in whole units of its currency, rounded down
+func (m Money) Units() int {
+	return m.Amount / MinorUnits(m.WithDefaultCurrency().Currency)
+}
+
+// WithDefaultCurrency returns the amount in DefaultCurrency if it has no
+// currency of its own
+func (m Money) WithDefaultCurrency() Money {
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	return m
+}
+
+// UnmarshalJSON reads an amount as an object, or as a bare number of whole
+// units of DefaultCurrency, which is how prices were written before they had
+// a currency and how v1 of the API still writes them
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' && !bytes.Equal(data, []byte("null")) {
+		var units int
+		if err := json.Unmarshal(data, &units); err != nil {
+			return err
+		}
+		*m = MoneyFromUnits(units, DefaultCurrency)
+		return nil
+	}
+
+	type money Money
+	return json.Unmarshal(data, (*money)(m))
+}
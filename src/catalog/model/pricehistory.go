@@ -0,0 +1,39 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "time"
+
+// PriceChange records a product's price from the time it was set. The
+// history of a product's price is the list of its changes.
+type PriceChange struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	ProductID string    `json:"-" gorm:"size:64;index"`
+	Price     Money     `json:"price" gorm:"embedded"`
+	ChangedAt time.Time `json:"changedAt" gorm:"index"`
+}
+
+// PriceHistoryResponse is a product's current price and how it got there
+type PriceHistoryResponse struct {
+	ProductID string `json:"productId"`
+	Price     Money  `json:"price"`
+	// PreviousPrice is the price before the last change, for showing a
+	// struck-through price, and is omitted if the price has never changed
+	PreviousPrice *Money `json:"previousPrice,omitempty"`
+	// Changes lists the changes to the price, newest first
+	Changes []PriceChange `json:"changes"`
+}
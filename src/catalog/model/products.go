@@ -16,14 +16,261 @@
 
 package model
 
+import (
+	"encoding/xml"
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Product struct {
-	ID          string `json:"id" gorm:"primaryKey"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Price       int    `json:"price"`
-	Tags        []Tag  `json:"tags" gorm:"many2many:product_tags;"`
+	XMLName      xml.Name  `json:"-" xml:"product" gorm:"-"`
+	ID           string    `json:"id" xml:"id,attr" gorm:"primaryKey"`
+	Name         string    `json:"name" xml:"name"`
+	Description  string    `json:"description" xml:"description"`
+	Price        Money     `json:"price" xml:"price" gorm:"embedded"`
+	Tags         []Tag     `json:"tags" xml:"tags>tag" gorm:"many2many:product_tags;"`
+	CategoryName *string   `json:"-" xml:"-" gorm:"index"`
+	Category     *Category `json:"category,omitempty" xml:"category,omitempty" gorm:"foreignKey:CategoryName"`
+	Variants     []Variant `json:"variants,omitempty" xml:"variants>variant,omitempty" gorm:"foreignKey:ProductID"`
+	// SKU identifies the product in stock systems, and is unique among the
+	// SKUs of products and variants. EAN is the product's barcode.
+	SKU *string `json:"sku,omitempty" xml:"sku,attr,omitempty" gorm:"size:64;uniqueIndex"`
+	EAN *string `json:"ean,omitempty" xml:"ean,attr,omitempty" gorm:"size:13;uniqueIndex"`
+	// Slug is made from the name when the product is created or renamed, and
+	// is unique among the slugs products have and had before
+	Slug string `json:"slug,omitempty" xml:"slug,attr,omitempty" gorm:"size:128;uniqueIndex"`
+	// Images are shown in the order they are given
+	Images []ProductImage `json:"images,omitempty" xml:"images>image,omitempty" gorm:"foreignKey:ProductID"`
+	// Attributes are in name order
+	Attributes []ProductAttribute `json:"attributes,omitempty" xml:"attributes>attribute,omitempty" gorm:"foreignKey:ProductID"`
+	// Translations are in locale order
+	Translations []ProductTranslation `json:"translations,omitempty" xml:"translations>translation,omitempty" gorm:"foreignKey:ProductID"`
+	// Related are the product's relations to others, by type and then
+	// related product. They are set on their own rather than with the
+	// product's other fields.
+	Related []ProductRelation `json:"related,omitempty" xml:"related>relation,omitempty" gorm:"foreignKey:ProductID"`
+	// Rating summarizes the product's reviews, and can't be changed directly
+	Rating Rating `json:"rating,omitzero" xml:"rating" gorm:"embedded;embeddedPrefix:rating_"`
+	// Stock is the number of units available, the total of the variants'
+	// for products that have them. It is only nil in a change that leaves
+	// the stored stock as it is.
+	Stock *int `json:"stock" xml:"stock" gorm:"not null;default:0;index"`
+	// Status is one of Statuses, and decides who can see the product. It is
+	// only empty in a change that leaves the stored status as it is.
+	Status string `json:"status,omitempty" xml:"status,attr,omitempty" gorm:"size:16;not null;default:active;index"`
+	// Version starts at 1 and increases with every change to the product
+	Version int `json:"version,omitempty" xml:"version,attr,omitempty" gorm:"not null;default:1"`
+	// SalePrice is the price with the best running promotion taken off, and
+	// OnSale is set with it. Both are worked out when the product is read.
+	SalePrice *Money `json:"salePrice,omitempty" xml:"salePrice,omitempty" gorm:"-"`
+	OnSale    bool   `json:"onSale,omitempty" xml:"onSale,attr,omitempty" gorm:"-"`
+	// CreatedAt is when the product was added to the catalog, and UpdatedAt
+	// when it last moved to a new version. Reviews and relations don't
+	// change either.
+	CreatedAt time.Time `json:"createdAt,omitzero" xml:"createdAt" gorm:"index"`
+	UpdatedAt time.Time `json:"updatedAt,omitzero" xml:"updatedAt"`
+	// DeletedAt is when the product was deleted. Deleted products are left
+	// out of every query unless it is unscoped, and are only shown to admins
+	// until they are restored.
+	DeletedAt gorm.DeletedAt `json:"deletedAt,omitzero" xml:"-" gorm:"index"`
+}
+
+// ProductList is the XML representation of a list of products
+type ProductList struct {
+	XMLName xml.Name `xml:"products"`
+	// NextCursor continues the list from the end of this page, and is omitted
+	// on the last page
+	NextCursor string    `xml:"nextCursor,attr,omitempty"`
+	Products   []Product `xml:"product"`
+}
+
+// ProductFields are the JSON field names of a product that can be selected
+// with sparse fieldsets
+var ProductFields = []string{"id", "name", "slug", "description", "price", "tags", "category", "variants", "stock", "images", "attributes", "translations", "related", "rating", "status", "sku", "ean", "salePrice", "onSale", "createdAt", "updatedAt"}
+
+// ProductRequest is the body accepted when creating or updating a product
+type ProductRequest struct {
+	ID          string   `json:"id" binding:"max=64"`
+	Name        string   `json:"name" binding:"required,max=255"`
+	Description string   `json:"description" binding:"max=4096"`
+	Price       Money    `json:"price"`
+	Tags        []string `json:"tags"`
+	Category    string   `json:"category" binding:"max=64"`
+	SKU         string   `json:"sku,omitempty" binding:"max=64"`
+	// EAN is an EAN-8 or EAN-13 barcode
+	EAN string `json:"ean,omitempty" binding:"omitempty,numeric,len=8|len=13"`
+	// Variants replace those of the product, which are kept if it's omitted
+	Variants []VariantRequest `json:"variants,omitempty" binding:"omitempty,max=100,dive"`
+	// Stock is kept if it's omitted, and ignored for products with variants
+	Stock *int `json:"stock,omitempty" binding:"omitempty,gte=0"`
+	// Images replace those of the product, which are kept if it's omitted
+	Images []ImageRequest `json:"images,omitempty" binding:"omitempty,max=20,dive"`
+	// Attributes replace those of the product, which are kept if it's omitted
+	Attributes map[string]AttributeValue `json:"attributes,omitempty" binding:"omitempty,max=50,dive,keys,required,max=64,endkeys"`
+	// Translations replace those of the product, which are kept if it's
+	// omitted. They are keyed by locale, which must be one of Locales.
+	Translations map[string]TranslationRequest `json:"translations,omitempty" binding:"omitempty,dive,keys,oneof=de es fr,endkeys,required"`
+	// Status is kept if it's omitted, and new products are active unless
+	// they're created as drafts
+	Status string `json:"status,omitempty" binding:"omitempty,oneof=draft active discontinued"`
+}
+
+// ToProduct converts the request to a product with the given ID
+func (r ProductRequest) ToProduct(id string) *Product {
+	tags := make([]Tag, len(r.Tags))
+	for i, name := range r.Tags {
+		tags[i] = Tag{Name: name}
+	}
+
+	product := &Product{
+		ID:          id,
+		Name:        r.Name,
+		Description: r.Description,
+		Price:       r.Price.WithDefaultCurrency(),
+		Tags:        tags,
+		Stock:       r.Stock,
+		Status:      r.Status,
+	}
+
+	if r.Category != "" {
+		product.CategoryName = &r.Category
+		product.Category = &Category{Name: r.Category}
+	}
+
+	if r.SKU != "" {
+		product.SKU = &r.SKU
+	}
+	if r.EAN != "" {
+		product.EAN = &r.EAN
+	}
+
+	if r.Variants != nil {
+		product.Variants = make([]Variant, len(r.Variants))
+		for i, variant := range r.Variants {
+			product.Variants[i] = variant.ToVariant(id, product.Price)
+		}
+	}
+
+	if r.Images != nil {
+		product.Images = make([]ProductImage, len(r.Images))
+		for i, image := range r.Images {
+			product.Images[i] = image.ToImage(id, i)
+		}
+	}
+
+	if r.Attributes != nil {
+		product.Attributes = ToAttributes(id, r.Attributes)
+	}
+
+	if r.Translations != nil {
+		product.Translations = ToTranslations(id, r.Translations)
+	}
+
+	return product
+}
+
+// ToRequest converts the product to the request that would create it
+func (p Product) ToRequest() ProductRequest {
+	tags := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		tags[i] = tag.Name
+	}
+
+	request := ProductRequest{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Tags:        tags,
+		Stock:       p.Stock,
+		Status:      p.Status,
+	}
+
+	if p.CategoryName != nil {
+		request.Category = *p.CategoryName
+	}
+
+	if p.SKU != nil {
+		request.SKU = *p.SKU
+	}
+	if p.EAN != nil {
+		request.EAN = *p.EAN
+	}
+
+	if p.Variants != nil {
+		request.Variants = make([]VariantRequest, len(p.Variants))
+		for i, variant := range p.Variants {
+			request.Variants[i] = variant.toRequest()
+		}
+	}
+
+	if p.Images != nil {
+		request.Images = make([]ImageRequest, len(p.Images))
+		for i, image := range p.Images {
+			request.Images[i] = image.toRequest()
+		}
+	}
+
+	if p.Attributes != nil {
+		request.Attributes = attributeValues(p.Attributes)
+	}
+
+	if p.Translations != nil {
+		request.Translations = translationRequests(p.Translations)
+	}
+
+	return request
+}
+
+// InStock reports whether any units of the product are available
+func (p Product) InStock() bool {
+	return p.Stock != nil && *p.Stock > 0
+}
+
+// StockAdjustment is the body accepted when changing the stock of a product,
+// or of one of its variants if a SKU is given. Either the change in the
+// number of units or the new number is given.
+type StockAdjustment struct {
+	SKU   string `json:"sku,omitempty" binding:"max=64"`
+	Delta *int   `json:"delta,omitempty" binding:"required_without=Stock,excluded_with=Stock"`
+	Stock *int   `json:"stock,omitempty" binding:"omitempty,gte=0"`
 }
 
 type CatalogSizeResponse struct {
 	Size int `json:"size"`
 }
+
+// ProductLookupRequest is the body accepted when looking up products by ID
+type ProductLookupRequest struct {
+	IDs []string `json:"ids" binding:"required,max=100"`
+}
+
+// LiveSearchRequest is a message sent by clients of the live search endpoint
+type LiveSearchRequest struct {
+	Query string `json:"query"`
+	Size  int    `json:"size,omitempty"`
+}
+
+// LiveSearchResponse is sent to live search clients with the results for
+// their latest query
+type LiveSearchResponse struct {
+	Query    string    `json:"query"`
+	Products []Product `json:"products"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SearchResponse is the envelope returned by the v2 search endpoint
+type SearchResponse struct {
+	XMLName  xml.Name  `json:"-" xml:"searchResponse"`
+	Products []Product `json:"products" xml:"products>product"`
+	Page     int       `json:"page" xml:"page"`
+	Size     int       `json:"size" xml:"size"`
+	// NextCursor continues the search from the end of this page, and is
+	// omitted on the last page
+	NextCursor string `json:"nextCursor,omitempty" xml:"nextCursor,omitempty"`
+	// Facets count the products matching the search in each bucket of a
+	// facet, such as in_stock or attributes.material, when the search
+	// provider can count them
+	Facets map[string]map[string]int `json:"facets,omitempty" xml:"-"`
+}
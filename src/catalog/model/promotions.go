@@ -0,0 +1,91 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Promotion takes money off the price of the products it applies to while it
+// runs. It applies to the products it lists and to those with any of its
+// tags.
+type Promotion struct {
+	ID   string `json:"id" gorm:"primaryKey;size:64"`
+	Name string `json:"name" gorm:"size:255;not null"`
+	// PercentOff takes a percentage off the price, and AmountOff an amount in
+	// the minor units of the product's currency. A promotion has one of them.
+	PercentOff int `json:"percentOff,omitempty" gorm:"not null;default:0"`
+	AmountOff  int `json:"amountOff,omitempty" gorm:"not null;default:0"`
+	// StartsAt and EndsAt bound when the promotion runs, and a promotion
+	// without them runs from when it is created until it is deleted
+	StartsAt   *time.Time `json:"startsAt,omitempty"`
+	EndsAt     *time.Time `json:"endsAt,omitempty"`
+	ProductIDs []string   `json:"productIds,omitempty" gorm:"serializer:json"`
+	Tags       []string   `json:"tags,omitempty" gorm:"serializer:json"`
+}
+
+// Active reports whether the promotion runs at the given time
+func (p Promotion) Active(now time.Time) bool {
+	return (p.StartsAt == nil || !now.Before(*p.StartsAt)) && (p.EndsAt == nil || now.Before(*p.EndsAt))
+}
+
+// AppliesTo reports whether the promotion discounts the product
+func (p Promotion) AppliesTo(product Product) bool {
+	if slices.Contains(p.ProductIDs, product.ID) {
+		return true
+	}
+	for _, tag := range product.Tags {
+		if slices.Contains(p.Tags, tag.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount returns the price with the promotion's discount taken off, never
+// below zero. Percentages off are rounded down to a whole minor unit.
+func (p Promotion) Discount(price Money) Money {
+	amount := price.Amount - price.Amount*p.PercentOff/100 - p.AmountOff
+	return Money{Amount: max(amount, 0), Currency: price.Currency}
+}
+
+// PromotionRequest is the body accepted when creating a promotion
+type PromotionRequest struct {
+	ID         string     `json:"id" binding:"max=64"`
+	Name       string     `json:"name" binding:"required,max=255"`
+	PercentOff int        `json:"percentOff" binding:"required_without=AmountOff,excluded_with=AmountOff,omitempty,min=1,max=100"`
+	AmountOff  int        `json:"amountOff" binding:"required_without=PercentOff,omitempty,min=1"`
+	StartsAt   *time.Time `json:"startsAt"`
+	EndsAt     *time.Time `json:"endsAt"`
+	ProductIDs []string   `json:"productIds" binding:"required_without=Tags,dive,min=1,max=64"`
+	Tags       []string   `json:"tags" binding:"required_without=ProductIDs,dive,min=1,max=64"`
+}
+
+// ToPromotion converts the request to a promotion with the given ID
+func (r PromotionRequest) ToPromotion(id string) Promotion {
+	return Promotion{
+		ID:         id,
+		Name:       r.Name,
+		PercentOff: r.PercentOff,
+		AmountOff:  r.AmountOff,
+		StartsAt:   r.StartsAt,
+		EndsAt:     r.EndsAt,
+		ProductIDs: r.ProductIDs,
+		Tags:       r.Tags,
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+
+// ToProto converts the product to its protobuf message
+func (p Product) ToProto() *catalogv1.Product {
+	tags := make([]*catalogv1.Tag, len(p.Tags))
+	for i, tag := range p.Tags {
+		tags[i] = &catalogv1.Tag{
+			Name:        tag.Name,
+			DisplayName: tag.DisplayName,
+		}
+	}
+
+	message := &catalogv1.Product{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       int32(p.Price.Units()),
+		PriceMoney: &catalogv1.Money{
+			Amount:   int64(p.Price.Amount),
+			Currency: p.Price.WithDefaultCurrency().Currency,
+		},
+		Tags:    tags,
+		Version: int32(p.Version),
+	}
+
+	if p.CategoryName != nil {
+		message.Category = *p.CategoryName
+	} else if p.Category != nil {
+		message.Category = p.Category.Name
+	}
+
+	return message
+}
+
+// ProductsToProto converts a list of products to protobuf messages
+func ProductsToProto(products []Product) []*catalogv1.Product {
+	result := make([]*catalogv1.Product, len(products))
+	for i, product := range products {
+		result[i] = product.ToProto()
+	}
+
+	return result
+}
@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// Types of relationship a product can have with another
+const (
+	// RelationBundle is a product bought together with the other
+	RelationBundle = "bundle"
+	// RelationAccessoryOf is an accessory of the other product
+	RelationAccessoryOf = "accessory-of"
+	// RelationReplacementFor replaces the other product, such as a newer
+	// model of one that's discontinued
+	RelationReplacementFor = "replacement-for"
+)
+
+// RelationTypes are the types of relationship between products. The binding
+// of RelationRequest.Type and the OpenSearch mapping list them as well.
+var RelationTypes = []string{RelationBundle, RelationAccessoryOf, RelationReplacementFor}
+
+// ProductRelation relates a product to another. Relations are one way, so
+// a product's accessories each have an accessory-of relation to it.
+type ProductRelation struct {
+	ProductID string `json:"-" xml:"-" gorm:"primaryKey;size:64"`
+	Type      string `json:"type" xml:"type,attr" gorm:"primaryKey;size:16"`
+	RelatedID string `json:"productId" xml:"productId,attr" gorm:"primaryKey;size:64;index"`
+}
+
+// RelationRequest is a relation of a product to another in a request
+type RelationRequest struct {
+	Type      string `json:"type" binding:"required,oneof=bundle accessory-of replacement-for"`
+	ProductID string `json:"productId" binding:"required,max=64"`
+}
+
+// RelationsRequest is the body accepted when setting the relations of a
+// product, which replace those it has
+type RelationsRequest struct {
+	Related []RelationRequest `json:"related" binding:"max=50,dive"`
+}
+
+// ToRelations converts the relations in a request to those of a product,
+// sorted by type and then related product, without duplicates
+func (r RelationsRequest) ToRelations(productID string) []ProductRelation {
+	relations := make([]ProductRelation, 0, len(r.Related))
+	for _, request := range r.Related {
+		relations = append(relations, ProductRelation{ProductID: productID, Type: request.Type, RelatedID: request.ProductID})
+	}
+	SortRelations(relations)
+	return slices.Compact(relations)
+}
+
+// SortRelations sorts relations by type and then related product
+func SortRelations(relations []ProductRelation) {
+	slices.SortFunc(relations, func(a, b ProductRelation) int {
+		if a.Type != b.Type {
+			return strings.Compare(a.Type, b.Type)
+		}
+		return strings.Compare(a.RelatedID, b.RelatedID)
+	})
+}
+
+// RelatedProduct is a product related to another, with the type of the
+// relation
+type RelatedProduct struct {
+	Type    string  `json:"type"`
+	Product Product `json:"product"`
+}
+
+// RelatedProductsResponse is a product's related products, in the order of
+// its relations
+type RelatedProductsResponse struct {
+	ProductID string           `json:"productId"`
+	Related   []RelatedProduct `json:"related"`
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// MaxRating is the highest rating a review can give, and 1 the lowest
+const MaxRating = 5
+
+// Rating summarizes the reviews of a product. It is kept on the product as
+// reviews are added, so that products can be sorted and filtered by it.
+type Rating struct {
+	// Average is the mean rating rounded to two decimal places, or 0 for a
+	// product without reviews
+	Average float64 `json:"average" xml:"average,attr" gorm:"not null;default:0;index"`
+	Count   int     `json:"count" xml:"count,attr" gorm:"not null;default:0"`
+}
+
+// RoundRating rounds an average rating to two decimal places
+func RoundRating(average float64) float64 {
+	return math.Round(average*100) / 100
+}
+
+// ProductReview is a customer's review of a product
+type ProductReview struct {
+	ID        uint      `json:"id" xml:"id,attr" gorm:"primaryKey;autoIncrement"`
+	ProductID string    `json:"-" xml:"-" gorm:"size:64;index"`
+	Rating    int       `json:"rating" xml:"rating,attr" gorm:"not null"`
+	Title     string    `json:"title" xml:"title" gorm:"size:255"`
+	Text      string    `json:"text" xml:"text"`
+	Author    string    `json:"author" xml:"author" gorm:"size:64"`
+	CreatedAt time.Time `json:"createdAt" xml:"createdAt" gorm:"index"`
+}
+
+// ReviewRequest is the body accepted when reviewing a product
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"required,max=255"`
+	Text   string `json:"text" binding:"max=4096"`
+	Author string `json:"author" binding:"required,max=64"`
+}
+
+// ToReview converts the request to a review of the product
+func (r ReviewRequest) ToReview(productID string) ProductReview {
+	return ProductReview{ProductID: productID, Rating: r.Rating, Title: r.Title, Text: r.Text, Author: r.Author}
+}
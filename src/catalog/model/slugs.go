@@ -0,0 +1,73 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength limits the length of the slug made from a product's name,
+// leaving room in the stored slug for the number that makes it unique
+const MaxSlugLength = 100
+
+// ProductSlug is a slug a product had before it was renamed, which still
+// finds the product so that links to it can be redirected
+type ProductSlug struct {
+	Slug      string `gorm:"primaryKey;size:128"`
+	ProductID string `gorm:"size:64;index"`
+}
+
+// unaccented spells the Latin letters that have no accent to take off
+var unaccented = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+
+// Slugify returns the slug for a product name: lower case letters and
+// digits separated by dashes, with accents taken off. Long names are cut
+// at a word, and names with nothing that can be kept, such as those in
+// non-Latin scripts, get "product".
+func Slugify(name string) string {
+	var slug strings.Builder
+	separate := false
+	for _, r := range norm.NFKD.String(unaccented.Replace(strings.ToLower(name))) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if separate && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			separate = false
+			slug.WriteRune(r)
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			// Accents and apostrophes are dropped without splitting the word
+		default:
+			separate = true
+		}
+	}
+
+	if slug.Len() == 0 {
+		return "product"
+	}
+	if slug.Len() <= MaxSlugLength {
+		return slug.String()
+	}
+	cut := slug.String()[:MaxSlugLength+1]
+	if i := strings.LastIndexByte(cut, '-'); i > 0 {
+		return cut[:i]
+	}
+	return cut[:MaxSlugLength]
+}
@@ -17,6 +17,12 @@
 package model
 
 type Tag struct {
-	Name        string `json:"name" gorm:"primaryKey"`
-	DisplayName string `json:"displayName"`
+	Name        string `json:"name" xml:"name,attr" gorm:"primaryKey"`
+	DisplayName string `json:"displayName" xml:",chardata"`
+}
+
+// TagCount is a tag with the number of products that have it
+type TagCount struct {
+	Tag
+	ProductCount int `json:"productCount"`
 }
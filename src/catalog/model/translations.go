@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import "slices"
+
+// DefaultLocale is the language of products' own names and descriptions
+const DefaultLocale = "en"
+
+// Locales are the other languages products can be translated into. The
+// binding of ProductRequest.Translations and the OpenSearch mapping list
+// them as well.
+var Locales = []string{"de", "es", "fr"}
+
+// SupportsLocale reports whether products can be shown in the language
+func SupportsLocale(locale string) bool {
+	return locale == DefaultLocale || slices.Contains(Locales, locale)
+}
+
+// ProductTranslation is a product's name and description in another language
+type ProductTranslation struct {
+	ProductID   string `json:"-" xml:"-" gorm:"primaryKey;size:64"`
+	Locale      string `json:"locale" xml:"locale,attr" gorm:"primaryKey;size:16"`
+	Name        string `json:"name" xml:"name" gorm:"size:255"`
+	Description string `json:"description" xml:"description"`
+}
+
+// TranslationRequest is a product's name and description in another language
+// in a request. A translation without a description leaves the product's.
+type TranslationRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description,omitempty" binding:"max=4096"`
+}
+
+// ToTranslations converts translations in a request to those of a product,
+// sorted by locale
+func ToTranslations(productID string, requests map[string]TranslationRequest) []ProductTranslation {
+	locales := make([]string, 0, len(requests))
+	for locale := range requests {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+
+	translations := make([]ProductTranslation, len(locales))
+	for i, locale := range locales {
+		request := requests[locale]
+		translations[i] = ProductTranslation{ProductID: productID, Locale: locale, Name: request.Name, Description: request.Description}
+	}
+	return translations
+}
+
+// translationRequests converts the translations of a product to those that
+// would create them
+func translationRequests(translations []ProductTranslation) map[string]TranslationRequest {
+	requests := make(map[string]TranslationRequest, len(translations))
+	for _, translation := range translations {
+		requests[translation.Locale] = TranslationRequest{Name: translation.Name, Description: translation.Description}
+	}
+	return requests
+}
+
+// Localized returns the product with its name and description in the
+// language, if it has been translated into it
+func (p Product) Localized(locale string) Product {
+	for _, translation := range p.Translations {
+		if translation.Locale != locale {
+			continue
+		}
+		p.Name = translation.Name
+		if translation.Description != "" {
+			p.Description = translation.Description
+		}
+		break
+	}
+	return p
+}
@@ -0,0 +1,125 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variant is a version of a product that is sold separately, such as a size
+// or color, identified by its SKU. Each variant has its own price and stock.
+type Variant struct {
+	SKU        string             `json:"sku" xml:"sku,attr" gorm:"primaryKey;size:64"`
+	ProductID  string             `json:"-" xml:"-" gorm:"index;not null"`
+	Price      Money              `json:"price" xml:"price" gorm:"embedded"`
+	Stock      int                `json:"stock" xml:"stock"`
+	Attributes []VariantAttribute `json:"attributes" xml:"attributes>attribute" gorm:"foreignKey:SKU;references:SKU"`
+}
+
+// VariantAttribute is one of the ways a variant differs from the others of
+// its product, such as its size
+type VariantAttribute struct {
+	SKU   string `json:"-" xml:"-" gorm:"primaryKey;size:64"`
+	Name  string `json:"name" xml:"name,attr" gorm:"primaryKey;size:64"`
+	Value string `json:"value" xml:",chardata" gorm:"index;size:255"`
+}
+
+// Attribute returns the value of the variant's attribute with the name, or
+// an empty string if it doesn't have one
+func (v Variant) Attribute(name string) string {
+	for _, attribute := range v.Attributes {
+		if attribute.Name == name {
+			return attribute.Value
+		}
+	}
+	return ""
+}
+
+// MatchesAttributes reports whether the variant has every one of the
+// attribute values
+func (v Variant) MatchesAttributes(attributes map[string]string) bool {
+	for name, value := range attributes {
+		if v.Attribute(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// VariantRequest is a variant of a product being created or updated. The
+// variant has the product's price if it isn't given one.
+type VariantRequest struct {
+	SKU        string            `json:"sku" binding:"required,max=64"`
+	Price      *Money            `json:"price,omitempty"`
+	Stock      int               `json:"stock" binding:"gte=0"`
+	Attributes map[string]string `json:"attributes" binding:"dive,keys,required,max=64,endkeys,max=255"`
+}
+
+// ParseVariantAttribute reads an attribute filter written as name:value
+func ParseVariantAttribute(filter string) (string, string, error) {
+	name, value, ok := strings.Cut(filter, ":")
+	if !ok || name == "" || value == "" {
+		return "", "", fmt.Errorf("variant filter %q must be written as name:value", filter)
+	}
+	return name, value, nil
+}
+
+// ToVariant converts the request to a variant of the product, sorting its
+// attributes by name
+func (r VariantRequest) ToVariant(productID string, productPrice Money) Variant {
+	price := productPrice
+	if r.Price != nil {
+		price = r.Price.WithDefaultCurrency()
+	}
+
+	names := make([]string, 0, len(r.Attributes))
+	for name := range r.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]VariantAttribute, len(names))
+	for i, name := range names {
+		attributes[i] = VariantAttribute{SKU: r.SKU, Name: name, Value: r.Attributes[name]}
+	}
+
+	return Variant{
+		SKU:        r.SKU,
+		ProductID:  productID,
+		Price:      price,
+		Stock:      r.Stock,
+		Attributes: attributes,
+	}
+}
+
+// toRequest converts the variant to the request that would create it
+func (v Variant) toRequest() VariantRequest {
+	attributes := make(map[string]string, len(v.Attributes))
+	for _, attribute := range v.Attributes {
+		attributes[attribute.Name] = attribute.Value
+	}
+
+	price := v.Price
+	return VariantRequest{
+		SKU:        v.SKU,
+		Price:      &price,
+		Stock:      v.Stock,
+		Attributes: attributes,
+	}
+}
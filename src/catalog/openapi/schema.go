@@ -0,0 +1,207 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+// Binary is the type of response bodies in a binary format such as protobuf
+type Binary []byte
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	binaryType    = reflect.TypeOf(Binary{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaOf returns the schema for t, adding named struct types to schemas and
+// referencing them using the same "package.Type" names as swag
+func schemaOf(t reflect.Type, schemas map[string]*Schema) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t == binaryType {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	// Types that write their own JSON may be written as any value
+	if t.Kind() == reflect.Struct && t.Implements(marshalerType) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := schemas[name]; !ok {
+			// Reserve the name first so that recursive types terminate
+			schemas[name] = &Schema{}
+			*schemas[name] = *structSchema(t, schemas)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]*Schema) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := schemaOf(field.Type, schemas)
+			if embedded.Ref != "" {
+				embedded = schemas[strings.TrimPrefix(embedded.Ref, "#/components/schemas/")]
+			}
+
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type, schemas)
+		if property.Ref == "" {
+			applyBinding(property, field.Tag.Get("binding"))
+			applyExample(property, field.Tag.Get("example"))
+		}
+
+		if hasRule(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = property
+	}
+
+	return schema
+}
+
+// applyBinding reflects the validator rules used by gin binding in the schema
+func applyBinding(schema *Schema, binding string) {
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+
+		switch key {
+		case "max":
+			if schema.Type == "string" {
+				if n, err := strconv.Atoi(value); err == nil {
+					schema.MaxLength = &n
+				}
+			}
+		case "gte", "min":
+			if schema.Type == "integer" || schema.Type == "number" {
+				if n, err := strconv.ParseFloat(value, 64); err == nil {
+					schema.Minimum = &n
+				}
+			}
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		}
+	}
+}
+
+func applyExample(schema *Schema, example string) {
+	if example == "" {
+		return
+	}
+
+	if schema.Type == "string" {
+		schema.Example = example
+		return
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(example), &value); err == nil {
+		schema.Example = value
+	}
+}
+
+// hasRule reports whether a binding tag has the rule for the field itself.
+// Rules after dive are for the elements or keys of a slice or map.
+func hasRule(binding, name string) bool {
+	for _, rule := range strings.Split(binding, ",") {
+		if rule == "dive" {
+			break
+		}
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
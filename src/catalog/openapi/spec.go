@@ -0,0 +1,357 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Document is the subset of the OpenAPI 3 object model produced by Spec
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version"`
+	License     *License `json:"license,omitempty"`
+}
+
+// License of the API
+type License struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*operationObject
+
+// Components holds the schemas and security schemes referenced from operations
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes a way of authenticating requests
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Parameter describes a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Response describes one possible response of an operation. Body is a value
+// of the type returned, or nil when the response has no content.
+type Response struct {
+	Description string
+	Body        any
+	ContentType string
+	// Alternatives are other content types the response can be negotiated
+	// as, with a value of the type returned as each
+	Alternatives map[string]any
+}
+
+// Operation describes a route handler
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Parameters  []Parameter
+	Body        any
+	// BodyContentType is the media type of the body, JSON unless it is set
+	BodyContentType string
+	Responses       map[int]Response
+	// Security names the schemes, any one of which authenticates the request
+	Security []string
+}
+
+type operationObject struct {
+	Tags        []string                  `json:"tags,omitempty"`
+	Summary     string                    `json:"summary,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	OperationID string                    `json:"operationId,omitempty"`
+	Parameters  []Parameter               `json:"parameters,omitempty"`
+	RequestBody *requestBodyObject        `json:"requestBody,omitempty"`
+	Responses   map[string]responseObject `json:"responses"`
+	Security    []map[string][]string     `json:"security,omitempty"`
+}
+
+type requestBodyObject struct {
+	Required bool                       `json:"required"`
+	Content  map[string]mediaTypeObject `json:"content"`
+}
+
+type responseObject struct {
+	Description string                     `json:"description"`
+	Content     map[string]mediaTypeObject `json:"content,omitempty"`
+}
+
+type mediaTypeObject struct {
+	Schema *Schema `json:"schema"`
+}
+
+// QueryParam describes an optional query parameter of the given type
+func QueryParam(name, description, schemaType string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      &Schema{Type: schemaType},
+	}
+}
+
+// HeaderParam describes an optional string request header
+func HeaderParam(name, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+// PathParam describes a required string path parameter
+func PathParam(name, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+// Spec collects operation descriptions for route handlers and builds an
+// OpenAPI document from the routes registered with a gin engine
+type Spec struct {
+	info            Info
+	operations      map[string]Operation
+	securitySchemes map[string]SecurityScheme
+
+	once     sync.Once
+	document *Document
+}
+
+// New creates an empty Spec
+func New(info Info) *Spec {
+	return &Spec{
+		info:            info,
+		operations:      map[string]Operation{},
+		securitySchemes: map[string]SecurityScheme{},
+	}
+}
+
+// SecurityScheme adds a named security scheme that operations can reference
+func (s *Spec) SecurityScheme(name string, scheme SecurityScheme) {
+	s.securitySchemes[name] = scheme
+}
+
+// Describe documents the operation served by handler, wherever it is routed
+func (s *Spec) Describe(handler gin.HandlerFunc, operation Operation) {
+	s.operations[handlerName(handler)] = operation
+}
+
+// Build generates a document for the given routes. Routes without a
+// description are still listed, with their path parameters and a plain 200
+// response, so the document always matches what the server exposes.
+func (s *Spec) Build(routes gin.RoutesInfo) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.1",
+		Info:    s.info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: s.securitySchemes,
+		},
+	}
+
+	ownPackage := reflect.TypeOf(Spec{}).PkgPath()
+
+	for _, route := range routes {
+		if strings.HasPrefix(route.Handler, ownPackage+".") {
+			continue
+		}
+
+		path, pathParams := convertPath(route.Path)
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+
+		operation, ok := s.operations[route.Handler]
+		if !ok {
+			operation = Operation{
+				Responses: map[int]Response{
+					http.StatusOK: {Description: "OK"},
+				},
+			}
+		}
+
+		item[strings.ToLower(route.Method)] = s.buildOperation(route.Handler, operation, pathParams, doc.Components.Schemas)
+	}
+
+	return doc
+}
+
+// Handler serves the document as JSON, generating it on the first request
+// once all routes have been registered
+func (s *Spec) Handler(engine *gin.Engine) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		s.once.Do(func() {
+			s.document = s.Build(engine.Routes())
+		})
+
+		ctx.JSON(http.StatusOK, s.document)
+	}
+}
+
+func (s *Spec) buildOperation(handler string, operation Operation, pathParams []string, schemas map[string]*Schema) *operationObject {
+	result := &operationObject{
+		Tags:        operation.Tags,
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		OperationID: operationID(handler),
+		Parameters:  operation.Parameters,
+		Responses:   map[string]responseObject{},
+	}
+
+	for _, scheme := range operation.Security {
+		result.Security = append(result.Security, map[string][]string{scheme: {}})
+	}
+
+	for _, name := range pathParams {
+		if !hasParameter(operation.Parameters, name, "path") {
+			result.Parameters = append(result.Parameters, PathParam(name, ""))
+		}
+	}
+
+	if operation.Body != nil {
+		contentType := operation.BodyContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		result.RequestBody = &requestBodyObject{
+			Required: true,
+			Content: map[string]mediaTypeObject{
+				contentType: {Schema: schemaOf(reflect.TypeOf(operation.Body), schemas)},
+			},
+		}
+	}
+
+	statuses := make([]int, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	for _, status := range statuses {
+		response := operation.Responses[status]
+
+		description := response.Description
+		if description == "" {
+			description = http.StatusText(status)
+		}
+
+		object := responseObject{Description: description}
+		if response.Body != nil {
+			contentType := response.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+
+			object.Content = map[string]mediaTypeObject{
+				contentType: {Schema: schemaOf(reflect.TypeOf(response.Body), schemas)},
+			}
+			for alternative, body := range response.Alternatives {
+				object.Content[alternative] = mediaTypeObject{Schema: schemaOf(reflect.TypeOf(body), schemas)}
+			}
+		}
+
+		result.Responses[strconv.Itoa(status)] = object
+	}
+
+	return result
+}
+
+// convertPath turns gin path parameters (:id, *path) into OpenAPI templates
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	params := []string{}
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// handlerName matches the names gin reports in RouteInfo.Handler
+func handlerName(handler gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+// operationID derives an ID from method value handlers such as
+// "controller.(*Controller).GetProducts-fm", and leaves closures without one
+func operationID(handler string) string {
+	if !strings.HasSuffix(handler, "-fm") {
+		return ""
+	}
+
+	name := strings.TrimSuffix(handler, "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
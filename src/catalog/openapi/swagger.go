@@ -0,0 +1,47 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerHTML string
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+// SwaggerUI serves a Swagger UI page for the document at specURL. The page is
+// embedded in the binary, the UI assets themselves are loaded from a CDN.
+func SwaggerUI(title, specURL string) gin.HandlerFunc {
+	var page bytes.Buffer
+	if err := swaggerTemplate.Execute(&page, map[string]string{
+		"Title":   title,
+		"SpecURL": specURL,
+	}); err != nil {
+		panic(err)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// FacetAttributePrefix starts the names of the facets counting search
+// results by the values of an attribute, such as attributes.material. They
+// are generated for whichever attributes the matching products have.
+const FacetAttributePrefix = "attributes."
+
+// Limits of the attribute facets of a search, so that attributes with many
+// distinct values don't swamp the response. The most common values are kept.
+const (
+	maxAttributeFacets      = 50
+	maxAttributeFacetValues = 20
+)
+
+// DescribeAttributeFilters writes attribute filters the way they are parsed,
+// in order, for keys that must not depend on the order they were given in
+func DescribeAttributeFilters(filters []model.AttributeFilter) string {
+	described := make([]string, len(filters))
+	for i, filter := range filters {
+		described[i] = filter.String()
+	}
+	sort.Strings(described)
+	return strings.Join(described, ",")
+}
+
+// attributeFiltersCondition returns an SQL condition matching products with
+// every one of the attribute filters. A value that is a number also matches
+// the same number written differently.
+func attributeFiltersCondition(filters []model.AttributeFilter) (string, []interface{}) {
+	terms := make([]string, len(filters))
+	args := []interface{}{}
+	for i, filter := range filters {
+		conditions := []string{"product_attributes.name = ?"}
+		args = append(args, filter.Name)
+		if number, ok := filter.Number(); ok {
+			conditions = append(conditions, "(product_attributes.value = ? OR product_attributes.number = ?)")
+			args = append(args, *filter.Value, number)
+		} else if filter.Value != nil {
+			conditions = append(conditions, "product_attributes.value = ?")
+			args = append(args, *filter.Value)
+		}
+		if filter.Min != nil {
+			conditions = append(conditions, "product_attributes.number >= ?")
+			args = append(args, *filter.Min)
+		}
+		if filter.Max != nil {
+			conditions = append(conditions, "product_attributes.number <= ?")
+			args = append(args, *filter.Max)
+		}
+		terms[i] = "products.id IN (SELECT product_attributes.product_id FROM product_attributes WHERE " + strings.Join(conditions, " AND ") + ")"
+	}
+	return strings.Join(terms, " AND "), args
+}
+
+// attributeFiltersQueries returns OpenSearch filters matching products with
+// every one of the attribute filters, one for each since each is matched by
+// a different nested attribute document
+func attributeFiltersQueries(filters []model.AttributeFilter) []map[string]interface{} {
+	queries := make([]map[string]interface{}, len(filters))
+	for i, filter := range filters {
+		conditions := []map[string]interface{}{{"term": map[string]interface{}{"attributes.name": filter.Name}}}
+		if number, ok := filter.Number(); ok {
+			conditions = append(conditions, map[string]interface{}{"bool": map[string]interface{}{
+				"minimum_should_match": 1,
+				"should": []map[string]interface{}{
+					{"term": map[string]interface{}{"attributes.value": *filter.Value}},
+					{"term": map[string]interface{}{"attributes.number": number}},
+				},
+			}})
+		} else if filter.Value != nil {
+			conditions = append(conditions, map[string]interface{}{"term": map[string]interface{}{"attributes.value": *filter.Value}})
+		}
+		if filter.Min != nil || filter.Max != nil {
+			bounds := map[string]interface{}{}
+			if filter.Min != nil {
+				bounds["gte"] = *filter.Min
+			}
+			if filter.Max != nil {
+				bounds["lte"] = *filter.Max
+			}
+			conditions = append(conditions, map[string]interface{}{"range": map[string]interface{}{"attributes.number": bounds}})
+		}
+
+		queries[i] = map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path":  "attributes",
+				"query": map[string]interface{}{"bool": map[string]interface{}{"filter": conditions}},
+			},
+		}
+	}
+	return queries
+}
+
+// attributeFacetsAggregation returns the OpenSearch aggregation counting the
+// products with each value of each attribute
+func attributeFacetsAggregation() map[string]interface{} {
+	return map[string]interface{}{
+		"nested": map[string]interface{}{"path": "attributes"},
+		"aggs": map[string]interface{}{
+			"names": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "attributes.name", "size": maxAttributeFacets},
+				"aggs": map[string]interface{}{
+					"values": map[string]interface{}{
+						"terms": map[string]interface{}{"field": "attributes.value", "size": maxAttributeFacetValues},
+					},
+				},
+			},
+		},
+	}
+}
+
+// attributeCount is the number of products with a value of an attribute
+type attributeCount struct {
+	Name  string
+	Value string
+	Count int
+}
+
+// countAttributes counts the products whose IDs the subquery selects by the
+// values of their attributes
+func countAttributes(db *gorm.DB, ids interface{}) ([]attributeCount, error) {
+	counts := []attributeCount{}
+	err := db.Model(&model.ProductAttribute{}).
+		Select("name, value, COUNT(*) AS count").
+		Where("product_id IN (?)", ids).
+		Group("name, value").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// addAttributeFacets adds the facet of each attribute to the facets, keeping
+// the most common attributes and values like OpenSearch's terms aggregations
+func addAttributeFacets(result map[string]map[string]int, counts []attributeCount) {
+	products := map[string]int{}
+	facets := map[string]map[string]int{}
+	for _, count := range counts {
+		if facets[count.Name] == nil {
+			facets[count.Name] = map[string]int{}
+		}
+		facets[count.Name][count.Value] = count.Count
+		products[count.Name] += count.Count
+	}
+
+	names := make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if products[names[i]] != products[names[j]] {
+			return products[names[i]] > products[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxAttributeFacets {
+		names = names[:maxAttributeFacets]
+	}
+
+	for _, name := range names {
+		result[FacetAttributePrefix+name] = topValues(facets[name], maxAttributeFacetValues)
+	}
+}
+
+// topValues keeps the most common values of a facet
+func topValues(counts map[string]int, limit int) map[string]int {
+	if len(counts) <= limit {
+		return counts
+	}
+
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	top := make(map[string]int, limit)
+	for _, value := range values[:limit] {
+		top[value] = counts[value]
+	}
+	return top
+}
+
+// preloadAttributes loads the attributes of the products a query returns,
+// in name order
+func preloadAttributes(query *gorm.DB) *gorm.DB {
+	return query.Preload("Attributes", func(db *gorm.DB) *gorm.DB { return db.Order("product_attributes.name asc") })
+}
+
+// saveAttributes replaces the attributes of a product, unless they are nil,
+// and loads the stored attributes into the product
+func saveAttributes(tx *gorm.DB, product *model.Product) error {
+	if product.Attributes != nil {
+		if err := deleteAttributes(tx, product.ID); err != nil {
+			return err
+		}
+
+		for i := range product.Attributes {
+			product.Attributes[i].ProductID = product.ID
+		}
+		if len(product.Attributes) > 0 {
+			if err := tx.Create(&product.Attributes).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	attributes := []model.ProductAttribute{}
+	if err := tx.Where("product_id = ?", product.ID).Order("name asc").Find(&attributes).Error; err != nil {
+		return err
+	}
+	product.Attributes = attributes
+	return nil
+}
+
+// deleteAttributes removes the attributes of a product
+func deleteAttributes(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.ProductAttribute{}).Error
+}
+
+// seedAttributes returns the attributes of a bundled product
+func seedAttributes(product ProductData) []model.ProductAttribute {
+	return model.ToAttributes(product.ID, product.Attributes)
+}
@@ -0,0 +1,150 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned instead of calling a dependency whose circuit
+// breaker is open
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// States of a circuit breaker
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// breaker stops calls to a dependency after threshold of them have failed in
+// a row, so that requests fail fast rather than waiting on an outage. Once
+// cooldown has passed a single call is let through as a probe, closing the
+// breaker again if it succeeds and reopening it if it fails.
+type breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newBreaker returns a closed breaker, or nil, which allows every call, when
+// threshold isn't positive
+func newBreaker(name string, threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	observeBreakerState(name, breakerClosed)
+	return &breaker{name: name, threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// allow reports whether a call can be made, moving an open breaker whose
+// cooldown has passed to half-open and letting the caller probe
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.transition(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record counts the outcome of an allowed call. A call cancelled by its
+// caller says nothing about the dependency, so only gives up the probe.
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		b.probing = false
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		if b.state != breakerClosed {
+			b.transition(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.transition(breakerOpen)
+	}
+}
+
+// State returns whether the breaker is closed, open or half-open
+func (b *breaker) State() string {
+	if b == nil {
+		return breakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// transition moves the breaker to a new state, which is logged since it
+// marks the start or end of an outage
+func (b *breaker) transition(state string) {
+	previous := b.state
+	b.state = state
+	b.probing = false
+	observeBreakerState(b.name, state)
+
+	switch state {
+	case breakerOpen:
+		slog.Warn("Circuit breaker opened", "dependency", b.name, "from", previous, "failures", b.failures, "cooldown", b.cooldown)
+	case breakerHalfOpen:
+		slog.Info("Circuit breaker half-open, probing", "dependency", b.name)
+	default:
+		slog.Info("Circuit breaker closed", "dependency", b.name, "from", previous)
+	}
+}
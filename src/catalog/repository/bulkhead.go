@@ -0,0 +1,154 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BulkheadFullError is returned instead of calling a dependency that already
+// has as many calls in flight as its bulkhead allows
+type BulkheadFullError struct {
+	Dependency string
+	Limit      int
+}
+
+func (e *BulkheadFullError) Error() string {
+	return fmt.Sprintf("%s bulkhead is full, %d calls are in flight", e.Dependency, e.Limit)
+}
+
+// Unavailable reports that the call was turned away rather than failed, so
+// that it can be retried once the dependency has caught up
+func (e *BulkheadFullError) Unavailable() bool {
+	return true
+}
+
+// bulkhead limits the calls in flight to a dependency, so that a dependency
+// slowing down holds on to a bounded share of the service's goroutines and
+// connections and calls to the others keep being served. A call waits up to
+// wait for a slot before being turned away.
+type bulkhead struct {
+	dependency string
+	slots      chan struct{}
+	wait       time.Duration
+}
+
+type bulkheadKey struct{}
+
+var (
+	bulkheadsMu sync.RWMutex
+	bulkheads   = map[string]*bulkhead{}
+)
+
+// SetBulkhead limits the calls in flight to a dependency to limit, waiting up
+// to wait for a slot. A limit of zero removes the bulkhead. Calls already in
+// flight aren't counted against a replaced bulkhead.
+func SetBulkhead(dependency string, limit int, wait time.Duration) error {
+	if limit < 0 {
+		return fmt.Errorf("bulkhead limit can't be negative")
+	}
+	if wait < 0 {
+		return fmt.Errorf("bulkhead wait can't be negative")
+	}
+
+	bulkheadsMu.Lock()
+	defer bulkheadsMu.Unlock()
+
+	if limit == 0 {
+		delete(bulkheads, dependency)
+		return nil
+	}
+	bulkheads[dependency] = &bulkhead{dependency: dependency, slots: make(chan struct{}, limit), wait: wait}
+	return nil
+}
+
+// acquireBulkhead takes a slot in the bulkhead of a dependency for a call,
+// returning a func to give it back once the call is done. Calls made while
+// the context already holds a slot, such as the statements loading the
+// associations of a query, share it, so that they can't wait on themselves.
+func acquireBulkhead(dependency string, ctx context.Context) (context.Context, func(), error) {
+	bulkheadsMu.RLock()
+	b := bulkheads[dependency]
+	bulkheadsMu.RUnlock()
+
+	if b == nil || ctx.Value(bulkheadKey{}) == b {
+		return ctx, func() {}, nil
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		timer := time.NewTimer(b.wait)
+		defer timer.Stop()
+
+		select {
+		case b.slots <- struct{}{}:
+		case <-timer.C:
+			observeBulkheadRejected(dependency)
+			return ctx, nil, &BulkheadFullError{Dependency: dependency, Limit: cap(b.slots)}
+		case <-ctx.Done():
+			return ctx, nil, ctx.Err()
+		}
+	}
+	observeBulkheadInFlight(dependency, len(b.slots))
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			<-b.slots
+			observeBulkheadInFlight(dependency, len(b.slots))
+		})
+	}
+	return context.WithValue(ctx, bulkheadKey{}, b), release, nil
+}
+
+// bulkheadTransport holds a slot in the OpenSearch bulkhead for each request
+// until its response body is closed, since the connection is busy until then
+type bulkheadTransport struct {
+	base http.RoundTripper
+}
+
+func (t *bulkheadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, release, err := acquireBulkhead(DependencyOpenSearch, req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// releasingBody gives back a bulkhead slot once the response is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
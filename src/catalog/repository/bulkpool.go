@@ -0,0 +1,81 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// IndexBatches sends the batches of products read by produce to the indexer
+// with a pool of workers, each sending one bulk request at a time, and
+// reports the products indexed and rejected by each batch to progress, which
+// is called from the workers. No more batches are read than the workers can
+// take on, so memory use is bounded however large the catalog is. The first
+// error, or the context ending, stops reading and returns once the batches
+// already being indexed have finished.
+func IndexBatches(indexer BulkIndexer, workers int, produce func(send func([]model.Product) error) error, progress func(indexed, failed int), ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first error from a worker, which stops the rest
+	var failure error
+	var failOnce sync.Once
+
+	batches := make(chan []model.Product)
+	var wg sync.WaitGroup
+	for i := range max(workers, 1) {
+		worker := strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				started := time.Now()
+				failed, err := indexer.IndexProducts(batch, ctx)
+				observeIndexBatch(worker, len(batch), failed, err, time.Since(started))
+				if err != nil {
+					failOnce.Do(func() { failure = err })
+					cancel()
+					return
+				}
+				progress(len(batch)-failed, failed)
+			}
+		}()
+	}
+
+	err := produce(func(batch []model.Product) error {
+		select {
+		case batches <- batch:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(batches)
+	wg.Wait()
+
+	// A worker's error is what stopped the batches being read, rather than
+	// the cancellation it caused
+	if failure != nil {
+		return failure
+	}
+	return err
+}
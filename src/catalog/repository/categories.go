@@ -0,0 +1,35 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// categoryCondition returns an SQL condition matching products in the
+// category or its descendants, whose paths start with the category's
+func categoryCondition(category model.Category) (string, []interface{}) {
+	return "products.category_name IN (SELECT name FROM categories WHERE path = ? OR path LIKE ? ESCAPE '!')",
+		[]interface{}{category.Path, escapeLike(category.Path+model.CategoryPathSeparator) + "%"}
+}
+
+// categoryQuery returns an OpenSearch filter matching products in the
+// category or its descendants. The path is indexed with a path_hierarchy
+// tokenizer, so that a product's path has a term for each of its ancestors.
+func categoryQuery(category model.Category) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{"categoryPath.tree": category.Path}}
+}
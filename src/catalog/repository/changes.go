@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"gorm.io/gorm"
+)
+
+// ChangeLog interface for repositories that record every change to a
+// product, so that other services can follow the catalog incrementally
+type ChangeLog interface {
+	// GetChangesAfter returns up to limit changes with a higher sequence
+	// number, oldest first
+	GetChangesAfter(sequence uint64, limit int, ctx context.Context) ([]model.ProductChange, error)
+	// GetChangesSince returns up to limit changes made after a point in time,
+	// oldest first
+	GetChangesSince(since time.Time, limit int, ctx context.Context) ([]model.ProductChange, error)
+	// LatestChangeSequence returns the sequence number of the most recent
+	// change, or zero if nothing has changed
+	LatestChangeSequence(ctx context.Context) (uint64, error)
+}
+
+// recordChange adds a change to the log in the transaction that makes it
+func recordChange(tx *gorm.DB, changeType, productID string) error {
+	return tx.Create(&model.ProductChange{
+		Type:      changeType,
+		ProductID: productID,
+		ChangedAt: time.Now().UTC(),
+	}).Error
+}
+
+func (db *Database) GetChangesAfter(sequence uint64, limit int, ctx context.Context) ([]model.ProductChange, error) {
+	changes := []model.ProductChange{}
+	err := db.reads().WithContext(ctx).
+		Where("sequence > ?", sequence).
+		Order("sequence").
+		Limit(limit).
+		Find(&changes).Error
+
+	return changes, err
+}
+
+func (db *Database) GetChangesSince(since time.Time, limit int, ctx context.Context) ([]model.ProductChange, error) {
+	changes := []model.ProductChange{}
+	err := db.reads().WithContext(ctx).
+		Where("changed_at > ?", since.UTC()).
+		Order("sequence").
+		Limit(limit).
+		Find(&changes).Error
+
+	return changes, err
+}
+
+func (db *Database) LatestChangeSequence(ctx context.Context) (uint64, error) {
+	var sequence uint64
+	err := db.reads().WithContext(ctx).
+		Model(&model.ProductChange{}).
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&sequence).Error
+
+	return sequence, err
+}
@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescer runs a single copy of identical calls that are in flight at the
+// same time and shares its result, so that a burst of the same search sends
+// one query to the cluster rather than one per request
+type coalescer struct {
+	group singleflight.Group
+}
+
+// newCoalescer returns a coalescer, or nil, which runs every call, when
+// coalescing isn't enabled
+func newCoalescer(enabled bool) *coalescer {
+	if !enabled {
+		return nil
+	}
+	return &coalescer{}
+}
+
+// coalesce runs call unless one with the same key is already in flight, in
+// which case it waits for that call's result instead and reports that it was
+// shared. The call isn't cancelled when the caller that started it gives up,
+// since others may be waiting for it, but keeps that caller's deadline, and
+// each caller stops waiting once its own context is done.
+func coalesce[T any](c *coalescer, key string, call func(ctx context.Context) (T, error), ctx context.Context) (value T, shared bool, err error) {
+	if c == nil {
+		value, err = call(ctx)
+		return value, false, err
+	}
+
+	// Read only once the result is received, which happens after the call
+	leader := false
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		leader = true
+
+		callCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithDeadline(callCtx, deadline)
+			defer cancel()
+		}
+		return call(callCtx)
+	})
+
+	select {
+	case result := <-results:
+		if !leader {
+			observeCoalesced()
+		}
+		if result.Err != nil {
+			return value, !leader, result.Err
+		}
+		return result.Val.(T), !leader, nil
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// SKULookup interface for repositories that can find a product by the exact
+// SKU of the product or one of its variants, or by its EAN
+type SKULookup interface {
+	GetProductBySKU(code string, ctx context.Context) (*model.Product, error)
+}
+
+// GetProductBySKU returns the product with the SKU or EAN, or with a variant
+// with the SKU. Codes are matched exactly.
+func (db *Database) GetProductBySKU(code string, ctx context.Context) (*model.Product, error) {
+	product := model.Product{}
+
+	reads := db.reads().WithContext(ctx)
+	variants := reads.Session(&gorm.Session{NewDB: true}).Model(&model.Variant{}).Select("product_id").Where("sku = ?", code)
+	err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(reads.Preload("Tags").Preload("Category")))))).
+		Where("products.sku = ? OR products.ean = ? OR products.id IN (?)", code, code, variants).
+		First(&product).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// skuPattern matches keywords that could be a SKU or barcode: a single word
+// of letters and digits, which may be separated by dashes, dots, slashes or
+// underscores
+var skuPattern = regexp.MustCompile(`^[\pL\pN]+([-_./][\pL\pN]+)*$`)
+
+// looksLikeSKU reports whether a keyword could be a SKU or barcode, which
+// searches then also match against codes. Codes have at least one digit,
+// which tells them apart from most words.
+func looksLikeSKU(keyword string) bool {
+	return skuPattern.MatchString(keyword) && strings.ContainsAny(keyword, "0123456789")
+}
+
+// skuQuery returns an OpenSearch query matching the keyword as text, or as
+// the SKU or EAN of the product or the SKU of one of its variants, with exact
+// code matches ranked first. SKUs are also matched by their parts.
+func skuQuery(keyword string, text map[string]interface{}) map[string]interface{} {
+	exact := func(field string) map[string]interface{} {
+		return map[string]interface{}{"term": map[string]interface{}{field: map[string]interface{}{"value": keyword, "case_insensitive": true}}}
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []map[string]interface{}{
+				text,
+				{"constant_score": map[string]interface{}{"filter": exact("sku"), "boost": 100}},
+				{"constant_score": map[string]interface{}{"filter": exact("ean"), "boost": 100}},
+				{"nested": map[string]interface{}{"path": "variants", "query": map[string]interface{}{
+					"constant_score": map[string]interface{}{"filter": exact("variants.sku"), "boost": 100},
+				}}},
+				{"match": map[string]interface{}{"sku.ngram": map[string]interface{}{"query": keyword, "operator": "and"}}},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// checkCodes checks that a product's SKU isn't another product's or one of
+// their variants', that its variants' SKUs aren't other products', and that
+// its EAN is a valid barcode no other product has. Deleted products keep
+// their codes, so that they can be restored.
+func checkCodes(tx *gorm.DB, product *model.Product) error {
+	products := func() *gorm.DB { return tx.Unscoped().Model(&model.Product{}) }
+
+	if product.SKU != nil {
+		var taken int64
+		err := products().Where("sku = ? AND id <> ?", *product.SKU, product.ID).Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken == 0 {
+			err = tx.Model(&model.Variant{}).Where("sku = ? AND product_id <> ?", *product.SKU, product.ID).Count(&taken).Error
+			if err != nil {
+				return err
+			}
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: %s", ErrSKUExists, *product.SKU)
+		}
+	}
+
+	if len(product.Variants) > 0 {
+		skus := make([]string, len(product.Variants))
+		for i, variant := range product.Variants {
+			skus[i] = variant.SKU
+		}
+
+		var taken []string
+		err := products().Where("sku IN ? AND id <> ?", skus, product.ID).Pluck("sku", &taken).Error
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("%w: %s", ErrSKUExists, strings.Join(taken, ", "))
+		}
+	}
+
+	if product.EAN != nil {
+		if !model.ValidEAN(*product.EAN) {
+			return fmt.Errorf("%w: %s", ErrInvalidEAN, *product.EAN)
+		}
+
+		var taken int64
+		if err := products().Where("ean = ? AND id <> ?", *product.EAN, product.ID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: %s", ErrEANExists, *product.EAN)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// CSVColumns are the columns of a CSV product dataset. The first row names
+// the columns it has, in any order, and id, name and price are required.
+var CSVColumns = []string{"id", "name", "description", "price", "currency", "tags", "category", "stock"}
+
+var requiredCSVColumns = []string{"id", "name", "price"}
+
+// csvTagSeparator separates multiple tags within the tags column
+const csvTagSeparator = "|"
+
+// currencyCode matches the form of an ISO 4217 currency code
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// parseCSVProductData reads products from a CSV dataset. Problems are
+// reported with the row they're on, counting the header as row 1, as
+// spreadsheets do.
+func parseCSVProductData(data []byte) ([]ProductData, error) {
+	// Spreadsheets often start their exports with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV product data has no header row")
+	} else if err != nil {
+		return nil, fmt.Errorf("error parsing CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(CSVColumns, name) {
+			return nil, fmt.Errorf("row 1: unknown column %q, the columns are %s", name, strings.Join(CSVColumns, ","))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("row 1: column %q is given more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("row 1: the %q column is required", name)
+		}
+	}
+	reader.FieldsPerRecord = len(header)
+
+	products := []ProductData{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return products, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("row %d: %w", parseErr.StartLine, parseErr.Err)
+		} else if err != nil {
+			return nil, fmt.Errorf("error parsing CSV: %w", err)
+		}
+
+		product, err := csvProduct(record, columns)
+		if err != nil {
+			row, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		products = append(products, product)
+	}
+}
+
+// csvProduct converts a CSV record to a product
+func csvProduct(record []string, columns map[string]int) (ProductData, error) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	product := ProductData{
+		ID:          value("id"),
+		Name:        value("name"),
+		Description: value("description"),
+		Category:    value("category"),
+	}
+	if product.ID == "" {
+		return product, errors.New("id is required")
+	}
+	if product.Name == "" {
+		return product, errors.New("name is required")
+	}
+
+	amount, err := strconv.Atoi(value("price"))
+	if err != nil || amount < 0 {
+		return product, fmt.Errorf("price must be a whole number of minor units such as cents, got %q", value("price"))
+	}
+	product.Price = model.Money{Amount: amount, Currency: strings.ToUpper(value("currency"))}.WithDefaultCurrency()
+	if !currencyCode.MatchString(product.Price.Currency) {
+		return product, fmt.Errorf("currency must be an ISO 4217 code such as USD, got %q", value("currency"))
+	}
+
+	for _, tag := range strings.Split(value("tags"), csvTagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			product.Tags = append(product.Tags, tag)
+		}
+	}
+
+	if stock := value("stock"); stock != "" {
+		units, err := strconv.Atoi(stock)
+		if err != nil || units < 0 {
+			return product, fmt.Errorf("stock must be a whole number that isn't negative, got %q", stock)
+		}
+		product.Stock = &units
+	}
+
+	return product, nil
+}
@@ -4,6 +4,8 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
 )
 
 //go:embed products.json
@@ -12,12 +14,39 @@ var productsString []byte
 //go:embed tags.json
 var tagsString []byte
 
+//go:embed categories.json
+var categoriesString []byte
+
 type ProductData struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	ID          string   `json:"id"`
-	Price       int      `json:"price"`
-	Tags        []string `json:"tags"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	ID          string      `json:"id"`
+	Price       model.Money `json:"price"`
+	Tags        []string    `json:"tags"`
+	Category    string      `json:"category"`
+	// Variants are priced like the product unless they say otherwise
+	Variants []model.VariantRequest `json:"variants"`
+	Stock    *int                   `json:"stock"`
+	Images   []model.ImageRequest   `json:"images"`
+	// Attributes are strings, numbers or booleans
+	Attributes map[string]model.AttributeValue `json:"attributes"`
+	// Translations are keyed by locale
+	Translations map[string]model.TranslationRequest `json:"translations"`
+	Reviews      []model.ReviewRequest               `json:"reviews"`
+}
+
+// stock returns the stock of a bundled product, the total of its variants'
+// if it has any
+func (p ProductData) stock() *int {
+	if len(p.Variants) == 0 {
+		return p.Stock
+	}
+
+	stock := 0
+	for _, variant := range p.Variants {
+		stock += variant.Stock
+	}
+	return &stock
 }
 
 type ProductTagData struct {
@@ -25,12 +54,37 @@ type ProductTagData struct {
 	DisplayName string `json:"displayName"`
 }
 
+// CategoryData is a bundled category, listed after its parent
+type CategoryData struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Parent      string `json:"parent"`
+}
+
+// LoadProductData returns the bundled products, or the dataset that
+// replaces them
 func LoadProductData() ([]ProductData, error) {
+	productDataMu.RLock()
+	data, format := productData, productDataFormat
+	productDataMu.RUnlock()
+
+	if data == nil {
+		data, format = productsString, ProductDataJSON
+	}
+	return parseProductData(data, format)
+}
+
+// parseProductData reads products from a dataset in the format
+func parseProductData(data []byte, format string) ([]ProductData, error) {
+	if format == ProductDataCSV {
+		return parseCSVProductData(data)
+	}
+
 	// Create a slice to hold the products
 	var products []ProductData
 
 	// Unmarshal JSON array into the slice
-	err := json.Unmarshal(productsString, &products)
+	err := json.Unmarshal(data, &products)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing JSON: %v", err)
 	}
@@ -50,3 +104,40 @@ func LoadProductTagData() ([]ProductTagData, error) {
 
 	return productTags, nil
 }
+
+func LoadCategoryData() ([]CategoryData, error) {
+	var categories []CategoryData
+
+	err := json.Unmarshal(categoriesString, &categories)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+
+	return categories, nil
+}
+
+// categoryModels converts the bundled categories to models with their paths
+func categoryModels(categories []CategoryData) ([]model.Category, error) {
+	paths := make(map[string]string, len(categories))
+	result := make([]model.Category, 0, len(categories))
+
+	for _, category := range categories {
+		entity := model.Category{Name: category.Name, DisplayName: category.DisplayName, Path: category.Name}
+
+		if category.Parent != "" {
+			parentPath, ok := paths[category.Parent]
+			if !ok {
+				return nil, fmt.Errorf("category %s is listed before its parent %s", category.Name, category.Parent)
+			}
+
+			parent := category.Parent
+			entity.ParentName = &parent
+			entity.Path = parentPath + model.CategoryPathSeparator + category.Name
+		}
+
+		paths[category.Name] = entity.Path
+		result = append(result, entity)
+	}
+
+	return result, nil
+}
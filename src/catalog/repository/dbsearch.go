@@ -0,0 +1,187 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	RegisterSearch("database", func(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+		searcher, ok := catalog.(SearchRepository)
+		if !ok {
+			return nil, fmt.Errorf("database search is not supported by the %s persistence provider", config.Database.Type)
+		}
+
+		slog.Info("Using database search")
+		return searcher, nil
+	})
+}
+
+// SearchProducts is a basic search directly against the product table. A
+// product matches if any keyword term appears in its name, description or
+// tags, or in its translation into the locale searched in, or if the keyword
+// is its SKU or EAN or the SKU of one of its variants. Name and code matches
+// are ranked first unless results are sorted by rating.
+func (db *Database) SearchProducts(search SearchQuery, page, size int, ctx context.Context) (SearchResult, error) {
+	keyword := search.Keyword
+	terms := strings.Fields(strings.ToLower(keyword))
+	if len(terms) == 0 {
+		return SearchResult{Products: []model.Product{}}, nil
+	}
+
+	query := db.reads().WithContext(ctx).
+		Model(&model.Product{}).
+		Joins("LEFT JOIN product_tags ON product_tags.product_id = products.id")
+
+	locale := search.translationLocale()
+	if locale != "" {
+		query = query.Joins("LEFT JOIN product_translations ON product_translations.product_id = products.id AND product_translations.locale = ?", locale)
+	}
+
+	if len(search.PriceRanges) > 0 {
+		condition, args := priceRangesCondition(search.PriceRanges)
+		query = query.Where(condition, args...)
+	}
+
+	if len(search.VariantAttributes) > 0 {
+		condition, args := variantAttributesCondition(search.VariantAttributes)
+		query = query.Where(condition, args...)
+	}
+
+	if search.Category != nil {
+		condition, args := categoryCondition(*search.Category)
+		query = query.Where(condition, args...)
+	}
+
+	if len(search.Attributes) > 0 {
+		condition, args := attributeFiltersCondition(search.Attributes)
+		query = query.Where(condition, args...)
+	}
+
+	if search.MinRating != nil {
+		query = query.Where("products.rating_average >= ?", *search.MinRating)
+	}
+
+	if statuses := search.statuses(); len(statuses) > 0 {
+		query = query.Where("products.status IN ?", statuses)
+	}
+
+	if search.OnSale != nil {
+		condition, args := saleCondition(*search.OnSale, search.Sale)
+		query = query.Where(condition, args...)
+	}
+
+	conditions := []string{}
+	nameConditions := []string{}
+	args := []interface{}{}
+	nameArgs := []interface{}{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions = append(conditions, "LOWER(products.name) LIKE ? ESCAPE '!' OR LOWER(products.description) LIKE ? ESCAPE '!' OR product_tags.tag_name = ?")
+		args = append(args, pattern, pattern, term)
+
+		nameConditions = append(nameConditions, "LOWER(products.name) LIKE ? ESCAPE '!'")
+		nameArgs = append(nameArgs, pattern)
+
+		if locale != "" {
+			conditions = append(conditions, "LOWER(product_translations.name) LIKE ? ESCAPE '!' OR LOWER(product_translations.description) LIKE ? ESCAPE '!'")
+			args = append(args, pattern, pattern)
+
+			nameConditions = append(nameConditions, "LOWER(product_translations.name) LIKE ? ESCAPE '!'")
+			nameArgs = append(nameArgs, pattern)
+		}
+	}
+
+	if looksLikeSKU(keyword) {
+		code := strings.ToLower(keyword)
+		condition := "LOWER(products.sku) = ? OR products.ean = ? OR products.id IN (SELECT product_id FROM variants WHERE LOWER(variants.sku) = ?)"
+		conditions = append(conditions, condition)
+		args = append(args, code, code, code)
+		nameConditions = append(nameConditions, condition)
+		nameArgs = append(nameArgs, code, code, code)
+	}
+
+	query = query.Where(strings.Join(conditions, " OR "), args...).Session(&gorm.Session{})
+
+	result := SearchResult{}
+	if search.Facets {
+		var counts struct {
+			InStock int
+			Total   int
+		}
+		err := db.reads().WithContext(ctx).
+			Table("(?) AS matches", query.Distinct("products.id", "products.stock")).
+			Select("COALESCE(SUM(CASE WHEN stock > 0 THEN 1 ELSE 0 END), 0) AS in_stock, COUNT(*) AS total").
+			Scan(&counts).Error
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to count search results in stock: %w", err)
+		}
+		result.Facets = map[string]map[string]int{FacetInStock: stockCounts(counts.InStock, counts.Total-counts.InStock)}
+
+		attributes, err := countAttributes(db.reads().WithContext(ctx), query.Distinct("products.id"))
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to count search results by attribute: %w", err)
+		}
+		addAttributeFacets(result.Facets, attributes)
+	}
+
+	if search.InStock != nil {
+		query = query.Where(stockCondition(*search.InStock))
+	}
+
+	if order := searchOrder(search.Order); order != "" {
+		query = query.Order(order)
+	}
+
+	result.Products = []model.Product{}
+	err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(query.Preload("Tags")))))).
+		Group("products.id").
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "CASE WHEN " + strings.Join(nameConditions, " OR ") + " THEN 0 ELSE 1 END",
+			Vars:               nameArgs,
+			WithoutParentheses: true,
+		}}).
+		Order("products.name asc").
+		Offset((page - 1) * size).
+		Limit(size).
+		Find(&result.Products).Error
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("search request failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Reindex has nothing to rebuild since database search reads the product table directly
+func (db *Database) Reindex(ctx context.Context) error {
+	return nil
+}
+
+// escapeLike escapes LIKE wildcards using "!", which unlike a backslash
+// behaves the same in MySQL and SQLite
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
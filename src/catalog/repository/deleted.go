@@ -0,0 +1,129 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// DeletedProductRepository interface for repositories that soft delete
+// products, so that they can be listed and restored
+type DeletedProductRepository interface {
+	// GetDeletedProducts returns a page of the deleted products, most
+	// recently deleted first
+	GetDeletedProducts(pageNum, pageSize int, ctx context.Context) ([]model.Product, error)
+	// RestoreProduct undeletes a product, returning ErrProductNotFound if
+	// there is no deleted product with the ID
+	RestoreProduct(id string, ctx context.Context) (*model.Product, error)
+}
+
+// GetDeletedProducts returns a page of the deleted products, most recently
+// deleted first
+func (db *Database) GetDeletedProducts(pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
+	products := []model.Product{}
+
+	err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(db.reads().WithContext(ctx).Unscoped().Preload("Tags").Preload("Category")))))).
+		Where("products.deleted_at IS NOT NULL").
+		Order("products.deleted_at desc").
+		Order("products.id asc").
+		Offset((pageNum - 1) * pageSize).
+		Limit(pageSize).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// RestoreProduct undeletes a product, moving it to its next version
+func (db *Database) RestoreProduct(id string, ctx context.Context) (*model.Product, error) {
+	defer db.markWrite()
+
+	var product model.Product
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r := tx.Unscoped().Model(&model.Product{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(tx.Preload("Tags").Preload("Category")))))).
+			Where("id = ?", id).
+			First(&product).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeCreated, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// purgeDeletedProduct permanently removes a deleted product with the ID, if
+// there is one, with its variants, reviews and everything else
+func purgeDeletedProduct(tx *gorm.DB, id string) error {
+	var count int64
+	if err := tx.Unscoped().Model(&model.Product{}).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&model.Product{ID: id}).Association("Tags").Clear(); err != nil {
+		return err
+	}
+	if err := deleteVariants(tx, id); err != nil {
+		return err
+	}
+	if err := deleteImages(tx, id); err != nil {
+		return err
+	}
+	if err := deleteAttributes(tx, id); err != nil {
+		return err
+	}
+	if err := deleteTranslations(tx, id); err != nil {
+		return err
+	}
+	if err := deleteReviews(tx, id); err != nil {
+		return err
+	}
+	if err := deletePriceHistory(tx, id); err != nil {
+		return err
+	}
+	if err := deleteRelations(tx, id); err != nil {
+		return err
+	}
+	if err := deleteSlugs(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(&model.Product{}, "id = ?", id).Error
+}
@@ -0,0 +1,563 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// IndexFailure records a write that reached the primary store but not the search index
+type IndexFailure struct {
+	ProductID string    `json:"productId"`
+	Operation string    `json:"operation"`
+	Error     string    `json:"error"`
+	Time      time.Time `json:"time"`
+}
+
+// ReconcileReport describes the differences found between the primary store
+// and the search index, and what was done about them
+type ReconcileReport struct {
+	PrimaryCount     int      `json:"primaryCount"`
+	IndexCount       int      `json:"indexCount"`
+	MissingFromIndex []string `json:"missingFromIndex"`
+	OrphanedInIndex  []string `json:"orphanedInIndex"`
+	Stale            []string `json:"stale"`
+	Repaired         int      `json:"repaired"`
+	Errors           []string `json:"errors"`
+}
+
+// DualWriteRepository writes products to the primary store and then to the
+// search index. The primary store is the source of truth: index failures do
+// not fail the write but are tracked so that Reconcile can repair them later.
+type DualWriteRepository struct {
+	WritableCatalogRepository
+	index SearchIndexer
+
+	mu       sync.Mutex
+	failures map[string]IndexFailure
+}
+
+// NewDualWriteRepository wraps a writable primary store and a search index
+func NewDualWriteRepository(primary WritableCatalogRepository, index SearchIndexer) *DualWriteRepository {
+	return &DualWriteRepository{
+		WritableCatalogRepository: primary,
+		index:                     index,
+		failures:                  make(map[string]IndexFailure),
+	}
+}
+
+func (r *DualWriteRepository) CreateProduct(product *model.Product, ctx context.Context) error {
+	if err := r.WritableCatalogRepository.CreateProduct(product, ctx); err != nil {
+		return err
+	}
+
+	r.track(product.ID, "create", r.index.IndexProduct(*product, ctx), ctx)
+	return nil
+}
+
+func (r *DualWriteRepository) UpdateProduct(product *model.Product, ctx context.Context) error {
+	if err := r.WritableCatalogRepository.UpdateProduct(product, ctx); err != nil {
+		return err
+	}
+
+	r.track(product.ID, "update", r.index.IndexProduct(*product, ctx), ctx)
+	return nil
+}
+
+// PatchProduct patches the product in the primary store, then sends only the
+// fields that changed to the index if it supports partial updates
+func (r *DualWriteRepository) PatchProduct(id string, fn func(product *model.Product) error, ctx context.Context) (*model.Product, error) {
+	patcher, ok := r.WritableCatalogRepository.(ProductPatcher)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not support patches")
+	}
+
+	var before model.Product
+	product, err := patcher.PatchProduct(id, func(product *model.Product) error {
+		before = *product
+		return fn(product)
+	}, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partial, ok := r.index.(PartialIndexer)
+	if !ok {
+		r.track(id, "update", r.index.IndexProduct(*product, ctx), ctx)
+		return product, nil
+	}
+
+	if fields := changedFields(before, *product); len(fields) > 0 {
+		r.track(id, "update", partial.UpdateProductFields(*product, fields, ctx), ctx)
+	}
+	return product, nil
+}
+
+// AddReview adds a review in the primary store, then sends the product's new
+// rating to the index
+func (r *DualWriteRepository) AddReview(review *model.ProductReview, ctx context.Context) (*model.Product, error) {
+	reviews, ok := r.WritableCatalogRepository.(ReviewRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not support reviews")
+	}
+
+	product, err := reviews.AddReview(review, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if partial, ok := r.index.(PartialIndexer); ok {
+		r.track(product.ID, "update", partial.UpdateProductFields(*product, []string{"rating"}, ctx), ctx)
+	} else {
+		r.track(product.ID, "update", r.index.IndexProduct(*product, ctx), ctx)
+	}
+	return product, nil
+}
+
+// SetRelations sets the relations of a product in the primary store, then
+// sends them to the index
+func (r *DualWriteRepository) SetRelations(productID string, relations []model.ProductRelation, ctx context.Context) (*model.Product, error) {
+	related, ok := r.WritableCatalogRepository.(RelationRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not store relations")
+	}
+
+	product, err := related.SetRelations(productID, relations, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if partial, ok := r.index.(PartialIndexer); ok {
+		r.track(product.ID, "update", partial.UpdateProductFields(*product, []string{"related"}, ctx), ctx)
+	} else {
+		r.track(product.ID, "update", r.index.IndexProduct(*product, ctx), ctx)
+	}
+	return product, nil
+}
+
+// GetReviews reads the reviews of a product from the primary store
+func (r *DualWriteRepository) GetReviews(productID string, pageNum, pageSize int, ctx context.Context) ([]model.ProductReview, error) {
+	reviews, ok := r.WritableCatalogRepository.(ReviewRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not support reviews")
+	}
+	return reviews.GetReviews(productID, pageNum, pageSize, ctx)
+}
+
+// GetProductBySKU looks up a product by SKU or EAN in the primary store
+func (r *DualWriteRepository) GetProductBySKU(code string, ctx context.Context) (*model.Product, error) {
+	lookup, ok := r.WritableCatalogRepository.(SKULookup)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not support SKU lookups")
+	}
+	return lookup.GetProductBySKU(code, ctx)
+}
+
+// GetProductBySlug looks up a product by its current or a previous slug in
+// the primary store
+func (r *DualWriteRepository) GetProductBySlug(slug string, ctx context.Context) (*model.Product, error) {
+	lookup, ok := r.WritableCatalogRepository.(SlugLookup)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not support slug lookups")
+	}
+	return lookup.GetProductBySlug(slug, ctx)
+}
+
+// GetPriceHistory reads the price history of a product from the primary
+// store
+func (r *DualWriteRepository) GetPriceHistory(productID string, limit int, ctx context.Context) ([]model.PriceChange, error) {
+	history, ok := r.WritableCatalogRepository.(PriceHistoryRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not record price history")
+	}
+	return history.GetPriceHistory(productID, limit, ctx)
+}
+
+// GetPromotions reads promotions from the primary store
+func (r *DualWriteRepository) GetPromotions(ctx context.Context) ([]model.Promotion, error) {
+	promotions, ok := r.WritableCatalogRepository.(PromotionRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not store promotions")
+	}
+	return promotions.GetPromotions(ctx)
+}
+
+// CreatePromotion stores a promotion in the primary store. Promotions are
+// applied as products are read, so the index isn't changed.
+func (r *DualWriteRepository) CreatePromotion(promotion *model.Promotion, ctx context.Context) error {
+	promotions, ok := r.WritableCatalogRepository.(PromotionRepository)
+	if !ok {
+		return fmt.Errorf("the primary store does not store promotions")
+	}
+	return promotions.CreatePromotion(promotion, ctx)
+}
+
+// DeletePromotion removes a promotion from the primary store
+func (r *DualWriteRepository) DeletePromotion(id string, ctx context.Context) error {
+	promotions, ok := r.WritableCatalogRepository.(PromotionRepository)
+	if !ok {
+		return fmt.Errorf("the primary store does not store promotions")
+	}
+	return promotions.DeletePromotion(id, ctx)
+}
+
+func (r *DualWriteRepository) DeleteProduct(id string, ctx context.Context) error {
+	if err := r.WritableCatalogRepository.DeleteProduct(id, ctx); err != nil {
+		return err
+	}
+
+	r.track(id, "delete", r.index.RemoveProduct(id, ctx), ctx)
+	return nil
+}
+
+// GetDeletedProducts reads the deleted products from the primary store
+func (r *DualWriteRepository) GetDeletedProducts(pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
+	deleted, ok := r.WritableCatalogRepository.(DeletedProductRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not keep deleted products")
+	}
+	return deleted.GetDeletedProducts(pageNum, pageSize, ctx)
+}
+
+// RestoreProduct restores a product in the primary store, then adds it back
+// to the index
+func (r *DualWriteRepository) RestoreProduct(id string, ctx context.Context) (*model.Product, error) {
+	deleted, ok := r.WritableCatalogRepository.(DeletedProductRepository)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not keep deleted products")
+	}
+
+	product, err := deleted.RestoreProduct(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.track(id, "restore", r.index.IndexProduct(*product, ctx), ctx)
+	return product, nil
+}
+
+// Ping checks the primary store, if it supports it
+func (r *DualWriteRepository) Ping(ctx context.Context) error {
+	if pinger, ok := r.WritableCatalogRepository.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close closes the primary store, if it supports it. The index is closed
+// with the search repository it belongs to.
+func (r *DualWriteRepository) Close() error {
+	if closer, ok := r.WritableCatalogRepository.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Status reports the state of the primary store, if it supports it
+func (r *DualWriteRepository) Status(ctx context.Context) (map[string]any, error) {
+	if reporter, ok := r.WritableCatalogRepository.(StatusReporter); ok {
+		return reporter.Status(ctx)
+	}
+	return nil, r.Ping(ctx)
+}
+
+// GetChangesAfter reads the change log of the primary store
+func (r *DualWriteRepository) GetChangesAfter(sequence uint64, limit int, ctx context.Context) ([]model.ProductChange, error) {
+	changeLog, ok := r.WritableCatalogRepository.(ChangeLog)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not record changes")
+	}
+	return changeLog.GetChangesAfter(sequence, limit, ctx)
+}
+
+// GetChangesSince reads the change log of the primary store
+func (r *DualWriteRepository) GetChangesSince(since time.Time, limit int, ctx context.Context) ([]model.ProductChange, error) {
+	changeLog, ok := r.WritableCatalogRepository.(ChangeLog)
+	if !ok {
+		return nil, fmt.Errorf("the primary store does not record changes")
+	}
+	return changeLog.GetChangesSince(since, limit, ctx)
+}
+
+// LatestChangeSequence reads the change log of the primary store
+func (r *DualWriteRepository) LatestChangeSequence(ctx context.Context) (uint64, error) {
+	changeLog, ok := r.WritableCatalogRepository.(ChangeLog)
+	if !ok {
+		return 0, fmt.Errorf("the primary store does not record changes")
+	}
+	return changeLog.LatestChangeSequence(ctx)
+}
+
+// ResetCatalog resets the primary store, if it supports it, and forgets any
+// outstanding index failures since the index is expected to be rebuilt
+func (r *DualWriteRepository) ResetCatalog(ctx context.Context) error {
+	resetter, ok := r.WritableCatalogRepository.(CatalogResetter)
+	if !ok {
+		return fmt.Errorf("the primary store does not support resets")
+	}
+
+	if err := resetter.ResetCatalog(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = make(map[string]IndexFailure)
+	return nil
+}
+
+// changedFields lists the fields of a product that differ between versions
+func changedFields(before, after model.Product) []string {
+	fields := []string{}
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if before.Slug != after.Slug {
+		fields = append(fields, "slug")
+	}
+	if before.Description != after.Description {
+		fields = append(fields, "description")
+	}
+	if before.Price != after.Price {
+		fields = append(fields, "price")
+	}
+	if !slices.Equal(tagNames(before.Tags), tagNames(after.Tags)) {
+		fields = append(fields, "tags")
+	}
+	if categoryName(before) != categoryName(after) {
+		fields = append(fields, "category")
+	}
+	if !reflect.DeepEqual(newVariantDocuments(before.Variants), newVariantDocuments(after.Variants)) {
+		fields = append(fields, "variants")
+	}
+	if stockOf(before) != stockOf(after) {
+		fields = append(fields, "stock")
+	}
+	if !reflect.DeepEqual(newImageDocuments(before.Images), newImageDocuments(after.Images)) {
+		fields = append(fields, "images")
+	}
+	if !reflect.DeepEqual(newAttributeDocuments(before.Attributes), newAttributeDocuments(after.Attributes)) {
+		fields = append(fields, "attributes")
+	}
+	if !reflect.DeepEqual(newTranslationDocuments(before.Translations), newTranslationDocuments(after.Translations)) {
+		fields = append(fields, "translations")
+	}
+	if !reflect.DeepEqual(newRelatedDocument(before.Related), newRelatedDocument(after.Related)) {
+		fields = append(fields, "related")
+	}
+	if before.Rating != after.Rating {
+		fields = append(fields, "rating")
+	}
+	if before.Status != after.Status {
+		fields = append(fields, "status")
+	}
+	if !equalCodes(before.SKU, after.SKU) {
+		fields = append(fields, "sku")
+	}
+	if !equalCodes(before.EAN, after.EAN) {
+		fields = append(fields, "ean")
+	}
+	// Every patch moves the update time, so it is only sent with other
+	// changes
+	if len(fields) > 0 && !before.UpdatedAt.Equal(after.UpdatedAt) {
+		fields = append(fields, "updatedAt")
+	}
+	return fields
+}
+
+func tagNames(tags []model.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// equalCodes reports whether two optional SKUs or EANs are the same
+func equalCodes(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func stockOf(product model.Product) int {
+	if product.Stock == nil {
+		return 0
+	}
+	return *product.Stock
+}
+
+func categoryName(product model.Product) string {
+	if product.CategoryName == nil {
+		return ""
+	}
+	return *product.CategoryName
+}
+
+// track records the outcome of an index write, clearing any earlier failure
+// for the product once a write succeeds
+func (r *DualWriteRepository) track(id, operation string, err error, ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		delete(r.failures, id)
+		return
+	}
+
+	slog.ErrorContext(ctx, "Failed to update the search index", "operation", operation, "product", id, "error", err)
+	r.failures[id] = IndexFailure{
+		ProductID: id,
+		Operation: operation,
+		Error:     err.Error(),
+		Time:      time.Now(),
+	}
+}
+
+// Failures returns the outstanding index write failures, oldest first
+func (r *DualWriteRepository) Failures() []IndexFailure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failures := make([]IndexFailure, 0, len(r.failures))
+	for _, failure := range r.failures {
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Time.Before(failures[j].Time)
+	})
+
+	return failures
+}
+
+// Reconcile compares the product IDs in the primary store and the search
+// index, and when repair is set re-indexes missing or stale products and
+// removes orphaned documents
+func (r *DualWriteRepository) Reconcile(repair bool, ctx context.Context) (*ReconcileReport, error) {
+	primaryIDs, err := r.primaryProductIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list primary products: %w", err)
+	}
+
+	indexIDs, err := r.index.IndexedProductIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed products: %w", err)
+	}
+
+	inPrimary := toSet(primaryIDs)
+	inIndex := toSet(indexIDs)
+
+	report := &ReconcileReport{
+		PrimaryCount:     len(inPrimary),
+		IndexCount:       len(inIndex),
+		MissingFromIndex: []string{},
+		OrphanedInIndex:  []string{},
+		Stale:            []string{},
+		Errors:           []string{},
+	}
+
+	for id := range inPrimary {
+		if !inIndex[id] {
+			report.MissingFromIndex = append(report.MissingFromIndex, id)
+		}
+	}
+	for id := range inIndex {
+		if !inPrimary[id] {
+			report.OrphanedInIndex = append(report.OrphanedInIndex, id)
+		}
+	}
+
+	// Products whose last index write failed may be present but out of date
+	for _, failure := range r.Failures() {
+		if inPrimary[failure.ProductID] && inIndex[failure.ProductID] {
+			report.Stale = append(report.Stale, failure.ProductID)
+		}
+	}
+
+	sort.Strings(report.MissingFromIndex)
+	sort.Strings(report.OrphanedInIndex)
+	sort.Strings(report.Stale)
+
+	if !repair {
+		return report, nil
+	}
+
+	for _, id := range append(append([]string{}, report.MissingFromIndex...), report.Stale...) {
+		product, err := r.WritableCatalogRepository.GetProduct(id, ctx)
+		if err == nil {
+			err = r.index.IndexProduct(*product, ctx)
+		}
+		r.recordRepair(report, id, "index", err)
+	}
+
+	for _, id := range report.OrphanedInIndex {
+		r.recordRepair(report, id, "remove", r.index.RemoveProduct(id, ctx))
+	}
+
+	return report, nil
+}
+
+func (r *DualWriteRepository) recordRepair(report *ReconcileReport, id, operation string, err error) {
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to %s %s: %v", operation, id, err))
+		return
+	}
+
+	report.Repaired++
+
+	r.mu.Lock()
+	delete(r.failures, id)
+	r.mu.Unlock()
+}
+
+// primaryProductIDs pages through the primary store to collect every product ID
+func (r *DualWriteRepository) primaryProductIDs(ctx context.Context) ([]string, error) {
+	const pageSize = 100
+
+	ids := []string{}
+	for page := 1; ; page++ {
+		products, err := r.WritableCatalogRepository.GetProducts(ProductFilter{}, "", page, pageSize, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, product := range products {
+			ids = append(ids, product.ID)
+		}
+
+		if len(products) < pageSize {
+			return ids, nil
+		}
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+
+	return set
+}
@@ -0,0 +1,66 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+type degradedKey struct{}
+
+// TrackDegraded returns a context in which search providers record that they
+// answered from a fallback rather than their own index, and a func reporting
+// whether any did. It is passed through the context so that it is recorded
+// wherever a search falls back, including in providers wrapped by others.
+func TrackDegraded(ctx context.Context) (context.Context, func() bool) {
+	degraded := &atomic.Bool{}
+	return context.WithValue(ctx, degradedKey{}, degraded), degraded.Load
+}
+
+// MarkDegraded records that results were answered from a fallback, if the
+// context is tracking it
+func MarkDegraded(ctx context.Context) {
+	if degraded, ok := ctx.Value(degradedKey{}).(*atomic.Bool); ok {
+		degraded.Store(true)
+	}
+}
+
+// SetFallback answers searches from another search provider, such as the
+// database, while the circuit breaker around OpenSearch is open, so that
+// shoppers get basic results during an outage rather than errors
+func (r *OpenSearchRepository) SetFallback(fallback SearchRepository) {
+	r.fallback = fallback
+}
+
+// failover reports whether a search that failed with err should be answered
+// by the fallback instead, counting and marking it degraded if so. That is
+// the case while the breaker is open, including for the search whose failure
+// opened it and for a probe that failed to close it.
+func (r *OpenSearchRepository) failover(err error, ctx context.Context) bool {
+	if r.fallback == nil || err == nil {
+		return false
+	}
+	if !errors.Is(err, ErrBreakerOpen) && r.breaker.State() != breakerOpen {
+		return false
+	}
+
+	observeFailover()
+	MarkDegraded(ctx)
+	return true
+}
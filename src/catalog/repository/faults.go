@@ -0,0 +1,180 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dependencies that faults can be injected into
+const (
+	DependencyDatabase   = "database"
+	DependencyOpenSearch = "opensearch"
+)
+
+// ErrInjectedFault is the error returned by calls failed on purpose
+var ErrInjectedFault = errors.New("injected fault")
+
+// Fault describes how calls to a dependency misbehave, to demonstrate how the
+// service and its clients cope with a slow or failing backend
+type Fault struct {
+	// Latency is added before each call
+	Latency time.Duration
+	// ErrorRate is the fraction of calls, from 0 to 1, that fail
+	ErrorRate float64
+	// Blackhole leaves calls waiting until their deadline, as if the
+	// dependency stopped responding
+	Blackhole bool
+}
+
+func (f Fault) validate() error {
+	if f.Latency < 0 {
+		return fmt.Errorf("latency can't be negative")
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func (f Fault) active() bool {
+	return f.Latency > 0 || f.ErrorRate > 0 || f.Blackhole
+}
+
+// faultInjector holds the faults of each dependency. Faults only apply while
+// enabled reports true, so that they can be turned off with a feature flag
+// without losing them.
+type faultInjector struct {
+	mu      sync.RWMutex
+	faults  map[string]Fault
+	enabled func() bool
+}
+
+var faults = &faultInjector{
+	faults:  map[string]Fault{},
+	enabled: func() bool { return true },
+}
+
+// SetFault makes calls to a dependency misbehave, replacing any fault it
+// already has. A fault with no latency, errors or blackhole clears it.
+func SetFault(dependency string, fault Fault) error {
+	if !slices.Contains(FaultDependencies(), dependency) {
+		return fmt.Errorf("unknown dependency %q, expected one of %s", dependency, strings.Join(FaultDependencies(), ", "))
+	}
+	if err := fault.validate(); err != nil {
+		return err
+	}
+
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+
+	if fault.active() {
+		faults.faults[dependency] = fault
+	} else {
+		delete(faults.faults, dependency)
+	}
+	return nil
+}
+
+// ClearFaults stops injecting faults into every dependency
+func ClearFaults() {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+
+	faults.faults = map[string]Fault{}
+}
+
+// Faults returns the fault of each dependency that has one
+func Faults() map[string]Fault {
+	faults.mu.RLock()
+	defer faults.mu.RUnlock()
+
+	current := make(map[string]Fault, len(faults.faults))
+	for dependency, fault := range faults.faults {
+		current[dependency] = fault
+	}
+	return current
+}
+
+// FaultDependencies lists the dependencies that faults can be injected into
+func FaultDependencies() []string {
+	return []string{DependencyDatabase, DependencyOpenSearch}
+}
+
+// SetFaultsEnabled sets the function consulted before each call to decide
+// whether faults apply
+func SetFaultsEnabled(enabled func() bool) {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+
+	faults.enabled = enabled
+}
+
+// injectFault applies the fault of a dependency to a call about to be made,
+// returning the error the call should fail with
+func injectFault(dependency string, ctx context.Context) error {
+	faults.mu.RLock()
+	fault, ok := faults.faults[dependency]
+	enabled := faults.enabled
+	faults.mu.RUnlock()
+
+	if !ok || !enabled() {
+		return nil
+	}
+
+	if fault.Blackhole {
+		<-ctx.Done()
+		return fmt.Errorf("%s is blackholed: %w", dependency, ctx.Err())
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if fault.ErrorRate > 0 && rand.Float64() < fault.ErrorRate {
+		return fmt.Errorf("%s: %w", dependency, ErrInjectedFault)
+	}
+
+	return nil
+}
+
+// faultTransport injects the OpenSearch fault into requests to the cluster
+type faultTransport struct {
+	base http.RoundTripper
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := injectFault(DependencyOpenSearch, req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
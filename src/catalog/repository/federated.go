@@ -0,0 +1,309 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// rrfRankConstant dampens the influence of top ranks in reciprocal rank
+// fusion, 60 is the value used in the original paper
+const rrfRankConstant = 60
+
+// defaultFederatedBackends is used when RETAIL_CATALOG_SEARCH_FEDERATED_BACKENDS
+// is not set, envconfig cannot express a default containing commas
+var defaultFederatedBackends = []string{"opensearch", "database"}
+
+func init() {
+	RegisterSearch("federated", newFederatedSearchProvider)
+}
+
+func newFederatedSearchProvider(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+	names := config.Search.FederatedBackends
+	if len(names) == 0 {
+		names = defaultFederatedBackends
+	}
+
+	backends := []FederatedBackend{}
+	for _, name := range names {
+		if name == "federated" {
+			return nil, fmt.Errorf("federated search cannot include itself as a backend")
+		}
+
+		repo, err := newSearchProvider(name, config, catalog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize federated backend %s: %w", name, err)
+		}
+
+		backends = append(backends, FederatedBackend{Name: name, Repository: repo})
+	}
+
+	slog.Info("Using federated search", "backends", names)
+	return NewFederatedSearchRepository(backends...), nil
+}
+
+// FederatedBackend is a named search backend queried by FederatedSearchRepository
+type FederatedBackend struct {
+	Name       string
+	Repository SearchRepository
+}
+
+// FederatedSearchRepository queries several search backends concurrently and
+// merges their results with reciprocal rank fusion, which only relies on the
+// rank of each hit so scores from different engines never need comparing
+type FederatedSearchRepository struct {
+	backends []FederatedBackend
+}
+
+// NewFederatedSearchRepository creates a search repository over the given backends
+func NewFederatedSearchRepository(backends ...FederatedBackend) *FederatedSearchRepository {
+	return &FederatedSearchRepository{
+		backends: backends,
+	}
+}
+
+// SearchProducts fetches enough hits from every backend to fill the requested
+// page, fuses the rankings and deduplicates products by ID. Failing backends
+// are skipped unless all of them fail.
+func (r *FederatedSearchRepository) SearchProducts(query SearchQuery, page, size int, ctx context.Context) (SearchResult, error) {
+	window := page * size
+
+	results := make([]SearchResult, len(r.backends))
+	errs := make([]error, len(r.backends))
+
+	var wg sync.WaitGroup
+	for i, backend := range r.backends {
+		wg.Add(1)
+		go func(i int, backend FederatedBackend) {
+			defer wg.Done()
+
+			results[i], errs[i] = backend.Repository.SearchProducts(query, 1, window, ctx)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", backend.Name, errs[i])
+				slog.WarnContext(ctx, "Federated search backend failed", "backend", backend.Name, "error", errs[i])
+			}
+		}(i, backend)
+	}
+	wg.Wait()
+
+	// Each backend counts facets over the products it matched, so rather than
+	// adding them up those of the first backend that counted them are used
+	failed := 0
+	rankings := make([][]model.Product, 0, len(results))
+	var facets map[string]map[string]int
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			continue
+		}
+		rankings = append(rankings, results[i].Products)
+		if facets == nil {
+			facets = results[i].Facets
+		}
+	}
+	if failed == len(r.backends) {
+		return SearchResult{}, fmt.Errorf("all federated search backends failed: %w", errors.Join(errs...))
+	}
+
+	fused := fuseRankings(rankings)
+	sortSearchResults(fused, query.Order)
+
+	from := (page - 1) * size
+	if from >= len(fused) {
+		return SearchResult{Products: []model.Product{}, Facets: facets}, nil
+	}
+
+	return SearchResult{Products: fused[from:min(from+size, len(fused))], Facets: facets}, nil
+}
+
+// fuseRankings merges ranked result lists, scoring each product by the sum of
+// 1/(k+rank) over the lists it appears in
+func fuseRankings(results [][]model.Product) []model.Product {
+	type fusedHit struct {
+		product model.Product
+		score   float64
+		first   int
+	}
+
+	hits := map[string]*fusedHit{}
+	order := 0
+	for _, products := range results {
+		for rank, product := range products {
+			hit, ok := hits[product.ID]
+			if !ok {
+				hit = &fusedHit{product: product, first: order}
+				hits[product.ID] = hit
+				order++
+			}
+			hit.score += 1.0 / float64(rrfRankConstant+rank+1)
+		}
+	}
+
+	ranked := make([]*fusedHit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, hit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].first < ranked[j].first
+	})
+
+	products := make([]model.Product, len(ranked))
+	for i, hit := range ranked {
+		products[i] = hit.product
+	}
+
+	return products
+}
+
+// Reindex rebuilds every backend
+func (r *FederatedSearchRepository) Reindex(ctx context.Context) error {
+	errs := []error{}
+	for _, backend := range r.backends {
+		if err := backend.Repository.Reindex(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Ping checks every backend that supports it
+func (r *FederatedSearchRepository) Ping(ctx context.Context) error {
+	errs := []error{}
+	for _, backend := range r.backends {
+		if pinger, ok := backend.Repository.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes every backend that supports it
+func (r *FederatedSearchRepository) Close() error {
+	errs := []error{}
+	for _, backend := range r.backends {
+		if closer, ok := backend.Repository.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Status reports the state of every backend that supports it, by name
+func (r *FederatedSearchRepository) Status(ctx context.Context) (map[string]any, error) {
+	details := map[string]any{}
+	errs := []error{}
+	for _, backend := range r.backends {
+		if reporter, ok := backend.Repository.(StatusReporter); ok {
+			status, err := reporter.Status(ctx)
+			details[backend.Name] = status
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+			}
+		}
+	}
+
+	return details, errors.Join(errs...)
+}
+
+// SetBoosts changes the boosts of every backend that supports tuning
+func (r *FederatedSearchRepository) SetBoosts(boosts map[string]float64) {
+	for _, backend := range r.backends {
+		if tuner, ok := backend.Repository.(SearchTuner); ok {
+			tuner.SetBoosts(boosts)
+		}
+	}
+}
+
+// indexers returns the backends that maintain their own index
+func (r *FederatedSearchRepository) indexers() []SearchIndexer {
+	indexers := []SearchIndexer{}
+	for _, backend := range r.backends {
+		if indexer, ok := backend.Repository.(SearchIndexer); ok {
+			indexers = append(indexers, indexer)
+		}
+	}
+
+	return indexers
+}
+
+// IndexProduct indexes the product in every backend that maintains an index
+func (r *FederatedSearchRepository) IndexProduct(product model.Product, ctx context.Context) error {
+	errs := []error{}
+	for _, indexer := range r.indexers() {
+		errs = append(errs, indexer.IndexProduct(product, ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RemoveProduct removes the product from every backend that maintains an index
+func (r *FederatedSearchRepository) RemoveProduct(id string, ctx context.Context) error {
+	errs := []error{}
+	for _, indexer := range r.indexers() {
+		errs = append(errs, indexer.RemoveProduct(id, ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// IndexedProductIDs returns the IDs present in every indexing backend, so a
+// product missing from any one of them is reported as missing
+func (r *FederatedSearchRepository) IndexedProductIDs(ctx context.Context) ([]string, error) {
+	indexers := r.indexers()
+	if len(indexers) == 0 {
+		return nil, fmt.Errorf("no federated search backend maintains an index")
+	}
+
+	counts := map[string]int{}
+	for _, indexer := range indexers {
+		ids, err := indexer.IndexedProductIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for id := range toSet(ids) {
+			counts[id]++
+		}
+	}
+
+	ids := []string{}
+	for id, count := range counts {
+		if count == len(indexers) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
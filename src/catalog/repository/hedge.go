@@ -0,0 +1,135 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hedgeBurst is the most hedges that can be sent in a row after a quiet
+// period, however large the budget has grown
+const hedgeBurst = 10
+
+// Results of searches that ran past the hedge delay
+const (
+	hedgeSent    = "sent"
+	hedgeSkipped = "skipped"
+	hedgeWon     = "won"
+)
+
+// hedger sends a second copy of a call that is taking longer than delay and
+// takes whichever succeeds first, trading extra load for lower tail latency.
+// Each call earns budget tokens and each hedge spends one, so that no more
+// than that fraction of calls are hedged and a slow cluster isn't sent twice
+// the load.
+type hedger struct {
+	delay  time.Duration
+	budget float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// newHedger returns a hedger with a full burst of tokens, or nil, which never
+// hedges, when delay isn't positive
+func newHedger(delay time.Duration, budget float64) *hedger {
+	if delay <= 0 {
+		return nil
+	}
+	return &hedger{delay: delay, budget: budget, tokens: hedgeBurst}
+}
+
+// earn adds the budget of a call, up to the burst
+func (h *hedger) earn() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tokens = min(h.tokens+h.budget, hedgeBurst)
+}
+
+// spend takes the token for a hedge, if one is available
+func (h *hedger) spend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+type hedgeResult[T any] struct {
+	value T
+	err   error
+	hedge bool
+}
+
+// hedge runs call, and a copy of it if it hasn't returned after the delay
+// and the budget allows, returning the first success. A call failing before
+// the delay isn't hedged, since hedging is meant for slow calls rather than
+// as a retry. The call still running is cancelled once one succeeds.
+func hedge[T any](h *hedger, call func(ctx context.Context) (T, error), ctx context.Context) (T, error) {
+	if h == nil {
+		return call(ctx)
+	}
+	h.earn()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so that the call that loses doesn't block once abandoned
+	results := make(chan hedgeResult[T], 2)
+	run := func(hedge bool) {
+		value, err := call(ctx)
+		results <- hedgeResult[T]{value: value, err: err, hedge: hedge}
+	}
+
+	go run(false)
+	pending := 1
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	var err error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !h.spend() {
+				observeHedge(hedgeSkipped)
+				continue
+			}
+			observeHedge(hedgeSent)
+			go run(true)
+			pending++
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				if result.hedge {
+					observeHedge(hedgeWon)
+				}
+				return result.value, nil
+			}
+			err = result.err
+		}
+	}
+
+	var zero T
+	return zero, err
+}
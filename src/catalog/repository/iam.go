@@ -0,0 +1,169 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds/rdsutils"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// RDS IAM authentication tokens are valid for 15 minutes, refresh them
+	// with enough margin that a token never expires mid-handshake
+	iamTokenLifetime      = 15 * time.Minute
+	iamTokenRefreshMargin = 5 * time.Minute
+
+	rdsTLSConfigName = "rds"
+)
+
+// iamTokenProvider generates RDS IAM authentication tokens for one endpoint,
+// caching each token until it is close to expiring
+type iamTokenProvider struct {
+	endpoint string
+	region   string
+	user     string
+	creds    *credentials.Credentials
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (p *iamTokenProvider) Token() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Now().Before(p.expires.Add(-iamTokenRefreshMargin)) {
+		return p.token, nil
+	}
+
+	token, err := rdsutils.BuildAuthToken(p.endpoint, p.region, p.user, p.creds)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate IAM auth token: %w", err)
+	}
+
+	p.token = token
+	p.expires = time.Now().Add(iamTokenLifetime)
+
+	return token, nil
+}
+
+// newIAMAuthDialector creates a MySQL dialector that authenticates every new
+// connection with a fresh (or cached) IAM token instead of a static password
+func newIAMAuthDialector(config config.DatabaseConfiguration, endpoint string) (gorm.Dialector, error) {
+	awsConfig := aws.NewConfig()
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	region := aws.StringValue(sess.Config.Region)
+	if region == "" {
+		return nil, fmt.Errorf("IAM database authentication requires a region, set RETAIL_CATALOG_PERSISTENCE_REGION or AWS_REGION")
+	}
+
+	provider := &iamTokenProvider{
+		endpoint: endpoint,
+		region:   region,
+		user:     config.User,
+		creds:    sess.Config.Credentials,
+	}
+
+	tlsConfig := "true"
+	if config.CABundle != "" {
+		if err := registerRDSTLSConfig(config.CABundle); err != nil {
+			return nil, err
+		}
+		tlsConfig = rdsTLSConfigName
+	}
+
+	slog.Info("Using IAM database authentication", "user", config.User, "region", region)
+
+	cfg := mysqldriver.NewConfig()
+	cfg.User = config.User
+	cfg.Net = "tcp"
+	cfg.Addr = endpoint
+	cfg.DBName = config.Name
+	cfg.Timeout = time.Duration(config.ConnectTimeout) * time.Second
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	cfg.TLSConfig = tlsConfig
+	// IAM tokens are sent with the cleartext plugin, which is why TLS is required
+	cfg.AllowCleartextPasswords = true
+
+	err = cfg.Apply(mysqldriver.BeforeConnect(func(ctx context.Context, cfg *mysqldriver.Config) error {
+		token, err := provider.Token()
+		if err != nil {
+			return err
+		}
+
+		cfg.Passwd = token
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql connector: %w", err)
+	}
+
+	return mysql.New(mysql.Config{
+		Conn: sql.OpenDB(connector),
+	}), nil
+}
+
+// registerRDSTLSConfig trusts the certificates in the given PEM bundle, which
+// is needed for RDS endpoints because their CA is not in the system store
+func registerRDSTLSConfig(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return mysqldriver.RegisterTLSConfig(rdsTLSConfigName, &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"gorm.io/gorm"
+)
+
+// preloadImages loads the images of the products a query returns, in the
+// order they are shown
+func preloadImages(query *gorm.DB) *gorm.DB {
+	return query.Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("product_images.position asc") })
+}
+
+// saveImages replaces the images of a product, unless they are nil, and
+// loads the stored images into the product
+func saveImages(tx *gorm.DB, product *model.Product) error {
+	if product.Images != nil {
+		if err := deleteImages(tx, product.ID); err != nil {
+			return err
+		}
+
+		for i := range product.Images {
+			product.Images[i].ProductID = product.ID
+			product.Images[i].Position = i
+		}
+		if len(product.Images) > 0 {
+			if err := tx.Create(&product.Images).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	images := []model.ProductImage{}
+	if err := tx.Where("product_id = ?", product.ID).Order("position asc").Find(&images).Error; err != nil {
+		return err
+	}
+	product.Images = images
+	return nil
+}
+
+// deleteImages removes the images of a product
+func deleteImages(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.ProductImage{}).Error
+}
+
+// seedImages returns the images of a bundled product
+func seedImages(product ProductData) []model.ProductImage {
+	images := make([]model.ProductImage, len(product.Images))
+	for i, image := range product.Images {
+		images[i] = image.ToImage(product.ID, i)
+	}
+	return images
+}
@@ -0,0 +1,370 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// metrics are created by RegisterMetrics rather than when the package is
+// loaded, since their buckets are configurable
+type metrics struct {
+	operationDuration *prometheus.HistogramVec
+	searches          *prometheus.CounterVec
+	zeroResults       *prometheus.CounterVec
+	searchHits        *prometheus.HistogramVec
+	searchTook        *prometheus.HistogramVec
+	hedges            *prometheus.CounterVec
+	coalesced         prometheus.Counter
+	breakerState      *prometheus.GaugeVec
+	failovers         prometheus.Counter
+	bulkheadInFlight  *prometheus.GaugeVec
+	bulkheadRejected  *prometheus.CounterVec
+	indexBatches      *prometheus.CounterVec
+	indexDocuments    *prometheus.CounterVec
+	indexBusy         *prometheus.CounterVec
+	bulkRejected      prometheus.Counter
+	throttleBatch     prometheus.Gauge
+	throttleDelay     prometheus.Gauge
+}
+
+var registeredMetrics atomic.Pointer[metrics]
+
+// operationStatsD is set by RegisterStatsDMetrics when the metrics are sent
+// to a DogStatsD agent instead
+var operationStatsD atomic.Pointer[statsd.Client]
+
+// searchHitBuckets bound the number of products matching a search, which
+// varies far less than durations do
+var searchHitBuckets = []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// RegisterMetrics registers the histogram of the time taken by database
+// statements and OpenSearch operations, and the metrics describing the
+// quality of OpenSearch keyword searches, using the Prometheus default
+// buckets for durations if none are given. Operations before it is called
+// aren't recorded.
+func RegisterMetrics(buckets []float64, registerer prometheus.Registerer) error {
+	searchLabels := []string{"fuzzy", "filtered"}
+	m := &metrics{
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "catalog_repository_operation_duration_seconds",
+			Help:    "Time taken by database statements and OpenSearch operations",
+			Buckets: buckets,
+		}, []string{"repository", "operation", "outcome"}),
+		searches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_search_requests_total",
+			Help: "Keyword searches answered by OpenSearch",
+		}, searchLabels),
+		zeroResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_search_zero_results_total",
+			Help: "Keyword searches that matched no products",
+		}, searchLabels),
+		searchHits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "catalog_search_hits",
+			Help:    "Number of products matching keyword searches",
+			Buckets: searchHitBuckets,
+		}, searchLabels),
+		searchTook: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "catalog_search_took_seconds",
+			Help:    "Time OpenSearch reports spending on keyword searches",
+			Buckets: buckets,
+		}, searchLabels),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_search_hedges_total",
+			Help: "Searches slow enough to hedge, by whether a hedge was sent, skipped for lack of budget, or answered first",
+		}, []string{"result"}),
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "catalog_search_coalesced_total",
+			Help: "Searches answered by an identical search already in flight rather than by a query of their own",
+		}),
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "catalog_breaker_state",
+			Help: "Whether the circuit breaker around each dependency is in each state, 1 for the current one",
+		}, []string{"dependency", "state"}),
+		failovers: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "catalog_search_failovers_total",
+			Help: "Searches answered by the database because the circuit breaker around OpenSearch was open",
+		}),
+		bulkheadInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "catalog_bulkhead_in_flight",
+			Help: "Calls holding a slot in the bulkhead of each dependency",
+		}, []string{"dependency"}),
+		bulkheadRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_bulkhead_rejected_total",
+			Help: "Calls turned away because the bulkhead of their dependency stayed full",
+		}, []string{"dependency"}),
+		indexBatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_index_worker_batches_total",
+			Help: "Batches of products sent to the search index by each reindex worker, by whether the request succeeded",
+		}, []string{"worker", "outcome"}),
+		indexDocuments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_index_worker_documents_total",
+			Help: "Products sent to the search index by each reindex worker, by whether they were indexed or rejected",
+		}, []string{"worker", "result"}),
+		indexBusy: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "catalog_index_worker_busy_seconds_total",
+			Help: "Time each reindex worker spent sending batches to the search index",
+		}, []string{"worker"}),
+		bulkRejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "catalog_index_rejected_documents_total",
+			Help: "Documents OpenSearch rejected because its write queues were full, each of which is retried more slowly",
+		}),
+		throttleBatch: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "catalog_index_throttle_batch_size",
+			Help: "Most documents sent to the search index in a bulk request while writes are being rejected, 0 for no limit",
+		}),
+		throttleDelay: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "catalog_index_throttle_delay_seconds",
+			Help: "Pause before each write to the search index while writes are being rejected",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.operationDuration, m.searches, m.zeroResults, m.searchHits, m.searchTook, m.hedges, m.coalesced, m.breakerState, m.failovers, m.bulkheadInFlight, m.bulkheadRejected, m.indexBatches, m.indexDocuments, m.indexBusy, m.bulkRejected, m.throttleBatch, m.throttleDelay} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	registeredMetrics.Store(m)
+	return nil
+}
+
+// RegisterStatsDMetrics sends the duration of database statements and
+// OpenSearch operations to a DogStatsD agent as the
+// catalog.repository.operation.duration distribution, in seconds, along with
+// the search quality metrics, until it is called with nil
+func RegisterStatsDMetrics(client *statsd.Client) {
+	operationStatsD.Store(client)
+}
+
+// observeOperation records the duration of an operation that started at the
+// given time, labelled by whether it failed
+func observeOperation(repository, operation string, started time.Time, failed bool, ctx context.Context) {
+	outcome := "success"
+	if failed {
+		outcome = "error"
+	}
+	seconds := time.Since(started).Seconds()
+
+	if m := registeredMetrics.Load(); m != nil {
+		observe(m.operationDuration.WithLabelValues(repository, operation, outcome), seconds, ctx)
+	}
+
+	// Errors sending to the agent are dropped, as the client does for
+	// metrics it can't buffer
+	if client := operationStatsD.Load(); client != nil {
+		client.Distribution("catalog.repository.operation.duration", seconds, []string{
+			"repository:" + repository,
+			"operation:" + operation,
+			"outcome:" + outcome,
+		}, 1)
+	}
+}
+
+// observeSearch records how many products a keyword search matched in total,
+// across every page, and how long OpenSearch took to run it, labelled by
+// whether the query matched keywords approximately and filtered the results
+func observeSearch(query map[string]interface{}, totalHits int, took time.Duration, ctx context.Context) {
+	fuzzy, filtered := searchFeatures(query)
+	labels := []string{strconv.FormatBool(fuzzy), strconv.FormatBool(filtered)}
+
+	if m := registeredMetrics.Load(); m != nil {
+		m.searches.WithLabelValues(labels...).Inc()
+		if totalHits == 0 {
+			m.zeroResults.WithLabelValues(labels...).Inc()
+		}
+		m.searchHits.WithLabelValues(labels...).Observe(float64(totalHits))
+		observe(m.searchTook.WithLabelValues(labels...), took.Seconds(), ctx)
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		tags := []string{"fuzzy:" + labels[0], "filtered:" + labels[1]}
+		client.Incr("catalog.search.requests", tags, 1)
+		if totalHits == 0 {
+			client.Incr("catalog.search.zero_results", tags, 1)
+		}
+		client.Distribution("catalog.search.hits", float64(totalHits), tags, 1)
+		client.Distribution("catalog.search.took", took.Seconds(), tags, 1)
+	}
+}
+
+// observeHedge counts a search that ran past the hedge delay, with the
+// result sent, skipped or won
+func observeHedge(result string) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.hedges.WithLabelValues(result).Inc()
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Incr("catalog.search.hedges", []string{"result:" + result}, 1)
+	}
+}
+
+// observeCoalesced counts a search answered by an identical one that was
+// already in flight
+func observeCoalesced() {
+	if m := registeredMetrics.Load(); m != nil {
+		m.coalesced.Inc()
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Incr("catalog.search.coalesced", nil, 1)
+	}
+}
+
+// observeBreakerState records the state a circuit breaker moved to
+func observeBreakerState(dependency, state string) {
+	if m := registeredMetrics.Load(); m != nil {
+		for _, s := range []string{breakerClosed, breakerOpen, breakerHalfOpen} {
+			value := 0.0
+			if s == state {
+				value = 1
+			}
+			m.breakerState.WithLabelValues(dependency, s).Set(value)
+		}
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Incr("catalog.breaker.transitions", []string{"dependency:" + dependency, "state:" + state}, 1)
+	}
+}
+
+// observeFailover counts a search answered by the fallback provider
+func observeFailover() {
+	if m := registeredMetrics.Load(); m != nil {
+		m.failovers.Inc()
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Incr("catalog.search.failovers", nil, 1)
+	}
+}
+
+// observeBulkheadInFlight records the calls holding a slot in the bulkhead of
+// a dependency
+func observeBulkheadInFlight(dependency string, inFlight int) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.bulkheadInFlight.WithLabelValues(dependency).Set(float64(inFlight))
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Gauge("catalog.bulkhead.in_flight", float64(inFlight), []string{"dependency:" + dependency}, 1)
+	}
+}
+
+// observeBulkheadRejected counts a call turned away by a full bulkhead
+func observeBulkheadRejected(dependency string) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.bulkheadRejected.WithLabelValues(dependency).Inc()
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Incr("catalog.bulkhead.rejected", []string{"dependency:" + dependency}, 1)
+	}
+}
+
+// observeIndexBatch records a batch of products sent to the search index by
+// a reindex worker, with how many were rejected and how long it took
+func observeIndexBatch(worker string, documents, failed int, err error, took time.Duration) {
+	indexed, outcome := documents-failed, "success"
+	if err != nil {
+		indexed, failed, outcome = 0, 0, "error"
+	}
+
+	if m := registeredMetrics.Load(); m != nil {
+		m.indexBatches.WithLabelValues(worker, outcome).Inc()
+		m.indexDocuments.WithLabelValues(worker, "indexed").Add(float64(indexed))
+		m.indexDocuments.WithLabelValues(worker, "failed").Add(float64(failed))
+		m.indexBusy.WithLabelValues(worker).Add(took.Seconds())
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		tags := []string{"worker:" + worker}
+		client.Incr("catalog.index.worker.batches", append(tags, "outcome:"+outcome), 1)
+		client.Count("catalog.index.worker.documents", int64(indexed), append(tags, "result:indexed"), 1)
+		client.Count("catalog.index.worker.documents", int64(failed), append(tags, "result:failed"), 1)
+		client.Distribution("catalog.index.worker.batch.duration", took.Seconds(), tags, 1)
+	}
+}
+
+// observeBulkRejected counts documents OpenSearch rejected because its write
+// queues were full
+func observeBulkRejected(documents int) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.bulkRejected.Add(float64(documents))
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Count("catalog.index.rejected", int64(documents), nil, 1)
+	}
+}
+
+// observeBulkThrottle records how far writes to the search index are being
+// slowed down
+func observeBulkThrottle(batch int, delay time.Duration) {
+	if m := registeredMetrics.Load(); m != nil {
+		m.throttleBatch.Set(float64(batch))
+		m.throttleDelay.Set(delay.Seconds())
+	}
+
+	if client := operationStatsD.Load(); client != nil {
+		client.Gauge("catalog.index.throttle.batch_size", float64(batch), nil, 1)
+		client.Gauge("catalog.index.throttle.delay", delay.Seconds(), nil, 1)
+	}
+}
+
+// observe records a duration with the ID of the trace it was part of as an
+// exemplar, so that a latency spike on a dashboard leads to the traces
+// behind it. Traces that aren't sampled can't be found, so aren't linked.
+func observe(observer prometheus.Observer, seconds float64, ctx context.Context) {
+	span := trace.SpanContextFromContext(ctx)
+	if exemplars, ok := observer.(prometheus.ExemplarObserver); ok && span.IsSampled() {
+		exemplars.ObserveWithExemplar(seconds, prometheus.Labels{"trace_id": span.TraceID().String()})
+		return
+	}
+
+	observer.Observe(seconds)
+}
+
+// searchFeatures reports whether a search query allows fuzzy matches of the
+// keyword and whether it filters the matching documents
+func searchFeatures(query map[string]interface{}) (fuzzy, filtered bool) {
+	clause, _ := query["query"].(map[string]interface{})
+	clause = withoutActiveFilter(clause)
+
+	if match, ok := clause["multi_match"].(map[string]interface{}); ok {
+		fuzziness, ok := match["fuzziness"]
+		fuzzy = ok && fuzziness != "0" && fuzziness != 0
+	}
+
+	if boolean, ok := clause["bool"].(map[string]interface{}); ok {
+		filtered = boolean["filter"] != nil
+		if must, ok := boolean["must"].(map[string]interface{}); ok {
+			fuzzy, _ = searchFeatures(map[string]interface{}{"query": must})
+		}
+	}
+
+	filtered = filtered || query["post_filter"] != nil
+	return fuzzy, filtered
+}
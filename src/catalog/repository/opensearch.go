@@ -22,8 +22,13 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws-containers/retail-store-sample-app/catalog/config"
 	"github.com/aws-containers/retail-store-sample-app/catalog/model"
@@ -31,87 +36,634 @@ import (
 	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
 )
 
+// initializeTimeout bounds loading the seed data into a search index at
+// startup, so that an unresponsive backend fails startup rather than hanging it
+const initializeTimeout = time.Minute
+
+func init() {
+	RegisterSearch("opensearch", newOpenSearchProvider)
+}
+
+func newOpenSearchProvider(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+	slog.Info("OpenSearch is enabled, initializing", "index", config.OpenSearch.IndexName)
+
+	repo, err := NewOpenSearchRepository(config.OpenSearch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenSearch: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initializeTimeout)
+	defer cancel()
+
+	if err := repo.InitializeData(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenSearch data: %w", err)
+	}
+
+	// The catalog's own basic search answers while OpenSearch is down
+	if searcher, ok := catalog.(SearchRepository); ok && config.OpenSearch.Failover {
+		repo.SetFallback(searcher)
+	}
+
+	slog.Info("OpenSearch initialized successfully", "index", config.OpenSearch.IndexName)
+	return repo, nil
+}
+
 // SearchRepository interface for search operations
 type SearchRepository interface {
-	SearchProducts(keyword string, page, size int, ctx context.Context) ([]model.Product, error)
-	Reindex() error
+	SearchProducts(query SearchQuery, page, size int, ctx context.Context) (SearchResult, error)
+	Reindex(ctx context.Context) error
+}
+
+// SearchIndexer interface for search repositories that can be updated one
+// product at a time
+type SearchIndexer interface {
+	IndexProduct(product model.Product, ctx context.Context) error
+	RemoveProduct(id string, ctx context.Context) error
+	IndexedProductIDs(ctx context.Context) ([]string, error)
+}
+
+// PartialIndexer interface for search indexes that can update some fields of
+// a product document without replacing it. Fields are named as in the
+// product's JSON representation.
+type PartialIndexer interface {
+	UpdateProductFields(product model.Product, fields []string, ctx context.Context) error
+}
+
+// BulkIndexer interface for search repositories whose index can be rebuilt
+// from products read out of the catalog a batch at a time
+type BulkIndexer interface {
+	// ResetIndex drops the index and recreates it empty
+	ResetIndex(ctx context.Context) error
+	// IndexProducts adds or replaces a batch of products, returning how many
+	// of them could not be indexed
+	IndexProducts(products []model.Product, ctx context.Context) (int, error)
+}
+
+// CursorSearcher interface for search repositories that can continue a search
+// from the sort values of the last hit rather than an offset, which stays
+// consistent while the index changes and doesn't slow down on deep pages
+type CursorSearcher interface {
+	// SearchProductsAfter returns the hits following those with the after sort
+	// values, or the first hits if after is empty, and the sort values of the
+	// last hit returned
+	SearchProductsAfter(query SearchQuery, after []interface{}, size int, ctx context.Context) (SearchResult, []interface{}, error)
+}
+
+// SearchStreamer interface for search repositories that can pass every hit
+// for a keyword to a callback a batch at a time, from a consistent view of
+// the index, so that large result sets can be exported without holding them
+// in memory
+type SearchStreamer interface {
+	StreamSearch(keyword string, batchSize int, ctx context.Context, fn func([]model.Product) error) error
+}
+
+// SearchExplainer interface for search repositories that can show the query
+// they run for a keyword and how they score its hits, for tuning relevance
+type SearchExplainer interface {
+	ExplainSearch(keyword string, size int, ctx context.Context) (*model.SearchExplanation, error)
+}
+
+// SearchTuner interface for search repositories whose relevance tuning can
+// be changed while they serve queries, such as when configuration is reloaded
+type SearchTuner interface {
+	SetBoosts(boosts map[string]float64)
 }
 
 // OpenSearchRepository implements SearchRepository
 type OpenSearchRepository struct {
 	client    *opensearch.Client
+	transport *http.Transport
 	indexName string
+	synonyms  []string
+	log       *slog.Logger
+	// hedger hedges slow searches, if configured
+	hedger *hedger
+	// coalescer shares the result of identical searches in flight at the
+	// same time, if configured
+	coalescer *coalescer
+	// breaker stops searches while OpenSearch keeps failing, if configured,
+	// and fallback answers them meanwhile, if set
+	breaker  *breaker
+	fallback SearchRepository
+	// throttle slows writes while OpenSearch rejects them
+	throttle *bulkThrottle
+
+	mu sync.RWMutex
+	// fields searched for keywords, with their boosts
+	fields []string
+
+	// lastSync is when documents were last written to the index, in Unix
+	// nanoseconds
+	lastSync atomic.Int64
 }
 
 // ProductDocument represents the product structure stored in OpenSearch
 type ProductDocument struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Price       int      `json:"price"`
-	Tags        []string `json:"tags"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       model.Money `json:"price"`
+	Tags        []string    `json:"tags"`
+	Stock       int         `json:"stock"`
+	// Rating is kept up to date as the product is reviewed
+	Rating model.Rating `json:"rating"`
+	// Status is missing from documents indexed before products had one,
+	// which are active
+	Status string `json:"status,omitempty"`
+	// SKU and EAN are matched exactly by searches for them, and SKU also by
+	// parts of at least three characters
+	SKU string `json:"sku,omitempty"`
+	EAN string `json:"ean,omitempty"`
+	// Slug is missing from documents indexed before products had one
+	Slug string `json:"slug,omitempty"`
+	// Category is the name of the product's category, and CategoryPath its
+	// full path so that a category's descendants can be matched by prefix
+	Category     string `json:"category,omitempty"`
+	CategoryPath string `json:"categoryPath,omitempty"`
+	// Variants are nested documents, so that a search for several
+	// attribute values only matches products with a variant that has them all
+	Variants []VariantDocument `json:"variants,omitempty"`
+	// Images are stored with the product but not indexed
+	Images []ImageDocument `json:"images,omitempty"`
+	// Attributes are nested documents, so that a filter matches the name
+	// and value of the same attribute
+	Attributes []AttributeDocument `json:"attributes,omitempty"`
+	// I18n holds the product's translations by locale, each analyzed for
+	// its language
+	I18n map[string]TranslationDocument `json:"i18n,omitempty"`
+	// Related holds the IDs of the products the product is related to by
+	// type, so that a search can find those bought together with another
+	Related map[string][]string `json:"related,omitempty"`
+	// CreatedAt and UpdatedAt are missing from documents indexed before
+	// products had them
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+}
+
+// newRelatedDocument converts the relations of a product to the related
+// product IDs by type
+func newRelatedDocument(relations []model.ProductRelation) map[string][]string {
+	if len(relations) == 0 {
+		return nil
+	}
+
+	related := make(map[string][]string)
+	for _, relation := range relations {
+		related[relation.Type] = append(related[relation.Type], relation.RelatedID)
+	}
+	return related
+}
+
+// toRelations converts related product IDs by type back to the relations of
+// a product, by type and then related product
+func toRelations(productID string, related map[string][]string) []model.ProductRelation {
+	if len(related) == 0 {
+		return nil
+	}
+
+	relations := []model.ProductRelation{}
+	for relationType, ids := range related {
+		for _, id := range ids {
+			relations = append(relations, model.ProductRelation{ProductID: productID, Type: relationType, RelatedID: id})
+		}
+	}
+	model.SortRelations(relations)
+	return relations
+}
+
+// TranslationDocument represents a product translation stored in OpenSearch
+type TranslationDocument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// newTranslationDocuments converts translations to their OpenSearch
+// representation
+func newTranslationDocuments(translations []model.ProductTranslation) map[string]TranslationDocument {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	docs := make(map[string]TranslationDocument, len(translations))
+	for _, translation := range translations {
+		docs[translation.Locale] = TranslationDocument{Name: translation.Name, Description: translation.Description}
+	}
+	return docs
+}
+
+// toTranslations converts translation documents back to the translations of
+// a product, in locale order
+func toTranslations(productID string, docs map[string]TranslationDocument) []model.ProductTranslation {
+	if docs == nil {
+		return nil
+	}
+
+	requests := make(map[string]model.TranslationRequest, len(docs))
+	for locale, doc := range docs {
+		requests[locale] = model.TranslationRequest{Name: doc.Name, Description: doc.Description}
+	}
+	return model.ToTranslations(productID, requests)
+}
+
+// localizedFields adds the fields of the translation into the locale to
+// the fields keywords are matched against, boosted like the product's own
+func localizedFields(fields []string, locale string) []string {
+	localized := append([]string{}, fields...)
+	for _, field := range fields {
+		name, boost, _ := strings.Cut(field, "^")
+		if name != "name" && name != "description" {
+			continue
+		}
+		if boost != "" {
+			boost = "^" + boost
+		}
+		localized = append(localized, "i18n."+locale+"."+name+boost)
+	}
+	return localized
+}
+
+// AttributeDocument represents a product attribute stored in OpenSearch.
+// Number is only set for numbers, so that they can be filtered by range.
+type AttributeDocument struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Value  string   `json:"value"`
+	Number *float64 `json:"number,omitempty"`
+}
+
+// newAttributeDocuments converts attributes to their OpenSearch
+// representation
+func newAttributeDocuments(attributes []model.ProductAttribute) []AttributeDocument {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	docs := make([]AttributeDocument, len(attributes))
+	for i, attribute := range attributes {
+		docs[i] = AttributeDocument{Name: attribute.Name, Type: attribute.Type, Value: attribute.Value, Number: attribute.Number}
+	}
+	return docs
+}
+
+// toAttributes converts attribute documents back to the attributes of a
+// product
+func toAttributes(productID string, docs []AttributeDocument) []model.ProductAttribute {
+	if docs == nil {
+		return nil
+	}
+
+	attributes := make([]model.ProductAttribute, len(docs))
+	for i, doc := range docs {
+		attributes[i] = model.ProductAttribute{ProductID: productID, Name: doc.Name, Type: doc.Type, Value: doc.Value, Number: doc.Number}
+	}
+	return attributes
+}
+
+// ImageDocument represents a product image stored in OpenSearch
+type ImageDocument struct {
+	Key string `json:"key,omitempty"`
+	URL string `json:"url,omitempty"`
+	Alt string `json:"alt"`
+}
+
+// newImageDocuments converts images to their OpenSearch representation
+func newImageDocuments(images []model.ProductImage) []ImageDocument {
+	if len(images) == 0 {
+		return nil
+	}
+
+	docs := make([]ImageDocument, len(images))
+	for i, image := range images {
+		docs[i] = ImageDocument{Key: image.Key, URL: image.URL, Alt: image.Alt}
+	}
+	return docs
+}
+
+// toImages converts image documents back to images
+func toImages(productID string, docs []ImageDocument) []model.ProductImage {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	images := make([]model.ProductImage, len(docs))
+	for i, doc := range docs {
+		images[i] = model.ImageRequest{Key: doc.Key, URL: doc.URL, Alt: doc.Alt}.ToImage(productID, i)
+	}
+	return images
+}
+
+// VariantDocument represents a product variant stored in OpenSearch, with its
+// attributes as fields named after them
+type VariantDocument struct {
+	SKU        string            `json:"sku"`
+	Price      model.Money       `json:"price"`
+	Stock      int               `json:"stock"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// newVariantDocuments converts variants to their OpenSearch representation
+func newVariantDocuments(variants []model.Variant) []VariantDocument {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	docs := make([]VariantDocument, len(variants))
+	for i, variant := range variants {
+		attributes := make(map[string]string, len(variant.Attributes))
+		for _, attribute := range variant.Attributes {
+			attributes[attribute.Name] = attribute.Value
+		}
+		docs[i] = VariantDocument{SKU: variant.SKU, Price: variant.Price, Stock: variant.Stock, Attributes: attributes}
+	}
+	return docs
+}
+
+// toVariants converts variant documents back to variants, with their
+// attributes in name order
+func toVariants(productID string, docs []VariantDocument) []model.Variant {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	variants := make([]model.Variant, len(docs))
+	for i, doc := range docs {
+		price := doc.Price
+		variants[i] = model.VariantRequest{SKU: doc.SKU, Price: &price, Stock: doc.Stock, Attributes: doc.Attributes}.ToVariant(productID, doc.Price)
+	}
+	return variants
+}
+
+// newProductDocument converts a product to its OpenSearch representation
+func newProductDocument(product model.Product) ProductDocument {
+	tags := make([]string, len(product.Tags))
+	for i, tag := range product.Tags {
+		tags[i] = tag.Name
+	}
+
+	doc := ProductDocument{
+		ID:          product.ID,
+		Name:        product.Name,
+		Slug:        product.Slug,
+		Description: product.Description,
+		Price:       product.Price,
+		Tags:        tags,
+		Variants:    newVariantDocuments(product.Variants),
+		Images:      newImageDocuments(product.Images),
+		Attributes:  newAttributeDocuments(product.Attributes),
+		I18n:        newTranslationDocuments(product.Translations),
+		Related:     newRelatedDocument(product.Related),
+		Rating:      product.Rating,
+		Status:      product.Status,
+		CreatedAt:   product.CreatedAt,
+		UpdatedAt:   product.UpdatedAt,
+	}
+	if product.Stock != nil {
+		doc.Stock = *product.Stock
+	}
+	if product.SKU != nil {
+		doc.SKU = *product.SKU
+	}
+	if product.EAN != nil {
+		doc.EAN = *product.EAN
+	}
+
+	if product.Category != nil {
+		doc.Category = product.Category.Name
+		doc.CategoryPath = product.Category.Path
+	}
+
+	return doc
+}
+
+// toProduct converts a search document back to a product
+func (doc ProductDocument) toProduct() model.Product {
+	tags := make([]model.Tag, len(doc.Tags))
+	for i, tagName := range doc.Tags {
+		tags[i] = model.Tag{Name: tagName}
+	}
+
+	product := model.Product{
+		ID:           doc.ID,
+		Name:         doc.Name,
+		Slug:         doc.Slug,
+		Description:  doc.Description,
+		Price:        doc.Price,
+		Tags:         tags,
+		Variants:     toVariants(doc.ID, doc.Variants),
+		Images:       toImages(doc.ID, doc.Images),
+		Attributes:   toAttributes(doc.ID, doc.Attributes),
+		Translations: toTranslations(doc.ID, doc.I18n),
+		Related:      toRelations(doc.ID, doc.Related),
+		Stock:        &doc.Stock,
+		Rating:       doc.Rating,
+		Status:       doc.Status,
+		CreatedAt:    doc.CreatedAt,
+		UpdatedAt:    doc.UpdatedAt,
+	}
+	if product.Status == "" {
+		product.Status = model.StatusActive
+	}
+
+	if doc.SKU != "" {
+		product.SKU = &doc.SKU
+	}
+	if doc.EAN != "" {
+		product.EAN = &doc.EAN
+	}
+
+	if doc.Category != "" {
+		product.CategoryName = &doc.Category
+		product.Category = &model.Category{Name: doc.Category, Path: doc.CategoryPath}
+	}
+
+	return product
 }
 
 // SearchResponse represents the OpenSearch search response structure
 type SearchResponse struct {
-	Hits struct {
+	// Took is the time OpenSearch spent running the search, in milliseconds
+	Took int `json:"took"`
+	// PitID is the point in time to continue a search from, which may
+	// change between batches
+	PitID string `json:"pit_id"`
+	Hits  struct {
 		Total struct {
 			Value int `json:"value"`
 		} `json:"total"`
 		Hits []struct {
+			ID     string          `json:"_id"`
+			Score  float64         `json:"_score"`
 			Source ProductDocument `json:"_source"`
+			Sort   []interface{}   `json:"sort"`
 		} `json:"hits"`
 	} `json:"hits"`
+	// Aggregations are the facets asked for with SearchQuery.Facets
+	Aggregations struct {
+		InStock *struct {
+			Buckets map[string]struct {
+				DocCount int `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"in_stock"`
+		Attributes *struct {
+			Names struct {
+				Buckets []struct {
+					Key    string `json:"key"`
+					Values struct {
+						Buckets []struct {
+							Key      string `json:"key"`
+							DocCount int    `json:"doc_count"`
+						} `json:"buckets"`
+					} `json:"values"`
+				} `json:"buckets"`
+			} `json:"names"`
+		} `json:"attributes"`
+	} `json:"aggregations"`
+}
+
+// facets returns the facet counts of a search, or nil if it didn't ask for
+// them
+func (s *SearchResponse) facets() map[string]map[string]int {
+	if s.Aggregations.InStock == nil && s.Aggregations.Attributes == nil {
+		return nil
+	}
+
+	facets := map[string]map[string]int{}
+	if s.Aggregations.InStock != nil {
+		buckets := s.Aggregations.InStock.Buckets
+		facets[FacetInStock] = stockCounts(buckets["true"].DocCount, buckets["false"].DocCount)
+	}
+	if s.Aggregations.Attributes != nil {
+		for _, name := range s.Aggregations.Attributes.Names.Buckets {
+			counts := make(map[string]int, len(name.Values.Buckets))
+			for _, value := range name.Values.Buckets {
+				counts[value.Key] = value.DocCount
+			}
+			facets[FacetAttributePrefix+name.Key] = counts
+		}
+	}
+	return facets
+}
+
+// observe records the quality metrics of a keyword search. Explaining a
+// search also runs it, but isn't recorded as one.
+func (s *SearchResponse) observe(query map[string]interface{}, ctx context.Context) {
+	observeSearch(query, s.Hits.Total.Value, time.Duration(s.Took)*time.Millisecond, ctx)
 }
 
 // NewOpenSearchRepository creates a new OpenSearch repository
 func NewOpenSearchRepository(config config.OpenSearchConfiguration) (*OpenSearchRepository, error) {
+	client, transport, err := newOpenSearchClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log := slog.With("index", config.IndexName)
+	return &OpenSearchRepository{
+		client:    client,
+		transport: transport,
+		indexName: config.IndexName,
+		fields:    searchFields(config.Boosts),
+		synonyms:  config.Synonyms,
+		log:       log,
+		hedger:    newHedger(config.HedgeDelay, config.HedgeBudget),
+		coalescer: newCoalescer(config.Coalesce),
+		breaker:   newBreaker(DependencyOpenSearch, config.BreakerThreshold, config.BreakerCooldown),
+		throttle:  newBulkThrottle(config.BulkMinBatchSize, config.BulkMaxDelay, config.BulkRetries, log),
+	}, nil
+}
+
+// NewOpenSearchClient connects to the configured OpenSearch cluster, failing
+// if it can't be reached
+func NewOpenSearchClient(config config.OpenSearchConfiguration) (*opensearch.Client, error) {
+	client, _, err := newOpenSearchClient(config)
+	return client, err
+}
+
+// newOpenSearchClient connects to the cluster, also returning the transport
+// holding its connections so that they can be closed
+func newOpenSearchClient(config config.OpenSearchConfiguration) (*opensearch.Client, *http.Transport, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: config.TLSSkipVerify},
+	}
 	cfg := opensearch.Config{
 		Addresses: []string{config.Endpoint},
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: config.TLSSkipVerify},
-		},
+		Transport: tracedTransport(&bulkheadTransport{base: &faultTransport{base: transport}}),
+	}
+
+	source, err := newSearchSecretSource(config)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Add authentication if provided
-	if config.Username != "" && config.Password != "" {
+	// Add authentication if provided, reading the password from a secret for
+	// each request so that it can be rotated
+	if source != nil {
+		cfg.Transport = &secretTransport{base: cfg.Transport, source: source, username: config.Username}
+	} else if config.Username != "" && config.Password != "" {
 		cfg.Username = config.Username
 		cfg.Password = config.Password
 
-		fmt.Printf("Connecting to OpenSearch as %s\n", config.Username)
+		slog.Info("Connecting to OpenSearch", "username", config.Username)
 	}
 
 	client, err := opensearch.NewClient(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenSearch client: %w", err)
+		return nil, nil, fmt.Errorf("failed to create OpenSearch client: %w", err)
 	}
 
 	// Test connection
 	res, err := client.Info()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to OpenSearch: %w", err)
+		return nil, nil, fmt.Errorf("failed to connect to OpenSearch: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, fmt.Errorf("OpenSearch connection error: %s", res.String())
+		return nil, nil, fmt.Errorf("OpenSearch connection error: %s", res.String())
 	}
 
-	fmt.Println("Successfully connected to OpenSearch")
+	slog.Info("Successfully connected to OpenSearch", "endpoint", config.Endpoint)
 
-	return &OpenSearchRepository{
-		client:    client,
-		indexName: config.IndexName,
-	}, nil
+	return client, transport, nil
+}
+
+// SetBoosts changes the boosts applied to matches in each field, taking
+// effect from the next search
+func (r *OpenSearchRepository) SetBoosts(boosts map[string]float64) {
+	fields := searchFields(boosts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fields = fields
+}
+
+func (r *OpenSearchRepository) searchedFields() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fields
+}
+
+// searchFields returns the fields to match keywords against in the form
+// field^boost, favoring the product name unless boosts are configured
+func searchFields(boosts map[string]float64) []string {
+	if len(boosts) == 0 {
+		return []string{"name^2", "description", "tags"}
+	}
+
+	fields := make([]string, 0, len(boosts))
+	for field, boost := range boosts {
+		fields = append(fields, fmt.Sprintf("%s^%g", field, boost))
+	}
+	sort.Strings(fields)
+
+	return fields
 }
 
 // InitializeData creates the index and loads product data into OpenSearch
 // If the index already exists and contains documents, indexing is skipped.
-func (r *OpenSearchRepository) InitializeData() error {
-	ctx := context.Background()
-
+func (r *OpenSearchRepository) InitializeData(ctx context.Context) error {
 	// Check if index exists
-	existsRes, err := r.client.Indices.Exists([]string{r.indexName})
+	existsRes, err := r.client.Indices.Exists([]string{r.indexName}, r.client.Indices.Exists.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to check index existence: %w", err)
 	}
@@ -135,19 +687,19 @@ func (r *OpenSearchRepository) InitializeData() error {
 			}
 			if err := json.NewDecoder(countRes.Body).Decode(&countResponse); err == nil {
 				if len(countResponse) > 0 && countResponse[0].Count != "0" {
-					fmt.Printf("OpenSearch index '%s' already exists with %s documents, skipping re-index\n", r.indexName, countResponse[0].Count)
+					r.log.InfoContext(ctx, "OpenSearch index already exists, skipping re-index", "documents", countResponse[0].Count)
 					return nil
 				}
 			}
 		}
 
 		// Index exists but is empty, delete and recreate
-		deleteRes, err := r.client.Indices.Delete([]string{r.indexName})
+		deleteRes, err := r.client.Indices.Delete([]string{r.indexName}, r.client.Indices.Delete.WithContext(ctx))
 		if err != nil {
 			return fmt.Errorf("failed to delete existing index: %w", err)
 		}
 		defer deleteRes.Body.Close()
-		fmt.Println("Deleted empty OpenSearch index, will recreate")
+		r.log.InfoContext(ctx, "Deleted empty OpenSearch index, will recreate")
 	}
 
 	return r.createAndPopulateIndex(ctx)
@@ -155,17 +707,89 @@ func (r *OpenSearchRepository) InitializeData() error {
 
 // createAndPopulateIndex creates the index with mappings and loads product data.
 func (r *OpenSearchRepository) createAndPopulateIndex(ctx context.Context) error {
-	// Create index with mappings
+	if err := r.createIndex(ctx); err != nil {
+		return err
+	}
+
+	// Load products from JSON file
+	products, err := LoadProductData()
+	if err != nil {
+		return fmt.Errorf("failed to load product data: %w", err)
+	}
+
+	categoryData, err := LoadCategoryData()
+	if err != nil {
+		return fmt.Errorf("failed to load category data: %w", err)
+	}
+
+	categories, err := categoryModels(categoryData)
+	if err != nil {
+		return fmt.Errorf("failed to load category data: %w", err)
+	}
+
+	categoryPaths := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryPaths[category.Name] = category.Path
+	}
+
+	docs := make([]ProductDocument, len(products))
+	for i, product := range products {
+		docs[i] = ProductDocument{
+			ID:           product.ID,
+			Name:         product.Name,
+			Description:  product.Description,
+			Price:        product.Price,
+			Tags:         product.Tags,
+			Category:     product.Category,
+			CategoryPath: categoryPaths[product.Category],
+		}
+	}
+
+	failed, err := r.bulkIndex(docs, ctx)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to index %d of %d products", failed, len(docs))
+	}
+
+	r.log.InfoContext(ctx, "Indexed products into OpenSearch", "products", len(products))
+	return nil
+}
+
+// createIndex creates the empty index with its settings and mappings
+func (r *OpenSearchRepository) createIndex(ctx context.Context) error {
 	mapping := `{
 		"settings": {
 			"number_of_shards": 1,
 			"number_of_replicas": 0,
 			"analysis": {
+				"tokenizer": {
+					"category_path": {
+						"type": "path_hierarchy",
+						"delimiter": "/"
+					},
+					"sku_ngram": {
+						"type": "ngram",
+						"min_gram": 3,
+						"max_gram": 4,
+						"token_chars": ["letter", "digit"]
+					}
+				},
 				"analyzer": {
 					"product_analyzer": {
 						"type": "custom",
 						"tokenizer": "standard",
 						"filter": ["lowercase", "stop", "snowball"]
+					},
+					"category_path": {
+						"type": "custom",
+						"tokenizer": "category_path"
+					},
+					"sku_ngram": {
+						"type": "custom",
+						"tokenizer": "sku_ngram",
+						"filter": ["lowercase"]
 					}
 				}
 			}
@@ -184,15 +808,111 @@ func (r *OpenSearchRepository) createAndPopulateIndex(ctx context.Context) error
 					"type": "text",
 					"analyzer": "product_analyzer"
 				},
-				"price": { "type": "integer" },
-				"tags": { "type": "keyword" }
-			}
+				"price": {
+					"properties": {
+						"amount": { "type": "long" },
+						"currency": { "type": "keyword" }
+					}
+				},
+				"tags": { "type": "keyword" },
+				"category": { "type": "keyword" },
+				"categoryPath": {
+					"type": "keyword",
+					"fields": {
+						"tree": { "type": "text", "analyzer": "category_path", "search_analyzer": "keyword" }
+					}
+				},
+				"stock": { "type": "integer" },
+				"status": { "type": "keyword" },
+				"sku": {
+					"type": "keyword",
+					"fields": {
+						"ngram": { "type": "text", "analyzer": "sku_ngram" }
+					}
+				},
+				"ean": { "type": "keyword" },
+				"slug": { "type": "keyword" },
+				"createdAt": { "type": "date" },
+				"updatedAt": { "type": "date" },
+				"rating": {
+					"properties": {
+						"average": { "type": "float" },
+						"count": { "type": "integer" }
+					}
+				},
+				"variants": {
+					"type": "nested",
+					"properties": {
+						"sku": { "type": "keyword" },
+						"price": {
+							"properties": {
+								"amount": { "type": "long" },
+								"currency": { "type": "keyword" }
+							}
+						},
+						"stock": { "type": "integer" },
+						"attributes": { "type": "object" }
+					}
+				},
+				"images": { "type": "object", "enabled": false },
+				"attributes": {
+					"type": "nested",
+					"properties": {
+						"name": { "type": "keyword" },
+						"type": { "type": "keyword" },
+						"value": { "type": "keyword" },
+						"number": { "type": "double" }
+					}
+				},
+				"related": {
+					"properties": {
+						"bundle": { "type": "keyword" },
+						"accessory-of": { "type": "keyword" },
+						"replacement-for": { "type": "keyword" }
+					}
+				},
+				"i18n": {
+					"properties": {
+						"de": {
+							"properties": {
+								"name": { "type": "text", "analyzer": "german" },
+								"description": { "type": "text", "analyzer": "german" }
+							}
+						},
+						"es": {
+							"properties": {
+								"name": { "type": "text", "analyzer": "spanish" },
+								"description": { "type": "text", "analyzer": "spanish" }
+							}
+						},
+						"fr": {
+							"properties": {
+								"name": { "type": "text", "analyzer": "french" },
+								"description": { "type": "text", "analyzer": "french" }
+							}
+						}
+					}
+				}
+			},
+			"dynamic_templates": [
+				{
+					"variant_attributes": {
+						"path_match": "variants.attributes.*",
+						"mapping": { "type": "keyword" }
+					}
+				}
+			]
 		}
 	}`
 
+	body, err := withSynonyms(mapping, r.synonyms)
+	if err != nil {
+		return fmt.Errorf("failed to add synonyms to the index settings: %w", err)
+	}
+
 	createRes, err := r.client.Indices.Create(
 		r.indexName,
-		r.client.Indices.Create.WithBody(strings.NewReader(mapping)),
+		r.client.Indices.Create.WithBody(bytes.NewReader(body)),
 		r.client.Indices.Create.WithContext(ctx),
 	)
 	if err != nil {
@@ -204,33 +924,76 @@ func (r *OpenSearchRepository) createAndPopulateIndex(ctx context.Context) error
 		return fmt.Errorf("failed to create index: %s", createRes.String())
 	}
 
-	fmt.Println("Created OpenSearch index with mappings")
+	r.log.InfoContext(ctx, "Created OpenSearch index with mappings")
+	return nil
+}
+
+// bulkIndex adds or replaces documents with bulk requests, returning how
+// many of them were rejected
+func (r *OpenSearchRepository) bulkIndex(docs []ProductDocument, ctx context.Context) (int, error) {
+	ctx, op := r.startOperation("bulk", ctx, attrDocuments.Int(len(docs)))
+	defer op.End()
 
-	// Load products from JSON file
-	products, err := LoadProductData()
-	if err != nil {
-		return fmt.Errorf("failed to load product data: %w", err)
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	// Documents rejected because the cluster is overloaded are sent again,
+	// more slowly and in smaller requests, once the rest have been sent
+	failed, retried := 0, 0
+	pending := docs
+	for attempt := 0; len(pending) > 0; attempt++ {
+		var rejected []ProductDocument
+		for len(pending) > 0 {
+			if err := r.throttle.wait(ctx); err != nil {
+				return 0, op.fail(err)
+			}
+
+			n := r.throttle.limit(len(pending))
+			batchRejected, batchFailed, err := r.sendBulk(pending[:n], ctx)
+			if err != nil {
+				return 0, op.fail(err)
+			}
+
+			if len(batchRejected) > 0 {
+				observeBulkRejected(len(batchRejected))
+				r.throttle.rejected(n)
+			} else {
+				r.throttle.accepted(n, len(pending))
+			}
+			failed += batchFailed
+			rejected = append(rejected, batchRejected...)
+			pending = pending[n:]
+		}
+
+		if attempt == r.throttle.retries {
+			failed += len(rejected)
+			break
+		}
+		retried += len(rejected)
+		pending = rejected
 	}
 
-	// Bulk index products
+	op.SetAttributes(attrFailed.Int(failed), attrRetried.Int(retried))
+	r.lastSync.Store(time.Now().UnixNano())
+	return failed, nil
+}
+
+// sendBulk adds or replaces documents in one bulk request, returning those
+// rejected because the cluster's write queues were full, which can be sent
+// again, and how many others failed
+func (r *OpenSearchRepository) sendBulk(docs []ProductDocument, ctx context.Context) ([]ProductDocument, int, error) {
 	var bulkBody strings.Builder
-	for _, product := range products {
+	for _, doc := range docs {
 		// Action line
-		action := fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}`, r.indexName, product.ID)
+		action := fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}`, r.indexName, doc.ID)
 		bulkBody.WriteString(action)
 		bulkBody.WriteString("\n")
 
 		// Document line
-		doc := ProductDocument{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			Tags:        product.Tags,
-		}
 		docJSON, err := json.Marshal(doc)
 		if err != nil {
-			return fmt.Errorf("failed to marshal product: %w", err)
+			return nil, 0, fmt.Errorf("failed to marshal product: %w", err)
 		}
 		bulkBody.WriteString(string(docJSON))
 		bulkBody.WriteString("\n")
@@ -243,67 +1006,401 @@ func (r *OpenSearchRepository) createAndPopulateIndex(ctx context.Context) error
 
 	bulkRes, err := bulkReq.Do(ctx, r.client)
 	if err != nil {
-		return fmt.Errorf("failed to bulk index products: %w", err)
+		return nil, 0, fmt.Errorf("failed to bulk index products: %w", err)
 	}
 	defer bulkRes.Body.Close()
 
+	if bulkRes.StatusCode == http.StatusTooManyRequests {
+		return docs, 0, nil
+	}
 	if bulkRes.IsError() {
-		return fmt.Errorf("bulk indexing error: %s", bulkRes.String())
+		return nil, 0, fmt.Errorf("bulk indexing error: %s", bulkRes.String())
 	}
 
-	fmt.Printf("Successfully indexed %d products into OpenSearch\n", len(products))
-	return nil
+	// The request succeeds even if some documents are rejected, which is
+	// reported per item in the order they were sent
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(bulkRes.Body).Decode(&response); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+
+	var rejected []ProductDocument
+	failed := 0
+	if response.Errors {
+		for i, item := range response.Items {
+			for _, result := range item {
+				switch {
+				case result.Status == http.StatusTooManyRequests && i < len(docs):
+					rejected = append(rejected, docs[i])
+				case result.Status >= 300:
+					failed++
+				}
+			}
+		}
+	}
+
+	return rejected, failed, nil
 }
 
-// Reindex drops the existing index and recreates it with fresh data.
-func (r *OpenSearchRepository) Reindex() error {
-	ctx := context.Background()
+// write sends a request writing a single document, built afresh for each
+// attempt, sending it again more slowly while the cluster's write queues are
+// full
+func (r *OpenSearchRepository) write(ctx context.Context, do func() (*opensearchapi.Response, error)) (*opensearchapi.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := r.throttle.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		res, err := do()
+		if err != nil || res.StatusCode != http.StatusTooManyRequests {
+			if err == nil && !res.IsError() {
+				r.throttle.accepted(0, 0)
+			}
+			return res, err
+		}
+
+		observeBulkRejected(1)
+		r.throttle.rejected(0)
+		if attempt == r.throttle.retries {
+			return res, nil
+		}
+		res.Body.Close()
+	}
+}
 
+// Reindex drops the existing index and recreates it with fresh data.
+func (r *OpenSearchRepository) Reindex(ctx context.Context) error {
 	// Delete existing index if it exists
-	existsRes, err := r.client.Indices.Exists([]string{r.indexName})
+	existsRes, err := r.client.Indices.Exists([]string{r.indexName}, r.client.Indices.Exists.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to check index existence: %w", err)
 	}
 	defer existsRes.Body.Close()
 
 	if !existsRes.IsError() {
-		deleteRes, err := r.client.Indices.Delete([]string{r.indexName})
+		deleteRes, err := r.client.Indices.Delete([]string{r.indexName}, r.client.Indices.Delete.WithContext(ctx))
 		if err != nil {
 			return fmt.Errorf("failed to delete existing index: %w", err)
 		}
 		defer deleteRes.Body.Close()
-		fmt.Println("Deleted existing OpenSearch index for reindex")
+		r.log.InfoContext(ctx, "Deleted existing OpenSearch index for reindex")
 	}
 
 	return r.createAndPopulateIndex(ctx)
 }
 
 // SearchProducts searches for products matching the keyword with pagination
-func (r *OpenSearchRepository) SearchProducts(keyword string, page, size int, ctx context.Context) ([]model.Product, error) {
+func (r *OpenSearchRepository) SearchProducts(search SearchQuery, page, size int, ctx context.Context) (SearchResult, error) {
 	// Calculate offset for pagination
 	from := (page - 1) * size
 
-	// Build the search query
-	query := map[string]interface{}{
+	query := r.searchQuery(search, size)
+	query["from"] = from
+
+	searchResponse, err := r.search(query, ctx)
+	if r.failover(err, ctx) {
+		return r.fallback.SearchProducts(search, page, size, ctx)
+	} else if err != nil {
+		return SearchResult{}, err
+	}
+	searchResponse.observe(query, ctx)
+
+	// Convert to Product model
+	products := make([]model.Product, 0, len(searchResponse.Hits.Hits))
+	for _, hit := range searchResponse.Hits.Hits {
+		products = append(products, hit.Source.toProduct())
+	}
+
+	return SearchResult{Products: products, Facets: searchResponse.facets()}, nil
+}
+
+// SearchProductsAfter searches for products matching the keyword, sorted by
+// rating or newest if asked, then relevance and then ID so that every hit has a unique
+// position
+func (r *OpenSearchRepository) SearchProductsAfter(search SearchQuery, after []interface{}, size int, ctx context.Context) (SearchResult, []interface{}, error) {
+	query := r.searchQuery(search, size)
+	sort := []map[string]interface{}{{"_score": "desc"}, {"id": "asc"}}
+	if sorted := searchSort(search.Order); sorted != nil {
+		sort = append([]map[string]interface{}{sorted}, sort...)
+	}
+	query["sort"] = sort
+	if len(after) > 0 {
+		query["search_after"] = after
+	}
+
+	// The fallback has no cursors, so only the first page can be answered
+	// by it, without sort values to continue from
+	searchResponse, err := r.search(query, ctx)
+	if len(after) == 0 && r.failover(err, ctx) {
+		result, err := r.fallback.SearchProducts(search, 1, size, ctx)
+		return result, nil, err
+	} else if err != nil {
+		return SearchResult{}, nil, err
+	}
+	searchResponse.observe(query, ctx)
+
+	var last []interface{}
+	products := make([]model.Product, 0, len(searchResponse.Hits.Hits))
+	for _, hit := range searchResponse.Hits.Hits {
+		products = append(products, hit.Source.toProduct())
+		last = hit.Sort
+	}
+
+	return SearchResult{Products: products, Facets: searchResponse.facets()}, last, nil
+}
+
+// ExplainSearch runs the first page of a keyword search and asks the Explain
+// API how the score of each hit was calculated
+func (r *OpenSearchRepository) ExplainSearch(keyword string, size int, ctx context.Context) (*model.SearchExplanation, error) {
+	query := r.searchQuery(SearchQuery{Keyword: keyword}, size)
+
+	searchResponse, err := r.search(query, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &model.SearchExplanation{
+		Keyword: keyword,
+		Query:   query,
+		Hits:    make([]model.ExplainedHit, 0, len(searchResponse.Hits.Hits)),
+	}
+
+	for _, hit := range searchResponse.Hits.Hits {
+		scored, err := r.explain(hit.ID, query["query"], ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		explanation.Hits = append(explanation.Hits, model.ExplainedHit{
+			ID:          hit.ID,
+			Name:        hit.Source.Name,
+			Score:       hit.Score,
+			Explanation: *scored,
+		})
+	}
+
+	return explanation, nil
+}
+
+// explain returns the breakdown of a document's score for a query
+func (r *OpenSearchRepository) explain(id string, query interface{}, ctx context.Context) (*model.ScoreExplanation, error) {
+	ctx, op := r.startOperation("explain", ctx, attrProduct.String(id))
+	defer op.End()
+
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return nil, op.fail(fmt.Errorf("failed to marshal explain query: %w", err))
+	}
+
+	explainReq := opensearchapi.ExplainRequest{
+		Index:      r.indexName,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+	}
+
+	res, err := explainReq.Do(ctx, r.client)
+	if err != nil {
+		return nil, op.fail(fmt.Errorf("explain request failed: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, op.fail(fmt.Errorf("explain error: %s", res.String()))
+	}
+
+	var explainResponse struct {
+		Explanation model.ScoreExplanation `json:"explanation"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&explainResponse); err != nil {
+		return nil, op.fail(fmt.Errorf("failed to parse explain response: %w", err))
+	}
+
+	return &explainResponse.Explanation, nil
+}
+
+// withSynonyms adds an analyzer that expands synonyms to the index settings
+// and uses it for keywords searched in the name and description. Synonyms are
+// only applied at search time, so the index doesn't need to be rebuilt when
+// they change, only recreated for the new settings.
+func withSynonyms(mapping string, synonyms []string) ([]byte, error) {
+	if len(synonyms) == 0 {
+		return []byte(mapping), nil
+	}
+
+	var index map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(mapping), &index); err != nil {
+		return nil, err
+	}
+
+	analysis := index["settings"]["analysis"].(map[string]interface{})
+	analysis["filter"] = map[string]interface{}{
+		"product_synonyms": map[string]interface{}{
+			"type":     "synonym_graph",
+			"synonyms": synonyms,
+		},
+	}
+	analysis["analyzer"].(map[string]interface{})["product_search_analyzer"] = map[string]interface{}{
+		"type":      "custom",
+		"tokenizer": "standard",
+		"filter":    []string{"lowercase", "product_synonyms", "stop", "snowball"},
+	}
+
+	properties := index["mappings"]["properties"].(map[string]interface{})
+	for _, field := range []string{"name", "description"} {
+		properties[field].(map[string]interface{})["search_analyzer"] = "product_search_analyzer"
+	}
+
+	return json.Marshal(index)
+}
+
+// searchQuery builds the keyword query shared by both kinds of pagination
+func (r *OpenSearchRepository) searchQuery(search SearchQuery, size int) map[string]interface{} {
+	keyword := search.Keyword
+	fields := r.searchedFields()
+	if locale := search.translationLocale(); locale != "" {
+		fields = localizedFields(fields, locale)
+	}
+
+	query := map[string]interface{}{
 		"query": map[string]interface{}{
 			"multi_match": map[string]interface{}{
 				"query":     keyword,
-				"fields":    []string{"name^2", "description", "tags"},
+				"fields":    fields,
 				"fuzziness": "AUTO",
 			},
 		},
-		"from": from,
 		"size": size,
 	}
+	if looksLikeSKU(keyword) {
+		query["query"] = skuQuery(keyword, query["query"].(map[string]interface{}))
+	}
+
+	filters := []map[string]interface{}{}
+	if len(search.PriceRanges) > 0 {
+		filters = append(filters, priceRangesQuery(search.PriceRanges))
+	}
+	if len(search.VariantAttributes) > 0 {
+		filters = append(filters, variantAttributesQuery(search.VariantAttributes))
+	}
+	if search.Category != nil {
+		filters = append(filters, categoryQuery(*search.Category))
+	}
+	if len(search.Attributes) > 0 {
+		filters = append(filters, attributeFiltersQueries(search.Attributes)...)
+	}
+	if search.MinRating != nil {
+		filters = append(filters, minRatingQuery(*search.MinRating))
+	}
+	if statuses := search.statuses(); len(statuses) > 0 {
+		filters = append(filters, statusQuery(statuses))
+	}
+	if search.OnSale != nil {
+		filters = append(filters, saleQuery(*search.OnSale, search.Sale))
+	}
+
+	switch len(filters) {
+	case 0:
+	case 1:
+		query["query"] = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   query["query"],
+				"filter": filters[0],
+			},
+		}
+	default:
+		query["query"] = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   query["query"],
+				"filter": filters,
+			},
+		}
+	}
+
+	// As a post filter stock leaves the in-stock facet counting the
+	// products it filters out
+	if search.InStock != nil {
+		query["post_filter"] = stockQuery(*search.InStock)
+	}
+	if search.Facets {
+		query["aggs"] = map[string]interface{}{
+			FacetInStock: map[string]interface{}{
+				"filters": map[string]interface{}{
+					"filters": map[string]interface{}{"true": stockQuery(true), "false": stockQuery(false)},
+				},
+			},
+			"attributes": attributeFacetsAggregation(),
+		}
+	}
+
+	if sort := searchSort(search.Order); sort != nil {
+		query["sort"] = []map[string]interface{}{sort, {"_score": "desc"}}
+	}
+
+	if fields := search.LoadedFields(); fields != nil {
+		// Translations are indexed by locale under i18n
+		source := make([]string, len(fields))
+		for i, field := range fields {
+			source[i] = field
+			if field == "translations" {
+				source[i] = "i18n"
+			}
+		}
+		query["_source"] = source
+	}
+
+	return query
+}
+
+func (r *OpenSearchRepository) search(query map[string]interface{}, ctx context.Context) (*SearchResponse, error) {
+	ctx, op := r.startOperation("search", ctx, attrQueryType.String(queryType(query)))
+	defer op.End()
 
 	queryJSON, err := json.Marshal(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal search query: %w", err)
+		return nil, op.fail(fmt.Errorf("failed to marshal search query: %w", err))
+	}
+
+	// A search of a point in time names the index it was created for
+	// instead
+	indices := []string{r.indexName}
+	if _, ok := query["pit"]; ok {
+		indices = nil
+	}
+
+	if !r.breaker.allow() {
+		return nil, op.fail(ErrBreakerOpen)
 	}
 
-	// Execute search
+	// The query is the key, so that only searches for the same keyword,
+	// filters, page and fields are coalesced. The breaker counts the queries
+	// sent rather than the searches sharing them.
+	searchResponse, shared, err := coalesce(r.coalescer, string(queryJSON), func(ctx context.Context) (*SearchResponse, error) {
+		searchResponse, err := hedge(r.hedger, func(ctx context.Context) (*SearchResponse, error) {
+			return r.sendSearch(indices, queryJSON, ctx)
+		}, ctx)
+		r.breaker.record(err)
+		return searchResponse, err
+	}, ctx)
+	op.SetAttributes(attrCoalesced.Bool(shared))
+	if err != nil {
+		return nil, op.fail(err)
+	}
+
+	op.SetAttributes(attrHits.Int(len(searchResponse.Hits.Hits)), attrTotalHits.Int(searchResponse.Hits.Total.Value))
+
+	return searchResponse, nil
+}
+
+// sendSearch runs a search query against the index, reading the whole
+// response so that a hedged copy can be cancelled once it has returned
+func (r *OpenSearchRepository) sendSearch(indices []string, queryJSON []byte, ctx context.Context) (*SearchResponse, error) {
 	searchReq := opensearchapi.SearchRequest{
-		Index: []string{r.indexName},
+		Index: indices,
 		Body:  bytes.NewReader(queryJSON),
 	}
 
@@ -323,22 +1420,412 @@ func (r *OpenSearchRepository) SearchProducts(keyword string, page, size int, ct
 		return nil, fmt.Errorf("failed to parse search response: %w", err)
 	}
 
-	// Convert to Product model
-	products := make([]model.Product, 0, len(searchResponse.Hits.Hits))
-	for _, hit := range searchResponse.Hits.Hits {
-		tags := make([]model.Tag, len(hit.Source.Tags))
-		for i, tagName := range hit.Source.Tags {
-			tags[i] = model.Tag{Name: tagName}
+	return &searchResponse, nil
+}
+
+// CountProductsByTag counts the documents with each tag using a terms aggregation
+func (r *OpenSearchRepository) CountProductsByTag(ctx context.Context) (map[string]int, error) {
+	ctx, op := r.startOperation("aggregate", ctx, attrQueryType.String("terms"))
+	defer op.End()
+
+	query := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"tags": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "tags", "size": 1000},
+			},
+		},
+	}
+
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, op.fail(fmt.Errorf("failed to marshal aggregation query: %w", err))
+	}
+
+	searchReq := opensearchapi.SearchRequest{
+		Index: []string{r.indexName},
+		Body:  bytes.NewReader(queryJSON),
+	}
+
+	res, err := searchReq.Do(ctx, r.client)
+	if err != nil {
+		return nil, op.fail(fmt.Errorf("aggregation request failed: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, op.fail(fmt.Errorf("aggregation error: %s", res.String()))
+	}
+
+	var aggResponse struct {
+		Aggregations struct {
+			Tags struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"tags"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&aggResponse); err != nil {
+		return nil, op.fail(fmt.Errorf("failed to parse aggregation response: %w", err))
+	}
+
+	counts := make(map[string]int, len(aggResponse.Aggregations.Tags.Buckets))
+	for _, bucket := range aggResponse.Aggregations.Tags.Buckets {
+		counts[bucket.Key] = bucket.DocCount
+	}
+
+	return counts, nil
+}
+
+// Close closes the idle connections to the cluster. Requests still in
+// progress are left to finish.
+func (r *OpenSearchRepository) Close() error {
+	r.transport.CloseIdleConnections()
+	return nil
+}
+
+// Ping checks the cluster is reachable and the product index exists
+func (r *OpenSearchRepository) Ping(ctx context.Context) error {
+	res, err := r.client.Indices.Exists([]string{r.indexName}, r.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to reach OpenSearch: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("index %s does not exist", r.indexName)
+	}
+	if res.IsError() {
+		return fmt.Errorf("failed to check index existence: %s", res.String())
+	}
+
+	return nil
+}
+
+// Status reports the health of the cluster and the number of documents in
+// the product index, along with when documents were last written to it. A
+// yellow cluster, which has unassigned replicas as single nodes do, is still
+// healthy.
+func (r *OpenSearchRepository) Status(ctx context.Context) (map[string]any, error) {
+	details := map[string]any{"index": r.indexName}
+	if r.breaker != nil {
+		details["breaker"] = r.breaker.State()
+	}
+	if batch, delay := r.throttle.state(); batch != 0 || delay != 0 {
+		details["throttle"] = map[string]any{"batchSize": batch, "delay": delay.String()}
+	}
+	if synced := r.lastSync.Load(); synced != 0 {
+		details["lastSync"] = time.Unix(0, synced).UTC()
+	}
+
+	res, err := r.client.Cluster.Health(r.client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return details, fmt.Errorf("failed to reach OpenSearch: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return details, fmt.Errorf("failed to read cluster health: %s", res.String())
+	}
+
+	var cluster struct {
+		Name   string `json:"cluster_name"`
+		Status string `json:"status"`
+		Nodes  int    `json:"number_of_nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&cluster); err != nil {
+		return details, fmt.Errorf("failed to decode cluster health: %w", err)
+	}
+	details["cluster"] = cluster.Name
+	details["clusterStatus"] = cluster.Status
+	details["nodes"] = cluster.Nodes
+
+	countRes, err := r.client.Count(r.client.Count.WithIndex(r.indexName), r.client.Count.WithContext(ctx))
+	if err != nil {
+		return details, fmt.Errorf("failed to count documents: %w", err)
+	}
+	defer countRes.Body.Close()
+
+	if countRes.IsError() {
+		return details, fmt.Errorf("failed to count documents: %s", countRes.String())
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(countRes.Body).Decode(&count); err != nil {
+		return details, fmt.Errorf("failed to decode document count: %w", err)
+	}
+	details["documents"] = count.Count
+
+	if cluster.Status == "red" {
+		return details, fmt.Errorf("cluster health is red")
+	}
+	return details, nil
+}
+
+// ResetIndex drops the index, if it exists, and recreates it without any
+// documents
+func (r *OpenSearchRepository) ResetIndex(ctx context.Context) error {
+	existsRes, err := r.client.Indices.Exists([]string{r.indexName}, r.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to check index existence: %w", err)
+	}
+	defer existsRes.Body.Close()
+
+	if !existsRes.IsError() {
+		deleteRes, err := r.client.Indices.Delete([]string{r.indexName}, r.client.Indices.Delete.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to delete existing index: %w", err)
 		}
+		defer deleteRes.Body.Close()
+	}
 
-		products = append(products, model.Product{
-			ID:          hit.Source.ID,
-			Name:        hit.Source.Name,
-			Description: hit.Source.Description,
-			Price:       hit.Source.Price,
-			Tags:        tags,
-		})
+	return r.createIndex(ctx)
+}
+
+// IndexProducts adds or replaces a batch of product documents
+func (r *OpenSearchRepository) IndexProducts(products []model.Product, ctx context.Context) (int, error) {
+	docs := make([]ProductDocument, len(products))
+	for i, product := range products {
+		docs[i] = newProductDocument(product)
+	}
+
+	return r.bulkIndex(docs, ctx)
+}
+
+// IndexProduct adds or replaces a single product document
+func (r *OpenSearchRepository) IndexProduct(product model.Product, ctx context.Context) error {
+	ctx, op := r.startOperation("index", ctx, attrProduct.String(product.ID))
+	defer op.End()
+
+	docJSON, err := json.Marshal(newProductDocument(product))
+	if err != nil {
+		return op.fail(fmt.Errorf("failed to marshal product: %w", err))
+	}
+
+	res, err := r.write(ctx, func() (*opensearchapi.Response, error) {
+		indexReq := opensearchapi.IndexRequest{
+			Index:      r.indexName,
+			DocumentID: product.ID,
+			Body:       bytes.NewReader(docJSON),
+			Refresh:    "true",
+		}
+		return indexReq.Do(ctx, r.client)
+	})
+	if err != nil {
+		return op.fail(fmt.Errorf("failed to index product: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return op.fail(fmt.Errorf("indexing error: %s", res.String()))
 	}
 
-	return products, nil
+	r.lastSync.Store(time.Now().UnixNano())
+	return nil
+}
+
+// UpdateProductFields updates the given fields of a product document
+func (r *OpenSearchRepository) UpdateProductFields(product model.Product, fields []string, ctx context.Context) error {
+	ctx, op := r.startOperation("update", ctx, attrProduct.String(product.ID), attrFields.StringSlice(fields))
+	defer op.End()
+
+	doc := newProductDocument(product)
+
+	partial := map[string]interface{}{}
+	for _, field := range fields {
+		switch field {
+		case "name":
+			partial["name"] = doc.Name
+		case "slug":
+			partial["slug"] = doc.Slug
+		case "description":
+			partial["description"] = doc.Description
+		case "price":
+			partial["price"] = doc.Price
+		case "tags":
+			partial["tags"] = doc.Tags
+		case "variants":
+			partial["variants"] = doc.Variants
+		case "stock":
+			partial["stock"] = doc.Stock
+		case "rating":
+			partial["rating"] = doc.Rating
+		case "status":
+			partial["status"] = doc.Status
+		case "updatedAt":
+			partial["updatedAt"] = doc.UpdatedAt
+		case "sku":
+			// Null removes the field when the product's SKU is removed
+			partial["sku"] = nil
+			if doc.SKU != "" {
+				partial["sku"] = doc.SKU
+			}
+		case "ean":
+			partial["ean"] = nil
+			if doc.EAN != "" {
+				partial["ean"] = doc.EAN
+			}
+		case "images":
+			partial["images"] = doc.Images
+		case "attributes":
+			partial["attributes"] = doc.Attributes
+		case "translations":
+			// Objects are merged into the document, so locales the product
+			// is no longer translated into are set to null
+			i18n := map[string]interface{}{}
+			for _, locale := range model.Locales {
+				i18n[locale] = nil
+				if translation, ok := doc.I18n[locale]; ok {
+					i18n[locale] = translation
+				}
+			}
+			partial["i18n"] = i18n
+		case "related":
+			// Like translations, types the product no longer has relations
+			// of are set to null
+			related := map[string]interface{}{}
+			for _, relationType := range model.RelationTypes {
+				related[relationType] = nil
+				if ids, ok := doc.Related[relationType]; ok {
+					related[relationType] = ids
+				}
+			}
+			partial["related"] = related
+		case "category":
+			// Null removes the fields when the product leaves its category
+			partial["category"] = nil
+			partial["categoryPath"] = nil
+			if doc.Category != "" {
+				partial["category"] = doc.Category
+				partial["categoryPath"] = doc.CategoryPath
+			}
+		default:
+			return op.fail(fmt.Errorf("field %q can't be updated in the index", field))
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"doc": partial})
+	if err != nil {
+		return op.fail(fmt.Errorf("failed to marshal product update: %w", err))
+	}
+
+	res, err := r.write(ctx, func() (*opensearchapi.Response, error) {
+		updateReq := opensearchapi.UpdateRequest{
+			Index:      r.indexName,
+			DocumentID: product.ID,
+			Body:       bytes.NewReader(body),
+			Refresh:    "true",
+		}
+		return updateReq.Do(ctx, r.client)
+	})
+	if err != nil {
+		return op.fail(fmt.Errorf("failed to update product document: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return op.fail(fmt.Errorf("update error: %s", res.String()))
+	}
+
+	r.lastSync.Store(time.Now().UnixNano())
+	return nil
+}
+
+// RemoveProduct deletes a single product document, ignoring documents that
+// are already absent
+func (r *OpenSearchRepository) RemoveProduct(id string, ctx context.Context) error {
+	ctx, op := r.startOperation("delete", ctx, attrProduct.String(id))
+	defer op.End()
+
+	res, err := r.write(ctx, func() (*opensearchapi.Response, error) {
+		deleteReq := opensearchapi.DeleteRequest{
+			Index:      r.indexName,
+			DocumentID: id,
+			Refresh:    "true",
+		}
+		return deleteReq.Do(ctx, r.client)
+	})
+	if err != nil {
+		return op.fail(fmt.Errorf("failed to delete product document: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return op.fail(fmt.Errorf("delete error: %s", res.String()))
+	}
+
+	r.lastSync.Store(time.Now().UnixNano())
+	return nil
+}
+
+// IndexedProductIDs returns the IDs of every document in the index, paging
+// through the index in ID order
+func (r *OpenSearchRepository) IndexedProductIDs(ctx context.Context) ([]string, error) {
+	ctx, op := r.startOperation("scan", ctx, attrQueryType.String("match_all"))
+	defer op.End()
+
+	ids := []string{}
+	var searchAfter []interface{}
+
+	for {
+		query := map[string]interface{}{
+			"query":   map[string]interface{}{"match_all": map[string]interface{}{}},
+			"_source": []string{"id"},
+			"sort":    []map[string]string{{"id": "asc"}},
+			"size":    1000,
+		}
+		if searchAfter != nil {
+			query["search_after"] = searchAfter
+		}
+
+		queryJSON, err := json.Marshal(query)
+		if err != nil {
+			return nil, op.fail(fmt.Errorf("failed to marshal search query: %w", err))
+		}
+
+		searchReq := opensearchapi.SearchRequest{
+			Index: []string{r.indexName},
+			Body:  bytes.NewReader(queryJSON),
+		}
+
+		res, err := searchReq.Do(ctx, r.client)
+		if err != nil {
+			return nil, op.fail(fmt.Errorf("search request failed: %w", err))
+		}
+
+		var response struct {
+			Hits struct {
+				Hits []struct {
+					Source ProductDocument `json:"_source"`
+					Sort   []interface{}   `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+
+		if res.IsError() {
+			res.Body.Close()
+			return nil, op.fail(fmt.Errorf("search error: %s", res.String()))
+		}
+
+		err = json.NewDecoder(res.Body).Decode(&response)
+		res.Body.Close()
+		if err != nil {
+			return nil, op.fail(fmt.Errorf("failed to parse search response: %w", err))
+		}
+
+		hits := response.Hits.Hits
+		for _, hit := range hits {
+			ids = append(ids, hit.Source.ID)
+		}
+
+		if len(hits) < 1000 {
+			op.SetAttributes(attrDocuments.Int(len(ids)))
+			return ids, nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+	}
 }
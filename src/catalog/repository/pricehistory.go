@@ -0,0 +1,94 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// PriceHistoryRepository interface for repositories that record every change
+// to the price of a product
+type PriceHistoryRepository interface {
+	// GetPriceHistory returns up to limit changes to the price of a product,
+	// newest first
+	GetPriceHistory(productID string, limit int, ctx context.Context) ([]model.PriceChange, error)
+}
+
+// GetPriceHistory returns up to limit changes to the price of a product,
+// newest first
+func (db *Database) GetPriceHistory(productID string, limit int, ctx context.Context) ([]model.PriceChange, error) {
+	exists, err := productExists(db.reads().WithContext(ctx), productID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	changes := []model.PriceChange{}
+	err = db.reads().WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("changed_at desc").
+		Order("id desc").
+		Limit(limit).
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// recordPrice adds the price of a product to its history in the transaction
+// that sets it, unless it is already the latest price recorded
+func recordPrice(tx *gorm.DB, productID string, price model.Money) error {
+	var latest []model.PriceChange
+	err := tx.Where("product_id = ?", productID).Order("changed_at desc").Order("id desc").Limit(1).Find(&latest).Error
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 && latest[0].Price == price {
+		return nil
+	}
+
+	return tx.Create(&model.PriceChange{
+		ProductID: productID,
+		Price:     price,
+		ChangedAt: time.Now().UTC(),
+	}).Error
+}
+
+// deletePriceHistory removes the price history of a product
+func deletePriceHistory(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.PriceChange{}).Error
+}
+
+// seedPriceHistory starts the history of products stored before prices were
+// tracked with their current price
+func seedPriceHistory(db *gorm.DB) error {
+	return db.Exec(
+		"INSERT INTO price_changes (product_id, price, currency, changed_at) "+
+			"SELECT id, price, currency, ? FROM products "+
+			"WHERE id NOT IN (SELECT product_id FROM price_changes)",
+		time.Now().UTC(),
+	).Error
+}
@@ -0,0 +1,100 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PriceRange is an inclusive range of prices in minor units of a currency,
+// or of any currency if none is given. Either bound may be missing.
+type PriceRange struct {
+	Currency string `json:"currency,omitempty"`
+	Min      *int   `json:"min,omitempty"`
+	Max      *int   `json:"max,omitempty"`
+}
+
+func (r PriceRange) String() string {
+	bound := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(*value)
+	}
+	return fmt.Sprintf("%s:%s-%s", r.Currency, bound(r.Min), bound(r.Max))
+}
+
+// priceRangesCondition returns an SQL condition matching products priced
+// within any of the ranges
+func priceRangesCondition(ranges []PriceRange) (string, []interface{}) {
+	conditions := make([]string, 0, len(ranges))
+	args := []interface{}{}
+	for _, r := range ranges {
+		terms := []string{}
+		if r.Currency != "" {
+			terms = append(terms, "products.currency = ?")
+			args = append(args, r.Currency)
+		}
+		if r.Min != nil {
+			terms = append(terms, "products.price >= ?")
+			args = append(args, *r.Min)
+		}
+		if r.Max != nil {
+			terms = append(terms, "products.price <= ?")
+			args = append(args, *r.Max)
+		}
+		if len(terms) == 0 {
+			terms = append(terms, "1 = 1")
+		}
+		conditions = append(conditions, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// priceRangesQuery returns an OpenSearch filter matching products priced
+// within any of the ranges
+func priceRangesQuery(ranges []PriceRange) map[string]interface{} {
+	should := make([]map[string]interface{}, 0, len(ranges))
+	for _, r := range ranges {
+		filter := []map[string]interface{}{}
+		if r.Currency != "" {
+			filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"price.currency": r.Currency}})
+		}
+
+		bounds := map[string]interface{}{}
+		if r.Min != nil {
+			bounds["gte"] = *r.Min
+		}
+		if r.Max != nil {
+			bounds["lte"] = *r.Max
+		}
+		if len(bounds) > 0 {
+			filter = append(filter, map[string]interface{}{"range": map[string]interface{}{"price.amount": bounds}})
+		}
+
+		should = append(should, map[string]interface{}{"bool": map[string]interface{}{"filter": filter}})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+var (
+	// ErrPromotionExists is returned when creating a promotion with the ID of
+	// an existing one
+	ErrPromotionExists = errors.New("promotion already exists")
+	// ErrPromotionNotFound is returned for unknown promotion IDs
+	ErrPromotionNotFound = errors.New("promotion not found")
+)
+
+// PromotionRepository interface for repositories that store promotions
+type PromotionRepository interface {
+	// GetPromotions returns every promotion, whether running or not, in ID
+	// order
+	GetPromotions(ctx context.Context) ([]model.Promotion, error)
+	CreatePromotion(promotion *model.Promotion, ctx context.Context) error
+	DeletePromotion(id string, ctx context.Context) error
+}
+
+func (db *Database) GetPromotions(ctx context.Context) ([]model.Promotion, error) {
+	promotions := []model.Promotion{}
+	if err := db.reads().WithContext(ctx).Order("id").Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (db *Database) CreatePromotion(promotion *model.Promotion, ctx context.Context) error {
+	defer db.markWrite()
+
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Promotion{}).Where("id = ?", promotion.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPromotionExists
+		}
+
+		return tx.Create(promotion).Error
+	})
+}
+
+func (db *Database) DeletePromotion(id string, ctx context.Context) error {
+	defer db.markWrite()
+
+	r := db.DB.WithContext(ctx).Delete(&model.Promotion{}, "id = ?", id)
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.RowsAffected == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
+
+// Sale is the products that running promotions apply to, those with the IDs
+// and those with any of the tags
+type Sale struct {
+	ProductIDs []string
+	Tags       []string
+}
+
+// String describes the products on sale, for cache keys
+func (s Sale) String() string {
+	ids, tags := slices.Sorted(slices.Values(s.ProductIDs)), slices.Sorted(slices.Values(s.Tags))
+	return strings.Join(ids, ",") + ";" + strings.Join(tags, ",")
+}
+
+// saleCondition returns an SQL condition matching products on sale, or those
+// that aren't
+func saleCondition(onSale bool, sale Sale) (string, []interface{}) {
+	conditions, args := []string{}, []interface{}{}
+	if len(sale.ProductIDs) > 0 {
+		conditions = append(conditions, "products.id IN ?")
+		args = append(args, sale.ProductIDs)
+	}
+	if len(sale.Tags) > 0 {
+		conditions = append(conditions, "products.id IN (SELECT product_id FROM product_tags WHERE tag_name IN ?)")
+		args = append(args, sale.Tags)
+	}
+
+	switch {
+	case len(conditions) == 0 && onSale:
+		return "1 = 0", args
+	case len(conditions) == 0:
+		return "1 = 1", args
+	case onSale:
+		return "(" + strings.Join(conditions, " OR ") + ")", args
+	default:
+		return "NOT (" + strings.Join(conditions, " OR ") + ")", args
+	}
+}
+
+// saleQuery returns an OpenSearch filter matching products on sale, or those
+// that aren't
+func saleQuery(onSale bool, sale Sale) map[string]interface{} {
+	should := []map[string]interface{}{}
+	if len(sale.ProductIDs) > 0 {
+		should = append(should, map[string]interface{}{"terms": map[string]interface{}{"id": sale.ProductIDs}})
+	}
+	if len(sale.Tags) > 0 {
+		should = append(should, map[string]interface{}{"terms": map[string]interface{}{"tags": sale.Tags}})
+	}
+
+	if onSale {
+		return map[string]interface{}{"bool": map[string]interface{}{"should": should, "minimum_should_match": 1}}
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"must_not": should}}
+}
@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+const (
+	roleWriter = "writer"
+	roleReader = "reader"
+)
+
+var dbQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "catalog_db_queries_total",
+	Help: "Number of SQL statements executed, by database role",
+}, []string{"role"})
+
+// useReader routes read queries to a replica connection, except within the
+// lag tolerance window following a write
+func (db *Database) useReader(reader *gorm.DB, lagTolerance time.Duration) error {
+	if err := reader.Use(tracing.NewPlugin(tracing.WithoutMetrics())); err != nil {
+		return err
+	}
+
+	if err := registerQueryMetrics(reader, roleReader); err != nil {
+		return err
+	}
+
+	db.reader = reader
+	db.lagTolerance = lagTolerance
+
+	return nil
+}
+
+// reads returns the connection that read queries should use
+func (db *Database) reads() *gorm.DB {
+	if db.reader == nil {
+		return db.DB
+	}
+
+	if time.Since(time.Unix(0, db.lastWrite.Load())) < db.lagTolerance {
+		return db.DB
+	}
+
+	return db.reader
+}
+
+func (db *Database) markWrite() {
+	db.lastWrite.Store(time.Now().UnixNano())
+}
+
+// registerQueryMetrics counts and times every statement executed through the
+// connection
+func registerQueryMetrics(db *gorm.DB, role string) error {
+	counter := dbQueries.WithLabelValues(role)
+
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Query().Before("gorm:query").Register("catalog:query_start", startStatement),
+		callbacks.Query().After("gorm:query").Register("catalog:query_metrics", statementMetrics(counter, "query")),
+		callbacks.Create().Before("gorm:create").Register("catalog:create_start", startStatement),
+		callbacks.Create().After("gorm:create").Register("catalog:create_metrics", statementMetrics(counter, "create")),
+		callbacks.Update().Before("gorm:update").Register("catalog:update_start", startStatement),
+		callbacks.Update().After("gorm:update").Register("catalog:update_metrics", statementMetrics(counter, "update")),
+		callbacks.Delete().Before("gorm:delete").Register("catalog:delete_start", startStatement),
+		callbacks.Delete().After("gorm:delete").Register("catalog:delete_metrics", statementMetrics(counter, "delete")),
+		callbacks.Row().Before("gorm:row").Register("catalog:row_start", startStatement),
+		callbacks.Row().After("gorm:row").Register("catalog:row_metrics", statementMetrics(counter, "row")),
+		callbacks.Raw().Before("gorm:raw").Register("catalog:raw_start", startStatement),
+		callbacks.Raw().After("gorm:raw").Register("catalog:raw_metrics", statementMetrics(counter, "raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const (
+	statementStartKey   = "catalog:statement_start"
+	statementReleaseKey = "catalog:statement_release"
+)
+
+// startStatement records when a statement started, and fails it before it
+// runs if the database bulkhead is full or a fault is being injected into
+// the database. The statement's slot in the bulkhead is held until it has
+// run, including the statements loading its associations.
+func startStatement(db *gorm.DB) {
+	db.InstanceSet(statementStartKey, time.Now())
+
+	original := db.Statement.Context
+	ctx, release, err := acquireBulkhead(DependencyDatabase, original)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.Statement.Context = ctx
+	db.InstanceSet(statementReleaseKey, func() {
+		release()
+		db.Statement.Context = original
+	})
+
+	if err := injectFault(DependencyDatabase, db.Statement.Context); err != nil {
+		db.AddError(err)
+	}
+}
+
+// statementMetrics returns a callback recording a statement of the given kind
+// once it has run. Finding no record isn't counted as a failure, as lookups
+// of missing products are expected.
+func statementMetrics(counter prometheus.Counter, kind string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		counter.Inc()
+
+		if release, ok := db.InstanceGet(statementReleaseKey); ok {
+			release.(func())()
+		}
+
+		if started, ok := db.InstanceGet(statementStartKey); ok {
+			failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+			observeOperation("database", kind, started.(time.Time), failed, db.Statement.Context)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// CatalogFactory creates a CatalogRepository from the persistence configuration
+type CatalogFactory func(config config.DatabaseConfiguration) (CatalogRepository, error)
+
+// SearchFactory creates a SearchRepository, receiving the catalog repository
+// so that providers can build on top of the primary store
+type SearchFactory func(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error)
+
+// UnknownProviderError is returned when no provider is registered under the
+// configured name, which retrying can't fix
+type UnknownProviderError struct {
+	Kind      string
+	Name      string
+	Available []string
+}
+
+func (e *UnknownProviderError) Error() string {
+	return fmt.Sprintf("unknown %s provider %q, available providers: %s", e.Kind, e.Name, strings.Join(e.Available, ", "))
+}
+
+var (
+	providersMu      sync.RWMutex
+	catalogProviders = make(map[string]CatalogFactory)
+	searchProviders  = make(map[string]SearchFactory)
+)
+
+// Register makes a persistence provider available by name. It is intended to
+// be called from the init function of the file implementing the provider, and
+// panics if the name is registered twice.
+func Register(name string, factory CatalogFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("repository: Register factory is nil")
+	}
+	if _, dup := catalogProviders[name]; dup {
+		panic("repository: Register called twice for provider " + name)
+	}
+	catalogProviders[name] = factory
+}
+
+// RegisterSearch makes a search provider available by name. It is intended to
+// be called from the init function of the file implementing the provider, and
+// panics if the name is registered twice.
+func RegisterSearch(name string, factory SearchFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("repository: RegisterSearch factory is nil")
+	}
+	if _, dup := searchProviders[name]; dup {
+		panic("repository: RegisterSearch called twice for provider " + name)
+	}
+	searchProviders[name] = factory
+}
+
+// CatalogProviders returns the sorted names of the registered persistence providers
+func CatalogProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	return sortedKeys(catalogProviders)
+}
+
+// SearchProviders returns the sorted names of the registered search providers
+func SearchProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	return sortedKeys(searchProviders)
+}
+
+// NewRepository creates the persistence provider selected by the configuration
+func NewRepository(config config.DatabaseConfiguration) (CatalogRepository, error) {
+	providersMu.RLock()
+	factory, ok := catalogProviders[config.Type]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, &UnknownProviderError{Kind: "persistence", Name: config.Type, Available: CatalogProviders()}
+	}
+
+	return factory(config)
+}
+
+// NewSearchRepository creates the search provider selected by the configuration.
+// It returns nil without an error when search is not enabled.
+func NewSearchRepository(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+	name := SearchBackend(config)
+	if name == "" {
+		return nil, nil
+	}
+
+	return newSearchProvider(name, config, catalog)
+}
+
+// newSearchProvider creates a search provider by name
+func newSearchProvider(name string, config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+	providersMu.RLock()
+	factory, ok := searchProviders[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, &UnknownProviderError{Kind: "search", Name: name, Available: SearchProviders()}
+	}
+
+	return factory(config, catalog)
+}
+
+// SearchBackend resolves the name of the search provider to use. An explicit
+// backend always wins, otherwise OpenSearch is used when search is enabled and
+// the SQLite full-text index is used when the catalog is stored in SQLite.
+func SearchBackend(config config.AppConfiguration) string {
+	if config.Search.Backend != "" {
+		return config.Search.Backend
+	}
+
+	if config.OpenSearch.Enabled {
+		return "opensearch"
+	}
+
+	if config.Database.Type == "sqlite" {
+		return "sqlite"
+	}
+
+	return ""
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
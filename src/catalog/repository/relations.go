@@ -0,0 +1,121 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// RelationRepository interface for repositories that store relations
+// between products. Setting the relations of a product replaces those it
+// has, and the product is returned with them.
+type RelationRepository interface {
+	SetRelations(productID string, relations []model.ProductRelation, ctx context.Context) (*model.Product, error)
+}
+
+// preloadRelations loads the relations of the products a query returns, by
+// type and then related product
+func preloadRelations(query *gorm.DB) *gorm.DB {
+	return query.Preload("Related", func(db *gorm.DB) *gorm.DB {
+		return db.Order("product_relations.type asc").Order("product_relations.related_id asc")
+	})
+}
+
+// SetRelations replaces the relations of a product in one transaction,
+// checking the products it is related to exist
+func (db *Database) SetRelations(productID string, relations []model.ProductRelation, ctx context.Context) (*model.Product, error) {
+	defer db.markWrite()
+
+	var product model.Product
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		exists, err := productExists(tx, productID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrProductNotFound
+		}
+
+		ids := make([]string, 0, len(relations))
+		for i, relation := range relations {
+			if relation.RelatedID == productID {
+				return fmt.Errorf("%w: %s can't be related to itself", ErrInvalidRelation, productID)
+			}
+			relations[i].ProductID = productID
+			ids = append(ids, relation.RelatedID)
+		}
+
+		var found []string
+		if len(ids) > 0 {
+			if err := tx.Model(&model.Product{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+				return err
+			}
+		}
+		known := toSet(found)
+		for _, id := range ids {
+			if !known[id] {
+				return fmt.Errorf("%w: %s is related to %s, which doesn't exist", ErrInvalidRelation, productID, id)
+			}
+		}
+
+		if err := tx.Where("product_id = ?", productID).Delete(&model.ProductRelation{}).Error; err != nil {
+			return err
+		}
+		if len(relations) > 0 {
+			if err := tx.Create(&relations).Error; err != nil {
+				return err
+			}
+		}
+
+		err = preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(tx.Preload("Tags").Preload("Category")))))).
+			Where("id = ?", productID).
+			First(&product).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeUpdated, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// loadRelations loads the stored relations of a product, which aren't
+// changed with its other fields
+func loadRelations(tx *gorm.DB, product *model.Product) error {
+	relations := []model.ProductRelation{}
+	err := tx.Where("product_id = ?", product.ID).Order("type asc").Order("related_id asc").Find(&relations).Error
+	if err != nil {
+		return err
+	}
+	product.Related = relations
+	return nil
+}
+
+// deleteRelations removes the relations of a product, and those of other
+// products to it
+func deleteRelations(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ? OR related_id = ?", productID, productID).Delete(&model.ProductRelation{}).Error
+}
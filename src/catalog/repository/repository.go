@@ -2,102 +2,453 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws-containers/retail-store-sample-app/catalog/config"
 	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/secrets"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/plugin/opentelemetry/tracing"
 )
 
 type Database struct {
 	DB *gorm.DB
+
+	// reader is an optional replica connection used for read queries
+	reader       *gorm.DB
+	lagTolerance time.Duration
+	lastWrite    atomic.Int64
 }
 
 type CatalogRepository interface {
-	GetProducts(tags []string, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error)
-	CountProducts(tags []string, ctx context.Context) (int, error)
+	GetProducts(filter ProductFilter, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error)
+	GetProductsAfter(filter ProductFilter, order string, after ProductPosition, pageSize int, ctx context.Context) ([]model.Product, error)
+	CountProducts(filter ProductFilter, ctx context.Context) (int, error)
 	GetProduct(id string, ctx context.Context) (*model.Product, error)
+	GetProductsByIDs(ids []string, ctx context.Context) ([]model.Product, error)
 	GetTags(ctx context.Context) ([]model.Tag, error)
+	GetCategories(ctx context.Context) ([]model.Category, error)
+	CountProductsByTag(ctx context.Context) (map[string]int, error)
+}
+
+// TagCounter interface for search repositories that can count products per
+// tag, so that the counts can be taken from the index rather than the database
+type TagCounter interface {
+	CountProductsByTag(ctx context.Context) (map[string]int, error)
 }
 
-func createMySQLDatabase(config config.DatabaseConfiguration) (*gorm.DB, error) {
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s)/%s?timeout=%ds&charset=utf8mb4&parseTime=True&loc=Local", config.User, config.Password, config.Endpoint, config.Name, config.ConnectTimeout)
+// ProductFilter narrows the products returned by GetProducts and CountProducts.
+// Products match if they have any of the tags, a price within the range, and
+// are in the category or one of its descendants, with a variant that has the
+// variant attribute values. MinPrice and MaxPrice compare
+// amounts whatever their currency, while Prices, when set, matches products
+// priced within the range given for their currency.
+type ProductFilter struct {
+	Tags     []string
+	MinPrice *int
+	MaxPrice *int
+	Prices   []PriceRange
+	Category string
+	// Variants are attribute values that one of the product's variants must
+	// all have
+	Variants map[string]string
+	// InStock, when set, matches products with or without stock
+	InStock *bool
+	// Statuses are those the products may have, any status if it's empty
+	Statuses []string
+}
 
-	var db *gorm.DB
-	var err error
+// ProductPosition is a product's place in an ordering. GetProductsAfter
+// continues from it, so that pages don't shift when products are added or
+// removed between requests.
+type ProductPosition struct {
+	Name      string    `json:"name,omitempty"`
+	Price     int       `json:"price,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+	ID        string    `json:"id"`
+}
 
-	for i := 0; i < 6; i++ {
-		db, err = gorm.Open(mysql.Open(connectionString), &gorm.Config{})
-		if err == nil {
-			return db, nil
+// PositionOf returns the position of a product in any ordering
+func PositionOf(product model.Product) ProductPosition {
+	return ProductPosition{Name: product.Name, Price: product.Price.Amount, CreatedAt: product.CreatedAt, ID: product.ID}
+}
+
+// Orders accepted by GetProducts, anything else sorts by name. OrderNewest
+// is also accepted by SearchQuery.Order.
+const (
+	OrderNameAsc   = "name_asc"
+	OrderNameDesc  = "name_desc"
+	OrderPriceAsc  = "price_asc"
+	OrderPriceDesc = "price_desc"
+	OrderNewest    = "newest"
+)
+
+// CatalogWriter interface for repositories that accept product changes
+type CatalogWriter interface {
+	CreateProduct(product *model.Product, ctx context.Context) error
+	UpdateProduct(product *model.Product, ctx context.Context) error
+	DeleteProduct(id string, ctx context.Context) error
+}
+
+// ProductPatcher interface for repositories that can apply a change to a
+// product atomically. The function is given the stored product to modify,
+// and nothing is saved if it returns an error.
+type ProductPatcher interface {
+	PatchProduct(id string, fn func(product *model.Product) error, ctx context.Context) (*model.Product, error)
+}
+
+// Pinger interface for repositories that can check their backing store is
+// reachable, for readiness checks
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// StatusReporter interface for repositories that can describe the state of
+// their backing store in more detail than Pinger, for the detailed health
+// report. The details are returned along with any error making it unhealthy.
+type StatusReporter interface {
+	Status(ctx context.Context) (map[string]any, error)
+}
+
+// CatalogResetter interface for repositories that can be restored to the
+// bundled seed data
+type CatalogResetter interface {
+	ResetCatalog(ctx context.Context) error
+}
+
+// WritableCatalogRepository is a CatalogRepository that also accepts changes
+type WritableCatalogRepository interface {
+	CatalogRepository
+	CatalogWriter
+}
+
+var (
+	// ErrProductExists is returned when creating a product whose ID is taken
+	ErrProductExists = errors.New("product already exists")
+	// ErrProductNotFound is returned when changing a product that does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrUnknownTag is returned when a product references a tag that does not exist
+	ErrUnknownTag = errors.New("unknown tag")
+	// ErrUnknownCategory is returned when a product references a category that does not exist
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrCategoryNotFound is returned when a requested category does not exist
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrVersionConflict is returned when changing a product that has been
+	// changed since the version the change was based on
+	ErrVersionConflict = errors.New("the product has been changed by another request")
+	// ErrSKUExists is returned when a product is given a variant whose SKU
+	// another variant already has
+	ErrSKUExists = errors.New("SKU already exists")
+	// ErrVariantNotFound is returned when changing a variant that does not exist
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrInsufficientStock is returned when a stock adjustment would leave
+	// fewer than no units
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidStatusTransition is returned when a product is given a
+	// status it can't move to from its current one
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	// ErrEANExists is returned when a product is given the EAN of another
+	ErrEANExists = errors.New("EAN already exists")
+	// ErrInvalidEAN is returned when a product is given an EAN whose check
+	// digit is wrong
+	ErrInvalidEAN = errors.New("invalid EAN")
+	// ErrInvalidRelation is returned when a product is related to itself or
+	// to a product that does not exist
+	ErrInvalidRelation = errors.New("invalid product relation")
+)
+
+func createMySQLDatabase(config config.DatabaseConfiguration, endpoint string, source secrets.Source) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+
+	if config.IAMAuth {
+		var err error
+		dialector, err = newIAMAuthDialector(config, endpoint)
+		if err != nil {
+			return nil, err
+		}
+	} else if source != nil {
+		var err error
+		dialector, err = newSecretDialector(config, endpoint, source)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Printf("Waiting for MySQL to be ready... (%v)\n", err)
-		time.Sleep(5 * time.Second)
+	} else {
+		connectionString := fmt.Sprintf("%s:%s@tcp(%s)/%s?timeout=%ds&charset=utf8mb4&parseTime=True&loc=Local", config.User, config.Password, endpoint, config.Name, config.ConnectTimeout)
+		dialector = mysql.Open(connectionString)
 	}
 
-	return nil, err
+	// Retried by WaitFor when MySQL isn't ready yet
+	return gorm.Open(dialector, &gorm.Config{})
 }
 
-func NewRepository(config config.DatabaseConfiguration) (CatalogRepository, error) {
-	var db *gorm.DB
-	var err error
+func init() {
+	Register("in-memory", newInMemoryRepository)
+	Register("mysql", newMySQLRepository)
+}
 
-	if config.Type == "mysql" {
-		fmt.Printf("Using mysql database %s\n", config.Endpoint)
-		db, err = createMySQLDatabase(config)
-	} else {
-		fmt.Println("Using in-memory database")
-		db, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+func newInMemoryRepository(config config.DatabaseConfiguration) (CatalogRepository, error) {
+	slog.Info("Using in-memory database")
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	return newDatabase(db)
+}
+
+func newMySQLRepository(config config.DatabaseConfiguration) (CatalogRepository, error) {
+	slog.Info("Using mysql database", "endpoint", config.Endpoint)
+
+	// The writer and reader share the source so the secret is read once
+	source, err := newDatabaseSecretSource(config)
+	if err != nil {
+		return nil, err
 	}
 
+	db, err := createMySQLDatabase(config, config.Endpoint, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
+	repo, err := newDatabase(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ReaderEndpoint != "" {
+		slog.Info("Using mysql reader endpoint", "endpoint", config.ReaderEndpoint)
+
+		reader, err := createMySQLDatabase(config, config.ReaderEndpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect reader database: %w", err)
+		}
+
+		if err := repo.useReader(reader, config.ReplicaLagTolerance); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+func newDatabase(db *gorm.DB) (*Database, error) {
+	if err := initializeDatabase(db); err != nil {
+		return nil, err
+	}
+
+	if err := registerQueryMetrics(db, roleWriter); err != nil {
+		return nil, err
+	}
+
+	return &Database{
+		DB: db,
+	}, nil
+}
+
+// initializeDatabase migrates the schema and loads the bundled seed data
+func initializeDatabase(db *gorm.DB) error {
 	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics())); err != nil {
 		panic(err)
 	}
 
-	fmt.Println("Running database migration...")
+	slog.Info("Running database migration")
+
+	// Prices were stored in whole dollars before they had a currency
+	legacyPrices := db.Migrator().HasTable(&model.Product{}) && !db.Migrator().HasColumn(&model.Product{}, "currency")
+	// and stock wasn't tracked at all
+	untrackedStock := db.Migrator().HasTable(&model.Product{}) && !db.Migrator().HasColumn(&model.Product{}, "stock")
+	// nor were price changes
+	untrackedPrices := db.Migrator().HasTable(&model.Product{}) && !db.Migrator().HasTable(&model.PriceChange{})
+	// nor when products were created and updated
+	untrackedTimes := db.Migrator().HasTable(&model.Product{}) && !db.Migrator().HasColumn(&model.Product{}, "created_at")
+	// and products had no slugs
+	unslugged := db.Migrator().HasTable(&model.Product{}) && !db.Migrator().HasColumn(&model.Product{}, "slug")
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Product{})
+	db.AutoMigrate(&model.Category{}, &model.Product{}, &model.Variant{}, &model.VariantAttribute{}, &model.ProductImage{}, &model.ProductAttribute{}, &model.ProductTranslation{}, &model.ProductReview{}, &model.ProductChange{}, &model.PriceChange{}, &model.Promotion{}, &model.ProductRelation{}, &model.ProductSlug{})
+
+	if legacyPrices {
+		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Product{}).Update("price", gorm.Expr("price * ?", model.MinorUnits(model.DefaultCurrency)))
+		if r.Error != nil {
+			return fmt.Errorf("failed to convert prices to %s minor units: %w", model.DefaultCurrency, r.Error)
+		}
+		slog.Info("Converted prices to minor units", "currency", model.DefaultCurrency, "products", r.RowsAffected)
+	}
+
+	if untrackedStock {
+		if err := seedStock(db); err != nil {
+			return fmt.Errorf("failed to set the stock of bundled products: %w", err)
+		}
+	}
+
+	if untrackedPrices {
+		if err := seedPriceHistory(db); err != nil {
+			return fmt.Errorf("failed to record the prices of existing products: %w", err)
+		}
+	}
+
+	// Existing products are taken to have been created when they are first
+	// seen with timestamps
+	if untrackedTimes {
+		now := db.NowFunc()
+		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Model(&model.Product{}).
+			UpdateColumns(map[string]interface{}{"created_at": now, "updated_at": now})
+		if r.Error != nil {
+			return fmt.Errorf("failed to set the creation time of existing products: %w", r.Error)
+		}
+		slog.Info("Set the creation time of existing products", "products", r.RowsAffected)
+	}
+
+	if unslugged {
+		if err := seedSlugs(db); err != nil {
+			return fmt.Errorf("failed to make slugs for existing products: %w", err)
+		}
+	}
 
-	fmt.Println("Database migration complete")
+	slog.Info("Database migration complete")
 
+	return seedDatabase(db)
+}
+
+// seedDatabase loads the bundled categories, tags and products, leaving
+// products that already exist unchanged
+func seedDatabase(db *gorm.DB) error {
 	products, err := LoadProductData()
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return nil, err
+		return err
 	}
 
 	tags, err := LoadProductTagData()
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return nil, err
+		return err
+	}
+
+	categoryData, err := LoadCategoryData()
+	if err != nil {
+		return err
+	}
+
+	categories, err := categoryModels(categoryData)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		if err := db.Save(category).Error; err != nil {
+			return err
+		}
 	}
 
 	tagMap := make(map[string]model.Tag)
 
 	for _, tag := range tags {
 		tagEntity := model.Tag{Name: tag.Name, DisplayName: tag.DisplayName}
-		db.Save(tagEntity)
+		if err := db.Save(tagEntity).Error; err != nil {
+			return err
+		}
 
 		tagMap[tag.Name] = tagEntity
 	}
 
 	for _, product := range products {
+		// Deleted products aren't seeded again
 		var result model.Product
-		r := db.
+		r := db.Unscoped().
 			Where("id = ?", product.ID).
 			Limit(1).
 			Find(&result)
+		if r.Error != nil {
+			return r.Error
+		}
 
 		if r.RowsAffected > 0 {
+			if result.DeletedAt.Valid {
+				continue
+			}
+
+			// Products seeded before categories were introduced get theirs now
+			if result.CategoryName == nil && product.Category != "" {
+				if err := db.Model(&result).Update("category_name", product.Category).Error; err != nil {
+					return err
+				}
+			}
+
+			// As do those seeded before variants were
+			if len(product.Variants) > 0 {
+				var count int64
+				if err := db.Model(&model.Variant{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					result.Variants = seedVariants(product)
+					if err := saveVariants(db, &result); err != nil {
+						return err
+					}
+				}
+			}
+
+			// and images
+			if len(product.Images) > 0 {
+				var count int64
+				if err := db.Model(&model.ProductImage{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					result.Images = seedImages(product)
+					if err := saveImages(db, &result); err != nil {
+						return err
+					}
+				}
+			}
+
+			// and attributes
+			if len(product.Attributes) > 0 {
+				var count int64
+				if err := db.Model(&model.ProductAttribute{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					result.Attributes = seedAttributes(product)
+					if err := saveAttributes(db, &result); err != nil {
+						return err
+					}
+				}
+			}
+
+			// and translations
+			if len(product.Translations) > 0 {
+				var count int64
+				if err := db.Model(&model.ProductTranslation{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					result.Translations = seedTranslations(product)
+					if err := saveTranslations(db, &result); err != nil {
+						return err
+					}
+				}
+			}
+
+			// and reviews
+			if len(product.Reviews) > 0 {
+				var count int64
+				if err := db.Model(&model.ProductReview{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					if err := seedReviews(db, product); err != nil {
+						return err
+					}
+				}
+			}
 			continue
 		}
 
@@ -106,43 +457,171 @@ func NewRepository(config config.DatabaseConfiguration) (CatalogRepository, erro
 			productTags = append(productTags, tagMap[tag])
 		}
 
-		db.Create(&model.Product{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			Tags:        productTags,
-		})
+		entity := &model.Product{
+			ID:           product.ID,
+			Name:         product.Name,
+			Description:  product.Description,
+			Price:        product.Price,
+			Tags:         productTags,
+			Variants:     seedVariants(product),
+			Images:       seedImages(product),
+			Attributes:   seedAttributes(product),
+			Translations: seedTranslations(product),
+			Stock:        product.stock(),
+			Status:       model.StatusActive,
+		}
+		if product.Category != "" {
+			entity.CategoryName = &product.Category
+		}
+		if err := assignSlug(db, entity, ""); err != nil {
+			return err
+		}
+
+		if err := db.Create(entity).Error; err != nil {
+			return err
+		}
+		if err := recordPrice(db, entity.ID, entity.Price); err != nil {
+			return err
+		}
+		if err := seedReviews(db, product); err != nil {
+			return err
+		}
 	}
 
-	return &Database{
-		DB: db,
-	}, nil
+	return nil
 }
 
-func (db *Database) GetProducts(tags []string, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
-	products := []model.Product{}
+// seedStock gives products that were stored before stock was tracked the
+// stock of the bundled products, so that they don't show as out of stock
+func seedStock(db *gorm.DB) error {
+	products, err := LoadProductData()
+	if err != nil {
+		return err
+	}
 
-	query := db.DB.Preload("Tags")
+	for _, product := range products {
+		stock := product.stock()
+		if stock == nil {
+			continue
+		}
+		if err := db.Model(&model.Product{}).Where("id = ?", product.ID).Update("stock", *stock).Error; err != nil {
+			return err
+		}
+	}
 
-	// Apply tags filter if provided
-	if len(tags) > 0 {
-		query = query.Joins("JOIN product_tags ON product_tags.product_id = products.id").
-			Joins("JOIN tags ON tags.name = product_tags.tag_name").
-			Where("tags.name IN ?", tags).
-			Group("products.id")
+	slog.Info("Set the stock of bundled products")
+	return nil
+}
+
+// seedVariants returns the variants of a bundled product
+func seedVariants(product ProductData) []model.Variant {
+	variants := make([]model.Variant, len(product.Variants))
+	for i, variant := range product.Variants {
+		variants[i] = variant.ToVariant(product.ID, product.Price)
 	}
+	return variants
+}
 
-	// Apply ordering
-	switch order {
-	case "price_asc":
-		query = query.Order("price asc")
-	case "price_desc":
-		query = query.Order("price desc")
-	default:
-		query = query.Order("products.name asc") // default ordering
+// Close closes the connections to the primary database and the replica
+func (db *Database) Close() error {
+	connections := []*gorm.DB{db.DB}
+	if db.reader != nil {
+		connections = append(connections, db.reader)
+	}
+
+	errs := []error{}
+	for _, conn := range connections {
+		sqlDB, err := conn.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Status reports how long the primary database, and the replica when one is
+// configured, take to answer a ping, along with their connection pools
+func (db *Database) Status(ctx context.Context) (map[string]any, error) {
+	connections := map[string]*gorm.DB{"primary": db.DB}
+	if db.reader != nil {
+		connections["reader"] = db.reader
+	}
+
+	details := map[string]any{}
+	errs := []error{}
+	for role, conn := range connections {
+		sqlDB, err := conn.DB()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s database: %w", role, err))
+			continue
+		}
+
+		started := time.Now()
+		err = sqlDB.PingContext(ctx)
+		stats := sqlDB.Stats()
+		details[role] = map[string]any{
+			"pingLatencyMs":   time.Since(started).Milliseconds(),
+			"openConnections": stats.OpenConnections,
+			"inUse":           stats.InUse,
+			"idle":            stats.Idle,
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s database: %w", role, err))
+		}
+	}
+
+	return details, errors.Join(errs...)
+}
+
+// Ping checks the primary database, and the replica when one is configured,
+// accept connections
+func (db *Database) Ping(ctx context.Context) error {
+	connections := map[string]*gorm.DB{"primary": db.DB}
+	if db.reader != nil {
+		connections["reader"] = db.reader
 	}
 
+	for role, conn := range connections {
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return fmt.Errorf("%s database: %w", role, err)
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return fmt.Errorf("%s database: %w", role, err)
+		}
+	}
+
+	return nil
+}
+
+// ResetCatalog deletes every product, tag and category and loads the bundled
+// seed data again, in one transaction
+func (db *Database) ResetCatalog(ctx context.Context) error {
+	defer db.markWrite()
+
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"variant_attributes", "variants", "product_images", "product_attributes", "product_translations", "product_reviews", "product_relations", "product_slugs", "price_changes", "product_tags", "products", "tags", "categories"} {
+			if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+				return fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+
+		if err := seedDatabase(tx); err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeReset, "")
+	})
+}
+
+func (db *Database) GetProducts(filter ProductFilter, order string, pageNum, pageSize int, ctx context.Context) ([]model.Product, error) {
+	products := []model.Product{}
+
+	query := applyFilter(preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(db.reads().Preload("Tags").Preload("Category")))))), filter)
+	query = applyOrder(query, order)
+
 	// Apply pagination
 	offset := (pageNum - 1) * pageSize
 	query = query.Offset(offset).Limit(pageSize)
@@ -156,11 +635,60 @@ func (db *Database) GetProducts(tags []string, order string, pageNum, pageSize i
 	return products, err
 }
 
+// GetProductsAfter returns the page of products that follows the position in
+// the given order
+func (db *Database) GetProductsAfter(filter ProductFilter, order string, after ProductPosition, pageSize int, ctx context.Context) ([]model.Product, error) {
+	products := []model.Product{}
+
+	query := applyFilter(preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(db.reads().Preload("Tags").Preload("Category")))))), filter)
+
+	// Rows after the position have a later sort value, or the same value and
+	// a later ID since the ID breaks ties in ascending order
+	switch order {
+	case OrderPriceAsc:
+		query = query.Where("products.price > ? OR (products.price = ? AND products.id > ?)", after.Price, after.Price, after.ID)
+	case OrderPriceDesc:
+		query = query.Where("products.price < ? OR (products.price = ? AND products.id > ?)", after.Price, after.Price, after.ID)
+	case OrderNameDesc:
+		query = query.Where("products.name < ? OR (products.name = ? AND products.id > ?)", after.Name, after.Name, after.ID)
+	case OrderNewest:
+		query = query.Where("products.created_at < ? OR (products.created_at = ? AND products.id > ?)", after.CreatedAt, after.CreatedAt, after.ID)
+	default:
+		query = query.Where("products.name > ? OR (products.name = ? AND products.id > ?)", after.Name, after.Name, after.ID)
+	}
+
+	err := applyOrder(query, order).
+		Limit(pageSize).
+		WithContext(ctx).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// applyOrder sorts products, with the ID as a tie-breaker so pages are stable
+func applyOrder(query *gorm.DB, order string) *gorm.DB {
+	switch order {
+	case OrderPriceAsc:
+		query = query.Order("products.price asc")
+	case OrderPriceDesc:
+		query = query.Order("products.price desc")
+	case OrderNameDesc:
+		query = query.Order("products.name desc")
+	case OrderNewest:
+		query = query.Order("products.created_at desc")
+	default:
+		query = query.Order("products.name asc") // default ordering
+	}
+	return query.Order("products.id asc")
+}
+
 func (db *Database) GetProduct(id string, ctx context.Context) (*model.Product, error) {
 	product := model.Product{}
 
-	err := db.DB.WithContext(ctx).
-		Preload("Tags").
+	err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(db.reads().WithContext(ctx).Preload("Tags").Preload("Category")))))).
 		Where("id = ?", id).
 		First(&product).Error
 
@@ -171,31 +699,103 @@ func (db *Database) GetProduct(id string, ctx context.Context) (*model.Product,
 	return &product, err
 }
 
-func (db *Database) CountProducts(tags []string, ctx context.Context) (int, error) {
+// GetProductsByIDs fetches the products with the given IDs in one query,
+// returning them in the order requested. IDs that don't exist are skipped.
+func (db *Database) GetProductsByIDs(ids []string, ctx context.Context) ([]model.Product, error) {
+	found := []model.Product{}
+
+	if len(ids) > 0 {
+		err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(db.reads().WithContext(ctx).Preload("Tags").Preload("Category")))))).
+			Where("products.id IN ?", ids).
+			Find(&found).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[string]model.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+
+	products := make([]model.Product, 0, len(found))
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			products = append(products, product)
+			delete(byID, id)
+		}
+	}
+
+	return products, nil
+}
+
+func (db *Database) CountProducts(filter ProductFilter, ctx context.Context) (int, error) {
 	var count int64
 
-	query := db.DB.WithContext(ctx).Model(&model.Product{})
+	query := applyFilter(db.reads().WithContext(ctx).Model(&model.Product{}), filter)
 
+	err := query.Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+// applyFilter adds the conditions for a ProductFilter to a products query
+func applyFilter(query *gorm.DB, filter ProductFilter) *gorm.DB {
 	// Apply tags filter if provided
-	if len(tags) > 0 {
+	if len(filter.Tags) > 0 {
 		query = query.Joins("JOIN product_tags ON product_tags.product_id = products.id").
 			Joins("JOIN tags ON tags.name = product_tags.tag_name").
-			Where("tags.name IN ?", tags).
+			Where("tags.name IN ?", filter.Tags).
 			Group("products.id")
 	}
 
-	err := query.Count(&count).Error
-	if err != nil {
-		return 0, err
+	if filter.MinPrice != nil {
+		query = query.Where("products.price >= ?", *filter.MinPrice)
 	}
 
-	return int(count), nil
+	if filter.MaxPrice != nil {
+		query = query.Where("products.price <= ?", *filter.MaxPrice)
+	}
+
+	if len(filter.Prices) > 0 {
+		condition, args := priceRangesCondition(filter.Prices)
+		query = query.Where(condition, args...)
+	}
+
+	if len(filter.Variants) > 0 {
+		condition, args := variantAttributesCondition(filter.Variants)
+		query = query.Where(condition, args...)
+	}
+
+	if filter.InStock != nil {
+		query = query.Where(stockCondition(*filter.InStock))
+	}
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("products.status IN ?", filter.Statuses)
+	}
+
+	// A category's descendants are those with its name as a segment of their path
+	if filter.Category != "" {
+		name := escapeLike(filter.Category)
+		sep := model.CategoryPathSeparator
+		query = query.Where("products.category_name IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Model(&model.Category{}).
+			Select("name").
+			Where("path = ? OR path LIKE ? ESCAPE '!' OR path LIKE ? ESCAPE '!' OR path LIKE ? ESCAPE '!'",
+				filter.Category, name+sep+"%", "%"+sep+name, "%"+sep+name+sep+"%"))
+	}
+
+	return query
 }
 
 func (db *Database) GetTags(ctx context.Context) ([]model.Tag, error) {
 	tags := []model.Tag{}
 
-	err := db.DB.WithContext(ctx).
+	err := db.reads().WithContext(ctx).
 		Model(&model.Tag{}).
 		Order("display_name asc"). // Order by display name alphabetically
 		Find(&tags).Error
@@ -206,3 +806,367 @@ func (db *Database) GetTags(ctx context.Context) ([]model.Tag, error) {
 
 	return tags, err
 }
+
+// CountProductsByTag returns the number of products with each tag, omitting
+// tags that no products have
+func (db *Database) CountProductsByTag(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		TagName string
+		Count   int
+	}
+
+	err := db.reads().WithContext(ctx).
+		Table("product_tags").
+		Joins("JOIN products ON products.id = product_tags.product_id AND products.deleted_at IS NULL").
+		Select("tag_name, COUNT(*) AS count").
+		Group("tag_name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products by tag: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.TagName] = row.Count
+	}
+
+	return counts, nil
+}
+
+// GetCategories returns every category, ordered so that parents come before
+// their children
+func (db *Database) GetCategories(ctx context.Context) ([]model.Category, error) {
+	categories := []model.Category{}
+
+	err := db.reads().WithContext(ctx).
+		Order("path asc").
+		Find(&categories).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (db *Database) CreateProduct(product *model.Product, ctx context.Context) error {
+	defer db.markWrite()
+
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		exists, err := productExists(tx, product.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrProductExists
+		}
+
+		// A deleted product's ID can be reused, which purges it for good
+		if err := purgeDeletedProduct(tx, product.ID); err != nil {
+			return err
+		}
+
+		tags, err := resolveTags(tx, product.Tags)
+		if err != nil {
+			return err
+		}
+		product.Tags = tags
+
+		if err := resolveCategory(tx, product); err != nil {
+			return err
+		}
+
+		if err := checkCodes(tx, product); err != nil {
+			return err
+		}
+
+		product.Version = 1
+		product.Price = product.Price.WithDefaultCurrency()
+		if product.Stock == nil {
+			product.Stock = new(int)
+		}
+		if product.Status == "" {
+			product.Status = model.StatusActive
+		}
+		if err := assignSlug(tx, product, ""); err != nil {
+			return err
+		}
+		if err := tx.Omit("Category", "Variants", "Images", "Attributes", "Translations", "Related").Create(product).Error; err != nil {
+			return err
+		}
+
+		if err := saveVariants(tx, product); err != nil {
+			return err
+		}
+		if err := saveImages(tx, product); err != nil {
+			return err
+		}
+		if err := saveAttributes(tx, product); err != nil {
+			return err
+		}
+		if err := saveTranslations(tx, product); err != nil {
+			return err
+		}
+		if err := recordPrice(tx, product.ID, product.Price); err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeCreated, product.ID)
+	})
+}
+
+// UpdateProduct replaces a product. If the product's version is set the
+// update only succeeds if it is still the stored version, otherwise
+// ErrVersionConflict is returned.
+func (db *Database) UpdateProduct(product *model.Product, ctx context.Context) error {
+	defer db.markWrite()
+
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		exists, err := productExists(tx, product.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrProductNotFound
+		}
+
+		if err := saveProduct(tx, product); err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeUpdated, product.ID)
+	})
+}
+
+// PatchProduct changes a product in a single transaction, so that the
+// changes are made to the product as it is stored and not to a stale copy
+func (db *Database) PatchProduct(id string, fn func(product *model.Product) error, ctx context.Context) (*model.Product, error) {
+	defer db.markWrite()
+
+	var product model.Product
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(forUpdate(tx).Preload("Tags").Preload("Category")))))).Where("id = ?", id).Limit(1).Find(&product)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		version := product.Version
+		if err := fn(&product); err != nil {
+			return err
+		}
+		product.ID = id
+		product.Version = version
+
+		if err := saveProduct(tx, &product); err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeUpdated, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// saveProduct writes the fields, tags and variants of an existing product and
+// moves it to the next version, checking it is still at product.Version if
+// that is set
+func saveProduct(tx *gorm.DB, product *model.Product) error {
+	product.Price = product.Price.WithDefaultCurrency()
+
+	tags, err := resolveTags(tx, product.Tags)
+	if err != nil {
+		return err
+	}
+	product.Tags = tags
+
+	if err := resolveCategory(tx, product); err != nil {
+		return err
+	}
+
+	if err := checkCodes(tx, product); err != nil {
+		return err
+	}
+
+	update := tx.Model(&model.Product{}).Where("id = ?", product.ID)
+	if product.Version != 0 {
+		update = update.Where("version = ?", product.Version)
+	}
+
+	changes := map[string]interface{}{
+		"name":          product.Name,
+		"description":   product.Description,
+		"price":         product.Price.Amount,
+		"currency":      product.Price.Currency,
+		"category_name": product.CategoryName,
+		"sku":           product.SKU,
+		"ean":           product.EAN,
+		"version":       gorm.Expr("version + 1"),
+	}
+	if product.Stock != nil {
+		changes["stock"] = *product.Stock
+	}
+
+	// The slug is only made again when the name changes
+	var slug string
+	if err := tx.Model(&model.Product{}).Where("id = ?", product.ID).Select("slug").Row().Scan(&slug); err != nil {
+		return err
+	}
+	if err := assignSlug(tx, product, slug); err != nil {
+		return err
+	}
+	changes["slug"] = product.Slug
+
+	if product.Status != "" {
+		var status string
+		if err := tx.Model(&model.Product{}).Where("id = ?", product.ID).Select("status").Row().Scan(&status); err != nil {
+			return err
+		}
+		if !model.CanTransition(status, product.Status) {
+			return fmt.Errorf("%w: %s can't move from %s to %s", ErrInvalidStatusTransition, product.ID, status, product.Status)
+		}
+		changes["status"] = product.Status
+	}
+
+	r := update.Updates(changes)
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s is no longer at version %d", ErrVersionConflict, product.ID, product.Version)
+	}
+
+	if err := recordPrice(tx, product.ID, product.Price); err != nil {
+		return err
+	}
+
+	// The stored rating is kept, since it's calculated from the reviews, and
+	// so is the creation time
+	var stock int
+	err = tx.Model(&model.Product{}).Where("id = ?", product.ID).
+		Select("version", "stock", "status", "rating_average", "rating_count", "created_at", "updated_at").
+		Row().Scan(&product.Version, &stock, &product.Status, &product.Rating.Average, &product.Rating.Count, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	product.Stock = &stock
+
+	association := tx.Model(&model.Product{ID: product.ID}).Association("Tags")
+	if len(tags) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(tags)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := saveVariants(tx, product); err != nil {
+		return err
+	}
+	if err := saveImages(tx, product); err != nil {
+		return err
+	}
+	if err := saveAttributes(tx, product); err != nil {
+		return err
+	}
+	if err := saveTranslations(tx, product); err != nil {
+		return err
+	}
+	return loadRelations(tx, product)
+}
+
+// forUpdate locks the rows read by a query until the transaction ends. SQLite
+// has no row locks, but only allows one write transaction at a time anyway.
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialector.Name() == "sqlite" {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// DeleteProduct soft deletes a product, which is then left out of every
+// query but keeps its variants, reviews and everything else so that it can
+// be restored
+func (db *Database) DeleteProduct(id string, ctx context.Context) error {
+	defer db.markWrite()
+
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r := tx.Delete(&model.Product{}, "id = ?", id)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		return recordChange(tx, model.ChangeDeleted, id)
+	})
+}
+
+func productExists(tx *gorm.DB, id string) (bool, error) {
+	var count int64
+	if err := tx.Model(&model.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// resolveTags replaces the tags referenced by name with the stored tag records
+func resolveTags(tx *gorm.DB, tags []model.Tag) ([]model.Tag, error) {
+	if len(tags) == 0 {
+		return []model.Tag{}, nil
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+
+	stored := []model.Tag{}
+	if err := tx.Where("name IN ?", names).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]model.Tag, len(stored))
+	for _, tag := range stored {
+		byName[tag.Name] = tag
+	}
+
+	resolved := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		tag, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTag, name)
+		}
+		resolved = append(resolved, tag)
+	}
+
+	return resolved, nil
+}
+
+// resolveCategory replaces the category referenced by name with the stored
+// category record, so that its path is known
+func resolveCategory(tx *gorm.DB, product *model.Product) error {
+	if product.CategoryName == nil {
+		product.Category = nil
+		return nil
+	}
+
+	var category model.Category
+	r := tx.Where("name = ?", *product.CategoryName).Limit(1).Find(&category)
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownCategory, *product.CategoryName)
+	}
+
+	product.Category = &category
+	return nil
+}
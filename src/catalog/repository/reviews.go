@@ -0,0 +1,196 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// Search orders accepted by SearchQuery.Order, along with OrderNewest, which
+// otherwise sorts by relevance. Products with the same rating are sorted by
+// relevance.
+const (
+	OrderRatingAsc  = "rating_asc"
+	OrderRatingDesc = "rating_desc"
+)
+
+// ReviewRepository interface for repositories that store product reviews.
+// Adding a review updates the rating of the product, which is returned.
+type ReviewRepository interface {
+	AddReview(review *model.ProductReview, ctx context.Context) (*model.Product, error)
+	GetReviews(productID string, pageNum, pageSize int, ctx context.Context) ([]model.ProductReview, error)
+}
+
+// searchOrder returns the SQL ordering of products by rating or newest, or
+// "" for relevance
+func searchOrder(order string) string {
+	switch order {
+	case OrderRatingAsc:
+		return "products.rating_average asc"
+	case OrderRatingDesc:
+		return "products.rating_average desc"
+	case OrderNewest:
+		return "products.created_at desc"
+	}
+	return ""
+}
+
+// searchSort returns the OpenSearch sort of hits by rating or newest, or nil
+// for relevance. Documents indexed before products had a creation time come
+// last.
+func searchSort(order string) map[string]interface{} {
+	switch order {
+	case OrderRatingAsc:
+		return map[string]interface{}{"rating.average": map[string]interface{}{"order": "asc", "missing": "_first"}}
+	case OrderRatingDesc:
+		return map[string]interface{}{"rating.average": map[string]interface{}{"order": "desc", "missing": "_last"}}
+	case OrderNewest:
+		return map[string]interface{}{"createdAt": map[string]interface{}{"order": "desc", "missing": "_last"}}
+	}
+	return nil
+}
+
+// minRatingQuery returns an OpenSearch filter matching products with an
+// average rating of at least the given one
+func minRatingQuery(rating float64) map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{"rating.average": map[string]interface{}{"gte": rating}}}
+}
+
+// sortSearchResults sorts products by rating or newest, keeping products
+// that tie in the order they were in
+func sortSearchResults(products []model.Product, order string) {
+	switch order {
+	case OrderRatingAsc:
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Rating.Average < products[j].Rating.Average })
+	case OrderRatingDesc:
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Rating.Average > products[j].Rating.Average })
+	case OrderNewest:
+		sort.SliceStable(products, func(i, j int) bool { return products[i].CreatedAt.After(products[j].CreatedAt) })
+	}
+}
+
+// AddReview stores a review and updates the rating of the product in the
+// same transaction, so that concurrent reviews are all counted
+func (db *Database) AddReview(review *model.ProductReview, ctx context.Context) (*model.Product, error) {
+	defer db.markWrite()
+
+	var product model.Product
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		exists, err := productExists(tx, review.ProductID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrProductNotFound
+		}
+
+		review.ID = 0
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+
+		if err := updateRating(tx, review.ProductID); err != nil {
+			return err
+		}
+
+		err = preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(tx.Preload("Tags").Preload("Category")))))).
+			Where("id = ?", review.ProductID).
+			First(&product).Error
+		if err != nil {
+			return err
+		}
+
+		return recordChange(tx, model.ChangeUpdated, review.ProductID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetReviews returns a page of the reviews of a product, newest first
+func (db *Database) GetReviews(productID string, pageNum, pageSize int, ctx context.Context) ([]model.ProductReview, error) {
+	exists, err := productExists(db.reads().WithContext(ctx), productID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	reviews := []model.ProductReview{}
+	err = db.reads().WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("created_at desc").
+		Order("id desc").
+		Offset((pageNum - 1) * pageSize).
+		Limit(pageSize).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+// updateRating recalculates the rating of a product from its reviews
+func updateRating(tx *gorm.DB, productID string) error {
+	var rating model.Rating
+	err := tx.Model(&model.ProductReview{}).
+		Where("product_id = ?", productID).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Scan(&rating).Error
+	if err != nil {
+		return fmt.Errorf("failed to calculate the rating of %s: %w", productID, err)
+	}
+
+	// Columns are updated directly so that reviews don't count as product
+	// updates
+	return tx.Model(&model.Product{}).Where("id = ?", productID).UpdateColumns(map[string]interface{}{
+		"rating_average": model.RoundRating(rating.Average),
+		"rating_count":   rating.Count,
+	}).Error
+}
+
+// deleteReviews removes the reviews of a product
+func deleteReviews(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.ProductReview{}).Error
+}
+
+// seedReviews stores the reviews of a bundled product and its rating
+func seedReviews(tx *gorm.DB, product ProductData) error {
+	if len(product.Reviews) == 0 {
+		return nil
+	}
+
+	reviews := make([]model.ProductReview, len(product.Reviews))
+	for i, review := range product.Reviews {
+		reviews[i] = review.ToReview(product.ID)
+	}
+	if err := tx.Create(&reviews).Error; err != nil {
+		return err
+	}
+
+	return updateRating(tx, product.ID)
+}
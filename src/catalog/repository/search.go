@@ -0,0 +1,157 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// SearchQuery is a keyword search and the filters that narrow it down. Filters
+// that are left empty match every product, except that searches are limited
+// to active products unless Statuses or AnyStatus say otherwise.
+type SearchQuery struct {
+	Keyword string
+
+	// PriceRanges match products priced within any of the ranges
+	PriceRanges []PriceRange
+	// VariantAttributes match products with a variant that has all of the
+	// attribute values
+	VariantAttributes map[string]string
+	// InStock matches only products with stock if true, or only those without
+	// if false
+	InStock *bool
+	// Category matches products in the category or its descendants
+	Category *model.Category
+	// Attributes match products matching every one of the filters
+	Attributes []model.AttributeFilter
+	// MinRating matches products with an average rating of at least it
+	MinRating *float64
+	// OnSale matches only products on sale if true, or only those that aren't
+	// if false. Promotions are applied when products are read rather than
+	// stored with them, so Sale lists the products that are on sale. These
+	// are all the products a running promotion applies to, whether or not it
+	// makes them cheaper.
+	OnSale *bool
+	Sale   Sale
+	// Statuses match products with any of them, and AnyStatus products with
+	// any status at all
+	Statuses  []string
+	AnyStatus bool
+
+	// Locale is a language keywords are matched against the products' names
+	// and descriptions in, as well as their own
+	Locale string
+	// Order sorts results by rating or newest rather than relevance
+	Order string
+	// Fields are the product fields to load, or nil for all of them.
+	// Providers that ignore them return complete products.
+	Fields []string
+	// Facets asks for the facet counts of the matching products, which ignore
+	// InStock so that the other bucket can be offered too
+	Facets bool
+}
+
+// SearchResult is a page of the products matching a search
+type SearchResult struct {
+	Products []model.Product
+	// Facets are the counts of each facet's buckets, when the query asks for
+	// them and the provider counts them
+	Facets map[string]map[string]int
+}
+
+// LoadedFields returns the product fields to load, always including the ID
+// which is needed to merge and identify results, or nil for all fields
+func (q SearchQuery) LoadedFields() []string {
+	if len(q.Fields) == 0 {
+		return nil
+	}
+
+	if !slices.Contains(q.Fields, "id") {
+		return append([]string{"id"}, q.Fields...)
+	}
+	return q.Fields
+}
+
+// Key describes the products the query matches and the order they are in,
+// the same way whatever order its filters were given in, for keys of cached
+// results and scopes of cursors. Fields and Facets only change what is
+// loaded for each result, so they are left out.
+func (q SearchQuery) Key() string {
+	parts := []string{}
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, name+"="+value)
+		}
+	}
+
+	ranges := make([]string, len(q.PriceRanges))
+	for i, r := range q.PriceRanges {
+		ranges[i] = r.String()
+	}
+	add("prices", strings.Join(ranges, ","))
+	add("variants", DescribeVariantAttributes(q.VariantAttributes))
+	if q.InStock != nil {
+		add("inStock", strconv.FormatBool(*q.InStock))
+	}
+	if q.Category != nil {
+		add("category", q.Category.Name)
+	}
+	add("attributes", DescribeAttributeFilters(q.Attributes))
+	add("locale", q.translationLocale())
+	add("order", q.Order)
+	if q.MinRating != nil {
+		add("rating", strconv.FormatFloat(*q.MinRating, 'g', -1, 64))
+	}
+	// Searches of active products, as shoppers make, aren't described
+	switch statuses := q.statuses(); {
+	case statuses == nil:
+		add("status", "any")
+	case !slices.Equal(statuses, []string{model.StatusActive}):
+		add("status", strings.Join(statuses, ","))
+	}
+	if q.OnSale != nil {
+		add("onSale", strconv.FormatBool(*q.OnSale)+";"+q.Sale.String())
+	}
+
+	// The keyword goes last, since it may contain anything
+	return strings.Join(append(parts, q.Keyword), ":")
+}
+
+// statuses returns the statuses results may have, or nil for any status
+func (q SearchQuery) statuses() []string {
+	switch {
+	case q.AnyStatus:
+		return nil
+	case len(q.Statuses) > 0:
+		return q.Statuses
+	default:
+		return []string{model.StatusActive}
+	}
+}
+
+// translationLocale returns the language keywords are searched in besides the
+// products' own, or "" for none
+func (q SearchQuery) translationLocale() string {
+	if q.Locale == model.DefaultLocale {
+		return ""
+	}
+	return q.Locale
+}
@@ -0,0 +1,192 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/secrets"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// mysqlAccessDenied is the MySQL error number for rejected credentials
+const mysqlAccessDenied = 1045
+
+// newDatabaseSecretSource returns the source of the database credentials, or
+// nil if they are configured directly
+func newDatabaseSecretSource(config config.DatabaseConfiguration) (secrets.Source, error) {
+	return newSecretSource("database", config.PasswordFile, config.PasswordSecret, config.VaultPath, config.SecretRefreshInterval)
+}
+
+// newSearchSecretSource returns the source of the OpenSearch credentials, or
+// nil if they are configured directly
+func newSearchSecretSource(config config.OpenSearchConfiguration) (secrets.Source, error) {
+	return newSecretSource("OpenSearch", config.PasswordFile, config.PasswordSecret, config.VaultPath, config.SecretRefreshInterval)
+}
+
+func newSecretSource(service, file, secretID, vaultPath string, refreshInterval time.Duration) (secrets.Source, error) {
+	switch {
+	case (file != "" && secretID != "") || (file != "" && vaultPath != "") || (secretID != "" && vaultPath != ""):
+		return nil, fmt.Errorf("the %s credentials can be read from one of a file, Secrets Manager or Vault", service)
+	case file != "":
+		slog.Info("Reading credentials from a file", "service", service, "file", file)
+		return secrets.NewFile(file), nil
+	case secretID != "":
+		slog.Info("Reading credentials from Secrets Manager", "service", service, "secret", secretID)
+		return secrets.NewSecretsManager(secretID, refreshInterval)
+	case vaultPath != "":
+		vault, err := secrets.NewVaultFromEnvironment(vaultPath, refreshInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		// Leases are renewed for the life of the process
+		go vault.Watch(context.Background())
+
+		slog.Info("Reading credentials from Vault", "service", service, "path", vaultPath)
+		return vault, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newSecretDialector creates a MySQL dialector that authenticates every new
+// connection with the credentials from the source, so rotated credentials are
+// used as soon as the source picks them up
+func newSecretDialector(config config.DatabaseConfiguration, endpoint string, source secrets.Source) (gorm.Dialector, error) {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = config.User
+	cfg.Net = "tcp"
+	cfg.Addr = endpoint
+	cfg.DBName = config.Name
+	cfg.Timeout = time.Duration(config.ConnectTimeout) * time.Second
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+
+	err := cfg.Apply(mysqldriver.BeforeConnect(func(ctx context.Context, cfg *mysqldriver.Config) error {
+		credentials, err := source.Credentials(ctx)
+		if err != nil {
+			return err
+		}
+
+		credentials = credentials.WithDefaultUsername(config.User)
+		cfg.User = credentials.Username
+		cfg.Passwd = credentials.Password
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql connector: %w", err)
+	}
+
+	// Connections outlive the credentials they were opened with, so replace
+	// them over time to drop sessions of rotated or revoked users gracefully
+	db := sql.OpenDB(&rotatingConnector{Connector: connector, source: source})
+	db.SetConnMaxLifetime(config.SecretRefreshInterval)
+
+	return mysql.New(mysql.Config{
+		Conn: db,
+	}), nil
+}
+
+// rotatingConnector retries a connection once with credentials read again
+// from the source when MySQL denies access, which is how rotation is noticed
+// before the source's next refresh
+type rotatingConnector struct {
+	driver.Connector
+	source secrets.Source
+}
+
+func (c *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+
+	var mysqlErr *mysqldriver.MySQLError
+	invalidator, ok := c.source.(secrets.Invalidator)
+	if ok && errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlAccessDenied {
+		slog.WarnContext(ctx, "Database denied access, reading credentials again")
+		invalidator.Invalidate()
+		return c.Connector.Connect(ctx)
+	}
+
+	return conn, err
+}
+
+// secretTransport adds basic authentication with the credentials from a
+// source to each request to OpenSearch
+type secretTransport struct {
+	base     http.RoundTripper
+	source   secrets.Source
+	username string
+}
+
+func (t *secretTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.authenticated(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	// Retry once with credentials read again, as they have likely been
+	// rotated, if the request body can be sent again
+	invalidator, ok := t.source.(secrets.Invalidator)
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return res, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	slog.WarnContext(req.Context(), "OpenSearch rejected the credentials, reading them again")
+	res.Body.Close()
+	invalidator.Invalidate()
+
+	return t.authenticated(req)
+}
+
+func (t *secretTransport) authenticated(req *http.Request) (*http.Response, error) {
+	credentials, err := t.source.Credentials(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	credentials = credentials.WithDefaultUsername(t.username)
+
+	// RoundTrippers must not modify the request they are given
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(credentials.Username, credentials.Password)
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,217 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxProductDataSize is the largest product dataset that is downloaded
+const maxProductDataSize = 64 << 20
+
+// ErrChecksumMismatch is returned when a downloaded dataset doesn't match
+// its configured checksum
+var ErrChecksumMismatch = errors.New("product data doesn't match its checksum")
+
+// Formats of a product dataset
+const (
+	ProductDataJSON = "json"
+	ProductDataCSV  = "csv"
+)
+
+var (
+	productDataMu sync.RWMutex
+	// productData replaces the bundled products when it's set
+	productData       []byte
+	productDataFormat string
+)
+
+// ProductDataFormat returns the configured format of the dataset, which by
+// default is CSV for URLs ending in .csv and JSON otherwise
+func ProductDataFormat(config config.SeedConfiguration) string {
+	if config.Format != "" {
+		return config.Format
+	}
+
+	location, err := url.Parse(config.URL)
+	if err == nil && strings.HasSuffix(strings.ToLower(location.Path), ".csv") {
+		return ProductDataCSV
+	}
+	return ProductDataJSON
+}
+
+// FetchProductData downloads the product dataset at the configured URL, from
+// S3 for s3://bucket/key URLs and over HTTP otherwise, and checks it against
+// the configured SHA-256 checksum
+func FetchProductData(config config.SeedConfiguration, ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	location, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product data URL: %w", err)
+	}
+
+	var body io.ReadCloser
+	switch location.Scheme {
+	case "s3":
+		body, err = openS3Object(location.Host, strings.TrimPrefix(location.Path, "/"), config.S3Endpoint, ctx)
+	case "http", "https":
+		body, err = openURL(config.URL, ctx)
+	default:
+		err = fmt.Errorf("unsupported product data URL scheme %q", location.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxProductDataSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download product data from %s: %w", config.URL, err)
+	}
+	if len(data) > maxProductDataSize {
+		return nil, fmt.Errorf("product data at %s is larger than %d bytes", config.URL, maxProductDataSize)
+	}
+
+	sum := sha256.Sum256(data)
+	if checksum := hex.EncodeToString(sum[:]); !strings.EqualFold(checksum, config.SHA256) {
+		return nil, fmt.Errorf("%w: %s has SHA-256 %s", ErrChecksumMismatch, config.URL, checksum)
+	}
+
+	return data, nil
+}
+
+func openS3Object(bucket, key, endpoint string, ctx context.Context) (io.ReadCloser, error) {
+	awsConfig := aws.NewConfig()
+	if endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	object, err := s3.New(sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download product data from s3://%s/%s: %w", bucket, key, err)
+	}
+	return object.Body, nil
+}
+
+func openURL(location string, ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download product data from %s: %w", location, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download product data from %s: %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// UseProductData replaces the bundled products with the dataset, which is
+// either JSON in the layout of the bundled products.json or CSV with
+// CSVColumns, for seeding, resets and the search index. The dataset is
+// checked first, so that a product with a missing ID or an unknown tag or
+// category is reported before anything is seeded. Nil restores the bundled
+// products.
+func UseProductData(data []byte, format string) error {
+	if data != nil {
+		if err := checkProductData(data, format); err != nil {
+			return err
+		}
+	}
+
+	productDataMu.Lock()
+	defer productDataMu.Unlock()
+
+	productData, productDataFormat = data, format
+	return nil
+}
+
+func checkProductData(data []byte, format string) error {
+	products, err := parseProductData(data, format)
+	if err != nil {
+		return err
+	}
+	if len(products) == 0 {
+		return errors.New("product data has no products")
+	}
+
+	tags, err := LoadProductTagData()
+	if err != nil {
+		return err
+	}
+	knownTags := map[string]bool{}
+	for _, tag := range tags {
+		knownTags[tag.Name] = true
+	}
+
+	categories, err := LoadCategoryData()
+	if err != nil {
+		return err
+	}
+	knownCategories := map[string]bool{}
+	for _, category := range categories {
+		knownCategories[category.Name] = true
+	}
+
+	ids := map[string]bool{}
+	for i, product := range products {
+		switch {
+		case product.ID == "" || product.Name == "":
+			return fmt.Errorf("product %d must have an id and a name", i)
+		case ids[product.ID]:
+			return fmt.Errorf("product %s is listed more than once", product.ID)
+		case product.Category != "" && !knownCategories[product.Category]:
+			return fmt.Errorf("product %s: %w: %s", product.ID, ErrUnknownCategory, product.Category)
+		}
+		for _, tag := range product.Tags {
+			if !knownTags[tag] {
+				return fmt.Errorf("product %s: %w: %s", product.ID, ErrUnknownTag, tag)
+			}
+		}
+		ids[product.ID] = true
+	}
+
+	return nil
+}
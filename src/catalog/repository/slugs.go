@@ -0,0 +1,128 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// SlugLookup interface for repositories that can find a product by its
+// slug, or by a slug it had before it was renamed
+type SlugLookup interface {
+	GetProductBySlug(slug string, ctx context.Context) (*model.Product, error)
+}
+
+// GetProductBySlug returns the product with the slug, or that had it before
+// it was renamed. The product's current slug tells the two apart.
+func (db *Database) GetProductBySlug(slug string, ctx context.Context) (*model.Product, error) {
+	product := model.Product{}
+
+	reads := db.reads().WithContext(ctx)
+	previous := reads.Session(&gorm.Session{NewDB: true}).Model(&model.ProductSlug{}).Select("product_id").Where("slug = ?", slug)
+	err := preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(reads.Preload("Tags").Preload("Category")))))).
+		Where("products.slug = ? OR products.id IN (?)", slug, previous).
+		First(&product).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// assignSlug gives a product a slug made from its name, numbered from 2 if
+// another product has or had it. A product keeps its slug while its name
+// makes the same one, and its old slug is kept to find it by. Deleted
+// products keep their slugs, so that they can be restored.
+func assignSlug(tx *gorm.DB, product *model.Product, current string) error {
+	base := model.Slugify(product.Name)
+	if current != "" && numberedSlug(base).MatchString(current) {
+		product.Slug = current
+		return nil
+	}
+
+	pattern := base + "-%"
+	var taken []string
+	err := tx.Unscoped().Model(&model.Product{}).
+		Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, pattern, product.ID).
+		Pluck("slug", &taken).Error
+	if err != nil {
+		return err
+	}
+	var previous []string
+	err = tx.Model(&model.ProductSlug{}).
+		Where("(slug = ? OR slug LIKE ?) AND product_id <> ?", base, pattern, product.ID).
+		Pluck("slug", &previous).Error
+	if err != nil {
+		return err
+	}
+	taken = append(taken, previous...)
+
+	product.Slug = base
+	for n := 2; slices.Contains(taken, product.Slug); n++ {
+		product.Slug = base + "-" + strconv.Itoa(n)
+	}
+
+	// A product renamed back takes its old slug back
+	if err := tx.Where("slug = ?", product.Slug).Delete(&model.ProductSlug{}).Error; err != nil {
+		return err
+	}
+	if current != "" {
+		return tx.Create(&model.ProductSlug{Slug: current, ProductID: product.ID}).Error
+	}
+	return nil
+}
+
+// numberedSlug matches a slug and the numbered slugs made from it
+func numberedSlug(base string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `(-[0-9]+)?$`)
+}
+
+// deleteSlugs removes the slugs a product had before
+func deleteSlugs(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.ProductSlug{}).Error
+}
+
+// seedSlugs gives products that were stored before they had slugs one made
+// from their name, oldest first so that they get the unnumbered slugs
+func seedSlugs(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var products []model.Product
+		if err := tx.Unscoped().Select("id", "name").Order("created_at, id").Find(&products).Error; err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			if err := assignSlug(tx, &product, ""); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.Product{}).Where("id = ?", product.ID).UpdateColumn("slug", product.Slug).Error; err != nil {
+				return err
+			}
+		}
+
+		slog.Info("Made slugs for existing products", "products", len(products))
+		return nil
+	})
+}
@@ -0,0 +1,345 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const sqliteFTSTable = "products_fts"
+
+func init() {
+	Register("sqlite", func(config config.DatabaseConfiguration) (CatalogRepository, error) {
+		repo, err := NewSQLiteRepository(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return repo, nil
+	})
+	RegisterSearch("sqlite", func(config config.AppConfiguration, catalog CatalogRepository) (SearchRepository, error) {
+		repo, ok := catalog.(*SQLiteRepository)
+		if !ok {
+			return nil, fmt.Errorf("sqlite search requires the sqlite persistence provider")
+		}
+
+		slog.Info("Using SQLite full-text search")
+		return repo, nil
+	})
+}
+
+// SQLiteRepository stores the catalog in a single SQLite file and serves
+// search from an FTS5 virtual table, so it implements both CatalogRepository
+// and SearchRepository
+type SQLiteRepository struct {
+	*Database
+}
+
+// NewSQLiteRepository opens (or creates) the SQLite database file, seeds it and
+// builds the full-text index if it is empty
+func NewSQLiteRepository(config config.DatabaseConfiguration) (*SQLiteRepository, error) {
+	slog.Info("Using sqlite database", "path", config.Path)
+
+	db, err := gorm.Open(sqlite.Open(config.Path), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	database, err := newDatabase(db)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &SQLiteRepository{
+		Database: database,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initializeTimeout)
+	defer cancel()
+
+	if err := repo.initializeSearch(ctx); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// initializeSearch creates the FTS5 table and populates it when it holds no rows
+func (r *SQLiteRepository) initializeSearch(ctx context.Context) error {
+	// Tables created before SKUs were indexed are rebuilt with them
+	var definition string
+	err := r.DB.WithContext(ctx).Raw("SELECT COALESCE(MAX(sql), '') FROM sqlite_master WHERE name = ?", sqliteFTSTable).Scan(&definition).Error
+	if err != nil {
+		return fmt.Errorf("failed to read the full-text table definition: %w", err)
+	}
+	if definition != "" && !strings.Contains(definition, "skus") {
+		slog.InfoContext(ctx, "Rebuilding SQLite full-text index to include SKUs")
+		if err := r.DB.WithContext(ctx).Exec("DROP TABLE " + sqliteFTSTable).Error; err != nil {
+			return fmt.Errorf("failed to drop the full-text table: %w", err)
+		}
+	}
+
+	err = r.DB.WithContext(ctx).Exec(
+		"CREATE VIRTUAL TABLE IF NOT EXISTS " + sqliteFTSTable +
+			" USING fts5(id UNINDEXED, name, description, tags, skus, tokenize = 'porter unicode61')",
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to create full-text table (is the binary built with -tags sqlite_fts5?): %w", err)
+	}
+
+	var count int64
+	if err := r.DB.WithContext(ctx).Table(sqliteFTSTable).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count full-text rows: %w", err)
+	}
+
+	if count > 0 {
+		slog.InfoContext(ctx, "SQLite full-text index already populated, skipping re-index", "products", count)
+		return nil
+	}
+
+	return r.populateSearch(ctx)
+}
+
+// populateSearch rebuilds the FTS5 table from the product and tag tables.
+// Translated names and descriptions are indexed after the product's own, so
+// that keywords in any language match, and the SKUs of the product and its
+// variants with its EAN.
+func (r *SQLiteRepository) populateSearch(ctx context.Context) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + sqliteFTSTable).Error; err != nil {
+			return fmt.Errorf("failed to clear full-text index: %w", err)
+		}
+
+		err := tx.Exec(
+			"INSERT INTO " + sqliteFTSTable + " (id, name, description, tags, skus) " +
+				"SELECT products.id, " +
+				"products.name || COALESCE((SELECT ' ' || GROUP_CONCAT(name, ' ') FROM product_translations WHERE product_id = products.id), ''), " +
+				"products.description || COALESCE((SELECT ' ' || GROUP_CONCAT(description, ' ') FROM product_translations WHERE product_id = products.id), ''), " +
+				"COALESCE(GROUP_CONCAT(product_tags.tag_name, ' '), ''), " +
+				"COALESCE(products.sku, '') || ' ' || COALESCE(products.ean, '') || COALESCE((SELECT ' ' || GROUP_CONCAT(sku, ' ') FROM variants WHERE product_id = products.id), '') " +
+				"FROM products LEFT JOIN product_tags ON product_tags.product_id = products.id " +
+				"WHERE products.deleted_at IS NULL " +
+				"GROUP BY products.id",
+		).Error
+		if err != nil {
+			return fmt.Errorf("failed to populate full-text index: %w", err)
+		}
+
+		slog.InfoContext(ctx, "Populated SQLite full-text index")
+		return nil
+	})
+}
+
+// Reindex rebuilds the full-text index from the product table
+func (r *SQLiteRepository) Reindex(ctx context.Context) error {
+	return r.populateSearch(ctx)
+}
+
+// SearchProducts matches the keyword against the FTS5 table, ranking name
+// matches above description and tag matches
+func (r *SQLiteRepository) SearchProducts(search SearchQuery, page, size int, ctx context.Context) (SearchResult, error) {
+	match := buildFTSMatchExpression(search.Keyword)
+	if match == "" {
+		return SearchResult{Products: []model.Product{}}, nil
+	}
+
+	// Prices, variants and stock are filtered on the product table, which the
+	// full-text table is joined to by ID
+	join, where, args := "", "", []interface{}{match}
+	if len(search.PriceRanges) > 0 {
+		condition, rangeArgs := priceRangesCondition(search.PriceRanges)
+		where += " AND " + condition
+		args = append(args, rangeArgs...)
+	}
+	if len(search.VariantAttributes) > 0 {
+		condition, attributeArgs := variantAttributesCondition(search.VariantAttributes)
+		where += " AND " + condition
+		args = append(args, attributeArgs...)
+	}
+	if search.Category != nil {
+		condition, categoryArgs := categoryCondition(*search.Category)
+		where += " AND " + condition
+		args = append(args, categoryArgs...)
+	}
+	if len(search.Attributes) > 0 {
+		condition, attributeArgs := attributeFiltersCondition(search.Attributes)
+		where += " AND " + condition
+		args = append(args, attributeArgs...)
+	}
+	if search.MinRating != nil {
+		where += " AND products.rating_average >= ?"
+		args = append(args, *search.MinRating)
+	}
+	if statuses := search.statuses(); len(statuses) > 0 {
+		where += " AND products.status IN ?"
+		args = append(args, statuses)
+	}
+	if search.OnSale != nil {
+		condition, saleArgs := saleCondition(*search.OnSale, search.Sale)
+		where += " AND " + condition
+		args = append(args, saleArgs...)
+	}
+	result := SearchResult{Products: []model.Product{}}
+	if search.Facets {
+		var counts struct {
+			InStock int
+			Total   int
+		}
+		err := r.DB.WithContext(ctx).
+			Raw("SELECT COALESCE(SUM(CASE WHEN products.stock > 0 THEN 1 ELSE 0 END), 0) AS in_stock, COUNT(*) AS total "+
+				"FROM "+sqliteFTSTable+" JOIN products ON products.id = "+sqliteFTSTable+".id WHERE "+sqliteFTSTable+" MATCH ?"+where, args...).
+			Scan(&counts).Error
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to count search results in stock: %w", err)
+		}
+		result.Facets = map[string]map[string]int{FacetInStock: stockCounts(counts.InStock, counts.Total-counts.InStock)}
+
+		attributes, err := countAttributes(r.DB.WithContext(ctx), gorm.Expr(
+			"SELECT "+sqliteFTSTable+".id FROM "+sqliteFTSTable+" JOIN products ON products.id = "+sqliteFTSTable+".id WHERE "+sqliteFTSTable+" MATCH ?"+where, args...))
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to count search results by attribute: %w", err)
+		}
+		addAttributeFacets(result.Facets, attributes)
+	}
+	if search.InStock != nil {
+		where += " AND " + stockCondition(*search.InStock)
+	}
+	order := "bm25(" + sqliteFTSTable + ", 0.0, 2.0, 1.0, 1.0, 10.0)"
+	sorted := searchOrder(search.Order)
+	if sorted != "" {
+		order = sorted + ", " + order
+	}
+	if where != "" || sorted != "" {
+		join = " JOIN products ON products.id = " + sqliteFTSTable + ".id"
+	}
+
+	var ids []string
+	err := r.DB.WithContext(ctx).
+		Raw("SELECT "+sqliteFTSTable+".id FROM "+sqliteFTSTable+join+" WHERE "+sqliteFTSTable+" MATCH ?"+where+" "+
+			"ORDER BY "+order+" LIMIT ? OFFSET ?",
+			append(args, size, (page-1)*size)...).
+		Scan(&ids).Error
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("search request failed: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var found []model.Product
+	err = preloadRelations(preloadTranslations(preloadAttributes(preloadImages(preloadVariants(r.DB.WithContext(ctx).Preload("Tags")))))).
+		Where("id IN ?", ids).
+		Find(&found).Error
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("failed to load search results: %w", err)
+	}
+
+	// Preserve the order returned by the full-text query
+	byID := make(map[string]model.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			result.Products = append(result.Products, product)
+		}
+	}
+
+	return result, nil
+}
+
+// buildFTSMatchExpression turns free text into an FTS5 query where any term
+// may match as a prefix, quoting each term so user input can't inject syntax
+func buildFTSMatchExpression(keyword string) string {
+	terms := []string{}
+	for _, term := range strings.Fields(keyword) {
+		term = strings.ReplaceAll(term, `"`, "")
+		if term == "" {
+			continue
+		}
+		terms = append(terms, `"`+term+`"*`)
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+// IndexProduct adds or replaces a single product in the full-text index
+func (r *SQLiteRepository) IndexProduct(product model.Product, ctx context.Context) error {
+	tags := make([]string, len(product.Tags))
+	for i, tag := range product.Tags {
+		tags[i] = tag.Name
+	}
+
+	names, descriptions := []string{product.Name}, []string{product.Description}
+	for _, translation := range product.Translations {
+		names = append(names, translation.Name)
+		descriptions = append(descriptions, translation.Description)
+	}
+
+	skus := []string{}
+	for _, code := range []*string{product.SKU, product.EAN} {
+		if code != nil {
+			skus = append(skus, *code)
+		}
+	}
+	for _, variant := range product.Variants {
+		skus = append(skus, variant.SKU)
+	}
+
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM "+sqliteFTSTable+" WHERE id = ?", product.ID).Error; err != nil {
+			return fmt.Errorf("failed to remove stale full-text row: %w", err)
+		}
+
+		err := tx.Exec("INSERT INTO "+sqliteFTSTable+" (id, name, description, tags, skus) VALUES (?, ?, ?, ?, ?)",
+			product.ID, strings.Join(names, " "), strings.Join(descriptions, " "), strings.Join(tags, " "), strings.Join(skus, " ")).Error
+		if err != nil {
+			return fmt.Errorf("failed to index product: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RemoveProduct deletes a single product from the full-text index
+func (r *SQLiteRepository) RemoveProduct(id string, ctx context.Context) error {
+	if err := r.DB.WithContext(ctx).Exec("DELETE FROM "+sqliteFTSTable+" WHERE id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to remove full-text row: %w", err)
+	}
+
+	return nil
+}
+
+// IndexedProductIDs returns the IDs of every product in the full-text index
+func (r *SQLiteRepository) IndexedProductIDs(ctx context.Context) ([]string, error) {
+	ids := []string{}
+	if err := r.DB.WithContext(ctx).Raw("SELECT id FROM " + sqliteFTSTable).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list full-text rows: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,56 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// statusQuery returns an OpenSearch filter matching products with any of
+// the given statuses. Products indexed before they had a status are active,
+// so when active products are wanted the others are excluded instead.
+func statusQuery(statuses []string) map[string]interface{} {
+	if !slices.Contains(statuses, model.StatusActive) {
+		return map[string]interface{}{"terms": map[string]interface{}{"status": statuses}}
+	}
+
+	excluded := []string{}
+	for _, status := range model.Statuses {
+		if !slices.Contains(statuses, status) {
+			excluded = append(excluded, status)
+		}
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{
+		"must_not": map[string]interface{}{"terms": map[string]interface{}{"status": excluded}},
+	}}
+}
+
+// withoutActiveFilter returns the query clause of a search without the filter
+// limiting it to active products, which searches made by shoppers all have,
+// so that metrics and traces describe the rest of the query
+func withoutActiveFilter(clause map[string]interface{}) map[string]interface{} {
+	boolean, ok := clause["bool"].(map[string]interface{})
+	if !ok || !reflect.DeepEqual(boolean["filter"], statusQuery([]string{model.StatusActive})) {
+		return clause
+	}
+
+	must, _ := boolean["must"].(map[string]interface{})
+	return must
+}
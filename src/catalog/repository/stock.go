@@ -0,0 +1,44 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+// FacetInStock is the facet counting search results with and without stock,
+// in buckets named "true" and "false"
+const FacetInStock = "in_stock"
+
+// stockCondition returns an SQL condition matching products with stock, or
+// those without
+func stockCondition(inStock bool) string {
+	if inStock {
+		return "products.stock > 0"
+	}
+	return "products.stock <= 0"
+}
+
+// stockQuery returns an OpenSearch filter matching products with stock, or
+// those without
+func stockQuery(inStock bool) map[string]interface{} {
+	if inStock {
+		return map[string]interface{}{"range": map[string]interface{}{"stock": map[string]interface{}{"gt": 0}}}
+	}
+	return map[string]interface{}{"range": map[string]interface{}{"stock": map[string]interface{}{"lte": 0}}}
+}
+
+// stockCounts returns the in-stock facet buckets
+func stockCounts(inStock, outOfStock int) map[string]int {
+	return map[string]int{"true": inStock, "false": outOfStock}
+}
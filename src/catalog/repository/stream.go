@@ -0,0 +1,158 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// pitKeepAlive is how long a point in time is kept open waiting for the next
+// batch of a streamed search
+const pitKeepAlive = time.Minute
+
+// pitCloseTimeout bounds closing a point in time once a streamed search has
+// finished, even if the request it was for was cancelled
+const pitCloseTimeout = 5 * time.Second
+
+// StreamSearch passes every hit for the keyword to the callback, a batch at
+// a time in order of relevance, paging with search_after through a point in
+// time so that documents indexed or removed meanwhile don't shift hits
+// between batches. Clusters older than OpenSearch 2.4, which can't open a
+// point in time, are paged through the live index instead.
+func (r *OpenSearchRepository) StreamSearch(keyword string, batchSize int, ctx context.Context, fn func([]model.Product) error) error {
+	pit, err := r.openPointInTime(ctx)
+	if err != nil {
+		return err
+	}
+	if pit != "" {
+		// The ID is updated by each batch, and the last is the one to close
+		defer func() { r.closePointInTime(pit, ctx) }()
+	}
+
+	var after []interface{}
+	for first := true; ; first = false {
+		query := r.searchQuery(SearchQuery{Keyword: keyword}, batchSize)
+		query["sort"] = []map[string]string{{"_score": "desc"}, {"id": "asc"}}
+		if pit != "" {
+			query["pit"] = map[string]interface{}{"id": pit, "keep_alive": formatKeepAlive(pitKeepAlive)}
+		}
+		if len(after) > 0 {
+			query["search_after"] = after
+		}
+
+		searchResponse, err := r.search(query, ctx)
+		if err != nil {
+			return err
+		}
+		// Only the first batch is counted, as the search it answers
+		if first {
+			searchResponse.observe(query, ctx)
+		}
+		if searchResponse.PitID != "" {
+			pit = searchResponse.PitID
+		}
+
+		hits := searchResponse.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+
+		products := make([]model.Product, 0, len(hits))
+		for _, hit := range hits {
+			products = append(products, hit.Source.toProduct())
+		}
+		after = hits[len(hits)-1].Sort
+
+		if err := fn(products); err != nil {
+			return err
+		}
+		if len(hits) < batchSize {
+			return nil
+		}
+	}
+}
+
+// openPointInTime opens a point in time on the index, returning an empty ID
+// if the cluster doesn't support them
+func (r *OpenSearchRepository) openPointInTime(ctx context.Context) (string, error) {
+	ctx, op := r.startOperation("create_pit", ctx)
+	defer op.End()
+
+	// Filtering the response leaves it to be read here, so that errors can be
+	// told apart
+	res, _, err := opensearchapi.PointInTimeCreateRequest{
+		Index:      []string{r.indexName},
+		KeepAlive:  pitKeepAlive,
+		FilterPath: []string{"pit_id"},
+	}.Do(ctx, r.client)
+	if err != nil {
+		return "", op.fail(fmt.Errorf("failed to create point in time: %w", err))
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body := res.String()
+		if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusMethodNotAllowed || strings.Contains(body, "no handler found") {
+			r.log.DebugContext(ctx, "Points in time aren't supported, streaming search results from the live index")
+			return "", nil
+		}
+		return "", op.fail(fmt.Errorf("failed to create point in time: %s", body))
+	}
+
+	var pit opensearchapi.PointInTimeCreateResp
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", op.fail(fmt.Errorf("failed to parse point in time: %w", err))
+	}
+
+	return pit.PitID, nil
+}
+
+// closePointInTime frees the resources the cluster holds for a point in
+// time, rather than leaving them until it expires. Failures are only logged,
+// since the results have already been sent.
+func (r *OpenSearchRepository) closePointInTime(pit string, ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pitCloseTimeout)
+	defer cancel()
+
+	ctx, op := r.startOperation("delete_pit", ctx)
+	defer op.End()
+
+	res, _, err := opensearchapi.PointInTimeDeleteRequest{PitID: []string{pit}, FilterPath: []string{"pits"}}.Do(ctx, r.client)
+	if err == nil {
+		defer res.Body.Close()
+		if res.IsError() {
+			err = errors.New(res.String())
+		}
+	}
+	if err != nil {
+		r.log.WarnContext(ctx, "Failed to close point in time", "error", op.fail(err))
+	}
+}
+
+// formatKeepAlive formats a keep alive in the units OpenSearch expects
+func formatKeepAlive(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
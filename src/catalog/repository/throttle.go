@@ -0,0 +1,142 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// bulkThrottleDelay is the pause added before the next write the first time
+// OpenSearch rejects one, which is doubled by each rejection after it
+const bulkThrottleDelay = 100 * time.Millisecond
+
+// bulkThrottle slows writes to the search index while OpenSearch rejects them
+// with 429 because its write queues are full. Each rejection halves the
+// documents sent in a bulk request, down to minBatch, and doubles the pause
+// before every write, up to maxDelay, and the rejected documents are sent
+// again up to retries times. Each write accepted in full moves both back
+// towards where they started, so that ingest speeds up again once the cluster
+// has caught up. It's shared by everything writing to the index, since they
+// all fill the same queues.
+type bulkThrottle struct {
+	minBatch int
+	maxDelay time.Duration
+	retries  int
+	log      *slog.Logger
+
+	mu sync.Mutex
+	// batch is the most documents sent in a bulk request, zero for as many
+	// as the caller has
+	batch int
+	delay time.Duration
+}
+
+func newBulkThrottle(minBatch int, maxDelay time.Duration, retries int, log *slog.Logger) *bulkThrottle {
+	observeBulkThrottle(0, 0)
+	return &bulkThrottle{minBatch: max(minBatch, 1), maxDelay: maxDelay, retries: retries, log: log}
+}
+
+// limit returns how many of the n documents waiting to be indexed can be
+// sent in the next bulk request
+func (t *bulkThrottle) limit(n int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.batch == 0 {
+		return n
+	}
+	return min(n, t.batch)
+}
+
+// wait pauses before a write for as long as the throttle asks
+func (t *bulkThrottle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	delay := t.delay
+	t.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rejected slows down after OpenSearch rejected some of the sent documents,
+// or a single document write when sent is zero
+func (t *bulkThrottle) rejected(sent int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fullSpeed := t.batch == 0 && t.delay == 0
+	if sent > 0 {
+		t.batch = max(t.minBatch, sent/2)
+	}
+	t.delay = min(max(2*t.delay, bulkThrottleDelay), t.maxDelay)
+
+	if fullSpeed && (t.batch != 0 || t.delay != 0) {
+		t.log.Warn("OpenSearch is rejecting writes, slowing down", "batch", t.batch, "delay", t.delay)
+	}
+	observeBulkThrottle(t.batch, t.delay)
+}
+
+// accepted speeds up after OpenSearch accepted every document sent out of
+// wanted, or a single document write when both are zero. The batch only
+// grows when it was what held the request back.
+func (t *bulkThrottle) accepted(sent, wanted int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.batch == 0 && t.delay == 0 {
+		return
+	}
+
+	if t.batch > 0 && sent >= t.batch {
+		t.batch *= 2
+		if t.batch >= wanted {
+			t.batch = 0
+		}
+	}
+	t.delay /= 2
+	if t.delay < bulkThrottleDelay {
+		t.delay = 0
+	}
+
+	if t.batch == 0 && t.delay == 0 {
+		t.log.Info("OpenSearch is accepting writes again, back to full speed")
+	}
+	observeBulkThrottle(t.batch, t.delay)
+}
+
+// state returns the most documents sent in a bulk request, zero for no
+// limit, and the pause before each write
+func (t *bulkThrottle) state() (int, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.batch, t.delay
+}
@@ -0,0 +1,102 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/aws-containers/retail-store-sample-app/catalog/repository"
+
+// Attributes of the spans for OpenSearch operations
+const (
+	attrIndex     = attribute.Key("opensearch.index")
+	attrQueryType = attribute.Key("opensearch.query.type")
+	attrHits      = attribute.Key("opensearch.hits")
+	attrTotalHits = attribute.Key("opensearch.hits.total")
+	attrCoalesced = attribute.Key("opensearch.coalesced")
+	attrDocuments = attribute.Key("opensearch.documents")
+	attrFailed    = attribute.Key("opensearch.documents.failed")
+	attrRetried   = attribute.Key("opensearch.documents.retried")
+	attrProduct   = attribute.Key("catalog.product.id")
+	attrFields    = attribute.Key("catalog.product.fields")
+)
+
+// operation is a call to OpenSearch, which is traced and timed
+type operation struct {
+	trace.Span
+	name    string
+	started time.Time
+	failed  bool
+}
+
+// startOperation starts a span for an operation on the OpenSearch index. The
+// tracer is looked up each time so that spans go to the provider installed
+// when tracing is configured, whenever that happens.
+func (r *OpenSearchRepository) startOperation(name string, ctx context.Context, attributes ...attribute.KeyValue) (context.Context, *operation) {
+	attributes = append(attributes,
+		attribute.String("db.system", "opensearch"),
+		attribute.String("db.operation", name),
+		attrIndex.String(r.indexName),
+	)
+
+	ctx, span := otel.GetTracerProvider().Tracer(tracerName).Start(ctx, "OpenSearch "+name, trace.WithAttributes(attributes...))
+	return ctx, &operation{Span: span, name: name, started: time.Now()}
+}
+
+// fail marks the operation as failed and returns the error
+func (o *operation) fail(err error) error {
+	o.failed = true
+	o.RecordError(err)
+	o.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// End records the duration of the operation and ends its span
+func (o *operation) End(options ...trace.SpanEndOption) {
+	observeOperation("opensearch", o.name, o.started, o.failed, trace.ContextWithSpan(context.Background(), o.Span))
+	o.Span.End(options...)
+}
+
+// tracedTransport records a client span for each HTTP request to OpenSearch
+// and propagates the trace to it. The peer service names the cluster's node
+// in service maps such as X-Ray's.
+func tracedTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return "OpenSearch HTTP " + req.Method
+		}),
+		otelhttp.WithSpanOptions(trace.WithAttributes(attribute.String("peer.service", "opensearch"))),
+	)
+}
+
+// queryType names the query clause of a search, such as multi_match
+func queryType(query map[string]interface{}) string {
+	clause, _ := query["query"].(map[string]interface{})
+	for name := range withoutActiveFilter(clause) {
+		return name
+	}
+	return "none"
+}
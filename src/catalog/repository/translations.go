@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// preloadTranslations loads the translations of the products a query
+// returns, in locale order
+func preloadTranslations(query *gorm.DB) *gorm.DB {
+	return query.Preload("Translations", func(db *gorm.DB) *gorm.DB { return db.Order("product_translations.locale asc") })
+}
+
+// saveTranslations replaces the translations of a product, unless they are
+// nil, and loads the stored translations into the product
+func saveTranslations(tx *gorm.DB, product *model.Product) error {
+	if product.Translations != nil {
+		if err := deleteTranslations(tx, product.ID); err != nil {
+			return err
+		}
+
+		for i := range product.Translations {
+			product.Translations[i].ProductID = product.ID
+		}
+		if len(product.Translations) > 0 {
+			if err := tx.Create(&product.Translations).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	translations := []model.ProductTranslation{}
+	if err := tx.Where("product_id = ?", product.ID).Order("locale asc").Find(&translations).Error; err != nil {
+		return err
+	}
+	product.Translations = translations
+	return nil
+}
+
+// deleteTranslations removes the translations of a product
+func deleteTranslations(tx *gorm.DB, productID string) error {
+	return tx.Where("product_id = ?", productID).Delete(&model.ProductTranslation{}).Error
+}
+
+// seedTranslations returns the translations of a bundled product
+func seedTranslations(product ProductData) []model.ProductTranslation {
+	return model.ToTranslations(product.ID, product.Translations)
+}
@@ -0,0 +1,161 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// DescribeVariantAttributes writes attribute values as name:value pairs in
+// name order, for keys that must not depend on map ordering
+func DescribeVariantAttributes(attributes map[string]string) string {
+	described := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		described = append(described, name+":"+value)
+	}
+	sort.Strings(described)
+	return strings.Join(described, ",")
+}
+
+// variantAttributesCondition returns an SQL condition matching products with
+// a variant that has all of the attribute values
+func variantAttributesCondition(attributes map[string]string) (string, []interface{}) {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	terms := make([]string, len(names))
+	args := make([]interface{}, 0, 2*len(names))
+	for i, name := range names {
+		terms[i] = "variants.sku IN (SELECT variant_attributes.sku FROM variant_attributes WHERE variant_attributes.name = ? AND variant_attributes.value = ?)"
+		args = append(args, name, attributes[name])
+	}
+
+	return "products.id IN (SELECT variants.product_id FROM variants WHERE " + strings.Join(terms, " AND ") + ")", args
+}
+
+// variantAttributesQuery returns an OpenSearch filter matching products with
+// a variant that has all of the attribute values
+func variantAttributesQuery(attributes map[string]string) map[string]interface{} {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filter := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		filter[i] = map[string]interface{}{"term": map[string]interface{}{"variants.attributes." + name: attributes[name]}}
+	}
+
+	return map[string]interface{}{
+		"nested": map[string]interface{}{
+			"path":  "variants",
+			"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filter}},
+		},
+	}
+}
+
+// preloadVariants loads the variants of the products a query returns, in SKU
+// order, with their attributes
+func preloadVariants(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("variants.sku asc") }).
+		Preload("Variants.Attributes", orderAttributes)
+}
+
+func orderAttributes(db *gorm.DB) *gorm.DB {
+	return db.Order("variant_attributes.name asc")
+}
+
+// saveVariants replaces the variants of a product, unless they are nil, and
+// loads the stored variants into the product. A SKU can only belong to one
+// product, so ErrSKUExists is returned for one that another product has. The
+// stock of a product with variants is kept at the total of theirs.
+func saveVariants(tx *gorm.DB, product *model.Product) error {
+	if product.Variants != nil {
+		skus := make([]string, len(product.Variants))
+		seen := make(map[string]bool, len(product.Variants))
+		for i, variant := range product.Variants {
+			if seen[variant.SKU] {
+				return fmt.Errorf("%w: %s is given more than once", ErrSKUExists, variant.SKU)
+			}
+			seen[variant.SKU] = true
+			skus[i] = variant.SKU
+		}
+
+		if len(skus) > 0 {
+			var taken []string
+			err := tx.Model(&model.Variant{}).Where("sku IN ? AND product_id <> ?", skus, product.ID).Pluck("sku", &taken).Error
+			if err != nil {
+				return err
+			}
+			if len(taken) > 0 {
+				return fmt.Errorf("%w: %s", ErrSKUExists, strings.Join(taken, ", "))
+			}
+		}
+
+		if err := deleteVariants(tx, product.ID); err != nil {
+			return err
+		}
+
+		for i := range product.Variants {
+			product.Variants[i].ProductID = product.ID
+			product.Variants[i].Price = product.Variants[i].Price.WithDefaultCurrency()
+		}
+		if len(product.Variants) > 0 {
+			if err := tx.Create(&product.Variants).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	variants := []model.Variant{}
+	err := tx.Preload("Attributes", orderAttributes).Where("product_id = ?", product.ID).Order("sku asc").Find(&variants).Error
+	if err != nil {
+		return err
+	}
+	product.Variants = variants
+
+	if len(variants) == 0 {
+		return nil
+	}
+
+	stock := 0
+	for _, variant := range variants {
+		stock += variant.Stock
+	}
+	product.Stock = &stock
+	return tx.Model(&model.Product{}).Where("id = ?", product.ID).Update("stock", stock).Error
+}
+
+// deleteVariants removes the variants of a product and their attributes
+func deleteVariants(tx *gorm.DB, productID string) error {
+	skus := tx.Session(&gorm.Session{NewDB: true}).Model(&model.Variant{}).Select("sku").Where("product_id = ?", productID)
+	if err := tx.Where("sku IN (?)", skus).Delete(&model.VariantAttribute{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("product_id = ?", productID).Delete(&model.Variant{}).Error
+}
@@ -0,0 +1,74 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+// WaitFor calls connect until it succeeds, so that the service can be started
+// before its dependencies, such as with docker-compose. Failed attempts are
+// retried with a jittered exponential backoff until the wait timeout runs out,
+// when the last error is returned. Unknown providers are never retried.
+func WaitFor[T any](name string, config config.StartupConfiguration, connect func() (T, error), ctx context.Context) (T, error) {
+	deadline := time.Now().Add(config.WaitTimeout)
+	backoff := config.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		result, err := connect()
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("Dependency is available", "dependency", name, "attempts", attempt)
+			}
+			return result, nil
+		}
+
+		var unknown *UnknownProviderError
+		if errors.As(err, &unknown) {
+			return result, err
+		}
+
+		// Between half and all of the backoff, so that replicas started
+		// together don't retry in lockstep
+		sleep := backoff/2 + rand.N(backoff/2+1)
+		if time.Now().Add(sleep).After(deadline) {
+			if attempt > 1 {
+				err = fmt.Errorf("%s still unavailable after %d attempts: %w", name, attempt, err)
+			}
+			return result, err
+		}
+
+		slog.Warn("Dependency is unavailable, retrying", "dependency", name, "attempt", attempt, "retryIn", sleep, "error", err)
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, config.MaxBackoff)
+	}
+}
@@ -0,0 +1,8 @@
+#!/bin/bash
+
+set -euo pipefail
+
+SCRIPT_DIR=$(dirname "$0")
+
+# Requires the service to be running locally, for example with `go run main.go`
+curl -sf localhost:${PORT:-8080}/openapi.json | yq eval -oy -P - > $SCRIPT_DIR/../openapi.yml
@@ -0,0 +1,87 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File reads credentials from a file, such as a Kubernetes secret volume or
+// one written by the Secrets Store CSI driver or Vault Agent. The file holds
+// the password alone or a JSON object with the username and password, and is
+// read again whenever it is modified.
+type File struct {
+	path string
+
+	mu          sync.Mutex
+	credentials Credentials
+	modified    time.Time
+}
+
+// NewFile creates a source for the file at path
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Credentials returns the credentials in the file, reading it first if it has
+// changed since it was last read
+func (f *File) Credentials(ctx context.Context) (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Secret volumes update the file by swapping a symlink, which Stat follows
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.cached(err)
+	}
+	if info.ModTime().Equal(f.modified) {
+		return f.credentials, nil
+	}
+
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.cached(err)
+	}
+
+	f.credentials = ParseCredentials(strings.TrimSpace(string(content)))
+	f.modified = info.ModTime()
+
+	return f.credentials, nil
+}
+
+// Invalidate reads the file again on the next request for credentials, in
+// case it was replaced without its modification time changing
+func (f *File) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.modified = time.Time{}
+}
+
+// cached returns the credentials last read when the file can't be read, which
+// happens briefly while it is being replaced
+func (f *File) cached(err error) (Credentials, error) {
+	if f.credentials.Password == "" {
+		return Credentials{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	return f.credentials, nil
+}
@@ -0,0 +1,73 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package secrets supplies credentials for the database and search index from
+// secret stores, so they don't have to be passed in plaintext
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Credentials are a username and password for a backing service
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Source supplies credentials that may change while the service is running,
+// so they should be requested each time a connection is made
+type Source interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// Invalidator is implemented by sources that cache credentials. Invalidate
+// is called when the backing service rejects the cached credentials, which
+// usually means they have been rotated, so that the next request for
+// credentials reads them again rather than waiting for a refresh.
+type Invalidator interface {
+	Invalidate()
+}
+
+// minInvalidateInterval stops a service that keeps rejecting credentials from
+// causing a read of the secret on every attempt
+const minInvalidateInterval = time.Second
+
+// ParseCredentials reads credentials from the value of a secret. JSON objects
+// like the secrets RDS manages provide the username and password, and any
+// other value is taken as the password alone.
+func ParseCredentials(value string) Credentials {
+	var secret struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(value), "{") && json.Unmarshal([]byte(value), &secret) == nil {
+		return Credentials{Username: secret.Username, Password: secret.Password}
+	}
+
+	return Credentials{Password: value}
+}
+
+// WithDefaultUsername fills in the username when the secret doesn't have one
+func (c Credentials) WithDefaultUsername(username string) Credentials {
+	if c.Username == "" {
+		c.Username = username
+	}
+	return c
+}
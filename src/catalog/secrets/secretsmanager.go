@@ -0,0 +1,113 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// SecretsManager reads credentials from an AWS Secrets Manager secret. They
+// are cached and read again once the refresh interval has passed, so rotated
+// credentials are picked up without restarting.
+type SecretsManager struct {
+	client          secretsmanageriface.SecretsManagerAPI
+	secretID        string
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	credentials Credentials
+	fetched     time.Time
+	stale       bool
+}
+
+// NewSecretsManager creates a source for the secret with the given ARN or
+// name. The region is taken from the ARN, or the AWS configuration otherwise.
+func NewSecretsManager(secretID string, refreshInterval time.Duration) (*SecretsManager, error) {
+	awsConfig := aws.NewConfig()
+	if parsed, err := arn.Parse(secretID); err == nil {
+		awsConfig = awsConfig.WithRegion(parsed.Region)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return NewSecretsManagerWithClient(secretsmanager.New(sess), secretID, refreshInterval), nil
+}
+
+// NewSecretsManagerWithClient creates a source that reads the secret with the
+// given client
+func NewSecretsManagerWithClient(client secretsmanageriface.SecretsManagerAPI, secretID string, refreshInterval time.Duration) *SecretsManager {
+	return &SecretsManager{
+		client:          client,
+		secretID:        secretID,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Credentials returns the cached credentials, reading the secret first if
+// they are missing or due a refresh. If a refresh fails the cached
+// credentials are returned, since they are likely to still be valid.
+func (s *SecretsManager) Credentials(ctx context.Context) (Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.fetched.IsZero() && !s.stale && time.Since(s.fetched) < s.refreshInterval {
+		return s.credentials, nil
+	}
+
+	output, err := s.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.secretID),
+	})
+	if err != nil {
+		if !s.fetched.IsZero() {
+			slog.WarnContext(ctx, "Failed to refresh secret, using cached credentials", "secret", s.secretID, "error", err)
+			// Try again after another interval rather than on every connection
+			s.fetched = time.Now()
+			s.stale = false
+			return s.credentials, nil
+		}
+		return Credentials{}, fmt.Errorf("failed to read secret %s: %w", s.secretID, err)
+	}
+
+	s.credentials = ParseCredentials(aws.StringValue(output.SecretString))
+	s.fetched = time.Now()
+	s.stale = false
+
+	return s.credentials, nil
+}
+
+// Invalidate reads the secret again on the next request for credentials
+func (s *SecretsManager) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.fetched) >= minInvalidateInterval {
+		s.stale = true
+	}
+}
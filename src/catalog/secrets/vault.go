@@ -0,0 +1,298 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT-0
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this
+// software and associated documentation files (the "Software"), to deal in the Software
+// without restriction, including without limitation the rights to use, copy, modify,
+// merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED,
+// INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A
+// PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+// SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Leases are renewed once this fraction of their duration has passed, which
+// leaves time to read new credentials if renewal fails
+const vaultRenewFraction = 2.0 / 3.0
+
+// Vault reads credentials from a HashiCorp Vault secret, either a KV version
+// 2 secret with username and password keys or credentials generated by a
+// secrets engine such as the database engine. Generated credentials are
+// renewed while their lease allows, and new ones are read once it runs out.
+// KV secrets are read again once the refresh interval has passed.
+type Vault struct {
+	client          *http.Client
+	address         string
+	token           string
+	namespace       string
+	path            string
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	credentials Credentials
+	fetched     time.Time
+	stale       bool
+	lease       vaultLease
+}
+
+// vaultLease is the lease of generated credentials
+type vaultLease struct {
+	id        string
+	renewable bool
+	duration  time.Duration
+	// renewAt is when the lease should be renewed, or the credentials read
+	// again if it can't be
+	renewAt time.Time
+}
+
+// vaultResponse is the common shape of Vault API responses
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	Renewable     bool                   `json:"renewable"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Data          map[string]interface{} `json:"data"`
+	Errors        []string               `json:"errors"`
+}
+
+// NewVaultFromEnvironment creates a source for the secret at path, using the
+// VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables like the
+// Vault CLI
+func NewVaultFromEnvironment(path string, refreshInterval time.Duration) (*Vault, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		address = "https://127.0.0.1:8200"
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN must be set to read %s from Vault", path)
+	}
+
+	vault := NewVault(address, token, path, refreshInterval)
+	vault.namespace = os.Getenv("VAULT_NAMESPACE")
+	return vault, nil
+}
+
+// NewVault creates a source for the secret at path, for example
+// secret/data/catalog for a KV secret or database/creds/catalog for
+// generated database credentials
+func NewVault(address, token, path string, refreshInterval time.Duration) *Vault {
+	return &Vault{
+		client:          &http.Client{Timeout: 10 * time.Second},
+		address:         strings.TrimSuffix(address, "/"),
+		token:           token,
+		path:            strings.Trim(path, "/"),
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Credentials returns the current credentials, renewing their lease or
+// reading the secret first when they are due
+func (v *Vault) Credentials(ctx context.Context) (Credentials, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.fetched.IsZero() {
+		return v.read(ctx)
+	}
+
+	if v.stale {
+		return v.refresh(ctx)
+	}
+
+	if v.lease.id == "" {
+		if time.Since(v.fetched) < v.refreshInterval {
+			return v.credentials, nil
+		}
+		return v.refresh(ctx)
+	}
+
+	if time.Now().Before(v.lease.renewAt) {
+		return v.credentials, nil
+	}
+
+	if v.lease.renewable {
+		err := v.renew(ctx)
+		if err == nil {
+			return v.credentials, nil
+		}
+		slog.WarnContext(ctx, "Failed to renew Vault lease, reading new credentials", "path", v.path, "error", err)
+	}
+
+	return v.refresh(ctx)
+}
+
+// Invalidate reads the secret again on the next request for credentials,
+// which for a secrets engine generates new ones
+func (v *Vault) Invalidate() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if time.Since(v.fetched) >= minInvalidateInterval {
+		v.stale = true
+	}
+}
+
+// Watch renews the lease or reads the secret whenever it is due until the
+// context is cancelled, so that credentials stay valid while no new
+// connections ask for them
+func (v *Vault) Watch(ctx context.Context) {
+	for {
+		timer := time.NewTimer(v.nextRefresh())
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if _, err := v.Credentials(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to read from Vault", "path", v.path, "error", err)
+			}
+		}
+	}
+}
+
+// nextRefresh returns how long until the credentials are due to be renewed
+// or read again
+func (v *Vault) nextRefresh() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.fetched.IsZero() {
+		return time.Second
+	}
+	if v.lease.id != "" {
+		return max(time.Until(v.lease.renewAt), time.Second)
+	}
+	return max(time.Until(v.fetched.Add(v.refreshInterval)), time.Second)
+}
+
+// refresh reads the secret again, keeping the current credentials if that
+// fails since they may still be valid
+func (v *Vault) refresh(ctx context.Context) (Credentials, error) {
+	credentials, err := v.read(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read from Vault, using cached credentials", "path", v.path, "error", err)
+		v.fetched = time.Now()
+		v.stale = false
+		return v.credentials, nil
+	}
+	return credentials, nil
+}
+
+// read reads the secret, unwrapping the data of KV version 2 secrets
+func (v *Vault) read(ctx context.Context) (Credentials, error) {
+	response, err := v.call(ctx, http.MethodGet, v.path, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	data := response.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	password, _ := data["password"].(string)
+	if password == "" {
+		return Credentials{}, fmt.Errorf("secret %s in Vault has no password", v.path)
+	}
+	username, _ := data["username"].(string)
+
+	v.credentials = Credentials{Username: username, Password: password}
+	v.fetched = time.Now()
+	v.stale = false
+	v.lease = vaultLease{}
+	if response.LeaseID != "" {
+		v.setLease(response)
+	}
+
+	return v.credentials, nil
+}
+
+// renew extends the lease of generated credentials
+func (v *Vault) renew(ctx context.Context) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"lease_id":  v.lease.id,
+		"increment": int(v.lease.duration.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := v.call(ctx, http.MethodPut, "sys/leases/renew", body)
+	if err != nil {
+		return err
+	}
+
+	// A lease can't be renewed past its maximum TTL, so the duration shrinks
+	// as it approaches it and the next renewal comes sooner
+	if response.LeaseDuration <= 0 {
+		return fmt.Errorf("lease %s has expired", v.lease.id)
+	}
+
+	v.setLease(response)
+	return nil
+}
+
+// setLease records the lease from a response. The duration of the original
+// lease is kept as the increment asked for when renewing.
+func (v *Vault) setLease(response *vaultResponse) {
+	duration := time.Duration(response.LeaseDuration) * time.Second
+	if v.lease.duration == 0 {
+		v.lease.duration = duration
+	}
+
+	v.lease.id = response.LeaseID
+	v.lease.renewable = response.Renewable
+	v.lease.renewAt = time.Now().Add(time.Duration(float64(duration) * vaultRenewFraction))
+}
+
+// call makes a request to the Vault HTTP API
+func (v *Vault) call(ctx context.Context, method, path string, body []byte) (*vaultResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, v.address+"/v1/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Vault: %w", err)
+	}
+	defer res.Body.Close()
+
+	var response vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil && res.StatusCode < 300 {
+		return nil, fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("Vault returned %s for %s: %s", res.Status, path, strings.Join(response.Errors, "; "))
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := func(accessLog config.AccessLogConfiguration) *gin.Engine {
+		r := gin.New()
+		r.Use(middleware.RequestID())
+		r.Use(middleware.NewAccessLog(accessLog, "/healthz"))
+		r.GET("/products/:id", func(c *gin.Context) {
+			if c.Query("sleep") != "" {
+				time.Sleep(20 * time.Millisecond)
+			}
+			c.String(http.StatusOK, "product")
+		})
+		r.GET("/fail", func(c *gin.Context) {
+			c.Status(http.StatusInternalServerError)
+		})
+		r.GET("/healthz", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	get := func(r *gin.Engine, path string) {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "loadgen/1.0")
+		req.Header.Set(middleware.RequestIDHeader, "req-123")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("Request fields", func(t *testing.T) {
+		buffer := captureLogs(t, "info")
+
+		get(router(config.AccessLogConfiguration{Enabled: true, SampleRate: 1}), "/products/a1")
+
+		records := logRecords(t, buffer)
+		require.Len(t, records, 1)
+		assert.Equal(t, "INFO", records[0]["level"])
+		assert.Equal(t, "GET", records[0]["method"])
+		assert.Equal(t, "/products/a1", records[0]["path"])
+		assert.Equal(t, "/products/:id", records[0]["route"])
+		assert.EqualValues(t, 200, records[0]["status"])
+		assert.EqualValues(t, 7, records[0]["bytes"])
+		assert.Contains(t, records[0], "latency_ms")
+		assert.Equal(t, "loadgen/1.0", records[0]["user_agent"])
+		assert.Equal(t, "req-123", records[0]["request_id"])
+	})
+
+	t.Run("Skipped paths", func(t *testing.T) {
+		buffer := captureLogs(t, "info")
+
+		r := router(config.AccessLogConfiguration{Enabled: true, SampleRate: 1, SkipPaths: []string{"/products/a1"}})
+		get(r, "/healthz")
+		get(r, "/products/a1")
+		get(r, "/products/b2")
+
+		records := logRecords(t, buffer)
+		require.Len(t, records, 1)
+		assert.Equal(t, "/products/b2", records[0]["path"])
+	})
+
+	t.Run("Sampling keeps errors and slow requests", func(t *testing.T) {
+		buffer := captureLogs(t, "info")
+
+		r := router(config.AccessLogConfiguration{Enabled: true, SampleRate: 0, SlowThreshold: 10 * time.Millisecond})
+		get(r, "/products/a1")
+		get(r, "/products/a1?sleep=1")
+		get(r, "/fail")
+
+		records := logRecords(t, buffer)
+		require.Len(t, records, 2)
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, "ERROR", records[1]["level"])
+		assert.EqualValues(t, 500, records[1]["status"])
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		buffer := captureLogs(t, "info")
+
+		get(router(config.AccessLogConfiguration{SampleRate: 1}), "/fail")
+		assert.Empty(t, logRecords(t, buffer))
+	})
+}
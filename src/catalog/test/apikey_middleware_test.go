@@ -0,0 +1,76 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func setupAPIKeyRouter(keys []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := middleware.NewAuth(middleware.NewAPIKeyAuth(keys))
+
+	router.Use(auth.Identify())
+	router.GET("/public", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ClientIDKey))
+	})
+	router.POST("/admin", auth.Require(middleware.PermissionWrite), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ClientIDKey))
+	})
+
+	return router
+}
+
+func apiKeyRequest(router *gin.Engine, method, path, key string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set(middleware.APIKeyHeader, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupAPIKeyRouter([]string{"first-key", " second-key "})
+
+	t.Run("Public endpoints stay open", func(t *testing.T) {
+		w := apiKeyRequest(router, "GET", "/public", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("Missing key", func(t *testing.T) {
+		w := apiKeyRequest(router, "POST", "/admin", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "ApiKey", w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, apiKeyRequest(router, "POST", "/admin", "wrong").Code)
+		assert.Equal(t, http.StatusUnauthorized, apiKeyRequest(router, "GET", "/public", "wrong").Code)
+	})
+
+	t.Run("Valid keys identify the client", func(t *testing.T) {
+		first := apiKeyRequest(router, "POST", "/admin", "first-key")
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Regexp(t, "^key:[0-9a-f]{16}$", first.Body.String())
+
+		second := apiKeyRequest(router, "GET", "/public", "second-key")
+		assert.Equal(t, http.StatusOK, second.Code)
+		assert.NotEqual(t, first.Body.String(), second.Body.String())
+	})
+}
+
+func TestAPIKeyAuthDisabled(t *testing.T) {
+	router := setupAPIKeyRouter(nil)
+
+	assert.Equal(t, http.StatusOK, apiKeyRequest(router, "POST", "/admin", "").Code)
+	assert.Equal(t, http.StatusOK, apiKeyRequest(router, "POST", "/admin", "anything").Code)
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestParseAttributeFilter(t *testing.T) {
+	for _, filter := range []string{"brand:Velocity Motors", "size:S:M", "seats:2..", "seats:..4", "weight:0.5..1.5"} {
+		parsed, err := model.ParseAttributeFilter(filter)
+		require.NoError(t, err, filter)
+		assert.Equal(t, filter, parsed.String())
+	}
+
+	for _, filter := range []string{"brand", "brand:", ":cotton", "seats:..", "seats:4..2", "seats:few..", "seats:1..many"} {
+		_, err := model.ParseAttributeFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	search := func(keyword string, filters ...string) model.SearchResponse {
+		query := url.Values{"keyword": {keyword}, "attribute": filters}
+		w := send("GET", "/v2/catalog/search?"+query.Encode(), "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	attributes := func(id string) string {
+		w := send("GET", "/catalog/products/"+id+"?fields=attributes", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product struct {
+			Attributes json.RawMessage `json:"attributes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return string(product.Attributes)
+	}
+
+	const aquaAce = "a1258cd2-176c-4507-ade6-746dab5ad625"
+	const skycycle = "d3104128-1d14-4465-99d3-8ab9267c687b"
+	const phantom = "d77f9ae6-e9a8-4a3e-86bd-b72af75cbc49"
+
+	t.Run("Seeded attributes", func(t *testing.T) {
+		assert.JSONEq(t, `[
+			{"name": "brand", "type": "string", "value": "Velocity Motors"},
+			{"name": "material", "type": "string", "value": "carbon fiber"},
+			{"name": "seats", "type": "number", "value": "2"},
+			{"name": "waterproof", "type": "boolean", "value": "true"}
+		]`, attributes(aquaAce))
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{aquaAce, skycycle, phantom}, productIDs(search("features", "brand:Velocity Motors").Products))
+		assert.ElementsMatch(t, []string{aquaAce, phantom}, productIDs(search("features", "brand:Velocity Motors", "seats:2..").Products))
+		assert.ElementsMatch(t, []string{aquaAce, skycycle}, productIDs(search("features", "seats:..2").Products))
+		assert.Equal(t, []string{aquaAce}, productIDs(search("features", "seats:2.0").Products), "numbers match however they're written")
+		assert.Contains(t, productIDs(search("features", "waterproof:true").Products), aquaAce)
+		assert.Empty(t, search("features", "brand:Velocity Motors", "material:brass").Products)
+		assert.Empty(t, search("features", "material:1..").Products, "ranges only match numbers")
+
+		w := send("GET", "/v2/catalog/search?keyword=features&attribute=seats:4..2", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Facets", func(t *testing.T) {
+		response := search("features", "brand:Velocity Motors")
+		assert.Equal(t, map[string]int{"Velocity Motors": 3}, response.Facets[repository.FacetAttributePrefix+"brand"])
+		assert.Equal(t, map[string]int{"1": 1, "2": 1, "4": 1}, response.Facets[repository.FacetAttributePrefix+"seats"])
+		assert.Equal(t, map[string]int{"true": 1}, response.Facets[repository.FacetAttributePrefix+"waterproof"])
+		assert.NotContains(t, response.Facets, repository.FacetAttributePrefix+"faces")
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "tee", "name": "Tee", "attributes": {"material": "cotton", "weight": 120.0, "organic": true}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.JSONEq(t, `[
+			{"name": "material", "type": "string", "value": "cotton"},
+			{"name": "organic", "type": "boolean", "value": "true"},
+			{"name": "weight", "type": "number", "value": "120"}
+		]`, attributes("tee"))
+
+		// Attributes are kept when a change doesn't mention them
+		w = send("PUT", "/catalog/products/tee", `{"name": "Tee"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = send("PATCH", "/catalog/products/tee", `{"name": "T-shirt"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, attributes("tee"), "cotton")
+
+		w = send("PATCH", "/catalog/products/tee", `{"attributes": {"weight": 150}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `[
+			{"name": "material", "type": "string", "value": "cotton"},
+			{"name": "organic", "type": "boolean", "value": "true"},
+			{"name": "weight", "type": "number", "value": "150"}
+		]`, attributes("tee"))
+
+		w = send("PUT", "/catalog/products/tee", `{"name": "Tee", "attributes": {}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "null", attributes("tee"))
+
+		for _, body := range []string{
+			`{"name": "Bad", "attributes": {"sizes": ["S", "M"]}}`,
+			`{"name": "Bad", "attributes": {"": "blank"}}`,
+		} {
+			w = send("POST", "/catalog/products", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
+
+func TestOpenSearchAttributes(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "_source": {
+			"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"},
+			"attributes": [{"name": "weight", "type": "number", "value": "120", "number": 120}]
+		}}]}, "aggregations": {"attributes": {"doc_count": 3, "names": {"buckets": [
+			{"key": "material", "doc_count": 2, "values": {"buckets": [{"key": "cotton", "doc_count": 2}]}},
+			{"key": "weight", "doc_count": 1, "values": {"buckets": [{"key": "120", "doc_count": 1}]}}
+		]}}}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	cotton, weight := "cotton", "120"
+	minimum := 100.0
+	result, err := search.SearchProducts(repository.SearchQuery{
+		Keyword: "tee",
+		Attributes: []model.AttributeFilter{
+			{Name: "material", Value: &cotton},
+			{Name: "weight", Value: &weight},
+			{Name: "weight", Min: &minimum},
+		},
+		Facets: true,
+	}, 1, 10, context.Background())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": [
+				{"nested": {"path": "attributes", "query": {"bool": {"filter": [
+					{"term": {"attributes.name": "material"}},
+					{"term": {"attributes.value": "cotton"}}
+				]}}}},
+				{"nested": {"path": "attributes", "query": {"bool": {"filter": [
+					{"term": {"attributes.name": "weight"}},
+					{"bool": {"minimum_should_match": 1, "should": [
+						{"term": {"attributes.value": "120"}},
+						{"term": {"attributes.number": 120}}
+					]}}
+				]}}}},
+				{"nested": {"path": "attributes", "query": {"bool": {"filter": [
+					{"term": {"attributes.name": "weight"}},
+					{"range": {"attributes.number": {"gte": 100}}}
+				]}}}},
+				{"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+			]
+		}
+	}`, searchQueryOf(t, query))
+
+	products := result.Products
+	require.Len(t, products, 1)
+	require.Len(t, products[0].Attributes, 1)
+	assert.Equal(t, "p1", products[0].Attributes[0].ProductID)
+	assert.Equal(t, 120.0, *products[0].Attributes[0].Number)
+	assert.Equal(t, map[string]map[string]int{
+		repository.FacetAttributePrefix + "material": {"cotton": 2},
+		repository.FacetAttributePrefix + "weight":   {"120": 1},
+	}, result.Facets)
+}
@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/audit"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// memoryAuditLog keeps the entries recorded in it
+type memoryAuditLog struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+func (l *memoryAuditLog) Record(entry model.AuditEntry, ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *memoryAuditLog) last(t *testing.T) model.AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	require.NotEmpty(t, l.entries)
+	return l.entries[len(l.entries)-1]
+}
+
+func TestAuditLog(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	auditLog := &memoryAuditLog{}
+	catalogAPI.SetAuditLog(auditLog)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	auth := middleware.NewAuth(middleware.NewAPIKeyAuth([]string{"audit-key"}))
+	writes := r.Group("/catalog", auth.Identify(), auth.Require(middleware.PermissionWrite))
+	writes.POST("/products", c.CreateProduct)
+	writes.PUT("/products/:id", c.UpdateProduct)
+	writes.PATCH("/products/:id", c.PatchProduct)
+	writes.DELETE("/products/:id", c.DeleteProduct)
+	r.POST("/admin/imports", auth.Identify(), auth.Require(middleware.PermissionWrite), c.StartImport)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.APIKeyHeader, "audit-key")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "audit-1", "name": "Audited", "price": 10}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		entry := auditLog.last(t)
+		assert.Equal(t, model.AuditProductCreate, entry.Action)
+		assert.Equal(t, model.AuditSucceeded, entry.Outcome)
+		assert.Regexp(t, "^key:[0-9a-f]{16}$", entry.Actor)
+		assert.Equal(t, []string{"audit-1"}, entry.ProductIDs)
+		assert.Nil(t, entry.Before)
+		require.NotNil(t, entry.After)
+		assert.Equal(t, "Audited", entry.After.Name)
+		assert.False(t, entry.Time.IsZero())
+	})
+
+	t.Run("Update and patch", func(t *testing.T) {
+		w := send("PUT", "/catalog/products/audit-1", `{"name": "Renamed", "price": 20}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		entry := auditLog.last(t)
+		assert.Equal(t, model.AuditProductUpdate, entry.Action)
+		require.NotNil(t, entry.Before)
+		require.NotNil(t, entry.After)
+		assert.Equal(t, "Audited", entry.Before.Name)
+		assert.Equal(t, "Renamed", entry.After.Name)
+
+		w = send("PATCH", "/catalog/products/audit-1", `{"price": 30}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		entry = auditLog.last(t)
+		assert.Equal(t, model.AuditProductPatch, entry.Action)
+		require.NotNil(t, entry.Before)
+		assert.Equal(t, 20, entry.Before.Price.Units())
+		assert.Equal(t, 30, entry.After.Price.Units())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/audit-1", "").Code)
+
+		entry := auditLog.last(t)
+		assert.Equal(t, model.AuditProductDelete, entry.Action)
+		require.NotNil(t, entry.Before)
+		assert.Equal(t, "Renamed", entry.Before.Name)
+		assert.Nil(t, entry.After)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send("DELETE", "/catalog/products/audit-1", "").Code)
+
+		entry := auditLog.last(t)
+		assert.Equal(t, model.AuditProductDelete, entry.Action)
+		assert.Equal(t, model.AuditFailed, entry.Outcome)
+		assert.NotEmpty(t, entry.Error)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		w := send("POST", "/admin/imports", `[{"id": "audit-2", "name": "Imported", "price": 5}, {"id": "audit-3", "name": "Imported", "price": 6}]`)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+		var job model.ImportJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+
+		entry := auditLog.last(t)
+		assert.Equal(t, model.AuditCatalogImport, entry.Action)
+		assert.Equal(t, job.ID, entry.JobID)
+		assert.Equal(t, []string{"audit-2", "audit-3"}, entry.ProductIDs)
+
+		assert.Equal(t, model.ImportCompleted, waitForImport(t, catalogAPI, job.ID).Status)
+		assert.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/audit-2", "").Code)
+		assert.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/audit-3", "").Code)
+	})
+
+	t.Run("Anonymous clients", func(t *testing.T) {
+		writable := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+		catalogAPI, err := api.NewCatalogAPI(writable, nil)
+		require.NoError(t, err)
+		catalogAPI.SetAuditLog(auditLog)
+
+		_, err = catalogAPI.CreateProduct(&model.Product{ID: "audit-4", Name: "Anonymous", Price: model.Money{Amount: 1}}, context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, audit.Anonymous, auditLog.last(t).Actor)
+
+		require.NoError(t, catalogAPI.DeleteProduct("audit-4", audit.WithActor(context.Background(), "sub:alice")))
+		assert.Equal(t, "sub:alice", auditLog.last(t).Actor)
+	})
+}
+
+func TestAuditLogSinks(t *testing.T) {
+	entry := model.AuditEntry{
+		Actor:      "sub:alice",
+		Action:     model.AuditProductUpdate,
+		Outcome:    model.AuditSucceeded,
+		ProductIDs: []string{"a1"},
+		Before:     &model.Product{ID: "a1", Name: "Before", Price: model.Money{Amount: 1}},
+		After:      &model.Product{ID: "a1", Name: "After", Price: model.Money{Amount: 2}},
+	}
+
+	t.Run("Writer", func(t *testing.T) {
+		var buffer bytes.Buffer
+		require.NoError(t, audit.NewWriterLog(&buffer).Record(entry, context.Background()))
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buffer.Bytes(), &record))
+		assert.Equal(t, "audit", record["msg"])
+		assert.Equal(t, "sub:alice", record["actor"])
+		assert.Equal(t, model.AuditProductUpdate, record["action"])
+		assert.Equal(t, []any{"a1"}, record["productIds"])
+		assert.Equal(t, "Before", record["before"].(map[string]any)["name"])
+		assert.Equal(t, "After", record["after"].(map[string]any)["name"])
+		assert.NotContains(t, record, "jobId")
+	})
+
+	t.Run("OpenSearch", func(t *testing.T) {
+		var paths, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			paths = append(paths, r.Method+" "+r.URL.Path)
+			bodies = append(bodies, string(body))
+
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/" {
+				io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"result": "created"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		client, err := repository.NewOpenSearchClient(config.OpenSearchConfiguration{Endpoint: server.URL})
+		require.NoError(t, err)
+
+		require.NoError(t, audit.NewOpenSearchLog(client, "catalog-audit").Record(entry, context.Background()))
+		require.Len(t, paths, 2)
+		assert.Equal(t, "POST /catalog-audit/_doc", paths[1])
+
+		var indexed model.AuditEntry
+		require.NoError(t, json.Unmarshal([]byte(bodies[1]), &indexed))
+		assert.Equal(t, entry.Actor, indexed.Actor)
+		assert.Equal(t, "After", indexed.After.Name)
+	})
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestBulkheads(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	t.Cleanup(func() {
+		repository.SetBulkhead(repository.DependencyDatabase, 0, 0)
+		repository.SetBulkhead(repository.DependencyOpenSearch, 0, 0)
+	})
+
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+	const productID = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("Slow searches don't hold up lookups", func(t *testing.T) {
+		search, searches, release := newHeldSearch(t, false)
+		require.NoError(t, repository.SetBulkhead(repository.DependencyOpenSearch, 1, 20*time.Millisecond))
+		defer repository.SetBulkhead(repository.DependencyOpenSearch, 0, 0)
+
+		held := make(chan error)
+		go func() {
+			_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+			held <- err
+		}()
+		require.Eventually(t, func() bool { return searches.Load() == 1 }, time.Second, time.Millisecond)
+
+		_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 2, 10, ctx)
+		var full *repository.BulkheadFullError
+		require.ErrorAs(t, err, &full)
+		assert.Equal(t, repository.DependencyOpenSearch, full.Dependency)
+		assert.EqualValues(t, 1, searches.Load(), "the turned away search isn't sent")
+
+		product, err := db.GetProduct(productID, ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, product.Tags)
+
+		close(release)
+		require.NoError(t, <-held)
+
+		// The slot is given back once the response has been read
+		_, err = search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 2, 10, ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		require.NoError(t, repository.SetBulkhead(repository.DependencyDatabase, 1, 20*time.Millisecond))
+		defer repository.SetBulkhead(repository.DependencyDatabase, 0, 0)
+
+		// Loading the product's tags and category shares its slot
+		_, err := db.GetProduct(productID, ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, repository.SetFault(repository.DependencyDatabase, repository.Fault{Latency: 200 * time.Millisecond}))
+		defer repository.ClearFaults()
+
+		catalogAPI, err := api.NewCatalogAPI(db, nil)
+		require.NoError(t, err)
+		c, err := controller.NewController(catalogAPI)
+		require.NoError(t, err)
+
+		r := gin.New()
+		r.GET("/catalog/products/:id", c.GetProduct)
+
+		held := make(chan error)
+		go func() {
+			_, err := db.GetProduct(productID, ctx)
+			held <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/products/"+productID, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "database bulkhead is full")
+
+		require.NoError(t, <-held)
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_bulkhead_in_flight Calls holding a slot in the bulkhead of each dependency
+# TYPE catalog_bulkhead_in_flight gauge
+catalog_bulkhead_in_flight{dependency="database"} 0
+catalog_bulkhead_in_flight{dependency="opensearch"} 0
+# HELP catalog_bulkhead_rejected_total Calls turned away because the bulkhead of their dependency stayed full
+# TYPE catalog_bulkhead_rejected_total counter
+catalog_bulkhead_rejected_total{dependency="database"} 1
+catalog_bulkhead_rejected_total{dependency="opensearch"} 1
+`), "catalog_bulkhead_in_flight", "catalog_bulkhead_rejected_total"))
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// slowIndexer takes a while over each batch, recording the most batches it
+// was sent at once, and fails the batch containing the product failOn
+type slowIndexer struct {
+	delay  time.Duration
+	failOn string
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+
+	mu      sync.Mutex
+	indexed []string
+}
+
+func (s *slowIndexer) ResetIndex(ctx context.Context) error {
+	return nil
+}
+
+func (s *slowIndexer) IndexProducts(products []model.Product, ctx context.Context) (int, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		highest := s.maxInFlight.Load()
+		if n <= highest || s.maxInFlight.CompareAndSwap(highest, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, product := range products {
+		if product.ID == s.failOn {
+			return 0, errors.New("bulk request rejected")
+		}
+	}
+	// The first product of every batch is rejected
+	for _, product := range products[1:] {
+		s.indexed = append(s.indexed, product.ID)
+	}
+	return 1, nil
+}
+
+// produceBatches sends count batches of size products, recording how many
+// were read
+func produceBatches(count, size int, read *atomic.Int32) func(send func([]model.Product) error) error {
+	return func(send func([]model.Product) error) error {
+		for i := range count {
+			batch := make([]model.Product, size)
+			for j := range batch {
+				batch[j] = model.Product{ID: fmt.Sprintf("p%d-%d", i, j)}
+			}
+			read.Add(1)
+			if err := send(batch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestIndexBatches(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	ctx := context.Background()
+
+	t.Run("Workers index concurrently", func(t *testing.T) {
+		indexer := &slowIndexer{delay: 20 * time.Millisecond}
+		var read atomic.Int32
+		var indexed, failed atomic.Int32
+
+		started := time.Now()
+		err := repository.IndexBatches(indexer, 4, produceBatches(20, 5, &read), func(i, f int) {
+			indexed.Add(int32(i))
+			failed.Add(int32(f))
+		}, ctx)
+		require.NoError(t, err)
+
+		// 20 batches four at a time take five rounds rather than twenty
+		assert.Less(t, time.Since(started), 200*time.Millisecond)
+		assert.EqualValues(t, 4, indexer.maxInFlight.Load())
+		assert.EqualValues(t, 80, indexed.Load())
+		assert.EqualValues(t, 20, failed.Load())
+		assert.Len(t, indexer.indexed, 80)
+	})
+
+	t.Run("Reading is bounded by the workers", func(t *testing.T) {
+		indexer := &slowIndexer{delay: time.Hour}
+		var read atomic.Int32
+
+		timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		err := repository.IndexBatches(indexer, 2, produceBatches(100, 5, &read), func(int, int) {}, timeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Two batches being indexed and one waiting to be sent
+		assert.EqualValues(t, 3, read.Load())
+	})
+
+	t.Run("First error stops the workers", func(t *testing.T) {
+		indexer := &slowIndexer{delay: 5 * time.Millisecond, failOn: "p3-0"}
+		var read atomic.Int32
+
+		err := repository.IndexBatches(indexer, 2, produceBatches(100, 5, &read), func(int, int) {}, ctx)
+		assert.EqualError(t, err, "bulk request rejected")
+		assert.Less(t, read.Load(), int32(10))
+		assert.Zero(t, indexer.inFlight.Load())
+	})
+
+	t.Run("Reading fails", func(t *testing.T) {
+		indexer := &slowIndexer{}
+		failure := errors.New("database unavailable")
+
+		err := repository.IndexBatches(indexer, 2, func(send func([]model.Product) error) error {
+			return failure
+		}, func(int, int) {}, ctx)
+		assert.ErrorIs(t, err, failure)
+	})
+
+	// Which worker took each batch varies, so only the totals are known
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	outcomes := map[string]float64{}
+	workers := map[string]bool{}
+	for _, family := range families {
+		if family.GetName() != "catalog_index_worker_batches_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			outcomes[labels["outcome"]] += metric.GetCounter().GetValue()
+			workers[labels["worker"]] = true
+		}
+	}
+	assert.GreaterOrEqual(t, outcomes["success"], 23.0)
+	assert.GreaterOrEqual(t, outcomes["error"], 3.0)
+	assert.Equal(t, map[string]bool{"0": true, "1": true, "2": true, "3": true}, workers)
+}
@@ -0,0 +1,216 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/cache"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// countedRepository counts the products looked up in the repository it wraps
+type countedRepository struct {
+	repository.WritableCatalogRepository
+	lookups atomic.Int32
+}
+
+func (r *countedRepository) GetProduct(id string, ctx context.Context) (*model.Product, error) {
+	r.lookups.Add(1)
+	return r.WritableCatalogRepository.GetProduct(id, ctx)
+}
+
+// countedSearch counts the searches it answers
+type countedSearch struct {
+	stubSearch
+	searches atomic.Int32
+}
+
+func (s *countedSearch) SearchProducts(query repository.SearchQuery, page, size int, ctx context.Context) (repository.SearchResult, error) {
+	s.searches.Add(1)
+	return s.stubSearch.SearchProducts(query, page, size, ctx)
+}
+
+func TestLRU(t *testing.T) {
+	load := func(value string) func() (string, error) {
+		return func() (string, error) { return value, nil }
+	}
+
+	t.Run("Evicts the least recently used", func(t *testing.T) {
+		lru := cache.New[string, string]("test", 2, time.Minute)
+		lru.Get("a", load("1"))
+		lru.Get("b", load("2"))
+		lru.Get("a", load("ignored"))
+		lru.Get("c", load("3"))
+
+		assert.Equal(t, 2, lru.Len())
+		value, _ := lru.Get("a", load("reloaded"))
+		assert.Equal(t, "1", value)
+		value, _ = lru.Get("b", load("reloaded"))
+		assert.Equal(t, "reloaded", value)
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		lru := cache.New[string, string]("test", 2, 20*time.Millisecond)
+		lru.Get("a", load("1"))
+		time.Sleep(30 * time.Millisecond)
+
+		value, _ := lru.Get("a", load("2"))
+		assert.Equal(t, "2", value)
+	})
+
+	t.Run("Errors aren't cached", func(t *testing.T) {
+		lru := cache.New[string, string]("test", 2, time.Minute)
+		_, err := lru.Get("a", func() (string, error) { return "", errors.New("unavailable") })
+		assert.Error(t, err)
+
+		value, err := lru.Get("a", load("1"))
+		require.NoError(t, err)
+		assert.Equal(t, "1", value)
+	})
+
+	t.Run("Invalidated while loading", func(t *testing.T) {
+		lru := cache.New[string, string]("test", 2, time.Minute)
+		value, _ := lru.Get("a", func() (string, error) {
+			lru.Remove("a")
+			return "stale", nil
+		})
+		assert.Equal(t, "stale", value)
+
+		value, _ = lru.Get("a", load("fresh"))
+		assert.Equal(t, "fresh", value)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		lru := cache.New[string, string]("test", 2, time.Minute)
+		lru.Get("a", load("1"))
+		lru.Get("b", load("2"))
+		lru.Purge()
+
+		assert.Equal(t, 0, lru.Len())
+	})
+}
+
+func TestResponseCache(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, cache.RegisterMetrics(registry))
+
+	db := &countedRepository{WritableCatalogRepository: newInMemoryRepository(t).(repository.WritableCatalogRepository)}
+	search := &countedSearch{stubSearch: stubSearch{ids: []string{"cache-1"}}}
+
+	catalogAPI, err := api.NewCatalogAPI(db, search)
+	require.NoError(t, err)
+	catalogAPI.SetCache(10, time.Minute)
+	ctx := context.Background()
+
+	const id = "cache-1"
+	_, err = catalogAPI.CreateProduct(&model.Product{ID: id, Name: "Cached", Price: model.Money{Amount: 1}}, ctx)
+	require.NoError(t, err)
+
+	t.Run("Product lookups", func(t *testing.T) {
+		first, err := catalogAPI.GetProduct(id, ctx)
+		require.NoError(t, err)
+
+		// Changing the product returned doesn't change the cache
+		first.Name = "Changed by the caller"
+
+		second, err := catalogAPI.GetProduct(id, ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, "Changed by the caller", second.Name)
+		assert.Equal(t, int32(1), db.lookups.Load())
+	})
+
+	t.Run("Missing products aren't cached", func(t *testing.T) {
+		db.lookups.Store(0)
+		for range 2 {
+			_, err := catalogAPI.GetProduct("missing", ctx)
+			assert.Error(t, err)
+		}
+		assert.Equal(t, int32(2), db.lookups.Load())
+	})
+
+	t.Run("Searches", func(t *testing.T) {
+		for range 3 {
+			products, err := catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses"}, 1, 10, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{id}, productIDs(products))
+		}
+		assert.Equal(t, int32(1), search.searches.Load())
+
+		_, err := catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses"}, 2, 10, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), search.searches.Load())
+	})
+
+	t.Run("Writes invalidate", func(t *testing.T) {
+		db.lookups.Store(0)
+		search.searches.Store(0)
+
+		product, err := catalogAPI.GetProduct(id, ctx)
+		require.NoError(t, err)
+		product.Name = "Renamed"
+		_, err = catalogAPI.UpdateProduct(product, ctx)
+		require.NoError(t, err)
+
+		updated, err := catalogAPI.GetProduct(id, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", updated.Name)
+		assert.Equal(t, int32(1), db.lookups.Load())
+
+		_, err = catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), search.searches.Load())
+	})
+
+	t.Run("Reindex invalidates", func(t *testing.T) {
+		search.searches.Store(0)
+		require.NoError(t, catalogAPI.Reindex(ctx))
+
+		_, err := catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), search.searches.Load())
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_cache_requests_total Cache lookups, by cache, tier and whether the value was cached
+# TYPE catalog_cache_requests_total counter
+catalog_cache_requests_total{cache="products",result="hit",tier="memory"} 2
+catalog_cache_requests_total{cache="products",result="miss",tier="memory"} 4
+catalog_cache_requests_total{cache="search",result="hit",tier="memory"} 2
+catalog_cache_requests_total{cache="search",result="miss",tier="memory"} 4
+`), "catalog_cache_requests_total"))
+}
+
+func TestResponseCache_Fields(t *testing.T) {
+	search := &countedSearch{stubSearch: stubSearch{ids: []string{"cache-fields"}}}
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+	require.NoError(t, err)
+	catalogAPI.SetCache(10, time.Minute)
+	ctx := context.Background()
+
+	_, err = catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses", Fields: []string{"id"}}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), search.searches.Load())
+
+	// Results loaded with only some fields don't answer a full search
+	_, err = catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses"}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), search.searches.Load())
+
+	// The ID is always loaded and the order fields are asked for in doesn't matter
+	_, err = catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses", Fields: []string{"name"}}, 1, 10, ctx)
+	require.NoError(t, err)
+	_, err = catalogAPI.SearchProducts(repository.SearchQuery{Keyword: "sunglasses", Fields: []string{"name", "id"}}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), search.searches.Load())
+}
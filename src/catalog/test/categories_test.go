@@ -0,0 +1,251 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestCategoryFilter(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		category string
+		expected int
+	}{
+		{"accessories", 5},
+		{"gadgets", 3},
+		{"vehicles", 3},
+		{"cars", 2},
+		{"missing", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			filter := repository.ProductFilter{Category: tt.category}
+
+			products, err := db.GetProducts(filter, "", 1, 20, ctx)
+			require.NoError(t, err)
+			assert.Len(t, products, tt.expected)
+
+			count, err := db.CountProducts(filter, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, count)
+		})
+	}
+
+	products, err := db.GetProducts(repository.ProductFilter{Category: "gadgets"}, "", 1, 20, ctx)
+	require.NoError(t, err)
+	for _, product := range products {
+		require.NotNil(t, product.Category)
+		assert.Equal(t, "accessories/gadgets", product.Category.Path)
+	}
+}
+
+func TestCreateProductCategory(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	unknown := "nope"
+	err := writable.CreateProduct(&model.Product{ID: "category-1", Name: "Test", CategoryName: &unknown}, ctx)
+	assert.ErrorIs(t, err, repository.ErrUnknownCategory)
+
+	category := "eyewear"
+	product := &model.Product{ID: "category-2", Name: "Test", CategoryName: &category}
+	require.NoError(t, writable.CreateProduct(product, ctx))
+	require.NotNil(t, product.Category)
+	assert.Equal(t, "accessories/eyewear", product.Category.Path)
+
+	count, err := writable.CountProducts(repository.ProductFilter{Category: "accessories"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+}
+
+func TestCatalogCategories(t *testing.T) {
+	db := newInMemoryRepository(t)
+
+	catalogAPI, err := api.NewCatalogAPI(db, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/categories", c.ListCategories)
+	r.GET("/catalog/categories/:name/products", c.GetCategoryProducts)
+
+	t.Run("Tree", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/categories", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var tree []model.CategoryNode
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tree))
+
+		names := map[string][]string{}
+		for _, node := range tree {
+			assert.Nil(t, node.ParentName)
+			for _, child := range node.Children {
+				assert.Equal(t, node.Name, *child.ParentName)
+				assert.Equal(t, node.Path+"/"+child.Name, child.Path)
+				names[node.Name] = append(names[node.Name], child.Name)
+			}
+		}
+		assert.ElementsMatch(t, []string{"timepieces", "eyewear", "gadgets"}, names["accessories"])
+		assert.ElementsMatch(t, []string{"cars", "motorcycles"}, names["vehicles"])
+	})
+
+	t.Run("Products including descendants", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/categories/vehicles/products?sort=price", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		require.Len(t, products, 3)
+		assert.LessOrEqual(t, products[0].Price.Amount, products[1].Price.Amount)
+	})
+
+	t.Run("Unknown category", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/categories/missing/products", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestCategoryTaxonomy(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/categories/:name", c.GetCategory)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.GET("/catalog/search", c.SearchProducts)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	search := func(url string) []string {
+		w := get(url)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		return productIDs(products)
+	}
+
+	const quill = "d27cf49f-b689-4a75-a249-d373e0330bb5"
+	const forgetter = "1ca35e86-4b4c-4124-b6b5-076ba4134d0d"
+	const teleporter = "631a3db5-ac07-492c-a994-8cd56923c112"
+	const tickstopper = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("Category", func(t *testing.T) {
+		w := get("/catalog/categories/gadgets")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"name": "gadgets", "displayName": "Gadgets", "parent": "accessories", "path": "accessories/gadgets", "breadcrumbs": [
+			{"name": "accessories", "displayName": "Accessories"},
+			{"name": "gadgets", "displayName": "Gadgets"}
+		]}`, w.Body.String())
+
+		assert.Equal(t, http.StatusNotFound, get("/catalog/categories/missing").Code)
+	})
+
+	t.Run("Product breadcrumbs", func(t *testing.T) {
+		w := get("/catalog/products/" + forgetter)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		require.NotNil(t, product.Category)
+		assert.Equal(t, []model.Breadcrumb{{Name: "accessories", DisplayName: "Accessories"}, {Name: "eyewear", DisplayName: "Eyewear"}}, product.Category.Breadcrumbs)
+
+		// Breadcrumbs are added to responses, not to the cached product
+		cached, err := catalogAPI.GetProduct(forgetter, context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, cached.Category.Breadcrumbs)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{quill, forgetter, teleporter, tickstopper}, search("/catalog/search?keyword=the&category=accessories"))
+		assert.ElementsMatch(t, []string{quill, teleporter}, search("/catalog/search?keyword=the&category=gadgets"))
+		assert.Empty(t, search("/catalog/search?keyword=the&category=food"))
+		assert.Equal(t, http.StatusBadRequest, get("/catalog/search?keyword=the&category=missing").Code)
+	})
+}
+
+func TestOpenSearchCategory(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 0}, "hits": []}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Settings struct {
+			Analysis map[string]map[string]map[string]interface{} `json:"analysis"`
+		} `json:"settings"`
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.Equal(t, "path_hierarchy", index.Settings.Analysis["tokenizer"]["category_path"]["type"])
+	assert.Equal(t, "category_path", index.Settings.Analysis["analyzer"]["category_path"]["tokenizer"])
+	assert.JSONEq(t, `{"type": "keyword", "fields": {"tree": {"type": "text", "analyzer": "category_path", "search_analyzer": "keyword"}}}`, string(index.Mappings.Properties["categoryPath"]))
+
+	category := model.Category{Name: "gadgets", Path: "accessories/gadgets"}
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "tee", Category: &category}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": [
+				{"term": {"categoryPath.tree": "accessories/gadgets"}},
+				{"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+			]
+		}
+	}`, searchQueryOf(t, query))
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestChanges(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	// The in-memory database is shared between tests, so only look at changes
+	// made from here on
+	since := time.Now().UTC().Format(time.RFC3339Nano)
+
+	require.NoError(t, writable.CreateProduct(&model.Product{ID: "changes-1", Name: "First", Price: model.Money{Amount: 10}}, ctx))
+	defer writable.DeleteProduct("changes-1", ctx)
+	require.NoError(t, writable.CreateProduct(&model.Product{ID: "changes-2", Name: "Second", Price: model.Money{Amount: 20}}, ctx))
+	require.NoError(t, writable.UpdateProduct(&model.Product{ID: "changes-1", Name: "Renamed", Price: model.Money{Amount: 15}}, ctx))
+	require.NoError(t, writable.DeleteProduct("changes-2", ctx))
+
+	catalogAPI, err := api.NewCatalogAPI(writable, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/changes", c.GetChanges)
+	r.GET("/admin/changes", controller.AllStatuses(), c.GetChanges)
+
+	getFrom := func(path string, query url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path+"?"+query.Encode(), nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	get := func(query url.Values) *httptest.ResponseRecorder {
+		return getFrom("/catalog/changes", query)
+	}
+
+	getChangesFrom := func(path string, query url.Values) model.ChangesResponse {
+		w := getFrom(path, query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.ChangesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	getChanges := func(query url.Values) model.ChangesResponse {
+		return getChangesFrom("/catalog/changes", query)
+	}
+
+	t.Run("Since a timestamp", func(t *testing.T) {
+		response := getChanges(url.Values{"since": {since}})
+		require.Len(t, response.Changes, 4)
+		assert.False(t, response.HasMore)
+
+		types := []string{}
+		for _, change := range response.Changes {
+			types = append(types, change.Type+" "+change.ProductID)
+		}
+		assert.Equal(t, []string{"created changes-1", "created changes-2", "updated changes-1", "deleted changes-2"}, types)
+
+		require.NotNil(t, response.Changes[0].Product)
+		assert.Equal(t, "Renamed", response.Changes[0].Product.Name)
+		assert.Nil(t, response.Changes[1].Product)
+		assert.Nil(t, response.Changes[3].Product)
+	})
+
+	t.Run("Continue from the cursor", func(t *testing.T) {
+		first := getChanges(url.Values{"since": {since}, "size": {"3"}})
+		require.Len(t, first.Changes, 3)
+		assert.True(t, first.HasMore)
+
+		second := getChanges(url.Values{"since": {first.Cursor}, "size": {"3"}})
+		require.Len(t, second.Changes, 1)
+		assert.False(t, second.HasMore)
+		assert.Equal(t, model.ChangeDeleted, second.Changes[0].Type)
+		assert.Greater(t, second.Changes[0].Sequence, first.Changes[2].Sequence)
+
+		patcher, ok := writable.(repository.ProductPatcher)
+		require.True(t, ok)
+
+		_, err := patcher.PatchProduct("changes-1", func(product *model.Product) error {
+			product.Price = model.Money{Amount: 30}
+			return nil
+		}, ctx)
+		require.NoError(t, err)
+
+		third := getChanges(url.Values{"since": {second.Cursor}})
+		require.Len(t, third.Changes, 1)
+		assert.Equal(t, model.ChangeUpdated, third.Changes[0].Type)
+		assert.Equal(t, model.Money{Amount: 30, Currency: "USD"}, third.Changes[0].Product.Price)
+
+		empty := getChanges(url.Values{"since": {third.Cursor}})
+		assert.Empty(t, empty.Changes)
+		assert.Equal(t, third.Cursor, empty.Cursor)
+	})
+
+	t.Run("Drafts are left out", func(t *testing.T) {
+		since := time.Now().UTC().Format(time.RFC3339Nano)
+
+		require.NoError(t, writable.CreateProduct(&model.Product{ID: "changes-draft", Name: "Secret", Status: model.StatusDraft, Price: model.Money{Amount: 10}}, ctx))
+		defer writable.DeleteProduct("changes-draft", ctx)
+		require.NoError(t, writable.UpdateProduct(&model.Product{ID: "changes-draft", Name: "Still secret", Status: model.StatusDraft, Price: model.Money{Amount: 12}}, ctx))
+
+		w := get(url.Values{"since": {since}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Secret")
+		assert.NotContains(t, w.Body.String(), "Still secret")
+
+		response := getChanges(url.Values{"since": {since}})
+		require.Len(t, response.Changes, 2)
+		for _, change := range response.Changes {
+			assert.Equal(t, "changes-draft", change.ProductID)
+			assert.Nil(t, change.Product)
+		}
+
+		admin := getChangesFrom("/admin/changes", url.Values{"since": {since}})
+		require.Len(t, admin.Changes, 2)
+		require.NotNil(t, admin.Changes[0].Product)
+		assert.Equal(t, "Still secret", admin.Changes[0].Product.Name)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(url.Values{"since": {"yesterday"}}).Code)
+		assert.Equal(t, http.StatusBadRequest, get(url.Values{"size": {"0"}}).Code)
+		assert.Equal(t, http.StatusBadRequest, get(url.Values{"size": {"1001"}}).Code)
+	})
+}
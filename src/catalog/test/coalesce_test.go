@@ -0,0 +1,146 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// newHeldSearch connects to an OpenSearch server that holds every search
+// until release is closed, counting the searches it receives
+func newHeldSearch(t *testing.T, coalesce bool) (*repository.OpenSearchRepository, *atomic.Int32, chan struct{}) {
+	var searches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		searches.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "a1", "_source": {"id": "a1", "tags": ["watch"]}}]}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+		Coalesce:  coalesce,
+	})
+	require.NoError(t, err)
+
+	return search, &searches, release
+}
+
+// searchConcurrently starts a search for each page, returning once every
+// search has been sent and a function waiting for their results
+func searchConcurrently(t *testing.T, search *repository.OpenSearchRepository, searches *atomic.Int32, sent int32, pages []int, ctx context.Context) func() [][]string {
+	results := make([][]string, len(pages))
+	var wg sync.WaitGroup
+	for i, page := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, page, 10, ctx)
+			if assert.NoError(t, err) {
+				results[i] = productIDs(result.Products)
+			}
+		}()
+	}
+
+	require.Eventually(t, func() bool { return searches.Load() == sent }, time.Second, 5*time.Millisecond)
+	// Give searches that would wrongly send a query of their own time to
+	time.Sleep(50 * time.Millisecond)
+
+	return func() [][]string {
+		wg.Wait()
+		return results
+	}
+}
+
+func TestCoalescedSearch(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	ctx := context.Background()
+
+	t.Run("Identical searches", func(t *testing.T) {
+		search, searches, release := newHeldSearch(t, true)
+
+		wait := searchConcurrently(t, search, searches, 1, []int{1, 1, 1, 1, 1}, ctx)
+		assert.EqualValues(t, 1, searches.Load())
+		close(release)
+
+		for _, ids := range wait() {
+			assert.Equal(t, []string{"a1"}, ids)
+		}
+
+		// Searches made once the first has returned send a query of their own
+		_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, searches.Load())
+	})
+
+	t.Run("Different pages", func(t *testing.T) {
+		search, searches, release := newHeldSearch(t, true)
+
+		wait := searchConcurrently(t, search, searches, 2, []int{1, 2, 1, 2}, ctx)
+		assert.EqualValues(t, 2, searches.Load())
+		close(release)
+		wait()
+	})
+
+	t.Run("Caller gives up", func(t *testing.T) {
+		search, searches, release := newHeldSearch(t, true)
+
+		wait := searchConcurrently(t, search, searches, 1, []int{1, 1}, ctx)
+
+		// The search that sent the query, or any other, giving up doesn't
+		// cancel it for those still waiting
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, timeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		for _, ids := range wait() {
+			assert.Equal(t, []string{"a1"}, ids)
+		}
+		assert.EqualValues(t, 1, searches.Load())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		search, searches, release := newHeldSearch(t, false)
+
+		wait := searchConcurrently(t, search, searches, 3, []int{1, 1, 1}, ctx)
+		close(release)
+		wait()
+	})
+
+	// The search that timed out gave up before the result was shared
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_search_coalesced_total Searches answered by an identical search already in flight rather than by a query of their own
+# TYPE catalog_search_coalesced_total counter
+catalog_search_coalesced_total 7
+`), "catalog_search_coalesced_total"))
+}
@@ -0,0 +1,191 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestValidEAN(t *testing.T) {
+	assert.True(t, model.ValidEAN("4006381333931"))
+	assert.True(t, model.ValidEAN("96385074"))
+
+	assert.False(t, model.ValidEAN("4006381333932"), "wrong check digit")
+	assert.False(t, model.ValidEAN("400638133393"), "wrong length")
+	assert.False(t, model.ValidEAN("400638133393a"))
+}
+
+func TestProductCodes(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/sku/:sku", c.GetProductBySKU)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	lookup := func(sku string) string {
+		w := send("GET", "/catalog/sku/"+sku, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product.ID
+	}
+	search := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return productIDs(response.Products)
+	}
+
+	const oxfords = "4f18544b-70a5-4352-8e19-0d070f46745d"
+
+	w := send("POST", "/catalog/products", `{"id": "codes-scanner", "name": "Scanner", "sku": "SCN-100", "ean": "4006381333931"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	t.Run("Lookup", func(t *testing.T) {
+		assert.Equal(t, "codes-scanner", lookup("SCN-100"))
+		assert.Equal(t, "codes-scanner", lookup("4006381333931"))
+		assert.Equal(t, oxfords, lookup("LEV-OXF-BLK-10"), "variant SKUs find their product")
+
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/sku/SCN-1", "").Code, "codes are matched exactly")
+
+		w := send("POST", "/catalog/products", `{"id": "codes-draft", "name": "Draft", "sku": "DRF-100", "status": "draft"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/sku/DRF-100", "").Code)
+	})
+
+	t.Run("Codes are unique", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"name": "Copy", "sku": "SCN-100"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Copy", "sku": "LEV-OXF-BLK-10"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Copy", "variants": [{"sku": "SCN-100"}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Copy", "ean": "4006381333931"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Typo", "ean": "4006381333932"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Short", "ean": "12345"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		w := send("PATCH", "/catalog/products/codes-scanner", `{"sku": "SCN-200"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "codes-scanner", lookup("SCN-200"))
+		assert.Equal(t, "codes-scanner", lookup("4006381333931"), "the EAN is kept")
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		assert.Equal(t, []string{"codes-scanner"}, search("/v2/catalog/search?keyword=scn-200"))
+		assert.Equal(t, []string{"codes-scanner"}, search("/v2/catalog/search?keyword=4006381333931"))
+		assert.Equal(t, []string{oxfords}, search("/v2/catalog/search?keyword=LEV-OXF-BLK-10"))
+	})
+
+	for _, id := range []string{"codes-scanner", "codes-draft"} {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/"+id, "").Code)
+	}
+}
+
+func TestOpenSearchCodes(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [
+				{"_id": "p1", "_source": {"id": "p1", "name": "Scanner", "price": {"amount": 1500, "currency": "USD"}, "sku": "SCN-100", "ean": "4006381333931"}}
+			]}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"type": "keyword", "fields": {"ngram": {"type": "text", "analyzer": "sku_ngram"}}}`, string(index.Mappings.Properties["sku"]))
+	assert.JSONEq(t, `{"type": "keyword"}`, string(index.Mappings.Properties["ean"]))
+
+	ctx := context.Background()
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "SCN-100", AnyStatus: true}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"should": [
+				{"multi_match": {"query": "SCN-100", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+				{"constant_score": {"filter": {"term": {"sku": {"value": "SCN-100", "case_insensitive": true}}}, "boost": 100}},
+				{"constant_score": {"filter": {"term": {"ean": {"value": "SCN-100", "case_insensitive": true}}}, "boost": 100}},
+				{"nested": {"path": "variants", "query": {"constant_score": {"filter": {"term": {"variants.sku": {"value": "SCN-100", "case_insensitive": true}}}, "boost": 100}}}},
+				{"match": {"sku.ngram": {"query": "SCN-100", "operator": "and"}}}
+			],
+			"minimum_should_match": 1
+		}
+	}`, searchQueryOf(t, query))
+	products := result.Products
+	require.Len(t, products, 1)
+	require.NotNil(t, products[0].SKU)
+	assert.Equal(t, "SCN-100", *products[0].SKU)
+	require.NotNil(t, products[0].EAN)
+	assert.Equal(t, "4006381333931", *products[0].EAN)
+
+	// Words aren't matched against codes
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "scanner", AnyStatus: true}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"multi_match": {"query": "scanner", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}
+	}`, searchQueryOf(t, query))
+}
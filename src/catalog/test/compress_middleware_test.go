@@ -0,0 +1,94 @@
+package test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(middleware.NewCompression(config.CompressionConfiguration{Enabled: true, MinSize: 100}))
+
+	large := strings.Repeat("catalog ", 50)
+	router.GET("/large", func(c *gin.Context) {
+		c.Header("ETag", `"abc"`)
+		c.JSON(http.StatusOK, gin.H{"value": large})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"value": "small"})
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	expected := `{"value":"` + large + `"}`
+
+	t.Run("Brotli preferred", func(t *testing.T) {
+		w := request("/large", "gzip, deflate, br")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Equal(t, `W/"abc"`, w.Header().Get("ETag"))
+		assert.Less(t, w.Body.Len(), len(expected))
+
+		body, err := io.ReadAll(brotli.NewReader(w.Body))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	})
+
+	t.Run("Gzip", func(t *testing.T) {
+		w := request("/large", "gzip;q=1.0, br;q=0.5")
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	})
+
+	t.Run("Not accepted", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "identity", "br;q=0, gzip;q=0"} {
+			w := request("/large", acceptEncoding)
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, `"abc"`, w.Header().Get("ETag"))
+			assert.Equal(t, expected, w.Body.String())
+		}
+	})
+
+	t.Run("Below minimum size", func(t *testing.T) {
+		w := request("/small", "br")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Equal(t, `{"value":"small"}`, w.Body.String())
+	})
+
+	t.Run("Not JSON", func(t *testing.T) {
+		w := request("/text", "br")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+}
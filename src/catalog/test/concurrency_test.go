@@ -0,0 +1,136 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestProductVersions(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	product := &model.Product{ID: "version-1", Name: "Versioned", Price: model.Money{Amount: 10}}
+	require.NoError(t, writable.CreateProduct(product, ctx))
+	defer writable.DeleteProduct("version-1", ctx)
+	assert.Equal(t, 1, product.Version)
+
+	update := &model.Product{ID: "version-1", Name: "Updated", Price: model.Money{Amount: 20}, Version: 1}
+	require.NoError(t, writable.UpdateProduct(update, ctx))
+	assert.Equal(t, 2, update.Version)
+
+	stale := &model.Product{ID: "version-1", Name: "Stale", Price: model.Money{Amount: 30}, Version: 1}
+	assert.ErrorIs(t, writable.UpdateProduct(stale, ctx), repository.ErrVersionConflict)
+
+	unconditional := &model.Product{ID: "version-1", Name: "Unconditional", Price: model.Money{Amount: 40}}
+	require.NoError(t, writable.UpdateProduct(unconditional, ctx))
+	assert.Equal(t, 3, unconditional.Version)
+
+	stored, err := writable.GetProduct("version-1", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Unconditional", stored.Name)
+	assert.Equal(t, 3, stored.Version)
+}
+
+func TestConditionalUpdates(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	require.NoError(t, writable.CreateProduct(&model.Product{ID: "conditional-1", Name: "Conditional", Price: model.Money{Amount: 10}}, ctx))
+	defer writable.DeleteProduct("conditional-1", ctx)
+
+	catalogAPI, err := api.NewCatalogAPI(writable, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+
+	strict := gin.New()
+	strict.PUT("/catalog/products/:id", middleware.RequireIfMatch(), c.UpdateProduct)
+
+	send := func(r *gin.Engine, method, ifMatch, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/catalog/products/conditional-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(r, "GET", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	original := w.Header().Get("ETag")
+	require.NotEmpty(t, original)
+
+	var updated string
+
+	t.Run("Matching If-Match", func(t *testing.T) {
+		w := send(r, "PUT", original, `{"name": "Renamed", "price": 15}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "Renamed", product.Name)
+		assert.Equal(t, 2, product.Version)
+
+		updated = w.Header().Get("ETag")
+		assert.NotEqual(t, original, updated)
+		assert.Equal(t, updated, send(r, "GET", "", "").Header().Get("ETag"))
+	})
+
+	t.Run("Stale If-Match", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionFailed, send(r, "PUT", original, `{"name": "Lost", "price": 1}`).Code)
+		assert.Equal(t, http.StatusPreconditionFailed, send(r, "PATCH", original, `{"price": 1}`).Code)
+
+		stored, err := writable.GetProduct("conditional-1", ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", stored.Name)
+		assert.Equal(t, 15, stored.Price.Units())
+	})
+
+	t.Run("Patch with If-Match", func(t *testing.T) {
+		w := send(r, "PATCH", updated, `{"price": 20}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, w.Header().Get("ETag"), send(r, "GET", "", "").Header().Get("ETag"))
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, 20, product.Price.Units())
+		assert.Equal(t, 3, product.Version)
+	})
+
+	t.Run("Any version", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send(r, "PATCH", "*", `{"price": 25}`).Code)
+		assert.Equal(t, http.StatusOK, send(r, "PATCH", "", `{"price": 30}`).Code)
+	})
+
+	t.Run("If-Match required", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionRequired, send(strict, "PUT", "", `{"name": "Renamed", "price": 15}`).Code)
+
+		etag := send(r, "GET", "", "").Header().Get("ETag")
+		assert.Equal(t, http.StatusOK, send(strict, "PUT", etag, `{"name": "Renamed", "price": 15}`).Code)
+	})
+}
@@ -0,0 +1,333 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	var cfg config.AppConfiguration
+	require.NoError(t, envconfig.ProcessWith(context.Background(), &cfg, envconfig.MapLookuper(map[string]string{})))
+
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "in-memory", cfg.Database.Type)
+	assert.Empty(t, cfg.Search.FederatedBackends)
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+port: 9000
+http2:
+  enabled: false
+database:
+  type: mysql
+  endpoint: file-db:3306
+  replicaLagTolerance: 5s
+openSearch:
+  enabled: true
+  boosts:
+    name: 3
+    tags: 1.5
+  synonyms:
+    - "tv, television"
+    - "laptop, notebook"
+`), 0o600))
+
+	cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(map[string]string{
+		config.FileEnv:                        path,
+		"RETAIL_CATALOG_PERSISTENCE_ENDPOINT": "env-db:3306",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9000, cfg.Port)
+	assert.False(t, cfg.HTTP2.Enabled)
+	assert.Equal(t, "mysql", cfg.Database.Type)
+	assert.Equal(t, 5*time.Second, cfg.Database.ReplicaLagTolerance)
+	assert.Equal(t, map[string]float64{"name": 3, "tags": 1.5}, cfg.OpenSearch.Boosts)
+	assert.Equal(t, []string{"tv, television", "laptop, notebook"}, cfg.OpenSearch.Synonyms)
+
+	// Environment variables take precedence over the file
+	assert.Equal(t, "env-db:3306", cfg.Database.Endpoint)
+
+	// Defaults apply to anything neither sets
+	assert.Equal(t, "catalogdb", cfg.Database.Name)
+	assert.Equal(t, "products", cfg.OpenSearch.IndexName)
+
+	t.Run("Unknown keys", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("database:\n  tpye: mysql\n"), 0o600))
+
+		_, err := config.LoadWith(context.Background(), envconfig.MapLookuper(map[string]string{config.FileEnv: path}))
+		assert.ErrorContains(t, err, "tpye")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := config.LoadWith(context.Background(), envconfig.MapLookuper(map[string]string{config.FileEnv: path + ".missing"}))
+		assert.Error(t, err)
+	})
+}
+
+func TestConfigValidation(t *testing.T) {
+	load := func(env map[string]string) config.AppConfiguration {
+		cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(env))
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		assert.NoError(t, load(map[string]string{}).Validate())
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, load(map[string]string{
+			"RETAIL_CATALOG_PERSISTENCE_PROVIDER":        "mysql",
+			"RETAIL_CATALOG_PERSISTENCE_ENDPOINT":        "catalog-db:3306",
+			"RETAIL_CATALOG_PERSISTENCE_PASSWORD_SECRET": "catalog-db",
+			"RETAIL_CATALOG_SEARCH_ENABLED":              "true",
+			"RETAIL_CATALOG_SEARCH_OS_ENDPOINT":          "https://search.example.com",
+		}).Validate())
+	})
+
+	t.Run("Problems are aggregated", func(t *testing.T) {
+		err := load(map[string]string{
+			"PORT":                                               "70000",
+			"RETAIL_CATALOG_TLS_CERT_FILE":                       "cert.pem",
+			"RETAIL_CATALOG_PERSISTENCE_PROVIDER":                "mysql",
+			"RETAIL_CATALOG_PERSISTENCE_PASSWORD":                "secret",
+			"RETAIL_CATALOG_PERSISTENCE_IAM_AUTH":                "true",
+			"RETAIL_CATALOG_SEARCH_BACKEND":                      "opensearch",
+			"RETAIL_CATALOG_SEARCH_OS_ENDPOINT":                  "search.example.com:9200",
+			"RETAIL_CATALOG_SEARCH_OS_BREAKER_THRESHOLD":         "-1",
+			"RETAIL_CATALOG_SEARCH_OS_BULK_MIN_BATCH_SIZE":       "0",
+			"RETAIL_CATALOG_CURRENCY_RATES":                      "EUR:-1",
+			"RETAIL_CATALOG_SITEMAP_BASE_URL":                    "shop.example.com",
+			"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_APPLICATION": "retail-store",
+			"RETAIL_CATALOG_CHAOS_OPENSEARCH_ERROR_RATE":         "1.5",
+			"RETAIL_CATALOG_STARTUP_MAX_BACKOFF":                 "100ms",
+			"RETAIL_CATALOG_LOADGEN_RPS":                         "0",
+		}).Validate()
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{
+			"PORT must be between 1 and 65535, got 70000",
+			"RETAIL_CATALOG_STARTUP_MAX_BACKOFF can't be less than RETAIL_CATALOG_STARTUP_INITIAL_BACKOFF",
+			"RETAIL_CATALOG_TLS_CERT_FILE and RETAIL_CATALOG_TLS_KEY_FILE must be set together",
+			"RETAIL_CATALOG_CURRENCY_RATES must be positive, got -1 for EUR",
+			`RETAIL_CATALOG_SITEMAP_BASE_URL must be a URL starting with http:// or https://, got "shop.example.com"`,
+			"RETAIL_CATALOG_PERSISTENCE_ENDPOINT must be set for the mysql provider",
+			"only one of RETAIL_CATALOG_PERSISTENCE_IAM_AUTH, RETAIL_CATALOG_PERSISTENCE_PASSWORD can be set",
+			`RETAIL_CATALOG_SEARCH_OS_ENDPOINT must be a URL starting with http:// or https://, got "search.example.com:9200"`,
+			"RETAIL_CATALOG_SEARCH_OS_BREAKER_THRESHOLD can't be negative",
+			"RETAIL_CATALOG_SEARCH_OS_BULK_MIN_BATCH_SIZE must be positive",
+			"RETAIL_CATALOG_FEATURE_FLAGS_APPCONFIG_APPLICATION, _ENVIRONMENT and _PROFILE must be set together",
+			"RETAIL_CATALOG_CHAOS_OPENSEARCH_ERROR_RATE must be between 0 and 1, got 1.5",
+			"RETAIL_CATALOG_LOADGEN_RPS must be positive",
+		}, validationErr.Problems)
+		assert.Contains(t, err.Error(), "invalid configuration:\n  - PORT")
+	})
+
+	t.Run("Seed data", func(t *testing.T) {
+		assert.NoError(t, load(map[string]string{
+			"RETAIL_CATALOG_SEED_URL":    "s3://workshop/products.json",
+			"RETAIL_CATALOG_SEED_SHA256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}).Validate())
+
+		err := load(map[string]string{
+			"RETAIL_CATALOG_SEED_URL":     "ftp://workshop/products.json",
+			"RETAIL_CATALOG_SEED_TIMEOUT": "0s",
+			"RETAIL_CATALOG_SEED_FORMAT":  "xml",
+		}).Validate()
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{
+			`RETAIL_CATALOG_SEED_URL must be a URL starting with s3:// or https:// or http://, got "ftp://workshop/products.json"`,
+			"RETAIL_CATALOG_SEED_SHA256 must be the SHA-256 checksum of the dataset as 64 hex digits when RETAIL_CATALOG_SEED_URL is set",
+			"RETAIL_CATALOG_SEED_TIMEOUT must be positive",
+			`RETAIL_CATALOG_SEED_FORMAT must be json or csv, got "xml"`,
+		}, validationErr.Problems)
+	})
+
+	t.Run("Kafka", func(t *testing.T) {
+		assert.NoError(t, load(map[string]string{
+			"RETAIL_CATALOG_EVENTS_KAFKA_BROKERS":             "b-1.msk.example.com:9098,b-2.msk.example.com:9098",
+			"RETAIL_CATALOG_EVENTS_KAFKA_TLS":                 "true",
+			"RETAIL_CATALOG_EVENTS_KAFKA_AUTH":                "iam",
+			"RETAIL_CATALOG_EVENTS_KAFKA_FORMAT":              "avro",
+			"RETAIL_CATALOG_EVENTS_KAFKA_SCHEMA_REGISTRY_URL": "http://registry:8081",
+		}).Validate())
+
+		err := load(map[string]string{
+			"RETAIL_CATALOG_EVENTS_KAFKA_BROKERS":             "kafka",
+			"RETAIL_CATALOG_EVENTS_KAFKA_AUTH":                "scram",
+			"RETAIL_CATALOG_EVENTS_KAFKA_FORMAT":              "protobuf",
+			"RETAIL_CATALOG_EVENTS_KAFKA_SCHEMA_REGISTRY_URL": "registry:8081",
+		}).Validate()
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{
+			`RETAIL_CATALOG_EVENTS_KAFKA_BROKERS must be a host and port such as db:3306, got "kafka"`,
+			"RETAIL_CATALOG_EVENTS_KAFKA_USERNAME and RETAIL_CATALOG_EVENTS_KAFKA_PASSWORD must be set for scram authentication",
+			`RETAIL_CATALOG_EVENTS_KAFKA_FORMAT must be json or avro, got "protobuf"`,
+			`RETAIL_CATALOG_EVENTS_KAFKA_SCHEMA_REGISTRY_URL must be a URL starting with https:// or http://, got "registry:8081"`,
+		}, validationErr.Problems)
+
+		assert.ErrorContains(t, load(map[string]string{
+			"RETAIL_CATALOG_EVENTS_KAFKA_BROKERS": "b-1.msk.example.com:9098",
+			"RETAIL_CATALOG_EVENTS_KAFKA_AUTH":    "iam",
+		}).Validate(), "RETAIL_CATALOG_EVENTS_KAFKA_TLS must be enabled for iam authentication")
+	})
+
+	t.Run("Index queue", func(t *testing.T) {
+		assert.NoError(t, load(map[string]string{
+			"RETAIL_CATALOG_SEARCH_QUEUE_URL":      "http://localstack:4566/000000000000/catalog-index",
+			"RETAIL_CATALOG_SEARCH_QUEUE_ENDPOINT": "http://localstack:4566",
+		}).Validate())
+
+		err := load(map[string]string{
+			"RETAIL_CATALOG_SEARCH_QUEUE_URL":        "catalog-index",
+			"RETAIL_CATALOG_SEARCH_QUEUE_BATCH_SIZE": "20",
+			"RETAIL_CATALOG_SEARCH_QUEUE_WAIT_TIME":  "1m",
+		}).Validate()
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{
+			`RETAIL_CATALOG_SEARCH_QUEUE_URL must be a URL starting with https:// or http://, got "catalog-index"`,
+			"RETAIL_CATALOG_SEARCH_QUEUE_BATCH_SIZE must be between 1 and 10, got 20",
+			"RETAIL_CATALOG_SEARCH_QUEUE_WAIT_TIME must be between 0s and 20s, got 1m0s",
+		}, validationErr.Problems)
+	})
+
+	t.Run("Vault token", func(t *testing.T) {
+		cfg := load(map[string]string{
+			"RETAIL_CATALOG_PERSISTENCE_PROVIDER":   "mysql",
+			"RETAIL_CATALOG_PERSISTENCE_ENDPOINT":   "catalog-db:3306",
+			"RETAIL_CATALOG_PERSISTENCE_VAULT_PATH": "database/creds/catalog",
+		})
+
+		t.Setenv("VAULT_TOKEN", "")
+		os.Unsetenv("VAULT_TOKEN")
+		assert.ErrorContains(t, cfg.Validate(), "VAULT_TOKEN must be set")
+
+		t.Setenv("VAULT_TOKEN", "root")
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rateLimit:\n  readRate: 5\n"), 0o600))
+	t.Setenv(config.FileEnv, path)
+
+	reloaded := make(chan config.AppConfiguration, 1)
+	reloader := config.NewReloader()
+	reloader.OnReload(func(cfg config.AppConfiguration) { reloaded <- cfg })
+
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		// Make sure the change is seen even with coarse modification times
+		later := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+	}
+
+	t.Run("Reload", func(t *testing.T) {
+		require.NoError(t, reloader.Reload(context.Background()))
+		assert.Equal(t, 5.0, (<-reloaded).RateLimit.ReadRate)
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		write("rateLimit:\n  readRtae: 5\n")
+		assert.Error(t, reloader.Reload(context.Background()))
+		assert.Empty(t, reloaded)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reloader.Watch(10*time.Millisecond, ctx)
+
+		write("rateLimit:\n  readRate: 20\n")
+
+		select {
+		case cfg := <-reloaded:
+			assert.Equal(t, 20.0, cfg.RateLimit.ReadRate)
+		case <-time.After(2 * time.Second):
+			t.Fatal("configuration was not reloaded")
+		}
+	})
+}
+
+// fakeSSM serves parameters two to a page
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	parameters map[string]string
+	input      *ssm.GetParametersByPathInput
+}
+
+func (f *fakeSSM) GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	f.input = input
+
+	page := &ssm.GetParametersByPathOutput{}
+	for name, value := range f.parameters {
+		page.Parameters = append(page.Parameters, &ssm.Parameter{Name: aws.String(name), Value: aws.String(value)})
+		if len(page.Parameters) == 2 {
+			if !fn(page, false) {
+				return nil
+			}
+			page = &ssm.GetParametersByPathOutput{}
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func TestConfigParameters(t *testing.T) {
+	client := &fakeSSM{parameters: map[string]string{
+		"/retail-store/catalog/RETAIL_CATALOG_PERSISTENCE_PROVIDER":   "mysql",
+		"/retail-store/catalog/RETAIL_CATALOG_PERSISTENCE_ENDPOINT":   "ssm-db:3306",
+		"/retail-store/catalog/RETAIL_CATALOG_PERSISTENCE_PASSWORD":   "decrypted",
+		"/retail-store/catalog/db/RETAIL_CATALOG_PERSISTENCE_DB_NAME": "nested",
+		"/retail-store/catalog/RETAIL_CATALOG_AUTH_API_KEYS":          "key-1,key-2",
+	}}
+
+	parameters, err := config.LoadParameters(context.Background(), client, "/retail-store/catalog/")
+	require.NoError(t, err)
+	assert.Equal(t, "/retail-store/catalog", aws.StringValue(client.input.Path))
+	assert.True(t, aws.BoolValue(client.input.WithDecryption))
+	assert.True(t, aws.BoolValue(client.input.Recursive))
+
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("port: 9000\ndatabase:\n  endpoint: file-db:3306\n  user: file-user\n"), 0o600))
+
+	environment := envconfig.MapLookuper(map[string]string{
+		config.FileEnv:                        path,
+		"RETAIL_CATALOG_PERSISTENCE_PROVIDER": "sqlite",
+	})
+
+	cfg, err := config.LoadWith(context.Background(), envconfig.MultiLookuper(environment, parameters))
+	require.NoError(t, err)
+
+	// Environment variables take precedence over parameters, which take
+	// precedence over the file
+	assert.Equal(t, "sqlite", cfg.Database.Type)
+	assert.Equal(t, "ssm-db:3306", cfg.Database.Endpoint)
+	assert.Equal(t, "file-user", cfg.Database.User)
+	assert.Equal(t, 9000, cfg.Port)
+
+	assert.Equal(t, "decrypted", cfg.Database.Password)
+	assert.Equal(t, "nested", cfg.Database.Name)
+	assert.Equal(t, []string{"key-1", "key-2"}, cfg.Auth.APIKeys)
+}
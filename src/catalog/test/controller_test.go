@@ -1,12 +1,16 @@
 package test
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
 	"github.com/aws-containers/retail-store-sample-app/catalog/model"
 )
 
@@ -32,8 +36,191 @@ func TestCatalogProduct(t *testing.T) {
 	assert.Equal(t, "Temporal Tickstopper", response.Name)
 }
 
+func TestCatalogConditionalGet(t *testing.T) {
+	for _, url := range []string{"/catalog", "/catalog/product/cc789f85-1476-452a-8100-9e74502198e0"} {
+		writer := makeRequest("GET", url, nil)
+
+		assert.Equal(t, http.StatusOK, writer.Code)
+
+		etag := writer.Header().Get("ETag")
+		assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+		request, _ := http.NewRequest("GET", url, nil)
+		request.Header.Set("If-None-Match", `"other", `+etag)
+		writer = httptest.NewRecorder()
+		router().ServeHTTP(writer, request)
+
+		assert.Equal(t, http.StatusNotModified, writer.Code)
+		assert.Equal(t, etag, writer.Header().Get("ETag"))
+		assert.Empty(t, writer.Body.Bytes())
+
+		request, _ = http.NewRequest("GET", url, nil)
+		request.Header.Set("If-None-Match", `"other"`)
+		writer = httptest.NewRecorder()
+		router().ServeHTTP(writer, request)
+
+		assert.Equal(t, http.StatusOK, writer.Code)
+	}
+}
+
+func TestCatalogSparseFields(t *testing.T) {
+	writer := makeRequest("GET", "/catalog?fields=id,price", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	var list []map[string]any
+	json.Unmarshal(writer.Body.Bytes(), &list)
+
+	assert.Equal(t, 10, len(list))
+	for _, product := range list {
+		assert.Len(t, product, 2)
+		assert.Contains(t, product, "id")
+		assert.Contains(t, product, "price")
+	}
+
+	writer = makeRequest("GET", "/catalog/product/cc789f85-1476-452a-8100-9e74502198e0?fields=name", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.JSONEq(t, `{"name":"Temporal Tickstopper"}`, writer.Body.String())
+
+	writer = makeRequest("GET", "/catalog?fields=id,secret", nil)
+
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+}
+
+func TestCatalogSortAndPriceRange(t *testing.T) {
+	writer := makeRequest("GET", "/catalog?sort=-price&minPrice=10&maxPrice=100", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	var response []model.Product
+	json.Unmarshal(writer.Body.Bytes(), &response)
+
+	for i, product := range response {
+		assert.True(t, product.Price.Units() >= 10 && product.Price.Units() <= 100)
+		if i > 0 {
+			assert.LessOrEqual(t, product.Price.Amount, response[i-1].Price.Amount)
+		}
+	}
+
+	assert.Equal(t, http.StatusBadRequest, makeRequest("GET", "/catalog?sort=rating", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, makeRequest("GET", "/catalog?minPrice=100&maxPrice=10", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, makeRequest("GET", "/catalog/size?maxPrice=cheap", nil).Code)
+}
+
 func TestCatalogProductMissing(t *testing.T) {
 	writer := makeRequest("GET", "/catalog/product/missing", nil)
 
 	assert.Equal(t, http.StatusNotFound, writer.Code)
 }
+
+func TestCatalogProductLifecycle(t *testing.T) {
+	writer := makeRequest("POST", "/catalog/product", model.ProductRequest{
+		ID:          "test-lifecycle",
+		Name:        "Test Product",
+		Description: "A product created by a test",
+		Price:       model.MoneyFromUnits(100, "USD"),
+		Tags:        []string{"accessories"},
+	})
+
+	assert.Equal(t, http.StatusCreated, writer.Code)
+	assert.Equal(t, "/catalog/product/test-lifecycle", writer.Header().Get("Location"))
+
+	writer = makeRequest("POST", "/catalog/product", model.ProductRequest{
+		ID:   "test-lifecycle",
+		Name: "Duplicate",
+	})
+
+	assert.Equal(t, http.StatusConflict, writer.Code)
+
+	writer = makeRequest("PUT", "/catalog/product/test-lifecycle", model.ProductRequest{
+		Name:  "Updated Product",
+		Price: model.MoneyFromUnits(150, "USD"),
+	})
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	writer = makeRequest("GET", "/catalog/product/test-lifecycle", nil)
+
+	var response model.Product
+	json.Unmarshal(writer.Body.Bytes(), &response)
+
+	assert.Equal(t, "Updated Product", response.Name)
+	assert.Equal(t, model.MoneyFromUnits(150, "USD"), response.Price)
+	assert.Empty(t, response.Tags)
+
+	writer = makeRequest("DELETE", "/catalog/product/test-lifecycle", nil)
+
+	assert.Equal(t, http.StatusNoContent, writer.Code)
+
+	writer = makeRequest("GET", "/catalog/product/test-lifecycle", nil)
+
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func TestCatalogProductValidation(t *testing.T) {
+	writer := makeRequest("POST", "/catalog/product", model.ProductRequest{
+		Price: model.Money{Amount: 10},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+
+	writer = makeRequest("POST", "/catalog/product", model.ProductRequest{
+		Name: "Unknown tag",
+		Tags: []string{"missing"},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+
+	writer = makeRequest("PUT", "/catalog/product/missing", model.ProductRequest{
+		Name: "Missing",
+	})
+
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+
+	writer = makeRequest("DELETE", "/catalog/product/missing", nil)
+
+	assert.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func TestCatalogExport(t *testing.T) {
+	writer := makeRequest("GET", "/catalog/export", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+
+	var products []model.Product
+	err := json.Unmarshal(writer.Body.Bytes(), &products)
+
+	assert.NoError(t, err)
+	assert.Greater(t, len(products), 10)
+
+	writer = makeRequest("GET", "/catalog/export?format=csv", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Equal(t, "text/csv", writer.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(writer.Body).ReadAll()
+
+	assert.NoError(t, err)
+	assert.Equal(t, controller.CSVHeader, records[0])
+	assert.Equal(t, len(products)+1, len(records))
+
+	writer = makeRequest("GET", "/catalog/export?format=ndjson", nil)
+
+	assert.Equal(t, http.StatusOK, writer.Code)
+	assert.Equal(t, "application/x-ndjson", writer.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSuffix(writer.Body.String(), "\n"), "\n")
+	assert.Equal(t, len(products), len(lines))
+	var product model.Product
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &product))
+	assert.Equal(t, products[0].ID, product.ID)
+
+	writer = makeRequest("GET", "/catalog/export?format=xml", nil)
+
+	assert.Equal(t, http.StatusBadRequest, writer.Code)
+
+	writer = makeRequest("GET", "/catalog/export?keyword=watch", nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, writer.Code)
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSRouter(cors config.CORSConfiguration) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(middleware.NewCORS(cors))
+	router.GET("/catalog/products", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/catalog/products", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", "POST")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	router := newCORSRouter(config.CORSConfiguration{
+		AllowedOrigins: []string{"http://localhost:3000", "https://*.example.com"},
+		MaxAge:         time.Minute,
+	})
+
+	t.Run("Allowed origin", func(t *testing.T) {
+		w := corsRequest(router, "GET", "http://localhost:3000")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+		assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "ETag")
+		assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Idempotent-Replayed")
+		assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Location")
+	})
+
+	t.Run("Wildcard subdomain", func(t *testing.T) {
+		w := corsRequest(router, "GET", "https://shop.example.com")
+		assert.Equal(t, "https://shop.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = corsRequest(router, "GET", "https://example.com.evil.net")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Disallowed origin", func(t *testing.T) {
+		w := corsRequest(router, "GET", "http://other.test")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+		assert.Equal(t, http.StatusForbidden, corsRequest(router, "OPTIONS", "http://other.test").Code)
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		w := corsRequest(router, "OPTIONS", "http://localhost:3000")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "POST")
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-API-Key")
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "If-Match")
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Idempotency-Key")
+		assert.Equal(t, "60", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("No origin", func(t *testing.T) {
+		w := corsRequest(router, "GET", "")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	t.Run("Without credentials", func(t *testing.T) {
+		router := newCORSRouter(config.CORSConfiguration{AllowedOrigins: []string{"*"}})
+
+		w := corsRequest(router, "GET", "http://localhost:3000")
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("With credentials", func(t *testing.T) {
+		router := newCORSRouter(config.CORSConfiguration{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+
+		w := corsRequest(router, "GET", "http://localhost:3000")
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		router := newCORSRouter(config.CORSConfiguration{})
+
+		assert.Empty(t, corsRequest(router, "GET", "http://localhost:3000").Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, http.StatusNotFound, corsRequest(router, "OPTIONS", "http://localhost:3000").Code)
+	})
+}
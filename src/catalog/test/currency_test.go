@@ -0,0 +1,248 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestExchangeRates(t *testing.T) {
+	rates := currency.New(map[string]float64{"EUR": 0.5, "JPY": 150})
+
+	t.Run("Convert", func(t *testing.T) {
+		price, err := rates.Convert(model.Money{Amount: 1999, Currency: "USD"}, "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, model.Money{Amount: 1000, Currency: "EUR"}, price, "rounded to the nearest cent")
+
+		price, err = rates.Convert(model.Money{Amount: 1000, Currency: "EUR"}, "JPY")
+		require.NoError(t, err)
+		assert.Equal(t, model.Money{Amount: 3000, Currency: "JPY"}, price)
+
+		price, err = rates.Convert(model.Money{Amount: 1000}, "USD")
+		require.NoError(t, err)
+		assert.Equal(t, model.Money{Amount: 1000, Currency: "USD"}, price)
+
+		_, err = rates.Convert(model.Money{Amount: 1000, Currency: "USD"}, "GBP")
+		assert.ErrorIs(t, err, currency.ErrUnsupported)
+		assert.Equal(t, []string{"EUR", "JPY", "USD"}, rates.Supported())
+	})
+
+	t.Run("Rebase", func(t *testing.T) {
+		rebased, err := currency.Rebase("EUR", map[string]float64{"USD": 2, "GBP": 1})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"EUR": 0.5, "GBP": 0.5}, rebased)
+
+		_, err = currency.Rebase("EUR", map[string]float64{"GBP": 1})
+		assert.Error(t, err, "rates must include the default currency")
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		body := `{"base": "USD", "rates": {"EUR": 0.8, "GBP": 0.75}}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, body)
+		}))
+		defer server.Close()
+
+		rates := currency.New(map[string]float64{"EUR": 0.5})
+		require.NoError(t, currency.NewSource(server.URL, 0, rates).Poll(context.Background()))
+
+		price, err := rates.Convert(model.Money{Amount: 1000, Currency: "USD"}, "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, 800, price.Amount, "fetched rates take precedence")
+		assert.True(t, rates.Supports("GBP"))
+
+		body = `{"rates": {"EUR": -1}}`
+		assert.Error(t, currency.NewSource(server.URL, 0, rates).Poll(context.Background()))
+		assert.True(t, rates.Supports("GBP"), "rates are kept when a poll fails")
+	})
+}
+
+func TestPriceCurrencies(t *testing.T) {
+	db := newInMemoryRepository(t)
+	euroProduct := model.Product{
+		ID:          "eur1",
+		Name:        "Euro Watch",
+		Description: "A watch priced in euros",
+		Price:       model.Money{Amount: 10000, Currency: "EUR"},
+	}
+	require.NoError(t, db.(repository.WritableCatalogRepository).CreateProduct(&euroProduct, context.Background()))
+
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+	c.SetExchangeRates(currency.New(map[string]float64{"EUR": 0.5}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	for version, prefix := range map[int]string{1: "/v1/catalog", 2: "/v2/catalog"} {
+		group := r.Group(prefix, controller.APIVersion(version))
+		group.GET("/products", c.GetProducts)
+		group.GET("/products/:id", c.GetProduct)
+	}
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	get := func(url string, acceptCurrency string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		if acceptCurrency != "" {
+			req.Header.Set("Accept-Currency", acceptCurrency)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	const productID = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("Converted prices", func(t *testing.T) {
+		w := get("/v2/catalog/products/"+productID+"?currency=eur", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "EUR", w.Header().Get("Content-Currency"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Currency")
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, model.Money{Amount: 12500, Currency: "EUR"}, product.Price)
+
+		var v1 map[string]any
+		w = get("/v1/catalog/products/"+productID+"?currency=EUR", "")
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v1))
+		assert.EqualValues(t, 125, v1["price"])
+	})
+
+	t.Run("Accept-Currency", func(t *testing.T) {
+		w := get("/v2/catalog/products/"+productID, "GBP, EUR;q=0.5, USD;q=0.1")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "EUR", w.Header().Get("Content-Currency"), "the most preferred currency with a rate")
+
+		// The query parameter takes precedence
+		w = get("/v2/catalog/products/eur1?currency=USD", "EUR")
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, model.Money{Amount: 20000, Currency: "USD"}, product.Price)
+
+		// Without a currency each product keeps its own
+		w = get("/v2/catalog/products/eur1", "GBP")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Currency"))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, euroProduct.Price, product.Price)
+	})
+
+	t.Run("Unsupported currency", func(t *testing.T) {
+		w := get("/v2/catalog/products/"+productID+"?currency=GBP", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "EUR, USD")
+	})
+
+	t.Run("Price ranges", func(t *testing.T) {
+		list := func(url string) []model.Product {
+			w := get(url, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var products []model.Product
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+			return products
+		}
+
+		// Ranges in a currency match products priced in any currency
+		assert.Equal(t, []string{"eur1"}, productIDs(list("/v2/catalog/products?currency=USD&minPrice=20000&maxPrice=20000")))
+		assert.Equal(t,
+			[]string{"eur1", "4f18544b-70a5-4352-8e19-0d070f46745d", "1ca35e86-4b4c-4124-b6b5-076ba4134d0d", productID},
+			productIDs(list("/v2/catalog/products?currency=EUR&minPrice=10000&maxPrice=12500&sort=price")))
+		assert.Equal(t, []string{"eur1"}, productIDs(list("/v1/catalog/products?currency=EUR&minPrice=100&maxPrice=100")))
+
+		// Without one amounts are compared whatever their currency
+		assert.Equal(t, []string{"eur1"}, productIDs(list("/v2/catalog/products?minPrice=10000&maxPrice=10000")))
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		search := func(url string) []model.Product {
+			w := get(url, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response model.SearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Products
+		}
+
+		all := productIDs(search("/v2/catalog/search?keyword=watch"))
+		require.Contains(t, all, productID)
+		require.Contains(t, all, "eur1")
+
+		products := search("/v2/catalog/search?keyword=watch&currency=EUR&maxPrice=10000")
+		assert.Contains(t, productIDs(products), "eur1")
+		assert.NotContains(t, productIDs(products), productID)
+		for _, product := range products {
+			assert.Equal(t, "EUR", product.Price.Currency)
+			assert.LessOrEqual(t, product.Price.Amount, 10000)
+		}
+
+		assert.Equal(t, []string{productID}, productIDs(search("/v2/catalog/search?keyword=watch&minPrice=25000&maxPrice=25000")))
+	})
+}
+
+func TestOpenSearchPriceRanges(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 0}, "hits": []}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	minPrice, maxPrice := 1000, 2000
+	_, err = search.SearchProducts(repository.SearchQuery{
+		Keyword: "watch",
+		PriceRanges: []repository.PriceRange{
+			{Currency: "EUR", Min: &minPrice},
+			{Currency: "USD", Min: &minPrice, Max: &maxPrice},
+		},
+	}, 1, 10, context.Background())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "watch", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": [
+				{"bool": {"minimum_should_match": 1, "should": [
+					{"bool": {"filter": [{"term": {"price.currency": "EUR"}}, {"range": {"price.amount": {"gte": 1000}}}]}},
+					{"bool": {"filter": [{"term": {"price.currency": "USD"}}, {"range": {"price.amount": {"gte": 1000, "lte": 2000}}}]}}
+				]}},
+				{"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+			]
+		}
+	}`, searchQueryOf(t, query))
+}
+
+// searchQueryOf returns the query clause of a search request body
+func searchQueryOf(t *testing.T, body string) string {
+	var request struct {
+		Query json.RawMessage `json:"query"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+	return string(request.Query)
+}
@@ -0,0 +1,252 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// afterSearch is a search repository that continues from the ID of the last
+// hit, like search_after sorted by ID
+type afterSearch struct {
+	stubSearch
+}
+
+func (s *afterSearch) SearchProductsAfter(query repository.SearchQuery, after []interface{}, size int, ctx context.Context) (repository.SearchResult, []interface{}, error) {
+	products := []model.Product{}
+	for _, id := range s.ids {
+		if len(after) > 0 && id <= after[0].(string) {
+			continue
+		}
+		if len(products) == size {
+			break
+		}
+		products = append(products, model.Product{ID: id})
+	}
+
+	if len(products) == 0 {
+		return repository.SearchResult{Products: products}, nil, nil
+	}
+	return repository.SearchResult{Products: products}, []interface{}{products[len(products)-1].ID}, nil
+}
+
+func TestProductCursors(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	catalogAPI, err := api.NewCatalogAPI(db, nil)
+	require.NoError(t, err)
+
+	filter := repository.ProductFilter{Tags: []string{}}
+	expected, err := db.GetProducts(filter, repository.OrderPriceDesc, 1, 100, ctx)
+	require.NoError(t, err)
+
+	t.Run("Walk the catalog", func(t *testing.T) {
+		page, err := catalogAPI.GetProductsPage(filter, repository.OrderPriceDesc, "", 1, 4, ctx)
+		require.NoError(t, err)
+
+		products := page.Products
+		for page.NextCursor != "" {
+			// The size in the cursor is used rather than the one requested
+			page, err = catalogAPI.GetProductsPage(filter, repository.OrderPriceDesc, page.NextCursor, 1, 1, ctx)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Products), 4)
+			products = append(products, page.Products...)
+		}
+
+		assert.Equal(t, productIDs(expected), productIDs(products))
+	})
+
+	page, err := catalogAPI.GetProductsPage(filter, repository.OrderPriceDesc, "", 1, 2, ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	t.Run("Different query", func(t *testing.T) {
+		_, err := catalogAPI.GetProductsPage(filter, repository.OrderNameAsc, page.NextCursor, 1, 2, ctx)
+		assert.ErrorIs(t, err, api.ErrInvalidCursor)
+
+		_, err = catalogAPI.GetProductsPage(repository.ProductFilter{Tags: []string{"luxury"}}, repository.OrderPriceDesc, page.NextCursor, 1, 2, ctx)
+		assert.ErrorIs(t, err, api.ErrInvalidCursor)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		payload, signature, _ := strings.Cut(page.NextCursor, ".")
+		for _, cursor := range []string{payload, "x" + page.NextCursor, payload + "." + signature[1:], "not-a-cursor"} {
+			_, err := catalogAPI.GetProductsPage(filter, repository.OrderPriceDesc, cursor, 1, 2, ctx)
+			assert.ErrorIs(t, err, api.ErrInvalidCursor, cursor)
+		}
+	})
+
+	t.Run("Signed with another secret", func(t *testing.T) {
+		other, err := api.NewCatalogAPI(db, nil)
+		require.NoError(t, err)
+
+		_, err = other.GetProductsPage(filter, repository.OrderPriceDesc, page.NextCursor, 1, 2, ctx)
+		assert.ErrorIs(t, err, api.ErrInvalidCursor)
+
+		catalogAPI.SetCursorSecret("shared")
+		other.SetCursorSecret("shared")
+
+		page, err := catalogAPI.GetProductsPage(filter, repository.OrderPriceDesc, "", 1, 2, ctx)
+		require.NoError(t, err)
+		_, err = other.GetProductsPage(filter, repository.OrderPriceDesc, page.NextCursor, 1, 2, ctx)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSearchCursors(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	ctx := context.Background()
+
+	walk := func(t *testing.T, catalogAPI *api.CatalogAPI) []string {
+		page, err := catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch"}, "", 1, 2, ctx)
+		require.NoError(t, err)
+
+		found := productIDs(page.Products)
+		for page.NextCursor != "" && len(found) < 20 {
+			page, err = catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch"}, page.NextCursor, 1, 2, ctx)
+			require.NoError(t, err)
+			found = append(found, productIDs(page.Products)...)
+		}
+		return found
+	}
+
+	t.Run("Search after", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &afterSearch{stubSearch{ids: ids}})
+		require.NoError(t, err)
+
+		assert.Equal(t, ids, walk(t, catalogAPI))
+
+		page, err := catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch"}, "", 1, 2, ctx)
+		require.NoError(t, err)
+		_, err = catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "other"}, page.NextCursor, 1, 2, ctx)
+		assert.ErrorIs(t, err, api.ErrInvalidCursor)
+	})
+
+	t.Run("Scoped by filters", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &afterSearch{stubSearch{ids: ids}})
+		require.NoError(t, err)
+
+		inStock := true
+		material, weight := "cotton", "120"
+		page, err := catalogAPI.SearchProductsPage(repository.SearchQuery{
+			Keyword:    "watch",
+			InStock:    &inStock,
+			Attributes: []model.AttributeFilter{{Name: "material", Value: &material}, {Name: "weight", Value: &weight}},
+		}, "", 1, 2, ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, page.NextCursor)
+
+		// Neither the order filters are given in nor asking for the active
+		// products searched anyway changes the query
+		_, err = catalogAPI.SearchProductsPage(repository.SearchQuery{
+			Keyword:    "watch",
+			InStock:    &inStock,
+			Attributes: []model.AttributeFilter{{Name: "weight", Value: &weight}, {Name: "material", Value: &material}},
+			Statuses:   []string{model.StatusActive},
+		}, page.NextCursor, 1, 2, ctx)
+		assert.NoError(t, err)
+
+		_, err = catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch", InStock: &inStock}, page.NextCursor, 1, 2, ctx)
+		assert.ErrorIs(t, err, api.ErrInvalidCursor)
+	})
+
+	t.Run("Offset fallback", func(t *testing.T) {
+		// stubSearch ignores the page, so every page is the first two results
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &stubSearch{ids: ids[:2]})
+		require.NoError(t, err)
+
+		page, err := catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch"}, "", 1, 2, ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, page.NextCursor)
+
+		page, err = catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: "watch"}, "", 1, 3, ctx)
+		require.NoError(t, err)
+		assert.Empty(t, page.NextCursor)
+	})
+}
+
+func TestCursorEndpoints(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &afterSearch{stubSearch{ids: []string{"a", "b", "c"}}})
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.GET("/catalog/categories/:name/products", c.GetCategoryProducts)
+	r.GET("/v2/catalog/search", c.SearchProductsV2)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	next := regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+	for _, url := range []string{"/catalog/products?sort=price&page=1&size=3", "/catalog/categories/accessories/products?size=2"} {
+		t.Run(url, func(t *testing.T) {
+			pages := 0
+			seen := map[string]bool{}
+			for url != "" {
+				w := get(url)
+				require.Equal(t, http.StatusOK, w.Code)
+				pages++
+
+				var products []model.Product
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+				for _, product := range products {
+					assert.False(t, seen[product.ID], product.ID)
+					seen[product.ID] = true
+				}
+
+				url = ""
+				if match := next.FindStringSubmatch(w.Header().Get("Link")); match != nil {
+					assert.NotContains(t, match[1], "page=")
+					url = match[1]
+				}
+			}
+			assert.Greater(t, pages, 1)
+		})
+	}
+
+	t.Run("Search envelope", func(t *testing.T) {
+		w := get("/v2/catalog/search?keyword=watch&size=2")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotEmpty(t, response.NextCursor)
+
+		w = get("/v2/catalog/search?keyword=watch&cursor=" + response.NextCursor)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var last model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &last))
+		assert.Equal(t, []string{"c"}, productIDs(last.Products))
+		assert.Empty(t, last.NextCursor)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		for _, url := range []string{"/catalog/products?cursor=bad", "/catalog/categories/accessories/products?cursor=bad", "/v2/catalog/search?keyword=watch&cursor=bad"} {
+			assert.Equal(t, http.StatusBadRequest, get(url).Code, url)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+	"github.com/aws-containers/retail-store-sample-app/catalog/grpcserver"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// newHangingSearch connects to an OpenSearch server that only answers once
+// the request is abandoned or the test ends
+func newHangingSearch(t *testing.T) *repository.OpenSearchRepository {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { search.Close() })
+
+	return search
+}
+
+func TestDeadlines(t *testing.T) {
+	search := newHangingSearch(t)
+
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+	require.NoError(t, err)
+
+	t.Run("REST", func(t *testing.T) {
+		c, err := controller.NewController(catalogAPI)
+		require.NoError(t, err)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/catalog/search", middleware.NewRequestLimits(50*time.Millisecond, 0), c.SearchProducts)
+
+		started := time.Now()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/search?keyword=watch", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code, w.Body.String())
+		assert.Less(t, time.Since(started), 2*time.Second)
+	})
+
+	t.Run("gRPC", func(t *testing.T) {
+		listener := bufconn.Listen(1024 * 1024)
+		server := grpc.NewServer(grpc.UnaryInterceptor(grpcserver.UnaryTimeouts(grpcserver.Timeouts{
+			Search: 50 * time.Millisecond,
+		})))
+		grpcserver.NewServer(catalogAPI).Register(server)
+
+		go server.Serve(listener)
+		defer server.Stop()
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		client := catalogv1.NewCatalogServiceClient(conn)
+
+		started := time.Now()
+		_, err = client.SearchProducts(context.Background(), &catalogv1.SearchProductsRequest{Keyword: "watch"})
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		assert.Less(t, time.Since(started), 2*time.Second)
+
+		// Calls without a timeout are left alone
+		_, err = client.GetProduct(context.Background(), &catalogv1.GetProductRequest{Id: "cc789f85-1476-452a-8100-9e74502198e0"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reindex", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, search.Reindex(ctx), context.DeadlineExceeded)
+		assert.ErrorIs(t, search.InitializeData(ctx), context.DeadlineExceeded)
+	})
+}
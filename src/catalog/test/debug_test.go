@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+)
+
+func TestDebugHandler(t *testing.T) {
+	handler := httputil.NewDebugHandler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Profiles", func(t *testing.T) {
+		w := get("/debug/pprof/")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "goroutine")
+		assert.Contains(t, w.Body.String(), "heap")
+
+		w = get("/debug/pprof/goroutine?debug=1")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "goroutine profile")
+	})
+
+	t.Run("Variables", func(t *testing.T) {
+		w := get("/debug/vars")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var vars map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vars))
+		assert.Contains(t, vars, "memstats")
+		assert.Contains(t, vars, "cmdline")
+	})
+
+	t.Run("Runtime statistics", func(t *testing.T) {
+		w := get("/debug/runtime")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+		var stats httputil.RuntimeStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.NotEmpty(t, stats.GoVersion)
+		assert.Positive(t, stats.Goroutines)
+		assert.Positive(t, stats.HeapAlloc)
+	})
+
+	t.Run("Only debug paths", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/catalog/products").Code)
+	})
+}
+
+func TestDebugConfigValidation(t *testing.T) {
+	validate := func(env map[string]string) error {
+		cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(env))
+		require.NoError(t, err)
+		return cfg.Validate()
+	}
+
+	assert.NoError(t, validate(map[string]string{"RETAIL_CATALOG_DEBUG_ENABLED": "true"}))
+	assert.NoError(t, validate(map[string]string{"RETAIL_CATALOG_DEBUG_ADDRESS": "invalid"}))
+
+	assert.ErrorContains(t, validate(map[string]string{
+		"RETAIL_CATALOG_DEBUG_ENABLED": "true",
+		"RETAIL_CATALOG_DEBUG_ADDRESS": "localhost",
+	}), "RETAIL_CATALOG_DEBUG_ADDRESS must be a host:port address")
+
+	assert.ErrorContains(t, validate(map[string]string{
+		"PORT":                         "6060",
+		"RETAIL_CATALOG_DEBUG_ENABLED": "true",
+	}), "RETAIL_CATALOG_DEBUG_ADDRESS must use a different port from PORT")
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestDeletedProducts(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/admin/deleted-products", c.GetDeletedProducts)
+	r.POST("/admin/deleted-products/:id/restore", c.RestoreProduct)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// deleted returns the deleted products this test made, as other tests
+	// share the database
+	deleted := func() map[string]model.Product {
+		w := send("GET", "/admin/deleted-products?size=100", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		ours := map[string]model.Product{}
+		for _, product := range products {
+			if product.ID == "deleted-lamp" || product.ID == "deleted-rug" {
+				ours[product.ID] = product
+			}
+		}
+		return ours
+	}
+	listed := func(id string) bool {
+		w := send("GET", "/catalog/products?sort=-price&size=10", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		for _, product := range products {
+			if product.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, body := range []string{
+		`{"id": "deleted-lamp", "name": "Lamp", "sku": "DEL-LAMP", "price": {"amount": 99999000, "currency": "USD"}, "variants": [{"sku": "DEL-LAMP-RED", "attributes": {"color": "red"}}]}`,
+		`{"id": "deleted-rug", "name": "Rug", "price": {"amount": 9000, "currency": "USD"}}`,
+	} {
+		w := send("POST", "/catalog/products", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	defer func() {
+		for _, id := range []string{"deleted-lamp", "deleted-rug", "deleted-other"} {
+			send("DELETE", "/catalog/products/"+id, "")
+		}
+	}()
+
+	t.Run("Delete", func(t *testing.T) {
+		require.True(t, listed("deleted-lamp"))
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/deleted-lamp", "").Code)
+
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/deleted-lamp", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", "/catalog/products/deleted-lamp", "").Code)
+		assert.False(t, listed("deleted-lamp"))
+
+		products := deleted()
+		require.Contains(t, products, "deleted-lamp")
+		assert.True(t, products["deleted-lamp"].DeletedAt.Valid)
+		assert.Len(t, products["deleted-lamp"].Variants, 1)
+	})
+
+	t.Run("Codes stay taken", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "deleted-other", "name": "Other", "sku": "DEL-LAMP"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		w := send("POST", "/admin/deleted-products/deleted-lamp/restore", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "Lamp", product.Name)
+		assert.False(t, product.DeletedAt.Valid)
+		assert.Len(t, product.Variants, 1)
+
+		assert.Equal(t, http.StatusOK, send("GET", "/catalog/products/deleted-lamp", "").Code)
+		assert.True(t, listed("deleted-lamp"))
+		assert.NotContains(t, deleted(), "deleted-lamp")
+
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/deleted-products/deleted-lamp/restore", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/deleted-products/missing/restore", "").Code)
+	})
+
+	t.Run("Reusing an ID", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/deleted-rug", "").Code)
+		require.Contains(t, deleted(), "deleted-rug")
+
+		w := send("POST", "/catalog/products", `{"id": "deleted-rug", "name": "New rug", "price": {"amount": 9500, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.NotContains(t, deleted(), "deleted-rug")
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/deleted-products/deleted-rug/restore", "").Code)
+	})
+
+	t.Run("Page size", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/deleted-products?size=0", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/deleted-products?page=0", "").Code)
+	})
+}
+
+func TestDualWrite_Restore(t *testing.T) {
+	index := newFakeIndex()
+	repo := newDualWriteRepository(t, index)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateProduct(&model.Product{ID: "dual-write-restore", Name: "Test", Price: model.Money{Amount: 10}}, ctx))
+	defer repo.DeleteProduct("dual-write-restore", ctx)
+
+	require.NoError(t, repo.DeleteProduct("dual-write-restore", ctx))
+	assert.NotContains(t, index.docs, "dual-write-restore")
+
+	product, err := repo.RestoreProduct("dual-write-restore", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Test", product.Name)
+	assert.Contains(t, index.docs, "dual-write-restore")
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+type fakeIndex struct {
+	mu      sync.Mutex
+	docs    map[string]model.Product
+	failing bool
+}
+
+func newFakeIndex() *fakeIndex {
+	return &fakeIndex{docs: map[string]model.Product{}}
+}
+
+func (f *fakeIndex) IndexProduct(product model.Product, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing {
+		return errors.New("index unavailable")
+	}
+	f.docs[product.ID] = product
+	return nil
+}
+
+func (f *fakeIndex) RemoveProduct(id string, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing {
+		return errors.New("index unavailable")
+	}
+	delete(f.docs, id)
+	return nil
+}
+
+func (f *fakeIndex) IndexedProductIDs(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := []string{}
+	for id := range f.docs {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func newDualWriteRepository(t *testing.T, index *fakeIndex) *repository.DualWriteRepository {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+
+	return repository.NewDualWriteRepository(writable, index)
+}
+
+func TestDualWrite_WritesBothSides(t *testing.T) {
+	index := newFakeIndex()
+	repo := newDualWriteRepository(t, index)
+	ctx := context.Background()
+
+	product := &model.Product{ID: "dual-write-1", Name: "Test", Price: model.Money{Amount: 10}, Tags: []model.Tag{{Name: "clothing"}}}
+	require.NoError(t, repo.CreateProduct(product, ctx))
+	defer repo.DeleteProduct(product.ID, ctx)
+
+	assert.Contains(t, index.docs, product.ID)
+	assert.ErrorIs(t, repo.CreateProduct(product, ctx), repository.ErrProductExists)
+
+	product.Name = "Updated"
+	require.NoError(t, repo.UpdateProduct(product, ctx))
+	assert.Equal(t, "Updated", index.docs[product.ID].Name)
+
+	stored, err := repo.GetProduct(product.ID, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", stored.Name)
+	assert.Equal(t, "clothing", stored.Tags[0].Name)
+
+	assert.ErrorIs(t, repo.UpdateProduct(&model.Product{ID: "missing"}, ctx), repository.ErrProductNotFound)
+	assert.ErrorIs(t, repo.CreateProduct(&model.Product{ID: "dual-write-2", Tags: []model.Tag{{Name: "nope"}}}, ctx), repository.ErrUnknownTag)
+}
+
+func TestDualWrite_Reconcile(t *testing.T) {
+	index := newFakeIndex()
+	repo := newDualWriteRepository(t, index)
+	ctx := context.Background()
+
+	index.docs["orphan"] = model.Product{ID: "orphan"}
+
+	index.failing = true
+	product := &model.Product{ID: "dual-write-3", Name: "Test", Price: model.Money{Amount: 10}}
+	require.NoError(t, repo.CreateProduct(product, ctx))
+	defer repo.DeleteProduct(product.ID, ctx)
+
+	require.Len(t, repo.Failures(), 1)
+	assert.Equal(t, "create", repo.Failures()[0].Operation)
+	index.failing = false
+
+	report, err := repo.Reconcile(false, ctx)
+	require.NoError(t, err)
+	assert.Contains(t, report.MissingFromIndex, product.ID)
+	assert.Equal(t, []string{"orphan"}, report.OrphanedInIndex)
+	assert.Equal(t, 0, report.Repaired)
+
+	report, err = repo.Reconcile(true, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, report.PrimaryCount+1, report.Repaired)
+	assert.Empty(t, report.Errors)
+	assert.Empty(t, repo.Failures())
+
+	report, err = repo.Reconcile(false, ctx)
+	require.NoError(t, err)
+	assert.Empty(t, report.MissingFromIndex)
+	assert.Empty(t, report.OrphanedInIndex)
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+func TestEventBroker(t *testing.T) {
+	broker := api.NewEventBroker()
+
+	subscription := broker.Subscribe(0)
+	broker.Publish(model.CatalogEvent{Type: model.EventProductCreated, ProductID: "a"})
+	broker.Publish(model.CatalogEvent{Type: model.EventProductDeleted, ProductID: "a"})
+
+	first := <-subscription.Events
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, model.EventProductCreated, first.Type)
+	assert.False(t, first.Time.IsZero())
+	assert.Equal(t, uint64(2), (<-subscription.Events).ID)
+
+	t.Run("Resume after last event ID", func(t *testing.T) {
+		resumed := broker.Subscribe(1)
+		defer resumed.Close()
+
+		event := <-resumed.Events
+		assert.Equal(t, uint64(2), event.ID)
+		assert.Equal(t, model.EventProductDeleted, event.Type)
+	})
+
+	t.Run("Slow subscribers are disconnected", func(t *testing.T) {
+		slow := broker.Subscribe(0)
+		for i := 0; i < 100; i++ {
+			broker.Publish(model.CatalogEvent{Type: model.EventProductUpdated})
+		}
+
+		received := 0
+		for range slow.Events {
+			received++
+		}
+		assert.Less(t, received, 100)
+	})
+
+	t.Run("Close ends subscriptions", func(t *testing.T) {
+		open := broker.Subscribe(0)
+		broker.Close()
+
+		_, ok := <-open.Events
+		assert.False(t, ok)
+
+		_, ok = <-broker.Subscribe(0).Events
+		assert.False(t, ok)
+	})
+}
+
+func TestStreamEvents(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/events", c.StreamEvents)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/catalog/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	product := &model.Product{ID: "sse-product", Name: "SSE product", Price: model.Money{Amount: 10}}
+	_, err = catalogAPI.CreateProduct(product, ctx)
+	require.NoError(t, err)
+	draft := &model.Product{ID: "sse-draft", Name: "SSE draft", Status: model.StatusDraft, Price: model.Money{Amount: 10}}
+	_, err = catalogAPI.CreateProduct(draft, ctx)
+	require.NoError(t, err)
+	defer catalogAPI.DeleteProduct(draft.ID, context.Background())
+	require.NoError(t, catalogAPI.DeleteProduct(product.ID, ctx))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() map[string]string {
+		fields := map[string]string{}
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return fields
+			}
+
+			name, value, _ := strings.Cut(line, ": ")
+			fields[name] = value
+		}
+	}
+
+	created := readEvent()
+	assert.Equal(t, "1", created["id"])
+	assert.Equal(t, model.EventProductCreated, created["event"])
+
+	var event model.CatalogEvent
+	require.NoError(t, json.Unmarshal([]byte(created["data"]), &event))
+	assert.Equal(t, "sse-product", event.ProductID)
+	assert.Equal(t, "SSE product", event.Product.Name)
+
+	// Drafts are announced without the product
+	createdDraft := readEvent()
+	assert.Equal(t, model.EventProductCreated, createdDraft["event"])
+	assert.NotContains(t, createdDraft["data"], "SSE draft")
+
+	var draftEvent model.CatalogEvent
+	require.NoError(t, json.Unmarshal([]byte(createdDraft["data"]), &draftEvent))
+	assert.Equal(t, "sse-draft", draftEvent.ProductID)
+	assert.Nil(t, draftEvent.Product)
+
+	deleted := readEvent()
+	assert.Equal(t, "3", deleted["id"])
+	assert.Equal(t, model.EventProductDeleted, deleted["event"])
+}
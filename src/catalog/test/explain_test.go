@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// fakeOpenSearch answers the search and explain requests of the OpenSearch
+// repository, recording the query body sent to explain
+func fakeOpenSearch(t *testing.T, explained *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.URL.Path == "/products/_search":
+			io.WriteString(w, `{"hits": {"total": {"value": 2}, "hits": [
+				{"_id": "a1", "_score": 2.5, "_source": {"id": "a1", "name": "Blue Watch"}},
+				{"_id": "b2", "_score": 1.25, "_source": {"id": "b2", "name": "Watch Strap"}}
+			]}}`)
+		case strings.HasPrefix(r.URL.Path, "/products/_explain/"):
+			body, _ := io.ReadAll(r.Body)
+			*explained = append(*explained, string(body))
+			io.WriteString(w, `{"matched": true, "explanation": {"value": 2.5, "description": "sum of:", "details": [
+				{"value": 2.5, "description": "weight(name:watch)"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExplainSearch(t *testing.T) {
+	var explained []string
+	server := fakeOpenSearch(t, &explained)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/search/explain", c.ExplainSearch)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/search/explain?"+query, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Explain", func(t *testing.T) {
+		w := get("keyword=watch&size=2")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var explanation model.SearchExplanation
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &explanation))
+		assert.Equal(t, "watch", explanation.Keyword)
+		assert.Contains(t, explanation.Query, "query")
+		assert.EqualValues(t, 2, explanation.Query["size"])
+
+		require.Len(t, explanation.Hits, 2)
+		assert.Equal(t, "a1", explanation.Hits[0].ID)
+		assert.Equal(t, "Blue Watch", explanation.Hits[0].Name)
+		assert.Equal(t, 2.5, explanation.Hits[0].Score)
+		assert.Equal(t, "sum of:", explanation.Hits[0].Explanation.Description)
+		require.Len(t, explanation.Hits[0].Explanation.Details, 1)
+		assert.Equal(t, "weight(name:watch)", explanation.Hits[0].Explanation.Details[0].Description)
+
+		require.Len(t, explained, 2)
+		assert.Contains(t, explained[0], `"multi_match"`)
+		assert.NotContains(t, explained[0], `"size"`)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("").Code)
+		assert.Equal(t, http.StatusBadRequest, get("keyword=watch&size=0").Code)
+		assert.Equal(t, http.StatusBadRequest, get("keyword=watch&size=100").Code)
+	})
+}
+
+func TestExplainSearch_Boosts(t *testing.T) {
+	var explained []string
+	server := fakeOpenSearch(t, &explained)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+		Boosts:    map[string]float64{"name": 3, "description": 0.5},
+	})
+	require.NoError(t, err)
+
+	explanation, err := search.ExplainSearch("watch", 1, context.Background())
+	require.NoError(t, err)
+
+	query := explanation.Query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})["multi_match"].(map[string]interface{})
+	assert.Equal(t, []string{"description^0.5", "name^3"}, query["fields"])
+
+	search.SetBoosts(map[string]float64{"tags": 2})
+
+	explanation, err = search.ExplainSearch("watch", 1, context.Background())
+	require.NoError(t, err)
+
+	query = explanation.Query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})["multi_match"].(map[string]interface{})
+	assert.Equal(t, []string{"tags^2"}, query["fields"])
+}
+
+func TestExplainSearch_NotSupported(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &stubSearch{})
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/search/explain", c.ExplainSearch)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/catalog/search/explain?keyword=watch", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
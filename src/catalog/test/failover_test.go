@@ -0,0 +1,225 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+const failoverCooldown = 100 * time.Millisecond
+
+// newFailingSearch connects to an OpenSearch server that fails every search
+// until healthy is set, behind a breaker that opens after two failures
+func newFailingSearch(t *testing.T) (*repository.OpenSearchRepository, *atomic.Int32, *atomic.Bool) {
+	var searches atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		searches.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"error": "unavailable"}`)
+			return
+		}
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "os1", "_source": {"id": "os1"}, "sort": [1, "os1"]}]}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:         server.URL,
+		IndexName:        "products",
+		BreakerThreshold: 2,
+		BreakerCooldown:  failoverCooldown,
+	})
+	require.NoError(t, err)
+
+	return search, &searches, &healthy
+}
+
+// searchDegraded runs a search, reporting whether it was answered by the fallback
+func searchDegraded(search repository.SearchRepository, ctx context.Context) ([]string, bool, error) {
+	ctx, degraded := repository.TrackDegraded(ctx)
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+	return productIDs(result.Products), degraded(), err
+}
+
+func TestSearchFailover(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	ctx := context.Background()
+
+	t.Run("Breaker opens after failures", func(t *testing.T) {
+		search, searches, _ := newFailingSearch(t)
+		search.SetFallback(&stubSearch{ids: []string{"db1"}})
+
+		_, degraded, err := searchDegraded(search, ctx)
+		require.Error(t, err)
+		assert.False(t, degraded)
+
+		// The failure that opens the breaker is answered by the fallback
+		ids, degraded, err := searchDegraded(search, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"db1"}, ids)
+		assert.True(t, degraded)
+
+		ids, degraded, err = searchDegraded(search, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"db1"}, ids)
+		assert.True(t, degraded)
+		assert.EqualValues(t, 2, searches.Load(), "no search is sent while the breaker is open")
+
+		status, _ := search.Status(ctx)
+		assert.Equal(t, "open", status["breaker"])
+	})
+
+	t.Run("Probe closes the breaker", func(t *testing.T) {
+		search, searches, healthy := newFailingSearch(t)
+		search.SetFallback(&stubSearch{ids: []string{"db1"}})
+
+		for range 2 {
+			searchDegraded(search, ctx)
+		}
+		healthy.Store(true)
+		time.Sleep(failoverCooldown)
+
+		ids, degraded, err := searchDegraded(search, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"os1"}, ids)
+		assert.False(t, degraded)
+		assert.EqualValues(t, 3, searches.Load())
+
+		_, degraded, err = searchDegraded(search, ctx)
+		require.NoError(t, err)
+		assert.False(t, degraded)
+	})
+
+	t.Run("Failed probe reopens the breaker", func(t *testing.T) {
+		search, searches, _ := newFailingSearch(t)
+		search.SetFallback(&stubSearch{ids: []string{"db1"}})
+
+		for range 2 {
+			searchDegraded(search, ctx)
+		}
+		time.Sleep(failoverCooldown)
+
+		for range 3 {
+			ids, degraded, err := searchDegraded(search, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"db1"}, ids)
+			assert.True(t, degraded)
+		}
+		assert.EqualValues(t, 3, searches.Load(), "only the probe is sent")
+	})
+
+	t.Run("Cursors can't fail over", func(t *testing.T) {
+		search, _, _ := newFailingSearch(t)
+		search.SetFallback(&stubSearch{ids: []string{"db1"}})
+
+		for range 2 {
+			searchDegraded(search, ctx)
+		}
+
+		result, last, err := search.SearchProductsAfter(repository.SearchQuery{Keyword: "watch"}, nil, 10, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"db1"}, productIDs(result.Products))
+		assert.Nil(t, last)
+
+		_, _, err = search.SearchProductsAfter(repository.SearchQuery{Keyword: "watch"}, []interface{}{1, "os1"}, 10, ctx)
+		assert.ErrorIs(t, err, repository.ErrBreakerOpen)
+	})
+
+	t.Run("Without a fallback", func(t *testing.T) {
+		search, _, _ := newFailingSearch(t)
+
+		for range 2 {
+			searchDegraded(search, ctx)
+		}
+
+		_, _, err := searchDegraded(search, ctx)
+		assert.ErrorIs(t, err, repository.ErrBreakerOpen)
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_search_failovers_total Searches answered by the database because the circuit breaker around OpenSearch was open
+# TYPE catalog_search_failovers_total counter
+catalog_search_failovers_total 9
+`), "catalog_search_failovers_total"))
+}
+
+func TestSearchFailoverHeader(t *testing.T) {
+	search, _, healthy := newFailingSearch(t)
+	search.SetFallback(&stubSearch{ids: []string{"db1"}})
+
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+	require.NoError(t, err)
+	catalogAPI.SetCache(10, time.Minute)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/search", c.SearchProducts)
+	r.GET("/v2/catalog/search", c.SearchProductsV2)
+
+	get := func(url string) (*httptest.ResponseRecorder, []string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+
+		var products []model.Product
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		}
+		return w, productIDs(products)
+	}
+
+	w, _ := get("/catalog/search?keyword=watch&page=2")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w, ids := get("/catalog/search?keyword=watch&page=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"db1"}, ids)
+	assert.Equal(t, "search", w.Header().Get("X-Catalog-Degraded"))
+
+	// A cursor can't be continued by the database
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v2/catalog/search?keyword=watch&size=1", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "search", w.Header().Get("X-Catalog-Degraded"))
+	assert.NotContains(t, w.Body.String(), "nextCursor")
+
+	// Degraded results aren't cached, so OpenSearch answers once it recovers
+	healthy.Store(true)
+	time.Sleep(failoverCooldown)
+
+	w, ids = get("/catalog/search?keyword=watch&page=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"os1"}, ids)
+	assert.Empty(t, w.Header().Get("X-Catalog-Degraded"))
+}
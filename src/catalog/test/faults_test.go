@@ -0,0 +1,145 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestDependencyFaults(t *testing.T) {
+	t.Cleanup(repository.ClearFaults)
+
+	router, _ := setupTestRouter()
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+	const productID = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("Errors", func(t *testing.T) {
+		w := send("POST", "/chaos/dependencies/database", `{"errorRate": 1}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		_, err := db.GetProduct(productID, ctx)
+		assert.ErrorIs(t, err, repository.ErrInjectedFault)
+
+		var faults map[string]map[string]any
+		require.NoError(t, json.Unmarshal(send("GET", "/chaos/dependencies", "").Body.Bytes(), &faults))
+		assert.Equal(t, 1.0, faults["database"]["errorRate"])
+		assert.Equal(t, 0.0, faults["opensearch"]["errorRate"])
+
+		require.Equal(t, http.StatusOK, send("DELETE", "/chaos/dependencies/database", "").Code)
+
+		_, err = db.GetProduct(productID, ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Surfaced as server errors", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, nil)
+		require.NoError(t, err)
+
+		c, err := controller.NewController(catalogAPI)
+		require.NoError(t, err)
+
+		r := gin.New()
+		r.GET("/catalog/products", c.GetProducts)
+		r.GET("/catalog/products/:id", c.GetProduct)
+
+		get := func(path string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		require.NoError(t, repository.SetFault(repository.DependencyDatabase, repository.Fault{ErrorRate: 1}))
+		defer repository.ClearFaults()
+
+		assert.Equal(t, http.StatusInternalServerError, get("/catalog/products"))
+		assert.Equal(t, http.StatusInternalServerError, get("/catalog/products/"+productID))
+
+		repository.ClearFaults()
+		assert.Equal(t, http.StatusNotFound, get("/catalog/products/missing"))
+	})
+
+	t.Run("Blackhole", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send("POST", "/chaos/dependencies/database", `{"blackhole": true}`).Code)
+		defer repository.ClearFaults()
+
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := db.GetProduct(productID, timeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("OpenSearch latency", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/":
+				io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			default:
+				io.WriteString(w, `{"hits": {"total": {"value": 0}, "hits": []}}`)
+			}
+		}))
+		defer server.Close()
+
+		search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+			Endpoint:  server.URL,
+			IndexName: "products",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, send("POST", "/chaos/dependencies/opensearch", `{"latencyMs": 100}`).Code)
+		defer repository.ClearFaults()
+
+		started := time.Now()
+		_, err = search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+
+		// The database is unaffected
+		_, err = db.GetProduct(productID, ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Feature flag off", func(t *testing.T) {
+		require.NoError(t, repository.SetFault(repository.DependencyDatabase, repository.Fault{ErrorRate: 1}))
+		defer repository.ClearFaults()
+
+		repository.SetFaultsEnabled(func() bool { return false })
+		defer repository.SetFaultsEnabled(func() bool { return true })
+
+		_, err := db.GetProduct(productID, ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid faults", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/chaos/dependencies/cache", `{"errorRate": 1}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/chaos/dependencies/database", `{"errorRate": 2}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/chaos/dependencies/database", `{"latencyMs": -1}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/chaos/dependencies/database", `not json`).Code)
+		assert.Empty(t, repository.Faults())
+	})
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/appconfigdata"
+	"github.com/aws/aws-sdk-go/service/appconfigdata/appconfigdataiface"
+	"github.com/gin-gonic/gin"
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/features"
+)
+
+// fakeAppConfig returns each of configurations in turn, then nothing as if
+// unchanged, failing once if err is set
+type fakeAppConfig struct {
+	appconfigdataiface.AppConfigDataAPI
+	configurations []string
+	sessions       int
+	err            error
+}
+
+func (f *fakeAppConfig) StartConfigurationSessionWithContext(ctx aws.Context, input *appconfigdata.StartConfigurationSessionInput, opts ...request.Option) (*appconfigdata.StartConfigurationSessionOutput, error) {
+	f.sessions++
+	return &appconfigdata.StartConfigurationSessionOutput{InitialConfigurationToken: aws.String("initial")}, nil
+}
+
+func (f *fakeAppConfig) GetLatestConfigurationWithContext(ctx aws.Context, input *appconfigdata.GetLatestConfigurationInput, opts ...request.Option) (*appconfigdata.GetLatestConfigurationOutput, error) {
+	if f.err != nil {
+		err := f.err
+		f.err = nil
+		return nil, err
+	}
+
+	output := &appconfigdata.GetLatestConfigurationOutput{
+		NextPollConfigurationToken: aws.String("next"),
+		NextPollIntervalInSeconds:  aws.Int64(60),
+		VersionLabel:               aws.String("1"),
+	}
+	if len(f.configurations) > 0 {
+		output.Configuration = []byte(f.configurations[0])
+		f.configurations = f.configurations[1:]
+	}
+	return output, nil
+}
+
+func TestFeatureFlags(t *testing.T) {
+	t.Run("Precedence", func(t *testing.T) {
+		flags := features.New(nil)
+		assert.True(t, flags.Enabled(features.Chaos))
+		assert.False(t, flags.Enabled("unknown"))
+
+		flags.SetConfigured(map[string]bool{features.Chaos: false})
+		assert.False(t, flags.Enabled(features.Chaos))
+
+		flags.SetRemote(map[string]bool{features.Chaos: true})
+		assert.True(t, flags.Enabled(features.Chaos))
+
+		flags.SetRemote(nil)
+		assert.False(t, flags.Enabled(features.Chaos))
+		assert.Equal(t, map[string]bool{features.Chaos: false, features.LiveSearch: true, features.SearchExplain: true}, flags.All())
+	})
+
+	t.Run("Environment", func(t *testing.T) {
+		cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(map[string]string{
+			"RETAIL_CATALOG_FEATURE_FLAGS": "chaos:false,liveSearch:true",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{features.Chaos: false, features.LiveSearch: true}, cfg.Features.Flags)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		flags := features.New(nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(flags.When(features.Chaos, func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusTeapot)
+		}))
+		r.GET("/live", flags.Require(features.LiveSearch), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		get := func() int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/live", nil)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusTeapot, get())
+
+		flags.SetConfigured(map[string]bool{features.Chaos: false})
+		assert.Equal(t, http.StatusOK, get())
+
+		flags.SetConfigured(map[string]bool{features.Chaos: false, features.LiveSearch: false})
+		assert.Equal(t, http.StatusNotFound, get())
+	})
+}
+
+func TestFeatureFlagsAppConfig(t *testing.T) {
+	ctx := context.Background()
+	flags := features.New(map[string]bool{features.LiveSearch: false})
+	client := &fakeAppConfig{configurations: []string{
+		`{"chaos": {"enabled": false}, "liveSearch": {"enabled": true, "rollout": 50}}`,
+		`{"chaos": {"enabled": true}}`,
+	}}
+	appConfig := features.NewAppConfigWithClient(client, config.AppConfigConfiguration{
+		Application:  "retail-store",
+		Environment:  "demo",
+		Profile:      "catalog-flags",
+		PollInterval: time.Second,
+	}, flags)
+
+	require.NoError(t, appConfig.Poll(ctx))
+	assert.False(t, flags.Enabled(features.Chaos))
+	assert.True(t, flags.Enabled(features.LiveSearch))
+
+	require.NoError(t, appConfig.Poll(ctx))
+	assert.True(t, flags.Enabled(features.Chaos))
+	assert.False(t, flags.Enabled(features.LiveSearch))
+
+	// Unchanged configuration keeps the current flags
+	require.NoError(t, appConfig.Poll(ctx))
+	assert.True(t, flags.Enabled(features.Chaos))
+	assert.Equal(t, 1, client.sessions)
+
+	// A new session is started after a failed poll
+	client.err = errors.New("token expired")
+	assert.Error(t, appConfig.Poll(ctx))
+	assert.True(t, flags.Enabled(features.Chaos))
+
+	require.NoError(t, appConfig.Poll(ctx))
+	assert.Equal(t, 2, client.sessions)
+
+	_, err := features.ParseAppConfigFlags([]byte("not json"))
+	assert.Error(t, err)
+}
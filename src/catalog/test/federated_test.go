@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+type stubSearch struct {
+	ids []string
+	err error
+}
+
+func (s *stubSearch) SearchProducts(query repository.SearchQuery, page, size int, ctx context.Context) (repository.SearchResult, error) {
+	if s.err != nil {
+		return repository.SearchResult{}, s.err
+	}
+
+	products := []model.Product{}
+	for _, id := range s.ids {
+		products = append(products, model.Product{ID: id})
+	}
+	return repository.SearchResult{Products: products}, nil
+}
+
+func (s *stubSearch) Reindex(ctx context.Context) error {
+	return s.err
+}
+
+func productIDs(products []model.Product) []string {
+	ids := []string{}
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	return ids
+}
+
+func TestFederatedSearch_Fusion(t *testing.T) {
+	repo := repository.NewFederatedSearchRepository(
+		repository.FederatedBackend{Name: "a", Repository: &stubSearch{ids: []string{"1", "2", "3"}}},
+		repository.FederatedBackend{Name: "b", Repository: &stubSearch{ids: []string{"3", "4"}}},
+	)
+
+	result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "test"}, 1, 10, context.Background())
+	require.NoError(t, err)
+
+	// "3" appears in both lists so outranks everything else
+	assert.Equal(t, []string{"3", "1", "2", "4"}, productIDs(result.Products))
+
+	result, err = repo.SearchProducts(repository.SearchQuery{Keyword: "test"}, 2, 3, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4"}, productIDs(result.Products))
+}
+
+func TestFederatedSearch_Failures(t *testing.T) {
+	failing := &stubSearch{err: errors.New("unavailable")}
+
+	repo := repository.NewFederatedSearchRepository(
+		repository.FederatedBackend{Name: "a", Repository: failing},
+		repository.FederatedBackend{Name: "b", Repository: &stubSearch{ids: []string{"1"}}},
+	)
+
+	result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "test"}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, productIDs(result.Products))
+	assert.Error(t, repo.Reindex(context.Background()))
+
+	repo = repository.NewFederatedSearchRepository(
+		repository.FederatedBackend{Name: "a", Repository: failing},
+	)
+
+	_, err = repo.SearchProducts(repository.SearchQuery{Keyword: "test"}, 1, 10, context.Background())
+	assert.ErrorContains(t, err, "all federated search backends failed")
+}
+
+func TestDatabaseSearch(t *testing.T) {
+	search, err := repository.NewSearchRepository(config.AppConfiguration{
+		Search: config.SearchConfiguration{Backend: "database"},
+	}, newInMemoryRepository(t))
+	require.NoError(t, err)
+
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "Tickstopper"}, 1, 10, context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Products)
+	assert.Equal(t, "Temporal Tickstopper", result.Products[0].Name)
+
+	result, err = search.SearchProducts(repository.SearchQuery{Keyword: "100%"}, 1, 10, context.Background())
+	require.NoError(t, err)
+	for _, product := range result.Products {
+		assert.Contains(t, product.Name+product.Description, "100%")
+	}
+}
+
+func newInMemoryRepository(t *testing.T) repository.CatalogRepository {
+	db, err := repository.NewRepository(config.DatabaseConfiguration{Type: "in-memory"})
+	require.NoError(t, err)
+
+	return db
+}
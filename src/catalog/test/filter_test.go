@@ -0,0 +1,41 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestProductFilterAndOrder(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	minPrice, maxPrice := 5000, 10000
+	filter := repository.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}
+
+	products, err := db.GetProducts(filter, repository.OrderPriceDesc, 1, 100, ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, products)
+
+	for i, product := range products {
+		assert.GreaterOrEqual(t, product.Price.Amount, minPrice)
+		assert.LessOrEqual(t, product.Price.Amount, maxPrice)
+		if i > 0 {
+			assert.LessOrEqual(t, product.Price.Amount, products[i-1].Price.Amount)
+		}
+	}
+
+	count, err := db.CountProducts(filter, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(products), count)
+
+	products, err = db.GetProducts(repository.ProductFilter{}, repository.OrderNameDesc, 1, 100, ctx)
+	require.NoError(t, err)
+	for i := 1; i < len(products); i++ {
+		assert.GreaterOrEqual(t, products[i-1].Name, products[i].Name)
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/grpcserver"
+)
+
+func TestGateway(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &stubSearch{ids: []string{"a1"}})
+	require.NoError(t, err)
+
+	gateway, err := grpcserver.NewServer(catalogAPI).Gateway(context.Background())
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		gateway.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Get product", func(t *testing.T) {
+		w := get("/v1/products/cc789f85-1476-452a-8100-9e74502198e0")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "cc789f85-1476-452a-8100-9e74502198e0", product["id"])
+		assert.NotEmpty(t, product["name"])
+
+		assert.Equal(t, http.StatusNotFound, get("/v1/products/missing").Code)
+	})
+
+	t.Run("List products", func(t *testing.T) {
+		w := get("/v1/products?size=3")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			Products      []map[string]any `json:"products"`
+			NextPageToken string           `json:"nextPageToken"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Products, 3)
+		require.NotEmpty(t, response.NextPageToken)
+
+		w = get("/v1/products?pageToken=" + response.NextPageToken)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, get("/v1/products?pageToken=bad").Code)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		w := get("/v1/search?keyword=watch")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"a1"`)
+
+		assert.Equal(t, http.StatusBadRequest, get("/v1/search").Code)
+	})
+}
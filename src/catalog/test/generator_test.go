@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/generator"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestGenerateProducts(t *testing.T) {
+	parent := "clothing"
+	tags := []model.Tag{{Name: "clothing"}}
+	categories := []model.Category{
+		{Name: "clothing", Path: "clothing"},
+		{Name: "footwear", ParentName: &parent, Path: "clothing/footwear"},
+		{Name: "hats", ParentName: &parent, Path: "clothing/hats"},
+	}
+
+	products := generator.Products(200, 7, tags, categories)
+	require.Len(t, products, 200)
+	assert.Equal(t, products, generator.Products(200, 7, tags, categories), "the same seed generates the same products")
+	assert.NotEqual(t, productNames(products[:10]), productNames(generator.Products(10, 8, tags, categories)), "other seeds generate other products")
+
+	names := map[string]bool{}
+	for i, product := range products {
+		assert.Equal(t, fmt.Sprintf("generated-7-%d", i+1), product.ID)
+		assert.NotEmpty(t, product.Name)
+		assert.NotEmpty(t, product.Description)
+		assert.Equal(t, "USD", product.Price.Currency)
+		assert.Positive(t, product.Price.Amount)
+		assert.Zero(t, product.Price.Amount%100, "prices are whole dollars")
+		require.NotNil(t, product.Stock)
+		assert.LessOrEqual(t, *product.Stock, 100)
+
+		require.NotNil(t, product.CategoryName)
+		assert.Contains(t, []string{"footwear", "hats"}, *product.CategoryName, "only categories without subcategories are used")
+		assert.Equal(t, []model.Tag{{Name: "clothing"}}, product.Tags)
+		names[product.Name] = true
+	}
+	assert.Greater(t, len(names), 50, "names vary")
+
+	uncategorized := generator.Products(10, 7, nil, nil)
+	for _, product := range uncategorized {
+		assert.Nil(t, product.CategoryName)
+		assert.Empty(t, product.Tags)
+	}
+}
+
+func TestGenerateEndpoint(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	catalogAPI, err := api.NewCatalogAPI(db, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/generate", c.GenerateProducts)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/generate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"count": 25, "seed": 3}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job model.ImportJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/admin/imports/"+job.ID, w.Header().Get("Location"))
+	assert.Equal(t, 25, job.Total)
+
+	job = waitForImport(t, catalogAPI, job.ID)
+	assert.Equal(t, model.ImportCompleted, job.Status)
+	assert.Equal(t, 25, job.Created)
+	assert.Empty(t, job.Errors, "generated products are in known tags and categories")
+
+	ids := make([]string, 25)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("generated-3-%d", i+1)
+	}
+	defer func() {
+		for _, id := range ids {
+			db.(repository.CatalogWriter).DeleteProduct(id, ctx)
+		}
+	}()
+
+	product, err := db.GetProduct("generated-3-1", ctx)
+	require.NoError(t, err)
+	require.NotNil(t, product.Category)
+	assert.Contains(t, product.Category.Path, "/")
+
+	// Generating again with the seed replaces the products
+	w = post(`{"count": 25, "seed": 3}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	job = waitForImport(t, catalogAPI, job.ID)
+	assert.Equal(t, 25, job.Updated)
+
+	for _, body := range []string{`{}`, `{"count": 0}`, `{"count": 100001}`, `{"count": "many"}`, `{"count": 1, "seed": -1}`} {
+		assert.Equal(t, http.StatusBadRequest, post(body).Code, body)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+	"github.com/aws-containers/retail-store-sample-app/catalog/grpcserver"
+)
+
+func newGRPCClient(t *testing.T) catalogv1.CatalogServiceClient {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	grpcserver.NewServer(catalogAPI).Register(server)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return catalogv1.NewCatalogServiceClient(conn)
+}
+
+func TestGRPCGetProduct(t *testing.T) {
+	client := newGRPCClient(t)
+
+	product, err := client.GetProduct(context.Background(), &catalogv1.GetProductRequest{Id: "cc789f85-1476-452a-8100-9e74502198e0"})
+	require.NoError(t, err)
+	assert.Equal(t, "cc789f85-1476-452a-8100-9e74502198e0", product.GetId())
+	assert.NotEmpty(t, product.GetName())
+
+	_, err = client.GetProduct(context.Background(), &catalogv1.GetProductRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetProduct(context.Background(), &catalogv1.GetProductRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCListProducts(t *testing.T) {
+	client := newGRPCClient(t)
+
+	response, err := client.ListProducts(context.Background(), &catalogv1.ListProductsRequest{Size: 5})
+	require.NoError(t, err)
+	assert.Len(t, response.GetProducts(), 5)
+	require.NotEmpty(t, response.GetNextPageToken())
+
+	next, err := client.ListProducts(context.Background(), &catalogv1.ListProductsRequest{PageToken: response.GetNextPageToken()})
+	require.NoError(t, err)
+	assert.Len(t, next.GetProducts(), 5)
+	assert.NotEqual(t, response.GetProducts()[0].GetId(), next.GetProducts()[0].GetId())
+
+	_, err = client.ListProducts(context.Background(), &catalogv1.ListProductsRequest{PageToken: "bad"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListProducts(context.Background(), &catalogv1.ListProductsRequest{Page: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCSearchDisabled(t *testing.T) {
+	client := newGRPCClient(t)
+
+	_, err := client.SearchProducts(context.Background(), &catalogv1.SearchProductsRequest{Keyword: "watch"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,229 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/health"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	alive := true
+	checker := health.NewChecker(func() bool { return alive })
+
+	db := newInMemoryRepository(t)
+	pinger, ok := db.(repository.Pinger)
+	require.True(t, ok)
+
+	var searchErr error
+	checker.AddReadinessCheck("database", pinger.Ping)
+	checker.AddReadinessCheck("search", func(ctx context.Context) error { return searchErr })
+	checker.RecordStartup("database", nil)
+	checker.RecordStartup("search", errors.New("connection refused"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", checker.Live)
+	r.GET("/readyz", checker.Ready)
+	r.GET("/startupz", checker.Startup)
+
+	get := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	t.Run("Before initialization finishes", func(t *testing.T) {
+		code, _ := get("/healthz")
+		assert.Equal(t, http.StatusOK, code)
+
+		code, report := get("/startupz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusDown, report.Status)
+
+		code, report = get("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusDown, report.Checks["startup"].Status)
+		assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+	})
+
+	checker.MarkStarted()
+
+	t.Run("Started", func(t *testing.T) {
+		code, report := get("/startupz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+		assert.Equal(t, health.StatusDown, report.Checks["search"].Status)
+		assert.Equal(t, "connection refused", report.Checks["search"].Error)
+
+		code, report = get("/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.NotContains(t, report.Checks, "startup")
+	})
+
+	t.Run("Dependency unavailable", func(t *testing.T) {
+		searchErr = errors.New("index catalog does not exist")
+		defer func() { searchErr = nil }()
+
+		code, report := get("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusDown, report.Checks["search"].Status)
+		assert.Equal(t, "index catalog does not exist", report.Checks["search"].Error)
+		assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+
+		code, _ = get("/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Not alive", func(t *testing.T) {
+		alive = false
+		defer func() { alive = true }()
+
+		for _, path := range []string{"/healthz", "/readyz", "/startupz"} {
+			code, report := get(path)
+			assert.Equal(t, http.StatusServiceUnavailable, code, path)
+			assert.Equal(t, health.StatusDown, report.Status, path)
+		}
+	})
+
+	t.Run("Shutting down", func(t *testing.T) {
+		checker.MarkStopping()
+
+		code, report := get("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusDown, report.Checks["shutdown"].Status)
+		assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+
+		code, _ = get("/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
+
+func TestHealthDetails(t *testing.T) {
+	var clusterRequests atomic.Int32
+	clusterStatus := "yellow"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case "/_cluster/health":
+			clusterRequests.Add(1)
+			fmt.Fprintf(w, `{"cluster_name": "catalog", "status": %q, "number_of_nodes": 1}`, clusterStatus)
+		case "/products/_count":
+			io.WriteString(w, `{"count": 42}`)
+		case "/_bulk":
+			io.WriteString(w, `{"errors": false, "items": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	db, ok := newInMemoryRepository(t).(repository.StatusReporter)
+	require.True(t, ok)
+
+	alive := true
+	checker := health.NewChecker(func() bool { return alive })
+	checker.AddDetails("database", db.Status)
+	checker.AddDetails("search", search.Status)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health/details", checker.Details)
+
+	get := func() (int, health.Report) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/health/details", nil)
+		r.ServeHTTP(w, req)
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	_, err = search.IndexProducts([]model.Product{{ID: "a1", Name: "Watch"}}, context.Background())
+	require.NoError(t, err)
+
+	code, report := get()
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Status)
+	require.NotNil(t, report.CheckedAt)
+
+	database := report.Checks["database"]
+	assert.Equal(t, health.StatusUp, database.Status)
+	primary := database.Details["primary"].(map[string]any)
+	assert.Contains(t, primary, "pingLatencyMs")
+	assert.Contains(t, primary, "openConnections")
+
+	details := report.Checks["search"].Details
+	assert.Equal(t, "yellow", details["clusterStatus"])
+	assert.EqualValues(t, 42, details["documents"])
+	assert.EqualValues(t, 1, details["nodes"])
+	assert.NotEmpty(t, details["lastSync"])
+
+	// The report is reused rather than checking the cluster again
+	clusterStatus = "red"
+	code, cached := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, report.CheckedAt, cached.CheckedAt)
+	assert.EqualValues(t, 1, clusterRequests.Load())
+
+	// Made unhealthy, the service reports it without waiting for the report to expire
+	alive = false
+	code, _ = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestOpenSearchStatusRed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case "/_cluster/health":
+			io.WriteString(w, `{"cluster_name": "catalog", "status": "red", "number_of_nodes": 1}`)
+		case "/products/_count":
+			io.WriteString(w, `{"count": 0}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	details, err := search.Status(context.Background())
+	assert.ErrorContains(t, err, "red")
+	assert.Equal(t, "red", details["clusterStatus"])
+	assert.NotContains(t, details, "lastSync")
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// newStragglingSearch connects to an OpenSearch server that leaves every
+// search for which slow returns true unanswered until it is abandoned
+func newStragglingSearch(t *testing.T, hedgeBudget float64, slow func(n int32) bool) (*repository.OpenSearchRepository, *atomic.Int32) {
+	var searches atomic.Int32
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		if slow(searches.Add(1)) {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+			return
+		}
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "a1", "_source": {"id": "a1"}}]}}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:    server.URL,
+		IndexName:   "products",
+		HedgeDelay:  50 * time.Millisecond,
+		HedgeBudget: hedgeBudget,
+	})
+	require.NoError(t, err)
+
+	return search, &searches
+}
+
+func TestHedgedSearch(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	ctx := context.Background()
+
+	t.Run("Hedge answers first", func(t *testing.T) {
+		// Every first copy straggles, every hedge answers
+		search, searches := newStragglingSearch(t, 0.1, func(n int32) bool { return n%2 == 1 })
+
+		started := time.Now()
+		result, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Len(t, result.Products, 1)
+		assert.Less(t, time.Since(started), time.Second)
+		assert.EqualValues(t, 2, searches.Load())
+	})
+
+	t.Run("Fast searches aren't hedged", func(t *testing.T) {
+		search, searches := newStragglingSearch(t, 0.1, func(int32) bool { return false })
+
+		for range 5 {
+			_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+			require.NoError(t, err)
+		}
+		assert.EqualValues(t, 5, searches.Load())
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		// Without any budget only the initial burst of hedges is sent, after
+		// which searches wait for their only copy
+		search, searches := newStragglingSearch(t, 0, func(n int32) bool { return n%2 == 1 })
+
+		for range 10 {
+			_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, ctx)
+			require.NoError(t, err)
+		}
+		assert.EqualValues(t, 20, searches.Load())
+
+		timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+
+		_, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 10, timeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualValues(t, 21, searches.Load())
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_search_hedges_total Searches slow enough to hedge, by whether a hedge was sent, skipped for lack of budget, or answered first
+# TYPE catalog_search_hedges_total counter
+catalog_search_hedges_total{result="sent"} 11
+catalog_search_hedges_total{result="skipped"} 1
+catalog_search_hedges_total{result="won"} 11
+`), "catalog_search_hedges_total"))
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	r := gin.New()
+	r.POST("/products", middleware.NewIdempotency(time.Minute).Middleware(), func(c *gin.Context) {
+		if c.Query("wait") != "" {
+			entered <- struct{}{}
+			<-release
+		}
+		n := calls.Add(1)
+		if c.Query("fail") != "" {
+			c.String(http.StatusInternalServerError, "failed")
+			return
+		}
+		c.Header("Location", "/products/"+string(rune('0'+n)))
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	postTo := func(path, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		return postTo("/products", key, body)
+	}
+
+	t.Run("Retry replays the response", func(t *testing.T) {
+		calls.Store(0)
+
+		first := post("key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		retry := post("key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+		assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+		assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+		assert.EqualValues(t, 1, calls.Load())
+
+		post("key-2", `{"name":"a"}`)
+		assert.EqualValues(t, 2, calls.Load())
+	})
+
+	t.Run("Without a key", func(t *testing.T) {
+		calls.Store(0)
+
+		post("", `{}`)
+		post("", `{}`)
+		assert.EqualValues(t, 2, calls.Load())
+	})
+
+	t.Run("Key reused with a different body", func(t *testing.T) {
+		post("key-3", `{"name":"a"}`)
+
+		w := post("key-3", `{"name":"b"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		calls.Store(0)
+
+		w := postTo("/products?fail=1", "key-4", `{}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		w = post("key-4", `{}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.EqualValues(t, 2, calls.Load())
+	})
+
+	t.Run("Retry while in progress", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- postTo("/products?wait=1", "key-5", `{}`) }()
+		<-entered
+
+		assert.Equal(t, http.StatusConflict, post("key-5", `{}`).Code)
+
+		close(release)
+		assert.Equal(t, http.StatusCreated, (<-done).Code)
+	})
+
+	t.Run("Key too long", func(t *testing.T) {
+		w := post(strings.Repeat("k", 256), `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
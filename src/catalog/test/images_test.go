@@ -0,0 +1,252 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/images"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// memoryImages keeps uploaded images in memory
+type memoryImages struct {
+	*images.EmbeddedStore
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (s *memoryImages) URL(key string) (string, error) {
+	if s.Has(key) {
+		return s.EmbeddedStore.URL(key)
+	}
+	return "https://cdn.example.com/" + key, nil
+}
+
+func (s *memoryImages) Put(key, contentType string, body io.ReadSeeker, ctx context.Context) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = contentType + ":" + string(data)
+	return nil
+}
+
+// pngImage starts like a PNG file, which is all content type detection reads
+var pngImage = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func imageUpload(t *testing.T, image []byte, alt string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	if image != nil {
+		part, err := form.CreateFormFile("image", "upload.bin")
+		require.NoError(t, err)
+		part.Write(image)
+	}
+	if alt != "" {
+		require.NoError(t, form.WriteField("alt", alt))
+	}
+	require.NoError(t, form.Close())
+	return body, form.FormDataContentType()
+}
+
+func TestImages(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.POST("/catalog/products/:id/images", c.UploadProductImage)
+	r.GET("/catalog/images/*key", c.GetImage)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	upload := func(id string, image []byte, alt string) *httptest.ResponseRecorder {
+		body, contentType := imageUpload(t, image, alt)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/catalog/products/"+id+"/images", body)
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	productImages := func(w *httptest.ResponseRecorder) []model.ProductImage {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product.Images
+	}
+
+	const tickstopper = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("Seeded images", func(t *testing.T) {
+		w := send("GET", "/catalog/products/"+tickstopper, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"images":[{"key":"products/`+tickstopper+`.jpg","url":"/catalog/images/products/`+tickstopper+`.jpg","alt":"Temporal Tickstopper"}]`)
+	})
+
+	t.Run("Serve bundled images", func(t *testing.T) {
+		w := send("GET", "/catalog/images/products/"+tickstopper+".jpg", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+		assert.NotZero(t, w.Body.Len())
+
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/images/products/missing.jpg", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/images/products", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/images/../products.json", "").Code)
+	})
+
+	t.Run("Bundled images can't be uploaded to", func(t *testing.T) {
+		w := upload(tickstopper, pngImage, "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code, w.Body.String())
+	})
+
+	t.Run("Images elsewhere", func(t *testing.T) {
+		images := productImages(send("PATCH", "/catalog/products/"+tickstopper, `{"images": [{"url": "https://images.example.com/tick.png", "alt": "Pocket watch"}]}`))
+		assert.Equal(t, []model.ProductImage{{URL: "https://images.example.com/tick.png", Alt: "Pocket watch"}}, images)
+
+		w := send("PATCH", "/catalog/products/"+tickstopper, `{"images": [{"key": "a.png", "url": "https://images.example.com/a.png"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		w = send("PATCH", "/catalog/products/"+tickstopper, `{"images": [{"alt": "Nothing"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		store := &memoryImages{EmbeddedStore: images.NewEmbeddedStore(), objects: map[string]string{}}
+		catalogAPI.SetImageStore(store)
+
+		images := productImages(upload(tickstopper, pngImage, "Watch face"))
+		require.Len(t, images, 2)
+		assert.Equal(t, "Pocket watch", images[0].Alt)
+		assert.True(t, strings.HasPrefix(images[1].Key, "products/"+tickstopper+"/"), images[1].Key)
+		assert.True(t, strings.HasSuffix(images[1].Key, ".png"), images[1].Key)
+		assert.Equal(t, "https://cdn.example.com/"+images[1].Key, images[1].URL)
+		assert.Equal(t, "Watch face", images[1].Alt)
+		assert.Equal(t, "image/png:"+string(pngImage), store.objects[images[1].Key])
+
+		// URLs are given when the product is read too, but not stored
+		images = productImages(send("GET", "/catalog/products/"+tickstopper, ""))
+		assert.Equal(t, "https://cdn.example.com/"+images[1].Key, images[1].URL)
+		product, err := db.GetProduct(tickstopper, context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, product.Images[1].URL)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, upload(tickstopper, []byte("<html>not an image</html>"), "").Code)
+		assert.Equal(t, http.StatusBadRequest, upload(tickstopper, nil, "No image").Code)
+		assert.Equal(t, http.StatusBadRequest, upload(tickstopper, pngImage, strings.Repeat("a", 256)).Code)
+		assert.Equal(t, http.StatusNotFound, upload("missing", pngImage, "").Code)
+		assert.Len(t, store.objects, 1, "nothing is uploaded for requests that fail")
+
+		c.SetMaxImageSize(16)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, upload(tickstopper, pngImage, "").Code)
+	})
+}
+
+func TestS3ImageStore(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads = append(uploads, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type")+" "+string(body))
+	}))
+	defer server.Close()
+
+	cfg := config.ImagesConfiguration{Store: "s3", S3Bucket: "images", S3Prefix: "catalog/", S3Endpoint: server.URL, PresignExpiry: time.Hour}
+	store, err := images.NewS3Store(cfg)
+	require.NoError(t, err)
+
+	t.Run("Upload", func(t *testing.T) {
+		require.NoError(t, store.Put("products/p1/a.png", "image/png", bytes.NewReader([]byte("png")), context.Background()))
+		assert.Equal(t, []string{"PUT /images/catalog/products/p1/a.png image/png png"}, uploads)
+	})
+
+	t.Run("Presigned URLs", func(t *testing.T) {
+		signed, err := store.URL("products/p1/a.png")
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "/images/catalog/products/p1/a.png", parsed.Path)
+		assert.Equal(t, "3600", parsed.Query().Get("X-Amz-Expires"))
+		assert.NotEmpty(t, parsed.Query().Get("X-Amz-Signature"))
+
+		again, err := store.URL("products/p1/a.png")
+		require.NoError(t, err)
+		assert.Equal(t, signed, again, "URLs are the same within a signing window")
+	})
+
+	t.Run("Bundled images", func(t *testing.T) {
+		bundled, err := store.URL("products/cc789f85-1476-452a-8100-9e74502198e0.jpg")
+		require.NoError(t, err)
+		assert.Equal(t, "/catalog/images/products/cc789f85-1476-452a-8100-9e74502198e0.jpg", bundled)
+
+		_, err = store.Open("products/p1/a.png")
+		assert.True(t, errors.Is(err, images.ErrNotServed))
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+		require.NoError(t, err)
+		catalogAPI.SetImageStore(store)
+		c, err := controller.NewController(catalogAPI)
+		require.NoError(t, err)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/catalog/images/*key", c.GetImage)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/images/products/p1/a.png", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), server.URL+"/images/catalog/products/p1/a.png?")
+	})
+
+	t.Run("CDN", func(t *testing.T) {
+		cfg.CDNURL = "https://cdn.example.com/"
+		store, err := images.NewS3Store(cfg)
+		require.NoError(t, err)
+
+		cdn, err := store.URL("products/p1/a.png")
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/catalog/products/p1/a.png", cdn)
+	})
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// flakyRepository fails writes of the product with the failID once
+type flakyRepository struct {
+	repository.WritableCatalogRepository
+	mu     sync.Mutex
+	failID string
+}
+
+func (r *flakyRepository) CreateProduct(product *model.Product, ctx context.Context) error {
+	r.mu.Lock()
+	fail := product.ID == r.failID
+	if fail {
+		r.failID = ""
+	}
+	r.mu.Unlock()
+
+	if fail {
+		return errors.New("connection reset")
+	}
+	return r.WritableCatalogRepository.CreateProduct(product, ctx)
+}
+
+func waitForImport(t *testing.T, catalogAPI *api.CatalogAPI, id string) model.ImportJob {
+	var job model.ImportJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = catalogAPI.GetImportJob(id)
+		require.NoError(t, err)
+		return job.Status != model.ImportQueued && job.Status != model.ImportRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestImportEndpoints(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	catalogAPI, err := api.NewCatalogAPI(db, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/imports", c.StartImport)
+	r.GET("/admin/imports/:jobId", c.GetImportJob)
+	r.POST("/admin/imports/:jobId/resume", c.ResumeImportJob)
+
+	post := func(url, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	start := func(contentType, body string) model.ImportJob {
+		w := post("/admin/imports", contentType, body)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+		var job model.ImportJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		assert.Equal(t, "/admin/imports/"+job.ID, w.Header().Get("Location"))
+		return waitForImport(t, catalogAPI, job.ID)
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		job := start("application/json", `[
+			{"id": "import-1", "name": "Imported", "price": 10, "tags": ["clothing"]},
+			{"id": "import-2", "name": "Unknown tag", "price": 10, "tags": ["nope"]}
+		]`)
+		assert.Equal(t, model.ImportCompleted, job.Status)
+		assert.Equal(t, 2, job.Processed)
+		assert.Equal(t, 1, job.Created)
+		require.Len(t, job.Errors, 1)
+		assert.Equal(t, 1, job.Errors[0].Index)
+		assert.Equal(t, "import-2", job.Errors[0].ProductID)
+
+		job = start("application/json", `[{"id": "import-1", "name": "Reimported", "price": 20}]`)
+		assert.Equal(t, 1, job.Updated)
+
+		product, err := db.GetProduct("import-1", ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Reimported", product.Name)
+		assert.Equal(t, 20, product.Price.Units())
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		job := start("text/csv", "id,name,description,price,currency,tags\nimport-3,From CSV,A product,1500,EUR,clothing|accessories\n")
+		assert.Equal(t, model.ImportCompleted, job.Status)
+		assert.Equal(t, 1, job.Created)
+
+		product, err := db.GetProduct("import-3", ctx)
+		require.NoError(t, err)
+		assert.Len(t, product.Tags, 2)
+		assert.Equal(t, model.Money{Amount: 1500, Currency: "EUR"}, product.Price)
+	})
+
+	t.Run("Invalid uploads", func(t *testing.T) {
+		for _, upload := range []struct{ contentType, body string }{
+			{"application/json", `{"id": "import-4"}`},
+			{"application/json", `[]`},
+			{"application/json", `[{"id": "import-4", "price": 1}]`},
+			{"application/json", `[{"id": "import-4", "name": "Name", "price": {"amount": 1, "currency": "dollars"}}]`},
+			{"text/csv", "id,name\nimport-4,Name\n"},
+			{"text/csv", "id,name,description,price,currency,tags\nimport-4,Name,,free,,\n"},
+		} {
+			assert.Equal(t, http.StatusBadRequest, post("/admin/imports", upload.contentType, upload.body).Code, upload.body)
+		}
+	})
+
+	t.Run("Unknown job", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/imports/missing", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		assert.Equal(t, http.StatusNotFound, post("/admin/imports/missing/resume", "", "").Code)
+	})
+}
+
+func TestResumeImport(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	flaky := &flakyRepository{WritableCatalogRepository: writable, failID: "resume-2"}
+	catalogAPI, err := api.NewCatalogAPI(flaky, nil)
+	require.NoError(t, err)
+
+	products := []model.Product{}
+	for _, id := range []string{"resume-1", "resume-2", "resume-3"} {
+		products = append(products, model.Product{ID: id, Name: id})
+	}
+
+	job, err := catalogAPI.StartImport(products, ctx)
+	require.NoError(t, err)
+
+	job = waitForImport(t, catalogAPI, job.ID)
+	assert.Equal(t, model.ImportFailed, job.Status)
+	assert.Equal(t, 1, job.Processed)
+	assert.Contains(t, job.Error, "resume-2")
+
+	_, err = writable.GetProduct("resume-3", ctx)
+	assert.Error(t, err)
+
+	job, err = catalogAPI.ResumeImport(job.ID, ctx)
+	require.NoError(t, err)
+
+	job = waitForImport(t, catalogAPI, job.ID)
+	assert.Equal(t, model.ImportCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 3, job.Created)
+	assert.Empty(t, job.Error)
+
+	for _, product := range products {
+		_, err := writable.GetProduct(product.ID, ctx)
+		assert.NoError(t, err)
+	}
+
+	_, err = catalogAPI.ResumeImport(job.ID, ctx)
+	assert.ErrorIs(t, err, api.ErrImportNotResumable)
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/events"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// memorySQS hands out the messages added to it and records those deleted.
+// Receiving from an empty queue waits for ctx to end.
+type memorySQS struct {
+	sqsiface.SQSAPI
+
+	mu       sync.Mutex
+	messages []*sqs.Message
+	input    *sqs.ReceiveMessageInput
+	deleted  []string
+}
+
+func (q *memorySQS) add(bodies ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, body := range bodies {
+		q.messages = append(q.messages, &sqs.Message{
+			MessageId:     aws.String(body),
+			ReceiptHandle: aws.String(body),
+			Body:          aws.String(body),
+		})
+	}
+}
+
+func (q *memorySQS) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	q.mu.Lock()
+	q.input = input
+	messages := q.messages
+	q.messages = nil
+	q.mu.Unlock()
+
+	if len(messages) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (q *memorySQS) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, entry := range input.Entries {
+		q.deleted = append(q.deleted, aws.StringValue(entry.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (q *memorySQS) deletedMessages() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]string{}, q.deleted...)
+}
+
+// indexedSearch is a search repository whose products can be updated one at a time
+type indexedSearch struct {
+	repository.SearchRepository
+	*fakeIndex
+}
+
+func TestIndexQueue(t *testing.T) {
+	db := newInMemoryRepository(t)
+	writable := db.(repository.WritableCatalogRepository)
+	index := newFakeIndex()
+	catalogAPI, err := api.NewCatalogAPI(db, indexedSearch{db.(repository.SearchRepository), index})
+	require.NoError(t, err)
+	defer catalogAPI.Shutdown(context.Background())
+	ctx := context.Background()
+
+	for _, id := range []string{"queue-tent", "queue-stove", "queue-lamp"} {
+		require.NoError(t, writable.CreateProduct(&model.Product{ID: id, Name: "Queued " + id, Price: model.Money{Amount: 10}}, ctx))
+		defer writable.DeleteProduct(id, ctx)
+	}
+	index.docs["queue-gone"] = model.Product{ID: "queue-gone"}
+
+	queue := &memorySQS{}
+	consumer := events.NewSQSConsumer(queue, "https://sqs.us-east-1.amazonaws.com/123456789012/catalog-index", catalogAPI, 10, 20*time.Second)
+
+	t.Run("Product changes", func(t *testing.T) {
+		queue.add(
+			`{"type": "product.changed", "productIds": ["queue-tent", "queue-stove", "queue-tent"]}`,
+			`{"type": "product.deleted", "productId": "queue-gone"}`,
+			`{"Type": "Notification", "TopicArn": "arn:aws:sns:us-east-1:123456789012:catalog", "Message": "{\"type\": \"product.updated\", \"productId\": \"queue-lamp\"}"}`,
+		)
+
+		applied, err := consumer.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, applied)
+		assert.Len(t, queue.deletedMessages(), 3)
+
+		assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123456789012/catalog-index", aws.StringValue(queue.input.QueueUrl))
+		assert.Equal(t, int64(10), aws.Int64Value(queue.input.MaxNumberOfMessages))
+		assert.Equal(t, int64(20), aws.Int64Value(queue.input.WaitTimeSeconds))
+
+		ids, err := index.IndexedProductIDs(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"queue-tent", "queue-stove", "queue-lamp"}, ids)
+		assert.Equal(t, "Queued queue-lamp", index.docs["queue-lamp"].Name)
+	})
+
+	t.Run("Deleted products", func(t *testing.T) {
+		require.NoError(t, writable.DeleteProduct("queue-stove", ctx))
+		queue.add(`{"type": "product.changed", "productId": "queue-stove"}`)
+
+		applied, err := consumer.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+		assert.NotContains(t, index.docs, "queue-stove")
+	})
+
+	t.Run("Failures are retried", func(t *testing.T) {
+		queue.deleted = nil
+		index.failing = true
+		queue.add(`{"type": "product.changed", "productId": "queue-tent"}`)
+
+		applied, err := consumer.Poll(ctx)
+		index.failing = false
+		require.NoError(t, err)
+		assert.Equal(t, 0, applied)
+		assert.Empty(t, queue.deletedMessages())
+	})
+
+	t.Run("Malformed messages are dropped", func(t *testing.T) {
+		queue.deleted = nil
+		queue.add(`not json`, `{"type": "product.renamed", "productId": "queue-tent"}`, `{"type": "product.changed"}`)
+
+		applied, err := consumer.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, applied)
+		assert.Len(t, queue.deletedMessages(), 3)
+	})
+
+	t.Run("Reindex requests", func(t *testing.T) {
+		queue.deleted = nil
+		queue.add(`{"type": "reindex.requested"}`, `{"type": "reindex.requested"}`)
+
+		// The second request finds the first reindex running, or finished
+		applied, err := consumer.Poll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, applied)
+		assert.Len(t, queue.deletedMessages(), 2)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		queue.deleted = nil
+		queue.add(`{"type": "product.changed", "productId": "queue-lamp"}`)
+
+		runCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			consumer.Run(runCtx)
+		}()
+
+		assert.Eventually(t, func() bool { return len(queue.deletedMessages()) == 1 }, 5*time.Second, 10*time.Millisecond)
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the consumer did not stop")
+		}
+	})
+}
+
+func TestIndexQueue_NotSupported(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+
+	err = catalogAPI.ApplyIndexMessage(model.IndexMessage{Type: model.IndexProductChanged, ProductID: "1"}, context.Background())
+	assert.ErrorIs(t, err, api.ErrIndexUpdatesNotSupported)
+}
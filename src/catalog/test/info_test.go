@@ -0,0 +1,57 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/buildinfo"
+	"github.com/aws-containers/retail-store-sample-app/catalog/features"
+)
+
+func TestInfo(t *testing.T) {
+	version, commit, date := buildinfo.Version, buildinfo.Commit, buildinfo.Date
+	t.Cleanup(func() {
+		buildinfo.Version, buildinfo.Commit, buildinfo.Date = version, commit, date
+	})
+	buildinfo.Version = "1.2.0"
+	buildinfo.Commit = "0123abc"
+	buildinfo.Date = "2026-01-02T03:04:05Z"
+
+	flags := features.New(map[string]bool{features.Chaos: false})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/info", buildinfo.NewHandler("mysql", "opensearch", flags))
+
+	get := func() buildinfo.Info {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/info", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var info buildinfo.Info
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+		return info
+	}
+
+	info := get()
+	assert.Equal(t, "1.2.0", info.Version)
+	assert.Equal(t, "0123abc", info.Commit)
+	assert.Equal(t, "2026-01-02T03:04:05Z", info.BuildDate)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, "mysql", info.PersistenceProvider)
+	assert.Equal(t, "opensearch", info.SearchProvider)
+	assert.Equal(t, false, info.Features[features.Chaos])
+	assert.Equal(t, true, info.Features[features.LiveSearch])
+
+	// Flags changed while running are reported as they are now
+	flags.SetRemote(map[string]bool{features.Chaos: true})
+	assert.Equal(t, true, get().Features[features.Chaos])
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+// newTestIssuer serves OpenID Connect discovery and a JWKS with one RSA key
+func newTestIssuer(t *testing.T) (*httptest.Server, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	return server, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTAuth(t *testing.T) {
+	issuer, key := newTestIssuer(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := middleware.NewAuth(middleware.NewJWTAuth(config.JWTConfiguration{
+		Issuer:     issuer.URL,
+		Audience:   "catalog",
+		WriteScope: "catalog/write",
+	}))
+
+	router.POST("/admin", auth.Require(middleware.PermissionWrite), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ClientIDKey))
+	})
+
+	request := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	claims := func(scope string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   issuer.URL,
+			"sub":   "user-1",
+			"aud":   "catalog",
+			"scope": scope,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	t.Run("Missing token", func(t *testing.T) {
+		w := request("")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Write scope", func(t *testing.T) {
+		w := request(signToken(t, key, claims("openid catalog/write")))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "sub:user-1", w.Body.String())
+	})
+
+	t.Run("Missing scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(signToken(t, key, claims("openid"))).Code)
+	})
+
+	t.Run("Cognito access token without audience", func(t *testing.T) {
+		c := claims("catalog/write")
+		delete(c, "aud")
+		c["client_id"] = "catalog"
+		assert.Equal(t, http.StatusOK, request(signToken(t, key, c)).Code)
+	})
+
+	t.Run("Invalid tokens", func(t *testing.T) {
+		wrongAudience := claims("catalog/write")
+		wrongAudience["aud"] = "other"
+		assert.Equal(t, http.StatusUnauthorized, request(signToken(t, key, wrongAudience)).Code)
+
+		wrongIssuer := claims("catalog/write")
+		wrongIssuer["iss"] = "https://example.com"
+		assert.Equal(t, http.StatusUnauthorized, request(signToken(t, key, wrongIssuer)).Code)
+
+		expired := claims("catalog/write")
+		expired["exp"] = time.Now().Add(-time.Hour).Unix()
+		assert.Equal(t, http.StatusUnauthorized, request(signToken(t, key, expired)).Code)
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, request(signToken(t, otherKey, claims("catalog/write"))).Code)
+	})
+}
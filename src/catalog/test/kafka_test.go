@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/events"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// memoryKafkaWriter keeps the messages written to it
+type memoryKafkaWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	closed   bool
+}
+
+func (w *memoryKafkaWriter) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func (w *memoryKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	encoder, err := events.NewEncoder(events.FormatJSON)
+	require.NoError(t, err)
+	writer := &memoryKafkaWriter{}
+	publisher := events.NewKafkaPublisher(writer, encoder)
+
+	broker := api.NewEventBroker()
+	broker.AddSink(publisher)
+	broker.Publish(model.CatalogEvent{Type: model.EventProductUpdated, ProductID: "kafka-1", Product: &model.Product{ID: "kafka-1", Name: "Kite"}})
+	broker.Publish(model.CatalogEvent{Type: model.EventReindexCompleted})
+
+	require.Len(t, writer.messages, 2)
+	message := writer.messages[0]
+	assert.Equal(t, []byte("kafka-1"), message.Key)
+	assert.Equal(t, []kafka.Header{{Key: "eventType", Value: []byte(model.EventProductUpdated)}}, message.Headers)
+	var event model.CatalogEvent
+	require.NoError(t, json.Unmarshal(message.Value, &event))
+	assert.Equal(t, uint64(1), event.ID)
+	assert.Equal(t, "Kite", event.Product.Name)
+	assert.False(t, event.Time.IsZero())
+
+	// Events that aren't about a product have no key
+	assert.Nil(t, writer.messages[1].Key)
+
+	require.NoError(t, publisher.Close())
+	assert.True(t, writer.closed)
+	var disabled *events.KafkaPublisher
+	assert.NoError(t, disabled.Close())
+}
+
+func TestEventEncoder_Avro(t *testing.T) {
+	encoder, err := events.NewEncoder(events.FormatAvro)
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal([]byte(encoder.Schema()), &schema))
+	assert.Equal(t, "CatalogEvent", schema["name"])
+
+	payload, err := encoder.Encode(model.CatalogEvent{ID: 3, Type: model.EventProductDeleted, ProductID: "p1", Time: time.UnixMilli(1000)})
+	require.NoError(t, err)
+	expected := append([]byte{0x06, 0x1e}, "product.deleted"...)
+	expected = append(append(expected, 0x02, 0x04), "p1"...)
+	expected = append(expected, 0x00, 0xd0, 0x0f)
+	assert.Equal(t, expected, payload)
+
+	payload, err = encoder.Encode(model.CatalogEvent{ID: 4, Type: model.EventProductCreated, ProductID: "p1", Product: &model.Product{ID: "p1"}, Time: time.UnixMilli(1000)})
+	require.NoError(t, err)
+	product, _ := json.Marshal(model.Product{ID: "p1"})
+	assert.Contains(t, string(payload), string(product))
+
+	_, err = events.NewEncoder("protobuf")
+	assert.Error(t, err)
+}
+
+func TestEventEncoder_SchemaRegistry(t *testing.T) {
+	var path, user, password, contentType string
+	var request map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, password, _ = r.BasicAuth()
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		request = nil
+		json.Unmarshal(body, &request)
+
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			io.WriteString(w, `{"id": 7}`)
+		} else {
+			io.WriteString(w, `{"error_code": 42201, "message": "Invalid schema"}`)
+		}
+	}))
+	defer server.Close()
+
+	encoder, err := events.NewEncoder(events.FormatJSON)
+	require.NoError(t, err)
+	registryURL := "http://key:secret@" + server.Listener.Addr().String()
+	require.NoError(t, encoder.Register(registryURL, "catalog-events-value", server.Client(), context.Background()))
+	assert.Equal(t, "/subjects/catalog-events-value/versions", path)
+	assert.Equal(t, "key", user)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "application/vnd.schemaregistry.v1+json", contentType)
+	assert.Equal(t, "JSON", request["schemaType"])
+	assert.JSONEq(t, encoder.Schema(), request["schema"])
+
+	// Payloads start with a zero byte and the schema ID
+	payload, err := encoder.Encode(model.CatalogEvent{ID: 1, Type: model.EventCatalogReset})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 7}, payload[:5])
+	var event model.CatalogEvent
+	require.NoError(t, json.Unmarshal(payload[5:], &event))
+	assert.Equal(t, model.EventCatalogReset, event.Type)
+
+	// Avro is the registry's default schema type
+	avro, err := events.NewEncoder(events.FormatAvro)
+	require.NoError(t, err)
+	require.NoError(t, avro.Register(server.URL, "catalog-events-value", server.Client(), context.Background()))
+	assert.NotContains(t, request, "schemaType")
+
+	status = http.StatusUnprocessableEntity
+	assert.ErrorContains(t, avro.Register(server.URL, "catalog-events-value", server.Client(), context.Background()), "Invalid schema")
+}
@@ -0,0 +1,234 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, model.CanTransition(model.StatusDraft, model.StatusActive))
+	assert.True(t, model.CanTransition(model.StatusActive, model.StatusDiscontinued))
+	assert.True(t, model.CanTransition(model.StatusDiscontinued, model.StatusActive))
+	assert.True(t, model.CanTransition(model.StatusDraft, model.StatusDraft))
+
+	assert.False(t, model.CanTransition(model.StatusActive, model.StatusDraft))
+	assert.False(t, model.CanTransition(model.StatusDiscontinued, model.StatusDraft))
+	assert.False(t, model.CanTransition(model.StatusDraft, model.StatusDiscontinued))
+}
+
+func TestProductStatus(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.GET("/catalog/products/:id/variants", c.GetProductVariants)
+	r.GET("/catalog/products/:id/reviews", c.GetReviews)
+	r.POST("/catalog/products/:id/reviews", c.AddReview)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/size", c.CatalogSize)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+	admin := r.Group("/admin", controller.APIVersion(2), controller.AllStatuses())
+	admin.GET("/products", c.GetProducts)
+	admin.GET("/products/:id", c.GetProduct)
+	admin.GET("/products/:id/variants", c.GetProductVariants)
+	admin.GET("/products/:id/reviews", c.GetReviews)
+	admin.GET("/search", c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	status := func(url string) string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product.Status
+	}
+	list := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		return productIDs(products)
+	}
+	search := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return productIDs(response.Products)
+	}
+	size := func() int {
+		w := send("GET", "/catalog/size", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response model.CatalogSizeResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Size
+	}
+
+	initial := size()
+
+	t.Run("Seeded products are active", func(t *testing.T) {
+		assert.Equal(t, model.StatusActive, status("/catalog/products/cc789f85-1476-452a-8100-9e74502198e0"))
+	})
+
+	t.Run("Drafts are hidden", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "status-lantern", "name": "Zephyrine Lantern", "status": "draft"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = send("POST", "/catalog/products", `{"id": "status-lamp", "name": "Zephyrine Lamp"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, model.StatusActive, status("/catalog/products/status-lamp"), "products are active by default")
+
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/status-lantern", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/status-lantern/variants", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/status-lantern/reviews", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/catalog/products/status-lantern/reviews", `{"rating": 5, "title": "Lovely", "author": "Sam"}`).Code)
+		assert.Equal(t, []string{"status-lamp"}, list("/catalog/products?ids=status-lantern,status-lamp"))
+		assert.NotContains(t, list("/catalog/products?size=100"), "status-lantern")
+		assert.Equal(t, []string{"status-lamp"}, search("/v2/catalog/search?keyword=zephyrine"))
+		assert.Equal(t, initial+1, size())
+
+		assert.Equal(t, model.StatusDraft, status("/admin/products/status-lantern"))
+		assert.Equal(t, http.StatusOK, send("GET", "/admin/products/status-lantern/variants", "").Code)
+		assert.Equal(t, http.StatusOK, send("GET", "/admin/products/status-lantern/reviews", "").Code)
+		assert.ElementsMatch(t, []string{"status-lantern", "status-lamp"}, search("/admin/search?keyword=zephyrine"))
+		assert.Equal(t, []string{"status-lantern"}, search("/admin/search?keyword=zephyrine&status=draft"))
+		assert.Equal(t, []string{"status-lantern"}, list("/admin/products?status=draft"))
+		assert.Contains(t, list("/admin/products?size=100"), "status-lantern")
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/products?status=archived", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/catalog/products", `{"name": "Archived", "status": "archived"}`).Code)
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		// Updates without a status keep it
+		w := send("PUT", "/catalog/products/status-lantern", `{"name": "Zephyrine Lantern", "description": "Warm light"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, model.StatusDraft, status("/admin/products/status-lantern"))
+
+		w = send("PATCH", "/catalog/products/status-lantern", `{"status": "discontinued"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("PATCH", "/catalog/products/status-lantern", `{"status": "active"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, model.StatusActive, status("/catalog/products/status-lantern"))
+		assert.ElementsMatch(t, []string{"status-lantern", "status-lamp"}, search("/v2/catalog/search?keyword=zephyrine"))
+
+		w = send("PUT", "/catalog/products/status-lantern", `{"name": "Zephyrine Lantern", "status": "draft"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("Discontinued products can be read but aren't listed", func(t *testing.T) {
+		w := send("PATCH", "/catalog/products/status-lamp", `{"status": "discontinued"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		assert.Equal(t, model.StatusDiscontinued, status("/catalog/products/status-lamp"))
+		assert.Equal(t, []string{"status-lantern"}, search("/v2/catalog/search?keyword=zephyrine"))
+		assert.NotContains(t, list("/catalog/products?size=100"), "status-lamp")
+		assert.Equal(t, []string{"status-lamp"}, list("/admin/products?status=discontinued"))
+		assert.ElementsMatch(t, []string{"status-lantern", "status-lamp"}, search("/admin/search?keyword=zephyrine&status=active,discontinued"))
+	})
+
+	for _, id := range []string{"status-lantern", "status-lamp"} {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/"+id, "").Code)
+	}
+}
+
+func TestOpenSearchStatuses(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 2}, "hits": [
+				{"_id": "p1", "_source": {"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}, "status": "draft"}},
+				{"_id": "p2", "_source": {"id": "p2", "name": "Old tee", "price": {"amount": 1500, "currency": "USD"}}}
+			]}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"type": "keyword"}`, string(index.Mappings.Properties["status"]))
+
+	// Documents indexed before products had a status are active
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "tee", Statuses: []string{model.StatusDraft}}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": {"terms": {"status": ["draft"]}}
+		}
+	}`, searchQueryOf(t, query))
+	require.Len(t, result.Products, 2)
+	assert.Equal(t, model.StatusDraft, result.Products[0].Status)
+	assert.Equal(t, model.StatusActive, result.Products[1].Status)
+
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "tee", Statuses: []string{model.StatusActive, model.StatusDiscontinued}}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": {"bool": {"must_not": {"terms": {"status": ["draft"]}}}}
+		}
+	}`, searchQueryOf(t, query))
+
+	// Searches of any status aren't filtered at all
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "tee", AnyStatus: true}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}
+	}`, searchQueryOf(t, query))
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func TestRequestLimitsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(middleware.NewRequestLimits(50*time.Millisecond, 16))
+	r.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			httputil.NewError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	r.GET("/query", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		httputil.NewError(c, http.StatusInternalServerError, c.Request.Context().Err())
+	})
+
+	unbounded := gin.New()
+	unbounded.Use(middleware.NewRequestLimits(0, 0))
+	unbounded.POST("/echo", func(c *gin.Context) {
+		_, hasDeadline := c.Request.Context().Deadline()
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"deadline": hasDeadline, "size": len(body)})
+	})
+
+	send := func(r *gin.Engine, method, path string, body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, body)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Small body", func(t *testing.T) {
+		w := send(r, "POST", "/echo", strings.NewReader("hello"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("Declared body too large", func(t *testing.T) {
+		w := send(r, "POST", "/echo", strings.NewReader(strings.Repeat("x", 17)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Streamed body too large", func(t *testing.T) {
+		// Without a Content-Length the limit applies while reading
+		w := send(r, "POST", "/echo", io.MultiReader(strings.NewReader(strings.Repeat("x", 17))))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		assert.Equal(t, http.StatusGatewayTimeout, send(r, "GET", "/slow", nil).Code)
+		assert.Equal(t, http.StatusGatewayTimeout, send(r, "GET", "/query", nil).Code)
+	})
+
+	t.Run("Unbounded", func(t *testing.T) {
+		w := send(unbounded, "POST", "/echo", strings.NewReader(strings.Repeat("x", 1024)))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Deadline bool `json:"deadline"`
+			Size     int  `json:"size"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.False(t, response.Deadline)
+		assert.Equal(t, 1024, response.Size)
+	})
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+)
+
+func TestListen(t *testing.T) {
+	t.Run("Addresses", func(t *testing.T) {
+		assert.Equal(t, []string{":8080"}, httputil.ListenAddresses(config.AppConfiguration{Port: 8080}))
+		assert.Equal(t, []string{"127.0.0.1:8080", "unix:/run/catalog.sock"}, httputil.ListenAddresses(config.AppConfiguration{
+			Port:   8080,
+			Server: config.ServerConfiguration{Addresses: []string{"127.0.0.1:8080", "unix:/run/catalog.sock"}},
+		}))
+	})
+
+	t.Run("TCP and Unix socket", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "catalog.sock")
+
+		// A socket left behind by a previous process is replaced
+		stale, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		listeners, err := httputil.Listen([]string{"127.0.0.1:0", "unix:" + socket}, config.ServerConfiguration{SocketMode: "0600"})
+		require.NoError(t, err)
+		require.Len(t, listeners, 2)
+
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "ok")
+		})}
+		for _, listener := range listeners {
+			go srv.Serve(listener)
+		}
+		defer srv.Close()
+
+		res, err := http.Get("http://" + listeners[0].Addr().String())
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return net.Dial("unix", socket)
+			},
+		}}
+		res, err = client.Get("http://catalog/")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "ok", string(body))
+
+		info, err := os.Stat(socket)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Other files are kept", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "catalog.sock")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+		_, err := httputil.Listen([]string{"unix:" + path}, config.ServerConfiguration{SocketMode: "0660"})
+		assert.Error(t, err)
+		assert.FileExists(t, path)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(map[string]string{}))
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		cfg.Server.Addresses = []string{"localhost", "unix:"}
+		cfg.Server.SocketMode = "rw"
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Len(t, validationErr.Problems, 3)
+	})
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestLiveSearch(t *testing.T) {
+	db := newInMemoryRepository(t)
+
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/search/live", c.LiveSearch)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/catalog/search/live", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	read := func(conn *websocket.Conn) model.LiveSearchResponse {
+		var response model.LiveSearchResponse
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&response))
+		return response
+	}
+
+	t.Run("Keystrokes are debounced", func(t *testing.T) {
+		conn := dial()
+		for _, query := range []string{"w", "wa", "wat", "watc", "watch"} {
+			require.NoError(t, conn.WriteJSON(model.LiveSearchRequest{Query: query, Size: 2}))
+		}
+
+		response := read(conn)
+		assert.Equal(t, "watch", response.Query)
+		assert.Empty(t, response.Error)
+		assert.NotEmpty(t, response.Products)
+		assert.LessOrEqual(t, len(response.Products), 2)
+
+		// No results are sent for the intermediate queries
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(500*time.Millisecond)))
+		_, _, err := conn.ReadMessage()
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid message", func(t *testing.T) {
+		conn := dial()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("watch")))
+		assert.NotEmpty(t, read(conn).Error)
+	})
+
+	t.Run("Empty query", func(t *testing.T) {
+		conn := dial()
+		require.NoError(t, conn.WriteJSON(model.LiveSearchRequest{Query: ""}))
+
+		response := read(conn)
+		assert.Empty(t, response.Error)
+		assert.Empty(t, response.Products)
+	})
+
+	t.Run("Plain HTTP requests", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/catalog/search/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestLiveSearchDisabled(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/catalog/search/live", c.LiveSearch)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/catalog/search/live", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/loadgen"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestLoadgen(t *testing.T) {
+	seeded, err := repository.LoadProductData()
+	require.NoError(t, err)
+	productIDs := map[string]bool{}
+	for _, product := range seeded {
+		productIDs[product.ID] = true
+	}
+
+	var mu sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+
+		if r.URL.Path == "/catalog/search" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	generator, err := loadgen.New(config.LoadgenConfiguration{
+		Target:   server.URL + "/",
+		RPS:      200,
+		Duration: time.Second,
+		Workers:  5,
+		Timeout:  time.Second,
+		APIKey:   "secret",
+	})
+	require.NoError(t, err)
+
+	summary := generator.Run(context.Background())
+	assert.InDelta(t, time.Second, summary.Elapsed, float64(100*time.Millisecond))
+	assert.Zero(t, summary.Dropped)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.InDelta(t, 200, len(requests), 20)
+
+	paths := map[string]int{}
+	keywords := map[string]int{}
+	for _, r := range requests {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		assert.True(t, strings.HasPrefix(r.UserAgent(), "catalog-loadgen/"))
+
+		path := r.URL.Path
+		if id, ok := strings.CutPrefix(path, "/catalog/products/"); ok {
+			assert.True(t, productIDs[id], "unknown product %s", id)
+			path = "/catalog/products/:id"
+		}
+		if path == "/catalog/search" {
+			keywords[r.URL.Query().Get("keyword")]++
+		}
+		paths[path]++
+	}
+
+	// Every kind of traffic is sent, in roughly the expected proportions
+	assert.InDelta(t, 80, paths["/catalog/search"], 35)
+	assert.InDelta(t, 50, paths["/catalog/products"], 30)
+	assert.InDelta(t, 60, paths["/catalog/products/:id"], 30)
+	assert.Positive(t, paths["/catalog/tags"]+paths["/catalog/categories"])
+
+	// Keywords come from the seed data, some far more often than others
+	assert.Greater(t, len(keywords), 5)
+	maxCount := 0
+	for _, count := range keywords {
+		maxCount = max(maxCount, count)
+	}
+	assert.Greater(t, maxCount, 1)
+
+	// Every request is counted, with searches failing
+	total := 0
+	for kind, stats := range summary.Kinds {
+		total += stats.Requests
+		if kind == "search" {
+			assert.Equal(t, stats.Requests, stats.Failed)
+			assert.Equal(t, stats.Requests, stats.Statuses[http.StatusServiceUnavailable])
+		} else {
+			assert.Zero(t, stats.Failed, kind)
+		}
+	}
+	assert.Equal(t, len(requests), total)
+	assert.NoError(t, summary.Err())
+}
+
+func TestLoadgenDropsRequests(t *testing.T) {
+	// A target that never answers keeps every worker busy
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	generator, err := loadgen.New(config.LoadgenConfiguration{
+		Target:   server.URL,
+		RPS:      100,
+		Duration: 500 * time.Millisecond,
+		Workers:  2,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+
+	summary := generator.Run(context.Background())
+	assert.Greater(t, summary.Dropped, 30)
+
+	// Requests abandoned when the run stopped aren't counted
+	for _, stats := range summary.Kinds {
+		assert.Zero(t, stats.Requests)
+	}
+}
+
+func TestLoadgenUnreachableTarget(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	generator, err := loadgen.New(config.LoadgenConfiguration{
+		Target:   server.URL,
+		RPS:      50,
+		Duration: 200 * time.Millisecond,
+		Workers:  5,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+
+	summary := generator.Run(context.Background())
+	assert.EqualError(t, summary.Err(), "every request failed")
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestGetProductsByIDs(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	all, err := db.GetProducts(repository.ProductFilter{}, "", 1, 3, ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	ids := []string{all[2].ID, "missing", all[0].ID, all[2].ID}
+
+	products, err := db.GetProductsByIDs(ids, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{all[2].ID, all[0].ID}, productIDs(products))
+	assert.Equal(t, all[0].Tags, products[1].Tags)
+
+	products, err = db.GetProductsByIDs(nil, ctx)
+	require.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestCatalogLookupProducts(t *testing.T) {
+	db := newInMemoryRepository(t)
+
+	all, err := db.GetProducts(repository.ProductFilter{}, "", 1, 2, context.Background())
+	require.NoError(t, err)
+
+	catalogAPI, err := api.NewCatalogAPI(db, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products/lookup", c.LookupProducts)
+
+	lookup := func(req *http.Request) (int, []model.Product) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var products []model.Product
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		}
+		return w.Code, products
+	}
+
+	expected := []string{all[1].ID, all[0].ID}
+
+	t.Run("Query parameter", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/catalog/products?ids="+all[1].ID+",missing,"+all[0].ID, nil)
+		code, products := lookup(req)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, productIDs(products))
+	})
+
+	t.Run("Request body", func(t *testing.T) {
+		body, _ := json.Marshal(model.ProductLookupRequest{IDs: expected})
+		req, _ := http.NewRequest("POST", "/catalog/products/lookup", bytes.NewReader(body))
+		code, products := lookup(req)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, productIDs(products))
+	})
+
+	t.Run("Too many IDs", func(t *testing.T) {
+		ids := make([]string, 101)
+		for i := range ids {
+			ids[i] = fmt.Sprint(i)
+		}
+
+		req, _ := http.NewRequest("GET", "/catalog/products?ids="+strings.Join(ids, ","), nil)
+		code, _ := lookup(req)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		body, _ := json.Marshal(model.ProductLookupRequest{IDs: ids})
+		req, _ = http.NewRequest("POST", "/catalog/products/lookup", bytes.NewReader(body))
+		code, _ = lookup(req)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
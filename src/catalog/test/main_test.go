@@ -86,7 +86,11 @@ func router() *gin.Engine {
 
 	catalog.GET("/size", c.CatalogSize)
 	catalog.GET("/tags", c.ListTags)
+	catalog.GET("/export", c.ExportProducts)
 	catalog.GET("/product/:id", c.GetProduct)
+	catalog.POST("/product", c.CreateProduct)
+	catalog.PUT("/product/:id", c.UpdateProduct)
+	catalog.DELETE("/product/:id", c.DeleteProduct)
 
 	return router
 }
@@ -0,0 +1,228 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := middleware.NewRequestMetrics([]float64{0.5, 1}, registry)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(metrics)
+	r.GET("/products/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/products/a1", "/products/b2", "/products/missing", "/unknown"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_http_requests_total HTTP requests handled, by route and status code
+# TYPE catalog_http_requests_total counter
+catalog_http_requests_total{method="GET",route="/products/:id",status="200"} 2
+catalog_http_requests_total{method="GET",route="/products/:id",status="404"} 1
+catalog_http_requests_total{method="GET",route="unmatched",status="404"} 1
+`), "catalog_http_requests_total"))
+
+	// One series per route, with the configured buckets
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "catalog_http_request_duration_seconds"))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "catalog_http_request_duration_seconds" {
+			assert.Len(t, family.GetMetric()[0].GetHistogram().GetBucket(), 2)
+		}
+	}
+
+	_, err = middleware.NewRequestMetrics(nil, registry)
+	assert.Error(t, err, "metrics can only be registered once")
+}
+
+func TestRepositoryMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	db := newInMemoryRepository(t)
+	_, err := db.GetProduct("missing", context.Background())
+	require.Error(t, err)
+
+	count := func(operation, outcome string) uint64 {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				labels := map[string]string{}
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				if labels["repository"] == "database" && labels["operation"] == operation && labels["outcome"] == outcome {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+
+	// A missing product is an answer rather than a failure
+	assert.NotZero(t, count("query", "success"))
+	assert.Zero(t, count("query", "error"))
+}
+
+func TestMetricsConfiguration(t *testing.T) {
+	load := func(env map[string]string) config.AppConfiguration {
+		cfg, err := config.LoadWith(context.Background(), envconfig.MapLookuper(env))
+		require.NoError(t, err)
+		return cfg
+	}
+
+	cfg := load(map[string]string{"RETAIL_CATALOG_METRICS_REQUEST_BUCKETS": "0.01,0.1,1"})
+	assert.Equal(t, []float64{0.01, 0.1, 1}, cfg.Metrics.RequestBuckets)
+	assert.Empty(t, cfg.Metrics.RepositoryBuckets)
+	assert.NoError(t, cfg.Validate())
+
+	err := load(map[string]string{"RETAIL_CATALOG_METRICS_REPOSITORY_BUCKETS": "1,0.5"}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "RETAIL_CATALOG_METRICS_REPOSITORY_BUCKETS")
+}
+
+func TestSearchMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "nothing") {
+			io.WriteString(w, `{"took": 4, "hits": {"total": {"value": 0}, "hits": []}}`)
+			return
+		}
+		io.WriteString(w, `{"took": 12, "hits": {"total": {"value": 7}, "hits": [
+			{"_id": "a1", "_source": {"id": "a1"}}
+		]}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, keyword := range []string{"watch", "nothing", "nothing"} {
+		_, err := search.SearchProducts(repository.SearchQuery{Keyword: keyword}, 1, 1, ctx)
+		require.NoError(t, err)
+	}
+
+	// Explaining a search isn't counted as one
+	_, err = search.ExplainSearch("watch", 1, ctx)
+	require.NoError(t, err)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_search_requests_total Keyword searches answered by OpenSearch
+# TYPE catalog_search_requests_total counter
+catalog_search_requests_total{filtered="false",fuzzy="true"} 3
+# HELP catalog_search_zero_results_total Keyword searches that matched no products
+# TYPE catalog_search_zero_results_total counter
+catalog_search_zero_results_total{filtered="false",fuzzy="true"} 2
+`), "catalog_search_requests_total", "catalog_search_zero_results_total"))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		switch family.GetName() {
+		case "catalog_search_hits":
+			assert.EqualValues(t, 7, family.GetMetric()[0].GetHistogram().GetSampleSum())
+		case "catalog_search_took_seconds":
+			assert.InDelta(t, 0.02, family.GetMetric()[0].GetHistogram().GetSampleSum(), 1e-9)
+		}
+	}
+}
+
+func TestMetricExemplars(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+		io.WriteString(w, `{"took": 3, "hits": {"total": {"value": 0}, "hits": []}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	_, err = newInMemoryRepository(t).GetProduct("missing", ctx)
+	require.Error(t, err)
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 1, ctx)
+	require.NoError(t, err)
+
+	// Observations made without a sampled trace don't replace the exemplars
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 1, context.Background())
+	require.NoError(t, err)
+
+	exemplars := func(name string) []string {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		var traceIDs []string
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				for _, bucket := range metric.GetHistogram().GetBucket() {
+					for _, label := range bucket.GetExemplar().GetLabel() {
+						traceIDs = append(traceIDs, label.GetName()+"="+label.GetValue())
+					}
+				}
+			}
+		}
+		return traceIDs
+	}
+
+	traceID := "trace_id=" + span.SpanContext().TraceID().String()
+	assert.Contains(t, exemplars("catalog_repository_operation_duration_seconds"), traceID)
+	assert.Equal(t, []string{traceID}, exemplars("catalog_search_took_seconds"))
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("Minor units", func(t *testing.T) {
+		assert.Equal(t, model.Money{Amount: 500, Currency: "USD"}, model.MoneyFromUnits(5, "USD"))
+		assert.Equal(t, model.Money{Amount: 5, Currency: "JPY"}, model.MoneyFromUnits(5, "JPY"))
+		assert.Equal(t, model.Money{Amount: 5000, Currency: "KWD"}, model.MoneyFromUnits(5, "KWD"))
+		assert.Equal(t, 19, model.Money{Amount: 1999, Currency: "USD"}.Units())
+		assert.Equal(t, 19, model.Money{Amount: 1999}.Units(), "without a currency the amount is in the default one")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var product model.Product
+		require.NoError(t, json.Unmarshal([]byte(`{"price": {"amount": 1250, "currency": "EUR"}}`), &product))
+		assert.Equal(t, model.Money{Amount: 1250, Currency: "EUR"}, product.Price)
+
+		data, err := json.Marshal(product)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"price":{"amount":1250,"currency":"EUR"}`)
+
+		// Prices written before they had a currency are whole units
+		require.NoError(t, json.Unmarshal([]byte(`{"price": 12}`), &product))
+		assert.Equal(t, model.MoneyFromUnits(12, model.DefaultCurrency), product.Price)
+
+		assert.Error(t, json.Unmarshal([]byte(`{"price": "12.50"}`), &product))
+	})
+}
+
+func TestPriceVersions(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	for version, prefix := range map[int]string{1: "/v1/catalog", 2: "/v2/catalog"} {
+		group := r.Group(prefix, controller.APIVersion(version))
+		group.GET("/products", c.GetProducts)
+		group.GET("/products/:id", c.GetProduct)
+	}
+
+	get := func(url string, body any) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), body))
+	}
+
+	const productID = "cc789f85-1476-452a-8100-9e74502198e0"
+
+	t.Run("v1 prices are whole units", func(t *testing.T) {
+		var product map[string]any
+		get("/v1/catalog/products/"+productID, &product)
+		assert.EqualValues(t, 250, product["price"])
+
+		var products []model.Product
+		get("/v1/catalog/products?minPrice=250&maxPrice=250", &products)
+		assert.Equal(t, []string{productID}, productIDs(products))
+	})
+
+	t.Run("v2 prices have a currency", func(t *testing.T) {
+		var product map[string]any
+		get("/v2/catalog/products/"+productID, &product)
+		assert.Equal(t, map[string]any{"amount": 25000.0, "currency": "USD"}, product["price"])
+
+		var products []model.Product
+		get("/v2/catalog/products?minPrice=25000&maxPrice=25000&fields=id,price", &products)
+		require.Equal(t, []string{productID}, productIDs(products))
+		assert.Equal(t, model.Money{Amount: 25000, Currency: "USD"}, products[0].Price)
+	})
+}
@@ -0,0 +1,150 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	catalogv1 "github.com/aws-containers/retail-store-sample-app/catalog/gen/catalog/v1"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+func TestContentNegotiation(t *testing.T) {
+	db := newInMemoryRepository(t)
+
+	catalogAPI, err := api.NewCatalogAPI(db, &stubSearch{ids: []string{"a1", "b2"}})
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.GET("/catalog/search", c.SearchProducts)
+	r.GET("/v2/catalog/search", c.SearchProductsV2)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/catalog/products?size=1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var products []model.Product
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+	require.Len(t, products, 1)
+	first := products[0]
+
+	t.Run("JSON by default", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "application/json", "text/html"} {
+			w := get("/catalog/products/"+first.ID, accept)
+			require.Equal(t, http.StatusOK, w.Code, accept)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json", accept)
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+		}
+	})
+
+	t.Run("Protobuf product", func(t *testing.T) {
+		w := get("/catalog/products/"+first.ID, "application/x-protobuf")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, controller.MIMEProtobuf, w.Header().Get("Content-Type"))
+
+		var product catalogv1.Product
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, first.ID, product.GetId())
+		assert.Equal(t, first.Name, product.GetName())
+		assert.EqualValues(t, first.Price.Units(), product.GetPrice())
+		assert.EqualValues(t, first.Price.Amount, product.GetPriceMoney().GetAmount())
+		assert.Equal(t, first.Price.Currency, product.GetPriceMoney().GetCurrency())
+		assert.Len(t, product.GetTags(), len(first.Tags))
+	})
+
+	t.Run("XML product", func(t *testing.T) {
+		w := get("/catalog/products/"+first.ID, "application/xml")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+		assert.Contains(t, w.Body.String(), `<product id="`+first.ID+`"`)
+
+		var product model.Product
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, first.ID, product.ID)
+		assert.Equal(t, first.Name, product.Name)
+		assert.Equal(t, first.Price, product.Price)
+		require.Len(t, product.Tags, len(first.Tags))
+		if len(first.Tags) > 0 {
+			assert.Equal(t, first.Tags[0].Name, product.Tags[0].Name)
+		}
+	})
+
+	t.Run("Product list", func(t *testing.T) {
+		w := get("/catalog/products?size=2", "application/x-protobuf")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list catalogv1.ListProductsResponse
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &list))
+		assert.Len(t, list.GetProducts(), 2)
+		assert.NotEmpty(t, list.GetNextPageToken())
+
+		w = get("/catalog/products?size=2", "text/xml")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var products model.ProductList
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &products))
+		assert.Len(t, products.Products, 2)
+		assert.Equal(t, list.GetNextPageToken(), products.NextCursor)
+	})
+
+	t.Run("ETag per representation", func(t *testing.T) {
+		jsonETag := get("/catalog/products/"+first.ID, "").Header().Get("ETag")
+		protoETag := get("/catalog/products/"+first.ID, "application/x-protobuf").Header().Get("ETag")
+		assert.NotEqual(t, jsonETag, protoETag)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/catalog/products/"+first.ID, nil)
+		req.Header.Set("Accept", "application/x-protobuf")
+		req.Header.Set("If-None-Match", protoETag)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		w := get("/catalog/search?keyword=watch", "application/x-protobuf")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var results catalogv1.SearchProductsResponse
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &results))
+		require.Len(t, results.GetProducts(), 2)
+		assert.Equal(t, "a1", results.GetProducts()[0].GetId())
+		assert.Equal(t, "b2", results.GetProducts()[1].GetId())
+
+		w = get("/v2/catalog/search?keyword=watch", "application/xml")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response model.SearchResponse
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []string{"a1", "b2"}, productIDs(response.Products))
+		assert.Equal(t, 1, response.Page)
+		assert.Equal(t, 10, response.Size)
+	})
+
+	t.Run("Sparse fieldsets are JSON only", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotAcceptable, get("/catalog/products/"+first.ID+"?fields=name", "application/xml").Code)
+		assert.Equal(t, http.StatusOK, get("/catalog/products/"+first.ID+"?fields=name", "application/json").Code)
+	})
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestNewArrivals(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/new-arrivals", c.GetNewArrivals)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	list := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		return productIDs(products)
+	}
+
+	created := map[string]model.Product{}
+	for _, body := range []string{
+		`{"id": "arrival-kite", "name": "Zeppelin kite", "price": {"amount": 2000, "currency": "USD"}}`,
+		`{"id": "arrival-model", "name": "Zeppelin model", "price": {"amount": 5000, "currency": "USD"}}`,
+		`{"id": "arrival-poster", "name": "Zeppelin poster", "price": {"amount": 1000, "currency": "USD"}}`,
+	} {
+		w := send("POST", "/catalog/products", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		created[product.ID] = product
+	}
+	defer func() {
+		for id := range created {
+			send("DELETE", "/catalog/products/"+id, "")
+		}
+	}()
+	newest := []string{"arrival-poster", "arrival-model", "arrival-kite"}
+
+	t.Run("Timestamps", func(t *testing.T) {
+		kite := created["arrival-kite"]
+		assert.False(t, kite.CreatedAt.IsZero())
+		assert.True(t, kite.CreatedAt.Equal(kite.UpdatedAt))
+
+		w := send("PATCH", "/catalog/products/arrival-kite", `{"description": "Flies"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var patched model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &patched))
+		assert.True(t, kite.CreatedAt.Equal(patched.CreatedAt), "updates keep the creation time")
+		assert.True(t, patched.UpdatedAt.After(kite.UpdatedAt))
+
+		stored, err := db.GetProduct("arrival-kite", context.Background())
+		require.NoError(t, err)
+		assert.True(t, patched.UpdatedAt.Equal(stored.UpdatedAt))
+	})
+
+	t.Run("Sort by newest", func(t *testing.T) {
+		assert.Equal(t, newest, list("/catalog/products?sort=newest&size=3"))
+
+		// A cursor continues after the last product
+		w := send("GET", "/catalog/products?sort=newest&size=2", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		match := regexp.MustCompile(`^<(.+)>; rel="next"$`).FindStringSubmatch(w.Header().Get("Link"))
+		require.NotNil(t, match)
+		assert.Equal(t, newest[2], list(match[1])[0])
+
+		w = send("GET", "/v2/catalog/search?keyword=zeppelin&sort=newest", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, newest, productIDs(response.Products))
+	})
+
+	t.Run("New arrivals", func(t *testing.T) {
+		assert.Equal(t, newest, list("/catalog/new-arrivals?size=3"))
+		assert.Equal(t, []string{"arrival-poster", "arrival-kite"}, list("/catalog/new-arrivals?size=2&maxPrice=20"))
+
+		products, err := catalogAPI.GetNewArrivals(repository.ProductFilter{}, created["arrival-model"].CreatedAt, 10, context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, newest[:2], productIDs(products))
+
+		products, err = catalogAPI.GetNewArrivals(repository.ProductFilter{}, time.Now().Add(time.Minute), 10, context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, products)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/new-arrivals?days=0", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/new-arrivals?days=366", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/new-arrivals?size=101", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products?sort=oldest", "").Code)
+	})
+}
+
+func TestDualWrite_UpdatedAt(t *testing.T) {
+	index := &partialIndex{fakeIndex: newFakeIndex()}
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	repo := repository.NewDualWriteRepository(writable, index)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateProduct(&model.Product{ID: "dual-write-updated", Name: "Test", Price: model.Money{Amount: 10}}, ctx))
+	defer repo.DeleteProduct("dual-write-updated", ctx)
+
+	product, err := repo.PatchProduct("dual-write-updated", func(product *model.Product) error {
+		product.Description = "Redescribed"
+		return nil
+	}, ctx)
+	require.NoError(t, err)
+	require.Len(t, index.updates, 1)
+	assert.Equal(t, []string{"description", "updatedAt"}, index.updates[0])
+	assert.True(t, product.UpdatedAt.Equal(index.docs["dual-write-updated"].UpdatedAt))
+}
+
+func TestOpenSearchTimestamps(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "sort": [1767225600000, 1.2, "p1"], "_source": {
+				"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}, "createdAt": "2026-01-01T00:00:00Z", "updatedAt": "2026-02-01T00:00:00Z"
+			}}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"type": "date"}`, string(index.Mappings.Properties["createdAt"]))
+	assert.JSONEq(t, `{"type": "date"}`, string(index.Mappings.Properties["updatedAt"]))
+
+	result, _, err := search.SearchProductsAfter(repository.SearchQuery{Keyword: "tee", Order: repository.OrderNewest}, nil, 10, context.Background())
+	require.NoError(t, err)
+
+	var request struct {
+		Sort json.RawMessage `json:"sort"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(query), &request))
+	assert.JSONEq(t, `[{"createdAt": {"order": "desc", "missing": "_last"}}, {"_score": "desc"}, {"id": "asc"}]`, string(request.Sort))
+
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), result.Products[0].CreatedAt)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), result.Products[0].UpdatedAt)
+}
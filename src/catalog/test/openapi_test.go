@@ -0,0 +1,66 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/openapi"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.GET("/catalog/search", c.SearchProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/undocumented/:name", func(ctx *gin.Context) {})
+
+	spec := openapi.New(openapi.Info{Title: "Catalog API", Version: "1.0"})
+	c.Describe(spec)
+	r.GET("/openapi.json", spec.Handler(r))
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+
+	assert.Equal(t, "3.0.1", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	assert.Len(t, paths, 4)
+	assert.NotContains(t, paths, "/openapi.json")
+
+	search := paths["/catalog/search"].(map[string]any)["get"].(map[string]any)
+	assert.Equal(t, "searchProducts", search["operationId"])
+	keyword := search["parameters"].([]any)[0].(map[string]any)
+	assert.Equal(t, "keyword", keyword["name"])
+	assert.Equal(t, true, keyword["required"])
+
+	// Routes without a description still get their path parameters
+	undocumented := paths["/undocumented/{name}"].(map[string]any)["get"].(map[string]any)
+	assert.Equal(t, "name", undocumented["parameters"].([]any)[0].(map[string]any)["name"])
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	assert.Contains(t, schemas, "model.Product")
+	assert.Contains(t, schemas, "model.Tag")
+	assert.Contains(t, schemas, "httputil.HTTPError")
+
+	request := schemas["model.ProductRequest"].(map[string]any)
+	assert.Equal(t, []any{"name"}, request["required"])
+}
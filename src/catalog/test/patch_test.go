@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// partialIndex is a fake index that records partial updates
+type partialIndex struct {
+	*fakeIndex
+	updates [][]string
+}
+
+func (p *partialIndex) UpdateProductFields(product model.Product, fields []string, ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.updates = append(p.updates, fields)
+	p.docs[product.ID] = product
+	return nil
+}
+
+func TestPatchProduct(t *testing.T) {
+	index := &partialIndex{fakeIndex: newFakeIndex()}
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	repo := repository.NewDualWriteRepository(writable, index)
+	ctx := context.Background()
+
+	category := "eyewear"
+	require.NoError(t, repo.CreateProduct(&model.Product{
+		ID:           "patch-1",
+		Name:         "Original",
+		Description:  "Described",
+		Price:        model.Money{Amount: 10},
+		Tags:         []model.Tag{{Name: "clothing"}},
+		CategoryName: &category,
+	}, ctx))
+	defer repo.DeleteProduct("patch-1", ctx)
+
+	catalogAPI, err := api.NewCatalogAPI(repo, nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+
+	patch := func(id, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", "/catalog/products/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		w := patch("patch-1", "application/merge-patch+json", `{"price": 5, "description": null, "category": null}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "Original", product.Name)
+		assert.Equal(t, 5, product.Price.Units())
+		assert.Empty(t, product.Description)
+		assert.Nil(t, product.Category)
+
+		stored, err := repo.GetProduct("patch-1", ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 5, stored.Price.Units())
+		assert.Equal(t, []string{"clothing"}, tagNames(stored.Tags))
+
+		require.Len(t, index.updates, 1)
+		assert.ElementsMatch(t, []string{"price", "description", "category", "updatedAt"}, index.updates[0])
+	})
+
+	t.Run("Replace array", func(t *testing.T) {
+		w := patch("patch-1", "application/json", `{"tags": ["accessories", "clothing"]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		stored, err := repo.GetProduct("patch-1", ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"accessories", "clothing"}, tagNames(stored.Tags))
+		assert.Equal(t, []string{"tags", "updatedAt"}, index.updates[len(index.updates)-1])
+	})
+
+	t.Run("No change", func(t *testing.T) {
+		updates := len(index.updates)
+		w := patch("patch-1", "application/json", `{"name": "Original"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, index.updates, updates)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		tests := []struct {
+			id, contentType, body string
+			expected              int
+		}{
+			{"patch-1", "application/json", `{"name": null}`, http.StatusBadRequest},
+			{"patch-1", "application/json", `{"price": -1}`, http.StatusBadRequest},
+			{"patch-1", "application/json", `{"price": "free"}`, http.StatusBadRequest},
+			{"patch-1", "application/json", `{"id": "other"}`, http.StatusBadRequest},
+			{"patch-1", "application/json", `{"colour": "red"}`, http.StatusBadRequest},
+			{"patch-1", "application/json", `[{"op": "replace"}]`, http.StatusBadRequest},
+			{"patch-1", "application/json", `{"tags": ["missing"]}`, http.StatusBadRequest},
+			{"patch-1", "text/plain", `{"price": 1}`, http.StatusUnsupportedMediaType},
+			{"missing", "application/json", `{"price": 1}`, http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			assert.Equal(t, tt.expected, patch(tt.id, tt.contentType, tt.body).Code, tt.body)
+		}
+
+		stored, err := repo.GetProduct("patch-1", ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Original", stored.Name)
+		assert.Equal(t, 5, stored.Price.Units())
+	})
+}
+
+func tagNames(tags []model.Tag) []string {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestPriceHistory(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/products/:id/price-history", c.GetPriceHistory)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	history := func(url string) model.PriceHistoryResponse {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.PriceHistoryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	amounts := func(changes []model.PriceChange) []int {
+		amounts := []int{}
+		for _, change := range changes {
+			amounts = append(amounts, change.Price.Amount)
+		}
+		return amounts
+	}
+
+	t.Run("Seeded products", func(t *testing.T) {
+		response := history("/catalog/products/cc789f85-1476-452a-8100-9e74502198e0/price-history")
+		require.Len(t, response.Changes, 1)
+		assert.Equal(t, response.Price, response.Changes[0].Price)
+		assert.False(t, response.Changes[0].ChangedAt.IsZero())
+		assert.Nil(t, response.PreviousPrice)
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "price-tee", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = send("PATCH", "/catalog/products/price-tee", `{"price": {"amount": 1200, "currency": "USD"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// Updates that keep the price don't change it
+		w = send("PATCH", "/catalog/products/price-tee", `{"name": "T-shirt"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = send("PUT", "/catalog/products/price-tee", `{"name": "Tee", "price": {"amount": 1200, "currency": "USD"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		response := history("/catalog/products/price-tee/price-history")
+		assert.Equal(t, "price-tee", response.ProductID)
+		assert.Equal(t, model.Money{Amount: 1200, Currency: "USD"}, response.Price)
+		assert.Equal(t, &model.Money{Amount: 1500, Currency: "USD"}, response.PreviousPrice)
+		assert.Equal(t, []int{1200, 1500}, amounts(response.Changes))
+		assert.False(t, response.Changes[0].ChangedAt.Before(response.Changes[1].ChangedAt))
+
+		w = send("PUT", "/catalog/products/price-tee", `{"name": "Tee", "price": {"amount": 1000, "currency": "USD"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		response = history("/catalog/products/price-tee/price-history?limit=1")
+		assert.Equal(t, []int{1000}, amounts(response.Changes))
+		assert.Equal(t, &model.Money{Amount: 1200, Currency: "USD"}, response.PreviousPrice, "the previous price is given whatever the limit")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/missing/price-history", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/price-tee/price-history?limit=0", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/price-tee/price-history?limit=101", "").Code)
+
+		w := send("POST", "/catalog/products", `{"id": "price-draft", "name": "Draft", "status": "draft"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/price-draft/price-history", "").Code)
+	})
+
+	t.Run("Deleted products lose their history", func(t *testing.T) {
+		for _, id := range []string{"price-tee", "price-draft"} {
+			require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/"+id, "").Code)
+		}
+
+		changes, err := db.(repository.PriceHistoryRepository).GetPriceHistory("price-tee", 10, context.Background())
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+		assert.Empty(t, changes)
+
+		w := send("POST", "/catalog/products", `{"id": "price-tee", "name": "Tee", "price": {"amount": 900, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, []int{900}, amounts(history("/catalog/products/price-tee/price-history").Changes))
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/price-tee", "").Code)
+	})
+}
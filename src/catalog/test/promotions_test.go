@@ -0,0 +1,242 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestPromotion(t *testing.T) {
+	now := time.Now()
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+
+	assert.Equal(t, model.Money{Amount: 1600, Currency: "USD"}, model.Promotion{PercentOff: 20}.Discount(model.Money{Amount: 1999, Currency: "USD"}))
+	assert.Equal(t, model.Money{Amount: 700, Currency: "USD"}, model.Promotion{AmountOff: 300}.Discount(model.Money{Amount: 1000, Currency: "USD"}))
+	assert.Equal(t, model.Money{Amount: 0, Currency: "USD"}, model.Promotion{AmountOff: 3000}.Discount(model.Money{Amount: 1000, Currency: "USD"}))
+
+	assert.True(t, model.Promotion{}.Active(now))
+	assert.True(t, model.Promotion{StartsAt: &earlier, EndsAt: &later}.Active(now))
+	assert.False(t, model.Promotion{StartsAt: &later}.Active(now))
+	assert.False(t, model.Promotion{EndsAt: &earlier}.Active(now))
+
+	product := model.Product{ID: "tee", Tags: []model.Tag{{Name: "clothing"}}}
+	assert.True(t, model.Promotion{ProductIDs: []string{"tee"}}.AppliesTo(product))
+	assert.True(t, model.Promotion{Tags: []string{"food", "clothing"}}.AppliesTo(product))
+	assert.False(t, model.Promotion{ProductIDs: []string{"hat"}, Tags: []string{"food"}}.AppliesTo(product))
+}
+
+func TestPromotions(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+	c.SetExchangeRates(currency.New(map[string]float64{"EUR": 0.5}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/promotions", c.GetPromotions)
+	r.POST("/catalog/promotions", c.CreatePromotion)
+	r.DELETE("/catalog/promotions/:id", c.DeletePromotion)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	product := func(url string) model.Product {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product
+	}
+	search := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return productIDs(response.Products)
+	}
+
+	for _, body := range []string{
+		`{"id": "promo-pop", "name": "Zorbly Pop", "price": {"amount": 2000, "currency": "USD"}, "tags": ["food"]}`,
+		`{"id": "promo-gum", "name": "Zorbly Gum", "price": {"amount": 1000, "currency": "USD"}}`,
+		`{"id": "promo-hat", "name": "Zorbly Hat", "price": {"amount": 3000, "currency": "USD"}}`,
+	} {
+		w := send("POST", "/catalog/products", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	hour := func(hours int) string {
+		return time.Now().Add(time.Duration(hours) * time.Hour).UTC().Format(time.RFC3339)
+	}
+	for _, body := range []string{
+		`{"id": "promo-food", "name": "Food", "percentOff": 25, "tags": ["food"]}`,
+		`{"id": "promo-gum", "name": "Gum", "amountOff": 300, "productIds": ["promo-gum", "promo-pop"]}`,
+		fmt.Sprintf(`{"id": "promo-later", "name": "Later", "percentOff": 90, "productIds": ["promo-hat"], "startsAt": %q}`, hour(1)),
+		fmt.Sprintf(`{"id": "promo-over", "name": "Over", "percentOff": 90, "productIds": ["promo-hat"], "startsAt": %q, "endsAt": %q}`, hour(-2), hour(-1)),
+	} {
+		w := send("POST", "/catalog/promotions", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	t.Run("Sale prices", func(t *testing.T) {
+		pop := product("/catalog/products/promo-pop")
+		assert.True(t, pop.OnSale)
+		assert.Equal(t, &model.Money{Amount: 1500, Currency: "USD"}, pop.SalePrice, "the promotion taking the most off applies")
+		assert.Equal(t, model.Money{Amount: 2000, Currency: "USD"}, pop.Price)
+
+		assert.Equal(t, &model.Money{Amount: 700, Currency: "USD"}, product("/catalog/products/promo-gum").SalePrice)
+		assert.Equal(t, &model.Money{Amount: 750, Currency: "EUR"}, product("/catalog/products/promo-pop?currency=EUR").SalePrice)
+
+		hat := product("/catalog/products/promo-hat")
+		assert.False(t, hat.OnSale, "promotions that haven't started or have ended don't apply")
+		assert.Nil(t, hat.SalePrice)
+
+		w := send("GET", "/catalog/products/promo-pop?fields=id,salePrice", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"id": "promo-pop", "salePrice": {"amount": 1500, "currency": "USD"}}`, w.Body.String())
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"promo-pop", "promo-gum"}, search("/v2/catalog/search?keyword=zorbly&onSale=true"))
+		assert.Equal(t, []string{"promo-hat"}, search("/v2/catalog/search?keyword=zorbly&onSale=false"))
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/v2/catalog/search?keyword=zorbly&onSale=maybe", "").Code)
+
+		w := send("GET", "/v2/catalog/search?keyword=zorbly&onSale=true&fields=id,onSale", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Products []map[string]any `json:"products"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Products, 2)
+		for _, product := range response.Products {
+			assert.Equal(t, true, product["onSale"], "search results are flagged")
+		}
+	})
+
+	t.Run("Search ignores prices", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "promo-free", "name": "Plimbo Sticker", "price": {"amount": 0, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		defer send("DELETE", "/catalog/products/promo-free", "")
+
+		w = send("POST", "/catalog/promotions", `{"id": "promo-free", "name": "Free", "percentOff": 50, "productIds": ["promo-free"]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		defer send("DELETE", "/catalog/promotions/promo-free", "")
+
+		// The promotion can't make a free product cheaper, so it isn't marked
+		// on sale, but searches for products on sale still include it
+		sticker := product("/catalog/products/promo-free")
+		assert.False(t, sticker.OnSale)
+		assert.Nil(t, sticker.SalePrice)
+
+		assert.Equal(t, []string{"promo-free"}, search("/v2/catalog/search?keyword=plimbo&onSale=true"))
+		assert.Empty(t, search("/v2/catalog/search?keyword=plimbo&onSale=false"))
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name": "Both", "percentOff": 10, "amountOff": 100, "tags": ["food"]}`,
+			`{"name": "Neither", "tags": ["food"]}`,
+			`{"name": "Everything", "percentOff": 10}`,
+			`{"name": "Too much", "percentOff": 150, "tags": ["food"]}`,
+			fmt.Sprintf(`{"name": "Backwards", "percentOff": 10, "tags": ["food"], "startsAt": %q, "endsAt": %q}`, hour(2), hour(1)),
+		} {
+			w := send("POST", "/catalog/promotions", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+
+		w := send("POST", "/catalog/promotions", `{"id": "promo-food", "name": "Again", "percentOff": 10, "tags": ["food"]}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("List and delete", func(t *testing.T) {
+		w := send("GET", "/catalog/promotions", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var promotions []model.Promotion
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &promotions))
+		ids := []string{}
+		for _, promotion := range promotions {
+			ids = append(ids, promotion.ID)
+		}
+		assert.Equal(t, []string{"promo-food", "promo-gum", "promo-later", "promo-over"}, ids)
+
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/promotions/promo-food", "").Code)
+		assert.Equal(t, &model.Money{Amount: 1700, Currency: "USD"}, product("/catalog/products/promo-pop").SalePrice)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", "/catalog/promotions/promo-food", "").Code)
+	})
+
+	for _, id := range []string{"promo-gum", "promo-later", "promo-over"} {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/promotions/"+id, "").Code)
+	}
+	for _, id := range []string{"promo-pop", "promo-gum", "promo-hat"} {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/"+id, "").Code)
+	}
+}
+
+func TestOpenSearchOnSale(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 0}, "hits": []}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	sale := repository.Sale{ProductIDs: []string{"p1"}, Tags: []string{"food"}}
+	onSale, notOnSale := true, false
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "tee", OnSale: &onSale, Sale: sale, AnyStatus: true}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": {"bool": {"minimum_should_match": 1, "should": [{"terms": {"id": ["p1"]}}, {"terms": {"tags": ["food"]}}]}}
+		}
+	}`, searchQueryOf(t, query))
+
+	_, err = search.SearchProducts(repository.SearchQuery{Keyword: "tee", OnSale: &notOnSale, Sale: sale, AnyStatus: true}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": {"bool": {"must_not": [{"terms": {"id": ["p1"]}}, {"terms": {"tags": ["food"]}}]}}
+		}
+	}`, searchQueryOf(t, query))
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	limiter := middleware.NewRateLimiter("test", 0.5, 2)
+	// Stands in for authentication, which only identifies clients with valid keys
+	router.Use(func(c *gin.Context) {
+		if key := c.GetHeader(middleware.APIKeyHeader); strings.HasPrefix(key, "valid-") {
+			c.Set(middleware.ClientIDKey, "client-"+key)
+		}
+	})
+	router.Use(limiter.Middleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set(middleware.APIKeyHeader, apiKey)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Burst then reject", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "").Code)
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "").Code)
+
+		w := request("10.0.0.1:1234", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Separate buckets per client", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("10.0.0.2:1234", "").Code)
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "valid-a").Code)
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "valid-b").Code)
+	})
+
+	t.Run("Unverified keys share the IP's bucket", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("10.0.0.4:1234", "forged-a").Code)
+		assert.Equal(t, http.StatusOK, request("10.0.0.4:1234", "forged-b").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.4:1234", "forged-c").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.4:1234", "").Code)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		limiter.SetEnabled(false)
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "").Code)
+
+		limiter.SetEnabled(true)
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1234", "").Code)
+	})
+
+	t.Run("Limit changed", func(t *testing.T) {
+		limiter.SetLimit(1000, 5)
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, request("10.0.0.3:1234", "").Code)
+		}
+
+		// Existing clients get the new rate too
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "").Code)
+	})
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+)
+
+func TestRecovery(t *testing.T) {
+	buffer := captureLogs(t, "info")
+
+	registry := prometheus.NewRegistry()
+	panics, err := middleware.NewPanicMetrics(registry)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Recovery(panics))
+	r.GET("/products/:id", func(c *gin.Context) {
+		if c.Param("id") == "broken" {
+			var product map[string]string
+			product["name"] = "nil map"
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		c.Writer.Flush()
+		panic("halfway through")
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/products/broken", nil)
+		req.Header.Set(middleware.RequestIDHeader, "panic-request")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, httputil.ProblemContentType, w.Header().Get("Content-Type"))
+
+		var problem httputil.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, httputil.Problem{
+			Type:     "about:blank",
+			Title:    "Internal Server Error",
+			Status:   http.StatusInternalServerError,
+			Detail:   "the server encountered an unexpected error",
+			Instance: "/products/broken",
+		}, problem)
+
+		records := logRecords(t, buffer)
+		require.Len(t, records, 1)
+		assert.Equal(t, "ERROR", records[0]["level"])
+		assert.Equal(t, "panic-request", records[0]["request_id"])
+		assert.Equal(t, "/products/:id", records[0]["route"])
+		assert.Contains(t, records[0]["panic"], "assignment to entry in nil map")
+		assert.Contains(t, records[0]["stack"], "recovery_test.go")
+	})
+
+	t.Run("Handled normally", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/products/a1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Response already started", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/stream", nil)
+
+		// The server drops the connection when a handler panics with
+		// ErrAbortHandler
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { r.ServeHTTP(w, req) })
+		assert.Equal(t, "partial", w.Body.String())
+	})
+
+	t.Run("Aborted on purpose", func(t *testing.T) {
+		r := gin.New()
+		r.Use(middleware.Recovery(panics))
+		r.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/abort", nil)
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { r.ServeHTTP(w, req) })
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_http_panics_total HTTP requests whose handler panicked, by route
+# TYPE catalog_http_panics_total counter
+catalog_http_panics_total{method="GET",route="/products/:id"} 1
+catalog_http_panics_total{method="GET",route="/stream"} 1
+`), "catalog_http_panics_total"))
+}
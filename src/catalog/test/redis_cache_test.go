@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/cache"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func newRedisCache(t *testing.T) (*cache.Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	shared := cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1, DialerRetries: 1}), "test:", 500*time.Millisecond)
+	t.Cleanup(func() { shared.Close() })
+	return shared, server
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	load := func(value string) func() (string, error) {
+		return func() (string, error) {
+			loads.Add(1)
+			return value, nil
+		}
+	}
+
+	t.Run("Cache-aside", func(t *testing.T) {
+		shared, server := newRedisCache(t)
+		loads.Store(0)
+
+		for range 3 {
+			value, err := cache.GetJSON(shared, "products", "a", time.Minute, load("1"), ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "1", value)
+		}
+		assert.Equal(t, int32(1), loads.Load())
+
+		cached, err := server.Get("test:{products}:0:a")
+		require.NoError(t, err)
+		assert.Equal(t, `"1"`, cached)
+		assert.Equal(t, time.Minute, server.TTL("test:{products}:0:a"))
+
+		server.FastForward(time.Minute)
+		value, _ := cache.GetJSON(shared, "products", "a", time.Minute, load("2"), ctx)
+		assert.Equal(t, "2", value)
+	})
+
+	t.Run("Invalidation", func(t *testing.T) {
+		shared, _ := newRedisCache(t)
+
+		cache.GetJSON(shared, "products", "a", time.Minute, load("1"), ctx)
+		cache.GetJSON(shared, "products", "b", time.Minute, load("1"), ctx)
+
+		shared.Delete("products", "a", ctx)
+		value, _ := cache.GetJSON(shared, "products", "a", time.Minute, load("2"), ctx)
+		assert.Equal(t, "2", value)
+		value, _ = cache.GetJSON(shared, "products", "b", time.Minute, load("2"), ctx)
+		assert.Equal(t, "1", value)
+
+		shared.Invalidate("products", ctx)
+		value, _ = cache.GetJSON(shared, "products", "b", time.Minute, load("3"), ctx)
+		assert.Equal(t, "3", value)
+	})
+
+	t.Run("Changed while loading", func(t *testing.T) {
+		shared, _ := newRedisCache(t)
+
+		value, _ := cache.GetJSON(shared, "products", "a", time.Minute, func() (string, error) {
+			shared.Delete("products", "a", ctx)
+			return "stale", nil
+		}, ctx)
+		assert.Equal(t, "stale", value)
+
+		value, _ = cache.GetJSON(shared, "products", "a", time.Minute, func() (string, error) {
+			shared.Invalidate("products", ctx)
+			return "stale", nil
+		}, ctx)
+		assert.Equal(t, "stale", value)
+
+		value, _ = cache.GetJSON(shared, "products", "a", time.Minute, load("fresh"), ctx)
+		assert.Equal(t, "fresh", value)
+	})
+
+	t.Run("Stampede protection", func(t *testing.T) {
+		shared, _ := newRedisCache(t)
+		loads.Store(0)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := cache.GetJSON(shared, "search", "popular", time.Minute, func() (string, error) {
+					loads.Add(1)
+					time.Sleep(100 * time.Millisecond)
+					return "results", nil
+				}, ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, "results", value)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), loads.Load())
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		shared, server := newRedisCache(t)
+		server.Close()
+
+		value, err := cache.GetJSON(shared, "products", "a", time.Minute, load("1"), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "1", value)
+
+		_, err = shared.Status(ctx)
+		assert.Error(t, err)
+	})
+}
+
+func TestSharedResponseCache(t *testing.T) {
+	shared, _ := newRedisCache(t)
+	ctx := context.Background()
+
+	// Two replicas sharing the cache, each with its own repository counting
+	// the lookups that reach it
+	db := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	replicas := make([]*api.CatalogAPI, 2)
+	counted := make([]*countedRepository, 2)
+	for i := range replicas {
+		counted[i] = &countedRepository{WritableCatalogRepository: db}
+		catalogAPI, err := api.NewCatalogAPI(counted[i], nil)
+		require.NoError(t, err)
+		catalogAPI.SetSharedCache(shared, time.Minute, time.Minute, time.Minute)
+		replicas[i] = catalogAPI
+	}
+
+	category := "timepieces"
+	_, err := replicas[0].CreateProduct(&model.Product{ID: "shared-1", Name: "Shared", Price: model.Money{Amount: 1}, CategoryName: &category}, ctx)
+	require.NoError(t, err)
+
+	first, err := replicas[0].GetProduct("shared-1", ctx)
+	require.NoError(t, err)
+	second, err := replicas[1].GetProduct("shared-1", ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), counted[0].lookups.Load())
+	assert.Equal(t, int32(0), counted[1].lookups.Load(), "read from the cache filled by the other replica")
+	assert.Equal(t, first.Name, second.Name)
+	require.NotNil(t, second.CategoryName)
+	assert.Equal(t, "timepieces", *second.CategoryName)
+
+	second.Name = "Renamed"
+	_, err = replicas[1].UpdateProduct(second, ctx)
+	require.NoError(t, err)
+
+	updated, err := replicas[0].GetProduct("shared-1", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+	assert.Equal(t, int32(2), counted[0].lookups.Load())
+
+	tags, err := replicas[0].GetTags(ctx)
+	require.NoError(t, err)
+	cachedTags, err := replicas[1].GetTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, tags, cachedTags)
+}
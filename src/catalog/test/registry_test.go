@@ -0,0 +1,69 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+type stubRepository struct {
+	repository.CatalogRepository
+}
+
+func (s *stubRepository) GetProduct(id string, ctx context.Context) (*model.Product, error) {
+	return &model.Product{ID: id, Name: "Stub"}, nil
+}
+
+func TestRegistry_CustomProvider(t *testing.T) {
+	repository.Register("stub", func(config config.DatabaseConfiguration) (repository.CatalogRepository, error) {
+		return &stubRepository{}, nil
+	})
+
+	assert.Contains(t, repository.CatalogProviders(), "stub")
+
+	repo, err := repository.NewRepository(config.DatabaseConfiguration{Type: "stub"})
+	require.NoError(t, err)
+
+	product, err := repo.GetProduct("abc", context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Stub", product.Name)
+
+	assert.Panics(t, func() {
+		repository.Register("stub", func(config config.DatabaseConfiguration) (repository.CatalogRepository, error) {
+			return nil, nil
+		})
+	})
+}
+
+func TestRegistry_UnknownProvider(t *testing.T) {
+	_, err := repository.NewRepository(config.DatabaseConfiguration{Type: "missing"})
+	assert.ErrorContains(t, err, "unknown persistence provider")
+
+	_, err = repository.NewSearchRepository(config.AppConfiguration{
+		Search: config.SearchConfiguration{Backend: "missing"},
+	}, nil)
+	assert.ErrorContains(t, err, "unknown search provider")
+}
+
+func TestRegistry_SearchBackend(t *testing.T) {
+	assert.Equal(t, "", repository.SearchBackend(config.AppConfiguration{}))
+
+	assert.Equal(t, "opensearch", repository.SearchBackend(config.AppConfiguration{
+		OpenSearch: config.OpenSearchConfiguration{Enabled: true},
+	}))
+
+	assert.Equal(t, "sqlite", repository.SearchBackend(config.AppConfiguration{
+		Database: config.DatabaseConfiguration{Type: "sqlite"},
+	}))
+
+	assert.Equal(t, "custom", repository.SearchBackend(config.AppConfiguration{
+		Search:     config.SearchConfiguration{Backend: "custom"},
+		OpenSearch: config.OpenSearchConfiguration{Enabled: true},
+	}))
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// bulkSearch is a search repository that records the products indexed in
+// batches, rejecting those with the IDs in reject
+type bulkSearch struct {
+	stubSearch
+	mu      sync.Mutex
+	docs    map[string]model.Product
+	reject  map[string]bool
+	resets  int
+	release chan struct{}
+}
+
+func (s *bulkSearch) ResetIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resets++
+	s.docs = map[string]model.Product{}
+	return nil
+}
+
+func (s *bulkSearch) IndexProducts(products []model.Product, ctx context.Context) (int, error) {
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := 0
+	for _, product := range products {
+		if s.reject[product.ID] {
+			failed++
+			continue
+		}
+		s.docs[product.ID] = product
+	}
+	return failed, nil
+}
+
+func waitForReindex(t *testing.T, catalogAPI *api.CatalogAPI, id string) model.ReindexJob {
+	var job model.ReindexJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = catalogAPI.GetReindexJob(id)
+		require.NoError(t, err)
+		return job.Status != model.ReindexRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestStartReindex(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	total, err := db.CountProducts(repository.ProductFilter{}, ctx)
+	require.NoError(t, err)
+
+	all, err := db.GetProducts(repository.ProductFilter{}, "", 1, 1, ctx)
+	require.NoError(t, err)
+
+	t.Run("Bulk indexer", func(t *testing.T) {
+		search := &bulkSearch{reject: map[string]bool{all[0].ID: true}}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, model.ReindexRunning, job.Status)
+
+		job = waitForReindex(t, catalogAPI, job.ID)
+		assert.Equal(t, model.ReindexCompleted, job.Status)
+		assert.Equal(t, total, job.Total)
+		assert.Equal(t, total-1, job.Indexed)
+		assert.Equal(t, 1, job.Failed)
+		assert.NotNil(t, job.FinishedAt)
+		assert.Nil(t, job.ETASeconds)
+
+		assert.Equal(t, 1, search.resets)
+		assert.Len(t, search.docs, total-1)
+	})
+
+	t.Run("Worker pool", func(t *testing.T) {
+		search := &bulkSearch{}
+		search.ResetIndex(ctx)
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+		catalogAPI.SetReindexOptions(3, 2)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		job = waitForReindex(t, catalogAPI, job.ID)
+		assert.Equal(t, model.ReindexCompleted, job.Status)
+		assert.Equal(t, total, job.Indexed)
+		assert.Len(t, search.docs, total)
+	})
+
+	t.Run("Provider without bulk indexing", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, &stubSearch{})
+		require.NoError(t, err)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		job = waitForReindex(t, catalogAPI, job.ID)
+		assert.Equal(t, model.ReindexCompleted, job.Status)
+		assert.Equal(t, total, job.Indexed)
+	})
+
+	t.Run("One job at a time", func(t *testing.T) {
+		search := &bulkSearch{release: make(chan struct{})}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		first, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		running, err := catalogAPI.StartReindex(ctx)
+		assert.ErrorIs(t, err, api.ErrReindexInProgress)
+		assert.Equal(t, first.ID, running.ID)
+
+		close(search.release)
+		waitForReindex(t, catalogAPI, first.ID)
+
+		next, err := catalogAPI.StartReindex(ctx)
+		assert.NoError(t, err)
+		waitForReindex(t, catalogAPI, next.ID)
+	})
+}
+
+func TestAdminReindexEndpoints(t *testing.T) {
+	search := &bulkSearch{}
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/reindex", c.StartReindex)
+	r.GET("/admin/reindex/:jobId", c.GetReindexJob)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/reindex", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var job model.ReindexJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/admin/reindex/"+job.ID, w.Header().Get("Location"))
+
+	waitForReindex(t, catalogAPI, job.ID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/reindex/"+job.ID, nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, model.ReindexCompleted, job.Status)
+	assert.Equal(t, job.Total, job.Indexed)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/reindex/missing", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
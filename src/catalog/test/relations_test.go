@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestRelatedProducts(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/products/:id/related", c.GetRelatedProducts)
+	r.PUT("/catalog/products/:id/related", c.SetRelatedProducts)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	related := func(url string) model.RelatedProductsResponse {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.RelatedProductsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	ids := func(response model.RelatedProductsResponse) []string {
+		ids := []string{}
+		for _, related := range response.Related {
+			ids = append(ids, related.Type+":"+related.Product.ID)
+		}
+		return ids
+	}
+
+	for _, body := range []string{
+		`{"id": "rel-camera", "name": "Camera", "price": {"amount": 50000, "currency": "USD"}}`,
+		`{"id": "rel-lens", "name": "Lens", "price": {"amount": 20000, "currency": "USD"}}`,
+		`{"id": "rel-bag", "name": "Bag", "price": {"amount": 4000, "currency": "USD"}}`,
+		`{"id": "rel-old", "name": "Old camera", "price": {"amount": 30000, "currency": "USD"}, "status": "discontinued"}`,
+		`{"id": "rel-draft", "name": "Draft", "status": "draft"}`,
+	} {
+		w := send("POST", "/catalog/products", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	defer func() {
+		for _, id := range []string{"rel-camera", "rel-lens", "rel-bag", "rel-old", "rel-draft"} {
+			send("DELETE", "/catalog/products/"+id, "")
+		}
+	}()
+
+	t.Run("Set", func(t *testing.T) {
+		w := send("PUT", "/catalog/products/rel-camera/related", `{"related": [
+			{"type": "replacement-for", "productId": "rel-old"},
+			{"type": "bundle", "productId": "rel-lens"},
+			{"type": "bundle", "productId": "rel-bag"},
+			{"type": "bundle", "productId": "rel-bag"},
+			{"type": "bundle", "productId": "rel-draft"}
+		]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `[
+			{"type": "bundle", "productId": "rel-bag"},
+			{"type": "bundle", "productId": "rel-draft"},
+			{"type": "bundle", "productId": "rel-lens"},
+			{"type": "replacement-for", "productId": "rel-old"}
+		]`, w.Body.String())
+
+		w = send("PUT", "/catalog/products/rel-lens/related", `{"related": [{"type": "accessory-of", "productId": "rel-camera"}]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		// Drafts are left out
+		response := related("/catalog/products/rel-camera/related")
+		assert.Equal(t, "rel-camera", response.ProductID)
+		assert.Equal(t, []string{"bundle:rel-bag", "bundle:rel-lens", "replacement-for:rel-old"}, ids(response))
+		assert.Equal(t, "Bag", response.Related[0].Product.Name)
+
+		response = related("/catalog/products/rel-camera/related?type=bundle")
+		assert.Equal(t, []string{"bundle:rel-bag", "bundle:rel-lens"}, ids(response))
+
+		response = related("/catalog/products/rel-lens/related?type=accessory-of")
+		assert.Equal(t, []string{"accessory-of:rel-camera"}, ids(response))
+
+		response = related("/catalog/products/rel-bag/related")
+		assert.Empty(t, response.Related)
+
+		w := send("GET", "/catalog/products/rel-camera", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Len(t, product.Related, 4)
+	})
+
+	t.Run("Kept by updates", func(t *testing.T) {
+		w := send("PATCH", "/catalog/products/rel-camera", `{"name": "Camera body"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Len(t, product.Related, 4)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/catalog/products/rel-camera/related", `{"related": [{"type": "sibling", "productId": "rel-bag"}]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/catalog/products/rel-camera/related", `{"related": [{"type": "bundle", "productId": "missing"}]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/catalog/products/rel-camera/related", `{"related": [{"type": "bundle", "productId": "rel-camera"}]}`).Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", "/catalog/products/missing/related", `{"related": []}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/rel-camera/related?type=sibling", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/rel-draft/related", "").Code)
+
+		// Failed changes leave the relations as they were
+		assert.Len(t, related("/catalog/products/rel-camera/related").Related, 3)
+	})
+
+	t.Run("Deleted products", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/rel-lens", "").Code)
+		assert.Equal(t, []string{"bundle:rel-bag", "replacement-for:rel-old"}, ids(related("/catalog/products/rel-camera/related")))
+
+		w := send("PUT", "/catalog/products/rel-camera/related", `{"related": []}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+}
+
+func TestDualWrite_Relations(t *testing.T) {
+	index := newFakeIndex()
+	repo := newDualWriteRepository(t, index)
+	ctx := context.Background()
+
+	for _, id := range []string{"dual-write-tent", "dual-write-pegs"} {
+		require.NoError(t, repo.CreateProduct(&model.Product{ID: id, Name: "Test", Price: model.Money{Amount: 10}}, ctx))
+		defer repo.DeleteProduct(id, ctx)
+	}
+
+	relations := model.RelationsRequest{Related: []model.RelationRequest{{Type: model.RelationAccessoryOf, ProductID: "dual-write-tent"}}}.ToRelations("dual-write-pegs")
+	product, err := repo.SetRelations("dual-write-pegs", relations, ctx)
+	require.NoError(t, err)
+	expected := []model.ProductRelation{{ProductID: "dual-write-pegs", Type: model.RelationAccessoryOf, RelatedID: "dual-write-tent"}}
+	assert.Equal(t, expected, product.Related)
+	assert.Equal(t, expected, index.docs["dual-write-pegs"].Related)
+}
+
+func TestOpenSearchRelations(t *testing.T) {
+	var mapping, indexed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && r.URL.Path == "/products":
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			indexed = string(body)
+			io.WriteString(w, `{"result": "updated"}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"properties": {"bundle": {"type": "keyword"}, "accessory-of": {"type": "keyword"}, "replacement-for": {"type": "keyword"}}}`, string(index.Mappings.Properties["related"]))
+
+	product := model.Product{ID: "tent", Name: "Tent", Related: []model.ProductRelation{
+		{ProductID: "tent", Type: model.RelationBundle, RelatedID: "mat"},
+		{ProductID: "tent", Type: model.RelationBundle, RelatedID: "pegs"},
+	}}
+	require.NoError(t, search.IndexProduct(product, context.Background()))
+	var doc struct {
+		Related json.RawMessage `json:"related"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(indexed), &doc))
+	assert.JSONEq(t, `{"bundle": ["mat", "pegs"]}`, string(doc.Related))
+
+	require.NoError(t, search.UpdateProductFields(product, []string{"related"}, context.Background()))
+	assert.JSONEq(t, `{"doc": {"related": {"bundle": ["mat", "pegs"], "accessory-of": null, "replacement-for": null}}}`, indexed)
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestResetCatalog(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	seed, err := repository.LoadProductData()
+	require.NoError(t, err)
+
+	seedIDs := []string{}
+	for _, product := range seed {
+		seedIDs = append(seedIDs, product.ID)
+	}
+
+	before, err := db.GetProducts(repository.ProductFilter{}, "", 1, 100, ctx)
+	require.NoError(t, err)
+
+	catalogAPI, err := api.NewCatalogAPI(db, &stubSearch{})
+	require.NoError(t, err)
+
+	_, err = catalogAPI.CreateProduct(&model.Product{ID: "reset-1", Name: "Demo product"}, ctx)
+	require.NoError(t, err)
+
+	changed := before[0]
+	changed.Price = model.Money{Amount: 1}
+	changed.Tags = nil
+	_, err = catalogAPI.UpdateProduct(&changed, ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, catalogAPI.DeleteProduct(before[1].ID, ctx))
+
+	subscription := catalogAPI.Events().Subscribe(0)
+	defer subscription.Close()
+
+	require.NoError(t, catalogAPI.ResetCatalog(ctx))
+
+	after, err := db.GetProducts(repository.ProductFilter{}, "", 1, 100, ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, seedIDs, productIDs(after))
+
+	restored, err := db.GetProduct(before[0].ID, ctx)
+	require.NoError(t, err)
+	// The seed products are created again, so only their timestamps differ
+	restored.CreatedAt, restored.UpdatedAt = before[0].CreatedAt, before[0].UpdatedAt
+	assert.Equal(t, before[0], *restored)
+
+	event := <-subscription.Events
+	assert.Equal(t, model.EventCatalogReset, event.Type)
+
+	t.Run("Search index fails to rebuild", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, &stubSearch{err: errors.New("unavailable")})
+		require.NoError(t, err)
+
+		assert.Error(t, catalogAPI.ResetCatalog(ctx))
+	})
+
+	t.Run("While reindexing", func(t *testing.T) {
+		search := &bulkSearch{release: make(chan struct{})}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, catalogAPI.ResetCatalog(ctx), api.ErrReindexInProgress)
+
+		close(search.release)
+		waitForReindex(t, catalogAPI, job.ID)
+	})
+}
+
+func TestAdminResetEndpoint(t *testing.T) {
+	catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/reset", c.ResetCatalog)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/reset", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
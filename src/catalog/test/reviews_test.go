@@ -0,0 +1,249 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestReviews(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/products/:id/reviews", c.GetReviews)
+	r.POST("/catalog/products/:id/reviews", c.AddReview)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	rating := func(id string) model.Rating {
+		w := send("GET", "/catalog/products/"+id, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product.Rating
+	}
+	reviews := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var reviews []model.ProductReview
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reviews))
+		titles := []string{}
+		for _, review := range reviews {
+			titles = append(titles, review.Title)
+		}
+		return titles
+	}
+
+	const tickstopper = "cc789f85-1476-452a-8100-9e74502198e0"
+	const quill = "d27cf49f-b689-4a75-a249-d373e0330bb5"
+	const forgetter = "1ca35e86-4b4c-4124-b6b5-076ba4134d0d"
+	const teleporter = "631a3db5-ac07-492c-a994-8cd56923c112"
+
+	t.Run("Seeded reviews", func(t *testing.T) {
+		assert.Equal(t, model.Rating{Average: 4.5, Count: 2}, rating(tickstopper))
+		assert.Equal(t, model.Rating{}, rating("8757729a-c518-4356-8694-9e795a9b3237"))
+		assert.ElementsMatch(t, []string{"Saved my morning commute", "Beautiful watch, loud tick"}, reviews("/catalog/products/"+tickstopper+"/reviews"))
+
+		w := send("GET", "/catalog/products/8757729a-c518-4356-8694-9e795a9b3237", "")
+		assert.NotContains(t, w.Body.String(), "rating", "products without reviews have no rating")
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "review-tee", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products/review-tee/reviews", `{"rating": 5, "title": "Soft", "text": "Very soft cotton", "author": "Sam"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var review model.ProductReview
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &review))
+		assert.NotZero(t, review.ID)
+		assert.False(t, review.CreatedAt.IsZero())
+
+		w = send("POST", "/catalog/products/review-tee/reviews", `{"rating": 2, "title": "Shrank", "author": "Alex"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = send("POST", "/catalog/products/review-tee/reviews", `{"rating": 3, "title": "Fine", "author": "Kim"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		assert.Equal(t, model.Rating{Average: 3.33, Count: 3}, rating("review-tee"))
+		assert.Equal(t, []string{"Fine", "Shrank", "Soft"}, reviews("/catalog/products/review-tee/reviews"))
+		assert.Equal(t, []string{"Shrank"}, reviews("/catalog/products/review-tee/reviews?page=2&size=1"))
+
+		// Changes to the product keep its rating
+		w = send("PUT", "/catalog/products/review-tee", `{"name": "T-shirt", "price": {"amount": 1500, "currency": "USD"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"rating":{"average":3.33,"count":3}`)
+		assert.Equal(t, model.Rating{Average: 3.33, Count: 3}, rating("review-tee"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"rating": 6, "title": "Great", "author": "Sam"}`,
+			`{"rating": 0, "title": "Great", "author": "Sam"}`,
+			`{"rating": 4, "author": "Sam"}`,
+			`{"rating": 4, "title": "Great"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, send("POST", "/catalog/products/"+quill+"/reviews", body).Code, body)
+		}
+
+		assert.Equal(t, http.StatusNotFound, send("POST", "/catalog/products/missing/reviews", `{"rating": 4, "title": "Great", "author": "Sam"}`).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/missing/reviews", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/"+quill+"/reviews?size=500", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/"+quill+"/reviews?page=0", "").Code)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		search := func(url string) []model.Product {
+			w := send("GET", url, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response model.SearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Products
+		}
+		ratings := func(products []model.Product) []float64 {
+			averages := []float64{}
+			for _, product := range products {
+				averages = append(averages, product.Rating.Average)
+			}
+			return averages
+		}
+
+		assert.ElementsMatch(t, []string{quill, teleporter}, productIDs(search("/v2/catalog/search?keyword=the&minRating=5")))
+
+		descending := search("/v2/catalog/search?keyword=the&minRating=2&sort=-rating&size=20")
+		require.NotEmpty(t, descending)
+		assert.IsNonIncreasing(t, ratings(descending))
+		assert.Equal(t, forgetter, descending[len(descending)-1].ID)
+
+		ascending := search("/v2/catalog/search?keyword=the&minRating=1&sort=rating&size=20")
+		assert.IsNonDecreasing(t, ratings(ascending))
+		assert.Equal(t, forgetter, ascending[0].ID)
+		assert.NotContains(t, productIDs(ascending), "8757729a-c518-4356-8694-9e795a9b3237", "products without reviews don't match a minimum rating")
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/v2/catalog/search?keyword=the&minRating=6", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/v2/catalog/search?keyword=the&minRating=high", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/v2/catalog/search?keyword=the&sort=price", "").Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/review-tee", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/review-tee/reviews", "").Code)
+
+		w := send("POST", "/catalog/products", `{"id": "review-tee", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, reviews("/catalog/products/review-tee/reviews"))
+		assert.Equal(t, model.Rating{}, rating("review-tee"))
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/review-tee", "").Code)
+	})
+}
+
+func TestDualWrite_Reviews(t *testing.T) {
+	index := newFakeIndex()
+	repo := newDualWriteRepository(t, index)
+	ctx := context.Background()
+
+	product := &model.Product{ID: "dual-write-review", Name: "Test", Price: model.Money{Amount: 10}}
+	require.NoError(t, repo.CreateProduct(product, ctx))
+	defer repo.DeleteProduct(product.ID, ctx)
+
+	review := model.ReviewRequest{Rating: 4, Title: "Good", Author: "Sam"}.ToReview(product.ID)
+	reviewed, err := repo.AddReview(&review, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, model.Rating{Average: 4, Count: 1}, reviewed.Rating)
+	assert.Equal(t, model.Rating{Average: 4, Count: 1}, index.docs[product.ID].Rating)
+
+	review = model.ReviewRequest{Rating: 4, Title: "Missing", Author: "Sam"}.ToReview("missing")
+	_, err = repo.AddReview(&review, ctx)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+}
+
+func TestOpenSearchRatings(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "sort": [4.5, 1.2, "p1"], "_source": {
+				"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}, "rating": {"average": 4.5, "count": 2}
+			}}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"properties": {"average": {"type": "float"}, "count": {"type": "integer"}}}`, string(index.Mappings.Properties["rating"]))
+
+	minRating := 4.0
+	result, last, err := search.SearchProductsAfter(repository.SearchQuery{Keyword: "tee", MinRating: &minRating, Order: repository.OrderRatingDesc}, nil, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": [
+				{"range": {"rating.average": {"gte": 4}}},
+				{"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+			]
+		}
+	}`, searchQueryOf(t, query))
+
+	var request struct {
+		Sort json.RawMessage `json:"sort"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(query), &request))
+	assert.JSONEq(t, `[{"rating.average": {"order": "desc", "missing": "_last"}}, {"_score": "desc"}, {"id": "asc"}]`, string(request.Sort))
+
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, model.Rating{Average: 4.5, Count: 2}, result.Products[0].Rating)
+	assert.Equal(t, []interface{}{4.5, 1.2, "p1"}, last)
+}
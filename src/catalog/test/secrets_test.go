@@ -0,0 +1,188 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+	"github.com/aws-containers/retail-store-sample-app/catalog/secrets"
+)
+
+// fakeSecretsManager returns the current value of a secret, or err if set
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	value string
+	err   error
+	calls int
+}
+
+func (f *fakeSecretsManager) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(f.value)}, nil
+}
+
+func TestParseCredentials(t *testing.T) {
+	assert.Equal(t, secrets.Credentials{Username: "catalog", Password: "s3cret"},
+		secrets.ParseCredentials(`{"username": "catalog", "password": "s3cret", "host": "db", "port": 3306}`))
+	assert.Equal(t, secrets.Credentials{Password: "s3cret"}, secrets.ParseCredentials(`{"password": "s3cret"}`))
+	assert.Equal(t, secrets.Credentials{Password: "plain{text"}, secrets.ParseCredentials("plain{text"))
+
+	assert.Equal(t, "catalog_user", secrets.ParseCredentials("s3cret").WithDefaultUsername("catalog_user").Username)
+	assert.Equal(t, "catalog", secrets.ParseCredentials(`{"username": "catalog"}`).WithDefaultUsername("catalog_user").Username)
+}
+
+func TestSecretsManagerSource(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeSecretsManager{value: `{"username": "catalog", "password": "first"}`}
+
+	t.Run("Cached", func(t *testing.T) {
+		source := secrets.NewSecretsManagerWithClient(client, "catalog-db", time.Hour)
+
+		for i := 0; i < 3; i++ {
+			credentials, err := source.Credentials(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "first", credentials.Password)
+		}
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("Refreshed", func(t *testing.T) {
+		source := secrets.NewSecretsManagerWithClient(client, "catalog-db", 10*time.Millisecond)
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "first", credentials.Password)
+
+		client.value = `{"username": "catalog", "password": "rotated"}`
+		time.Sleep(20 * time.Millisecond)
+
+		credentials, err = source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", credentials.Password)
+
+		// Cached credentials are kept when a refresh fails
+		client.err = errors.New("throttled")
+		time.Sleep(20 * time.Millisecond)
+
+		credentials, err = source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", credentials.Password)
+	})
+
+	t.Run("Unreadable", func(t *testing.T) {
+		source := secrets.NewSecretsManagerWithClient(&fakeSecretsManager{err: errors.New("access denied")}, "catalog-db", time.Hour)
+
+		_, err := source.Credentials(ctx)
+		assert.ErrorContains(t, err, "access denied")
+	})
+
+	t.Run("Invalidated", func(t *testing.T) {
+		client := &fakeSecretsManager{value: "first"}
+		source := secrets.NewSecretsManagerWithClient(client, "catalog-db", time.Hour)
+
+		_, err := source.Credentials(ctx)
+		require.NoError(t, err)
+
+		// Ignored right after a read
+		client.value = "rotated"
+		source.Invalidate()
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "first", credentials.Password)
+
+		time.Sleep(1100 * time.Millisecond)
+		source.Invalidate()
+		credentials, err = source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", credentials.Password)
+		assert.Equal(t, 2, client.calls)
+	})
+}
+
+func TestFileSource(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	source := secrets.NewFile(path)
+
+	credentials, err := source.Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, secrets.Credentials{Password: "first"}, credentials)
+
+	t.Run("Modified", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`{"username": "catalog", "password": "rotated"}`), 0o600))
+		later := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, secrets.Credentials{Username: "catalog", Password: "rotated"}, credentials)
+	})
+
+	t.Run("Missing while replaced", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", credentials.Password)
+
+		_, err = secrets.NewFile(path).Credentials(ctx)
+		assert.Error(t, err)
+	})
+}
+
+func TestOpenSearchCredentialRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0o600))
+	modified := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	password := "first"
+	var unauthorized int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, sent, _ := r.BasicAuth(); sent != password {
+			unauthorized++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:     server.URL,
+		IndexName:    "products",
+		Username:     "admin",
+		PasswordFile: path,
+	})
+	require.NoError(t, err)
+
+	// Rotate the password without the file appearing modified, so only the
+	// rejected request can prompt reading it again
+	password = "rotated"
+	require.NoError(t, os.WriteFile(path, []byte("rotated"), 0o600))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	require.NoError(t, search.Ping(context.Background()))
+	assert.Equal(t, 1, unauthorized)
+}
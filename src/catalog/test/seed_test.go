@@ -0,0 +1,159 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+const customProducts = `[
+	{"id": "workshop-1", "name": "Workshop Widget", "description": "A widget for the workshop", "price": {"amount": 1200, "currency": "USD"}, "tags": ["accessories"], "category": "gadgets", "stock": 5},
+	{"id": "workshop-2", "name": "Workshop Jacket", "price": {"amount": 5000, "currency": "USD"}, "tags": ["clothing"], "stock": 2}
+]`
+
+func checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// useProductData replaces the bundled products for the rest of the test
+func useProductData(t *testing.T, data, format string) {
+	require.NoError(t, repository.UseProductData([]byte(data), format))
+	t.Cleanup(func() { repository.UseProductData(nil, "") })
+}
+
+func TestFetchProductData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(customProducts))
+	}))
+	defer server.Close()
+
+	seed := config.SeedConfiguration{URL: server.URL + "/products.json", SHA256: checksum(customProducts), Timeout: time.Second}
+	ctx := context.Background()
+
+	t.Run("Matching checksum", func(t *testing.T) {
+		data, err := repository.FetchProductData(seed, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, customProducts, string(data))
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		mismatched := seed
+		mismatched.SHA256 = checksum("something else")
+		_, err := repository.FetchProductData(mismatched, ctx)
+		assert.ErrorIs(t, err, repository.ErrChecksumMismatch)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		missing := seed
+		missing.URL = server.URL + "/missing.json"
+		_, err := repository.FetchProductData(missing, ctx)
+		assert.ErrorContains(t, err, "404")
+	})
+}
+
+func TestUseProductData(t *testing.T) {
+	bundled, err := repository.LoadProductData()
+	require.NoError(t, err)
+
+	for name, data := range map[string]string{
+		"Not JSON":         `{`,
+		"No products":      `[]`,
+		"Missing ID":       `[{"name": "Nameless"}]`,
+		"Duplicate ID":     `[{"id": "p1", "name": "One"}, {"id": "p1", "name": "Two"}]`,
+		"Unknown tag":      `[{"id": "p1", "name": "One", "tags": ["nope"]}]`,
+		"Unknown category": `[{"id": "p1", "name": "One", "category": "nope"}]`,
+	} {
+		assert.Error(t, repository.UseProductData([]byte(data), repository.ProductDataJSON), name)
+	}
+
+	products, err := repository.LoadProductData()
+	require.NoError(t, err)
+	assert.Equal(t, bundled, products, "invalid data leaves the bundled products")
+
+	useProductData(t, customProducts, repository.ProductDataJSON)
+	products, err = repository.LoadProductData()
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "Workshop Widget", products[0].Name)
+
+	require.NoError(t, repository.UseProductData(nil, ""))
+	products, err = repository.LoadProductData()
+	require.NoError(t, err)
+	assert.Equal(t, bundled, products)
+}
+
+func TestCSVProductData(t *testing.T) {
+	t.Run("Columns in any order", func(t *testing.T) {
+		useProductData(t, "name,id,price,tags,description,stock,category,currency\n"+
+			"Workshop Widget,workshop-1,1200,accessories,\"Small, and useful\",5,gadgets,\n"+
+			"\n"+
+			"Workshop Jacket,workshop-2,5000,clothing | accessories,,,,eur\n", repository.ProductDataCSV)
+
+		products, err := repository.LoadProductData()
+		require.NoError(t, err)
+		require.Len(t, products, 2)
+
+		stock := 5
+		assert.Equal(t, repository.ProductData{
+			ID:          "workshop-1",
+			Name:        "Workshop Widget",
+			Description: "Small, and useful",
+			Price:       model.Money{Amount: 1200, Currency: "USD"},
+			Tags:        []string{"accessories"},
+			Category:    "gadgets",
+			Stock:       &stock,
+		}, products[0])
+		assert.Equal(t, model.Money{Amount: 5000, Currency: "EUR"}, products[1].Price)
+		assert.Equal(t, []string{"clothing", "accessories"}, products[1].Tags)
+		assert.Nil(t, products[1].Stock)
+	})
+
+	t.Run("Only required columns, after a byte order mark", func(t *testing.T) {
+		useProductData(t, "\ufeffid,name,price\nworkshop-1,Widget,100\n", repository.ProductDataCSV)
+
+		products, err := repository.LoadProductData()
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Empty(t, products[0].Tags)
+	})
+
+	t.Run("Errors name the row", func(t *testing.T) {
+		for data, message := range map[string]string{
+			"":                                           "no header row",
+			"id,name,price,colour\n":                     `row 1: unknown column "colour"`,
+			"id,name,price,name\n":                       `row 1: column "name" is given more than once`,
+			"id,name\nworkshop-1,Widget\n":               `row 1: the "price" column is required`,
+			"id,name,price\np1,One,1\np2,Two,free\n":     `row 3: price must be a whole number`,
+			"id,name,price\np1,One,1\np2,Two\n":          "row 3: wrong number of fields",
+			"id,name,price\np1,,1\n":                     "row 2: name is required",
+			"id,name,price,stock\np1,One,1,-2\n":         "row 2: stock must be a whole number",
+			"id,name,price,currency\np1,One,1,dollars\n": "row 2: currency must be an ISO 4217 code",
+			"id,name,price,tags\np1,One,1,nope\n":        "product p1: unknown tag: nope",
+		} {
+			assert.ErrorContains(t, repository.UseProductData([]byte(data), repository.ProductDataCSV), message, data)
+		}
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		assert.Equal(t, repository.ProductDataCSV, repository.ProductDataFormat(config.SeedConfiguration{URL: "s3://workshop/products.CSV"}))
+		assert.Equal(t, repository.ProductDataJSON, repository.ProductDataFormat(config.SeedConfiguration{URL: "https://example.com/products.json"}))
+		assert.Equal(t, repository.ProductDataJSON, repository.ProductDataFormat(config.SeedConfiguration{URL: "https://example.com/export?format=csv"}))
+		assert.Equal(t, repository.ProductDataCSV, repository.ProductDataFormat(config.SeedConfiguration{URL: "https://example.com/export", Format: "csv"}))
+	})
+}
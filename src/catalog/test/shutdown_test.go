@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+)
+
+// stuckSearch is a search repository whose bulk indexing only returns once
+// its context is cancelled
+type stuckSearch struct {
+	bulkSearch
+}
+
+func (s *stuckSearch) IndexProducts(products []model.Product, ctx context.Context) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestShutdown(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	t.Run("Waits for jobs to finish", func(t *testing.T) {
+		search := &bulkSearch{release: make(chan struct{})}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		time.AfterFunc(50*time.Millisecond, func() { close(search.release) })
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		require.NoError(t, catalogAPI.Shutdown(shutdownCtx))
+
+		job, err = catalogAPI.GetReindexJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ReindexCompleted, job.Status)
+
+		_, err = catalogAPI.StartReindex(ctx)
+		assert.ErrorIs(t, err, api.ErrShuttingDown)
+
+		_, err = catalogAPI.StartImport([]model.Product{{ID: "shutdown-1", Name: "Late", Price: model.Money{Amount: 1}}}, ctx)
+		assert.ErrorIs(t, err, api.ErrShuttingDown)
+	})
+
+	t.Run("Cancels jobs after the grace period", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, &stuckSearch{})
+		require.NoError(t, err)
+
+		job, err := catalogAPI.StartReindex(ctx)
+		require.NoError(t, err)
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, catalogAPI.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+		job = waitForReindex(t, catalogAPI, job.ID)
+		assert.Equal(t, model.ReindexFailed, job.Status)
+	})
+
+	t.Run("Endpoints respond 503", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, &bulkSearch{})
+		require.NoError(t, err)
+		require.NoError(t, catalogAPI.Shutdown(ctx))
+
+		c, err := controller.NewController(catalogAPI)
+		require.NoError(t, err)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.POST("/admin/reindex", c.StartReindex)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/reindex", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestSitemap(t *testing.T) {
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	catalogAPI, err := api.NewCatalogAPI(writable, nil)
+	require.NoError(t, err)
+	catalogAPI.SetSitemapOptions("https://shop.example.com/", 5)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/sitemap.xml", c.SitemapIndex)
+	r.GET("/sitemap/:file", c.SitemapPage)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Reads every page listed in the index
+	crawl := func(t *testing.T) []string {
+		w := get("/sitemap.xml")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+
+		var index model.SitemapIndex
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &index))
+
+		locations := []string{}
+		for i, entry := range index.Sitemaps {
+			assert.Equal(t, "https://shop.example.com/sitemap/products-"+string(rune('1'+i))+".xml", entry.Loc)
+
+			w := get(entry.Loc[len("https://shop.example.com"):])
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var urls model.URLSet
+			require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &urls))
+			assert.LessOrEqual(t, len(urls.URLs), 5)
+			for _, url := range urls.URLs {
+				locations = append(locations, url.Loc)
+			}
+		}
+		return locations
+	}
+
+	size, err := catalogAPI.GetSize(repository.ProductFilter{}, ctx)
+	require.NoError(t, err)
+
+	t.Run("Pages", func(t *testing.T) {
+		locations := crawl(t)
+		assert.Len(t, locations, size)
+		assert.Contains(t, locations, "https://shop.example.com/catalog/cc789f85-1476-452a-8100-9e74502198e0")
+
+		assert.Contains(t, get("/sitemap.xml").Body.String(), `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	})
+
+	t.Run("Refreshed on change", func(t *testing.T) {
+		require.NoError(t, writable.CreateProduct(&model.Product{ID: "0-sitemap", Name: "Sitemap", Price: model.Money{Amount: 10}}, ctx))
+		assert.Contains(t, crawl(t), "https://shop.example.com/catalog/0-sitemap")
+
+		require.NoError(t, writable.DeleteProduct("0-sitemap", ctx))
+		assert.NotContains(t, crawl(t), "https://shop.example.com/catalog/0-sitemap")
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		etag := get("/sitemap.xml").Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/sitemap.xml", nil)
+		req.Header.Set("If-None-Match", etag)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("Missing pages", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/sitemap/products-0.xml").Code)
+		assert.Equal(t, http.StatusNotFound, get("/sitemap/products-1000.xml").Code)
+		assert.Equal(t, http.StatusNotFound, get("/sitemap/products.xml").Code)
+		assert.Equal(t, http.StatusNotFound, get("/sitemap/products-1.json").Code)
+	})
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestSlugify(t *testing.T) {
+	for name, slug := range map[string]string{
+		"Up & Away Parasol":        "up-away-parasol",
+		"Størm Lantern":            "storm-lantern",
+		"Arrête-Temps Temporel":    "arrete-temps-temporel",
+		"  Men's  Oxford Shoes! ":  "mens-oxford-shoes",
+		"Größe 42":                 "grosse-42",
+		"ﬁne Ｗatch":                "fine-watch",
+		"時計":                       "product",
+		"":                         "product",
+		strings.Repeat("ab ", 100): strings.TrimSuffix(strings.Repeat("ab-", 33), "-"),
+	} {
+		assert.Equal(t, slug, model.Slugify(name), name)
+	}
+}
+
+func TestProductSlugs(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/catalog/products", c.CreateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/p/:slug", c.GetProductBySlug)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	get := func(url string) model.Product {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product
+	}
+
+	slugs := map[string]string{}
+	for _, body := range []string{
+		`{"id": "slug-lantern", "name": "Storm Lantern", "price": {"amount": 3000, "currency": "USD"}}`,
+		`{"id": "slug-lantern-2", "name": "Storm lantern!", "price": {"amount": 3500, "currency": "USD"}}`,
+		`{"id": "slug-lantern-3", "name": "Storm lantern (large)", "price": {"amount": 4000, "currency": "USD"}}`,
+		`{"id": "slug-draft", "name": "Storm lantern draft", "status": "draft"}`,
+	} {
+		w := send("POST", "/catalog/products", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		slugs[product.ID] = product.Slug
+	}
+	defer func() {
+		for id := range slugs {
+			send("DELETE", "/catalog/products/"+id, "")
+		}
+	}()
+
+	t.Run("Unique", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			"slug-lantern":   "storm-lantern",
+			"slug-lantern-2": "storm-lantern-2",
+			"slug-lantern-3": "storm-lantern-large",
+			"slug-draft":     "storm-lantern-draft",
+		}, slugs)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		product := get("/catalog/p/storm-lantern-2")
+		assert.Equal(t, "slug-lantern-2", product.ID)
+		assert.Equal(t, "storm-lantern-2", product.Slug)
+
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/p/storm-lantern-draft", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/p/missing-lantern", "").Code)
+	})
+
+	t.Run("Kept by updates", func(t *testing.T) {
+		w := send("PATCH", "/catalog/products/slug-lantern-2", `{"name": "Storm Lantern", "description": "Brighter"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "storm-lantern-2", product.Slug)
+	})
+
+	t.Run("Renamed", func(t *testing.T) {
+		w := send("PATCH", "/catalog/products/slug-lantern", `{"name": "Hurricane Lantern"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "hurricane-lantern", product.Slug)
+
+		w = send("GET", "/catalog/p/storm-lantern?fields=id,slug", "")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/catalog/p/hurricane-lantern?fields=id,slug", w.Header().Get("Location"))
+		assert.Equal(t, "slug-lantern", get("/catalog/p/hurricane-lantern").ID)
+
+		// The old slug isn't given to another product
+		w = send("PATCH", "/catalog/products/slug-lantern-3", `{"name": "Storm Lantern"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "storm-lantern-3", product.Slug)
+		assert.Equal(t, "/catalog/p/storm-lantern-3", send("GET", "/catalog/p/storm-lantern-large", "").Header().Get("Location"))
+
+		// but the product can take it back
+		w = send("PATCH", "/catalog/products/slug-lantern", `{"name": "Storm Lantern"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "storm-lantern", product.Slug)
+		assert.Equal(t, "/catalog/p/storm-lantern", send("GET", "/catalog/p/hurricane-lantern", "").Header().Get("Location"))
+	})
+
+	t.Run("Deleted products", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/slug-lantern-2", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/p/storm-lantern-2", "").Code)
+
+		// Deleted products keep their slugs so that they can be restored
+		w := send("POST", "/catalog/products", `{"id": "slug-lantern-4", "name": "Storm Lantern", "price": {"amount": 3000, "currency": "USD"}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		slugs["slug-lantern-4"] = ""
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "storm-lantern-4", product.Slug)
+	})
+}
+
+func TestDualWrite_Slugs(t *testing.T) {
+	index := &partialIndex{fakeIndex: newFakeIndex()}
+	writable, ok := newInMemoryRepository(t).(repository.WritableCatalogRepository)
+	require.True(t, ok)
+	repo := repository.NewDualWriteRepository(writable, index)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateProduct(&model.Product{ID: "dual-write-slug", Name: "Dual write lamp", Price: model.Money{Amount: 10}}, ctx))
+	defer repo.DeleteProduct("dual-write-slug", ctx)
+	assert.Equal(t, "dual-write-lamp", index.docs["dual-write-slug"].Slug)
+
+	_, err := repo.PatchProduct("dual-write-slug", func(product *model.Product) error {
+		product.Name = "Dual write desk lamp"
+		return nil
+	}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "slug", "updatedAt"}, index.updates[len(index.updates)-1])
+	assert.Equal(t, "dual-write-desk-lamp", index.docs["dual-write-slug"].Slug)
+
+	product, err := repo.GetProductBySlug("dual-write-lamp", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "dual-write-desk-lamp", product.Slug)
+}
+
+func TestOpenSearchSlugs(t *testing.T) {
+	var mapping, indexed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && r.URL.Path == "/products":
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			indexed = string(body)
+			io.WriteString(w, `{"result": "updated"}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	assert.JSONEq(t, `{"type": "keyword"}`, string(index.Mappings.Properties["slug"]))
+
+	product := model.Product{ID: "lamp", Name: "Desk lamp", Slug: "desk-lamp"}
+	require.NoError(t, search.IndexProduct(product, context.Background()))
+	var doc struct {
+		Slug string `json:"slug"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(indexed), &doc))
+	assert.Equal(t, "desk-lamp", doc.Slug)
+
+	require.NoError(t, search.UpdateProductFields(product, []string{"slug"}, context.Background()))
+	assert.JSONEq(t, `{"doc": {"slug": "desk-lamp"}}`, indexed)
+}
@@ -0,0 +1,200 @@
+//go:build sqlite_fts5
+
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func newSQLiteRepository(t *testing.T) *repository.SQLiteRepository {
+	repo, err := repository.NewSQLiteRepository(config.DatabaseConfiguration{
+		Type: "sqlite",
+		Path: filepath.Join(t.TempDir(), "catalog.db"),
+	})
+	require.NoError(t, err)
+
+	return repo
+}
+
+func TestSQLiteRepository_Products(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	product, err := repo.GetProduct("cc789f85-1476-452a-8100-9e74502198e0", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Temporal Tickstopper", product.Name)
+
+	products, err := repo.GetProducts(repository.ProductFilter{}, "", 1, 10, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 10, len(products))
+}
+
+func TestSQLiteRepository_Search(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	t.Run("Matches name", func(t *testing.T) {
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper"}, 1, 10, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.NotEmpty(t, products)
+		assert.Equal(t, "Temporal Tickstopper", products[0].Name)
+	})
+
+	t.Run("Matches prefix", func(t *testing.T) {
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "tick"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Products)
+	})
+
+	t.Run("Ignores query syntax", func(t *testing.T) {
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: `"NEAR(`}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Empty(t, result.Products)
+	})
+
+	t.Run("Attributes", func(t *testing.T) {
+		seats := 2.0
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "features", Attributes: []model.AttributeFilter{{Name: "seats", Min: &seats}}, Facets: true}, 1, 10, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.Len(t, products, 2)
+		assert.ElementsMatch(t, []string{"Aqua Ace GT", "Phantom Pursuit"}, []string{products[0].Name, products[1].Name})
+		assert.Equal(t, map[string]int{"2": 1, "4": 1}, result.Facets[repository.FacetAttributePrefix+"seats"])
+	})
+
+	t.Run("Translations", func(t *testing.T) {
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "Schwebe"}, 1, 10, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.Len(t, products, 1)
+		assert.Equal(t, "Levitator Oxfords", products[0].Name)
+		assert.Len(t, products[0].Translations, 2)
+	})
+
+	t.Run("Ratings", func(t *testing.T) {
+		minRating := 4.0
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "the", MinRating: &minRating, Order: repository.OrderRatingDesc}, 1, 20, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.NotEmpty(t, products)
+		for i, product := range products {
+			assert.GreaterOrEqual(t, product.Rating.Average, 4.0)
+			if i > 0 {
+				assert.LessOrEqual(t, product.Rating.Average, products[i-1].Rating.Average)
+			}
+		}
+	})
+
+	t.Run("Statuses", func(t *testing.T) {
+		setStatus := func(status string) {
+			_, err := repo.PatchProduct("cc789f85-1476-452a-8100-9e74502198e0", func(product *model.Product) error {
+				product.Status = status
+				return nil
+			}, ctx)
+			require.NoError(t, err)
+		}
+
+		setStatus(model.StatusDiscontinued)
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Empty(t, result.Products)
+
+		result, err = repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper", AnyStatus: true}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Products)
+
+		setStatus(model.StatusActive)
+	})
+
+	t.Run("SKUs", func(t *testing.T) {
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "LEV-OXF-BLK-10"}, 1, 10, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.NotEmpty(t, products)
+		assert.Equal(t, "Levitator Oxfords", products[0].Name)
+	})
+
+	t.Run("On sale", func(t *testing.T) {
+		sale := repository.Sale{Tags: []string{"vehicles"}}
+		onSale, notOnSale := true, false
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "features", OnSale: &onSale, Sale: sale}, 1, 10, ctx)
+		require.NoError(t, err)
+		products := result.Products
+		require.NotEmpty(t, products)
+		for _, product := range products {
+			assert.Equal(t, "vehicles", product.Tags[0].Name)
+		}
+
+		result, err = repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper", OnSale: &notOnSale, Sale: sale}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Products)
+
+		result, err = repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper", OnSale: &onSale}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.Empty(t, result.Products, "nothing is on sale without promotions")
+	})
+
+	t.Run("Reindex", func(t *testing.T) {
+		require.NoError(t, repo.Reindex(context.Background()))
+
+		result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "tickstopper"}, 1, 10, ctx)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Products)
+	})
+}
+
+func TestSQLiteRepository_CustomSeedData(t *testing.T) {
+	useProductData(t, customProducts, repository.ProductDataJSON)
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	products, err := repo.GetProducts(repository.ProductFilter{}, "", 1, 10, ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"workshop-1", "workshop-2"}, productIDs(products))
+
+	result, err := repo.SearchProducts(repository.SearchQuery{Keyword: "widget"}, 1, 10, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"workshop-1"}, productIDs(result.Products))
+}
+
+func TestSQLiteRepository_LegacyPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.db")
+
+	// A database created before prices had a currency, holding whole dollars
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE products (id text PRIMARY KEY, name text, description text, price integer, category_name text, version integer NOT NULL DEFAULT 1)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO products (id, name, price) VALUES ('legacy-1', 'Legacy', 12)`).Error)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	repo, err := repository.NewSQLiteRepository(config.DatabaseConfiguration{Type: "sqlite", Path: path})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	product, err := repo.GetProduct("legacy-1", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, model.Money{Amount: 1200, Currency: "USD"}, product.Price)
+
+	// Only once
+	require.NoError(t, repo.Close())
+	repo, err = repository.NewSQLiteRepository(config.DatabaseConfiguration{Type: "sqlite", Path: path})
+	require.NoError(t, err)
+
+	product, err = repo.GetProduct("legacy-1", ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1200, product.Price.Amount)
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/middleware"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// fakeDogStatsD listens for DogStatsD packets, returning a client sending to
+// it and a function that flushes the client and returns the lines received
+func fakeDogStatsD(t *testing.T) (*statsd.Client, func() []string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	client, err := statsd.New(conn.LocalAddr().String(), statsd.WithoutTelemetry(), statsd.WithoutOriginDetection())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client, func() []string {
+		require.NoError(t, client.Flush())
+
+		var lines []string
+		buffer := make([]byte, 65536)
+		for {
+			conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return lines
+			}
+			lines = append(lines, strings.Split(strings.TrimSpace(string(buffer[:n])), "\n")...)
+		}
+	}
+}
+
+// hasMetric reports whether a line is for the metric with all the tags
+func hasMetric(lines []string, prefix string, tags ...string) bool {
+	for _, line := range lines {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		matched := true
+		for _, tag := range tags {
+			matched = matched && strings.Contains(line, tag)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsDRequestMetrics(t *testing.T) {
+	client, received := fakeDogStatsD(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.NewStatsDRequestMetrics(client))
+	r.GET("/products/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/products/a1", "/products/b2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	lines := received()
+	assert.True(t, hasMetric(lines, "catalog.http.requests:2|c", "method:GET", "route:/products/:id", "status:200"), lines)
+	assert.True(t, hasMetric(lines, "catalog.http.requests:1|c", "route:unmatched", "status:404"), lines)
+	assert.True(t, hasMetric(lines, "catalog.http.request.duration:", "|d|", "route:/products/:id"), lines)
+}
+
+func TestStatsDRepositoryMetrics(t *testing.T) {
+	client, received := fakeDogStatsD(t)
+	repository.RegisterStatsDMetrics(client)
+	t.Cleanup(func() { repository.RegisterStatsDMetrics(nil) })
+
+	db := newInMemoryRepository(t)
+	_, err := db.GetProduct("missing", context.Background())
+	require.Error(t, err)
+
+	lines := received()
+	assert.True(t, hasMetric(lines, "catalog.repository.operation.duration:", "|d|", "repository:database", "operation:query", "outcome:success"), lines)
+}
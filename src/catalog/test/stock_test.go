@@ -0,0 +1,210 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestStock(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.POST("/catalog/products/:id/stock", c.AdjustStock)
+	r.GET("/catalog/size", c.CatalogSize)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	adjust := func(id, body string) model.Product {
+		w := send("POST", "/catalog/products/"+id+"/stock", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product
+	}
+	list := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		return productIDs(products)
+	}
+
+	const tickstopper = "cc789f85-1476-452a-8100-9e74502198e0"
+	const oxfords = "4f18544b-70a5-4352-8e19-0d070f46745d"
+	const quill = "d27cf49f-b689-4a75-a249-d373e0330bb5"
+	const skycycle = "d3104128-1d14-4465-99d3-8ab9267c687b"
+
+	t.Run("Seeded stock", func(t *testing.T) {
+		product, err := db.GetProduct(tickstopper, context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, product.Stock)
+		assert.Equal(t, 7, *product.Stock)
+		assert.True(t, product.InStock())
+
+		product, err = db.GetProduct(oxfords, context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 25, *product.Stock, "products with variants have the stock of all of them")
+	})
+
+	t.Run("Filter products", func(t *testing.T) {
+		assert.Equal(t, []string{skycycle, quill}, list("/catalog/products?inStock=false&sort=name"))
+		assert.Len(t, list("/catalog/products?inStock=true&size=20"), 10)
+
+		w := send("GET", "/catalog/size?inStock=false", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"size": 2}`, w.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products?inStock=maybe", "").Code)
+	})
+
+	t.Run("Adjust stock", func(t *testing.T) {
+		assert.Equal(t, 4, *adjust(tickstopper, `{"delta": -3}`).Stock)
+		assert.Equal(t, 10, *adjust(tickstopper, `{"delta": 6}`).Stock)
+		assert.Equal(t, 0, *adjust(tickstopper, `{"stock": 0}`).Stock)
+
+		w := send("POST", "/catalog/products/"+tickstopper+"/stock", `{"delta": -1}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Contains(t, list("/catalog/products?inStock=false"), tickstopper)
+
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/catalog/products/"+tickstopper+"/stock", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/catalog/products/"+tickstopper+"/stock", `{"delta": 1, "stock": 1}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/catalog/products/"+tickstopper+"/stock", `{"stock": -1}`).Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/catalog/products/missing/stock", `{"delta": 1}`).Code)
+	})
+
+	t.Run("Adjust variant stock", func(t *testing.T) {
+		product := adjust(oxfords, `{"sku": "LEV-OXF-BRN-10", "delta": 2}`)
+		assert.Equal(t, 27, *product.Stock)
+		for _, variant := range product.Variants {
+			if variant.SKU == "LEV-OXF-BRN-10" {
+				assert.Equal(t, 2, variant.Stock)
+			}
+		}
+
+		product = adjust(oxfords, `{"sku": "LEV-OXF-BLK-9", "stock": 0}`)
+		assert.Equal(t, 15, *product.Stock)
+
+		w := send("POST", "/catalog/products/"+oxfords+"/stock", `{"sku": "LEV-OXF-BRN-9", "delta": -6}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		w = send("POST", "/catalog/products/"+oxfords+"/stock", `{"sku": "LEV-OXF-GLD-9", "delta": 1}`)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		w = send("POST", "/catalog/products/"+oxfords+"/stock", `{"delta": 1}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("Patch keeps stock", func(t *testing.T) {
+		adjust(quill, `{"stock": 3}`)
+		w := send("PATCH", "/catalog/products/"+quill, `{"name": "The Loud Quill"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, 3, *product.Stock)
+	})
+
+	t.Run("Search facets", func(t *testing.T) {
+		search := func(url string) model.SearchResponse {
+			w := send("GET", url, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response model.SearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response
+		}
+
+		facets := map[string]map[string]int{
+			repository.FacetInStock:                      {"true": 0, "false": 1},
+			repository.FacetAttributePrefix + "brand":    {"Velocity Motors": 1},
+			repository.FacetAttributePrefix + "material": {"titanium": 1},
+			repository.FacetAttributePrefix + "seats":    {"1": 1},
+		}
+
+		response := search("/v2/catalog/search?keyword=skycycle")
+		assert.Equal(t, []string{skycycle}, productIDs(response.Products))
+		assert.Equal(t, facets, response.Facets)
+
+		// Filtering on stock leaves the facet counting both buckets
+		response = search("/v2/catalog/search?keyword=skycycle&inStock=true")
+		assert.Empty(t, response.Products)
+		assert.Equal(t, facets, response.Facets)
+	})
+}
+
+func TestOpenSearchStock(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "_source": {
+			"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}, "stock": 3
+		}}]}, "aggregations": {"in_stock": {"buckets": {"true": {"doc_count": 1}, "false": {"doc_count": 4}}}}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	inStock := true
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "tee", InStock: &inStock, Facets: true}, 1, 10, context.Background())
+	require.NoError(t, err)
+
+	var request struct {
+		PostFilter json.RawMessage `json:"post_filter"`
+		Aggs       json.RawMessage `json:"aggs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(query), &request))
+	assert.JSONEq(t, `{"range": {"stock": {"gt": 0}}}`, string(request.PostFilter))
+	assert.JSONEq(t, `{
+		"in_stock": {"filters": {"filters": {
+			"true": {"range": {"stock": {"gt": 0}}},
+			"false": {"range": {"stock": {"lte": 0}}}
+		}}},
+		"attributes": {"nested": {"path": "attributes"}, "aggs": {"names": {
+			"terms": {"field": "attributes.name", "size": 50},
+			"aggs": {"values": {"terms": {"field": "attributes.value", "size": 20}}}
+		}}}
+	}`, string(request.Aggs))
+
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, 3, *result.Products[0].Stock)
+	assert.Equal(t, map[string]map[string]int{repository.FacetInStock: {"true": 1, "false": 4}}, result.Facets)
+}
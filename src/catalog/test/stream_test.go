@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// pitCluster is an OpenSearch server holding products p00 to p24 that
+// answers searches sorted by ID, through a point in time if pits is true
+type pitCluster struct {
+	pits bool
+
+	mu        sync.Mutex
+	requests  []string
+	keepAlive string
+	searched  []string
+	closed    []string
+}
+
+func (c *pitCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/":
+		io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+	case r.URL.Path == "/products/_search/point_in_time":
+		if !c.pits {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error": "no handler found for uri [/products/_search/point_in_time] and method [POST]"}`)
+			return
+		}
+		c.keepAlive = r.URL.Query().Get("keep_alive")
+		io.WriteString(w, `{"pit_id": "pit-0"}`)
+	case r.URL.Path == "/_search/point_in_time" && r.Method == http.MethodDelete:
+		var body struct {
+			PitID []string `json:"pit_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		c.closed = append(c.closed, body.PitID...)
+		io.WriteString(w, `{"pits": []}`)
+	default:
+		var query struct {
+			Size int `json:"size"`
+			Pit  *struct {
+				ID string `json:"id"`
+			} `json:"pit"`
+			SearchAfter []interface{} `json:"search_after"`
+		}
+		json.NewDecoder(r.Body).Decode(&query)
+
+		// Every batch is answered with the next point in time ID
+		pit := ""
+		if query.Pit != nil {
+			c.searched = append(c.searched, query.Pit.ID)
+			pit = fmt.Sprintf("pit-%d", len(c.searched))
+		}
+
+		start := 0
+		if len(query.SearchAfter) == 2 {
+			fmt.Sscanf(query.SearchAfter[1].(string), "p%d", &start)
+			start++
+		}
+
+		hits := []map[string]interface{}{}
+		for i := start; i < min(start+query.Size, 25); i++ {
+			id := fmt.Sprintf("p%02d", i)
+			hits = append(hits, map[string]interface{}{
+				"_id":     id,
+				"_source": map[string]interface{}{"id": id},
+				"sort":    []interface{}{1.0, id},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":   1,
+			"pit_id": pit,
+			"hits":   map[string]interface{}{"total": map[string]int{"value": 25}, "hits": hits},
+		})
+	}
+}
+
+func TestStreamSearch(t *testing.T) {
+	stream := func(t *testing.T, cluster *pitCluster) [][]string {
+		server := httptest.NewServer(cluster)
+		t.Cleanup(server.Close)
+
+		search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+			Endpoint:  server.URL,
+			IndexName: "products",
+		})
+		require.NoError(t, err)
+
+		var batches [][]string
+		err = search.StreamSearch("watch", 10, context.Background(), func(products []model.Product) error {
+			batches = append(batches, productIDs(products))
+			return nil
+		})
+		require.NoError(t, err)
+
+		return batches
+	}
+
+	t.Run("Point in time", func(t *testing.T) {
+		cluster := &pitCluster{pits: true}
+		batches := stream(t, cluster)
+
+		require.Len(t, batches, 3)
+		assert.Equal(t, "60000ms", cluster.keepAlive)
+		assert.Equal(t, "p00", batches[0][0])
+		assert.Equal(t, "p10", batches[1][0])
+		assert.Equal(t, []string{"p20", "p21", "p22", "p23", "p24"}, batches[2])
+
+		// Each batch continues from the point in time the last returned, and
+		// the latest is closed
+		assert.Equal(t, []string{"pit-0", "pit-1", "pit-2"}, cluster.searched)
+		assert.Equal(t, []string{"pit-3"}, cluster.closed)
+		assert.NotContains(t, cluster.requests, "POST /products/_search")
+	})
+
+	t.Run("Points in time not supported", func(t *testing.T) {
+		cluster := &pitCluster{}
+		batches := stream(t, cluster)
+
+		require.Len(t, batches, 3)
+		assert.Len(t, batches[2], 5)
+		assert.Contains(t, cluster.requests, "POST /products/_search")
+		assert.Empty(t, cluster.closed)
+	})
+
+	t.Run("Callback fails", func(t *testing.T) {
+		cluster := &pitCluster{pits: true}
+		server := httptest.NewServer(cluster)
+		t.Cleanup(server.Close)
+
+		search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+			Endpoint:  server.URL,
+			IndexName: "products",
+		})
+		require.NoError(t, err)
+
+		failure := fmt.Errorf("client went away")
+		err = search.StreamSearch("watch", 10, context.Background(), func([]model.Product) error {
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		// The point in time is still closed
+		assert.Equal(t, []string{"pit-1"}, cluster.closed)
+	})
+
+	t.Run("Search providers that can't stream", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &stubSearch{ids: []string{"a1", "a2"}})
+		require.NoError(t, err)
+
+		var batches [][]string
+		err = catalogAPI.StreamSearch("watch", 10, context.Background(), func(products []model.Product) error {
+			batches = append(batches, productIDs(products))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"a1", "a2"}}, batches)
+	})
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// countingSearch is a search repository that reports fixed tag counts
+type countingSearch struct {
+	stubSearch
+	counts map[string]int
+}
+
+func (s *countingSearch) CountProductsByTag(ctx context.Context) (map[string]int, error) {
+	return s.counts, s.err
+}
+
+func TestCountProductsByTag(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	counts, err := db.CountProductsByTag(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, counts)
+
+	for tag, count := range counts {
+		filtered, err := db.CountProducts(repository.ProductFilter{Tags: []string{tag}}, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, filtered, count, tag)
+	}
+}
+
+func TestGetTagCounts(t *testing.T) {
+	db := newInMemoryRepository(t)
+	ctx := context.Background()
+
+	tags, err := db.GetTags(ctx)
+	require.NoError(t, err)
+
+	expected, err := db.CountProductsByTag(ctx)
+	require.NoError(t, err)
+
+	t.Run("From the catalog", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(db, nil)
+		require.NoError(t, err)
+
+		result, err := catalogAPI.GetTagCounts(ctx)
+		require.NoError(t, err)
+		require.Len(t, result, len(tags))
+
+		for i, tag := range result {
+			assert.Equal(t, tags[i], tag.Tag)
+			assert.Equal(t, expected[tag.Name], tag.ProductCount)
+		}
+	})
+
+	t.Run("From the search index", func(t *testing.T) {
+		search := &countingSearch{counts: map[string]int{tags[0].Name: 42}}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		result, err := catalogAPI.GetTagCounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 42, result[0].ProductCount)
+		assert.Equal(t, 0, result[1].ProductCount)
+	})
+
+	t.Run("Search index unavailable", func(t *testing.T) {
+		search := &countingSearch{stubSearch: stubSearch{err: errors.New("unavailable")}}
+		catalogAPI, err := api.NewCatalogAPI(db, search)
+		require.NoError(t, err)
+
+		result, err := catalogAPI.GetTagCounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected[result[0].Name], result[0].ProductCount)
+	})
+}
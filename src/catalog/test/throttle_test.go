@@ -0,0 +1,208 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// overloadedCluster is an OpenSearch server that accepts at most capacity
+// documents in each bulk request, rejecting the rest with 429, until it has
+// rejected overloaded requests
+type overloadedCluster struct {
+	capacity   int
+	overloaded atomic.Int32
+	rejectAll  atomic.Bool
+
+	mu      sync.Mutex
+	batches []int
+	indexed map[string]bool
+	writes  int
+}
+
+func newOverloadedCluster(t *testing.T, capacity int, overloaded int32, retries int) (*overloadedCluster, *repository.OpenSearchRepository) {
+	cluster := &overloadedCluster{capacity: capacity, indexed: map[string]bool{}}
+	cluster.overloaded.Store(overloaded)
+
+	server := httptest.NewServer(http.HandlerFunc(cluster.serve))
+	t.Cleanup(server.Close)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:         server.URL,
+		IndexName:        "products",
+		BulkRetries:      retries,
+		BulkMinBatchSize: 10,
+		BulkMaxDelay:     20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	return cluster, search
+}
+
+func (c *overloadedCluster) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/":
+		io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+	case r.URL.Path == "/_bulk":
+		c.bulk(w, r)
+	case strings.HasPrefix(r.URL.Path, "/products/_doc/"):
+		c.mu.Lock()
+		c.writes++
+		c.mu.Unlock()
+
+		if c.overloaded.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"error": {"type": "es_rejected_execution_exception"}, "status": 429}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"result": "created"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *overloadedCluster) bulk(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action struct {
+			Index *struct {
+				ID string `json:"_id"`
+			} `json:"index"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &action) == nil && action.Index != nil {
+			ids = append(ids, action.Index.ID)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, len(ids))
+
+	if c.rejectAll.Load() {
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error": {"type": "es_rejected_execution_exception"}, "status": 429}`)
+		return
+	}
+
+	overloaded := c.overloaded.Add(-1) >= 0
+	items := make([]string, len(ids))
+	errors := false
+	for i, id := range ids {
+		if overloaded && i >= c.capacity {
+			items[i] = `{"index": {"_id": "` + id + `", "status": 429, "error": {"type": "es_rejected_execution_exception"}}}`
+			errors = true
+			continue
+		}
+		c.indexed[id] = true
+		items[i] = `{"index": {"_id": "` + id + `", "status": 201}}`
+	}
+	fmt.Fprintf(w, `{"errors": %t, "items": [%s]}`, errors, strings.Join(items, ","))
+}
+
+func (c *overloadedCluster) sent() ([]int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]int{}, c.batches...), len(c.indexed)
+}
+
+func throttleProducts(n int) []model.Product {
+	products := make([]model.Product, n)
+	for i := range products {
+		products[i] = model.Product{ID: fmt.Sprintf("p%03d", i), Name: "Watch"}
+	}
+	return products
+}
+
+func TestBulkThrottle(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, repository.RegisterMetrics(nil, registry))
+
+	ctx := context.Background()
+
+	t.Run("Rejected documents are sent again in smaller batches", func(t *testing.T) {
+		cluster, search := newOverloadedCluster(t, 30, 3, 5)
+
+		failed, err := search.IndexProducts(throttleProducts(100), ctx)
+		require.NoError(t, err)
+		assert.Zero(t, failed)
+
+		batches, indexed := cluster.sent()
+		assert.Equal(t, 100, indexed)
+		assert.Equal(t, []int{100, 50, 20, 20}, batches)
+
+		status, _ := search.Status(ctx)
+		assert.Equal(t, map[string]any{"batchSize": 25, "delay": "0s"}, status["throttle"])
+
+		// Batches grow back once the cluster has caught up
+		cluster.mu.Lock()
+		cluster.batches = nil
+		cluster.mu.Unlock()
+
+		failed, err = search.IndexProducts(throttleProducts(100), ctx)
+		require.NoError(t, err)
+		assert.Zero(t, failed)
+		batches, _ = cluster.sent()
+		assert.Equal(t, []int{25, 50, 25}, batches)
+
+		status, _ = search.Status(ctx)
+		assert.NotContains(t, status, "throttle")
+	})
+
+	t.Run("Documents still rejected after the retries fail", func(t *testing.T) {
+		cluster, search := newOverloadedCluster(t, 0, 0, 2)
+		cluster.rejectAll.Store(true)
+
+		failed, err := search.IndexProducts(throttleProducts(40), ctx)
+		require.NoError(t, err, "an overloaded cluster doesn't fail the whole batch")
+		assert.Equal(t, 40, failed)
+
+		batches, indexed := cluster.sent()
+		assert.Zero(t, indexed)
+		assert.Equal(t, []int{40, 20, 10, 10, 10, 10, 10, 10}, batches)
+
+		status, _ := search.Status(ctx)
+		assert.Equal(t, map[string]any{"batchSize": 10, "delay": "20ms"}, status["throttle"])
+	})
+
+	t.Run("Single writes are sent again", func(t *testing.T) {
+		cluster, search := newOverloadedCluster(t, 0, 2, 5)
+
+		require.NoError(t, search.IndexProduct(model.Product{ID: "a1", Name: "Watch"}, ctx))
+		assert.Equal(t, 3, cluster.writes)
+	})
+
+	t.Run("Single writes give up", func(t *testing.T) {
+		cluster, search := newOverloadedCluster(t, 0, 5, 1)
+
+		assert.Error(t, search.IndexProduct(model.Product{ID: "a1", Name: "Watch"}, ctx))
+		assert.Equal(t, 2, cluster.writes)
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP catalog_index_rejected_documents_total Documents OpenSearch rejected because its write queues were full, each of which is retried more slowly
+# TYPE catalog_index_rejected_documents_total counter
+catalog_index_rejected_documents_total 214
+`), "catalog_index_rejected_documents_total"))
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate for localhost and returns it
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	first := writeCertificate(t, certFile, keyFile, "first")
+
+	reloader, err := httputil.NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	current := func() string {
+		certificate, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		return certificate.Leaf.Subject.CommonName
+	}
+	assert.Equal(t, first.Subject.CommonName, current())
+
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(10*time.Millisecond, ctx)
+
+	writeCertificate(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	assert.Eventually(t, func() bool { return current() == "second" }, 2*time.Second, 10*time.Millisecond)
+
+	t.Run("Invalid files keep the previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0600))
+		require.NoError(t, os.Chtimes(keyFile, later, later))
+
+		_, err := reloader.Reload()
+		assert.Error(t, err)
+		assert.Equal(t, "second", current())
+	})
+
+	t.Run("Missing files", func(t *testing.T) {
+		_, err := httputil.NewCertificateReloader(filepath.Join(dir, "missing.crt"), keyFile)
+		assert.Error(t, err)
+	})
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	certificate := writeCertificate(t, certFile, keyFile, "catalog")
+
+	reloader, err := httputil.NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+		Protocols: httputil.Protocols(config.HTTP2Configuration{Enabled: true}),
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "localhost"},
+		ForceAttemptHTTP2: true,
+	}}
+
+	resp, err := client.Get("https://" + listener.Addr().String())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+}
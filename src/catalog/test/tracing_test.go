@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestOpenSearchTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var explained []string
+	server := fakeOpenSearch(t, &explained)
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{
+		Endpoint:  server.URL,
+		IndexName: "products",
+	})
+	require.NoError(t, err)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "GET /catalog/search")
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "watch"}, 1, 2, ctx)
+	parent.End()
+	require.NoError(t, err)
+	require.Len(t, result.Products, 2)
+
+	var operation, request sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "OpenSearch search":
+			operation = span
+		case "OpenSearch HTTP POST":
+			request = span
+		}
+	}
+	require.NotNil(t, operation)
+	require.NotNil(t, request)
+
+	// The search is part of the request's trace, and the HTTP call part of the search
+	assert.Equal(t, parent.SpanContext().TraceID(), operation.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), operation.Parent().SpanID())
+	assert.Equal(t, operation.SpanContext().SpanID(), request.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, request.SpanKind())
+	assert.Equal(t, "opensearch", spanAttributes(request)["peer.service"].AsString())
+
+	attributes := spanAttributes(operation)
+	assert.Equal(t, "opensearch", attributes["db.system"].AsString())
+	assert.Equal(t, "products", attributes["opensearch.index"].AsString())
+	assert.Equal(t, "multi_match", attributes["opensearch.query.type"].AsString())
+	assert.EqualValues(t, 2, attributes["opensearch.hits"].AsInt64())
+	assert.EqualValues(t, 2, attributes["opensearch.hits.total"].AsInt64())
+
+	t.Run("Errors", func(t *testing.T) {
+		recorder.Reset()
+
+		// The fake cluster doesn't accept documents
+		err := search.IndexProduct(model.Product{ID: "a1"}, context.Background())
+		require.Error(t, err)
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		failed := spans[len(spans)-1]
+		assert.Equal(t, "OpenSearch index", failed.Name())
+		assert.Equal(t, "a1", spanAttributes(failed)["catalog.product.id"].AsString())
+		assert.Equal(t, "Error", failed.Status().Code.String())
+	})
+}
@@ -0,0 +1,218 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestTranslations(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, language, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if language != "" {
+			req.Header.Set("Accept-Language", language)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	product := func(url, language string) (model.Product, http.Header) {
+		w := send("GET", url, language, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product, w.Header()
+	}
+
+	const oxfords = "4f18544b-70a5-4352-8e19-0d070f46745d"
+
+	t.Run("Negotiation", func(t *testing.T) {
+		p, header := product("/catalog/products/"+oxfords, "")
+		assert.Equal(t, "Levitator Oxfords", p.Name)
+		assert.Equal(t, "en", header.Get("Content-Language"))
+		assert.Contains(t, header.Values("Vary"), "Accept-Language")
+		assert.Len(t, p.Translations, 2)
+
+		p, header = product("/catalog/products/"+oxfords, "fr-CA, de;q=0.5")
+		assert.Equal(t, "Richelieus Lévitateurs", p.Name)
+		assert.Contains(t, p.Description, "antigravité")
+		assert.Equal(t, "fr", header.Get("Content-Language"))
+
+		p, _ = product("/catalog/products/"+oxfords, "ja, de;q=0.5, fr;q=0.2")
+		assert.Equal(t, "Schwebe-Oxfords", p.Name)
+
+		p, header = product("/catalog/products/"+oxfords+"?locale=de", "fr")
+		assert.Equal(t, "Schwebe-Oxfords", p.Name)
+		assert.Equal(t, "de", header.Get("Content-Language"))
+
+		// Products without a translation keep their own name
+		p, header = product("/catalog/products/"+oxfords, "es")
+		assert.Equal(t, "Levitator Oxfords", p.Name)
+		assert.Equal(t, "es", header.Get("Content-Language"))
+
+		w := send("GET", "/catalog/products/"+oxfords+"?locale=ja", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = send("GET", "/catalog/products?size=20", "de", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Contains(t, productNames(products), "Phantomjagd")
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		search := func(url, language string) []string {
+			w := send("GET", url, language, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response model.SearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return productNames(response.Products)
+		}
+
+		assert.Empty(t, search("/v2/catalog/search?keyword=schwebe", ""))
+		assert.Equal(t, []string{"Schwebe-Oxfords"}, search("/v2/catalog/search?keyword=schwebe", "de"))
+		assert.Empty(t, search("/v2/catalog/search?keyword=schwebe", "fr"))
+
+		// Names are localized even when only they are selected
+		w := send("GET", "/v2/catalog/search?keyword=schwebe&fields=name", "de", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"name":"Schwebe-Oxfords"`)
+		assert.NotContains(t, w.Body.String(), "translations")
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		w := send("POST", "/catalog/products", "", `{"id": "plain-tee", "name": "Tee", "description": "A plain tee", "translations": {
+			"fr": {"name": "T-shirt", "description": "Un t-shirt uni"},
+			"de": {"name": "T-Shirt"}
+		}}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		p, _ := product("/catalog/products/plain-tee", "de")
+		assert.Equal(t, "T-Shirt", p.Name)
+		assert.Equal(t, "A plain tee", p.Description, "a translation without a description keeps the product's")
+
+		// Translations are merged like other objects in a patch
+		w = send("PATCH", "/catalog/products/plain-tee", "", `{"translations": {"es": {"name": "Camiseta"}, "fr": null}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		p, _ = product("/catalog/products/plain-tee", "")
+		assert.Equal(t, []model.ProductTranslation{{Locale: "de", Name: "T-Shirt"}, {Locale: "es", Name: "Camiseta"}}, p.Translations)
+
+		w = send("POST", "/catalog/products", "", `{"name": "Tee", "translations": {"ja": {"name": "Tシャツ"}}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", "", `{"name": "Tee", "translations": {"fr": {"description": "Sans nom"}}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+}
+
+func TestOpenSearchTranslations(t *testing.T) {
+	var mapping, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			mapping = string(body)
+			io.WriteString(w, `{"acknowledged": true}`)
+		default:
+			query = string(body)
+			io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "_source": {
+				"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"},
+				"i18n": {"fr": {"name": "T-shirt"}, "de": {"name": "T-Shirt", "description": "Ein T-Shirt"}}
+			}}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	require.NoError(t, search.ResetIndex(context.Background()))
+	var index struct {
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(mapping), &index))
+	var i18n struct {
+		Properties map[string]struct {
+			Properties map[string]struct {
+				Analyzer string `json:"analyzer"`
+			} `json:"properties"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(index.Mappings.Properties["i18n"], &i18n))
+	assert.Equal(t, "german", i18n.Properties["de"].Properties["name"].Analyzer)
+	assert.Equal(t, "french", i18n.Properties["fr"].Properties["description"].Analyzer)
+	for _, locale := range model.Locales {
+		assert.Contains(t, i18n.Properties, locale)
+	}
+
+	result, err := search.SearchProducts(repository.SearchQuery{Keyword: "shirt", Locale: "de", Fields: []string{"name", "translations"}}, 1, 10, context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "shirt", "fields": ["name^2", "description", "tags", "i18n.de.name^2", "i18n.de.description"], "fuzziness": "AUTO"}},
+			"filter": {"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+		}
+	}`, searchQueryOf(t, query))
+
+	var request struct {
+		Source []string `json:"_source"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(query), &request))
+	assert.Equal(t, []string{"id", "name", "i18n"}, request.Source)
+
+	products := result.Products
+	require.Len(t, products, 1)
+	assert.Equal(t, []model.ProductTranslation{
+		{ProductID: "p1", Locale: "de", Name: "T-Shirt", Description: "Ein T-Shirt"},
+		{ProductID: "p1", Locale: "fr", Name: "T-shirt"},
+	}, products[0].Translations)
+	assert.Equal(t, "T-Shirt", products[0].Localized("de").Name)
+}
+
+func productNames(products []model.Product) []string {
+	names := []string{}
+	for _, product := range products {
+		names = append(names, product.Name)
+	}
+	return names
+}
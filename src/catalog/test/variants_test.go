@@ -0,0 +1,230 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/currency"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestVariants(t *testing.T) {
+	db := newInMemoryRepository(t)
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+	c.SetExchangeRates(currency.New(map[string]float64{"EUR": 0.5}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog/products", c.GetProducts)
+	r.POST("/catalog/products", c.CreateProduct)
+	r.GET("/catalog/products/:id", c.GetProduct)
+	r.PUT("/catalog/products/:id", c.UpdateProduct)
+	r.PATCH("/catalog/products/:id", c.PatchProduct)
+	r.DELETE("/catalog/products/:id", c.DeleteProduct)
+	r.GET("/catalog/products/:id/variants", c.GetProductVariants)
+	r.GET("/catalog/size", c.CatalogSize)
+	r.GET("/v2/catalog/search", controller.APIVersion(2), c.SearchProductsV2)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	variants := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var variants []model.Variant
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &variants))
+		skus := []string{}
+		for _, variant := range variants {
+			skus = append(skus, variant.SKU)
+		}
+		return skus
+	}
+	list := func(url string) []string {
+		w := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		return productIDs(products)
+	}
+
+	const oxfords = "4f18544b-70a5-4352-8e19-0d070f46745d"
+	const formalWear = "79bce3f3-935f-4912-8c62-0d2f3e059405"
+
+	t.Run("Seeded variants", func(t *testing.T) {
+		product, err := db.GetProduct(formalWear, context.Background())
+		require.NoError(t, err)
+		require.Len(t, product.Variants, 3)
+		assert.Equal(t, "FCF-BLK", product.Variants[0].SKU)
+		assert.Equal(t, product.Price, product.Variants[0].Price, "variants are priced like their product by default")
+		assert.Equal(t, model.Money{Amount: 7500, Currency: "USD"}, product.Variants[1].Price)
+		assert.Equal(t, []model.VariantAttribute{{SKU: "FCF-BLK", Name: "color", Value: "black"}}, product.Variants[0].Attributes)
+	})
+
+	t.Run("List variants", func(t *testing.T) {
+		assert.Equal(t, []string{"LEV-OXF-BLK-10", "LEV-OXF-BLK-9", "LEV-OXF-BRN-10", "LEV-OXF-BRN-9"}, variants("/catalog/products/"+oxfords+"/variants"))
+		assert.Equal(t, []string{"LEV-OXF-BRN-10", "LEV-OXF-BRN-9"}, variants("/catalog/products/"+oxfords+"/variants?variant=color:brown"))
+		assert.Equal(t, []string{"LEV-OXF-BRN-9"}, variants("/catalog/products/"+oxfords+"/variants?variant=color:brown&variant=size:9"))
+		assert.Empty(t, variants("/catalog/products/"+oxfords+"/variants?variant=color:gold"))
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/"+oxfords+"/variants?variant=color", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/catalog/products/"+oxfords+"/variants?variant=color:black&variant=color:brown", "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/catalog/products/missing/variants", "").Code)
+
+		w := send("GET", "/catalog/products/"+formalWear+"/variants?currency=EUR&variant=color:gold", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"sku": "FCF-GLD", "price": {"amount": 3750, "currency": "EUR"}, "stock": 4, "attributes": [{"name": "color", "value": "gold"}]}]`, w.Body.String())
+	})
+
+	t.Run("Filter products", func(t *testing.T) {
+		assert.Equal(t, []string{formalWear}, list("/catalog/products?variant=color:navy"))
+		assert.Equal(t, []string{oxfords, formalWear}, list("/catalog/products?variant=color:black&sort=-price"))
+
+		w := send("GET", "/catalog/size?variant=size:10", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"size": 1}`, w.Body.String())
+	})
+
+	t.Run("Attributes match on one variant", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"id": "tee", "name": "Tee", "price": {"amount": 1500, "currency": "USD"}, "variants": [
+			{"sku": "TEE-RED-S", "stock": 3, "attributes": {"color": "red", "size": "S"}},
+			{"sku": "TEE-BLU-M", "price": {"amount": 1800}, "attributes": {"color": "blue", "size": "M"}}
+		]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var product model.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		require.Len(t, product.Variants, 2)
+		assert.Equal(t, model.Money{Amount: 1800, Currency: "USD"}, product.Variants[0].Price)
+
+		assert.Equal(t, []string{"tee"}, list("/catalog/products?variant=color:red&variant=size:S"))
+		assert.Empty(t, list("/catalog/products?variant=color:red&variant=size:M"))
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		search := func(url string) []string {
+			w := send("GET", url, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response model.SearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return productIDs(response.Products)
+		}
+
+		assert.Contains(t, search("/v2/catalog/search?keyword=oxfords"), oxfords)
+		assert.Equal(t, []string{oxfords}, search("/v2/catalog/search?keyword=oxfords&variant=color:brown"))
+		assert.Empty(t, search("/v2/catalog/search?keyword=oxfords&variant=color:navy"))
+	})
+
+	t.Run("SKUs are unique", func(t *testing.T) {
+		w := send("POST", "/catalog/products", `{"name": "Copy", "variants": [{"sku": "FCF-BLK"}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "Twice", "variants": [{"sku": "TWICE"}, {"sku": "TWICE"}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+		w = send("POST", "/catalog/products", `{"name": "No SKU", "variants": [{"stock": 1}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		// Variants are kept when a replacement doesn't mention them
+		w := send("PUT", "/catalog/products/tee", `{"name": "Tee", "price": {"amount": 1600, "currency": "USD"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, variants("/catalog/products/tee/variants"), 2)
+
+		w = send("PATCH", "/catalog/products/tee", `{"name": "T-shirt"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, variants("/catalog/products/tee/variants"), 2)
+
+		w = send("PATCH", "/catalog/products/tee", `{"variants": [{"sku": "TEE-GRN-L", "attributes": {"color": "green", "size": "L"}}]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []string{"TEE-GRN-L"}, variants("/catalog/products/tee/variants"))
+		assert.Empty(t, list("/catalog/products?variant=color:red"))
+
+		w = send("PUT", "/catalog/products/tee", `{"name": "Tee", "variants": []}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, variants("/catalog/products/tee/variants"))
+
+		// A deleted product keeps its SKUs, so that it can be restored
+		require.Equal(t, http.StatusNoContent, send("DELETE", "/catalog/products/"+formalWear, "").Code)
+		w = send("POST", "/catalog/products", `{"name": "Bowtie", "variants": [{"sku": "FCF-BLK"}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+}
+
+func TestOpenSearchVariants(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			io.WriteString(w, `{"version": {"number": "2.11.0"}}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_id": "p1", "_source": {
+			"id": "p1", "name": "Tee", "price": {"amount": 1500, "currency": "USD"},
+			"variants": [{"sku": "TEE-RED-S", "price": {"amount": 1500, "currency": "USD"}, "stock": 3, "attributes": {"size": "S", "color": "red"}}]
+		}}]}}`)
+	}))
+	defer server.Close()
+
+	search, err := repository.NewOpenSearchRepository(config.OpenSearchConfiguration{Endpoint: server.URL, IndexName: "products"})
+	require.NoError(t, err)
+
+	maxPrice := 2000
+	result, err := search.SearchProducts(repository.SearchQuery{
+		Keyword:           "tee",
+		PriceRanges:       []repository.PriceRange{{Max: &maxPrice}},
+		VariantAttributes: map[string]string{"size": "S", "color": "red"},
+	}, 1, 10, context.Background())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"bool": {
+			"must": {"multi_match": {"query": "tee", "fields": ["name^2", "description", "tags"], "fuzziness": "AUTO"}},
+			"filter": [
+				{"bool": {"minimum_should_match": 1, "should": [
+					{"bool": {"filter": [{"range": {"price.amount": {"lte": 2000}}}]}}
+				]}},
+				{"nested": {"path": "variants", "query": {"bool": {"filter": [
+					{"term": {"variants.attributes.color": "red"}},
+					{"term": {"variants.attributes.size": "S"}}
+				]}}}},
+				{"bool": {"must_not": {"terms": {"status": ["draft", "discontinued"]}}}}
+			]
+		}
+	}`, searchQueryOf(t, query))
+
+	products := result.Products
+	require.Len(t, products, 1)
+	require.Len(t, products[0].Variants, 1)
+	assert.Equal(t, "p1", products[0].Variants[0].ProductID)
+	assert.Equal(t, "red", products[0].Variants[0].Attribute("color"))
+	assert.Equal(t, []string{"color", "size"}, []string{products[0].Variants[0].Attributes[0].Name, products[0].Variants[0].Attributes[1].Name})
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/secrets"
+)
+
+// fakeVault serves a KV version 2 secret and generated database credentials
+// with a one second lease
+type fakeVault struct {
+	mu        sync.Mutex
+	reads     int
+	renewals  int
+	renewable bool
+	failRenew bool
+}
+
+func (f *fakeVault) server(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"errors": ["permission denied"]}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/catalog":
+			io.WriteString(w, `{"data": {"data": {"username": "catalog", "password": "kv-secret"}, "metadata": {"version": 1}}}`)
+		case "/v1/secret/data/empty":
+			io.WriteString(w, `{"data": {"data": {"username": "catalog"}}}`)
+		case "/v1/database/creds/catalog":
+			f.reads++
+			fmt.Fprintf(w, `{"lease_id": "database/creds/catalog/%d", "renewable": %t, "lease_duration": 1,
+				"data": {"username": "v-catalog-%d", "password": "generated-%d"}}`, f.reads, f.renewable, f.reads, f.reads)
+		case "/v1/sys/leases/renew":
+			var body struct {
+				LeaseID   string `json:"lease_id"`
+				Increment int    `json:"increment"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, 1, body.Increment)
+
+			f.renewals++
+			if f.failRenew {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"errors": ["lease not found"]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"lease_id": %q, "renewable": true, "lease_duration": 1}`, body.LeaseID)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"errors": []}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeVault) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads, f.renewals
+}
+
+func TestVaultSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("KV secret", func(t *testing.T) {
+		server := (&fakeVault{}).server(t)
+
+		credentials, err := secrets.NewVault(server.URL, "root", "secret/data/catalog", time.Hour).Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, secrets.Credentials{Username: "catalog", Password: "kv-secret"}, credentials)
+
+		_, err = secrets.NewVault(server.URL, "root", "secret/data/empty", time.Hour).Credentials(ctx)
+		assert.ErrorContains(t, err, "no password")
+
+		_, err = secrets.NewVault(server.URL, "wrong", "secret/data/catalog", time.Hour).Credentials(ctx)
+		assert.ErrorContains(t, err, "permission denied")
+	})
+
+	t.Run("Lease renewed", func(t *testing.T) {
+		vault := &fakeVault{renewable: true}
+		source := secrets.NewVault(vault.server(t).URL, "root", "database/creds/catalog", time.Hour)
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "v-catalog-1", credentials.Username)
+
+		credentials, err = source.Credentials(ctx)
+		require.NoError(t, err)
+		reads, renewals := vault.counts()
+		assert.Equal(t, 1, reads)
+		assert.Equal(t, 0, renewals)
+
+		time.Sleep(700 * time.Millisecond)
+
+		credentials, err = source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "generated-1", credentials.Password)
+		reads, renewals = vault.counts()
+		assert.Equal(t, 1, reads)
+		assert.Equal(t, 1, renewals)
+	})
+
+	t.Run("Renewal failed", func(t *testing.T) {
+		vault := &fakeVault{renewable: true, failRenew: true}
+		source := secrets.NewVault(vault.server(t).URL, "root", "database/creds/catalog", time.Hour)
+
+		_, err := source.Credentials(ctx)
+		require.NoError(t, err)
+
+		time.Sleep(700 * time.Millisecond)
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "generated-2", credentials.Password)
+		reads, renewals := vault.counts()
+		assert.Equal(t, 2, reads)
+		assert.Equal(t, 1, renewals)
+	})
+
+	t.Run("Lease not renewable", func(t *testing.T) {
+		vault := &fakeVault{}
+		source := secrets.NewVault(vault.server(t).URL, "root", "database/creds/catalog", time.Hour)
+
+		_, err := source.Credentials(ctx)
+		require.NoError(t, err)
+
+		time.Sleep(700 * time.Millisecond)
+
+		credentials, err := source.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "v-catalog-2", credentials.Username)
+		_, renewals := vault.counts()
+		assert.Equal(t, 0, renewals)
+	})
+}
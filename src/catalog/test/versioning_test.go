@@ -0,0 +1,67 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/controller"
+	"github.com/aws-containers/retail-store-sample-app/catalog/model"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestSearchVersions(t *testing.T) {
+	db := newInMemoryRepository(t)
+
+	catalogAPI, err := api.NewCatalogAPI(db, db.(repository.SearchRepository))
+	require.NoError(t, err)
+
+	c, err := controller.NewController(catalogAPI)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/v1/catalog/search", c.SearchProducts)
+	r.GET("/v2/catalog/search", c.SearchProductsV2)
+
+	req, _ := http.NewRequest("GET", "/v1/catalog/search?keyword=watch&size=2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var products []model.Product
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+	assert.NotEmpty(t, products)
+
+	req, _ = http.NewRequest("GET", "/v2/catalog/search?keyword=watch&size=2", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response model.SearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, products, response.Products)
+	assert.Equal(t, 1, response.Page)
+	assert.Equal(t, 2, response.Size)
+
+	req, _ = http.NewRequest("GET", "/v2/catalog/search?keyword=watch&size=2&fields=name", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var sparse struct {
+		Products []map[string]any `json:"products"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sparse))
+	assert.Equal(t, map[string]any{"name": products[0].Name}, sparse.Products[0])
+
+	req, _ = http.NewRequest("GET", "/v2/catalog/search", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/config"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+func TestWaitForDependencies(t *testing.T) {
+	startup := config.StartupConfiguration{
+		WaitTimeout:    time.Second,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	}
+	unavailable := errors.New("connection refused")
+
+	t.Run("Retries until available", func(t *testing.T) {
+		attempts := 0
+		result, err := repository.WaitFor("database", startup, func() (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", unavailable
+			}
+			return "connected", nil
+		}, context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "connected", result)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Gives up after the timeout", func(t *testing.T) {
+		startup := startup
+		startup.WaitTimeout = 100 * time.Millisecond
+
+		attempts := 0
+		started := time.Now()
+		_, err := repository.WaitFor("database", startup, func() (string, error) {
+			attempts++
+			return "", unavailable
+		}, context.Background())
+
+		assert.ErrorIs(t, err, unavailable)
+		assert.ErrorContains(t, err, "database still unavailable after")
+		assert.Greater(t, attempts, 1)
+		assert.Less(t, time.Since(started), startup.WaitTimeout+startup.MaxBackoff)
+	})
+
+	t.Run("No timeout", func(t *testing.T) {
+		startup := startup
+		startup.WaitTimeout = 0
+
+		attempts := 0
+		_, err := repository.WaitFor("database", startup, func() (string, error) {
+			attempts++
+			return "", unavailable
+		}, context.Background())
+
+		assert.Equal(t, unavailable, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		attempts := 0
+		_, err := repository.WaitFor("database", startup, func() (repository.CatalogRepository, error) {
+			attempts++
+			return repository.NewRepository(config.DatabaseConfiguration{Type: "nosuch"})
+		}, context.Background())
+
+		var unknown *repository.UnknownProviderError
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "nosuch", unknown.Name)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := repository.WaitFor("search", startup, func() (string, error) {
+			cancel()
+			return "", unavailable
+		}, ctx)
+
+		assert.ErrorIs(t, err, unavailable)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-containers/retail-store-sample-app/catalog/api"
+	"github.com/aws-containers/retail-store-sample-app/catalog/repository"
+)
+
+// recordingSearch remembers the keywords it was searched for
+type recordingSearch struct {
+	stubSearch
+
+	mu       sync.Mutex
+	keywords []string
+}
+
+func (s *recordingSearch) SearchProducts(query repository.SearchQuery, page, size int, ctx context.Context) (repository.SearchResult, error) {
+	s.mu.Lock()
+	s.keywords = append(s.keywords, query.Keyword)
+	s.mu.Unlock()
+
+	return s.stubSearch.SearchProducts(query, page, size, ctx)
+}
+
+func (s *recordingSearch) searched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.keywords...)
+}
+
+func TestWarmUp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Primes the cache", func(t *testing.T) {
+		search := &recordingSearch{stubSearch: stubSearch{ids: []string{"a"}}}
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), search)
+		require.NoError(t, err)
+		catalogAPI.SetCache(100, time.Minute)
+
+		result := catalogAPI.WarmUp(4, ctx)
+		assert.NoError(t, result.Err())
+		assert.Zero(t, result.Failed)
+
+		// The first page of products, each of them, the tags, the
+		// categories and the searches
+		keywords := search.searched()
+		require.Len(t, keywords, 5)
+		assert.Equal(t, 1+10+2+5, result.Requests)
+		assert.Contains(t, keywords, "accessories")
+
+		// The searches a storefront makes first are answered from the cache
+		for _, keyword := range keywords {
+			_, err := catalogAPI.SearchProductsPage(repository.SearchQuery{Keyword: keyword}, "", 1, 10, ctx)
+			require.NoError(t, err)
+		}
+		assert.Len(t, search.searched(), 5)
+	})
+
+	t.Run("Failures don't stop it", func(t *testing.T) {
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), &stubSearch{err: errors.New("unavailable")})
+		require.NoError(t, err)
+
+		result := catalogAPI.WarmUp(4, ctx)
+		assert.Equal(t, 5, result.Failed)
+		assert.Equal(t, 1+10+2+5, result.Requests)
+		assert.NoError(t, result.Err())
+	})
+
+	t.Run("Every request failing is an error", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		catalogAPI, err := api.NewCatalogAPI(newInMemoryRepository(t), nil)
+		require.NoError(t, err)
+
+		result := catalogAPI.WarmUp(4, canceled)
+		assert.Equal(t, result.Requests, result.Failed)
+		assert.Error(t, result.Err())
+	})
+}